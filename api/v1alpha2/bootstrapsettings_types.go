@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BootstrapSettingsAnnotation is the annotation on a Cluster naming the BootstrapSettings its machines are
+// bootstrapped with. Clusters without it use the DefaultBootstrapSettingsName settings, if they exist.
+const BootstrapSettingsAnnotation = "bootstrap.cluster.x-k8s.io/bootstrap-settings"
+
+// DefaultBootstrapSettingsName is the name of the BootstrapSettings used by clusters that do not name any.
+const DefaultBootstrapSettingsName = "default"
+
+// BootstrapSettingsSpec defines the environment-wide settings of the machines bootstrapped in an environment.
+// Settings also defined by a KubeadmConfig are overridden by it.
+type BootstrapSettingsSpec struct {
+	// Proxy is the HTTP proxy the container runtime, kubelet and bootstrap commands reach the network through.
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+
+	// RegistryMirrors are the mirrors the container runtime pulls images from, by registry.
+	// +optional
+	RegistryMirrors []RegistryMirror `json:"registryMirrors,omitempty"`
+
+	// NTP are the NTP servers the machines synchronize their clock with.
+	// +optional
+	NTP *NTP `json:"ntp,omitempty"`
+
+	// ImageRepository is the container registry the control plane images are pulled from, unless set in the
+	// ClusterConfiguration.
+	// +optional
+	ImageRepository string `json:"imageRepository,omitempty"`
+
+	// TokenTTL is how long the bootstrap tokens created for joining machines are valid. Defaults to 10m.
+	// +optional
+	TokenTTL *metav1.Duration `json:"tokenTTL,omitempty"`
+}
+
+// Proxy defines an HTTP proxy.
+type Proxy struct {
+	// HTTPProxy is the URL of the proxy for HTTP requests.
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is the URL of the proxy for HTTPS requests.
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy are the hosts, domains and CIDRs reached without the proxy, besides the loopback addresses, the
+	// service domain, service and pod subnets and API endpoints of the cluster, and the control plane and
+	// discovery endpoints of the KubeadmConfig, which always are.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+// RegistryMirror defines the mirrors of a container registry.
+type RegistryMirror struct {
+	// Registry is the host of the mirrored registry, e.g. docker.io.
+	Registry string `json:"registry"`
+
+	// Endpoints are the URLs of the mirrors, tried in order before the registry itself.
+	// +kubebuilder:validation:MinItems=1
+	Endpoints []string `json:"endpoints"`
+}
+
+// NTP defines the NTP client of the machines.
+type NTP struct {
+	// Enabled enables the NTP client. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Servers are the NTP servers to synchronize with, instead of the default pools of the OS.
+	// +optional
+	Servers []string `json:"servers,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=bootstrapsettings,scope=Cluster
+
+// BootstrapSettings is the Schema for the bootstrapsettings API
+type BootstrapSettings struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BootstrapSettingsSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BootstrapSettingsList contains a list of BootstrapSettings
+type BootstrapSettingsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BootstrapSettings `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BootstrapSettings{}, &BootstrapSettingsList{})
+}
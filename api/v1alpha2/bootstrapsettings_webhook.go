@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers the BootstrapSettings webhooks with mgr.
+func (b *BootstrapSettings) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(b).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-bootstrap-cluster-x-k8s-io-v1alpha2-bootstrapsettings,mutating=false,failurePolicy=fail,groups=bootstrap.cluster.x-k8s.io,resources=bootstrapsettings,versions=v1alpha2,name=validation.bootstrapsettings.bootstrap.cluster.x-k8s.io
+
+var _ webhook.Validator = &BootstrapSettings{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (b *BootstrapSettings) ValidateCreate() error {
+	return b.validate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (b *BootstrapSettings) ValidateUpdate(old runtime.Object) error {
+	return b.validate()
+}
+
+// validate rejects the settings a KubeadmConfig would be rejected for, as they are rendered the same way.
+func (b *BootstrapSettings) validate() error {
+	spec := &KubeadmConfigSpec{
+		Proxy:           b.Spec.Proxy,
+		RegistryMirrors: b.Spec.RegistryMirrors,
+		NTP:             b.Spec.NTP,
+	}
+	pathPrefix := field.NewPath("spec")
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, spec.validateProxy(pathPrefix)...)
+	allErrs = append(allErrs, spec.validateRegistries(pathPrefix)...)
+	allErrs = append(allErrs, spec.validateNTP(pathPrefix)...)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("BootstrapSettings").GroupKind(), b.Name, allErrs)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"testing"
+)
+
+func TestBootstrapSettingsValidate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		spec      BootstrapSettingsSpec
+		expectErr bool
+	}{
+		{
+			name: "proxy, mirrors and NTP servers",
+			spec: BootstrapSettingsSpec{
+				Proxy:           &Proxy{HTTPSProxy: "http://proxy.example.com:3128", NoProxy: []string{".internal.example.com"}},
+				RegistryMirrors: []RegistryMirror{{Registry: "docker.io", Endpoints: []string{"https://cache.example.com"}}},
+				NTP:             &NTP{Servers: []string{"ntp.example.com"}},
+			},
+		},
+		{
+			name:      "proxy that is not an http URL",
+			spec:      BootstrapSettingsSpec{Proxy: &Proxy{HTTPSProxy: "socks5://proxy.example.com:1080"}},
+			expectErr: true,
+		},
+		{
+			name: "registry mirrored twice",
+			spec: BootstrapSettingsSpec{
+				RegistryMirrors: []RegistryMirror{
+					{Registry: "docker.io", Endpoints: []string{"https://cache.example.com"}},
+					{Registry: "docker.io", Endpoints: []string{"https://other-cache.example.com"}},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name:      "NTP server with options",
+			spec:      BootstrapSettingsSpec{NTP: &NTP{Servers: []string{"ntp.example.com: iburst"}}},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := &BootstrapSettings{Spec: tc.spec}
+
+			err := settings.ValidateCreate()
+			if tc.expectErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("expected nil, got error %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// clusterNameLabel is the label cluster-api sets on the bootstrap configs of the machines of a cluster.
+const clusterNameLabel = "cluster.x-k8s.io/cluster-name"
+
+// SetupWebhookWithManager registers the KubeadmConfigTemplate webhooks with mgr.
+func (t *KubeadmConfigTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(t).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/mutate-bootstrap-cluster-x-k8s-io-v1alpha2-kubeadmconfig,mutating=true,failurePolicy=fail,groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigs,versions=v1alpha2,name=default.kubeadmconfig.bootstrap.cluster.x-k8s.io
+// +kubebuilder:webhook:verbs=create;update,path=/mutate-bootstrap-cluster-x-k8s-io-v1alpha2-kubeadmconfigtemplate,mutating=true,failurePolicy=fail,groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigtemplates,versions=v1alpha2,name=default.kubeadmconfigtemplate.bootstrap.cluster.x-k8s.io
+
+var (
+	_ webhook.Defaulter = &KubeadmConfig{}
+	_ webhook.Defaulter = &KubeadmConfigTemplate{}
+)
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+func (c *KubeadmConfig) Default() {
+	c.Spec.setDefaults(c.GetLabels()[clusterNameLabel])
+}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+func (t *KubeadmConfigTemplate) Default() {
+	t.Spec.Template.Spec.setDefaults(t.GetLabels()[clusterNameLabel])
+}
+
+// setDefaults fills the fields left empty with the values the controller or kubeadm would otherwise use, so that
+// they are visible on the object. A spec with neither an init nor a cluster configuration is a join one.
+func (s *KubeadmConfigSpec) setDefaults(clusterName string) {
+	if s.GeneratedFilesDir == "" {
+		s.GeneratedFilesDir = DefaultGeneratedFilesDir
+	}
+	if s.InitConfiguration == nil && s.ClusterConfiguration == nil && s.JoinConfiguration == nil {
+		s.JoinConfiguration = &kubeadmv1beta1.JoinConfiguration{}
+	}
+	if s.ClusterConfiguration != nil && s.ClusterConfiguration.ClusterName == "" {
+		s.ClusterConfiguration.ClusterName = clusterName
+	}
+	if s.InitConfiguration != nil {
+		defaultCRISocket(&s.InitConfiguration.NodeRegistration)
+	}
+	if s.JoinConfiguration != nil {
+		defaultCRISocket(&s.JoinConfiguration.NodeRegistration)
+	}
+}
+
+// defaultCRISocket defaults the CRI socket of the node registration to the container-runtime-endpoint of the
+// kubelet, kubeadm passing it to the kubelet as such. Otherwise the CRI socket is left empty for kubeadm to
+// detect the container runtime of the machine.
+func defaultCRISocket(nodeRegistration *kubeadmv1beta1.NodeRegistrationOptions) {
+	if nodeRegistration.CRISocket != "" {
+		return
+	}
+	nodeRegistration.CRISocket = nodeRegistration.KubeletExtraArgs["container-runtime-endpoint"]
+}
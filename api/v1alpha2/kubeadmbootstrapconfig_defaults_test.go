@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+func TestKubeadmConfigDefault(t *testing.T) {
+	worker := &KubeadmConfig{}
+	worker.Default()
+	if worker.Spec.JoinConfiguration == nil {
+		t.Fatal("expected a join configuration to be defaulted")
+	}
+	if s := worker.Spec.JoinConfiguration.NodeRegistration.CRISocket; s != "" {
+		t.Fatalf("expected the CRI socket to be left for kubeadm to detect, got %q", s)
+	}
+	if worker.Spec.GeneratedFilesDir != DefaultGeneratedFilesDir {
+		t.Fatalf("expected generated files dir %s, got %q", DefaultGeneratedFilesDir, worker.Spec.GeneratedFilesDir)
+	}
+
+	controlPlane := &KubeadmConfig{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{clusterNameLabel: "my-cluster"}},
+		Spec: KubeadmConfigSpec{
+			GeneratedFilesDir:    "/var/lib/kubeadm",
+			ClusterConfiguration: &kubeadmv1beta1.ClusterConfiguration{},
+			InitConfiguration: &kubeadmv1beta1.InitConfiguration{
+				NodeRegistration: kubeadmv1beta1.NodeRegistrationOptions{
+					KubeletExtraArgs: map[string]string{"container-runtime-endpoint": "unix:///run/containerd/containerd.sock"},
+				},
+			},
+		},
+	}
+	controlPlane.Default()
+	if controlPlane.Spec.JoinConfiguration != nil {
+		t.Fatal("expected no join configuration to be defaulted for an init configuration")
+	}
+	if controlPlane.Spec.ClusterConfiguration.ClusterName != "my-cluster" {
+		t.Fatalf("expected the cluster name to be defaulted from the label, got %q", controlPlane.Spec.ClusterConfiguration.ClusterName)
+	}
+	if s := controlPlane.Spec.InitConfiguration.NodeRegistration.CRISocket; s != "unix:///run/containerd/containerd.sock" {
+		t.Fatalf("expected the CRI socket of the kubelet, got %q", s)
+	}
+	if controlPlane.Spec.GeneratedFilesDir != "/var/lib/kubeadm" {
+		t.Fatalf("expected the generated files dir to be kept, got %q", controlPlane.Spec.GeneratedFilesDir)
+	}
+}
+
+func TestKubeadmConfigTemplateDefault(t *testing.T) {
+	template := &KubeadmConfigTemplate{}
+	template.Spec.Template.Spec.JoinConfiguration = &kubeadmv1beta1.JoinConfiguration{
+		NodeRegistration: kubeadmv1beta1.NodeRegistrationOptions{CRISocket: "/var/run/crio/crio.sock"},
+	}
+	template.Default()
+	if s := template.Spec.Template.Spec.JoinConfiguration.NodeRegistration.CRISocket; s != "/var/run/crio/crio.sock" {
+		t.Fatalf("expected the CRI socket to be kept, got %q", s)
+	}
+	if template.Spec.Template.Spec.GeneratedFilesDir != DefaultGeneratedFilesDir {
+		t.Fatalf("expected generated files dir %s, got %q", DefaultGeneratedFilesDir, template.Spec.Template.Spec.GeneratedFilesDir)
+	}
+}
@@ -0,0 +1,1623 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	kubeadmv1beta2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultEtcdHeartbeatInterval and defaultEtcdElectionTimeout are the etcd defaults, which etcd requires to be
+// at least five times apart.
+const (
+	defaultEtcdHeartbeatInterval = 100 * time.Millisecond
+	defaultEtcdElectionTimeout   = time.Second
+)
+
+// etcdTuningFlags are the etcd flags rendered from the EtcdTuning.
+var etcdTuningFlags = []string{"quota-backend-bytes", "heartbeat-interval", "election-timeout", "listen-metrics-urls"}
+
+// admissionDir is the directory of the admission configuration files written on control plane machines.
+const admissionDir = "/etc/kubernetes/admission"
+
+// schedulerConfigurationDir and controllerManagerConfigurationDir are the directories of the configuration files
+// of the scheduler and controller manager written on control plane machines.
+const (
+	schedulerConfigurationDir         = "/etc/kubernetes/scheduler"
+	controllerManagerConfigurationDir = "/etc/kubernetes/controller-manager"
+)
+
+// unsafeShellChars are the characters that can't be rendered within double quotes in the commands and scripts
+// written on the machine, which are themselves within single quotes.
+const unsafeShellChars = "\"$`\\'"
+
+var (
+	// reservedFilePaths are the files written by the controller itself on the machine, besides the ones in
+	// GeneratedFilesDir, see the cloudinit and certs packages. Users writing them would silently conflict with the
+	// controller.
+	reservedFilePaths = map[string]bool{
+		"/etc/kubernetes/pki/ca.crt":             true,
+		"/etc/kubernetes/pki/ca.key":             true,
+		"/etc/kubernetes/pki/etcd/ca.crt":        true,
+		"/etc/kubernetes/pki/etcd/ca.key":        true,
+		"/etc/kubernetes/pki/front-proxy-ca.crt": true,
+		"/etc/kubernetes/pki/front-proxy-ca.key": true,
+		"/etc/kubernetes/pki/sa.pub":             true,
+		"/etc/kubernetes/pki/sa.key":             true,
+	}
+
+	// reservedFileDirs are directories owned by cloud-init.
+	reservedFileDirs = []string{
+		"/var/lib/cloud/",
+	}
+
+	// permissionsRegexp matches octal file permissions.
+	permissionsRegexp = regexp.MustCompile(`^[0-7]{3,4}$`)
+
+	// sha256Regexp matches hex encoded sha256 checksums.
+	sha256Regexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+	// binaryInstallFilePaths are the files written when the Kubernetes binaries are installed.
+	binaryInstallFilePaths = map[string]bool{
+		"/etc/systemd/system/kubelet.service":                   true,
+		"/etc/systemd/system/kubelet.service.d/10-kubeadm.conf": true,
+	}
+
+	// unauthenticatedPackageFlags are package manager flags disabling the signature checks of package
+	// repositories.
+	unauthenticatedPackageFlags = []string{
+		"--allow-unauthenticated",
+		"--allow-insecure-repositories",
+		"--nogpgcheck",
+		"trusted=yes",
+	}
+
+	// cisFilePaths are the files written by the CIS hardening profile.
+	cisFilePaths = map[string]bool{
+		"/etc/sysctl.d/90-kubelet.conf": true,
+	}
+
+	// flagNameRegexp matches the names of the flags of the control plane components.
+	flagNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+	// admissionPluginNameRegexp matches the names of admission plugins.
+	admissionPluginNameRegexp = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+	// evictionSignals are the kubelet eviction signals that thresholds can be set for.
+	evictionSignals = []string{
+		"imagefs.available",
+		"imagefs.inodesFree",
+		"memory.available",
+		"nodefs.available",
+		"nodefs.inodesFree",
+		"pid.available",
+	}
+
+	// imageRegexp matches container image references, capturing the registry, the tag and the digest.
+	imageRegexp = regexp.MustCompile(`^(?:([a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?)/)?` +
+		`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+		`(:[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?(@sha256:[0-9a-f]{64})?$`)
+
+	// versionRegexp matches the versions the image preflight can check for.
+	versionRegexp = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)*([-+][0-9A-Za-z.+-]+)?$`)
+
+	// userNameRegexp matches the user names useradd accepts by default.
+	userNameRegexp = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+
+	// filesystemTypeRegexp matches the filesystem types mkfs can create, e.g. ext4.
+	filesystemTypeRegexp = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+	// filesystemLabelRegexp matches the filesystem labels that can be rendered as is.
+	filesystemLabelRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+	// diskSetupFieldRegexp matches the mkfs options and fstab fields that can be rendered as is.
+	diskSetupFieldRegexp = regexp.MustCompile(`^[A-Za-z0-9/=,._:@+-]+$`)
+
+	// partitionSuffixRegexp matches the suffix of the partitions of a device, e.g. 1 of /dev/sdb1 or p1 of
+	// /dev/nvme1n1p1.
+	partitionSuffixRegexp = regexp.MustCompile(`^p?[0-9]+$`)
+
+	// generatedCloudConfigKeys are the cloud-config keys the controller generates, and the fields they are generated
+	// from.
+	generatedCloudConfigKeys = map[string]string{
+		"write_files": "additionalUserDataFiles",
+		"runcmd":      "preKubeadmCommands and postKubeadmCommands",
+		"users":       "users",
+		"ntp":         "ntp",
+		"disk_setup":  "etcdDisk and diskSetup",
+		"fs_setup":    "etcdDisk and diskSetup",
+		"mounts":      "etcdDisk and mounts",
+		"output":      "the trace ID",
+	}
+
+	// mimeTypeRegexp matches the MIME types of the parts of the MIME multi-part archive, without parameters.
+	mimeTypeRegexp = regexp.MustCompile(`^[a-z]+/[a-z0-9.+-]+$`)
+)
+
+// SetupWebhookWithManager registers the KubeadmConfig webhooks with mgr.
+func (c *KubeadmConfig) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(c).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-bootstrap-cluster-x-k8s-io-v1alpha2-kubeadmconfig,mutating=false,failurePolicy=fail,groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigs,versions=v1alpha2,name=validation.kubeadmconfig.bootstrap.cluster.x-k8s.io
+
+var _ webhook.Validator = &KubeadmConfig{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (c *KubeadmConfig) ValidateCreate() error {
+	return observeAdmission("create", c.validate)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (c *KubeadmConfig) ValidateUpdate(old runtime.Object) error {
+	return observeAdmission("update", c.validate)
+}
+
+func (c *KubeadmConfig) validate() error {
+	allErrs := c.Spec.validate(field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("KubeadmConfig").GroupKind(), c.Name, allErrs)
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-bootstrap-cluster-x-k8s-io-v1alpha2-kubeadmconfigtemplate,mutating=false,failurePolicy=fail,groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigtemplates,versions=v1alpha2,name=validation.kubeadmconfigtemplate.bootstrap.cluster.x-k8s.io
+
+var _ webhook.Validator = &KubeadmConfigTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (t *KubeadmConfigTemplate) ValidateCreate() error {
+	return observeAdmission("create", t.validate)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (t *KubeadmConfigTemplate) ValidateUpdate(old runtime.Object) error {
+	return observeAdmission("update", t.validate)
+}
+
+// validate rejects the templates whose spec the KubeadmConfigs created from them would be rejected for.
+func (t *KubeadmConfigTemplate) validate() error {
+	allErrs := t.Spec.Template.Spec.validate(field.NewPath("spec", "template", "spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("KubeadmConfigTemplate").GroupKind(), t.Name, allErrs)
+}
+
+// validate returns the field errors of the spec, whose fields are under pathPrefix.
+func (s *KubeadmConfigSpec) validate(pathPrefix *field.Path) field.ErrorList {
+	allErrs := s.validateFiles(pathPrefix)
+	allErrs = append(allErrs, s.validateCloudInit(pathPrefix)...)
+	allErrs = append(allErrs, s.validateFormat(pathPrefix)...)
+	allErrs = append(allErrs, s.validateMultipart(pathPrefix)...)
+	allErrs = append(allErrs, s.validateAdditionalCloudConfig(pathPrefix)...)
+	allErrs = append(allErrs, s.validateKubeadmConfigPatches(pathPrefix)...)
+	allErrs = append(allErrs, s.validateImagePreflight(pathPrefix)...)
+	allErrs = append(allErrs, s.validateKubeadmContainer(pathPrefix)...)
+	allErrs = append(allErrs, s.validateVariants(pathPrefix)...)
+	allErrs = append(allErrs, s.validateArtifacts(pathPrefix)...)
+	allErrs = append(allErrs, s.validateBinaryInstall(pathPrefix)...)
+	allErrs = append(allErrs, s.validatePackageRepositories(pathPrefix)...)
+	allErrs = append(allErrs, s.validateTrustedCABundles(pathPrefix)...)
+	allErrs = append(allErrs, s.validateHardeningProfile(pathPrefix)...)
+	allErrs = append(allErrs, s.validateControlPlaneTaints(pathPrefix)...)
+	allErrs = append(allErrs, s.validateEtcdTuning(pathPrefix)...)
+	allErrs = append(allErrs, s.validateEtcdDisk(pathPrefix)...)
+	allErrs = append(allErrs, s.validateDiskSetup(pathPrefix)...)
+	allErrs = append(allErrs, s.validateKubeletTuning(pathPrefix)...)
+	allErrs = append(allErrs, s.validateClusterDNS(pathPrefix)...)
+	allErrs = append(allErrs, s.validateNTP(pathPrefix)...)
+	allErrs = append(allErrs, s.validateProxy(pathPrefix)...)
+	allErrs = append(allErrs, s.validateRegistries(pathPrefix)...)
+	allErrs = append(allErrs, s.validateUsers(pathPrefix)...)
+	allErrs = append(allErrs, s.validateStartupTaint(pathPrefix)...)
+	allErrs = append(allErrs, s.validateRetainedIdentity(pathPrefix)...)
+	allErrs = append(allErrs, s.validatePodSecurity(pathPrefix)...)
+	allErrs = append(allErrs, s.validateAdmissionPlugins(pathPrefix)...)
+	allErrs = append(allErrs, s.validateComponentConfigurations(pathPrefix)...)
+	allErrs = append(allErrs, s.validateExternalCAs(pathPrefix)...)
+	allErrs = append(allErrs, s.validateKubeadmAPIVersions(pathPrefix)...)
+	return allErrs
+}
+
+// validateFiles rejects files written more than once, conflicting with the files the controller writes, or with
+// content that can't be decoded.
+func (s *KubeadmConfigSpec) validateFiles(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	generatedFilesDir := DefaultGeneratedFilesDir
+	if s.GeneratedFilesDir != "" {
+		if !path.IsAbs(s.GeneratedFilesDir) {
+			allErrs = append(allErrs, field.Invalid(pathPrefix.Child("generatedFilesDir"), s.GeneratedFilesDir, "must be an absolute path"))
+		} else if strings.ContainsAny(s.GeneratedFilesDir, unsafeShellChars+" \t\n") {
+			allErrs = append(allErrs, field.Invalid(pathPrefix.Child("generatedFilesDir"), s.GeneratedFilesDir, "must not contain whitespace or any of "+unsafeShellChars))
+		}
+		generatedFilesDir = path.Clean(s.GeneratedFilesDir)
+	}
+
+	seen := map[string]bool{}
+	for i, file := range s.AdditionalUserDataFiles {
+		fldPath := pathPrefix.Child("additionalUserDataFiles").Index(i).Child("path")
+		filePath := path.Clean(file.Path)
+
+		if seen[filePath] {
+			allErrs = append(allErrs, field.Duplicate(fldPath, file.Path))
+		}
+		seen[filePath] = true
+
+		allErrs = append(allErrs, validateFilePath(fldPath, filePath, generatedFilesDir)...)
+		allErrs = append(allErrs, validateFileContent(pathPrefix.Child("additionalUserDataFiles").Index(i), file)...)
+	}
+
+	// files conditional on an OS family are moved in place by a command, so they must have an absolute path
+	for i, conditional := range s.OSConditionals {
+		for j, file := range conditional.Files {
+			fldPath := pathPrefix.Child("osConditionals").Index(i).Child("files").Index(j).Child("path")
+			if !path.IsAbs(file.Path) {
+				allErrs = append(allErrs, field.Invalid(fldPath, file.Path, "must be an absolute path"))
+				continue
+			}
+			allErrs = append(allErrs, validateFilePath(fldPath, path.Clean(file.Path), generatedFilesDir)...)
+			allErrs = append(allErrs, validateFileContent(pathPrefix.Child("osConditionals").Index(i).Child("files").Index(j), file)...)
+		}
+	}
+	return allErrs
+}
+
+// validateFileContent rejects content that can't be decoded from the encoding of the file, and content sources
+// that don't reference exactly one Secret or ConfigMap key.
+func validateFileContent(fldPath *field.Path, file Files) field.ErrorList {
+	var allErrs field.ErrorList
+	if source := file.ContentFrom; source != nil {
+		sourcePath := fldPath.Child("contentFrom")
+		if file.Content != "" {
+			allErrs = append(allErrs, field.Forbidden(sourcePath, "only one of content or contentFrom may be set"))
+		}
+		return append(allErrs, validateFileSource(sourcePath, source)...)
+	}
+
+	switch file.Encoding {
+	case FileEncodingBase64, FileEncodingGzipBase64:
+		if _, err := base64.StdEncoding.DecodeString(file.Content); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("content"), "", fmt.Sprintf("must be base64 encoded: %v", err)))
+		}
+	}
+	return allErrs
+}
+
+// validateFileSource rejects content sources that don't reference exactly one Secret or ConfigMap key.
+func validateFileSource(fldPath *field.Path, source *FileSource) field.ErrorList {
+	switch {
+	case source.Secret == nil && source.ConfigMap == nil:
+		return field.ErrorList{field.Required(fldPath, "secret or configMap must be set")}
+	case source.Secret != nil && source.ConfigMap != nil:
+		return field.ErrorList{field.Forbidden(fldPath.Child("configMap"), "only one of secret or configMap may be set")}
+	case source.Secret != nil:
+		return validateFileSourceKey(fldPath.Child("secret"), source.Secret)
+	default:
+		return validateFileSourceKey(fldPath.Child("configMap"), source.ConfigMap)
+	}
+}
+
+// validateFileSourceKey rejects references to invalid Secret or ConfigMap names or keys.
+func validateFileSourceKey(fldPath *field.Path, key *FileSourceKey) field.ErrorList {
+	var allErrs field.ErrorList
+	if msgs := validation.IsDNS1123Subdomain(key.Name); len(msgs) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), key.Name, strings.Join(msgs, ", ")))
+	}
+	if msgs := validation.IsConfigMapKey(key.Key); len(msgs) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("key"), key.Key, strings.Join(msgs, ", ")))
+	}
+	return allErrs
+}
+
+// validateFilePath rejects a cleaned file path conflicting with the files the controller or cloud-init write.
+func validateFilePath(fldPath *field.Path, filePath, generatedFilesDir string) field.ErrorList {
+	var allErrs field.ErrorList
+	if reservedFilePaths[filePath] {
+		return append(allErrs, field.Forbidden(fldPath, "path is written by the bootstrap provider"))
+	}
+	if strings.HasPrefix(filePath, generatedFilesDir+"/") {
+		return append(allErrs, field.Forbidden(fldPath, "path is in "+generatedFilesDir+", which is owned by the bootstrap provider"))
+	}
+	for _, dir := range reservedFileDirs {
+		if strings.HasPrefix(filePath, dir) {
+			allErrs = append(allErrs, field.Forbidden(fldPath, "path is in "+dir+", which is owned by cloud-init"))
+		}
+	}
+	return allErrs
+}
+
+// validateCloudInit rejects combinations of the cloud-init sections that are each valid but can't work together
+// on the machine: files conflicting with each other, hidden by a mount or owned by unknown users, and mounts of
+// filesystems that are not set up or of the same mount point.
+func (s *KubeadmConfigSpec) validateCloudInit(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	// a file can't be written where another file needs a directory
+	for i, file := range s.AdditionalUserDataFiles {
+		dir := path.Clean(file.Path) + "/"
+		for j, other := range s.AdditionalUserDataFiles {
+			if i != j && strings.HasPrefix(path.Clean(other.Path), dir) {
+				fldPath := pathPrefix.Child("additionalUserDataFiles").Index(i).Child("path")
+				allErrs = append(allErrs, field.Invalid(fldPath, file.Path,
+					fmt.Sprintf("path is a parent directory of %s", pathPrefix.Child("additionalUserDataFiles").Index(j).Child("path"))))
+				break
+			}
+		}
+	}
+
+	// files are written before the filesystems are mounted, so a file under a mount point is hidden
+	mountPoints := map[string]int{}
+	for i, m := range s.Mounts {
+		if len(m) < 2 || m[1] == "none" || m[1] == "swap" {
+			continue
+		}
+		mountPoint := path.Clean(m[1])
+		if _, ok := mountPoints[mountPoint]; ok {
+			allErrs = append(allErrs, field.Duplicate(pathPrefix.Child("mounts").Index(i).Index(1), m[1]))
+			continue
+		}
+		mountPoints[mountPoint] = i
+	}
+	for i, file := range s.AdditionalUserDataFiles {
+		for dir := path.Dir(path.Clean(file.Path)); dir != "/" && dir != "."; dir = path.Dir(dir) {
+			if j, ok := mountPoints[dir]; ok {
+				allErrs = append(allErrs, field.Invalid(pathPrefix.Child("additionalUserDataFiles").Index(i).Child("path"), file.Path,
+					fmt.Sprintf("path is under the mount point of %s, which hides it", pathPrefix.Child("mounts").Index(j))))
+				break
+			}
+		}
+	}
+
+	// only the filesystems of the disk setup can be mounted by label or device
+	var filesystems []Filesystem
+	if s.DiskSetup != nil {
+		filesystems = s.DiskSetup.Filesystems
+	}
+	for i, m := range s.Mounts {
+		if len(m) < 2 {
+			continue
+		}
+		if !filesystemDefined(filesystems, m[0]) {
+			allErrs = append(allErrs, field.Invalid(pathPrefix.Child("mounts").Index(i).Index(0), m[0], "must be a filesystem of diskSetup.filesystems"))
+		}
+	}
+
+	users := map[string]bool{"root": true}
+	for _, u := range s.Users {
+		users[u.Name] = true
+	}
+	for i, file := range s.AdditionalUserDataFiles {
+		if file.Owner == "" {
+			continue
+		}
+		owner := strings.SplitN(file.Owner, ":", 2)[0]
+		if _, err := strconv.ParseUint(owner, 10, 32); err != nil && !users[owner] {
+			allErrs = append(allErrs, field.Invalid(pathPrefix.Child("additionalUserDataFiles").Index(i).Child("owner"), file.Owner,
+				"must be owned by root, a user id or one of the users"))
+		}
+	}
+	return allErrs
+}
+
+// filesystemDefined returns whether filesystems define the device of a mount, referenced as LABEL=<label>, as the
+// device of a filesystem or as one of its partitions. Devices referenced otherwise, e.g. UUID=<uuid>, can't be
+// checked.
+func filesystemDefined(filesystems []Filesystem, device string) bool {
+	if label := strings.TrimPrefix(device, "LABEL="); label != device {
+		for _, f := range filesystems {
+			if f.Label == label {
+				return true
+			}
+		}
+		return false
+	}
+	if !strings.HasPrefix(device, "/dev/") {
+		return true
+	}
+	for _, f := range filesystems {
+		if device == f.Device || (strings.HasPrefix(device, f.Device) && partitionSuffixRegexp.MatchString(strings.TrimPrefix(device, f.Device))) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateFormat rejects format names that can't be registered, the delivery of Ignition configs and Windows
+// user data over SSH, which runs cloud-init with them, and the compressions the format or delivery can't use.
+func (s *KubeadmConfigSpec) validateFormat(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.Format != "" {
+		if msgs := validation.IsDNS1123Label(string(s.Format)); len(msgs) > 0 {
+			allErrs = append(allErrs, field.Invalid(pathPrefix.Child("format"), s.Format, strings.Join(msgs, ", ")))
+		}
+	}
+	if (s.Format == FormatIgnition || s.Format == FormatWindows) && s.Delivery != nil && s.Delivery.SSH != nil {
+		allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("delivery", "ssh"), fmt.Sprintf("the %s format can't be delivered over SSH", s.Format)))
+	}
+	if s.Format == FormatWindows {
+		allErrs = append(allErrs, s.validateWindowsFormat(pathPrefix)...)
+	}
+	if s.Compression != "" {
+		// Ignition does not decompress its config, and cloud-init is run with the pushed user data as is
+		if s.Format == FormatIgnition {
+			allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("compression"), "is not supported by the ignition format"))
+		}
+		if s.Delivery != nil && s.Delivery.SSH != nil {
+			allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("compression"), "is not supported by the SSH delivery"))
+		}
+	}
+	return allErrs
+}
+
+// validateMultipart rejects a MIME multi-part archive for the formats other than cloud-config, which don't render
+// one, and parts without a valid content type or with more than one content.
+func (s *KubeadmConfigSpec) validateMultipart(pathPrefix *field.Path) field.ErrorList {
+	if s.Multipart == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	if s.Format != "" && s.Format != FormatCloudConfig {
+		allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("multipart"), fmt.Sprintf("is not supported by the %s format", s.Format)))
+	}
+	for i, part := range s.Multipart.Parts {
+		fldPath := pathPrefix.Child("multipart", "parts").Index(i)
+		if !mimeTypeRegexp.MatchString(part.ContentType) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("contentType"), part.ContentType, "must be a MIME type, e.g. text/cloud-config"))
+		}
+		if strings.ContainsAny(part.Filename, "\"\r\n/") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("filename"), part.Filename, "must not contain quotes, line breaks or slashes"))
+		}
+		if part.ContentFrom != nil {
+			if part.Content != "" {
+				allErrs = append(allErrs, field.Forbidden(fldPath.Child("contentFrom"), "only one of content or contentFrom may be set"))
+			}
+			allErrs = append(allErrs, validateFileSource(fldPath.Child("contentFrom"), part.ContentFrom)...)
+		}
+	}
+	return allErrs
+}
+
+// validateAdditionalCloudConfig rejects an additional cloud-config for the formats other than cloud-config, and
+// one that isn't a YAML mapping or sets a key the controller generates, which would replace the generated one.
+func (s *KubeadmConfigSpec) validateAdditionalCloudConfig(pathPrefix *field.Path) field.ErrorList {
+	if s.AdditionalCloudConfig == "" {
+		return nil
+	}
+	fldPath := pathPrefix.Child("additionalCloudConfig")
+	var allErrs field.ErrorList
+	if s.Format != "" && s.Format != FormatCloudConfig {
+		allErrs = append(allErrs, field.Forbidden(fldPath, fmt.Sprintf("is not supported by the %s format", s.Format)))
+	}
+
+	// the mapping is appended to the generated one, so its keys must not be indented
+	for _, line := range strings.Split(s.AdditionalCloudConfig, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, " ") {
+			allErrs = append(allErrs, field.Invalid(fldPath, "", "must not be indented"))
+		}
+		break
+	}
+	cloudConfig := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(s.AdditionalCloudConfig), &cloudConfig); err != nil {
+		return append(allErrs, field.Invalid(fldPath, "", fmt.Sprintf("must be a YAML mapping: %v", err)))
+	}
+	for _, key := range sortedKeys(generatedCloudConfigKeys) {
+		if _, ok := cloudConfig[key]; ok {
+			allErrs = append(allErrs, field.Forbidden(fldPath, fmt.Sprintf("must not set %s, which is generated from %s", key, generatedCloudConfigKeys[key])))
+		}
+	}
+	return allErrs
+}
+
+// validateWindowsFormat rejects the control plane configurations, as Windows machines only join clusters as
+// workers, and the fields rendered as Linux files and shell commands, which the windows format does not render.
+func (s *KubeadmConfigSpec) validateWindowsFormat(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.ClusterConfiguration != nil {
+		allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("clusterConfiguration"), "the windows format only supports worker machines"))
+	}
+	if s.InitConfiguration != nil {
+		allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("initConfiguration"), "the windows format only supports worker machines"))
+	}
+	if s.JoinConfiguration != nil && s.JoinConfiguration.ControlPlane != nil {
+		allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("joinConfiguration", "controlPlane"), "the windows format only supports worker machines"))
+	}
+
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"controlPlaneEndpointIP", s.ControlPlaneEndpointIP != ""},
+		{"kubeadmContainer", s.KubeadmContainer != nil},
+		{"joinRetry", s.JoinRetry != nil},
+		{"kubeadmFailureReport", s.KubeadmFailureReport != nil},
+		{"imagePreflight", s.ImagePreflight != nil},
+		{"osConditionals", len(s.OSConditionals) > 0},
+		{"artifacts", len(s.Artifacts) > 0},
+		{"binaryInstall", s.BinaryInstall != nil},
+		{"packageRepositories", len(s.PackageRepositories) > 0},
+		{"trustedCABundles", len(s.TrustedCABundles) > 0},
+		{"hardeningProfile", s.HardeningProfile != ""},
+		{"etcdDisk", s.EtcdDisk != nil},
+		{"diskSetup", s.DiskSetup != nil},
+		{"mounts", len(s.Mounts) > 0},
+		{"ntp", s.NTP != nil},
+		{"proxy", s.Proxy != nil},
+		{"registryMirrors", len(s.RegistryMirrors) > 0},
+		{"registryCredentials", len(s.RegistryCredentials) > 0},
+		{"users", len(s.Users) > 0},
+		{"startupTaint", s.StartupTaint != nil},
+	} {
+		if f.set {
+			allErrs = append(allErrs, field.Forbidden(pathPrefix.Child(f.name), "is not supported by the windows format"))
+		}
+	}
+	return allErrs
+}
+
+// validateKubeadmConfigPatches rejects patches that can't be decoded, which would otherwise only fail at render time.
+func (s *KubeadmConfigSpec) validateKubeadmConfigPatches(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, p := range s.KubeadmConfigPatches {
+		fldPath := pathPrefix.Child("kubeadmConfigPatches").Index(i).Child("patch")
+
+		patchJSON, err := yaml.YAMLToJSON([]byte(p.Patch))
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath, p.Patch, err.Error()))
+			continue
+		}
+		if p.Type == KubeadmConfigMergePatch {
+			if err := json.Unmarshal(patchJSON, &map[string]interface{}{}); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath, p.Patch, "merge patch must be an object"))
+			}
+			continue
+		}
+		if _, err := jsonpatch.DecodePatch(patchJSON); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath, p.Patch, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+// validateArtifacts rejects artifacts that can't be safely rendered into the download commands.
+func (s *KubeadmConfigSpec) validateArtifacts(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	generatedFilesDir := DefaultGeneratedFilesDir
+	if s.GeneratedFilesDir != "" {
+		generatedFilesDir = path.Clean(s.GeneratedFilesDir)
+	}
+
+	for i, artifact := range s.Artifacts {
+		fldPath := pathPrefix.Child("artifacts").Index(i)
+		if !path.IsAbs(artifact.Path) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("path"), artifact.Path, "must be an absolute path"))
+		} else if strings.ContainsAny(artifact.Path, unsafeShellChars) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("path"), artifact.Path, "must not contain any of "+unsafeShellChars))
+		} else {
+			allErrs = append(allErrs, validateFilePath(fldPath.Child("path"), path.Clean(artifact.Path), generatedFilesDir)...)
+		}
+
+		if artifact.URL == "" && len(artifact.ArchitectureURLs) == 0 {
+			allErrs = append(allErrs, field.Required(fldPath.Child("url"), "url or architectureURLs must be set"))
+		}
+		if artifact.URL != "" {
+			allErrs = append(allErrs, validateArtifactURL(fldPath.Child("url"), artifact.URL)...)
+		}
+		for arch, u := range artifact.ArchitectureURLs {
+			allErrs = append(allErrs, validateArtifactURL(fldPath.Child("architectureURLs").Key(arch), u)...)
+		}
+
+		if artifact.Permissions != "" && !permissionsRegexp.MatchString(artifact.Permissions) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("permissions"), artifact.Permissions, "must be octal, e.g. 0755"))
+		}
+		if artifact.SHA256 != "" && !sha256Regexp.MatchString(artifact.SHA256) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("sha256"), artifact.SHA256, "must be a hex encoded sha256 checksum"))
+		}
+		if artifact.Cosign != nil {
+			allErrs = append(allErrs, validateCosignPublicKey(fldPath.Child("cosign", "publicKey"), artifact.Cosign.PublicKey)...)
+			if artifact.Cosign.SignatureURL != "" {
+				allErrs = append(allErrs, validateArtifactURL(fldPath.Child("cosign", "signatureURL"), artifact.Cosign.SignatureURL)...)
+			}
+		}
+	}
+	return allErrs
+}
+
+// validateCosignPublicKey rejects keys that are not PEM encoded public keys.
+func validateCosignPublicKey(fldPath *field.Path, key string) field.ErrorList {
+	var allErrs field.ErrorList
+	if block, _ := pem.Decode([]byte(key)); block == nil || block.Type != "PUBLIC KEY" {
+		allErrs = append(allErrs, field.Invalid(fldPath, key, "must be a PEM encoded PUBLIC KEY"))
+	}
+	return allErrs
+}
+
+func validateArtifactURL(fldPath *field.Path, rawURL string) field.ErrorList {
+	var allErrs field.ErrorList
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, rawURL, err.Error()))
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		allErrs = append(allErrs, field.Invalid(fldPath, rawURL, "must be an http or https URL"))
+	}
+	if strings.ContainsAny(rawURL, unsafeShellChars) {
+		allErrs = append(allErrs, field.Invalid(fldPath, rawURL, "must not contain any of "+unsafeShellChars))
+	}
+	return allErrs
+}
+
+// validateBinaryInstall rejects values that can't be safely rendered into the install commands, and files
+// conflicting with the kubelet systemd unit.
+func (s *KubeadmConfigSpec) validateBinaryInstall(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.BinaryInstall == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("binaryInstall")
+	if v := s.BinaryInstall.Version; v != "" && !versionRegexp.MatchString(v) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("version"), v, "must be a version, e.g. v1.15.3"))
+	}
+	if s.BinaryInstall.BaseURL != "" {
+		allErrs = append(allErrs, validateArtifactURL(fldPath.Child("baseURL"), s.BinaryInstall.BaseURL)...)
+	}
+	if dir := s.BinaryInstall.InstallDir; dir != "" {
+		if !path.IsAbs(dir) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("installDir"), dir, "must be an absolute path"))
+		} else if strings.ContainsAny(dir, unsafeShellChars+" ") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("installDir"), dir, "must not contain spaces or any of "+unsafeShellChars))
+		}
+	}
+	if s.BinaryInstall.CosignPublicKey != "" {
+		allErrs = append(allErrs, validateCosignPublicKey(fldPath.Child("cosignPublicKey"), s.BinaryInstall.CosignPublicKey)...)
+	}
+	for key, sum := range s.BinaryInstall.SHA256 {
+		parts := strings.Split(key, "/")
+		if len(parts) != 2 || parts[0] == "" || (parts[1] != "kubeadm" && parts[1] != "kubelet" && parts[1] != "kubectl") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("sha256"), key, "keys must be <architecture>/<binary>, the binary one of kubeadm, kubelet or kubectl"))
+		}
+		if !sha256Regexp.MatchString(sum) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("sha256").Key(key), sum, "must be a hex encoded sha256 checksum"))
+		}
+	}
+
+	for i, file := range s.AdditionalUserDataFiles {
+		if binaryInstallFilePaths[path.Clean(file.Path)] {
+			allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("additionalUserDataFiles").Index(i).Child("path"),
+				"path is written by binaryInstall, use binaryInstall.kubeletUnit instead"))
+		}
+	}
+	return allErrs
+}
+
+// validatePackageRepositories rejects repositories that can't be told apart, are not bound to a GPG key or
+// can't be safely rendered into the repository files, and commands disabling the signature checks.
+func (s *KubeadmConfigSpec) validatePackageRepositories(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(s.PackageRepositories) == 0 {
+		return allErrs
+	}
+
+	seen := map[string]bool{}
+	for i, repo := range s.PackageRepositories {
+		fldPath := pathPrefix.Child("packageRepositories").Index(i)
+		if msgs := validation.IsDNS1123Label(repo.Name); len(msgs) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), repo.Name, strings.Join(msgs, ", ")))
+		} else if seen[repo.Name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("name"), repo.Name))
+		}
+		seen[repo.Name] = true
+
+		allErrs = append(allErrs, validateArtifactURL(fldPath.Child("url"), repo.URL)...)
+		if strings.ContainsAny(repo.URL, " \n") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), repo.URL, "must not contain whitespace"))
+		}
+
+		switch repo.OSFamily {
+		case OSFamilyDebian:
+			if repo.Distribution == "" {
+				allErrs = append(allErrs, field.Required(fldPath.Child("distribution"), "distribution must be set for debian repositories"))
+			} else if strings.ContainsAny(repo.Distribution, " \n") {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("distribution"), repo.Distribution, "must not contain whitespace"))
+			}
+			for j, component := range repo.Components {
+				if component == "" || strings.ContainsAny(component, " \n") {
+					allErrs = append(allErrs, field.Invalid(fldPath.Child("components").Index(j), component, "must be a non empty word"))
+				}
+			}
+		case OSFamilyRHEL:
+			if repo.Distribution != "" {
+				allErrs = append(allErrs, field.Forbidden(fldPath.Child("distribution"), "distribution is only supported for debian repositories"))
+			}
+			if len(repo.Components) > 0 {
+				allErrs = append(allErrs, field.Forbidden(fldPath.Child("components"), "components are only supported for debian repositories"))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("osFamily"), repo.OSFamily,
+				[]string{string(OSFamilyDebian), string(OSFamilyRHEL)}))
+		}
+
+		switch {
+		case repo.GPGKey == "" && repo.GPGKeySecretName == "":
+			allErrs = append(allErrs, field.Required(fldPath.Child("gpgKey"), "gpgKey or gpgKeySecretName must be set"))
+		case repo.GPGKey != "" && repo.GPGKeySecretName != "":
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("gpgKeySecretName"), "only one of gpgKey or gpgKeySecretName may be set"))
+		case repo.GPGKey != "" && !strings.Contains(repo.GPGKey, "-----BEGIN PGP PUBLIC KEY BLOCK-----"):
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("gpgKey"), repo.GPGKey, "must be an ASCII armored PGP PUBLIC KEY BLOCK"))
+		}
+	}
+
+	for i, command := range s.PreKubeadmCommands {
+		allErrs = append(allErrs, validateAuthenticatedPackages(pathPrefix.Child("preKubeadmCommands").Index(i), command)...)
+	}
+	for i, conditional := range s.OSConditionals {
+		for j, command := range conditional.PreKubeadmCommands {
+			allErrs = append(allErrs, validateAuthenticatedPackages(pathPrefix.Child("osConditionals").Index(i).Child("preKubeadmCommands").Index(j), command)...)
+		}
+	}
+	return allErrs
+}
+
+// validateTrustedCABundles rejects trusted CA bundles that don't reference exactly one Secret or ConfigMap key.
+func (s *KubeadmConfigSpec) validateTrustedCABundles(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i := range s.TrustedCABundles {
+		allErrs = append(allErrs, validateFileSource(pathPrefix.Child("trustedCABundles").Index(i), &s.TrustedCABundles[i])...)
+	}
+	return allErrs
+}
+
+// validateAuthenticatedPackages rejects a command disabling the signature checks of the package repositories.
+func validateAuthenticatedPackages(fldPath *field.Path, command string) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, flag := range unauthenticatedPackageFlags {
+		if strings.Contains(command, flag) {
+			allErrs = append(allErrs, field.Forbidden(fldPath, fmt.Sprintf("%s disables the signature checks of packageRepositories", flag)))
+		}
+	}
+	return allErrs
+}
+
+// validateHardeningProfile rejects files conflicting with the ones written by the hardening profile.
+func (s *KubeadmConfigSpec) validateHardeningProfile(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.HardeningProfile != HardeningProfileCIS {
+		return allErrs
+	}
+
+	for i, file := range s.AdditionalUserDataFiles {
+		if cisFilePaths[path.Clean(file.Path)] {
+			allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("additionalUserDataFiles").Index(i).Child("path"),
+				"path is written by the cis hardeningProfile"))
+		}
+	}
+	return allErrs
+}
+
+// validateControlPlaneTaints rejects invalid control plane taints, and ones set along with the taints of a node
+// registration, which they would override.
+func (s *KubeadmConfigSpec) validateControlPlaneTaints(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.ControlPlaneTaints == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("controlPlaneTaints")
+	for i, taint := range *s.ControlPlaneTaints {
+		if msgs := validation.IsQualifiedName(taint.Key); len(msgs) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("key"), taint.Key, strings.Join(msgs, ", ")))
+		}
+		switch taint.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(i).Child("effect"), taint.Effect,
+				[]string{string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule), string(corev1.TaintEffectNoExecute)}))
+		}
+	}
+	if s.InitConfiguration != nil && s.InitConfiguration.NodeRegistration.Taints != nil {
+		allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("initConfiguration", "nodeRegistration", "taints"),
+			"must not be set along with controlPlaneTaints"))
+	}
+	if s.JoinConfiguration != nil && s.JoinConfiguration.ControlPlane != nil && s.JoinConfiguration.NodeRegistration.Taints != nil {
+		allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("joinConfiguration", "nodeRegistration", "taints"),
+			"must not be set along with controlPlaneTaints"))
+	}
+	return allErrs
+}
+
+// validateEtcdTuning rejects tuning etcd refuses or that can't be rendered as flags, and tuning conflicting with
+// the etcd configuration of the ClusterConfiguration.
+func (s *KubeadmConfigSpec) validateEtcdTuning(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	tuning := s.EtcdTuning
+	if tuning == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("etcdTuning")
+	if tuning.QuotaBackendBytes != nil && *tuning.QuotaBackendBytes <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("quotaBackendBytes"), *tuning.QuotaBackendBytes, "must be greater than zero"))
+	}
+	heartbeat, election := defaultEtcdHeartbeatInterval, defaultEtcdElectionTimeout
+	if tuning.HeartbeatInterval != nil {
+		heartbeat = tuning.HeartbeatInterval.Duration
+		allErrs = append(allErrs, validateMilliseconds(fldPath.Child("heartbeatInterval"), heartbeat)...)
+	}
+	if tuning.ElectionTimeout != nil {
+		election = tuning.ElectionTimeout.Duration
+		allErrs = append(allErrs, validateMilliseconds(fldPath.Child("electionTimeout"), election)...)
+	}
+	if election < 5*heartbeat {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("electionTimeout"), election.String(),
+			fmt.Sprintf("must be at least five times the heartbeat interval of %s", heartbeat)))
+	}
+	for i, rawURL := range tuning.ListenMetricsURLs {
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Contains(rawURL, ",") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("listenMetricsURLs").Index(i), rawURL, "must be an http or https URL, e.g. http://127.0.0.1:2381"))
+		}
+	}
+	if tuning.DataDir != "" && !path.IsAbs(tuning.DataDir) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("dataDir"), tuning.DataDir, "must be an absolute path"))
+	}
+
+	if s.ClusterConfiguration == nil {
+		return allErrs
+	}
+	etcdPath := pathPrefix.Child("clusterConfiguration", "etcd")
+	if s.ClusterConfiguration.Etcd.External != nil {
+		allErrs = append(allErrs, field.Forbidden(etcdPath.Child("external"), "must not be set along with etcdTuning, which tunes local etcd"))
+	}
+	if local := s.ClusterConfiguration.Etcd.Local; local != nil {
+		for _, flag := range etcdTuningFlags {
+			if _, ok := local.ExtraArgs[flag]; ok {
+				allErrs = append(allErrs, field.Forbidden(etcdPath.Child("local", "extraArgs").Key(flag), "the flag is generated from etcdTuning"))
+			}
+		}
+		if tuning.DataDir != "" && local.DataDir != "" && local.DataDir != tuning.DataDir {
+			allErrs = append(allErrs, field.Forbidden(etcdPath.Child("local", "dataDir"), "must not differ from etcdTuning.dataDir"))
+		}
+	}
+	return allErrs
+}
+
+// validateEtcdDisk rejects devices and mount paths that can't be safely rendered, and etcd data directories set
+// elsewhere, which the EtcdDisk sets.
+func (s *KubeadmConfigSpec) validateEtcdDisk(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	disk := s.EtcdDisk
+	if disk == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("etcdDisk")
+	allErrs = append(allErrs, validateDevice(fldPath.Child("device"), disk.Device)...)
+	if disk.MountPath != "" {
+		if !path.IsAbs(disk.MountPath) || path.Clean(disk.MountPath) == "/" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("mountPath"), disk.MountPath, "must be an absolute path other than /"))
+		} else if strings.ContainsAny(disk.MountPath, unsafeShellChars+" :") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("mountPath"), disk.MountPath, "must not contain spaces, colons or any of "+unsafeShellChars))
+		}
+	}
+	if s.EtcdTuning != nil && s.EtcdTuning.DataDir != "" {
+		allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("etcdTuning", "dataDir"), "must not be set along with etcdDisk"))
+	}
+	if s.ClusterConfiguration == nil {
+		return allErrs
+	}
+	etcdPath := pathPrefix.Child("clusterConfiguration", "etcd")
+	if s.ClusterConfiguration.Etcd.External != nil {
+		allErrs = append(allErrs, field.Forbidden(etcdPath.Child("external"), "must not be set along with etcdDisk, which holds local etcd data"))
+	}
+	if local := s.ClusterConfiguration.Etcd.Local; local != nil && local.DataDir != "" {
+		allErrs = append(allErrs, field.Forbidden(etcdPath.Child("local", "dataDir"), "must not be set along with etcdDisk"))
+	}
+	return allErrs
+}
+
+// validateDiskSetup rejects partitions, filesystems and mounts that can't be safely rendered, or that conflict
+// with each other or with the EtcdDisk.
+func (s *KubeadmConfigSpec) validateDiskSetup(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.Format == FormatIgnition {
+		if s.DiskSetup != nil {
+			allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("diskSetup"), "is not supported by the ignition format"))
+		}
+		if len(s.Mounts) > 0 {
+			allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("mounts"), "is not supported by the ignition format"))
+		}
+	}
+
+	var etcdDevice string
+	if s.EtcdDisk != nil {
+		etcdDevice = s.EtcdDisk.Device
+	}
+	if disk := s.DiskSetup; disk != nil {
+		devices := map[string]bool{}
+		for i, p := range disk.Partitions {
+			fldPath := pathPrefix.Child("diskSetup", "partitions").Index(i).Child("device")
+			allErrs = append(allErrs, validateDevice(fldPath, p.Device)...)
+			switch {
+			case etcdDevice != "" && p.Device == etcdDevice:
+				allErrs = append(allErrs, field.Forbidden(fldPath, "must not be the device of etcdDisk"))
+			case devices[p.Device]:
+				allErrs = append(allErrs, field.Duplicate(fldPath, p.Device))
+			}
+			devices[p.Device] = true
+		}
+
+		labels := map[string]bool{}
+		for i, f := range disk.Filesystems {
+			fldPath := pathPrefix.Child("diskSetup", "filesystems").Index(i)
+			allErrs = append(allErrs, validateDevice(fldPath.Child("device"), f.Device)...)
+			if etcdDevice != "" && f.Device == etcdDevice {
+				allErrs = append(allErrs, field.Forbidden(fldPath.Child("device"), "must not be the device of etcdDisk"))
+			}
+			if !filesystemTypeRegexp.MatchString(f.Filesystem) {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("filesystem"), f.Filesystem, "must be a filesystem type, e.g. ext4"))
+			}
+			switch {
+			case !filesystemLabelRegexp.MatchString(f.Label):
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("label"), f.Label, "must consist of alphanumeric characters, '_', '.' or '-'"))
+			case labels[f.Label] || (etcdDevice != "" && f.Label == "etcd_disk"):
+				allErrs = append(allErrs, field.Duplicate(fldPath.Child("label"), f.Label))
+			}
+			labels[f.Label] = true
+			switch f.Partition {
+			case "", "auto", "any", "none":
+			default:
+				if n, err := strconv.Atoi(f.Partition); err != nil || n < 1 || n > 128 {
+					allErrs = append(allErrs, field.Invalid(fldPath.Child("partition"), f.Partition, "must be a partition number, auto, any or none"))
+				}
+			}
+			for j, opt := range f.ExtraOpts {
+				if !diskSetupFieldRegexp.MatchString(opt) {
+					allErrs = append(allErrs, field.Invalid(fldPath.Child("extraOpts").Index(j), opt, "must not contain spaces or quotes"))
+				}
+			}
+		}
+	}
+
+	for i, m := range s.Mounts {
+		fldPath := pathPrefix.Child("mounts").Index(i)
+		if len(m) < 2 || len(m) > 6 {
+			allErrs = append(allErrs, field.Invalid(fldPath, m, "must have between 2 and 6 fstab fields"))
+			continue
+		}
+		for j, f := range m {
+			if !diskSetupFieldRegexp.MatchString(f) {
+				allErrs = append(allErrs, field.Invalid(fldPath.Index(j), f, "must not be empty or contain spaces or quotes"))
+			}
+		}
+		if mountPoint := m[1]; mountPoint != "none" && mountPoint != "swap" && (!path.IsAbs(mountPoint) || path.Clean(mountPoint) == "/") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(1), mountPoint, "must be an absolute path other than /, none or swap"))
+		}
+	}
+	return allErrs
+}
+
+// validateDevice rejects device paths that can't be safely rendered.
+func validateDevice(fldPath *field.Path, device string) field.ErrorList {
+	var allErrs field.ErrorList
+	if !strings.HasPrefix(device, "/dev/") || strings.ContainsAny(device, unsafeShellChars+" :") {
+		allErrs = append(allErrs, field.Invalid(fldPath, device, "must be a device path under /dev, e.g. /dev/nvme1n1"))
+	}
+	return allErrs
+}
+
+func validateMilliseconds(fldPath *field.Path, d time.Duration) field.ErrorList {
+	var allErrs field.ErrorList
+	if d <= 0 || d%time.Millisecond != 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, d.String(), "must be a positive number of whole milliseconds"))
+	}
+	return allErrs
+}
+
+// validateKubeletTuning rejects grace periods the kubelet would reject, and eviction thresholds of unknown signals
+// or that are neither quantities nor percentages.
+func (s *KubeadmConfigSpec) validateKubeletTuning(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.KubeletTuning == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("kubeletTuning")
+	if shutdown := s.KubeletTuning.GracefulShutdown; shutdown != nil {
+		shutdownPath := fldPath.Child("gracefulShutdown")
+		if shutdown.GracePeriod.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(shutdownPath.Child("gracePeriod"), shutdown.GracePeriod.String(), "must be positive"))
+		}
+		if critical := shutdown.CriticalPodsGracePeriod; critical != nil && (critical.Duration < 0 || critical.Duration > shutdown.GracePeriod.Duration) {
+			allErrs = append(allErrs, field.Invalid(shutdownPath.Child("criticalPodsGracePeriod"), critical.String(),
+				"must be positive and must not exceed the gracePeriod"))
+		}
+	}
+
+	eviction := s.KubeletTuning.Eviction
+	if eviction == nil {
+		return allErrs
+	}
+	evictionPath := fldPath.Child("eviction")
+	allErrs = append(allErrs, validateEvictionThresholds(evictionPath.Child("hard"), eviction.Hard)...)
+	allErrs = append(allErrs, validateEvictionThresholds(evictionPath.Child("soft"), eviction.Soft)...)
+	allErrs = append(allErrs, validateEvictionThresholds(evictionPath.Child("minimumReclaim"), eviction.MinimumReclaim)...)
+	for _, signal := range sortedKeys(eviction.Soft) {
+		if _, ok := eviction.SoftGracePeriod[signal]; !ok {
+			allErrs = append(allErrs, field.Required(evictionPath.Child("softGracePeriod").Key(signal), "soft thresholds require a grace period"))
+		}
+	}
+	for _, signal := range sortedKeys(eviction.SoftGracePeriod) {
+		period := eviction.SoftGracePeriod[signal]
+		if _, ok := eviction.Soft[signal]; !ok {
+			allErrs = append(allErrs, field.Forbidden(evictionPath.Child("softGracePeriod").Key(signal), "must be set along with a soft threshold"))
+		} else if d, err := time.ParseDuration(period); err != nil || d <= 0 {
+			allErrs = append(allErrs, field.Invalid(evictionPath.Child("softGracePeriod").Key(signal), period, "must be a positive duration"))
+		}
+	}
+	if period := eviction.PressureTransitionPeriod; period != nil && period.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(evictionPath.Child("pressureTransitionPeriod"), period.String(), "must be positive"))
+	}
+	if eviction.MaxPodGracePeriod < 0 {
+		allErrs = append(allErrs, field.Invalid(evictionPath.Child("maxPodGracePeriod"), eviction.MaxPodGracePeriod, "must not be negative"))
+	}
+	return allErrs
+}
+
+// validateClusterDNS rejects a cluster domain that is not a DNS subdomain and servers that are not IPs.
+func (s *KubeadmConfigSpec) validateClusterDNS(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	dns := s.ClusterDNS
+	if dns == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("clusterDNS")
+	if dns.Domain != "" {
+		if msgs := validation.IsDNS1123Subdomain(dns.Domain); len(msgs) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("domain"), dns.Domain, strings.Join(msgs, ", ")))
+		}
+	}
+	for i, server := range dns.Servers {
+		if net.ParseIP(server) == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("servers").Index(i), server, "must be an IP address"))
+		}
+	}
+	return allErrs
+}
+
+// validateEvictionThresholds rejects thresholds of unknown signals, and thresholds that are neither quantities nor
+// percentages.
+func validateEvictionThresholds(fldPath *field.Path, thresholds map[string]string) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, signal := range sortedKeys(thresholds) {
+		threshold := thresholds[signal]
+		if !isEvictionSignal(signal) {
+			allErrs = append(allErrs, field.NotSupported(fldPath, signal, evictionSignals))
+			continue
+		}
+		if strings.HasSuffix(threshold, "%") {
+			if p, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64); err != nil || p <= 0 || p > 100 {
+				allErrs = append(allErrs, field.Invalid(fldPath.Key(signal), threshold, "must be a percentage between 0 and 100"))
+			}
+		} else if q, err := resource.ParseQuantity(threshold); err != nil || q.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(signal), threshold, "must be a positive quantity or a percentage"))
+		}
+	}
+	return allErrs
+}
+
+// isEvictionSignal returns whether signal is one of the evictionSignals.
+func isEvictionSignal(signal string) bool {
+	for _, s := range evictionSignals {
+		if s == signal {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in order, so that errors are reported in a stable order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateNTP rejects NTP servers that are neither a host name nor an IP address.
+func (s *KubeadmConfigSpec) validateNTP(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.NTP == nil {
+		return allErrs
+	}
+
+	for i, server := range s.NTP.Servers {
+		if net.ParseIP(server) != nil {
+			continue
+		}
+		if msgs := validation.IsDNS1123Subdomain(server); len(msgs) > 0 {
+			allErrs = append(allErrs, field.Invalid(pathPrefix.Child("ntp", "servers").Index(i), server, "must be a host name or an IP address"))
+		}
+	}
+	return allErrs
+}
+
+// validateProxy rejects proxy URLs that aren't http or https URLs or can't be safely rendered into the bootstrap
+// commands, and destinations reached without the proxy that are neither a host name, a domain, an IP address nor
+// a CIDR.
+func (s *KubeadmConfigSpec) validateProxy(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.Proxy == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("proxy")
+	for _, u := range []struct {
+		name, value string
+	}{
+		{"httpProxy", s.Proxy.HTTPProxy},
+		{"httpsProxy", s.Proxy.HTTPSProxy},
+	} {
+		if u.value != "" {
+			allErrs = append(allErrs, validateArtifactURL(fldPath.Child(u.name), u.value)...)
+		}
+	}
+	for i, host := range s.Proxy.NoProxy {
+		if host == "*" || net.ParseIP(host) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(host); err == nil {
+			continue
+		}
+		// a leading dot matches the subdomains only
+		if msgs := validation.IsDNS1123Subdomain(strings.ToLower(strings.TrimPrefix(host, "."))); len(msgs) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("noProxy").Index(i), host, "must be a host name, a domain, an IP address or a CIDR"))
+		}
+	}
+	return allErrs
+}
+
+// validateRegistries rejects registries that aren't a host with an optional port, mirrored or authenticated to
+// more than once, mirrors that aren't http or https URLs, and invalid credentials Secret names.
+func (s *KubeadmConfigSpec) validateRegistries(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	mirrored := map[string]bool{}
+	for i, mirror := range s.RegistryMirrors {
+		fldPath := pathPrefix.Child("registryMirrors").Index(i)
+		allErrs = append(allErrs, validateRegistryHost(fldPath.Child("registry"), mirror.Registry)...)
+		if mirrored[mirror.Registry] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("registry"), mirror.Registry))
+		}
+		mirrored[mirror.Registry] = true
+		for j, endpoint := range mirror.Endpoints {
+			allErrs = append(allErrs, validateArtifactURL(fldPath.Child("endpoints").Index(j), endpoint)...)
+		}
+	}
+
+	authenticated := map[string]bool{}
+	for i, credentials := range s.RegistryCredentials {
+		fldPath := pathPrefix.Child("registryCredentials").Index(i)
+		allErrs = append(allErrs, validateRegistryHost(fldPath.Child("host"), credentials.Host)...)
+		if authenticated[credentials.Host] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("host"), credentials.Host))
+		}
+		authenticated[credentials.Host] = true
+		if msgs := validation.IsDNS1123Subdomain(credentials.SecretName); len(msgs) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("secretName"), credentials.SecretName, strings.Join(msgs, ", ")))
+		}
+	}
+	return allErrs
+}
+
+// validateRegistryHost rejects registry hosts that aren't a host name or IP address with an optional port.
+func validateRegistryHost(fldPath *field.Path, registry string) field.ErrorList {
+	host := registry
+	if h, port, err := net.SplitHostPort(registry); err == nil {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return field.ErrorList{field.Invalid(fldPath, registry, "must have a valid port")}
+		}
+		host = h
+	}
+	if net.ParseIP(host) == nil && len(validation.IsDNS1123Subdomain(host)) > 0 {
+		return field.ErrorList{field.Invalid(fldPath, registry, "must be a host name or an IP address, with an optional port")}
+	}
+	return nil
+}
+
+// validateStartupTaint rejects startup taint keys that are not qualified names.
+func (s *KubeadmConfigSpec) validateStartupTaint(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.StartupTaint == nil || s.StartupTaint.Key == "" {
+		return allErrs
+	}
+
+	if msgs := validation.IsQualifiedName(s.StartupTaint.Key); len(msgs) > 0 {
+		allErrs = append(allErrs, field.Invalid(pathPrefix.Child("startupTaint", "key"), s.StartupTaint.Key, strings.Join(msgs, ", ")))
+	}
+	return allErrs
+}
+
+// validateUsers rejects users that can't be created, and values that can't be rendered within double quotes.
+func (s *KubeadmConfigSpec) validateUsers(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	names := map[string]bool{}
+	for i, u := range s.Users {
+		fldPath := pathPrefix.Child("users").Index(i)
+		switch {
+		case !userNameRegexp.MatchString(u.Name):
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), u.Name, "must be a user name, e.g. ops"))
+		case names[u.Name]:
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("name"), u.Name))
+		}
+		names[u.Name] = true
+
+		for j, key := range u.SSHAuthorizedKeys {
+			if len(strings.Fields(key)) < 2 || strings.ContainsAny(key, "\"\\\n") {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("sshAuthorizedKeys").Index(j), key, "must be a single public key in the authorized_keys format"))
+			}
+		}
+		if strings.ContainsAny(u.Sudo, "\"\\\n") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("sudo"), u.Sudo, "must be a single sudoers rule without quotes or backslashes"))
+		}
+		if u.Shell != "" && (!path.IsAbs(u.Shell) || strings.ContainsAny(u.Shell, unsafeShellChars+" :#")) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("shell"), u.Shell, "must be the absolute path of a shell"))
+		}
+		if u.Passwd != "" && (!strings.HasPrefix(u.Passwd, "$") || strings.ContainsAny(u.Passwd, "\"\\ \n")) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("passwd"), "<hidden>", "must be a password hash in the crypt format, not a plain text password"))
+		}
+	}
+	return allErrs
+}
+
+// validateRetainedIdentity rejects node names that can't be registered, and join configurations that discover
+// the cluster otherwise or register another node.
+func (s *KubeadmConfigSpec) validateRetainedIdentity(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	identity := s.RetainedIdentity
+	if identity == nil {
+		return allErrs
+	}
+
+	if msgs := validation.IsDNS1123Subdomain(identity.NodeName); len(msgs) > 0 {
+		allErrs = append(allErrs, field.Invalid(pathPrefix.Child("retainedIdentity", "nodeName"), identity.NodeName, strings.Join(msgs, ", ")))
+	}
+	join := s.JoinConfiguration
+	if join == nil {
+		return allErrs
+	}
+	joinPath := pathPrefix.Child("joinConfiguration")
+	if join.ControlPlane != nil {
+		allErrs = append(allErrs, field.Forbidden(joinPath.Child("controlPlane"), "must not be set along with retainedIdentity, which only joins workers"))
+	}
+	if join.Discovery.BootstrapToken != nil || join.Discovery.File != nil || join.Discovery.TLSBootstrapToken != "" {
+		allErrs = append(allErrs, field.Forbidden(joinPath.Child("discovery"), "must not be set along with retainedIdentity, which sets it"))
+	}
+	if name := join.NodeRegistration.Name; name != "" && name != identity.NodeName {
+		allErrs = append(allErrs, field.Invalid(joinPath.Child("nodeRegistration", "name"), name, "must be the node name of retainedIdentity"))
+	}
+	return allErrs
+}
+
+// validatePodSecurity rejects namespaces that can't be exempted.
+func (s *KubeadmConfigSpec) validatePodSecurity(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.PodSecurity == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("podSecurity")
+	for i, namespace := range s.PodSecurity.ExemptNamespaces {
+		if msgs := validation.IsDNS1123Label(namespace); len(msgs) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("exemptNamespaces").Index(i), namespace, strings.Join(msgs, ", ")))
+		}
+	}
+	return allErrs
+}
+
+// validateExternalCAs rejects invalid secret names, and a single secret referenced for both CAs, which must differ.
+func (s *KubeadmConfigSpec) validateExternalCAs(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.ExternalCAs == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("externalCAs")
+	cas := s.ExternalCAs
+	if cas.EtcdCASecretName == "" && cas.FrontProxyCASecretName == "" {
+		allErrs = append(allErrs, field.Required(fldPath, "etcdCASecretName or frontProxyCASecretName must be set"))
+	}
+	for _, ref := range []struct{ field, name string }{
+		{"etcdCASecretName", cas.EtcdCASecretName},
+		{"frontProxyCASecretName", cas.FrontProxyCASecretName},
+	} {
+		if ref.name == "" {
+			continue
+		}
+		if msgs := validation.IsDNS1123Subdomain(ref.name); len(msgs) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child(ref.field), ref.name, strings.Join(msgs, ", ")))
+		}
+	}
+	if cas.EtcdCASecretName != "" && cas.EtcdCASecretName == cas.FrontProxyCASecretName {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("frontProxyCASecretName"), cas.FrontProxyCASecretName,
+			"the etcd and front proxy CAs must differ"))
+	}
+	return allErrs
+}
+
+// validateAdmissionPlugins rejects admission plugins that can't be told apart or whose configuration can't be
+// decoded, and API server flags and files conflicting with the ones generated for the admission plugins and
+// PodSecurity.
+func (s *KubeadmConfigSpec) validateAdmissionPlugins(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	fldPath := pathPrefix.Child("admissionPlugins")
+	generatedArgs := map[string]bool{}
+	if s.PodSecurity != nil {
+		generatedArgs["admission-control-config-file"] = true
+	}
+	if plugins := s.AdmissionPlugins; plugins != nil {
+		enabled := map[string]bool{}
+		for i, name := range plugins.Enable {
+			if !admissionPluginNameRegexp.MatchString(name) {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("enable").Index(i), name, "must be an admission plugin name, e.g. EventRateLimit"))
+			}
+			enabled[name] = true
+		}
+		for i, name := range plugins.Disable {
+			if !admissionPluginNameRegexp.MatchString(name) {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("disable").Index(i), name, "must be an admission plugin name, e.g. EventRateLimit"))
+			} else if enabled[name] {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("disable").Index(i), name, "admission plugin is also enabled"))
+			}
+		}
+
+		// configurations are written to files named after the lower cased plugin names
+		seen := map[string]bool{}
+		if s.PodSecurity != nil {
+			seen["podsecurity"] = true
+		}
+		for i, plugin := range plugins.Configurations {
+			pluginPath := fldPath.Child("configurations").Index(i)
+			if !admissionPluginNameRegexp.MatchString(plugin.Name) {
+				allErrs = append(allErrs, field.Invalid(pluginPath.Child("name"), plugin.Name, "must be an admission plugin name, e.g. EventRateLimit"))
+			} else if seen[strings.ToLower(plugin.Name)] {
+				allErrs = append(allErrs, field.Duplicate(pluginPath.Child("name"), plugin.Name))
+			}
+			seen[strings.ToLower(plugin.Name)] = true
+
+			configJSON, err := yaml.YAMLToJSON([]byte(plugin.Configuration))
+			if err != nil {
+				allErrs = append(allErrs, field.Invalid(pluginPath.Child("configuration"), plugin.Configuration, err.Error()))
+			} else if err := json.Unmarshal(configJSON, &map[string]interface{}{}); err != nil {
+				allErrs = append(allErrs, field.Invalid(pluginPath.Child("configuration"), plugin.Configuration, "configuration must be an object"))
+			}
+		}
+
+		generatedArgs["enable-admission-plugins"] = len(plugins.Enable) > 0
+		generatedArgs["disable-admission-plugins"] = len(plugins.Disable) > 0
+		generatedArgs["admission-control-config-file"] = generatedArgs["admission-control-config-file"] || len(plugins.Configurations) > 0
+	}
+
+	if s.ClusterConfiguration != nil {
+		for arg, generated := range generatedArgs {
+			if _, ok := s.ClusterConfiguration.APIServer.ExtraArgs[arg]; ok && generated {
+				allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("clusterConfiguration", "apiServer", "extraArgs").Key(arg),
+					"the flag is generated from admissionPlugins and podSecurity"))
+			}
+		}
+	}
+	if generatedArgs["admission-control-config-file"] {
+		for i, file := range s.AdditionalUserDataFiles {
+			if strings.HasPrefix(path.Clean(file.Path), admissionDir+"/") {
+				allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("additionalUserDataFiles").Index(i).Child("path"),
+					"directory is written by admissionPlugins and podSecurity"))
+			}
+		}
+	}
+	return allErrs
+}
+
+// validateComponentConfigurations rejects configuration files of the scheduler and controller manager that can't
+// be told apart, and flags and files conflicting with the ones generated for them.
+func (s *KubeadmConfigSpec) validateComponentConfigurations(pathPrefix *field.Path) field.ErrorList {
+	var schedulerArgs, controllerManagerArgs map[string]string
+	if s.ClusterConfiguration != nil {
+		schedulerArgs = s.ClusterConfiguration.Scheduler.ExtraArgs
+		controllerManagerArgs = s.ClusterConfiguration.ControllerManager.ExtraArgs
+	}
+
+	allErrs := s.validateComponentConfiguration(pathPrefix, "schedulerConfiguration", s.SchedulerConfiguration,
+		schedulerConfigurationDir, "scheduler", schedulerArgs)
+	return append(allErrs, s.validateComponentConfiguration(pathPrefix, "controllerManagerConfiguration", s.ControllerManagerConfiguration,
+		controllerManagerConfigurationDir, "controllerManager", controllerManagerArgs)...)
+}
+
+func (s *KubeadmConfigSpec) validateComponentConfiguration(pathPrefix *field.Path, name string, component *ControlPlaneComponentConfiguration,
+	dir, clusterConfigurationName string, extraArgs map[string]string) field.ErrorList {
+	var allErrs field.ErrorList
+	if component == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child(name)
+	names := map[string]bool{}
+	flags := map[string]bool{}
+	for i, file := range component.Files {
+		filePath := fldPath.Child("files").Index(i)
+		if file.Name == "" || file.Name == "." || file.Name == ".." || strings.Contains(file.Name, "/") {
+			allErrs = append(allErrs, field.Invalid(filePath.Child("name"), file.Name, "must be a file name"))
+		} else if names[file.Name] {
+			allErrs = append(allErrs, field.Duplicate(filePath.Child("name"), file.Name))
+		}
+		names[file.Name] = true
+
+		if file.Flag == "" {
+			continue
+		}
+		if !flagNameRegexp.MatchString(file.Flag) {
+			allErrs = append(allErrs, field.Invalid(filePath.Child("flag"), file.Flag, "must be a flag name without dashes, e.g. config"))
+		} else if flags[file.Flag] {
+			allErrs = append(allErrs, field.Duplicate(filePath.Child("flag"), file.Flag))
+		} else if _, ok := extraArgs[file.Flag]; ok {
+			allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("clusterConfiguration", clusterConfigurationName, "extraArgs").Key(file.Flag),
+				fmt.Sprintf("the flag is generated from %s", name)))
+		}
+		flags[file.Flag] = true
+	}
+
+	for i, file := range s.AdditionalUserDataFiles {
+		if strings.HasPrefix(path.Clean(file.Path), dir+"/") {
+			allErrs = append(allErrs, field.Forbidden(pathPrefix.Child("additionalUserDataFiles").Index(i).Child("path"),
+				fmt.Sprintf("directory is written by %s", name)))
+		}
+	}
+	return allErrs
+}
+
+// validateVariants rejects variants that can't be told apart or whose patch can't be decoded.
+func (s *KubeadmConfigSpec) validateVariants(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	seen := map[string]bool{}
+	for i, v := range s.Variants {
+		fldPath := pathPrefix.Child("variants").Index(i)
+		if v.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("name"), "variants must be named"))
+		} else if seen[v.Name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("name"), v.Name))
+		}
+		seen[v.Name] = true
+
+		patchJSON, err := yaml.YAMLToJSON([]byte(v.Patch))
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("patch"), v.Patch, err.Error()))
+			continue
+		}
+		if err := json.Unmarshal(patchJSON, &map[string]interface{}{}); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("patch"), v.Patch, "merge patch must be an object"))
+		}
+	}
+	return allErrs
+}
+
+// validateImagePreflight rejects values that can't be safely rendered into the image preflight script.
+func (s *KubeadmConfigSpec) validateImagePreflight(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.ImagePreflight == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("imagePreflight")
+	if v := s.ImagePreflight.ContainerdVersion; v != "" && !versionRegexp.MatchString(v) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("containerdVersion"), v, "must be a version, e.g. v1.2.6"))
+	}
+	for i, p := range s.ImagePreflight.RequiredPaths {
+		if !path.IsAbs(p) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("requiredPaths").Index(i), p, "must be an absolute path"))
+			continue
+		}
+		if strings.ContainsAny(p, unsafeShellChars) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("requiredPaths").Index(i), p, "must not contain any of "+unsafeShellChars))
+		}
+	}
+	return allErrs
+}
+
+// validateKubeadmContainer rejects kubeadm container images that aren't image references, or that ctr can't pull.
+func (s *KubeadmConfigSpec) validateKubeadmContainer(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.KubeadmContainer == nil {
+		return allErrs
+	}
+
+	fldPath := pathPrefix.Child("kubeadmContainer", "image")
+	image := s.KubeadmContainer.Image
+	match := imageRegexp.FindStringSubmatch(image)
+	if match == nil {
+		return append(allErrs, field.Invalid(fldPath, image, "must be an image reference, e.g. k8s.gcr.io/kubeadm:v1.15.3"))
+	}
+	// ctr neither defaults the registry nor the tag of the images it pulls
+	registry, tag, digest := match[1], match[2], match[3]
+	qualified := strings.ContainsAny(registry, ".:") || registry == "localhost"
+	if s.KubeadmContainer.Runtime != KubeadmContainerRuntimePodman && (!qualified || tag == "" && digest == "") {
+		allErrs = append(allErrs, field.Invalid(fldPath, image, "must be a fully qualified image reference with a registry and a tag or digest when run with ctr, e.g. docker.io/library/kubeadm:v1.15.3"))
+	}
+	return allErrs
+}
+
+// validateKubeadmAPIVersions rejects embedded kubeadm configurations with an apiVersion they can't be rendered with.
+func (s *KubeadmConfigSpec) validateKubeadmAPIVersions(pathPrefix *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	supported := []string{kubeadmv1beta1.GroupVersion.String(), kubeadmv1beta2.GroupVersion.String()}
+	validate := func(fldPath *field.Path, apiVersion string) {
+		if apiVersion == "" {
+			return
+		}
+		for _, v := range supported {
+			if apiVersion == v {
+				return
+			}
+		}
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("apiVersion"), apiVersion, supported))
+	}
+
+	if s.ClusterConfiguration != nil {
+		validate(pathPrefix.Child("clusterConfiguration"), s.ClusterConfiguration.APIVersion)
+	}
+	if s.InitConfiguration != nil {
+		validate(pathPrefix.Child("initConfiguration"), s.InitConfiguration.APIVersion)
+	}
+	if s.JoinConfiguration != nil {
+		validate(pathPrefix.Child("joinConfiguration"), s.JoinConfiguration.APIVersion)
+	}
+	return allErrs
+}
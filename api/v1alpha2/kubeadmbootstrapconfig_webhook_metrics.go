@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// admissionDurationSeconds tracks how long validating a KubeadmConfig takes, to spot validation slowing down
+	// applies.
+	admissionDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cabpk_webhook_admission_duration_seconds",
+			Help:    "Time in seconds the KubeadmConfig validating webhook took to decide on admission requests.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8), // 100µs to 1.6s
+		},
+		[]string{"operation"},
+	)
+
+	// admissionRequestsTotal counts the admission requests of the validating webhook by decision.
+	admissionRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cabpk_webhook_admission_requests_total",
+			Help: "Number of admission requests decided by the KubeadmConfig validating webhook.",
+		},
+		[]string{"operation", "allowed"},
+	)
+
+	// admissionRejectionsTotal counts the field errors of rejected admission requests, so that the offending fields
+	// of a failing apply can be told apart.
+	admissionRejectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cabpk_webhook_admission_rejections_total",
+			Help: "Number of field errors the KubeadmConfig validating webhook rejected admission requests for.",
+		},
+		[]string{"operation", "field", "reason"},
+	)
+
+	// fieldSubscriptRegexp matches the list indices and map keys of field paths, which are dropped from the
+	// field label to bound its cardinality.
+	fieldSubscriptRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+)
+
+func init() {
+	metrics.Registry.MustRegister(admissionDurationSeconds, admissionRequestsTotal, admissionRejectionsTotal)
+}
+
+// observeAdmission runs validate for an admission request of the given operation, recording its latency and
+// decision.
+func observeAdmission(operation string, validate func() error) error {
+	start := time.Now()
+	err := validate()
+	admissionDurationSeconds.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	admissionRequestsTotal.WithLabelValues(operation, strconv.FormatBool(err == nil)).Inc()
+
+	if status, ok := err.(apierrors.APIStatus); ok && status.Status().Details != nil {
+		for _, cause := range status.Status().Details.Causes {
+			admissionRejectionsTotal.WithLabelValues(operation, fieldSubscriptRegexp.ReplaceAllString(cause.Field, ""), string(cause.Type)).Inc()
+		}
+	}
+	return err
+}
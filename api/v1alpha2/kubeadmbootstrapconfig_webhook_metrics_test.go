@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func counterValue(t *testing.T, counter *prometheus.CounterVec, labels ...string) float64 {
+	metric := &dto.Metric{}
+	if err := counter.WithLabelValues(labels...).Write(metric); err != nil {
+		t.Fatalf("Failed to read metric:\n %+v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestValidateObservesAdmission(t *testing.T) {
+	allowed := counterValue(t, admissionRequestsTotal, "create", "true")
+	rejected := counterValue(t, admissionRequestsTotal, "update", "false")
+	duplicates := counterValue(t, admissionRejectionsTotal, "update", "spec.additionalUserDataFiles.path", "FieldValueDuplicate")
+
+	if err := (&KubeadmConfig{}).ValidateCreate(); err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	config := &KubeadmConfig{
+		Spec: KubeadmConfigSpec{
+			AdditionalUserDataFiles: []Files{{Path: "/etc/foo"}, {Path: "/etc/foo"}},
+		},
+	}
+	if err := config.ValidateUpdate(&KubeadmConfig{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if out := counterValue(t, admissionRequestsTotal, "create", "true"); out != allowed+1 {
+		t.Errorf("expected %v allowed create requests, got %v", allowed+1, out)
+	}
+	if out := counterValue(t, admissionRequestsTotal, "update", "false"); out != rejected+1 {
+		t.Errorf("expected %v rejected update requests, got %v", rejected+1, out)
+	}
+	if out := counterValue(t, admissionRejectionsTotal, "update", "spec.additionalUserDataFiles.path", "FieldValueDuplicate"); out != duplicates+1 {
+		t.Errorf("expected %v duplicate path rejections, got %v", duplicates+1, out)
+	}
+
+	metric := &dto.Metric{}
+	if err := admissionDurationSeconds.WithLabelValues("create").(prometheus.Metric).Write(metric); err != nil {
+		t.Fatalf("Failed to read metric:\n %+v", err)
+	}
+	if metric.GetHistogram().GetSampleCount() == 0 {
+		t.Fatal("expected the create latency to be observed")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	utilconversion "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/conversion"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// The v1alpha3 types start as identical to the v1alpha2 ones, so their specs and statuses convert through their
+// common json representation. Fields deprecated or added later need explicit conversions. The v1alpha3 spec is
+// stashed in the annotations of the stored v1alpha2 objects with the helpers of the conversion package, so that
+// the fields v1alpha2 lacks are restored from it when converting back. Statuses are not stashed: they are written
+// by the controller, which uses v1alpha2.
+
+var (
+	_ conversion.Convertible = &KubeadmConfig{}
+	_ conversion.Convertible = &KubeadmConfigTemplate{}
+)
+
+// ConvertTo converts this KubeadmConfig to the hub version.
+func (src *KubeadmConfig) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha2.KubeadmConfig)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	if err := convertJSON(&src.Spec, &dst.Spec); err != nil {
+		return errors.Wrap(err, "failed to convert the spec")
+	}
+	if err := convertJSON(&src.Status, &dst.Status); err != nil {
+		return errors.Wrap(err, "failed to convert the status")
+	}
+	return utilconversion.MarshalData(&KubeadmConfig{Spec: src.Spec}, dst)
+}
+
+// ConvertFrom converts the hub version to this KubeadmConfig.
+func (dst *KubeadmConfig) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha2.KubeadmConfig)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	if err := convertJSON(&src.Spec, &dst.Spec); err != nil {
+		return errors.Wrap(err, "failed to convert the spec")
+	}
+	if err := convertJSON(&src.Status, &dst.Status); err != nil {
+		return errors.Wrap(err, "failed to convert the status")
+	}
+
+	// every v1alpha3 field exists in v1alpha2 for now: fields added to v1alpha3 only are to be restored from the
+	// stashed spec
+	_, err := utilconversion.UnmarshalData(dst, &KubeadmConfig{})
+	return err
+}
+
+// ConvertTo converts this KubeadmConfigTemplate to the hub version.
+func (src *KubeadmConfigTemplate) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha2.KubeadmConfigTemplate)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	if err := convertJSON(&src.Spec, &dst.Spec); err != nil {
+		return errors.Wrap(err, "failed to convert the spec")
+	}
+	return utilconversion.MarshalData(&KubeadmConfigTemplate{Spec: src.Spec}, dst)
+}
+
+// ConvertFrom converts the hub version to this KubeadmConfigTemplate.
+func (dst *KubeadmConfigTemplate) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha2.KubeadmConfigTemplate)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	if err := convertJSON(&src.Spec, &dst.Spec); err != nil {
+		return errors.Wrap(err, "failed to convert the spec")
+	}
+
+	// every v1alpha3 field exists in v1alpha2 for now: fields added to v1alpha3 only are to be restored from the
+	// stashed spec
+	_, err := utilconversion.UnmarshalData(dst, &KubeadmConfigTemplate{})
+	return err
+}
+
+// convertJSON converts src into dst, of the same json representation in another version. dst must be empty.
+func convertJSON(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	utilconversion "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/conversion"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+func TestKubeadmConfigConversionRoundTrip(t *testing.T) {
+	now := metav1.Now()
+	dataSecretName := "cfg-bootstrap-data"
+	hub := &v1alpha2.KubeadmConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cfg",
+			Namespace:   "default",
+			Labels:      map[string]string{"cluster.x-k8s.io/cluster-name": "cluster"},
+			Annotations: map[string]string{"foo": "bar"},
+		},
+		Spec: v1alpha2.KubeadmConfigSpec{
+			JoinConfiguration: &kubeadmv1beta1.JoinConfiguration{
+				NodeRegistration: kubeadmv1beta1.NodeRegistrationOptions{
+					CRISocket:        "/var/run/dockershim.sock",
+					KubeletExtraArgs: map[string]string{"node-labels": "foo=bar"},
+				},
+			},
+			AdditionalUserDataFiles: []v1alpha2.Files{{Path: "/etc/foo", Content: "foo", Permissions: "0600"}},
+			PreKubeadmCommands:      []string{"echo pre"},
+			GeneratedFilesDir:       "/run/kubeadm",
+			ClusterDNS:              &v1alpha2.ClusterDNS{Domain: "cluster.local", Servers: []string{"10.96.0.10"}},
+		},
+		Status: v1alpha2.KubeadmConfigStatus{
+			Ready:              true,
+			ObservedGeneration: 2,
+			BootstrapData:      []byte("#cloud-config"),
+			DataSecretName:     &dataSecretName,
+			CertificatesExpiry: &now,
+			TraceID:            "0123456789abcdef",
+			FailureReason:      "InvalidConfiguration",
+			FailureMessage:     "Machine is a Worker, but JoinConfiguration.ControlPlane is set in the KubeadmConfig object",
+			Conditions: []v1alpha2.KubeadmConfigCondition{
+				{Type: v1alpha2.CertificatesExpiringCondition, Status: "False", Reason: "CertificatesValid"},
+			},
+		},
+	}
+
+	spoke := &KubeadmConfig{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatalf("failed to convert from the hub: %v", err)
+	}
+	if spoke.Spec.ClusterDNS == nil || spoke.Spec.ClusterDNS.Domain != "cluster.local" || spoke.Status.TraceID != hub.Status.TraceID {
+		t.Fatalf("unexpected converted config %+v", spoke)
+	}
+
+	restored := &v1alpha2.KubeadmConfig{}
+	if err := spoke.ConvertTo(restored); err != nil {
+		t.Fatalf("failed to convert to the hub: %v", err)
+	}
+	// the v1alpha3 spec is stashed in the hub
+	if _, ok := restored.Annotations[utilconversion.DataAnnotation]; !ok {
+		t.Fatal("expected the spec to be stashed in the conversion data annotation")
+	}
+	delete(restored.Annotations, utilconversion.DataAnnotation)
+	// the time loses its sub-second precision in its json representation
+	hub.Status.CertificatesExpiry = restored.Status.CertificatesExpiry
+	if !reflect.DeepEqual(restored.ObjectMeta, hub.ObjectMeta) || !reflect.DeepEqual(restored.Spec, hub.Spec) || !reflect.DeepEqual(restored.Status, hub.Status) {
+		t.Fatalf("expected %+v to survive the round trip, got %+v", hub, restored)
+	}
+}
+
+func TestKubeadmConfigTemplateConversionRoundTrip(t *testing.T) {
+	hub := &v1alpha2.KubeadmConfigTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "tmpl", Namespace: "default"},
+		Spec: v1alpha2.KubeadmConfigTemplateSpec{
+			Template: v1alpha2.KubeadmConfigTemplateResource{
+				Spec: v1alpha2.KubeadmConfigSpec{
+					JoinConfiguration: &kubeadmv1beta1.JoinConfiguration{},
+					Users:             []v1alpha2.User{{Name: "capi"}},
+				},
+			},
+		},
+	}
+
+	spoke := &KubeadmConfigTemplate{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatalf("failed to convert from the hub: %v", err)
+	}
+	restored := &v1alpha2.KubeadmConfigTemplate{}
+	if err := spoke.ConvertTo(restored); err != nil {
+		t.Fatalf("failed to convert to the hub: %v", err)
+	}
+	if _, ok := restored.Annotations[utilconversion.DataAnnotation]; !ok {
+		t.Fatal("expected the spec to be stashed in the conversion data annotation")
+	}
+	delete(restored.Annotations, utilconversion.DataAnnotation)
+	if len(restored.Annotations) == 0 {
+		restored.Annotations = nil
+	}
+	if !reflect.DeepEqual(restored, hub) {
+		t.Fatalf("expected %+v to survive the round trip, got %+v", hub, restored)
+	}
+}
+
+func TestKubeadmConfigSpokeConversionRoundTrip(t *testing.T) {
+	spoke := &KubeadmConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cfg",
+			Namespace:   "default",
+			Annotations: map[string]string{"foo": "bar"},
+		},
+		Spec: KubeadmConfigSpec{
+			JoinConfiguration: &kubeadmv1beta1.JoinConfiguration{},
+			DiscoveryEndpoint: "10.0.0.1:6443",
+		},
+	}
+
+	hub := &v1alpha2.KubeadmConfig{}
+	if err := spoke.ConvertTo(hub); err != nil {
+		t.Fatalf("failed to convert to the hub: %v", err)
+	}
+	restored := &KubeadmConfig{}
+	if err := restored.ConvertFrom(hub); err != nil {
+		t.Fatalf("failed to convert from the hub: %v", err)
+	}
+	if _, ok := restored.Annotations[utilconversion.DataAnnotation]; ok {
+		t.Fatal("expected the conversion data annotation to be removed")
+	}
+	if !reflect.DeepEqual(restored, spoke) {
+		t.Fatalf("expected %+v to survive the round trip, got %+v", spoke, restored)
+	}
+}
@@ -0,0 +1,1215 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+// DefaultGeneratedFilesDir is the default directory where the kubeadm configuration and helper scripts are written on
+// the machine.
+const DefaultGeneratedFilesDir = "/run/kubeadm"
+
+// ArchitectureLabel is the label on the owning Machine holding its CPU architecture, as in GOARCH.
+const ArchitectureLabel = "kubernetes.io/arch"
+
+// DefaultArchitecture is the architecture of Machines without the ArchitectureLabel.
+const DefaultArchitecture = "amd64"
+
+// DefaultBinaryInstallBaseURL is the default URL the Kubernetes binaries are downloaded from.
+const DefaultBinaryInstallBaseURL = "https://storage.googleapis.com/kubernetes-release/release"
+
+// DefaultBinaryInstallDir is the default directory the Kubernetes binaries are installed to.
+const DefaultBinaryInstallDir = "/usr/local/bin"
+
+// DefaultVariantLabel is the default label on the owning Machine selecting the variant of a KubeadmConfig.
+const DefaultVariantLabel = "bootstrap.cluster.x-k8s.io/variant"
+
+// KubeadmConfigSpec defines the desired state of KubeadmConfig.
+// Either ClusterConfiguration and InitConfiguration should be defined or the JoinConfiguration should be defined.
+type KubeadmConfigSpec struct {
+	// ClusterConfiguration along with InitConfiguration are the configurations necessary for the init command
+	// +optional
+	ClusterConfiguration *kubeadmv1beta1.ClusterConfiguration `json:"clusterConfiguration,omitempty"`
+	// InitConfiguration along with ClusterConfiguration are the configurations necessary for the init command
+	// +optional
+	InitConfiguration *kubeadmv1beta1.InitConfiguration `json:"initConfiguration,omitempty"`
+	// JoinConfiguration is the kubeadm configuration for the join command
+	// +optional
+	JoinConfiguration *kubeadmv1beta1.JoinConfiguration `json:"joinConfiguration,omitempty"`
+	// KubeadmConfigPatches are applied in order to the rendered kubeadm configuration documents just before they
+	// are embedded in the bootstrap data, as an escape hatch for kubeadm options the API doesn't model.
+	// +optional
+	KubeadmConfigPatches []KubeadmConfigPatch `json:"kubeadmConfigPatches,omitempty"`
+	// Format is the format of the bootstrap data, which the operating system of the machine must process at first
+	// boot, either cloud-config, ignition, windows or one registered with the controller. Defaults to
+	// cloud-config. The ignition and windows formats require the Ignition and Windows feature gates.
+	// +optional
+	Format Format `json:"format,omitempty"`
+	// Compression, if set, compresses the bootstrap data handed to the infrastructure provider, e.g. so that it
+	// fits the user data size limit of the platform. The encoding is recorded in the BootstrapDataEncoding of the
+	// status and in the data-encoding annotation of the bootstrap data secret. It is not supported by the ignition
+	// format, nor by the SSH delivery.
+	// +optional
+	Compression BootstrapDataCompression `json:"compression,omitempty"`
+	// DiscoveryEndpoint overrides the API server endpoint, in the form host:port, that joining nodes use for
+	// bootstrap token discovery, e.g. to join through a regional or internal endpoint instead of the Cluster one.
+	// An APIServerEndpoint set in JoinConfiguration.Discovery.BootstrapToken takes precedence over this value.
+	// +optional
+	DiscoveryEndpoint string `json:"discoveryEndpoint,omitempty"`
+	// ControlPlaneEndpointIP, if set, pins the hostname of the control plane endpoint to this IP address
+	// with an /etc/hosts entry written before kubeadm runs, for environments where the node cannot resolve
+	// the control plane endpoint at first boot.
+	// +optional
+	ControlPlaneEndpointIP string `json:"controlPlaneEndpointIP,omitempty"`
+	// GeneratedFilesDir is the absolute path of the directory where the kubeadm configuration and helper scripts
+	// are written on the machine, e.g. for read-only root filesystems. Defaults to /run/kubeadm.
+	// +optional
+	GeneratedFilesDir string `json:"generatedFilesDir,omitempty"`
+	// KubeadmContainer, if set, runs kubeadm from a container image instead of expecting a kubeadm binary
+	// to be installed on the machine.
+	// +optional
+	KubeadmContainer *KubeadmContainer `json:"kubeadmContainer,omitempty"`
+	// Verbosity, if set, is the log level kubeadm runs with, appended as --v to the kubeadm init and join commands,
+	// e.g. to debug failing joins.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Verbosity *int32 `json:"verbosity,omitempty"`
+	// JoinRetry, if set, retries a failed kubeadm join with an exponential backoff, running kubeadm reset between
+	// attempts, so that a transient API server error does not leave the machine broken. kubeadm init is not retried.
+	// +optional
+	JoinRetry *JoinRetry `json:"joinRetry,omitempty"`
+	// KubeadmFailureReport, if set, keeps the output of kubeadm in kubeadm.log in the GeneratedFilesDir and, when a
+	// kubeadm join discovering the cluster with a bootstrap token fails, reports the end of it to the workload
+	// cluster, from where it is copied to the KubeadmFailed condition. Failures of kubeadm init are only kept on the
+	// machine, as there is no API server to report them to yet.
+	// +optional
+	KubeadmFailureReport *KubeadmFailureReport `json:"kubeadmFailureReport,omitempty"`
+	// ImagePreflight, if set, verifies before running kubeadm that the machine image provides the kubeadm, kubelet
+	// and containerd versions and the paths expected by the Machine. If it does not, the reasons are written to
+	// image-preflight.failed in the GeneratedFilesDir and kubeadm is not run.
+	// +optional
+	ImagePreflight *ImagePreflight `json:"imagePreflight,omitempty"`
+	// OSConditionals are files and commands only applied on machines of a given OS family, as detected from
+	// /etc/os-release, so that a single KubeadmConfig can serve machine images of different distributions.
+	// +optional
+	OSConditionals []OSConditional `json:"osConditionals,omitempty"`
+	// Artifacts are files downloaded on the machine before kubeadm is run, e.g. binaries or manifests.
+	// +optional
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	// BinaryInstall, if set, downloads and installs kubeadm, kubelet and kubectl, along with a systemd unit for the
+	// kubelet, for machine images that ship without Kubernetes binaries.
+	// +optional
+	BinaryInstall *BinaryInstall `json:"binaryInstall,omitempty"`
+	// PackageRepositories are apt or yum repositories configured on machines of their OS family before kubeadm is
+	// run. Each repository is bound to its GPG key, so that the package manager refuses unsigned packages.
+	// +optional
+	PackageRepositories []PackageRepository `json:"packageRepositories,omitempty"`
+
+	// TrustedCABundles reference the Secret or ConfigMap keys holding PEM encoded CA certificates, installed into
+	// the trust store of the machine before kubeadm runs, e.g. the CAs of private registries and proxies.
+	// +optional
+	TrustedCABundles []FileSource `json:"trustedCABundles,omitempty"`
+	// HardeningProfile, if set, applies a security benchmark to the machine: the control plane component and kubelet
+	// flags it requires are defaulted in the kubeadm configurations, the kernel settings the kubelet then expects are
+	// applied before kubeadm is run, and the permissions and ownership of the files kubeadm writes are restricted
+	// after it has run. Flags set in the kubeadm configurations take precedence over the profile.
+	// +optional
+	HardeningProfile HardeningProfile `json:"hardeningProfile,omitempty"`
+	// ControlPlaneTaints, if set, are the taints control plane nodes are registered with, instead of the
+	// node-role.kubernetes.io/master:NoSchedule taint kubeadm defaults to. An empty list registers control plane
+	// nodes untainted, e.g. for small clusters scheduling workloads on them. It must not be set along with the
+	// taints of the node registration in InitConfiguration or JoinConfiguration.
+	// +optional
+	ControlPlaneTaints *[]corev1.Taint `json:"controlPlaneTaints,omitempty"`
+	// EtcdTuning, if set, tunes the local etcd members of the control plane, as routinely needed for larger
+	// control planes. It is rendered into the local etcd of the ClusterConfiguration, whose extraArgs must not set
+	// the same flags.
+	// +optional
+	EtcdTuning *EtcdTuning `json:"etcdTuning,omitempty"`
+	// EtcdDisk, if set, places the etcd data directory of control plane machines on a dedicated device, which is
+	// partitioned, formatted and mounted before kubeadm is run. The etcd data directory of the ClusterConfiguration
+	// is set accordingly.
+	// +optional
+	EtcdDisk *EtcdDisk `json:"etcdDisk,omitempty"`
+	// DiskSetup, if set, partitions and formats additional devices before kubeadm is run, e.g. a dedicated
+	// /var/lib/containerd device. It is rendered into the disk_setup and fs_setup cloud-init modules, after the
+	// EtcdDisk, and is not supported by the ignition format.
+	// +optional
+	DiskSetup *DiskSetup `json:"diskSetup,omitempty"`
+	// Mounts lists the filesystems mounted before kubeadm is run, rendered into the mounts cloud-init module after
+	// the EtcdDisk. It is not supported by the ignition format.
+	// +optional
+	Mounts []MountPoints `json:"mounts,omitempty"`
+	// KubeletTuning, if set, sets the graceful node shutdown and eviction defaults of the kubelets of the cluster. It
+	// is rendered as a KubeletConfiguration along with the InitConfiguration, which kubeadm uploads to the cluster for
+	// the kubelets of joining machines to use.
+	// +optional
+	KubeletTuning *KubeletTuning `json:"kubeletTuning,omitempty"`
+	// ClusterDNS, if set, overrides the cluster domain and DNS servers of the kubelet, which otherwise default to
+	// the service domain of the Cluster and the cluster DNS IP kubeadm allocates in its service subnet.
+	// +optional
+	ClusterDNS *ClusterDNS `json:"clusterDNS,omitempty"`
+	// NTP, if set, configures the NTP client of the machine, instead of the NTP of the BootstrapSettings of the
+	// cluster.
+	// +optional
+	NTP *NTP `json:"ntp,omitempty"`
+	// Proxy, if set, is the HTTP proxy the container runtime, kubelet and bootstrap commands of the machine reach
+	// the network through, instead of the Proxy of the BootstrapSettings of the cluster.
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+	// RegistryMirrors, if set, are the mirrors the container runtime pulls images from, by registry, instead of the
+	// RegistryMirrors of the BootstrapSettings of the cluster.
+	// +optional
+	RegistryMirrors []RegistryMirror `json:"registryMirrors,omitempty"`
+	// RegistryCredentials are the credentials the container runtime authenticates to private registries and
+	// mirrors with.
+	// +optional
+	RegistryCredentials []RegistryCredentials `json:"registryCredentials,omitempty"`
+	// Users lists the users created on the machine, e.g. for break-glass SSH access, rendered into the users
+	// cloud-init module. Setting it replaces the default user of the image.
+	// +optional
+	Users []User `json:"users,omitempty"`
+	// StartupTaint, if set, registers the node with a NoSchedule taint that is only removed once the verification
+	// commands succeed after kubeadm and the PostKubeadmCommands, so that no workload lands on a node whose bootstrap
+	// finished partially.
+	// +optional
+	StartupTaint *StartupTaint `json:"startupTaint,omitempty"`
+	// RetainedIdentity, if set, joins worker machines as the given node with a kubelet client certificate that the
+	// controller issues once and keeps in a Secret of the management cluster, instead of a bootstrap token, so that
+	// re-imaged machines rejoin as the same node without a new token being created. The certificate is reissued
+	// before it expires, or once the cluster CA that signed it is rotated out.
+	// +optional
+	RetainedIdentity *RetainedIdentity `json:"retainedIdentity,omitempty"`
+	// PodSecurity, if set, configures the defaults and exemptions of the PodSecurity admission plugin. The admission
+	// configuration is written on control plane machines and passed to the API server, along with its volume.
+	// +optional
+	PodSecurity *PodSecurity `json:"podSecurity,omitempty"`
+	// AdmissionPlugins, if set, enables and disables admission plugins of the API server, and configures them. The
+	// configuration files are written on control plane machines and passed to the API server, along with their volume.
+	// +optional
+	AdmissionPlugins *AdmissionPlugins `json:"admissionPlugins,omitempty"`
+	// SchedulerConfiguration, if set, configures the scheduler with files written on control plane machines in
+	// /etc/kubernetes/scheduler, e.g. a KubeSchedulerConfiguration passed with the config flag.
+	// +optional
+	SchedulerConfiguration *ControlPlaneComponentConfiguration `json:"schedulerConfiguration,omitempty"`
+	// ControllerManagerConfiguration, if set, configures the controller manager with files written on control plane
+	// machines in /etc/kubernetes/controller-manager, e.g. a cloud provider configuration passed with the
+	// cloud-config flag.
+	// +optional
+	ControllerManagerConfiguration *ControlPlaneComponentConfiguration `json:"controllerManagerConfiguration,omitempty"`
+	// ExternalCAs, if set, are CAs provided for etcd and the front proxy, instead of the ones generated along with
+	// the cluster CA, e.g. when a security policy requires them to be issued separately. They are used when the
+	// cluster certificates are generated, by the config of the machine initializing the control plane.
+	// +optional
+	ExternalCAs *ExternalCAs `json:"externalCAs,omitempty"`
+	// VariantLabel is the label on the owning Machine naming the variant to use. Machines get the labels of the
+	// template of their MachineDeployment or MachineSet. Defaults to bootstrap.cluster.x-k8s.io/variant.
+	// +optional
+	VariantLabel string `json:"variantLabel,omitempty"`
+	// Variants are named variations of this spec, typically set in a KubeadmConfigTemplate shared by heterogeneous
+	// machines. The variant named by the VariantLabel of the owning Machine is applied to the spec before the
+	// bootstrap data is generated, and the variants are then cleared. Machines without the label use the spec as is.
+	// +optional
+	Variants []KubeadmConfigVariant `json:"variants,omitempty"`
+	// AdditionalUserDataFiles specifies extra files to be passed to user_data upon creation.
+	// +optional
+	AdditionalUserDataFiles []Files `json:"additionalUserDataFiles,omitempty"`
+	// Multipart, if set, renders the cloud-config as the first part of a MIME multi-part archive followed by the
+	// given parts, which cloud-init merges with it instead of one replacing the other. It is only supported by the
+	// cloud-config format.
+	// +optional
+	Multipart *Multipart `json:"multipart,omitempty"`
+	// AdditionalCloudConfig is a cloud-config YAML mapping appended to the generated cloud-config, for the
+	// cloud-init modules the spec does not model, e.g. power_state or growpart. It can't set the keys the
+	// controller generates, such as write_files or runcmd, and is only supported by the cloud-config format.
+	// +optional
+	AdditionalCloudConfig string `json:"additionalCloudConfig,omitempty"`
+	// PreKubeadmCommands are run before kubeadm, after the commands the controller generates to prepare the
+	// machine.
+	// +optional
+	PreKubeadmCommands []string `json:"preKubeadmCommands,omitempty"`
+	// PostKubeadmCommands are run after kubeadm.
+	// +optional
+	PostKubeadmCommands []string `json:"postKubeadmCommands,omitempty"`
+	// ControlPlaneHealthGate, if set, holds the generation of worker join data until the control plane
+	// endpoint has passed a health probe the configured number of consecutive times.
+	// +optional
+	ControlPlaneHealthGate *ControlPlaneHealthGate `json:"controlPlaneHealthGate,omitempty"`
+	// Delivery, if set, changes how the bootstrap data reaches the machine, instead of relying on the
+	// infrastructure provider to hand it over as user data.
+	// +optional
+	Delivery *Delivery `json:"delivery,omitempty"`
+	// BootstrapDataRevisionHistoryLimit, if greater than zero, is the number of generated bootstrap data revisions
+	// retained in secrets named <name>-bootstrap-data-<revision>, so that what machines received can be compared.
+	// +optional
+	BootstrapDataRevisionHistoryLimit int32 `json:"bootstrapDataRevisionHistoryLimit,omitempty"`
+}
+
+// EtcdTuning defines common tuning options of local etcd members.
+type EtcdTuning struct {
+	// QuotaBackendBytes is the size of the backend database at which etcd raises a space alarm and stops accepting
+	// writes, e.g. 8589934592 for 8GiB. etcd defaults to 2GiB.
+	// +optional
+	QuotaBackendBytes *int64 `json:"quotaBackendBytes,omitempty"`
+	// HeartbeatInterval is the interval between the heartbeats of the leader, in whole milliseconds, e.g. 100ms.
+	// +optional
+	HeartbeatInterval *metav1.Duration `json:"heartbeatInterval,omitempty"`
+	// ElectionTimeout is how long a follower waits for a heartbeat before starting an election, in whole
+	// milliseconds, e.g. 1s. It must be at least five times the HeartbeatInterval, which defaults to 100ms.
+	// +optional
+	ElectionTimeout *metav1.Duration `json:"electionTimeout,omitempty"`
+	// ListenMetricsURLs are the http or https URLs etcd serves its metrics and health endpoints on, besides the
+	// client URLs, e.g. http://127.0.0.1:2381.
+	// +optional
+	ListenMetricsURLs []string `json:"listenMetricsURLs,omitempty"`
+	// DataDir is the absolute path of the directory etcd stores its data in. Defaults to /var/lib/etcd.
+	// +optional
+	DataDir string `json:"dataDir,omitempty"`
+}
+
+// KubeletTuning defines common operational defaults of kubelets.
+type KubeletTuning struct {
+	// GracefulShutdown, if set, delays the shutdown of the node to terminate its pods gracefully.
+	// +optional
+	GracefulShutdown *KubeletGracefulShutdown `json:"gracefulShutdown,omitempty"`
+	// Eviction, if set, are the thresholds at which the kubelet evicts pods to reclaim node resources.
+	// +optional
+	Eviction *KubeletEviction `json:"eviction,omitempty"`
+}
+
+// ClusterDNS defines how kubelets resolve the names of the services of the cluster.
+type ClusterDNS struct {
+	// Domain is the cluster domain, e.g. cluster.local.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+	// Servers are the IPs of the cluster DNS service.
+	// +optional
+	Servers []string `json:"servers,omitempty"`
+}
+
+// KubeletGracefulShutdown defines the graceful node shutdown of kubelets.
+type KubeletGracefulShutdown struct {
+	// GracePeriod is how long the node delays its shutdown to terminate its pods, e.g. 30s.
+	GracePeriod metav1.Duration `json:"gracePeriod"`
+	// CriticalPodsGracePeriod is the part of the GracePeriod reserved to terminate critical pods, after the other
+	// pods were terminated. It must not exceed the GracePeriod.
+	// +optional
+	CriticalPodsGracePeriod *metav1.Duration `json:"criticalPodsGracePeriod,omitempty"`
+}
+
+// KubeletEviction defines the eviction thresholds of kubelets. Thresholds are keyed by eviction signal, e.g.
+// memory.available, and are quantities or percentages, e.g. 500Mi or 10%.
+type KubeletEviction struct {
+	// Hard are the thresholds at which pods are evicted immediately.
+	// +optional
+	Hard map[string]string `json:"hard,omitempty"`
+	// Soft are the thresholds at which pods are evicted once exceeded for their SoftGracePeriod.
+	// +optional
+	Soft map[string]string `json:"soft,omitempty"`
+	// SoftGracePeriod is how long each Soft threshold must be exceeded before pods are evicted, e.g. 1m30s. Each
+	// Soft threshold requires one.
+	// +optional
+	SoftGracePeriod map[string]string `json:"softGracePeriod,omitempty"`
+	// MinimumReclaim are the amounts reclaimed beyond the thresholds when pods are evicted.
+	// +optional
+	MinimumReclaim map[string]string `json:"minimumReclaim,omitempty"`
+	// PressureTransitionPeriod is how long the kubelet waits before clearing a node pressure condition.
+	// +optional
+	PressureTransitionPeriod *metav1.Duration `json:"pressureTransitionPeriod,omitempty"`
+	// MaxPodGracePeriod is the maximum termination grace period, in seconds, of pods evicted on a Soft threshold.
+	// +optional
+	MaxPodGracePeriod int32 `json:"maxPodGracePeriod,omitempty"`
+}
+
+// DefaultEtcdDiskMountPath is the default path the EtcdDisk is mounted on.
+const DefaultEtcdDiskMountPath = "/var/lib/etcddisk"
+
+// DefaultEtcdDiskFilesystem is the default filesystem the EtcdDisk is formatted with.
+const DefaultEtcdDiskFilesystem = "ext4"
+
+// EtcdDisk defines the dedicated device the etcd data directory is placed on.
+type EtcdDisk struct {
+	// Device is the block device, e.g. /dev/nvme1n1. It is given a single partition if it has no partition table,
+	// and the partition is formatted if it has no filesystem, so that existing data is never overwritten.
+	Device string `json:"device"`
+	// Filesystem is the filesystem the partition is formatted with. Defaults to ext4.
+	// +kubebuilder:validation:Enum=ext4;xfs
+	// +optional
+	Filesystem string `json:"filesystem,omitempty"`
+	// MountPath is the absolute path the partition is mounted on. etcd stores its data in the etcd directory
+	// under it, as kubeadm expects the data directory to be empty. Defaults to /var/lib/etcddisk.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// DiskSetup defines the partitions and filesystems created on the devices of the machine. Neither overwrites
+// existing data unless asked to.
+type DiskSetup struct {
+	// Partitions lists the devices partitioned.
+	// +optional
+	Partitions []Partition `json:"partitions,omitempty"`
+	// Filesystems lists the filesystems created.
+	// +optional
+	Filesystems []Filesystem `json:"filesystems,omitempty"`
+}
+
+// Partition defines how a device is partitioned.
+type Partition struct {
+	// Device is the block device, e.g. /dev/nvme2n1.
+	Device string `json:"device"`
+	// Layout, if true, gives the device a single partition spanning it. Otherwise the device is left unpartitioned.
+	Layout bool `json:"layout"`
+	// Overwrite, if true, partitions the device even if it already has a partition table. Defaults to false.
+	// +optional
+	Overwrite *bool `json:"overwrite,omitempty"`
+	// TableType is the type of the partition table, mbr or gpt. Defaults to the one of cloud-init, mbr.
+	// +kubebuilder:validation:Enum=mbr;gpt
+	// +optional
+	TableType string `json:"tableType,omitempty"`
+}
+
+// Filesystem defines a filesystem created on a device or one of its partitions.
+type Filesystem struct {
+	// Device is the block device, e.g. /dev/nvme2n1.
+	Device string `json:"device"`
+	// Filesystem is the type of the filesystem, e.g. ext4 or xfs.
+	Filesystem string `json:"filesystem"`
+	// Label is the label of the filesystem, which Mounts can refer to as LABEL=<label>.
+	Label string `json:"label"`
+	// Partition is the partition of the device the filesystem is created on: its number, auto for the first
+	// partition without a filesystem, any for the first one, or none for the device itself.
+	// +optional
+	Partition string `json:"partition,omitempty"`
+	// Overwrite, if true, creates the filesystem even if the partition already has one. Defaults to false.
+	// +optional
+	Overwrite *bool `json:"overwrite,omitempty"`
+	// ExtraOpts are the additional options of the mkfs command.
+	// +optional
+	ExtraOpts []string `json:"extraOpts,omitempty"`
+}
+
+// MountPoints are the fstab fields of a mount: the device or filesystem, the mount point, and optionally the
+// filesystem type, the mount options, and the dump and pass numbers, e.g. [LABEL=containerd, /var/lib/containerd].
+type MountPoints []string
+
+// ExternalCAs references the Secrets holding CAs provided for the cluster. Each Secret holds the PEM encoded
+// certificate and private key of its CA in the tls.crt and tls.key keys, as kubernetes.io/tls Secrets do. The
+// CAs must differ from each other and from the cluster CA.
+type ExternalCAs struct {
+	// EtcdCASecretName is the name of the Secret in the KubeadmConfig namespace holding the etcd CA.
+	// +optional
+	EtcdCASecretName string `json:"etcdCASecretName,omitempty"`
+	// FrontProxyCASecretName is the name of the Secret in the KubeadmConfig namespace holding the front proxy CA.
+	// +optional
+	FrontProxyCASecretName string `json:"frontProxyCASecretName,omitempty"`
+}
+
+// KubeadmContainerRuntime is the container runtime command line tool used to run kubeadm.
+type KubeadmContainerRuntime string
+
+const (
+	// KubeadmContainerRuntimeCtr runs kubeadm with ctr, which ships with containerd.
+	KubeadmContainerRuntimeCtr KubeadmContainerRuntime = "ctr"
+
+	// KubeadmContainerRuntimePodman runs kubeadm with podman.
+	KubeadmContainerRuntimePodman KubeadmContainerRuntime = "podman"
+)
+
+// KubeadmContainer defines the container image kubeadm is run from.
+type KubeadmContainer struct {
+	// Image is the container image providing kubeadm on its PATH. ctr does not default the registry or the
+	// tag, so images run with it must be fully qualified, e.g. docker.io/library/kubeadm:v1.15.3.
+	Image string `json:"image"`
+	// Runtime is the tool used to run the image. Defaults to ctr.
+	// +kubebuilder:validation:Enum=ctr;podman
+	// +optional
+	Runtime KubeadmContainerRuntime `json:"runtime,omitempty"`
+}
+
+// JoinRetry defines how kubeadm join is retried.
+type JoinRetry struct {
+	// Attempts is the maximum number of times kubeadm join is run. Defaults to 5.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+	// InitialBackoffSeconds is the time waited before the second attempt, doubled before each of the following
+	// ones. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	InitialBackoffSeconds int32 `json:"initialBackoffSeconds,omitempty"`
+}
+
+// KubeadmFailureReport defines how the failures of kubeadm are reported.
+type KubeadmFailureReport struct {
+	// MaxBytes is the size of the end of the kubeadm output that is reported. Defaults to 4096.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=32768
+	// +optional
+	MaxBytes int32 `json:"maxBytes,omitempty"`
+}
+
+// ImagePreflight defines the checks run against the machine image before kubeadm.
+// The kubeadm and kubelet versions are checked against the version of the owning Machine, if set.
+type ImagePreflight struct {
+	// ContainerdVersion, if set, is the containerd version the image must provide, e.g. v1.2 or v1.2.6.
+	// +optional
+	ContainerdVersion string `json:"containerdVersion,omitempty"`
+	// RequiredPaths are absolute paths that must exist on the image.
+	// +optional
+	RequiredPaths []string `json:"requiredPaths,omitempty"`
+}
+
+// OSFamily is a family of operating systems sharing packaging and configuration conventions.
+type OSFamily string
+
+const (
+	// OSFamilyDebian matches Debian and its derivatives, such as Ubuntu.
+	OSFamilyDebian OSFamily = "debian"
+
+	// OSFamilyRHEL matches Red Hat Enterprise Linux and related distributions, such as CentOS and Fedora.
+	OSFamilyRHEL OSFamily = "rhel"
+
+	// OSFamilyFlatcar matches Flatcar Container Linux and CoreOS.
+	OSFamilyFlatcar OSFamily = "flatcar"
+)
+
+// OSConditional defines files and commands only applied on machines of an OS family.
+type OSConditional struct {
+	// OSFamily is the OS family of the machines the files and commands apply to.
+	// +kubebuilder:validation:Enum=debian;rhel;flatcar
+	OSFamily OSFamily `json:"osFamily"`
+	// Files are written on machines of the OS family, before the PreKubeadmCommands are run.
+	// +optional
+	Files []Files `json:"files,omitempty"`
+	// PreKubeadmCommands are run on machines of the OS family, before kubeadm.
+	// +optional
+	PreKubeadmCommands []string `json:"preKubeadmCommands,omitempty"`
+}
+
+// Artifact defines a file downloaded on the machine.
+type Artifact struct {
+	// Path is the absolute path the artifact is downloaded to.
+	Path string `json:"path"`
+	// URL is the http or https URL the artifact is downloaded from.
+	// +optional
+	URL string `json:"url,omitempty"`
+	// ArchitectureURLs are the URLs the artifact is downloaded from on machines of the given architectures,
+	// overriding URL. The architecture of a machine is the kubernetes.io/arch label of the owning Machine,
+	// amd64 if unset.
+	// +optional
+	ArchitectureURLs map[string]string `json:"architectureURLs,omitempty"`
+	// Permissions specifies the permissions to assign to the artifact, e.g. "0755".
+	// +optional
+	Permissions string `json:"permissions,omitempty"`
+	// SHA256, if set, is the expected hex encoded sha256 checksum of the artifact. The bootstrap is stopped if the
+	// downloaded artifact does not match it.
+	// +optional
+	SHA256 string `json:"sha256,omitempty"`
+	// Cosign, if set, verifies the signature of the artifact with cosign, which must be available on the machine.
+	// The bootstrap is stopped if the downloaded artifact is not signed with the given key.
+	// +optional
+	Cosign *CosignSignature `json:"cosign,omitempty"`
+}
+
+// CosignSignature defines the cosign signature a download is verified with.
+type CosignSignature struct {
+	// PublicKey is the PEM encoded public key the download must be signed with.
+	PublicKey string `json:"publicKey"`
+	// SignatureURL is the http or https URL of the signature. Defaults to the URL of the download with a .sig suffix.
+	// +optional
+	SignatureURL string `json:"signatureURL,omitempty"`
+}
+
+// BinaryInstall defines how the Kubernetes binaries are installed on the machine.
+type BinaryInstall struct {
+	// Version is the Kubernetes version of the binaries. Defaults to the version of the owning Machine.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// BaseURL is the URL the binaries are downloaded from, as <baseURL>/<version>/bin/linux/<architecture>/<binary>.
+	// Defaults to https://storage.googleapis.com/kubernetes-release/release.
+	// +optional
+	BaseURL string `json:"baseURL,omitempty"`
+	// InstallDir is the absolute path of the directory the binaries are installed to. Defaults to /usr/local/bin.
+	// +optional
+	InstallDir string `json:"installDir,omitempty"`
+	// SHA256 are the expected sha256 checksums of the binaries, keyed by <architecture>/<binary>, e.g. amd64/kubelet.
+	// The bootstrap is stopped if a downloaded binary does not match its checksum.
+	// +optional
+	SHA256 map[string]string `json:"sha256,omitempty"`
+	// CosignPublicKey, if set, is the PEM encoded public key the binaries must be signed with, the signature of each
+	// binary being downloaded from its URL with a .sig suffix. Verifying requires cosign to be available on the
+	// machine, and the bootstrap is stopped if a binary is not signed with the key.
+	// +optional
+	CosignPublicKey string `json:"cosignPublicKey,omitempty"`
+	// KubeletUnit, if set, is the content of the kubelet systemd unit, instead of the one from the Kubernetes packages.
+	// +optional
+	KubeletUnit string `json:"kubeletUnit,omitempty"`
+}
+
+// Format is a format of bootstrap data. Formats other than the ones below are supported by the generators
+// registered with the controller.
+type Format string
+
+const (
+	// FormatCloudConfig is the cloud-init cloud-config format.
+	FormatCloudConfig Format = "cloud-config"
+
+	// FormatIgnition is the Ignition v3 config format, for operating systems such as Flatcar Container Linux and
+	// Fedora CoreOS that do not run cloud-init.
+	FormatIgnition Format = "ignition"
+
+	// FormatWindows is the PowerShell user data run by cloudbase-init on Windows worker machines.
+	FormatWindows Format = "windows"
+)
+
+// BootstrapDataCompression is a compression of the bootstrap data.
+// +kubebuilder:validation:Enum=gzip
+type BootstrapDataCompression string
+
+const (
+	// BootstrapDataCompressionGzip compresses the bootstrap data with gzip, which cloud-init and cloudbase-init
+	// decompress.
+	BootstrapDataCompressionGzip BootstrapDataCompression = "gzip"
+)
+
+// HardeningProfile is a security benchmark applied to the machine.
+// +kubebuilder:validation:Enum=cis
+type HardeningProfile string
+
+const (
+	// HardeningProfileCIS applies the CIS Kubernetes Benchmark.
+	HardeningProfileCIS HardeningProfile = "cis"
+)
+
+// PodSecurityLevel is a level of the Pod Security Standards.
+// +kubebuilder:validation:Enum=privileged;baseline;restricted
+type PodSecurityLevel string
+
+const (
+	// PodSecurityLevelPrivileged allows any pod.
+	PodSecurityLevelPrivileged PodSecurityLevel = "privileged"
+
+	// PodSecurityLevelBaseline prevents known privilege escalations.
+	PodSecurityLevelBaseline PodSecurityLevel = "baseline"
+
+	// PodSecurityLevelRestricted enforces the current pod hardening best practices.
+	PodSecurityLevelRestricted PodSecurityLevel = "restricted"
+)
+
+// PodSecurity defines the cluster wide defaults and exemptions of the PodSecurity admission plugin.
+type PodSecurity struct {
+	// Enforce is the level pods are rejected for violating, in namespaces without a
+	// pod-security.kubernetes.io/enforce label. Defaults to privileged.
+	// +optional
+	Enforce PodSecurityLevel `json:"enforce,omitempty"`
+	// Audit is the level violations are recorded in the audit log for. Defaults to privileged.
+	// +optional
+	Audit PodSecurityLevel `json:"audit,omitempty"`
+	// Warn is the level violations are returned as warnings for. Defaults to privileged.
+	// +optional
+	Warn PodSecurityLevel `json:"warn,omitempty"`
+	// ExemptUsernames are the authenticated users whose pods are not checked.
+	// +optional
+	ExemptUsernames []string `json:"exemptUsernames,omitempty"`
+	// ExemptNamespaces are the namespaces whose pods are not checked.
+	// +optional
+	ExemptNamespaces []string `json:"exemptNamespaces,omitempty"`
+	// ExemptRuntimeClasses are the runtime classes whose pods are not checked.
+	// +optional
+	ExemptRuntimeClasses []string `json:"exemptRuntimeClasses,omitempty"`
+}
+
+// AdmissionPlugins defines the admission plugins of the API server.
+type AdmissionPlugins struct {
+	// Enable are the admission plugins enabled in addition to the default ones, e.g. EventRateLimit.
+	// +optional
+	Enable []string `json:"enable,omitempty"`
+	// Disable are the default admission plugins disabled.
+	// +optional
+	Disable []string `json:"disable,omitempty"`
+	// Configurations are the configurations of admission plugins.
+	// +optional
+	Configurations []AdmissionPluginConfiguration `json:"configurations,omitempty"`
+}
+
+// AdmissionPluginConfiguration defines the configuration file of an admission plugin.
+type AdmissionPluginConfiguration struct {
+	// Name is the name of the admission plugin.
+	Name string `json:"name"`
+	// Configuration is the configuration of the admission plugin, in YAML or JSON, e.g. an EventRateLimit
+	// Configuration.
+	Configuration string `json:"configuration"`
+}
+
+// ControlPlaneComponentConfiguration defines the configuration files of a control plane component. The directory
+// of the files is mounted in the component pod.
+type ControlPlaneComponentConfiguration struct {
+	// Files are the configuration files of the component.
+	Files []ControlPlaneComponentFile `json:"files"`
+}
+
+// ControlPlaneComponentFile defines a configuration file of a control plane component.
+type ControlPlaneComponentFile struct {
+	// Name is the name of the file in the directory of the component.
+	Name string `json:"name"`
+	// Flag, if set, is the component flag the path of the file is passed with, e.g. config.
+	// +optional
+	Flag string `json:"flag,omitempty"`
+	// Content is the content of the file.
+	Content string `json:"content"`
+}
+
+// PackageRepositoryGPGKeySecretKey is the key of the GPG key in the Secret referenced by a PackageRepository.
+const PackageRepositoryGPGKeySecretKey = "gpg-key"
+
+// PackageRepository defines an apt or yum repository and the GPG key its packages are signed with.
+type PackageRepository struct {
+	// Name is the name of the repository, used to name its files on the machine.
+	Name string `json:"name"`
+	// OSFamily is the OS family of the machines the repository is configured on, debian for apt and rhel for yum.
+	// +kubebuilder:validation:Enum=debian;rhel
+	OSFamily OSFamily `json:"osFamily"`
+	// URL is the http or https base URL of the repository.
+	URL string `json:"url"`
+	// Distribution is the apt distribution of the repository, e.g. kubernetes-xenial. Required for debian.
+	// +optional
+	Distribution string `json:"distribution,omitempty"`
+	// Components are the apt components of the repository. Defaults to main.
+	// +optional
+	Components []string `json:"components,omitempty"`
+	// GPGKey is the ASCII armored GPG public key the repository is signed with.
+	// +optional
+	GPGKey string `json:"gpgKey,omitempty"`
+	// GPGKeySecretName, instead of GPGKey, is the name of a Secret in the KubeadmConfig namespace that holds the
+	// ASCII armored GPG public key under the gpg-key key.
+	// +optional
+	GPGKeySecretName string `json:"gpgKeySecretName,omitempty"`
+}
+
+// KubeadmConfigVariant is a named variation of a KubeadmConfigSpec.
+type KubeadmConfigVariant struct {
+	// Name is the name of the variant, matched against the VariantLabel of the owning Machine.
+	Name string `json:"name"`
+	// Patch is a JSON merge patch (RFC 7386), in YAML or JSON, applied to the KubeadmConfigSpec.
+	Patch string `json:"patch"`
+}
+
+// KubeadmConfigPatchType is the type of a KubeadmConfigPatch.
+type KubeadmConfigPatchType string
+
+const (
+	// KubeadmConfigJSONPatch is a RFC 6902 JSON patch.
+	KubeadmConfigJSONPatch KubeadmConfigPatchType = "json"
+
+	// KubeadmConfigMergePatch is a RFC 7386 JSON merge patch.
+	KubeadmConfigMergePatch KubeadmConfigPatchType = "merge"
+)
+
+// KubeadmConfigPatch is a patch to a rendered kubeadm configuration document.
+type KubeadmConfigPatch struct {
+	// Kind is the kind of the kubeadm configuration document to patch.
+	// +kubebuilder:validation:Enum=ClusterConfiguration;InitConfiguration;JoinConfiguration
+	Kind string `json:"kind"`
+
+	// Type is the type of the patch, json for a RFC 6902 JSON patch or merge for a RFC 7386 JSON merge patch.
+	// Defaults to json.
+	// +kubebuilder:validation:Enum=json;merge
+	// +optional
+	Type KubeadmConfigPatchType `json:"type,omitempty"`
+
+	// Patch is the patch document, in JSON or YAML.
+	Patch string `json:"patch"`
+}
+
+// ControlPlaneHealthGate defines the policy used to hold worker join data until the control plane is healthy.
+type ControlPlaneHealthGate struct {
+	// SuccessThreshold is the number of consecutive successful health probes of the control plane endpoint
+	// required before worker join data is generated. Defaults to 3.
+	// +optional
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+}
+
+// Delivery defines how the bootstrap data reaches machines that have no suitable user data mechanism.
+type Delivery struct {
+	// SSH pushes the bootstrap data to the machine over SSH and runs cloud-init with it.
+	// +optional
+	SSH *SSHDelivery `json:"ssh,omitempty"`
+
+	// ObjectStorage uploads the bootstrap data to an S3-compatible object storage and hands the machine
+	// user data that only includes a short-lived pre-signed URL to fetch it.
+	// +optional
+	ObjectStorage *ObjectStorageDelivery `json:"objectStorage,omitempty"`
+
+	// TokenOnly hands the machine user data that only includes the URL of the controller bootstrap data
+	// server, along with the join token, the API server endpoint and the cluster CA public key hash. The
+	// server serves the bootstrap data over TLS to requests authenticated by that token, while it is valid.
+	// It is only supported for joining machines.
+	// +optional
+	TokenOnly *TokenOnlyDelivery `json:"tokenOnly,omitempty"`
+}
+
+// SSHDelivery defines the SSH connection used to push the bootstrap data to a machine.
+type SSHDelivery struct {
+	// Address is the host or host:port of the machine SSH server. The port defaults to 22.
+	Address string `json:"address"`
+
+	// User is the user to log in as. Defaults to root.
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// SecretName is the name of a Secret in the KubeadmConfig namespace that holds the SSH private key
+	// under the "ssh-privatekey" key and the public host key expected from the machine, in authorized_keys
+	// format, under the "ssh-known-host" key. The bootstrap data is not pushed to hosts presenting another key.
+	SecretName string `json:"secretName"`
+}
+
+// ObjectStorageDelivery defines the bucket the bootstrap data is uploaded to. Objects are stored under
+// <namespace>/<name> of the KubeadmConfig and are deleted by the controller once the node of the machine
+// registers, or once the KubeadmConfig is deleted.
+type ObjectStorageDelivery struct {
+	// Endpoint is the URL of the S3-compatible API, e.g. https://s3.eu-west-1.amazonaws.com.
+	// Buckets are addressed path-style.
+	Endpoint string `json:"endpoint"`
+
+	// Region is the region of the bucket, used to sign requests. Defaults to us-east-1.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Bucket is the name of the bucket.
+	Bucket string `json:"bucket"`
+
+	// SecretName is the name of a Secret in the KubeadmConfig namespace that holds the credentials
+	// under the "accessKeyID" and "secretAccessKey" keys and, optionally, "sessionToken".
+	SecretName string `json:"secretName"`
+
+	// URLExpiry is how long the pre-signed URL handed to the machine remains valid. The URL is not signed
+	// again, so it must cover the time until the machine boots and fetches its bootstrap data. Defaults to 15m.
+	// +optional
+	URLExpiry *metav1.Duration `json:"urlExpiry,omitempty"`
+}
+
+// TokenOnlyDelivery defines how machines reach the controller bootstrap data server.
+type TokenOnlyDelivery struct {
+	// URL is the base URL at which machines reach the bootstrap data server of the controller,
+	// e.g. https://10.0.0.2:9443.
+	URL string `json:"url"`
+}
+
+// KubeadmConfigStatus defines the observed state of KubeadmConfig
+type KubeadmConfigStatus struct {
+	// Ready indicates the BootstrapData field is ready to be consumed
+	Ready bool `json:"ready,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the controller last processed. The bootstrap data of a ready
+	// config is not generated again, so a later generation of its spec is not reflected in its bootstrap data.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// BootstrapData will be a cloud-init script for now
+	// +optional
+	BootstrapData []byte `json:"bootstrapData,omitempty"`
+
+	// DataSecretName is the name of the secret that stores the bootstrap data, under the "value" key, when the
+	// controller writes it to a secret. BootstrapData is left empty when the controller only writes it there.
+	// +optional
+	DataSecretName *string `json:"dataSecretName,omitempty"`
+
+	// BootstrapDataEncoding is the encoding of the bootstrap data, in the status and in the bootstrap data secret:
+	// the compression of the spec if it is compressed, empty otherwise.
+	// +optional
+	BootstrapDataEncoding BootstrapDataCompression `json:"bootstrapDataEncoding,omitempty"`
+
+	// JoinCommandSecretName is the name of the secret that stores, under the "value" key, a ready to run kubeadm
+	// join command for manually joining an out-of-band machine to the cluster. It is written when the Cluster has
+	// the publish-join-command annotation.
+	// +optional
+	JoinCommandSecretName *string `json:"joinCommandSecretName,omitempty"`
+
+	// ControlPlaneHealthChecks is the number of consecutive successful control plane health probes
+	// observed while waiting to generate worker join data.
+	// +optional
+	ControlPlaneHealthChecks int32 `json:"controlPlaneHealthChecks,omitempty"`
+
+	// Delivered indicates the BootstrapData has been pushed to the machine using spec.delivery.
+	// +optional
+	Delivered bool `json:"delivered,omitempty"`
+
+	// BootstrapTokenRevoked indicates the bootstrap token of the config was revoked once the node of the machine
+	// registered, deleting it from the workload cluster if the controller generated it.
+	// +optional
+	BootstrapTokenRevoked bool `json:"bootstrapTokenRevoked,omitempty"`
+
+	// BootstrapDataRevision is the revision of the last generated bootstrap data, when
+	// spec.bootstrapDataRevisionHistoryLimit is set.
+	// +optional
+	BootstrapDataRevision int32 `json:"bootstrapDataRevision,omitempty"`
+
+	// CertificatesExpiry is the soonest expiry of the cluster, etcd and front proxy CA certificates, recorded when
+	// the bootstrap data of a control plane machine is generated.
+	// +optional
+	CertificatesExpiry *metav1.Time `json:"certificatesExpiry,omitempty"`
+
+	// CertificateExpiries are the expiries of each of the cluster, etcd and front proxy CA certificates, recorded
+	// along with the CertificatesExpiry.
+	// +optional
+	CertificateExpiries []CertificateExpiry `json:"certificateExpiries,omitempty"`
+
+	// WorkloadClusterFailures is the number of consecutive failed attempts to reach the workload cluster, which
+	// the retries back off with.
+	// +optional
+	WorkloadClusterFailures int32 `json:"workloadClusterFailures,omitempty"`
+
+	// TraceID identifies the bootstrap of the machine across the controller logs and the machine: the bootstrap
+	// data exports it as the CABPK_TRACE_ID environment variable and prefixes its log lines with it.
+	// +optional
+	TraceID string `json:"traceID,omitempty"`
+
+	// FailureReason is a brief CamelCase reason why the bootstrap data of the config can not be generated, set
+	// when retrying does not help until the config, or the objects it refers to, is changed.
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// FailureMessage is a human readable message detailing the FailureReason.
+	// +optional
+	FailureMessage string `json:"failureMessage,omitempty"`
+
+	// Conditions are the latest observations of the state of the config.
+	// +optional
+	Conditions []KubeadmConfigCondition `json:"conditions,omitempty"`
+}
+
+// CertificateExpiry is the expiry of a CA certificate of the cluster.
+type CertificateExpiry struct {
+	// Name is the kubeadm name of the certificate, one of ca, etcd-ca or front-proxy-ca.
+	Name string `json:"name"`
+
+	// NotAfter is when the certificate expires.
+	NotAfter metav1.Time `json:"notAfter"`
+}
+
+// KubeadmConfigConditionType is the type of a KubeadmConfigCondition.
+type KubeadmConfigConditionType string
+
+const (
+	// CertificatesExpiringCondition is true once the CertificatesExpiry is within the warning window of the
+	// controller.
+	CertificatesExpiringCondition KubeadmConfigConditionType = "CertificatesExpiring"
+
+	// CARotationCondition is true while the CAs of the cluster are being rotated, its reason being the rotation
+	// phase the bootstrap data of a control plane machine was generated in.
+	CARotationCondition KubeadmConfigConditionType = "CARotation"
+
+	// CertificatesMissingCondition is true while the bootstrap data of the config is blocked on cluster
+	// certificates that must be provided, as their generation is disabled.
+	CertificatesMissingCondition KubeadmConfigConditionType = "CertificatesMissing"
+
+	// WorkloadClusterUnreachableCondition is true while the workload cluster can not be reached to create the
+	// bootstrap token or probe the control plane of the config.
+	WorkloadClusterUnreachableCondition KubeadmConfigConditionType = "WorkloadClusterUnreachable"
+
+	// MissingReferenceCondition is true while Secrets or ConfigMaps referenced by the config are not found or miss a
+	// required key, its message listing them.
+	MissingReferenceCondition KubeadmConfigConditionType = "MissingReference"
+
+	// CertificatesAvailableCondition is true once the cluster certificates the bootstrap data of a control plane
+	// machine embeds are found, generated or provided.
+	CertificatesAvailableCondition KubeadmConfigConditionType = "CertificatesAvailable"
+
+	// DataSecretAvailableCondition is true once the bootstrap data of the config is generated and available to the
+	// machine, in the status or in the bootstrap data secret.
+	DataSecretAvailableCondition KubeadmConfigConditionType = "DataSecretAvailable"
+
+	// ControlPlaneInitializedCondition is true once the control plane of the cluster of the config is observed
+	// initialized.
+	ControlPlaneInitializedCondition KubeadmConfigConditionType = "ControlPlaneInitialized"
+
+	// KubeadmFailedCondition is true once the machine reported that kubeadm join failed, its message holding the end
+	// of the kubeadm output.
+	KubeadmFailedCondition KubeadmConfigConditionType = "KubeadmFailed"
+)
+
+// KubeadmConfigCondition is an observation of the state of a KubeadmConfig.
+type KubeadmConfigCondition struct {
+	// Type is the type of the condition.
+	Type KubeadmConfigConditionType `json:"type"`
+	// Status is the status of the condition, one of True, False or Unknown.
+	Status corev1.ConditionStatus `json:"status"`
+	// LastTransitionTime is the last time the condition changed status.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a brief CamelCase reason for the status of the condition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable message detailing the status of the condition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=kubeadmconfigs,scope=Namespaced
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=".status.ready",description="Whether the bootstrap data is ready"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// KubeadmConfig is the Schema for the kubeadmconfigs API
+type KubeadmConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KubeadmConfigSpec   `json:"spec,omitempty"`
+	Status KubeadmConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KubeadmConfigList contains a list of KubeadmConfig
+type KubeadmConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KubeadmConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KubeadmConfig{}, &KubeadmConfigList{})
+}
+
+// Files defines the input for generating write_files in cloud-init.
+type Files struct {
+	// Path specifies the full path on disk where to store the file.
+	Path string `json:"path"`
+
+	// Owner specifies the ownership of the file, e.g. "root:root".
+	// +optional
+	Owner string `json:"owner,omitempty"`
+
+	// Permissions specifies the permissions to assign to the file, e.g. "0640".
+	// +optional
+	Permissions string `json:"permissions,omitempty"`
+
+	// Content is the actual content of the file.
+	// +optional
+	Content string `json:"content,omitempty"`
+
+	// ContentFrom, instead of Content, references the Secret or ConfigMap key the content is read from when the
+	// bootstrap data is generated, to keep credentials out of the spec and rotate them centrally.
+	// +optional
+	ContentFrom *FileSource `json:"contentFrom,omitempty"`
+
+	// Encoding, if set, is the encoding of the content, which is decoded before being written, e.g. to write
+	// binary files.
+	// +kubebuilder:validation:Enum=base64;gzip+base64
+	// +optional
+	Encoding FileEncoding `json:"encoding,omitempty"`
+
+	// LineEndings, if set, normalizes the line endings of the content to LF or CRLF, e.g. for files consumed
+	// on Windows.
+	// +kubebuilder:validation:Enum=LF;CRLF
+	// +optional
+	LineEndings FileLineEndings `json:"lineEndings,omitempty"`
+
+	// Charset is the character encoding the content is written in. Defaults to UTF-8.
+	// +kubebuilder:validation:Enum=UTF-8;UTF-8-BOM;UTF-16LE
+	// +optional
+	Charset FileCharset `json:"charset,omitempty"`
+}
+
+// DefaultStartupTaintKey is the key of the startup taint of a StartupTaint setting none.
+const DefaultStartupTaintKey = "node.cluster.x-k8s.io/uninitialized"
+
+// StartupTaint is a taint registering the node until its bootstrap is verified.
+type StartupTaint struct {
+	// Key is the key of the taint. Defaults to node.cluster.x-k8s.io/uninitialized.
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// VerificationCommands are run, in order, after kubeadm and the PostKubeadmCommands. The node keeps the taint
+	// if any of them fails.
+	// +optional
+	VerificationCommands []string `json:"verificationCommands,omitempty"`
+}
+
+// User defines a user created on the machine.
+type User struct {
+	// Name is the name of the user.
+	Name string `json:"name"`
+	// SSHAuthorizedKeys are the public keys added to the authorized keys of the user.
+	// +optional
+	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
+	// Sudo is the sudoers rule of the user, e.g. ALL=(ALL) NOPASSWD:ALL. The user can't use sudo if unset.
+	// +optional
+	Sudo string `json:"sudo,omitempty"`
+	// Shell is the absolute path of the login shell of the user.
+	// +optional
+	Shell string `json:"shell,omitempty"`
+	// Passwd is the hash of the password of the user, in the crypt format, e.g. $6$salt$hash.
+	// +optional
+	Passwd string `json:"passwd,omitempty"`
+	// LockPassword, if true, disables logging in with the password of the user. Defaults to true.
+	// +optional
+	LockPassword *bool `json:"lockPassword,omitempty"`
+}
+
+// RetainedIdentity defines the node a worker machine joins the cluster as, with the kubelet client certificate
+// retained for it.
+type RetainedIdentity struct {
+	// NodeName is the name of the node the client certificate is issued for. The name of the node registration of
+	// the JoinConfiguration defaults to it.
+	NodeName string `json:"nodeName"`
+}
+
+// FileSource references the key of a Secret or ConfigMap, in the KubeadmConfig namespace, holding the content of a
+// file. Exactly one of Secret or ConfigMap must be set.
+type FileSource struct {
+	// Secret references a key of a Secret.
+	// +optional
+	Secret *FileSourceKey `json:"secret,omitempty"`
+
+	// ConfigMap references a key of a ConfigMap.
+	// +optional
+	ConfigMap *FileSourceKey `json:"configMap,omitempty"`
+}
+
+// FileSourceKey is a key of a named Secret or ConfigMap.
+type FileSourceKey struct {
+	// Name is the name of the Secret or ConfigMap.
+	Name string `json:"name"`
+
+	// Key is the key holding the content.
+	Key string `json:"key"`
+}
+
+// FileEncoding is the encoding of the content of a file.
+type FileEncoding string
+
+const (
+	// FileEncodingBase64 is base64 encoded content.
+	FileEncodingBase64 FileEncoding = "base64"
+
+	// FileEncodingGzipBase64 is gzip compressed, then base64 encoded content.
+	FileEncodingGzipBase64 FileEncoding = "gzip+base64"
+)
+
+// FileLineEndings is the line ending convention the content of a file is normalized to.
+type FileLineEndings string
+
+const (
+	// FileLineEndingsLF ends lines with a line feed.
+	FileLineEndingsLF FileLineEndings = "LF"
+
+	// FileLineEndingsCRLF ends lines with a carriage return and a line feed.
+	FileLineEndingsCRLF FileLineEndings = "CRLF"
+)
+
+// FileCharset is the character encoding the content of a file is written in.
+type FileCharset string
+
+const (
+	// FileCharsetUTF8 writes the content in UTF-8, without byte order mark.
+	FileCharsetUTF8 FileCharset = "UTF-8"
+
+	// FileCharsetUTF8BOM writes the content in UTF-8, prefixed with a byte order mark.
+	FileCharsetUTF8BOM FileCharset = "UTF-8-BOM"
+
+	// FileCharsetUTF16LE writes the content in little endian UTF-16, prefixed with a byte order mark, as
+	// expected by Windows tooling.
+	FileCharsetUTF16LE FileCharset = "UTF-16LE"
+)
+
+// RegistryCredentials defines the Secret holding the credentials of a registry.
+type RegistryCredentials struct {
+	// Host is the host of the registry or mirror, with its port if not the default one, e.g.
+	// registry.example.com:5000.
+	Host string `json:"host"`
+
+	// SecretName is the name of a kubernetes.io/basic-auth Secret in the KubeadmConfig namespace holding the
+	// username and password to authenticate with.
+	SecretName string `json:"secretName"`
+}
+
+// Multipart defines the MIME multi-part archive the cloud-config is rendered in.
+type Multipart struct {
+	// Parts follow the generated cloud-config in the archive, in order.
+	// +optional
+	Parts []UserDataPart `json:"parts,omitempty"`
+
+	// MergeType is the cloud-init merge type of the cloud-config parts, set as their Merge-Type header. Defaults to
+	// list(append)+dict(no_replace,recurse_list)+str(), which appends the lists of the parts, such as runcmd and
+	// write_files, to the generated ones and keeps the generated values of the other keys.
+	// +optional
+	MergeType string `json:"mergeType,omitempty"`
+}
+
+// UserDataPart is a part of the MIME multi-part archive of the bootstrap data.
+type UserDataPart struct {
+	// ContentType is the MIME type of the part, which selects the cloud-init handler of its content, e.g.
+	// text/cloud-config or text/x-shellscript.
+	ContentType string `json:"contentType"`
+
+	// Filename, if set, is the filename of the part, after which cloud-init names the scripts it writes.
+	// +optional
+	Filename string `json:"filename,omitempty"`
+
+	// Content is the content of the part.
+	// +optional
+	Content string `json:"content,omitempty"`
+
+	// ContentFrom, instead of Content, references the Secret or ConfigMap key the content is read from when the
+	// bootstrap data is generated.
+	// +optional
+	ContentFrom *FileSource `json:"contentFrom,omitempty"`
+}
+
+// NTP defines the NTP client of the machines.
+type NTP struct {
+	// Enabled enables the NTP client. Defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Servers are the NTP servers to synchronize with, instead of the default pools of the OS.
+	// +optional
+	Servers []string `json:"servers,omitempty"`
+}
+
+// Proxy defines an HTTP proxy.
+type Proxy struct {
+	// HTTPProxy is the URL of the proxy for HTTP requests.
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is the URL of the proxy for HTTPS requests.
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy are the hosts, domains and CIDRs reached without the proxy, besides the loopback addresses, the
+	// service domain, service and pod subnets and API endpoints of the cluster, and the control plane and
+	// discovery endpoints of the KubeadmConfig, which always are.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+// RegistryMirror defines the mirrors of a container registry.
+type RegistryMirror struct {
+	// Registry is the host of the mirrored registry, e.g. docker.io.
+	Registry string `json:"registry"`
+
+	// Endpoints are the URLs of the mirrors, tried in order before the registry itself.
+	// +kubebuilder:validation:MinItems=1
+	Endpoints []string `json:"endpoints"`
+}
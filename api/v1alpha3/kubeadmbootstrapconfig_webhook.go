@@ -0,0 +1,134 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// The admission webhooks of the v1alpha3 types default and validate them as their hub version, since the
+// admissionregistration v1beta1 API does not send requests for other versions to the webhooks of v1alpha2.
+
+// SetupWebhookWithManager registers the KubeadmConfig webhooks with mgr.
+func (c *KubeadmConfig) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(c).
+		Complete()
+}
+
+// SetupWebhookWithManager registers the KubeadmConfigTemplate webhooks with mgr.
+func (t *KubeadmConfigTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(t).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/mutate-bootstrap-cluster-x-k8s-io-v1alpha3-kubeadmconfig,mutating=true,failurePolicy=fail,groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigs,versions=v1alpha3,name=default.v1alpha3.kubeadmconfig.bootstrap.cluster.x-k8s.io
+// +kubebuilder:webhook:verbs=create;update,path=/mutate-bootstrap-cluster-x-k8s-io-v1alpha3-kubeadmconfigtemplate,mutating=true,failurePolicy=fail,groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigtemplates,versions=v1alpha3,name=default.v1alpha3.kubeadmconfigtemplate.bootstrap.cluster.x-k8s.io
+// +kubebuilder:webhook:verbs=create;update,path=/validate-bootstrap-cluster-x-k8s-io-v1alpha3-kubeadmconfig,mutating=false,failurePolicy=fail,groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigs,versions=v1alpha3,name=validation.v1alpha3.kubeadmconfig.bootstrap.cluster.x-k8s.io
+// +kubebuilder:webhook:verbs=create;update,path=/validate-bootstrap-cluster-x-k8s-io-v1alpha3-kubeadmconfigtemplate,mutating=false,failurePolicy=fail,groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigtemplates,versions=v1alpha3,name=validation.v1alpha3.kubeadmconfigtemplate.bootstrap.cluster.x-k8s.io
+
+var (
+	_ webhook.Defaulter = &KubeadmConfig{}
+	_ webhook.Defaulter = &KubeadmConfigTemplate{}
+	_ webhook.Validator = &KubeadmConfig{}
+	_ webhook.Validator = &KubeadmConfigTemplate{}
+)
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+func (c *KubeadmConfig) Default() {
+	hub := &v1alpha2.KubeadmConfig{}
+	// a config that can't be converted is rejected by the validating webhook
+	if err := c.ConvertTo(hub); err != nil {
+		return
+	}
+	hub.Default()
+
+	defaulted := &KubeadmConfig{}
+	if err := defaulted.ConvertFrom(hub); err != nil {
+		return
+	}
+	defaulted.TypeMeta = c.TypeMeta
+	*c = *defaulted
+}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (c *KubeadmConfig) ValidateCreate() error {
+	hub := &v1alpha2.KubeadmConfig{}
+	if err := c.ConvertTo(hub); err != nil {
+		return errors.Wrap(err, "failed to convert the KubeadmConfig to v1alpha2")
+	}
+	return hub.ValidateCreate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (c *KubeadmConfig) ValidateUpdate(old runtime.Object) error {
+	hub, oldHub := &v1alpha2.KubeadmConfig{}, &v1alpha2.KubeadmConfig{}
+	if err := c.ConvertTo(hub); err != nil {
+		return errors.Wrap(err, "failed to convert the KubeadmConfig to v1alpha2")
+	}
+	if oldConfig, ok := old.(*KubeadmConfig); ok {
+		if err := oldConfig.ConvertTo(oldHub); err != nil {
+			return errors.Wrap(err, "failed to convert the old KubeadmConfig to v1alpha2")
+		}
+	}
+	return hub.ValidateUpdate(oldHub)
+}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+func (t *KubeadmConfigTemplate) Default() {
+	hub := &v1alpha2.KubeadmConfigTemplate{}
+	// a template that can't be converted is rejected by the validating webhook
+	if err := t.ConvertTo(hub); err != nil {
+		return
+	}
+	hub.Default()
+
+	defaulted := &KubeadmConfigTemplate{}
+	if err := defaulted.ConvertFrom(hub); err != nil {
+		return
+	}
+	defaulted.TypeMeta = t.TypeMeta
+	*t = *defaulted
+}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (t *KubeadmConfigTemplate) ValidateCreate() error {
+	hub := &v1alpha2.KubeadmConfigTemplate{}
+	if err := t.ConvertTo(hub); err != nil {
+		return errors.Wrap(err, "failed to convert the KubeadmConfigTemplate to v1alpha2")
+	}
+	return hub.ValidateCreate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (t *KubeadmConfigTemplate) ValidateUpdate(old runtime.Object) error {
+	hub, oldHub := &v1alpha2.KubeadmConfigTemplate{}, &v1alpha2.KubeadmConfigTemplate{}
+	if err := t.ConvertTo(hub); err != nil {
+		return errors.Wrap(err, "failed to convert the KubeadmConfigTemplate to v1alpha2")
+	}
+	if oldTemplate, ok := old.(*KubeadmConfigTemplate); ok {
+		if err := oldTemplate.ConvertTo(oldHub); err != nil {
+			return errors.Wrap(err, "failed to convert the old KubeadmConfigTemplate to v1alpha2")
+		}
+	}
+	return hub.ValidateUpdate(oldHub)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	utilconversion "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/conversion"
+)
+
+func TestKubeadmConfigDefault(t *testing.T) {
+	config := &KubeadmConfig{
+		TypeMeta:   metav1.TypeMeta{Kind: "KubeadmConfig", APIVersion: GroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: "cfg", Namespace: "default"},
+	}
+	config.Default()
+	if config.Spec.JoinConfiguration == nil || config.Spec.GeneratedFilesDir != v1alpha2.DefaultGeneratedFilesDir {
+		t.Fatalf("expected the config to be defaulted as v1alpha2, got %+v", config.Spec)
+	}
+	if config.APIVersion != GroupVersion.String() {
+		t.Fatalf("expected the config to be kept as %s, got %s", GroupVersion, config.APIVersion)
+	}
+	if _, ok := config.Annotations[utilconversion.DataAnnotation]; ok {
+		t.Fatal("did not expect the conversion data annotation to be left on the config")
+	}
+}
+
+func TestKubeadmConfigValidate(t *testing.T) {
+	config := &KubeadmConfig{
+		Spec: KubeadmConfigSpec{
+			AdditionalUserDataFiles: []Files{{Path: "/etc/foo"}, {Path: "/etc/foo"}},
+		},
+	}
+	if err := config.ValidateCreate(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if err := config.ValidateUpdate(&KubeadmConfig{}); err == nil {
+		t.Fatal("expected error on update, got nil")
+	}
+
+	config.Spec.AdditionalUserDataFiles = config.Spec.AdditionalUserDataFiles[:1]
+	if err := config.ValidateCreate(); err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+}
+
+func TestKubeadmConfigTemplateValidate(t *testing.T) {
+	template := &KubeadmConfigTemplate{}
+	template.Spec.Template.Spec.AdditionalUserDataFiles = []Files{{Path: "/etc/foo"}, {Path: "/etc/foo"}}
+	if err := template.ValidateCreate(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	template.Default()
+	if template.Spec.Template.Spec.GeneratedFilesDir != v1alpha2.DefaultGeneratedFilesDir {
+		t.Fatalf("expected the template to be defaulted as v1alpha2, got %+v", template.Spec.Template.Spec)
+	}
+}
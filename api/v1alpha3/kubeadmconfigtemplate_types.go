@@ -0,0 +1,55 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KubeadmConfigTemplateSpec defines the desired state of KubeadmConfigTemplate
+type KubeadmConfigTemplateSpec struct {
+	Template KubeadmConfigTemplateResource `json:"template"`
+}
+
+// KubeadmConfigTemplateResource defines the KubeadmConfig created for each machine from the template
+type KubeadmConfigTemplateResource struct {
+	Spec KubeadmConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=kubeadmconfigtemplates,scope=Namespaced
+
+// KubeadmConfigTemplate is the Schema for the kubeadmconfigtemplates API
+type KubeadmConfigTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KubeadmConfigTemplateSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// KubeadmConfigTemplateList contains a list of KubeadmConfigTemplate
+type KubeadmConfigTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KubeadmConfigTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KubeadmConfigTemplate{}, &KubeadmConfigTemplateList{})
+}
@@ -0,0 +1,1424 @@
+//go:build !ignore_autogenerated
+// +build !ignore_autogenerated
+
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// autogenerated by controller-gen object, do not modify manually
+
+package v1alpha3
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionPluginConfiguration) DeepCopyInto(out *AdmissionPluginConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionPluginConfiguration.
+func (in *AdmissionPluginConfiguration) DeepCopy() *AdmissionPluginConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionPluginConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionPlugins) DeepCopyInto(out *AdmissionPlugins) {
+	*out = *in
+	if in.Enable != nil {
+		in, out := &in.Enable, &out.Enable
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Disable != nil {
+		in, out := &in.Disable, &out.Disable
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Configurations != nil {
+		in, out := &in.Configurations, &out.Configurations
+		*out = make([]AdmissionPluginConfiguration, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionPlugins.
+func (in *AdmissionPlugins) DeepCopy() *AdmissionPlugins {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionPlugins)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Artifact) DeepCopyInto(out *Artifact) {
+	*out = *in
+	if in.ArchitectureURLs != nil {
+		in, out := &in.ArchitectureURLs, &out.ArchitectureURLs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Cosign != nil {
+		in, out := &in.Cosign, &out.Cosign
+		*out = new(CosignSignature)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Artifact.
+func (in *Artifact) DeepCopy() *Artifact {
+	if in == nil {
+		return nil
+	}
+	out := new(Artifact)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BinaryInstall) DeepCopyInto(out *BinaryInstall) {
+	*out = *in
+	if in.SHA256 != nil {
+		in, out := &in.SHA256, &out.SHA256
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BinaryInstall.
+func (in *BinaryInstall) DeepCopy() *BinaryInstall {
+	if in == nil {
+		return nil
+	}
+	out := new(BinaryInstall)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateExpiry) DeepCopyInto(out *CertificateExpiry) {
+	*out = *in
+	in.NotAfter.DeepCopyInto(&out.NotAfter)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateExpiry.
+func (in *CertificateExpiry) DeepCopy() *CertificateExpiry {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateExpiry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterDNS) DeepCopyInto(out *ClusterDNS) {
+	*out = *in
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterDNS.
+func (in *ClusterDNS) DeepCopy() *ClusterDNS {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterDNS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneComponentConfiguration) DeepCopyInto(out *ControlPlaneComponentConfiguration) {
+	*out = *in
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]ControlPlaneComponentFile, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneComponentConfiguration.
+func (in *ControlPlaneComponentConfiguration) DeepCopy() *ControlPlaneComponentConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneComponentConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneComponentFile) DeepCopyInto(out *ControlPlaneComponentFile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneComponentFile.
+func (in *ControlPlaneComponentFile) DeepCopy() *ControlPlaneComponentFile {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneComponentFile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneHealthGate) DeepCopyInto(out *ControlPlaneHealthGate) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneHealthGate.
+func (in *ControlPlaneHealthGate) DeepCopy() *ControlPlaneHealthGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneHealthGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CosignSignature) DeepCopyInto(out *CosignSignature) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CosignSignature.
+func (in *CosignSignature) DeepCopy() *CosignSignature {
+	if in == nil {
+		return nil
+	}
+	out := new(CosignSignature)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Delivery) DeepCopyInto(out *Delivery) {
+	*out = *in
+	if in.SSH != nil {
+		in, out := &in.SSH, &out.SSH
+		*out = new(SSHDelivery)
+		**out = **in
+	}
+	if in.ObjectStorage != nil {
+		in, out := &in.ObjectStorage, &out.ObjectStorage
+		*out = new(ObjectStorageDelivery)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenOnly != nil {
+		in, out := &in.TokenOnly, &out.TokenOnly
+		*out = new(TokenOnlyDelivery)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Delivery.
+func (in *Delivery) DeepCopy() *Delivery {
+	if in == nil {
+		return nil
+	}
+	out := new(Delivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskSetup) DeepCopyInto(out *DiskSetup) {
+	*out = *in
+	if in.Partitions != nil {
+		in, out := &in.Partitions, &out.Partitions
+		*out = make([]Partition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Filesystems != nil {
+		in, out := &in.Filesystems, &out.Filesystems
+		*out = make([]Filesystem, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskSetup.
+func (in *DiskSetup) DeepCopy() *DiskSetup {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskSetup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdDisk) DeepCopyInto(out *EtcdDisk) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdDisk.
+func (in *EtcdDisk) DeepCopy() *EtcdDisk {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdDisk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EtcdTuning) DeepCopyInto(out *EtcdTuning) {
+	*out = *in
+	if in.QuotaBackendBytes != nil {
+		in, out := &in.QuotaBackendBytes, &out.QuotaBackendBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.HeartbeatInterval != nil {
+		in, out := &in.HeartbeatInterval, &out.HeartbeatInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ElectionTimeout != nil {
+		in, out := &in.ElectionTimeout, &out.ElectionTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ListenMetricsURLs != nil {
+		in, out := &in.ListenMetricsURLs, &out.ListenMetricsURLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EtcdTuning.
+func (in *EtcdTuning) DeepCopy() *EtcdTuning {
+	if in == nil {
+		return nil
+	}
+	out := new(EtcdTuning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalCAs) DeepCopyInto(out *ExternalCAs) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalCAs.
+func (in *ExternalCAs) DeepCopy() *ExternalCAs {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalCAs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileSource) DeepCopyInto(out *FileSource) {
+	*out = *in
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(FileSourceKey)
+		**out = **in
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(FileSourceKey)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileSource.
+func (in *FileSource) DeepCopy() *FileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(FileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FileSourceKey) DeepCopyInto(out *FileSourceKey) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileSourceKey.
+func (in *FileSourceKey) DeepCopy() *FileSourceKey {
+	if in == nil {
+		return nil
+	}
+	out := new(FileSourceKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Files) DeepCopyInto(out *Files) {
+	*out = *in
+	if in.ContentFrom != nil {
+		in, out := &in.ContentFrom, &out.ContentFrom
+		*out = new(FileSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Files.
+func (in *Files) DeepCopy() *Files {
+	if in == nil {
+		return nil
+	}
+	out := new(Files)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Filesystem) DeepCopyInto(out *Filesystem) {
+	*out = *in
+	if in.Overwrite != nil {
+		in, out := &in.Overwrite, &out.Overwrite
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ExtraOpts != nil {
+		in, out := &in.ExtraOpts, &out.ExtraOpts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Filesystem.
+func (in *Filesystem) DeepCopy() *Filesystem {
+	if in == nil {
+		return nil
+	}
+	out := new(Filesystem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePreflight) DeepCopyInto(out *ImagePreflight) {
+	*out = *in
+	if in.RequiredPaths != nil {
+		in, out := &in.RequiredPaths, &out.RequiredPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePreflight.
+func (in *ImagePreflight) DeepCopy() *ImagePreflight {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePreflight)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JoinRetry) DeepCopyInto(out *JoinRetry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JoinRetry.
+func (in *JoinRetry) DeepCopy() *JoinRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(JoinRetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfig) DeepCopyInto(out *KubeadmConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfig.
+func (in *KubeadmConfig) DeepCopy() *KubeadmConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KubeadmConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigCondition) DeepCopyInto(out *KubeadmConfigCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigCondition.
+func (in *KubeadmConfigCondition) DeepCopy() *KubeadmConfigCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigList) DeepCopyInto(out *KubeadmConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KubeadmConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigList.
+func (in *KubeadmConfigList) DeepCopy() *KubeadmConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KubeadmConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigPatch) DeepCopyInto(out *KubeadmConfigPatch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigPatch.
+func (in *KubeadmConfigPatch) DeepCopy() *KubeadmConfigPatch {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigPatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigSpec) DeepCopyInto(out *KubeadmConfigSpec) {
+	*out = *in
+	if in.ClusterConfiguration != nil {
+		in, out := &in.ClusterConfiguration, &out.ClusterConfiguration
+		*out = new(v1beta1.ClusterConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InitConfiguration != nil {
+		in, out := &in.InitConfiguration, &out.InitConfiguration
+		*out = new(v1beta1.InitConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JoinConfiguration != nil {
+		in, out := &in.JoinConfiguration, &out.JoinConfiguration
+		*out = new(v1beta1.JoinConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KubeadmConfigPatches != nil {
+		in, out := &in.KubeadmConfigPatches, &out.KubeadmConfigPatches
+		*out = make([]KubeadmConfigPatch, len(*in))
+		copy(*out, *in)
+	}
+	if in.KubeadmContainer != nil {
+		in, out := &in.KubeadmContainer, &out.KubeadmContainer
+		*out = new(KubeadmContainer)
+		**out = **in
+	}
+	if in.Verbosity != nil {
+		in, out := &in.Verbosity, &out.Verbosity
+		*out = new(int32)
+		**out = **in
+	}
+	if in.JoinRetry != nil {
+		in, out := &in.JoinRetry, &out.JoinRetry
+		*out = new(JoinRetry)
+		**out = **in
+	}
+	if in.KubeadmFailureReport != nil {
+		in, out := &in.KubeadmFailureReport, &out.KubeadmFailureReport
+		*out = new(KubeadmFailureReport)
+		**out = **in
+	}
+	if in.ImagePreflight != nil {
+		in, out := &in.ImagePreflight, &out.ImagePreflight
+		*out = new(ImagePreflight)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OSConditionals != nil {
+		in, out := &in.OSConditionals, &out.OSConditionals
+		*out = make([]OSConditional, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]Artifact, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BinaryInstall != nil {
+		in, out := &in.BinaryInstall, &out.BinaryInstall
+		*out = new(BinaryInstall)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PackageRepositories != nil {
+		in, out := &in.PackageRepositories, &out.PackageRepositories
+		*out = make([]PackageRepository, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TrustedCABundles != nil {
+		in, out := &in.TrustedCABundles, &out.TrustedCABundles
+		*out = make([]FileSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ControlPlaneTaints != nil {
+		in, out := &in.ControlPlaneTaints, &out.ControlPlaneTaints
+		*out = new([]corev1.Taint)
+		if **in != nil {
+			in, out := *in, *out
+			*out = make([]corev1.Taint, len(*in))
+			for i := range *in {
+				(*in)[i].DeepCopyInto(&(*out)[i])
+			}
+		}
+	}
+	if in.EtcdTuning != nil {
+		in, out := &in.EtcdTuning, &out.EtcdTuning
+		*out = new(EtcdTuning)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EtcdDisk != nil {
+		in, out := &in.EtcdDisk, &out.EtcdDisk
+		*out = new(EtcdDisk)
+		**out = **in
+	}
+	if in.DiskSetup != nil {
+		in, out := &in.DiskSetup, &out.DiskSetup
+		*out = new(DiskSetup)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Mounts != nil {
+		in, out := &in.Mounts, &out.Mounts
+		*out = make([]MountPoints, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make(MountPoints, len(*in))
+				copy(*out, *in)
+			}
+		}
+	}
+	if in.KubeletTuning != nil {
+		in, out := &in.KubeletTuning, &out.KubeletTuning
+		*out = new(KubeletTuning)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterDNS != nil {
+		in, out := &in.ClusterDNS, &out.ClusterDNS
+		*out = new(ClusterDNS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NTP != nil {
+		in, out := &in.NTP, &out.NTP
+		*out = new(NTP)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RegistryMirrors != nil {
+		in, out := &in.RegistryMirrors, &out.RegistryMirrors
+		*out = make([]RegistryMirror, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RegistryCredentials != nil {
+		in, out := &in.RegistryCredentials, &out.RegistryCredentials
+		*out = make([]RegistryCredentials, len(*in))
+		copy(*out, *in)
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]User, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StartupTaint != nil {
+		in, out := &in.StartupTaint, &out.StartupTaint
+		*out = new(StartupTaint)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetainedIdentity != nil {
+		in, out := &in.RetainedIdentity, &out.RetainedIdentity
+		*out = new(RetainedIdentity)
+		**out = **in
+	}
+	if in.PodSecurity != nil {
+		in, out := &in.PodSecurity, &out.PodSecurity
+		*out = new(PodSecurity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdmissionPlugins != nil {
+		in, out := &in.AdmissionPlugins, &out.AdmissionPlugins
+		*out = new(AdmissionPlugins)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SchedulerConfiguration != nil {
+		in, out := &in.SchedulerConfiguration, &out.SchedulerConfiguration
+		*out = new(ControlPlaneComponentConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ControllerManagerConfiguration != nil {
+		in, out := &in.ControllerManagerConfiguration, &out.ControllerManagerConfiguration
+		*out = new(ControlPlaneComponentConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExternalCAs != nil {
+		in, out := &in.ExternalCAs, &out.ExternalCAs
+		*out = new(ExternalCAs)
+		**out = **in
+	}
+	if in.Variants != nil {
+		in, out := &in.Variants, &out.Variants
+		*out = make([]KubeadmConfigVariant, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdditionalUserDataFiles != nil {
+		in, out := &in.AdditionalUserDataFiles, &out.AdditionalUserDataFiles
+		*out = make([]Files, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Multipart != nil {
+		in, out := &in.Multipart, &out.Multipart
+		*out = new(Multipart)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreKubeadmCommands != nil {
+		in, out := &in.PreKubeadmCommands, &out.PreKubeadmCommands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostKubeadmCommands != nil {
+		in, out := &in.PostKubeadmCommands, &out.PostKubeadmCommands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ControlPlaneHealthGate != nil {
+		in, out := &in.ControlPlaneHealthGate, &out.ControlPlaneHealthGate
+		*out = new(ControlPlaneHealthGate)
+		**out = **in
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(Delivery)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigSpec.
+func (in *KubeadmConfigSpec) DeepCopy() *KubeadmConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigStatus) DeepCopyInto(out *KubeadmConfigStatus) {
+	*out = *in
+	if in.BootstrapData != nil {
+		in, out := &in.BootstrapData, &out.BootstrapData
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.DataSecretName != nil {
+		in, out := &in.DataSecretName, &out.DataSecretName
+		*out = new(string)
+		**out = **in
+	}
+	if in.JoinCommandSecretName != nil {
+		in, out := &in.JoinCommandSecretName, &out.JoinCommandSecretName
+		*out = new(string)
+		**out = **in
+	}
+	if in.CertificatesExpiry != nil {
+		in, out := &in.CertificatesExpiry, &out.CertificatesExpiry
+		*out = (*in).DeepCopy()
+	}
+	if in.CertificateExpiries != nil {
+		in, out := &in.CertificateExpiries, &out.CertificateExpiries
+		*out = make([]CertificateExpiry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]KubeadmConfigCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigStatus.
+func (in *KubeadmConfigStatus) DeepCopy() *KubeadmConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigTemplate) DeepCopyInto(out *KubeadmConfigTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigTemplate.
+func (in *KubeadmConfigTemplate) DeepCopy() *KubeadmConfigTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KubeadmConfigTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigTemplateList) DeepCopyInto(out *KubeadmConfigTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KubeadmConfigTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigTemplateList.
+func (in *KubeadmConfigTemplateList) DeepCopy() *KubeadmConfigTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KubeadmConfigTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigTemplateResource) DeepCopyInto(out *KubeadmConfigTemplateResource) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigTemplateResource.
+func (in *KubeadmConfigTemplateResource) DeepCopy() *KubeadmConfigTemplateResource {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigTemplateResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigTemplateSpec) DeepCopyInto(out *KubeadmConfigTemplateSpec) {
+	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigTemplateSpec.
+func (in *KubeadmConfigTemplateSpec) DeepCopy() *KubeadmConfigTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmConfigVariant) DeepCopyInto(out *KubeadmConfigVariant) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmConfigVariant.
+func (in *KubeadmConfigVariant) DeepCopy() *KubeadmConfigVariant {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmConfigVariant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmContainer) DeepCopyInto(out *KubeadmContainer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmContainer.
+func (in *KubeadmContainer) DeepCopy() *KubeadmContainer {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmContainer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeadmFailureReport) DeepCopyInto(out *KubeadmFailureReport) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeadmFailureReport.
+func (in *KubeadmFailureReport) DeepCopy() *KubeadmFailureReport {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeadmFailureReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletEviction) DeepCopyInto(out *KubeletEviction) {
+	*out = *in
+	if in.Hard != nil {
+		in, out := &in.Hard, &out.Hard
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Soft != nil {
+		in, out := &in.Soft, &out.Soft
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SoftGracePeriod != nil {
+		in, out := &in.SoftGracePeriod, &out.SoftGracePeriod
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MinimumReclaim != nil {
+		in, out := &in.MinimumReclaim, &out.MinimumReclaim
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PressureTransitionPeriod != nil {
+		in, out := &in.PressureTransitionPeriod, &out.PressureTransitionPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletEviction.
+func (in *KubeletEviction) DeepCopy() *KubeletEviction {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletEviction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletGracefulShutdown) DeepCopyInto(out *KubeletGracefulShutdown) {
+	*out = *in
+	out.GracePeriod = in.GracePeriod
+	if in.CriticalPodsGracePeriod != nil {
+		in, out := &in.CriticalPodsGracePeriod, &out.CriticalPodsGracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletGracefulShutdown.
+func (in *KubeletGracefulShutdown) DeepCopy() *KubeletGracefulShutdown {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletGracefulShutdown)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeletTuning) DeepCopyInto(out *KubeletTuning) {
+	*out = *in
+	if in.GracefulShutdown != nil {
+		in, out := &in.GracefulShutdown, &out.GracefulShutdown
+		*out = new(KubeletGracefulShutdown)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Eviction != nil {
+		in, out := &in.Eviction, &out.Eviction
+		*out = new(KubeletEviction)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeletTuning.
+func (in *KubeletTuning) DeepCopy() *KubeletTuning {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeletTuning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in MountPoints) DeepCopyInto(out *MountPoints) {
+	{
+		in := &in
+		*out = make(MountPoints, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MountPoints.
+func (in MountPoints) DeepCopy() MountPoints {
+	if in == nil {
+		return nil
+	}
+	out := new(MountPoints)
+	in.DeepCopyInto(out)
+	return *out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Multipart) DeepCopyInto(out *Multipart) {
+	*out = *in
+	if in.Parts != nil {
+		in, out := &in.Parts, &out.Parts
+		*out = make([]UserDataPart, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Multipart.
+func (in *Multipart) DeepCopy() *Multipart {
+	if in == nil {
+		return nil
+	}
+	out := new(Multipart)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NTP) DeepCopyInto(out *NTP) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NTP.
+func (in *NTP) DeepCopy() *NTP {
+	if in == nil {
+		return nil
+	}
+	out := new(NTP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OSConditional) DeepCopyInto(out *OSConditional) {
+	*out = *in
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]Files, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PreKubeadmCommands != nil {
+		in, out := &in.PreKubeadmCommands, &out.PreKubeadmCommands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OSConditional.
+func (in *OSConditional) DeepCopy() *OSConditional {
+	if in == nil {
+		return nil
+	}
+	out := new(OSConditional)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorageDelivery) DeepCopyInto(out *ObjectStorageDelivery) {
+	*out = *in
+	if in.URLExpiry != nil {
+		in, out := &in.URLExpiry, &out.URLExpiry
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorageDelivery.
+func (in *ObjectStorageDelivery) DeepCopy() *ObjectStorageDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorageDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PackageRepository) DeepCopyInto(out *PackageRepository) {
+	*out = *in
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PackageRepository.
+func (in *PackageRepository) DeepCopy() *PackageRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(PackageRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Partition) DeepCopyInto(out *Partition) {
+	*out = *in
+	if in.Overwrite != nil {
+		in, out := &in.Overwrite, &out.Overwrite
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Partition.
+func (in *Partition) DeepCopy() *Partition {
+	if in == nil {
+		return nil
+	}
+	out := new(Partition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodSecurity) DeepCopyInto(out *PodSecurity) {
+	*out = *in
+	if in.ExemptUsernames != nil {
+		in, out := &in.ExemptUsernames, &out.ExemptUsernames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExemptNamespaces != nil {
+		in, out := &in.ExemptNamespaces, &out.ExemptNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExemptRuntimeClasses != nil {
+		in, out := &in.ExemptRuntimeClasses, &out.ExemptRuntimeClasses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodSecurity.
+func (in *PodSecurity) DeepCopy() *PodSecurity {
+	if in == nil {
+		return nil
+	}
+	out := new(PodSecurity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Proxy) DeepCopyInto(out *Proxy) {
+	*out = *in
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proxy.
+func (in *Proxy) DeepCopy() *Proxy {
+	if in == nil {
+		return nil
+	}
+	out := new(Proxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCredentials) DeepCopyInto(out *RegistryCredentials) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCredentials.
+func (in *RegistryCredentials) DeepCopy() *RegistryCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryMirror) DeepCopyInto(out *RegistryMirror) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryMirror.
+func (in *RegistryMirror) DeepCopy() *RegistryMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetainedIdentity) DeepCopyInto(out *RetainedIdentity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetainedIdentity.
+func (in *RetainedIdentity) DeepCopy() *RetainedIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(RetainedIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SSHDelivery) DeepCopyInto(out *SSHDelivery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SSHDelivery.
+func (in *SSHDelivery) DeepCopy() *SSHDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(SSHDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StartupTaint) DeepCopyInto(out *StartupTaint) {
+	*out = *in
+	if in.VerificationCommands != nil {
+		in, out := &in.VerificationCommands, &out.VerificationCommands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StartupTaint.
+func (in *StartupTaint) DeepCopy() *StartupTaint {
+	if in == nil {
+		return nil
+	}
+	out := new(StartupTaint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TokenOnlyDelivery) DeepCopyInto(out *TokenOnlyDelivery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TokenOnlyDelivery.
+func (in *TokenOnlyDelivery) DeepCopy() *TokenOnlyDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(TokenOnlyDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *User) DeepCopyInto(out *User) {
+	*out = *in
+	if in.SSHAuthorizedKeys != nil {
+		in, out := &in.SSHAuthorizedKeys, &out.SSHAuthorizedKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LockPassword != nil {
+		in, out := &in.LockPassword, &out.LockPassword
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new User.
+func (in *User) DeepCopy() *User {
+	if in == nil {
+		return nil
+	}
+	out := new(User)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserDataPart) DeepCopyInto(out *UserDataPart) {
+	*out = *in
+	if in.ContentFrom != nil {
+		in, out := &in.ContentFrom, &out.ContentFrom
+		*out = new(FileSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserDataPart.
+func (in *UserDataPart) DeepCopy() *UserDataPart {
+	if in == nil {
+		return nil
+	}
+	out := new(UserDataPart)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package certs
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -32,6 +33,9 @@ import (
 const (
 	rsaKeySize   = 2048
 	duration365d = time.Hour * 24 * 365
+
+	// DefaultCAValidity is how long generated CA certificates are valid by default, the same as kubeadm.
+	DefaultCAValidity = duration365d * 10
 )
 
 // Certificates hold all the certificates necessary for a Kubernetes cluster
@@ -45,19 +49,25 @@ type Certificates struct {
 // NewCertificates generates all the necessary CAs and KeyPairs for a Kubernetes cluster.
 // nil values for the parameters will generate new KeyPairs, the same as if kubeadm generated them.
 func NewCertificates() (*Certificates, error) {
-	cluster, err := generateCACert()
+	return NewCertificatesWithOptions(Options{})
+}
+
+// NewCertificatesWithOptions generates all the necessary CAs and KeyPairs for a Kubernetes cluster, customized by
+// opts.
+func NewCertificatesWithOptions(opts Options) (*Certificates, error) {
+	cluster, err := generateCACert(opts)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create cluster CA")
 	}
-	etcd, err := generateCACert()
+	etcd, err := generateCACert(opts)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create Etcd CA")
 	}
-	frontProxy, err := generateCACert()
+	frontProxy, err := generateCACert(opts)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create frontproxy CA")
 	}
-	serviceAccount, err := generateServiceAccountKeys()
+	serviceAccount, err := generateServiceAccountKeys(opts)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create service account key pair")
 	}
@@ -86,31 +96,48 @@ type AltNames struct {
 // KeyPair holds the raw bytes for a certificate and key
 type KeyPair struct {
 	Cert, Key []byte
+
+	// Next is the CA introduced to replace this one while its rotation is in progress.
+	Next *KeyPair
+	// Previous is the certificate of the CA this one replaced, trusted until it is retired.
+	Previous []byte
 }
 
-func generateCACert() (*KeyPair, error) {
-	x509Cert, privKey, err := NewCertificateAuthority()
+func generateCACert(opts Options) (*KeyPair, error) {
+	privKey, err := opts.KeyType.newPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	x509Cert, err := newSelfSignedCACert(privKey, opts.caValidity())
+	if err != nil {
+		return nil, err
+	}
+	key, err := EncodeKeyPEM(privKey)
 	if err != nil {
 		return nil, err
 	}
 	return &KeyPair{
 		Cert: EncodeCertPEM(x509Cert),
-		Key:  EncodePrivateKeyPEM(privKey),
+		Key:  key,
 	}, nil
 }
 
-func generateServiceAccountKeys() (*KeyPair, error) {
-	saCreds, err := NewPrivateKey()
+func generateServiceAccountKeys(opts Options) (*KeyPair, error) {
+	saCreds, err := opts.KeyType.newPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	saPub, err := EncodePublicKeyPEM(saCreds.Public())
 	if err != nil {
 		return nil, err
 	}
-	saPub, err := EncodePublicKeyPEM(&saCreds.PublicKey)
+	key, err := EncodeKeyPEM(saCreds)
 	if err != nil {
 		return nil, err
 	}
 	return &KeyPair{
 		Cert: saPub,
-		Key:  EncodePrivateKeyPEM(saCreds),
+		Key:  key,
 	}, nil
 }
 
@@ -138,7 +165,11 @@ func NewCertificateAuthority() (*x509.Certificate, *rsa.PrivateKey, error) {
 }
 
 // NewSelfSignedCACert creates a CA certificate.
-func NewSelfSignedCACert(key *rsa.PrivateKey) (*x509.Certificate, error) {
+func NewSelfSignedCACert(key crypto.Signer) (*x509.Certificate, error) {
+	return newSelfSignedCACert(key, DefaultCAValidity)
+}
+
+func newSelfSignedCACert(key crypto.Signer, validity time.Duration) (*x509.Certificate, error) {
 	cfg := Config{
 		CommonName: "kubernetes",
 	}
@@ -152,13 +183,17 @@ func NewSelfSignedCACert(key *rsa.PrivateKey) (*x509.Certificate, error) {
 			Organization: cfg.Organization,
 		},
 		NotBefore:             now,
-		NotAfter:              now.Add(duration365d * 10),
+		NotAfter:              now.Add(validity),
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
 		MaxPathLenZero:        true,
 		BasicConstraintsValid: true,
 		MaxPathLen:            0,
 		IsCA:                  true,
 	}
+	// key encipherment only applies to RSA keys
+	if _, ok := key.(*rsa.PrivateKey); !ok {
+		tmpl.KeyUsage &^= x509.KeyUsageKeyEncipherment
+	}
 
 	b, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, key.Public(), key)
 	if err != nil {
@@ -189,7 +224,7 @@ func EncodePrivateKeyPEM(key *rsa.PrivateKey) []byte {
 }
 
 // EncodePublicKeyPEM returns PEM-encoded public key data.
-func EncodePublicKeyPEM(key *rsa.PublicKey) ([]byte, error) {
+func EncodePublicKeyPEM(key crypto.PublicKey) ([]byte, error) {
 	der, err := x509.MarshalPKIXPublicKey(key)
 	if err != nil {
 		return []byte{}, errors.WithStack(err)
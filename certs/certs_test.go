@@ -17,7 +17,9 @@ limitations under the License.
 package certs
 
 import (
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -85,3 +87,97 @@ func TestNewCertificateAuthority(t *testing.T) {
 		}
 	}
 }
+
+func TestExpiries(t *testing.T) {
+	c, err := NewCertificates()
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+
+	expiries, err := c.Expiries()
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	for _, name := range []string{"ca", "etcd-ca", "front-proxy-ca"} {
+		if _, ok := expiries[name]; !ok {
+			t.Fatalf("expected the expiry of the %s certificate, got %v", name, expiries)
+		}
+	}
+
+	c.EtcdCA.Cert = []byte("not a certificate")
+	if _, err := c.Expiries(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestValidateCA(t *testing.T) {
+	ca, err := generateCACert(Options{})
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+	if err := ca.ValidateCA(); err != nil {
+		t.Fatalf("expected a valid CA, got %v", err)
+	}
+
+	other, err := generateCACert(Options{})
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+	if err := (&KeyPair{Cert: ca.Cert, Key: other.Key}).ValidateCA(); err == nil {
+		t.Fatal("expected an error for a key not matching the certificate")
+	}
+	if err := (&KeyPair{Cert: []byte("not a certificate"), Key: ca.Key}).ValidateCA(); err == nil {
+		t.Fatal("expected an error for a certificate that is not PEM encoded")
+	}
+	if err := (&KeyPair{Cert: ca.Cert}).ValidateCA(); err == nil {
+		t.Fatal("expected an error without a key")
+	}
+
+	sa, err := generateServiceAccountKeys(Options{})
+	if err != nil {
+		t.Fatalf("failed to generate service account keys: %v", err)
+	}
+	if err := sa.ValidateCA(); err == nil {
+		t.Fatal("expected an error for a public key that is not a certificate")
+	}
+}
+
+func TestValidateDistinctCAs(t *testing.T) {
+	c, err := NewCertificates()
+	if err != nil {
+		t.Fatalf("failed to generate certificates: %v", err)
+	}
+	if err := c.ValidateDistinctCAs(); err != nil {
+		t.Fatalf("expected distinct CAs, got %v", err)
+	}
+
+	c.FrontProxyCA = c.ClusterCA
+	if err := c.ValidateDistinctCAs(); err == nil {
+		t.Fatal("expected an error for a front proxy CA shared with the cluster CA")
+	}
+}
+
+func TestPublicKeyHash(t *testing.T) {
+	cert, key, err := NewCertificateAuthority()
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+	kp := &KeyPair{Cert: EncodeCertPEM(cert), Key: EncodePrivateKeyPEM(key)}
+
+	hash, err := kp.PublicKeyHash()
+	if err != nil {
+		t.Fatalf("failed to hash the public key: %v", err)
+	}
+	spki, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal the public key: %v", err)
+	}
+	sum := sha256.Sum256(spki)
+	if expected := "sha256:" + hex.EncodeToString(sum[:]); hash != expected {
+		t.Fatalf("expected %s, got %s", expected, hash)
+	}
+
+	if _, err := (&KeyPair{Cert: []byte("not a certificate")}).PublicKeyHash(); err == nil {
+		t.Fatal("expected an error for a certificate that is not PEM encoded")
+	}
+}
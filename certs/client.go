@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewClientCertificate returns a client certificate for the subject of cfg, signed by the CA of the key pair and
+// valid for the given duration, along with its private key.
+func (kp *KeyPair) NewClientCertificate(cfg Config, validity time.Duration) (*KeyPair, error) {
+	ca, err := tls.X509KeyPair(kp.Cert, kp.Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the CA key pair")
+	}
+	caCert, err := kp.parseCertificate()
+	if err != nil {
+		return nil, err
+	}
+	key, err := NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	now := time.Now().UTC()
+	tmpl := x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   cfg.CommonName,
+			Organization: cfg.Organization,
+		},
+		NotBefore:   now,
+		NotAfter:    now.Add(validity),
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	b, err := x509.CreateCertificate(rand.Reader, &tmpl, caCert, key.Public(), ca.PrivateKey)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create client certificate for %s", cfg.CommonName)
+	}
+	cert, err := x509.ParseCertificate(b)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &KeyPair{
+		Cert: EncodeCertPEM(cert),
+		Key:  EncodePrivateKeyPEM(key),
+	}, nil
+}
+
+// SignedBy reports whether the certificate of the key pair is signed by the CA of the given key pair.
+func (kp *KeyPair) SignedBy(ca *KeyPair) bool {
+	cert, err := kp.parseCertificate()
+	if err != nil {
+		return false
+	}
+	caCert, err := ca.parseCertificate()
+	if err != nil {
+		return false
+	}
+	return cert.CheckSignatureFrom(caCert) == nil
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestNewClientCertificate(t *testing.T) {
+	c, err := NewCertificates()
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+
+	kp, err := c.ClusterCA.NewClientCertificate(Config{CommonName: "system:node:worker-0", Organization: []string{"system:nodes"}}, time.Hour)
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if _, err := tls.X509KeyPair(kp.Cert, kp.Key); err != nil {
+		t.Fatalf("expected the private key to match the certificate, got %v", err)
+	}
+	cert, err := kp.parseCertificate()
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if cert.Subject.CommonName != "system:node:worker-0" || len(cert.Subject.Organization) != 1 || cert.Subject.Organization[0] != "system:nodes" {
+		t.Fatalf("unexpected subject %v", cert.Subject)
+	}
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+		t.Fatalf("expected a client certificate, got usages %v", cert.ExtKeyUsage)
+	}
+	if cert.NotAfter.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("expected the certificate to expire within an hour, got %v", cert.NotAfter)
+	}
+
+	if !kp.SignedBy(c.ClusterCA) {
+		t.Fatal("expected the certificate to be signed by the cluster CA")
+	}
+	if kp.SignedBy(c.EtcdCA) {
+		t.Fatal("expected the certificate not to be signed by the etcd CA")
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NotAfter returns the expiry of the certificate of the key pair.
+func (kp *KeyPair) NotAfter() (time.Time, error) {
+	cert, err := kp.parseCertificate()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// parseCertificate decodes the PEM encoded certificate of the key pair.
+func (kp *KeyPair) parseCertificate() (*x509.Certificate, error) {
+	block, _ := pem.Decode(kp.Cert)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("failed to decode PEM encoded certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificate")
+	}
+	return cert, nil
+}
+
+// Expiries returns the expiry of the CA certificates, keyed by the kubeadm name of the certificate.
+func (c *Certificates) Expiries() (map[string]time.Time, error) {
+	expiries := map[string]time.Time{}
+	for name, kp := range map[string]*KeyPair{
+		"ca":             c.ClusterCA,
+		"etcd-ca":        c.EtcdCA,
+		"front-proxy-ca": c.FrontProxyCA,
+	} {
+		notAfter, err := kp.NotAfter()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get the expiry of the %s certificate", name)
+		}
+		expiries[name] = notAfter
+	}
+	return expiries, nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+// IsExternal returns true if only the certificate of kp is held, its private key being kept by an external PKI.
+func (kp *KeyPair) IsExternal() bool {
+	return len(kp.Cert) > 0 && len(kp.Key) == 0
+}
+
+// ExternalClusterCA returns true if the cluster CA is external. kubeadm then runs in external CA mode: the CA key
+// is not written on the machines, so the certificates and kubeconfig files it signs must be provided instead.
+func (c *Certificates) ExternalClusterCA() bool {
+	return c.ClusterCA.IsExternal()
+}
+
+// Complete generates the etcd CA, front proxy CA and service account key pairs missing from c, customized by
+// opts, as when only an external cluster CA is provided. The cluster CA itself is never generated. It returns
+// true if any key pair was generated.
+func (c *Certificates) Complete(opts Options) (bool, error) {
+	generated := false
+	for _, ca := range []**KeyPair{&c.EtcdCA, &c.FrontProxyCA} {
+		if !isMissing(*ca) {
+			continue
+		}
+		kp, err := generateCACert(opts)
+		if err != nil {
+			return false, err
+		}
+		*ca, generated = kp, true
+	}
+	if isMissing(c.ServiceAccount) {
+		kp, err := generateServiceAccountKeys(opts)
+		if err != nil {
+			return false, err
+		}
+		c.ServiceAccount, generated = kp, true
+	}
+	return generated, nil
+}
+
+func isMissing(kp *KeyPair) bool {
+	return kp == nil || len(kp.Cert) == 0 && len(kp.Key) == 0
+}
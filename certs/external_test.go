@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExternalClusterCA(t *testing.T) {
+	ca, err := generateCACert(Options{})
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	c := NewCertificatesFromMap(map[string][]byte{clusterCACertificate: ca.Cert})
+	if !c.ExternalClusterCA() {
+		t.Fatal("expected a cluster CA without key to be external")
+	}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected the certificates to be incomplete before being completed")
+	}
+
+	generated, err := c.Complete(Options{})
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if !generated {
+		t.Fatal("expected the missing key pairs to be generated")
+	}
+	if !bytes.Equal(c.ClusterCA.Cert, ca.Cert) || len(c.ClusterCA.Key) != 0 {
+		t.Fatal("expected the external cluster CA to be kept without key")
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if generated, err := c.Complete(Options{}); err != nil || generated {
+		t.Fatalf("expected complete certificates to be left unchanged, got %v, %v", generated, err)
+	}
+
+	for _, f := range CertificatesToFiles(*c) {
+		if f.Path == "/etc/kubernetes/pki/ca.key" {
+			t.Fatal("expected the key of the external cluster CA not to be written")
+		}
+	}
+	if err := c.Rotate(CARotationIntroduce); err == nil {
+		t.Fatal("expected the rotation of an external CA to fail")
+	}
+}
+
+func TestCertificatesToFilesWritesClusterCAKey(t *testing.T) {
+	c, err := NewCertificates()
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if c.ExternalClusterCA() {
+		t.Fatal("expected a generated cluster CA not to be external")
+	}
+	files := CertificatesToFiles(*c)
+	if len(files) != 8 || files[1].Path != "/etc/kubernetes/pki/ca.key" {
+		t.Fatalf("expected the 8 certificate files including the cluster CA key, got %v", files)
+	}
+}
@@ -22,26 +22,31 @@ const (
 	rootOwnerValue = "root:root"
 )
 
-// CertificatesToFiles writes Certificates to files
+// CertificatesToFiles writes Certificates to files. The key of an external cluster CA is left out, so that
+// kubeadm runs in external CA mode.
 func CertificatesToFiles(input Certificates) []v1alpha2.Files {
-	return []v1alpha2.Files{
+	files := []v1alpha2.Files{
 		{
 			Path:        "/etc/kubernetes/pki/ca.crt",
 			Owner:       rootOwnerValue,
 			Permissions: "0640",
-			Content:     string(input.ClusterCA.Cert),
+			Content:     string(input.ClusterCA.TrustBundle()),
 		},
-		{
+	}
+	if !input.ExternalClusterCA() {
+		files = append(files, v1alpha2.Files{
 			Path:        "/etc/kubernetes/pki/ca.key",
 			Owner:       rootOwnerValue,
 			Permissions: "0600",
 			Content:     string(input.ClusterCA.Key),
-		},
+		})
+	}
+	return append(files, []v1alpha2.Files{
 		{
 			Path:        "/etc/kubernetes/pki/etcd/ca.crt",
 			Owner:       rootOwnerValue,
 			Permissions: "0640",
-			Content:     string(input.EtcdCA.Cert),
+			Content:     string(input.EtcdCA.TrustBundle()),
 		},
 		{
 			Path:        "/etc/kubernetes/pki/etcd/ca.key",
@@ -53,7 +58,7 @@ func CertificatesToFiles(input Certificates) []v1alpha2.Files {
 			Path:        "/etc/kubernetes/pki/front-proxy-ca.crt",
 			Owner:       rootOwnerValue,
 			Permissions: "0640",
-			Content:     string(input.FrontProxyCA.Cert),
+			Content:     string(input.FrontProxyCA.TrustBundle()),
 		},
 		{
 			Path:        "/etc/kubernetes/pki/front-proxy-ca.key",
@@ -73,5 +78,5 @@ func CertificatesToFiles(input Certificates) []v1alpha2.Files {
 			Permissions: "0600",
 			Content:     string(input.ServiceAccount.Key),
 		},
-	}
+	}...)
 }
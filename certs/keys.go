@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// KeyType is the type of the private keys of generated key pairs.
+type KeyType string
+
+const (
+	// RSA2048KeyType generates 2048 bits RSA keys.
+	RSA2048KeyType KeyType = "rsa-2048"
+	// RSA3072KeyType generates 3072 bits RSA keys.
+	RSA3072KeyType KeyType = "rsa-3072"
+	// RSA4096KeyType generates 4096 bits RSA keys.
+	RSA4096KeyType KeyType = "rsa-4096"
+	// ECDSAP256KeyType generates ECDSA keys on the P-256 curve.
+	ECDSAP256KeyType KeyType = "ecdsa-p256"
+
+	// DefaultKeyType is the type of the keys generated by default, the same as kubeadm generates.
+	DefaultKeyType = RSA2048KeyType
+)
+
+// KeyTypes are the supported key types.
+var KeyTypes = []KeyType{RSA2048KeyType, RSA3072KeyType, RSA4096KeyType, ECDSAP256KeyType}
+
+// Options customize the generated key pairs.
+type Options struct {
+	// KeyType is the type of the generated keys, DefaultKeyType if empty.
+	KeyType KeyType
+	// CAValidity is how long the generated CA certificates are valid, DefaultCAValidity if zero.
+	CAValidity time.Duration
+}
+
+func (opts Options) caValidity() time.Duration {
+	if opts.CAValidity == 0 {
+		return DefaultCAValidity
+	}
+	return opts.CAValidity
+}
+
+// ParseKeyType returns the key type named s, DefaultKeyType if s is empty.
+func ParseKeyType(s string) (KeyType, error) {
+	if s == "" {
+		return DefaultKeyType, nil
+	}
+	for _, kt := range KeyTypes {
+		if KeyType(s) == kt {
+			return kt, nil
+		}
+	}
+	names := make([]string, len(KeyTypes))
+	for i, kt := range KeyTypes {
+		names[i] = string(kt)
+	}
+	return "", errors.Errorf("unknown key type %q, expected one of %s", s, strings.Join(names, ", "))
+}
+
+func (kt KeyType) newPrivateKey() (crypto.Signer, error) {
+	switch kt {
+	case "", RSA2048KeyType:
+		return NewPrivateKey()
+	case RSA3072KeyType:
+		pk, err := rsa.GenerateKey(rand.Reader, 3072)
+		return pk, errors.WithStack(err)
+	case RSA4096KeyType:
+		pk, err := rsa.GenerateKey(rand.Reader, 4096)
+		return pk, errors.WithStack(err)
+	case ECDSAP256KeyType:
+		pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		return pk, errors.WithStack(err)
+	}
+	return nil, errors.Errorf("unknown key type %q", kt)
+}
+
+// EncodeKeyPEM returns PEM-encoded RSA or ECDSA private key data.
+func EncodeKeyPEM(key crypto.Signer) ([]byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return EncodePrivateKeyPEM(k), nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	}
+	return nil, errors.Errorf("unsupported private key type %T", key)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestNewCertificatesWithKeyType(t *testing.T) {
+	testCases := []struct {
+		keyType KeyType
+		check   func(pub interface{}) bool
+	}{
+		{RSA3072KeyType, func(pub interface{}) bool {
+			k, ok := pub.(*rsa.PublicKey)
+			return ok && k.N.BitLen() == 3072
+		}},
+		{ECDSAP256KeyType, func(pub interface{}) bool {
+			k, ok := pub.(*ecdsa.PublicKey)
+			return ok && k.Curve.Params().Name == "P-256"
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.keyType), func(t *testing.T) {
+			c, err := NewCertificatesWithOptions(Options{KeyType: tc.keyType})
+			if err != nil {
+				t.Fatalf("error should be nil but is %v", err)
+			}
+			if err := c.Validate(); err != nil {
+				t.Fatalf("error should be nil but is %v", err)
+			}
+			for name, kp := range map[string]*KeyPair{"CA": c.ClusterCA, "ETCD CA": c.EtcdCA, "FrontProxy CA": c.FrontProxyCA} {
+				if err := kp.ValidateCA(); err != nil {
+					t.Fatalf("invalid %s: %v", name, err)
+				}
+				cert, err := kp.parseCertificate()
+				if err != nil {
+					t.Fatalf("error should be nil but is %v", err)
+				}
+				if !tc.check(cert.PublicKey) {
+					t.Fatalf("expected the %s key to be %s, got %T", name, tc.keyType, cert.PublicKey)
+				}
+			}
+			if _, err := c.ClusterCA.NewClientCertificate(Config{CommonName: "client"}, time.Hour); err != nil {
+				t.Fatalf("expected the CA to sign client certificates, got %v", err)
+			}
+			if err := c.RotateWithOptions(CARotationIntroduce, Options{KeyType: tc.keyType}); err != nil {
+				t.Fatalf("error should be nil but is %v", err)
+			}
+			if err := c.ClusterCA.Next.ValidateCA(); err != nil {
+				t.Fatalf("invalid next CA: %v", err)
+			}
+		})
+	}
+}
+
+func TestECDSACAKeyUsage(t *testing.T) {
+	c, err := NewCertificatesWithOptions(Options{KeyType: ECDSAP256KeyType})
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	cert, err := c.ClusterCA.parseCertificate()
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if cert.KeyUsage&x509.KeyUsageKeyEncipherment != 0 {
+		t.Fatal("expected an ECDSA CA not to allow key encipherment")
+	}
+}
+
+func TestParseKeyType(t *testing.T) {
+	if kt, err := ParseKeyType(""); err != nil || kt != DefaultKeyType {
+		t.Fatalf("expected the default key type, got %q, %v", kt, err)
+	}
+	if kt, err := ParseKeyType("ecdsa-p256"); err != nil || kt != ECDSAP256KeyType {
+		t.Fatalf("expected ecdsa-p256, got %q, %v", kt, err)
+	}
+	if _, err := ParseKeyType("dsa"); err == nil {
+		t.Fatal("expected an unknown key type to fail")
+	}
+}
+
+func TestNewCertificatesWithCAValidity(t *testing.T) {
+	c, err := NewCertificatesWithOptions(Options{CAValidity: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	notAfter, err := c.ClusterCA.NotAfter()
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if d := time.Until(notAfter); d > 48*time.Hour || d < 47*time.Hour {
+		t.Fatalf("expected the CA to be valid for 48h, got %s", d)
+	}
+
+	c, err = NewCertificates()
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if notAfter, _ = c.EtcdCA.NotAfter(); time.Until(notAfter) < DefaultCAValidity-time.Hour {
+		t.Fatalf("expected the CA to be valid for %s by default, got %s", DefaultCAValidity, time.Until(notAfter))
+	}
+}
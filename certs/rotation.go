@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+// CARotationPhase is a step of the rotation of the CAs of a cluster.
+type CARotationPhase string
+
+const (
+	// CARotationIntroduce generates the next CAs and adds them to the trust bundles, while the current CAs
+	// keep signing.
+	CARotationIntroduce CARotationPhase = "introduce"
+	// CARotationRegenerate makes the next CAs sign the bootstrap material of new machines, while the previous
+	// CAs stay in the trust bundles.
+	CARotationRegenerate CARotationPhase = "regenerate"
+	// CARotationRetire removes the previous CAs from the trust bundles.
+	CARotationRetire CARotationPhase = "retire"
+)
+
+// TrustBundle returns the PEM encoded certificates trusted for the CA: its own certificate first, followed by
+// the certificates of the next and previous CAs while a rotation is in progress.
+func (kp *KeyPair) TrustBundle() []byte {
+	if kp.Next == nil && kp.Previous == nil {
+		return kp.Cert
+	}
+	bundle := [][]byte{kp.Cert}
+	if kp.Next != nil {
+		bundle = append(bundle, kp.Next.Cert)
+	}
+	if kp.Previous != nil {
+		bundle = append(bundle, kp.Previous)
+	}
+	for i, cert := range bundle {
+		bundle[i] = bytes.TrimRight(cert, "\n")
+	}
+	return append(bytes.Join(bundle, []byte("\n")), '\n')
+}
+
+// Rotate applies a phase of the rotation to the cluster, etcd and front proxy CAs. Phases must be applied in
+// order: introduce, regenerate, then retire.
+func (c *Certificates) Rotate(phase CARotationPhase) error {
+	return c.RotateWithOptions(phase, Options{})
+}
+
+// RotateWithOptions applies a phase of the rotation as Rotate does, the CAs introduced being customized by opts.
+func (c *Certificates) RotateWithOptions(phase CARotationPhase, opts Options) error {
+	for name, kp := range c.cas() {
+		if err := kp.rotate(phase, opts); err != nil {
+			return errors.Wrapf(err, "failed to %s %s", phase, name)
+		}
+	}
+	return nil
+}
+
+func (kp *KeyPair) rotate(phase CARotationPhase, opts Options) error {
+	switch phase {
+	case CARotationIntroduce:
+		if kp.IsExternal() {
+			return errors.New("an external CA cannot be rotated")
+		}
+		if kp.Next != nil || kp.Previous != nil {
+			return errors.New("a rotation is already in progress")
+		}
+		next, err := generateCACert(opts)
+		if err != nil {
+			return err
+		}
+		kp.Next = next
+	case CARotationRegenerate:
+		if kp.Next == nil {
+			return errors.New("no next CA has been introduced")
+		}
+		kp.Previous = kp.Cert
+		kp.Cert, kp.Key = kp.Next.Cert, kp.Next.Key
+		kp.Next = nil
+	case CARotationRetire:
+		if kp.Previous == nil {
+			return errors.New("no previous CA is left to retire")
+		}
+		kp.Previous = nil
+	default:
+		return errors.Errorf("unknown rotation phase %q", phase)
+	}
+	return nil
+}
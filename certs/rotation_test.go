@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRotate(t *testing.T) {
+	c, err := NewCertificates()
+	if err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	original := c.ClusterCA.Cert
+
+	if err := c.Rotate(CARotationRegenerate); err == nil {
+		t.Fatal("expected regenerate to fail before introduce")
+	}
+
+	if err := c.Rotate(CARotationIntroduce); err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if !bytes.Equal(c.ClusterCA.Cert, original) {
+		t.Fatal("expected the current CA to keep signing once the next CA is introduced")
+	}
+	next := c.ClusterCA.Next.Cert
+	if bundle := c.ClusterCA.TrustBundle(); !bytes.Contains(bundle, original) || !bytes.Contains(bundle, next) {
+		t.Fatalf("expected the trust bundle to hold the current and next CAs, got %s", bundle)
+	}
+	if err := c.Rotate(CARotationIntroduce); err == nil {
+		t.Fatal("expected introduce to fail while a rotation is in progress")
+	}
+
+	// the rotation state survives a round trip through the secret data
+	c = NewCertificatesFromMap(c.ToMap())
+	if err := c.Rotate(CARotationRegenerate); err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if !bytes.Equal(c.ClusterCA.Cert, next) {
+		t.Fatal("expected the next CA to sign once regenerated")
+	}
+	if bundle := c.ClusterCA.TrustBundle(); !bytes.HasPrefix(bundle, next) || !bytes.Contains(bundle, original) {
+		t.Fatalf("expected the trust bundle to start with the new CA and hold the previous one, got %s", bundle)
+	}
+
+	c = NewCertificatesFromMap(c.ToMap())
+	if err := c.Rotate(CARotationRetire); err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	if bundle := c.ClusterCA.TrustBundle(); !bytes.Equal(bundle, next) {
+		t.Fatalf("expected the trust bundle to only hold the new CA, got %s", bundle)
+	}
+	if _, ok := c.ToMap()["cluster-ca-previous-cert"]; ok {
+		t.Fatal("expected the previous CA to be removed from the secret data")
+	}
+	if err := c.Rotate(CARotationRetire); err == nil {
+		t.Fatal("expected retire to fail without a previous CA")
+	}
+}
@@ -25,6 +25,11 @@ const (
 	frontProxyCACertificate  = "front-proxy-ca-cert"
 	serviceAccountPublicKey  = "service-account-public-key"
 	serviceAccountPrivateKey = "service-account-private-key"
+
+	// suffixes of the keys of the next and previous CAs while a rotation is in progress
+	nextKeySuffix             = "-next-key"
+	nextCertificateSuffix     = "-next-cert"
+	previousCertificateSuffix = "-previous-cert"
 )
 
 // NewCertificatesFromMap creates Certificates from a map
@@ -60,13 +65,23 @@ func NewCertificatesFromMap(m map[string][]byte) *Certificates {
 	if val, ok := m[serviceAccountPublicKey]; ok {
 		certs.ServiceAccount.Cert = val
 	}
+	for name, kp := range certs.cas() {
+		if val, ok := m[name+previousCertificateSuffix]; ok {
+			kp.Previous = val
+		}
+		key, hasKey := m[name+nextKeySuffix]
+		cert, hasCert := m[name+nextCertificateSuffix]
+		if hasKey || hasCert {
+			kp.Next = &KeyPair{Cert: cert, Key: key}
+		}
+	}
 
 	return certs
 }
 
 // ToMap converts Certificates into a map
 func (c *Certificates) ToMap() map[string][]byte {
-	return map[string][]byte{
+	m := map[string][]byte{
 		clusterCAKey:             c.ClusterCA.Key,
 		clusterCACertificate:     c.ClusterCA.Cert,
 		etcdCAKey:                c.EtcdCA.Key,
@@ -76,5 +91,23 @@ func (c *Certificates) ToMap() map[string][]byte {
 		serviceAccountPrivateKey: c.ServiceAccount.Key,
 		serviceAccountPublicKey:  c.ServiceAccount.Cert,
 	}
+	for name, kp := range c.cas() {
+		if kp.Next != nil {
+			m[name+nextKeySuffix] = kp.Next.Key
+			m[name+nextCertificateSuffix] = kp.Next.Cert
+		}
+		if kp.Previous != nil {
+			m[name+previousCertificateSuffix] = kp.Previous
+		}
+	}
+	return m
+}
 
+// cas returns the CAs that can be rotated, by the prefix of their keys.
+func (c *Certificates) cas() map[string]*KeyPair {
+	return map[string]*KeyPair{
+		"cluster-ca":     c.ClusterCA,
+		"etcd-ca":        c.EtcdCA,
+		"front-proxy-ca": c.FrontProxyCA,
+	}
 }
@@ -16,11 +16,16 @@ limitations under the License.
 
 package certs
 
-import "errors"
+import (
+	"bytes"
+	"crypto/tls"
 
-// Validate checks that all KeyPairs are valid
+	"github.com/pkg/errors"
+)
+
+// Validate checks that all KeyPairs are valid. The cluster CA may be external, without its key.
 func (c *Certificates) Validate() error {
-	if !c.ClusterCA.isValid() {
+	if !c.ClusterCA.isValid() && !c.ClusterCA.IsExternal() {
 		return errors.New("CA cert material is missing cert/key")
 	}
 
@@ -42,3 +47,48 @@ func (c *Certificates) Validate() error {
 func (kp *KeyPair) isValid() bool {
 	return kp.Cert != nil && kp.Key != nil
 }
+
+// ValidateCA checks that kp is a PEM encoded CA certificate along with its private key.
+func (kp *KeyPair) ValidateCA() error {
+	if !kp.isValid() {
+		return errors.New("cert material is missing cert/key")
+	}
+	cert, err := kp.parseCertificate()
+	if err != nil {
+		return err
+	}
+	if !cert.IsCA {
+		return errors.New("certificate is not a CA")
+	}
+	if _, err := tls.X509KeyPair(kp.Cert, kp.Key); err != nil {
+		return errors.Wrap(err, "private key does not match the certificate")
+	}
+	return nil
+}
+
+// ValidateDistinctCAs checks that the cluster, etcd and front proxy CAs have distinct keys, so that a certificate
+// signed by one of them is not trusted by the components relying on the others.
+func (c *Certificates) ValidateDistinctCAs() error {
+	cas := []struct {
+		name string
+		kp   *KeyPair
+	}{
+		{"CA", c.ClusterCA},
+		{"ETCD CA", c.EtcdCA},
+		{"FrontProxy CA", c.FrontProxyCA},
+	}
+	keys := make([][]byte, len(cas))
+	for i, ca := range cas {
+		cert, err := ca.kp.parseCertificate()
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s", ca.name)
+		}
+		keys[i] = cert.RawSubjectPublicKeyInfo
+		for j := 0; j < i; j++ {
+			if bytes.Equal(keys[i], keys[j]) {
+				return errors.Errorf("%s and %s share the same key", cas[j].name, ca.name)
+			}
+		}
+	}
+	return nil
+}
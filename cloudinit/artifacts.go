@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// download defines a file downloaded on the machine, and how it is verified.
+type download struct {
+	path        string
+	url         string
+	permissions string
+
+	// sha256 is the expected sha256 checksum of the file, if any.
+	sha256 string
+
+	// cosignKey is the path of the public key the file must be signed with, if any, and signatureURL the URL of
+	// its signature.
+	cosignKey    string
+	signatureURL string
+}
+
+// commands returns the commands downloading the file. The bootstrap is stopped if the file does not match its
+// checksum or signature.
+func (d download) commands() []string {
+	commands := []string{
+		fmt.Sprintf(`mkdir -p "%s"`, path.Dir(d.path)),
+		fmt.Sprintf(`curl -fsSL --retry 5 -o "%s" "%s"`, d.path, d.url),
+	}
+	if d.sha256 != "" {
+		commands = append(commands, fmt.Sprintf(`echo "%s  %s" | sha256sum -c - || exit 1`, d.sha256, d.path))
+	}
+	if d.cosignKey != "" {
+		signatureURL := d.signatureURL
+		if signatureURL == "" {
+			signatureURL = d.url + ".sig"
+		}
+		commands = append(commands, fmt.Sprintf(`cosign verify-blob --key "%s" --signature "%s" "%s" || exit 1`,
+			d.cosignKey, signatureURL, d.path))
+	}
+	if d.permissions != "" {
+		commands = append(commands, fmt.Sprintf(`chmod %s "%s"`, d.permissions, d.path))
+	}
+	return commands
+}
+
+// artifactCosignKeyPath returns where the cosign public key of the i-th artifact is written.
+func (b *BaseUserData) artifactCosignKeyPath(i int) string {
+	return path.Join(b.GeneratedFilesDir, "cosign", fmt.Sprintf("artifact-%d.pub", i))
+}
+
+// artifactURL returns the URL an artifact is downloaded from on machines of the given architecture.
+func artifactURL(artifact v1alpha2.Artifact, architecture string) (string, error) {
+	if url, ok := artifact.ArchitectureURLs[architecture]; ok {
+		return url, nil
+	}
+	if artifact.URL == "" {
+		return "", errors.Errorf("artifact %s has no URL for architecture %s", artifact.Path, architecture)
+	}
+	return artifact.URL, nil
+}
+
+// artifactCommands returns the commands downloading the artifacts for the architecture of the machine.
+func (b *BaseUserData) artifactCommands() ([]string, error) {
+	architecture := b.Architecture
+	if architecture == "" {
+		architecture = v1alpha2.DefaultArchitecture
+	}
+
+	var commands []string
+	for i, artifact := range b.Artifacts {
+		url, err := artifactURL(artifact, architecture)
+		if err != nil {
+			return nil, err
+		}
+		d := download{path: artifact.Path, url: url, permissions: artifact.Permissions, sha256: artifact.SHA256}
+		if artifact.Cosign != nil {
+			d.cosignKey = b.artifactCosignKeyPath(i)
+			d.signatureURL = artifact.Cosign.SignatureURL
+		}
+		commands = append(commands, d.commands()...)
+	}
+	return commands, nil
+}
+
+// artifactFiles returns the cosign public keys the artifacts are verified with.
+func (b *BaseUserData) artifactFiles() []v1alpha2.Files {
+	var files []v1alpha2.Files
+	for i, artifact := range b.Artifacts {
+		if artifact.Cosign == nil {
+			continue
+		}
+		files = append(files, v1alpha2.Files{
+			Path:        b.artifactCosignKeyPath(i),
+			Owner:       rootOwnerValue,
+			Permissions: "0644",
+			Content:     artifact.Cosign.PublicKey,
+		})
+	}
+	return files
+}
+
+// setArtifacts prepends the artifact downloads to the commands run before kubeadm, so the other commands, and
+// the image preflight, can rely on them.
+func (b *BaseUserData) setArtifacts() error {
+	commands, err := b.artifactCommands()
+	if err != nil {
+		return err
+	}
+	b.PreKubeadmCommands = append(commands, b.PreKubeadmCommands...)
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	kubeletUnitPath          = "/etc/systemd/system/kubelet.service"
+	kubeletKubeadmDropInPath = "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf"
+
+	// kubeletUnit and kubeletKubeadmDropIn are the ones shipped by the Kubernetes deb and rpm packages.
+	kubeletUnit = `[Unit]
+Description=kubelet: The Kubernetes Node Agent
+Documentation=https://kubernetes.io/docs/home/
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+ExecStart=%[1]s/kubelet
+Restart=always
+StartLimitInterval=0
+RestartSec=10
+
+[Install]
+WantedBy=multi-user.target
+`
+
+	kubeletKubeadmDropIn = `[Service]
+Environment="KUBELET_KUBECONFIG_ARGS=--bootstrap-kubeconfig=/etc/kubernetes/bootstrap-kubelet.conf --kubeconfig=/etc/kubernetes/kubelet.conf"
+Environment="KUBELET_CONFIG_ARGS=--config=/var/lib/kubelet/config.yaml"
+EnvironmentFile=-/var/lib/kubelet/kubeadm-flags.env
+EnvironmentFile=-/etc/default/kubelet
+ExecStart=
+ExecStart=%[1]s/kubelet $KUBELET_KUBECONFIG_ARGS $KUBELET_CONFIG_ARGS $KUBELET_KUBEADM_ARGS $KUBELET_EXTRA_ARGS
+`
+)
+
+// binaryInstallDir returns the directory the Kubernetes binaries are installed to.
+func (b *BaseUserData) binaryInstallDir() string {
+	if b.BinaryInstall.InstallDir == "" {
+		return v1alpha2.DefaultBinaryInstallDir
+	}
+	return path.Clean(b.BinaryInstall.InstallDir)
+}
+
+// binaryInstallCosignKeyPath returns where the cosign public key the binaries are verified with is written.
+func (b *BaseUserData) binaryInstallCosignKeyPath() string {
+	return path.Join(b.GeneratedFilesDir, "cosign", "binaries.pub")
+}
+
+// binaryInstallCommands returns the commands downloading the Kubernetes binaries and enabling the kubelet.
+func (b *BaseUserData) binaryInstallCommands() ([]string, error) {
+	version := b.BinaryInstall.Version
+	if version == "" {
+		version = b.KubernetesVersion
+	}
+	if version == "" {
+		return nil, errors.New("binaryInstall requires a version, either in binaryInstall or the Machine")
+	}
+	version = normalizeVersion(version)
+
+	baseURL := strings.TrimSuffix(b.BinaryInstall.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = v1alpha2.DefaultBinaryInstallBaseURL
+	}
+
+	architecture := b.Architecture
+	if architecture == "" {
+		architecture = v1alpha2.DefaultArchitecture
+	}
+
+	binaries := []string{"kubelet", "kubectl"}
+	if b.KubeadmContainer == nil {
+		binaries = append([]string{"kubeadm"}, binaries...)
+	}
+
+	var commands []string
+	for _, binary := range binaries {
+		url := fmt.Sprintf("%s/%s/bin/linux/%s/%s", baseURL, version, architecture, binary)
+		d := download{
+			path:        path.Join(b.binaryInstallDir(), binary),
+			url:         url,
+			permissions: "0755",
+			sha256:      b.BinaryInstall.SHA256[architecture+"/"+binary],
+		}
+		if b.BinaryInstall.CosignPublicKey != "" {
+			d.cosignKey = b.binaryInstallCosignKeyPath()
+		}
+		commands = append(commands, d.commands()...)
+	}
+	return append(commands, "systemctl daemon-reload", "systemctl enable kubelet"), nil
+}
+
+// setBinaryInstall prepends the installation of the Kubernetes binaries to the commands run before kubeadm.
+func (b *BaseUserData) setBinaryInstall() error {
+	if b.BinaryInstall == nil {
+		return nil
+	}
+
+	commands, err := b.binaryInstallCommands()
+	if err != nil {
+		return err
+	}
+	b.PreKubeadmCommands = append(commands, b.PreKubeadmCommands...)
+	if b.KubeadmContainer == nil {
+		b.KubeadmCommand = path.Join(b.binaryInstallDir(), defaultKubeadmCommand)
+	}
+	return nil
+}
+
+// binaryInstallFiles returns the kubelet systemd unit and its kubeadm drop-in, and the cosign public key the
+// binaries are verified with, if the binaries are installed.
+func (b *BaseUserData) binaryInstallFiles() []v1alpha2.Files {
+	if b.BinaryInstall == nil {
+		return nil
+	}
+
+	unit := b.BinaryInstall.KubeletUnit
+	if unit == "" {
+		unit = fmt.Sprintf(kubeletUnit, b.binaryInstallDir())
+	}
+	files := []v1alpha2.Files{
+		{
+			Path:        kubeletUnitPath,
+			Owner:       rootOwnerValue,
+			Permissions: "0644",
+			Content:     unit,
+		},
+		{
+			Path:        kubeletKubeadmDropInPath,
+			Owner:       rootOwnerValue,
+			Permissions: "0644",
+			Content:     fmt.Sprintf(kubeletKubeadmDropIn, b.binaryInstallDir()),
+		},
+	}
+	if b.BinaryInstall.CosignPublicKey != "" {
+		files = append(files, v1alpha2.Files{
+			Path:        b.binaryInstallCosignKeyPath(),
+			Owner:       rootOwnerValue,
+			Permissions: "0644",
+			Content:     b.BinaryInstall.CosignPublicKey,
+		})
+	}
+	return files
+}
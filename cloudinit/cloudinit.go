@@ -18,7 +18,10 @@ package cloudinit
 
 import (
 	"bytes"
+	"fmt"
+	"path"
 	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"strings"
 	"text/template"
 
 	"github.com/pkg/errors"
@@ -28,14 +31,143 @@ const (
 	cloudConfigHeader = `## template: jinja
 #cloud-config
 `
+
+	defaultKubeadmCommand = "kubeadm"
 )
 
-// BaseUserData is shared across all the various types of files written to disk.
+// kubeadmContainerMounts are the host paths kubeadm reads or writes when it runs in a container.
+var kubeadmContainerMounts = []string{
+	"/etc/kubernetes",
+	"/var/lib/kubelet",
+	"/var/lib/etcd",
+	"/run",
+}
+
+// BaseUserData is shared across all the various types of files written to disk. Manifest is set once the user
+// data is generated.
 type BaseUserData struct {
-	Header             string
-	AdditionalCommands []string
-	AdditionalFiles    []v1alpha2.Files
-	WriteFiles         []v1alpha2.Files
+	Header              string
+	GeneratedFilesDir   string
+	KubeadmContainer    *v1alpha2.KubeadmContainer
+	KubeadmCommand      string
+	KubeadmFlags        string
+	JoinRetry           *v1alpha2.JoinRetry
+	JoinCommand         string
+	BootstrapCommand    string
+	KeepKubeadmOutput   bool
+	KubeadmFailureHook  string
+	KubernetesVersion   string
+	Verbosity           *int32
+	ImagePreflight      *v1alpha2.ImagePreflight
+	OSConditionals      []v1alpha2.OSConditional
+	Architecture        string
+	Artifacts           []v1alpha2.Artifact
+	BinaryInstall       *v1alpha2.BinaryInstall
+	PackageRepositories []v1alpha2.PackageRepository
+	TrustedCABundles    []string
+	HardeningProfile    v1alpha2.HardeningProfile
+	Proxy               *v1alpha2.Proxy
+	RegistryMirrors     []v1alpha2.RegistryMirror
+	RegistryCredentials []RegistryCredential
+	NTP                 *v1alpha2.NTP
+	Users               []v1alpha2.User
+	TraceID             string
+	EtcdDisk            *v1alpha2.EtcdDisk
+	DiskSetup           *v1alpha2.DiskSetup
+	Mounts              []v1alpha2.MountPoints
+	PreKubeadmCommands  []string
+	AdditionalCommands  []string
+	AdditionalFiles     []v1alpha2.Files
+	WriteFiles          []v1alpha2.Files
+	ExtraCloudConfig    string
+	Multipart           *v1alpha2.Multipart
+	Manifest            *Manifest
+}
+
+// setDefaults defaults the directory where generated files are written and
+// the commands run before and to invoke kubeadm, and the flags kubeadm init or join run with.
+func (b *BaseUserData) setDefaults() error {
+	if b.GeneratedFilesDir == "" {
+		b.GeneratedFilesDir = v1alpha2.DefaultGeneratedFilesDir
+	}
+	b.GeneratedFilesDir = path.Clean(b.GeneratedFilesDir)
+	b.KubeadmCommand = defaultKubeadmCommand
+	if b.Verbosity != nil {
+		b.KubeadmFlags = fmt.Sprintf(" --v=%d", *b.Verbosity)
+	}
+	if err := b.validateTraceID(); err != nil {
+		return err
+	}
+
+	b.setImagePreflight()
+	if err := b.setBinaryInstall(); err != nil {
+		return err
+	}
+	if err := b.setArtifacts(); err != nil {
+		return err
+	}
+	b.setPackageRepositories()
+	b.setTrustedCABundles()
+	b.setOSConditionals()
+	b.setHardeningProfile()
+	b.setRegistries()
+	b.setProxy()
+	b.setNTP()
+	b.setEtcdDisk()
+	b.setDiskSetup()
+	b.setExtraCloudConfig()
+
+	if b.KubeadmContainer == nil {
+		return nil
+	}
+
+	mounts := append([]string{}, kubeadmContainerMounts...)
+	if !isUnder(b.GeneratedFilesDir, mounts) {
+		mounts = append(mounts, b.GeneratedFilesDir)
+	}
+
+	image := b.KubeadmContainer.Image
+	switch b.KubeadmContainer.Runtime {
+	case v1alpha2.KubeadmContainerRuntimePodman:
+		args := []string{"podman", "run", "--rm", "--privileged", "--net=host", "--pid=host", "--ipc=host", "--uts=host"}
+		for _, m := range mounts {
+			args = append(args, fmt.Sprintf("--volume=%s:%s", m, m))
+		}
+		b.KubeadmCommand = strings.Join(append(args, image, defaultKubeadmCommand), " ")
+	default:
+		// ctr does not pull images on run, so pull it right before kubeadm is invoked.
+		b.PreKubeadmCommands = append(b.PreKubeadmCommands, fmt.Sprintf("ctr images pull %s", image))
+		args := []string{"ctr", "run", "--rm", "--privileged", "--net-host"}
+		for _, m := range mounts {
+			args = append(args, fmt.Sprintf("--mount=type=bind,src=%s,dst=%s,options=rbind:rw", m, m))
+		}
+		b.KubeadmCommand = strings.Join(append(args, image, defaultKubeadmCommand, defaultKubeadmCommand), " ")
+	}
+	return nil
+}
+
+// generatedFiles returns the files the user data writes, besides the kubeadm configuration and certificates.
+func (b *BaseUserData) generatedFiles() ([]v1alpha2.Files, error) {
+	files, err := b.imagePreflightFiles()
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, b.binaryInstallFiles()...)
+	files = append(files, b.artifactFiles()...)
+	files = append(files, b.hardeningFiles()...)
+	files = append(files, b.proxyFiles()...)
+	files = append(files, b.registryMirrorFiles()...)
+	return append(files, b.osConditionalFiles()...), nil
+}
+
+// isUnder returns whether dir is one of, or nested within, the given directories.
+func isUnder(dir string, dirs []string) bool {
+	for _, d := range dirs {
+		if dir == d || strings.HasPrefix(dir, d+"/") {
+			return true
+		}
+	}
+	return false
 }
 
 func generate(kind string, tpl string, data interface{}) ([]byte, error) {
@@ -48,6 +180,30 @@ func generate(kind string, tpl string, data interface{}) ([]byte, error) {
 		return nil, errors.Wrap(err, "failed to parse commands template")
 	}
 
+	if _, err := tm.Parse(ntpTemplate); err != nil {
+		return nil, errors.Wrap(err, "failed to parse ntp template")
+	}
+
+	if _, err := tm.Parse(usersTemplate); err != nil {
+		return nil, errors.Wrap(err, "failed to parse users template")
+	}
+
+	if _, err := tm.Parse(diskSetupTemplate); err != nil {
+		return nil, errors.Wrap(err, "failed to parse disk setup template")
+	}
+
+	if _, err := tm.Parse(traceOutputTemplate); err != nil {
+		return nil, errors.Wrap(err, "failed to parse trace output template")
+	}
+
+	if _, err := tm.Parse(traceCommandTemplate); err != nil {
+		return nil, errors.Wrap(err, "failed to parse trace command template")
+	}
+
+	if _, err := tm.Parse(extraCloudConfigTemplate); err != nil {
+		return nil, errors.Wrap(err, "failed to parse extra cloud-config template")
+	}
+
 	t, err := tm.Parse(tpl)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to parse %s template", kind)
@@ -0,0 +1,1266 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+)
+
+func TestKubeadmConfigurationFiles(t *testing.T) {
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("failed to generate certificates: %v", err)
+	}
+
+	testcases := []struct {
+		name            string
+		generate        func() ([]byte, error)
+		expectedPath    string
+		expectedCommand string
+	}{
+		{
+			name: "init control plane",
+			generate: func() ([]byte, error) {
+				return NewInitControlPlane(&ControlPlaneInput{Certificates: *certificates})
+			},
+			expectedPath:    "/run/kubeadm/kubeadm-init.yaml",
+			expectedCommand: "kubeadm init --config /run/kubeadm/kubeadm-init.yaml",
+		},
+		{
+			name: "join control plane",
+			generate: func() ([]byte, error) {
+				return NewJoinControlPlane(&ControlPlaneJoinInput{Certificates: *certificates})
+			},
+			expectedPath:    "/run/kubeadm/kubeadm-join.yaml",
+			expectedCommand: "kubeadm join --config /run/kubeadm/kubeadm-join.yaml",
+		},
+		{
+			name: "init control plane with custom generated files directory",
+			generate: func() ([]byte, error) {
+				return NewInitControlPlane(&ControlPlaneInput{
+					BaseUserData: BaseUserData{GeneratedFilesDir: "/var/lib/kubeadm/"},
+					Certificates: *certificates,
+				})
+			},
+			expectedPath:    "/var/lib/kubeadm/kubeadm-init.yaml",
+			expectedCommand: "kubeadm init --config /var/lib/kubeadm/kubeadm-init.yaml",
+		},
+		{
+			name: "join node with custom generated files directory",
+			generate: func() ([]byte, error) {
+				return NewNode(&NodeInput{
+					BaseUserData: BaseUserData{GeneratedFilesDir: "/var/lib/kubeadm"},
+				})
+			},
+			expectedPath:    "/var/lib/kubeadm/kubeadm-join.yaml",
+			expectedCommand: "kubeadm join --config /var/lib/kubeadm/kubeadm-join.yaml",
+		},
+		{
+			name: "join node",
+			generate: func() ([]byte, error) {
+				return NewNode(&NodeInput{})
+			},
+			expectedPath:    "/run/kubeadm/kubeadm-join.yaml",
+			expectedCommand: "kubeadm join --config /run/kubeadm/kubeadm-join.yaml",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := tc.generate()
+			if err != nil {
+				t.Fatalf("failed to generate user data: %v", err)
+			}
+			if !strings.Contains(string(out), "path: "+tc.expectedPath+"\n") {
+				t.Fatalf("expected user data to write %s, got:\n%s", tc.expectedPath, string(out))
+			}
+			if !strings.Contains(string(out), "- '"+tc.expectedCommand+"'") {
+				t.Fatalf("expected user data to run %q, got:\n%s", tc.expectedCommand, string(out))
+			}
+		})
+	}
+}
+
+func TestKubeadmContainer(t *testing.T) {
+	testcases := []struct {
+		name             string
+		container        *v1alpha2.KubeadmContainer
+		expectedCommands []string
+	}{
+		{
+			name:             "host binary",
+			expectedCommands: []string{"kubeadm join --config /run/kubeadm/kubeadm-join.yaml"},
+		},
+		{
+			name:      "ctr",
+			container: &v1alpha2.KubeadmContainer{Image: "example.com/kubeadm:v1.15.3"},
+			expectedCommands: []string{
+				"ctr images pull example.com/kubeadm:v1.15.3",
+				"ctr run --rm --privileged --net-host " +
+					"--mount=type=bind,src=/etc/kubernetes,dst=/etc/kubernetes,options=rbind:rw " +
+					"--mount=type=bind,src=/var/lib/kubelet,dst=/var/lib/kubelet,options=rbind:rw " +
+					"--mount=type=bind,src=/var/lib/etcd,dst=/var/lib/etcd,options=rbind:rw " +
+					"--mount=type=bind,src=/run,dst=/run,options=rbind:rw " +
+					"example.com/kubeadm:v1.15.3 kubeadm kubeadm join --config /run/kubeadm/kubeadm-join.yaml",
+			},
+		},
+		{
+			name: "podman",
+			container: &v1alpha2.KubeadmContainer{
+				Image:   "example.com/kubeadm:v1.15.3",
+				Runtime: v1alpha2.KubeadmContainerRuntimePodman,
+			},
+			expectedCommands: []string{
+				"podman run --rm --privileged --net=host --pid=host --ipc=host --uts=host " +
+					"--volume=/etc/kubernetes:/etc/kubernetes --volume=/var/lib/kubelet:/var/lib/kubelet " +
+					"--volume=/var/lib/etcd:/var/lib/etcd --volume=/run:/run " +
+					"example.com/kubeadm:v1.15.3 kubeadm join --config /run/kubeadm/kubeadm-join.yaml",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := NewNode(&NodeInput{BaseUserData: BaseUserData{KubeadmContainer: tc.container}})
+			if err != nil {
+				t.Fatalf("failed to generate user data: %v", err)
+			}
+			for _, command := range tc.expectedCommands {
+				if !strings.Contains(string(out), "- '"+command+"'") {
+					t.Fatalf("expected user data to run %q, got:\n%s", command, string(out))
+				}
+			}
+		})
+	}
+}
+
+func TestKubeadmContainerMountsGeneratedFilesDir(t *testing.T) {
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			GeneratedFilesDir: "/var/lib/kubeadm",
+			KubeadmContainer: &v1alpha2.KubeadmContainer{
+				Image:   "example.com/kubeadm:v1.15.3",
+				Runtime: v1alpha2.KubeadmContainerRuntimePodman,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	if !strings.Contains(string(out), "--volume=/var/lib/kubeadm:/var/lib/kubeadm ") {
+		t.Fatalf("expected the generated files directory to be mounted, got:\n%s", string(out))
+	}
+}
+
+func TestImagePreflight(t *testing.T) {
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			KubernetesVersion:  "1.15.3",
+			ImagePreflight:     &v1alpha2.ImagePreflight{ContainerdVersion: "1.2"},
+			PreKubeadmCommands: []string{"echo pre"},
+			AdditionalFiles:    []v1alpha2.Files{{Path: "/etc/example", Content: "example"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+
+	for _, expected := range []string{
+		"path: /etc/example\n",
+		"path: /run/kubeadm/image-preflight.sh\n",
+		"runcmd:\n  - 'sh /run/kubeadm/image-preflight.sh || exit 1'\n  - 'echo pre'\n",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+		}
+	}
+}
+
+func TestImagePreflightScript(t *testing.T) {
+	b := &BaseUserData{
+		GeneratedFilesDir: "/run/kubeadm",
+		KubernetesVersion: "v1.15.3",
+		ImagePreflight:    &v1alpha2.ImagePreflight{RequiredPaths: []string{"/opt/cni/bin"}},
+		KubeadmContainer:  &v1alpha2.KubeadmContainer{Image: "example.com/kubeadm:v1.15.3"},
+	}
+	files, err := b.imagePreflightFiles()
+	if err != nil {
+		t.Fatalf("failed to generate image preflight: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected one file, got %d", len(files))
+	}
+
+	script := files[0].Content
+	for _, expected := range []string{
+		"failed=/run/kubeadm/image-preflight.failed\n",
+		"check_version kubelet v1.15.3 kubelet --version\n",
+		"check_command containerd\n",
+		"check_path \"/opt/cni/bin\"\n",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Fatalf("expected script to contain %q, got:\n%s", expected, script)
+		}
+	}
+	if strings.Contains(script, "kubeadm version") {
+		t.Fatalf("expected kubeadm not to be checked when run from a container, got:\n%s", script)
+	}
+}
+
+func TestOSConditionals(t *testing.T) {
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			PreKubeadmCommands: []string{"echo pre"},
+			OSConditionals: []v1alpha2.OSConditional{
+				{
+					OSFamily:           v1alpha2.OSFamilyDebian,
+					Files:              []v1alpha2.Files{{Path: "/etc/apt/apt.conf.d/90proxy", Content: "proxy"}},
+					PreKubeadmCommands: []string{"apt-get update"},
+				},
+				{
+					OSFamily:           v1alpha2.OSFamilyRHEL,
+					PreKubeadmCommands: []string{"setenforce 0"},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+
+	for _, expected := range []string{
+		"path: /run/kubeadm/os-family.sh\n",
+		"path: /run/kubeadm/os/debian/etc/apt/apt.conf.d/90proxy\n",
+		"  - 'echo pre'\n" +
+			`  - 'if [ "$(sh /run/kubeadm/os-family.sh)" = "debian" ]; then mkdir -p /etc/apt/apt.conf.d && mv /run/kubeadm/os/debian/etc/apt/apt.conf.d/90proxy /etc/apt/apt.conf.d/90proxy; fi'` + "\n" +
+			`  - 'if [ "$(sh /run/kubeadm/os-family.sh)" = "debian" ]; then eval ''apt-get update''; fi'` + "\n" +
+			`  - 'if [ "$(sh /run/kubeadm/os-family.sh)" = "rhel" ]; then eval ''setenforce 0''; fi'` + "\n" +
+			"  - 'kubeadm join",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+		}
+	}
+	if strings.Contains(string(out), "path: /etc/apt/apt.conf.d/90proxy\n") {
+		t.Fatalf("expected conditional files not to be written in place, got:\n%s", string(out))
+	}
+}
+
+func TestArtifacts(t *testing.T) {
+	artifacts := []v1alpha2.Artifact{
+		{
+			Path:        "/opt/bin/crictl",
+			URL:         "https://example.com/amd64/crictl",
+			Permissions: "0755",
+			ArchitectureURLs: map[string]string{
+				"arm64": "https://example.com/arm64/crictl",
+			},
+		},
+	}
+
+	testcases := []struct {
+		name         string
+		architecture string
+		expectedURL  string
+	}{
+		{
+			name:        "default architecture",
+			expectedURL: "https://example.com/amd64/crictl",
+		},
+		{
+			name:         "architecture URL",
+			architecture: "arm64",
+			expectedURL:  "https://example.com/arm64/crictl",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := NewNode(&NodeInput{
+				BaseUserData: BaseUserData{
+					Architecture:   tc.architecture,
+					Artifacts:      artifacts,
+					ImagePreflight: &v1alpha2.ImagePreflight{},
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to generate user data: %v", err)
+			}
+
+			expected := "runcmd:\n" +
+				`  - 'mkdir -p "/opt/bin"'` + "\n" +
+				`  - 'curl -fsSL --retry 5 -o "/opt/bin/crictl" "` + tc.expectedURL + `"'` + "\n" +
+				`  - 'chmod 0755 "/opt/bin/crictl"'` + "\n" +
+				"  - 'sh /run/kubeadm/image-preflight.sh || exit 1'\n"
+			if !strings.Contains(string(out), expected) {
+				t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+			}
+		})
+	}
+}
+
+func TestArtifactsWithoutArchitectureURL(t *testing.T) {
+	_, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			Architecture: "arm64",
+			Artifacts: []v1alpha2.Artifact{
+				{Path: "/opt/bin/crictl", ArchitectureURLs: map[string]string{"amd64": "https://example.com/amd64/crictl"}},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestBinaryInstall(t *testing.T) {
+	sha256 := strings.Repeat("a", 64)
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			KubernetesVersion: "1.15.3",
+			Architecture:      "arm64",
+			BinaryInstall: &v1alpha2.BinaryInstall{
+				InstallDir: "/opt/bin",
+				SHA256:     map[string]string{"arm64/kubelet": sha256},
+			},
+			ImagePreflight: &v1alpha2.ImagePreflight{},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+
+	for _, expected := range []string{
+		"path: /etc/systemd/system/kubelet.service\n",
+		"path: /etc/systemd/system/kubelet.service.d/10-kubeadm.conf\n",
+		"runcmd:\n" +
+			`  - 'mkdir -p "/opt/bin"'` + "\n" +
+			`  - 'curl -fsSL --retry 5 -o "/opt/bin/kubeadm" "https://storage.googleapis.com/kubernetes-release/release/v1.15.3/bin/linux/arm64/kubeadm"'` + "\n" +
+			`  - 'chmod 0755 "/opt/bin/kubeadm"'` + "\n",
+		`  - 'curl -fsSL --retry 5 -o "/opt/bin/kubelet" "https://storage.googleapis.com/kubernetes-release/release/v1.15.3/bin/linux/arm64/kubelet"'` + "\n" +
+			`  - 'echo "` + sha256 + `  /opt/bin/kubelet" | sha256sum -c - || exit 1'` + "\n",
+		"  - 'systemctl daemon-reload'\n  - 'systemctl enable kubelet'\n  - 'sh /run/kubeadm/image-preflight.sh || exit 1'\n",
+		"  - '/opt/bin/kubeadm join --config /run/kubeadm/kubeadm-join.yaml'",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+		}
+	}
+}
+
+func TestBinaryInstallFiles(t *testing.T) {
+	b := &BaseUserData{BinaryInstall: &v1alpha2.BinaryInstall{}}
+	files := b.binaryInstallFiles()
+	if len(files) != 2 {
+		t.Fatalf("expected two files, got %d", len(files))
+	}
+	if !strings.Contains(files[0].Content, "ExecStart=/usr/local/bin/kubelet\n") {
+		t.Fatalf("expected the kubelet unit to run the installed kubelet, got:\n%s", files[0].Content)
+	}
+	if !strings.Contains(files[1].Content, "ExecStart=/usr/local/bin/kubelet $KUBELET_KUBECONFIG_ARGS") {
+		t.Fatalf("expected the kubeadm drop-in to run the installed kubelet, got:\n%s", files[1].Content)
+	}
+}
+
+func TestBinaryInstallWithoutVersion(t *testing.T) {
+	_, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{BinaryInstall: &v1alpha2.BinaryInstall{}},
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestArtifactVerification(t *testing.T) {
+	sha256 := strings.Repeat("a", 64)
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			Artifacts: []v1alpha2.Artifact{
+				{Path: "/opt/bin/crictl", URL: "https://example.com/crictl"},
+				{
+					Path:   "/etc/kubernetes/addons/cni.yaml",
+					URL:    "https://example.com/cni.yaml",
+					SHA256: sha256,
+					Cosign: &v1alpha2.CosignSignature{PublicKey: "key"},
+				},
+			},
+			BinaryInstall: &v1alpha2.BinaryInstall{Version: "v1.15.3", CosignPublicKey: "key"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+
+	for _, expected := range []string{
+		"path: /run/kubeadm/cosign/artifact-1.pub\n",
+		"path: /run/kubeadm/cosign/binaries.pub\n",
+		`  - 'curl -fsSL --retry 5 -o "/etc/kubernetes/addons/cni.yaml" "https://example.com/cni.yaml"'` + "\n" +
+			`  - 'echo "` + sha256 + `  /etc/kubernetes/addons/cni.yaml" | sha256sum -c - || exit 1'` + "\n" +
+			`  - 'cosign verify-blob --key "/run/kubeadm/cosign/artifact-1.pub" --signature "https://example.com/cni.yaml.sig" "/etc/kubernetes/addons/cni.yaml" || exit 1'` + "\n",
+		`  - 'cosign verify-blob --key "/run/kubeadm/cosign/binaries.pub" --signature "https://storage.googleapis.com/kubernetes-release/release/v1.15.3/bin/linux/amd64/kubelet.sig" "/usr/local/bin/kubelet" || exit 1'` + "\n",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+		}
+	}
+	if strings.Contains(string(out), "artifact-0.pub") {
+		t.Fatalf("expected no cosign key for the unsigned artifact, got:\n%s", string(out))
+	}
+}
+
+func TestPackageRepositories(t *testing.T) {
+	repos := []v1alpha2.PackageRepository{
+		{Name: "kubernetes", OSFamily: v1alpha2.OSFamilyDebian, URL: "https://apt.kubernetes.io", Distribution: "kubernetes-xenial", GPGKey: "debian-key"},
+		{Name: "kubernetes", OSFamily: v1alpha2.OSFamilyRHEL, URL: "https://example.com/yum", GPGKey: "rhel-key"},
+	}
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			PackageRepositories: repos,
+			OSConditionals: []v1alpha2.OSConditional{
+				{OSFamily: v1alpha2.OSFamilyDebian, PreKubeadmCommands: []string{"apt-get install -y kubelet"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+
+	for _, expected := range []string{
+		"path: /run/kubeadm/os/debian/etc/apt/keyrings/kubernetes.asc\n",
+		"path: /run/kubeadm/os/rhel/etc/pki/rpm-gpg/RPM-GPG-KEY-kubernetes\n",
+		`  - 'if [ "$(sh /run/kubeadm/os-family.sh)" = "debian" ]; then eval ''apt-get update''; fi'` + "\n" +
+			`  - 'if [ "$(sh /run/kubeadm/os-family.sh)" = "debian" ]; then eval ''apt-get install -y kubelet''; fi'` + "\n",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+		}
+	}
+
+	debian := packageRepositoryConditional(repos[0]).Files[1].Content
+	if expected := "deb [signed-by=/etc/apt/keyrings/kubernetes.asc] https://apt.kubernetes.io kubernetes-xenial main\n"; debian != expected {
+		t.Fatalf("expected apt source %q, got %q", expected, debian)
+	}
+	rhel := packageRepositoryConditional(repos[1]).Files[1].Content
+	for _, expected := range []string{
+		"gpgcheck=1\n",
+		"repo_gpgcheck=1\n",
+		"gpgkey=file:///etc/pki/rpm-gpg/RPM-GPG-KEY-kubernetes\n",
+	} {
+		if !strings.Contains(rhel, expected) {
+			t.Fatalf("expected yum repository to contain %q, got:\n%s", expected, rhel)
+		}
+	}
+}
+
+func TestHardeningProfile(t *testing.T) {
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			HardeningProfile: v1alpha2.HardeningProfileCIS,
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+
+	for _, expected := range []string{
+		"path: /etc/sysctl.d/90-kubelet.conf\n",
+		"  - 'sysctl -p /etc/sysctl.d/90-kubelet.conf'\n" +
+			"  - 'kubeadm join",
+		`  - 'find /etc/kubernetes -type f -name "*.key" -exec chmod 600 {} +'` + "\n",
+		"  - 'chmod 600 /var/lib/kubelet/config.yaml'\n",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+		}
+	}
+
+	files := (&BaseUserData{HardeningProfile: v1alpha2.HardeningProfileCIS}).hardeningFiles()
+	if len(files) != 1 || !strings.Contains(files[0].Content, "kernel.panic_on_oops = 1\n") {
+		t.Fatalf("expected the CIS kernel settings, got %+v", files)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	b := &BaseUserData{
+		Proxy: &v1alpha2.Proxy{
+			HTTPProxy:  "http://proxy.example.com:3128",
+			HTTPSProxy: "http://proxy.example.com:3128",
+			NoProxy:    []string{"localhost", "10.0.0.0/8"},
+		},
+	}
+	files := b.proxyFiles()
+	if len(files) != 2 {
+		t.Fatalf("expected two drop-ins, got %d", len(files))
+	}
+	for i, expected := range []string{
+		"/etc/systemd/system/containerd.service.d/http-proxy.conf",
+		"/etc/systemd/system/kubelet.service.d/http-proxy.conf",
+	} {
+		if files[i].Path != expected {
+			t.Fatalf("expected drop-in %s, got %s", expected, files[i].Path)
+		}
+	}
+	expected := "[Service]\n" +
+		"Environment=\"HTTP_PROXY=http://proxy.example.com:3128\"\n" +
+		"Environment=\"HTTPS_PROXY=http://proxy.example.com:3128\"\n" +
+		"Environment=\"NO_PROXY=localhost,10.0.0.0/8\"\n"
+	if files[0].Content != expected {
+		t.Fatalf("expected drop-in:\n%s\ngot:\n%s", expected, files[0].Content)
+	}
+
+	out, err := NewNode(&NodeInput{BaseUserData: *b})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	if !strings.Contains(string(out), "runcmd:\n  - 'systemctl daemon-reload'\n  - 'systemctl restart containerd'\n") {
+		t.Fatalf("expected containerd to be restarted first, got:\n%s", string(out))
+	}
+	export := "  - 'export HTTP_PROXY=\"http://proxy.example.com:3128\" http_proxy=\"http://proxy.example.com:3128\" " +
+		"HTTPS_PROXY=\"http://proxy.example.com:3128\" https_proxy=\"http://proxy.example.com:3128\" " +
+		"NO_PROXY=\"localhost,10.0.0.0/8\" no_proxy=\"localhost,10.0.0.0/8\"'\n"
+	if !strings.Contains(string(out), "  - 'systemctl restart containerd'\n"+export) {
+		t.Fatalf("expected the proxy environment to be exported after containerd is restarted, got:\n%s", string(out))
+	}
+}
+
+func TestRegistryMirrors(t *testing.T) {
+	b := &BaseUserData{
+		RegistryMirrors: []v1alpha2.RegistryMirror{
+			{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com"}},
+			{Registry: "k8s.gcr.io", Endpoints: []string{"https://mirror.example.com", "https://backup.example.com"}},
+		},
+	}
+	files := b.registryMirrorFiles()
+	if len(files) != 3 {
+		t.Fatalf("expected two hosts files and the registries script, got %d", len(files))
+	}
+	if files[2].Path != registriesScriptName || files[2].Permissions != "0700" {
+		t.Fatalf("expected the registries script to be written last, got %s", files[2].Path)
+	}
+
+	testcases := []struct {
+		path    string
+		content string
+	}{
+		{
+			path: "/etc/containerd/certs.d/docker.io/hosts.toml",
+			content: "server = \"https://registry-1.docker.io\"\n" +
+				"\n[host.\"https://mirror.example.com\"]\n  capabilities = [\"pull\", \"resolve\"]\n",
+		},
+		{
+			path: "/etc/containerd/certs.d/k8s.gcr.io/hosts.toml",
+			content: "server = \"https://k8s.gcr.io\"\n" +
+				"\n[host.\"https://mirror.example.com\"]\n  capabilities = [\"pull\", \"resolve\"]\n" +
+				"\n[host.\"https://backup.example.com\"]\n  capabilities = [\"pull\", \"resolve\"]\n",
+		},
+	}
+	for i, tc := range testcases {
+		if files[i].Path != tc.path {
+			t.Fatalf("expected hosts file %s, got %s", tc.path, files[i].Path)
+		}
+		if files[i].Content != tc.content {
+			t.Fatalf("expected %s:\n%s\ngot:\n%s", tc.path, tc.content, files[i].Content)
+		}
+	}
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	input := &NodeInput{
+		BaseUserData: BaseUserData{
+			RegistryCredentials: []RegistryCredential{
+				{Host: "registry.example.com:5000", Username: "puller", Password: "s3cr\"t"},
+			},
+		},
+	}
+	out, err := NewNode(input)
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	if !strings.Contains(string(out), "runcmd:\n  - 'sh /run/kubeadm/containerd-registries.sh'\n") {
+		t.Fatalf("expected the registries script to be run first, got:\n%s", string(out))
+	}
+
+	files := map[string]v1alpha2.Files{}
+	for _, f := range input.WriteFiles {
+		files[f.Path] = f
+	}
+	auth, ok := files["/run/kubeadm/containerd-registry-auth.toml"]
+	if !ok || auth.Permissions != "0600" {
+		t.Fatalf("expected the registry credentials to be written readable by root only, got %v", auth)
+	}
+	expected := "\n" + registryAuthFileComment + "\n" +
+		"[plugins.\"io.containerd.grpc.v1.cri\".registry.configs.\"registry.example.com:5000\".auth]\n" +
+		"  username = \"puller\"\n  password = \"s3cr\\\"t\"\n"
+	if auth.Content != expected {
+		t.Fatalf("expected registry credentials:\n%s\ngot:\n%s", expected, auth.Content)
+	}
+	script, ok := files["/run/kubeadm/containerd-registries.sh"]
+	if !ok || !strings.Contains(script.Content, "auth=/run/kubeadm/containerd-registry-auth.toml\n") {
+		t.Fatalf("expected the registries script to append the registry credentials, got:\n%s", script.Content)
+	}
+}
+
+func TestNTP(t *testing.T) {
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			NTP: &v1alpha2.NTP{Servers: []string{"0.pool.example.com", "1.pool.example.com"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	expected := "\nntp:\n  enabled: true\n  servers:\n    - 0.pool.example.com\n    - 1.pool.example.com\nruncmd:\n"
+	if !strings.Contains(string(out), expected) {
+		t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+	}
+
+	out, err = NewNode(&NodeInput{})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	if strings.Contains(string(out), "ntp:") {
+		t.Fatalf("expected no ntp configuration, got:\n%s", string(out))
+	}
+}
+
+func TestManifest(t *testing.T) {
+	input := &NodeInput{
+		BaseUserData: BaseUserData{
+			KubernetesVersion:  "v1.15.3",
+			KubeadmContainer:   &v1alpha2.KubeadmContainer{Image: "registry.example.com/kubeadm:v1.15.3"},
+			AdditionalFiles:    []v1alpha2.Files{{Path: "/etc/motd", Owner: "root:root", Permissions: "0644", Content: "hello"}},
+			PreKubeadmCommands: []string{"echo pre"},
+			AdditionalCommands: []string{"echo post"},
+		},
+	}
+	if _, err := NewNode(input); err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+
+	m := input.Manifest
+	if m == nil {
+		t.Fatal("expected the manifest to be set")
+	}
+	if m.KubeadmVersion != "v1.15.3" || m.KubeadmImage != "registry.example.com/kubeadm:v1.15.3" {
+		t.Fatalf("expected the kubeadm version and image, got %q and %q", m.KubeadmVersion, m.KubeadmImage)
+	}
+	if len(m.Files) != 2 || m.Files[0].Path != "/etc/motd" || m.Files[1].Path != "/run/kubeadm/kubeadm-join.yaml" {
+		t.Fatalf("expected the additional and kubeadm configuration files, got %+v", m.Files)
+	}
+	if len(m.Commands) < 3 || m.Commands[0] != "echo pre" || m.Commands[len(m.Commands)-1] != "echo post" {
+		t.Fatalf("expected the commands in the order they run, got %v", m.Commands)
+	}
+	if !strings.HasSuffix(m.Commands[len(m.Commands)-3], "kubeadm join --config /run/kubeadm/kubeadm-join.yaml") ||
+		m.Commands[len(m.Commands)-2] != bootstrapSentinelCommand {
+		t.Fatalf("expected kubeadm join and the bootstrap sentinel to run before the additional commands, got %v", m.Commands)
+	}
+}
+
+func TestVerbosity(t *testing.T) {
+	verbosity := int32(5)
+	input := &NodeInput{BaseUserData: BaseUserData{Verbosity: &verbosity}}
+	out, err := NewNode(input)
+	if err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+
+	expected := "  - 'kubeadm join --config /run/kubeadm/kubeadm-join.yaml --v=5'\n"
+	if !strings.Contains(string(out), expected) {
+		t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+	}
+	if command := input.Manifest.Commands[len(input.Manifest.Commands)-2]; command != "kubeadm join --config /run/kubeadm/kubeadm-join.yaml --v=5" {
+		t.Fatalf("expected the manifest to record the kubeadm verbosity, got %q", command)
+	}
+
+	input = &NodeInput{}
+	out, err = NewNode(input)
+	if err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+	if strings.Contains(string(out), "--v=") {
+		t.Fatalf("expected kubeadm to run with its default verbosity, got:\n%s", string(out))
+	}
+}
+
+func TestBootstrapSentinel(t *testing.T) {
+	out, err := NewNode(&NodeInput{BaseUserData: BaseUserData{AdditionalCommands: []string{"echo post"}}})
+	if err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+
+	expected := "  - 'kubeadm join --config /run/kubeadm/kubeadm-join.yaml'\n" +
+		"  - 'test $? -eq 0 && mkdir -p /run/cluster-api && echo success > /run/cluster-api/bootstrap-success.complete'\n" +
+		"  - 'echo post'\n"
+	if !strings.Contains(string(out), expected) {
+		t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+	}
+}
+
+func TestJoinRetry(t *testing.T) {
+	input := &NodeInput{BaseUserData: BaseUserData{JoinRetry: &v1alpha2.JoinRetry{}}}
+	out, err := NewNode(input)
+	if err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+
+	for _, expected := range []string{
+		"path: /run/kubeadm/kubeadm-join.sh\n",
+		"  - 'sh /run/kubeadm/kubeadm-join.sh'\n",
+	} {
+		if !strings.Contains(string(out), expected) {
+			t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+		}
+	}
+	if strings.Contains(string(out), "  - 'kubeadm join") {
+		t.Fatalf("expected kubeadm join to only run from the retry script, got:\n%s", string(out))
+	}
+	if command := input.Manifest.Commands[len(input.Manifest.Commands)-2]; command != "sh /run/kubeadm/kubeadm-join.sh" {
+		t.Fatalf("expected the manifest to record the retry script, got %q", command)
+	}
+}
+
+func TestJoinRetryScript(t *testing.T) {
+	b := &BaseUserData{
+		GeneratedFilesDir: "/run/kubeadm",
+		KubeadmCommand:    "/opt/bin/kubeadm",
+		KubeadmFlags:      " --v=3",
+		JoinRetry:         &v1alpha2.JoinRetry{Attempts: 3},
+	}
+	files, err := b.setJoinCommand()
+	if err != nil {
+		t.Fatalf("failed to generate join retry script: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected one file, got %d", len(files))
+	}
+
+	script := files[0].Content
+	for _, expected := range []string{
+		"attempts=3\n",
+		"backoff=10\n",
+		"until /opt/bin/kubeadm join --config /run/kubeadm/kubeadm-join.yaml --v=3; do\n",
+		"  /opt/bin/kubeadm reset --force || true\n",
+		"  cp -a \"$backup/.\" \"$pki/\"\n",
+		"  backoff=$((backoff * 2))\n",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Fatalf("expected script to contain %q, got:\n%s", expected, script)
+		}
+	}
+
+	b.JoinRetry = nil
+	files, err = b.setJoinCommand()
+	if err != nil {
+		t.Fatalf("failed to set the join command: %v", err)
+	}
+	if len(files) != 0 || b.JoinCommand != "/opt/bin/kubeadm join --config /run/kubeadm/kubeadm-join.yaml --v=3" {
+		t.Fatalf("expected kubeadm join to run directly, got %q and %d files", b.JoinCommand, len(files))
+	}
+}
+
+func TestKeepKubeadmOutput(t *testing.T) {
+	input := &NodeInput{BaseUserData: BaseUserData{KeepKubeadmOutput: true, KubeadmFailureHook: "sh /run/kubeadm/report.sh"}}
+	out, err := NewNode(input)
+	if err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+
+	if !strings.Contains(string(out), "  - 'sh /run/kubeadm/kubeadm-run.sh'\n") {
+		t.Fatalf("expected kubeadm to run from the wrapper script, got:\n%s", string(out))
+	}
+	if strings.Contains(string(out), "  - 'kubeadm join") {
+		t.Fatalf("expected kubeadm join to only run from the wrapper script, got:\n%s", string(out))
+	}
+
+	if !strings.Contains(string(out), "path: /run/kubeadm/kubeadm-run.sh\n") {
+		t.Fatalf("expected user data to write the wrapper script, got:\n%s", string(out))
+	}
+}
+
+func TestKubeadmRunScript(t *testing.T) {
+	b := &BaseUserData{GeneratedFilesDir: "/run/kubeadm", KeepKubeadmOutput: true, KubeadmFailureHook: "sh /run/kubeadm/report.sh"}
+	files, command, err := b.kubeadmRunFiles("kubeadm join --config /run/kubeadm/kubeadm-join.yaml")
+	if err != nil {
+		t.Fatalf("failed to generate kubeadm run files: %v", err)
+	}
+	if len(files) != 1 || command != "sh /run/kubeadm/kubeadm-run.sh" {
+		t.Fatalf("expected kubeadm to run from the wrapper script, got %q and %d files", command, len(files))
+	}
+
+	script := files[0].Content
+	for _, expected := range []string{
+		"log=/run/kubeadm/kubeadm.log\n",
+		"{ kubeadm join --config /run/kubeadm/kubeadm-join.yaml 2>&1; echo $? >\"$status\"; } | tee \"$log\"\n",
+		"  sh /run/kubeadm/report.sh \"$log\" || true\n",
+		"exit \"$code\"\n",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Fatalf("expected the kubeadm run script to contain %q, got:\n%s", expected, script)
+		}
+	}
+}
+
+func TestKubeadmOutputNotKept(t *testing.T) {
+	b := &BaseUserData{GeneratedFilesDir: "/run/kubeadm", KubeadmFailureHook: "sh /run/kubeadm/report.sh"}
+	files, command, err := b.kubeadmRunFiles("kubeadm join")
+	if err != nil {
+		t.Fatalf("failed to generate kubeadm run files: %v", err)
+	}
+	if len(files) != 0 || command != "kubeadm join" {
+		t.Fatalf("expected kubeadm to run directly, got %q and %d files", command, len(files))
+	}
+}
+
+func TestFileLineEndingsAndCharset(t *testing.T) {
+	input := &NodeInput{
+		BaseUserData: BaseUserData{
+			AdditionalFiles: []v1alpha2.Files{
+				{Path: "/etc/lf", Content: "a\r\nb\n", LineEndings: v1alpha2.FileLineEndingsLF},
+				{Path: "/etc/crlf", Content: "a\r\nb\n", LineEndings: v1alpha2.FileLineEndingsCRLF},
+				{Path: "/etc/bom", Content: "a\n", Charset: v1alpha2.FileCharsetUTF8BOM},
+				{Path: "/etc/utf16", Content: "aé\n", LineEndings: v1alpha2.FileLineEndingsCRLF, Charset: v1alpha2.FileCharsetUTF16LE},
+				{Path: "/etc/unchanged", Content: "a\r\nb\n"},
+			},
+		},
+	}
+	if _, err := NewNode(input); err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+
+	expected := map[string]string{
+		"/etc/lf":        "a\nb\n",
+		"/etc/crlf":      "a\r\nb\r\n",
+		"/etc/bom":       "\ufeffa\n",
+		"/etc/utf16":     "\xff\xfea\x00\xe9\x00\r\x00\n\x00",
+		"/etc/unchanged": "a\r\nb\n",
+	}
+	for _, f := range input.WriteFiles {
+		if content, ok := expected[f.Path]; ok && f.Content != content {
+			t.Errorf("expected %s content %q, got %q", f.Path, content, f.Content)
+		}
+	}
+	if input.AdditionalFiles[0].Content != "a\r\nb\n" {
+		t.Fatal("expected the input files not to be modified")
+	}
+}
+
+func TestFileEncoding(t *testing.T) {
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	if _, err := w.Write([]byte("compressed\n")); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+
+	input := &NodeInput{
+		BaseUserData: BaseUserData{
+			AdditionalFiles: []v1alpha2.Files{
+				{Path: "/etc/base64", Content: base64.StdEncoding.EncodeToString([]byte("\x00\x01")), Encoding: v1alpha2.FileEncodingBase64},
+				{Path: "/etc/gzip", Content: base64.StdEncoding.EncodeToString(gzipped.Bytes()), Encoding: v1alpha2.FileEncodingGzipBase64},
+				{Path: "/etc/crlf", Content: base64.StdEncoding.EncodeToString([]byte("a\n")), Encoding: v1alpha2.FileEncodingBase64, LineEndings: v1alpha2.FileLineEndingsCRLF},
+			},
+		},
+	}
+	if _, err := NewNode(input); err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+
+	expected := map[string]string{
+		"/etc/base64": "\x00\x01",
+		"/etc/gzip":   "compressed\n",
+		"/etc/crlf":   "a\r\n",
+	}
+	for _, f := range input.WriteFiles {
+		if content, ok := expected[f.Path]; ok && f.Content != content {
+			t.Errorf("expected %s content %q, got %q", f.Path, content, f.Content)
+		}
+	}
+
+	input = &NodeInput{
+		BaseUserData: BaseUserData{
+			AdditionalFiles: []v1alpha2.Files{{Path: "/etc/gzip", Content: "Zm9vCg==", Encoding: v1alpha2.FileEncodingGzipBase64}},
+		},
+	}
+	if _, err := NewNode(input); err == nil {
+		t.Fatal("expected an error for content that is not gzip compressed")
+	}
+}
+
+func TestEtcdDisk(t *testing.T) {
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("failed to generate certificates: %v", err)
+	}
+
+	out, err := NewInitControlPlane(&ControlPlaneInput{
+		BaseUserData: BaseUserData{
+			EtcdDisk: &v1alpha2.EtcdDisk{Device: "/dev/nvme1n1"},
+		},
+		Certificates: *certificates,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	expected := `
+disk_setup:
+  /dev/nvme1n1:
+    table_type: gpt
+    layout: true
+    overwrite: false
+fs_setup:
+  - label: etcd_disk
+    filesystem: ext4
+    device: /dev/nvme1n1
+    partition: auto
+    overwrite: false
+mounts:
+  - - LABEL=etcd_disk
+    - /var/lib/etcddisk
+runcmd:
+`
+	if !strings.Contains(string(out), expected) {
+		t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+	}
+
+	out, err = NewInitControlPlane(&ControlPlaneInput{Certificates: *certificates})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	if strings.Contains(string(out), "disk_setup:") {
+		t.Fatalf("expected no disk setup, got:\n%s", string(out))
+	}
+}
+
+func TestDiskSetup(t *testing.T) {
+	overwrite := true
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			DiskSetup: &v1alpha2.DiskSetup{
+				Partitions: []v1alpha2.Partition{{Device: "/dev/nvme2n1", Layout: true, Overwrite: &overwrite}},
+				Filesystems: []v1alpha2.Filesystem{{
+					Device:     "/dev/nvme2n1",
+					Filesystem: "xfs",
+					Label:      "containerd",
+					Partition:  "1",
+					ExtraOpts:  []string{"-n", "ftype=1"},
+				}},
+			},
+			Mounts: []v1alpha2.MountPoints{{"LABEL=containerd", "/var/lib/containerd", "xfs", "defaults,nofail"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	expected := `
+disk_setup:
+  /dev/nvme2n1:
+    layout: true
+    overwrite: true
+fs_setup:
+  - label: containerd
+    filesystem: xfs
+    device: /dev/nvme2n1
+    partition: 1
+    extra_opts:
+      - -n
+      - ftype=1
+mounts:
+  - - LABEL=containerd
+    - /var/lib/containerd
+    - xfs
+    - defaults,nofail
+runcmd:
+`
+	if !strings.Contains(string(out), expected) {
+		t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+	}
+
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("failed to generate certificates: %v", err)
+	}
+	out, err = NewInitControlPlane(&ControlPlaneInput{
+		BaseUserData: BaseUserData{
+			EtcdDisk:  &v1alpha2.EtcdDisk{Device: "/dev/nvme1n1"},
+			DiskSetup: &v1alpha2.DiskSetup{Partitions: []v1alpha2.Partition{{Device: "/dev/nvme2n1", Layout: true}}},
+			Mounts:    []v1alpha2.MountPoints{{"/dev/nvme2n1p1", "/var/lib/containerd"}},
+		},
+		Certificates: *certificates,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	expected = `
+disk_setup:
+  /dev/nvme1n1:
+    table_type: gpt
+    layout: true
+    overwrite: false
+  /dev/nvme2n1:
+    layout: true
+fs_setup:
+  - label: etcd_disk
+    filesystem: ext4
+    device: /dev/nvme1n1
+    partition: auto
+    overwrite: false
+mounts:
+  - - LABEL=etcd_disk
+    - /var/lib/etcddisk
+  - - /dev/nvme2n1p1
+    - /var/lib/containerd
+runcmd:
+`
+	if !strings.Contains(string(out), expected) {
+		t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+	}
+}
+
+func TestUsers(t *testing.T) {
+	locked := true
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			Users: []v1alpha2.User{
+				{
+					Name:              "ops",
+					SSHAuthorizedKeys: []string{"ssh-ed25519 AAAA ops@example.com", "ssh-rsa BBBB"},
+					Sudo:              "ALL=(ALL) NOPASSWD:ALL",
+					Shell:             "/bin/bash",
+				},
+				{Name: "console", Passwd: "$6$salt$hash", LockPassword: &locked},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	expected := `
+users:
+  - name: ops
+    sudo: "ALL=(ALL) NOPASSWD:ALL"
+    shell: /bin/bash
+    ssh_authorized_keys:
+      - "ssh-ed25519 AAAA ops@example.com"
+      - "ssh-rsa BBBB"
+  - name: console
+    passwd: "$6$salt$hash"
+    lock_passwd: true
+runcmd:
+`
+	if !strings.Contains(string(out), expected) {
+		t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	input := &NodeInput{
+		BaseUserData: BaseUserData{
+			TraceID:            "0123abcd",
+			PreKubeadmCommands: []string{"echo pre"},
+		},
+	}
+	out, err := NewNode(input)
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+	expected := `
+output:
+  all: '| sed -u "s/^/[0123abcd] /" | tee -a /var/log/cloud-init-output.log'
+runcmd:
+  - 'export CABPK_TRACE_ID=0123abcd'
+  - 'echo pre'
+`
+	if !strings.Contains(string(out), expected) {
+		t.Fatalf("expected user data to contain %q, got:\n%s", expected, string(out))
+	}
+	if input.Manifest.TraceID != "0123abcd" {
+		t.Fatalf("expected the manifest to record the trace ID, got %q", input.Manifest.TraceID)
+	}
+
+	if _, err := NewNode(&NodeInput{BaseUserData: BaseUserData{TraceID: "a'; reboot"}}); err == nil {
+		t.Fatal("expected an invalid trace ID to be rejected")
+	}
+}
+
+func TestTrustedCABundles(t *testing.T) {
+	input := &NodeInput{
+		BaseUserData: BaseUserData{
+			TrustedCABundles: []string{"registry-ca"},
+			PackageRepositories: []v1alpha2.PackageRepository{
+				{Name: "internal", OSFamily: v1alpha2.OSFamilyRHEL, URL: "https://repo.example.com", GPGKey: "key"},
+			},
+		},
+	}
+	if _, err := NewNode(input); err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+
+	staged := map[string]string{}
+	for _, f := range input.OSConditionals[0].Files {
+		staged[f.Path] = f.Content
+	}
+	if staged["/usr/local/share/ca-certificates/cabpk-trusted-ca-0.crt"] != "registry-ca" {
+		t.Fatalf("expected the CA bundle to be installed on debian machines, got %v", input.OSConditionals[0].Files)
+	}
+
+	var rhelTrust, rhelRepo int
+	for i, command := range input.PreKubeadmCommands {
+		switch {
+		case strings.Contains(command, "update-ca-trust extract"):
+			rhelTrust = i
+		case strings.Contains(command, "/etc/yum.repos.d/internal.repo"):
+			rhelRepo = i
+		}
+	}
+	if rhelTrust == 0 || rhelRepo == 0 || rhelTrust > rhelRepo {
+		t.Fatalf("expected the trust store to be updated before the package repositories are configured, got %v", input.PreKubeadmCommands)
+	}
+}
+
+func TestMultipart(t *testing.T) {
+	newInput := func() *NodeInput {
+		return &NodeInput{
+			BaseUserData: BaseUserData{
+				Multipart: &v1alpha2.Multipart{
+					Parts: []v1alpha2.UserDataPart{
+						{ContentType: "text/cloud-config", Content: "#cloud-config\nruncmd:\n- echo proxy\n"},
+						{ContentType: "text/x-shellscript", Filename: "region.sh", Content: "#!/bin/sh\necho region\n"},
+					},
+				},
+			},
+		}
+	}
+	userData, err := NewNode(newInput())
+	if err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(userData))
+	if err != nil {
+		t.Fatalf("failed to read the MIME archive: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("expected a multipart/mixed archive, got %q: %v", msg.Header.Get("Content-Type"), err)
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []*multipart.Part
+	var contents []string
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read MIME part: %v", err)
+		}
+		content, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatalf("failed to read MIME part: %v", err)
+		}
+		parts = append(parts, part)
+		contents = append(contents, string(content))
+	}
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+
+	expected := []struct {
+		contentType string
+		mergeType   string
+		filename    string
+	}{
+		{`text/jinja2; charset="utf-8"`, DefaultMultipartMergeType, ""},
+		{`text/cloud-config; charset="utf-8"`, DefaultMultipartMergeType, ""},
+		{`text/x-shellscript; charset="utf-8"`, "", "region.sh"},
+	}
+	for i, e := range expected {
+		if got := parts[i].Header.Get("Content-Type"); got != e.contentType {
+			t.Errorf("expected part %d to have content type %q, got %q", i, e.contentType, got)
+		}
+		if got := parts[i].Header.Get("Merge-Type"); got != e.mergeType {
+			t.Errorf("expected part %d to have merge type %q, got %q", i, e.mergeType, got)
+		}
+		if got := parts[i].FileName(); got != e.filename {
+			t.Errorf("expected part %d to have filename %q, got %q", i, e.filename, got)
+		}
+	}
+	if !strings.HasPrefix(contents[0], cloudConfigHeader) || !strings.Contains(contents[0], "kubeadm join") {
+		t.Errorf("expected the first part to be the generated cloud-config, got:\n%s", contents[0])
+	}
+	if contents[2] != "#!/bin/sh\necho region\n" {
+		t.Errorf("expected the script part content to be kept, got %q", contents[2])
+	}
+
+	again, err := NewNode(newInput())
+	if err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+	if !bytes.Equal(userData, again) {
+		t.Error("expected the MIME archive of the same input to be the same")
+	}
+}
+
+func TestMultipartMergeType(t *testing.T) {
+	b := &BaseUserData{Multipart: &v1alpha2.Multipart{MergeType: "list(append)+dict(recurse_array)+str()"}}
+	userData, err := b.multipart([]byte("#cloud-config\n"))
+	if err != nil {
+		t.Fatalf("failed to generate the MIME archive: %v", err)
+	}
+	if !bytes.Contains(userData, []byte("Merge-Type: list(append)+dict(recurse_array)+str()\r\n")) {
+		t.Fatalf("expected the merge type to be set, got:\n%s", userData)
+	}
+
+	b.Multipart = nil
+	userData, err = b.multipart([]byte("#cloud-config\n"))
+	if err != nil || string(userData) != "#cloud-config\n" {
+		t.Fatalf("expected the user data to be returned as is, got %q: %v", userData, err)
+	}
+}
+
+func TestExtraCloudConfig(t *testing.T) {
+	input := &NodeInput{BaseUserData: BaseUserData{ExtraCloudConfig: "power_state:\n  mode: reboot\n\n"}}
+	userData, err := NewNode(input)
+	if err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+	if !strings.HasSuffix(string(userData), "'"+bootstrapSentinelCommand+"'\npower_state:\n  mode: reboot\n") {
+		t.Fatalf("expected the extra cloud-config to end the user data, got:\n%s", userData)
+	}
+
+	userData, err = NewNode(&NodeInput{})
+	if err != nil {
+		t.Fatalf("failed to generate node user data: %v", err)
+	}
+	if !strings.HasSuffix(string(userData), "'"+bootstrapSentinelCommand+"'\n") {
+		t.Fatalf("expected the runcmd to end the user data, got:\n%s", userData)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"testing"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCommandsRoundTrip(t *testing.T) {
+	command := `echo "it's: done" # not a comment`
+	out, err := NewNode(&NodeInput{
+		BaseUserData: BaseUserData{
+			PreKubeadmCommands: []string{command},
+			AdditionalCommands: []string{command},
+			OSConditionals: []v1alpha2.OSConditional{
+				{
+					OSFamily:           v1alpha2.OSFamilyDebian,
+					PreKubeadmCommands: []string{command},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to generate user data: %v", err)
+	}
+
+	cloudConfig := struct {
+		RunCmd []string `json:"runcmd"`
+	}{}
+	if err := yaml.Unmarshal(out, &cloudConfig); err != nil {
+		t.Fatalf("failed to unmarshal user data: %v\n%s", err, string(out))
+	}
+
+	expected := map[string]bool{
+		command: false,
+		`if [ "$(sh /run/kubeadm/os-family.sh)" = "debian" ]; then eval 'echo "it'\''s: done" # not a comment'; fi`: false,
+	}
+	for _, c := range cloudConfig.RunCmd {
+		if _, ok := expected[c]; ok {
+			expected[c] = true
+		}
+	}
+	for c, found := range expected {
+		if !found {
+			t.Errorf("expected runcmd to contain %q, got %q", c, cloudConfig.RunCmd)
+		}
+	}
+}
@@ -21,7 +21,7 @@ import "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
 const (
 	controlPlaneCloudInit = `{{.Header}}
 {{template "files" .WriteFiles}}
--   path: /tmp/kubeadm.yaml
+-   path: {{.GeneratedFilesDir}}/kubeadm-init.yaml
     owner: root:root
     permissions: '0640'
     content: |
@@ -29,9 +29,17 @@ const (
 {{.ClusterConfiguration | Indent 6}}
       ---
 {{.InitConfiguration | Indent 6}}
+{{- template "disk_setup" . }}
+{{- template "ntp" .NTP }}
+{{- template "users" .Users }}
+{{- template "trace_output" .TraceID }}
 runcmd:
-  - 'kubeadm init --config /tmp/kubeadm.yaml'
+{{- template "trace_command" .TraceID }}
+{{- template "commands" .PreKubeadmCommands }}
+  - '{{ SingleQuote .BootstrapCommand }}'
+  - '` + bootstrapSentinelCommand + `'
 {{- template "commands" .AdditionalCommands }}
+{{- template "extra_cloud_config" .ExtraCloudConfig }}
 `
 )
 
@@ -47,16 +55,34 @@ type ControlPlaneInput struct {
 // NewInitControlPlane returns the user data string to be used on a controlplane instance.
 func NewInitControlPlane(input *ControlPlaneInput) ([]byte, error) {
 	input.Header = cloudConfigHeader
+	if err := input.setDefaults(); err != nil {
+		return nil, err
+	}
 	if err := input.Certificates.Validate(); err != nil {
 		return nil, err
 	}
 
+	generatedFiles, err := input.generatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	bootstrapFiles, err := input.setBootstrapCommand(input.kubeadmCommandLine("init", "kubeadm-init.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	generatedFiles = append(generatedFiles, bootstrapFiles...)
+
 	input.WriteFiles = certs.CertificatesToFiles(input.Certificates)
 	input.WriteFiles = append(input.WriteFiles, input.AdditionalFiles...)
+	input.WriteFiles, err = encodeFiles(append(input.WriteFiles, generatedFiles...))
+	if err != nil {
+		return nil, err
+	}
 	userData, err := generate("InitControlplane", controlPlaneCloudInit, input)
 	if err != nil {
 		return nil, err
 	}
+	input.Manifest = input.manifest("kubeadm-init.yaml")
 
-	return userData, nil
+	return input.multipart(userData)
 }
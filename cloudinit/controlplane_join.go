@@ -24,14 +24,22 @@ import (
 const (
 	controlPlaneJoinCloudInit = `{{.Header}}
 {{template "files" .WriteFiles}}
--   path: /tmp/kubeadm-controlplane-join-config.yaml
+-   path: {{.GeneratedFilesDir}}/kubeadm-join.yaml
     owner: root:root
     permissions: '0640'
     content: |
 {{.JoinConfiguration | Indent 6}}
+{{- template "disk_setup" . }}
+{{- template "ntp" .NTP }}
+{{- template "users" .Users }}
+{{- template "trace_output" .TraceID }}
 runcmd:
-  - 'kubeadm join --config /tmp/kubeadm-controlplane-join-config.yaml'
+{{- template "trace_command" .TraceID }}
+{{- template "commands" .PreKubeadmCommands }}
+  - '{{ SingleQuote .BootstrapCommand }}'
+  - '` + bootstrapSentinelCommand + `'
 {{- template "commands" .AdditionalCommands }}
+{{- template "extra_cloud_config" .ExtraCloudConfig }}
 `
 )
 
@@ -47,16 +55,39 @@ type ControlPlaneJoinInput struct {
 // NewJoinControlPlane returns the user data string to be used on a new control plane instance.
 func NewJoinControlPlane(input *ControlPlaneJoinInput) ([]byte, error) {
 	input.Header = cloudConfigHeader
+	if err := input.setDefaults(); err != nil {
+		return nil, err
+	}
 	if err := input.Certificates.Validate(); err != nil {
 		return nil, errors.Wrapf(err, "ControlPlaneInput is invalid")
 	}
 
+	generatedFiles, err := input.generatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	joinFiles, err := input.setJoinCommand()
+	if err != nil {
+		return nil, err
+	}
+	generatedFiles = append(generatedFiles, joinFiles...)
+	bootstrapFiles, err := input.setBootstrapCommand(input.JoinCommand)
+	if err != nil {
+		return nil, err
+	}
+	generatedFiles = append(generatedFiles, bootstrapFiles...)
+
 	input.WriteFiles = certs.CertificatesToFiles(input.Certificates)
 	input.WriteFiles = append(input.WriteFiles, input.AdditionalFiles...)
+	input.WriteFiles, err = encodeFiles(append(input.WriteFiles, generatedFiles...))
+	if err != nil {
+		return nil, err
+	}
 	userData, err := generate("JoinControlplane", controlPlaneJoinCloudInit, input)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to generate user data for machine joining control plane")
 	}
+	input.Manifest = input.manifest("kubeadm-join.yaml")
 
-	return userData, err
+	return input.multipart(userData)
 }
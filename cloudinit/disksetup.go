@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+
+const (
+	// diskSetupTemplate renders the disk_setup, fs_setup and mounts modules, which run before runcmd.
+	diskSetupTemplate = `{{- define "disk_setup" -}}
+{{- with .DiskSetup }}
+{{- if .Partitions }}
+disk_setup:
+{{- range .Partitions }}
+  {{ .Device }}:
+{{- if .TableType }}
+    table_type: {{ .TableType }}
+{{- end }}
+    layout: {{ .Layout }}
+{{- if .Overwrite }}
+    overwrite: {{ .Overwrite }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if .Filesystems }}
+fs_setup:
+{{- range .Filesystems }}
+  - label: {{ .Label }}
+    filesystem: {{ .Filesystem }}
+    device: {{ .Device }}
+{{- if .Partition }}
+    partition: {{ .Partition }}
+{{- end }}
+{{- if .Overwrite }}
+    overwrite: {{ .Overwrite }}
+{{- end }}
+{{- if .ExtraOpts }}
+    extra_opts:
+{{- range .ExtraOpts }}
+      - {{ . }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if .Mounts }}
+mounts:
+{{- range .Mounts }}
+{{- range $i, $field := . }}
+{{- if eq $i 0 }}
+  - - {{ $field }}
+{{- else }}
+    - {{ $field }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- end -}}
+`
+
+	// etcdDiskLabel is the label of the filesystem of the etcd disk.
+	etcdDiskLabel = "etcd_disk"
+)
+
+// setDiskSetup prepends the partition, filesystem and mount of the etcd disk, which is set up without
+// overwriting existing data, to the disk setup and mounts.
+func (b *BaseUserData) setDiskSetup() {
+	if b.EtcdDisk == nil {
+		return
+	}
+	overwrite := false
+	disk := &v1alpha2.DiskSetup{
+		Partitions: []v1alpha2.Partition{{
+			Device:    b.EtcdDisk.Device,
+			Layout:    true,
+			Overwrite: &overwrite,
+			TableType: "gpt",
+		}},
+		Filesystems: []v1alpha2.Filesystem{{
+			Device:     b.EtcdDisk.Device,
+			Filesystem: b.EtcdDisk.Filesystem,
+			Label:      etcdDiskLabel,
+			Partition:  "auto",
+			Overwrite:  &overwrite,
+		}},
+	}
+	if b.DiskSetup != nil {
+		disk.Partitions = append(disk.Partitions, b.DiskSetup.Partitions...)
+		disk.Filesystems = append(disk.Filesystems, b.DiskSetup.Filesystems...)
+	}
+	b.DiskSetup = disk
+	b.Mounts = append([]v1alpha2.MountPoints{{"LABEL=" + etcdDiskLabel, b.EtcdDisk.MountPath}}, b.Mounts...)
+}
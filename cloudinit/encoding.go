@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const utf8BOM = "\ufeff"
+
+// encodeFiles returns the files with their content decoded, normalized to their line endings and written in their
+// charset. The user data carries the content base64 encoded, so any byte sequence is preserved.
+func encodeFiles(files []v1alpha2.Files) ([]v1alpha2.Files, error) {
+	encoded := make([]v1alpha2.Files, 0, len(files))
+	for _, f := range files {
+		content, err := decodeContent(f.Content, f.Encoding)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode the content of %s", f.Path)
+		}
+		f.Content = encodeContent(content, f.LineEndings, f.Charset)
+		f.Encoding = ""
+		encoded = append(encoded, f)
+	}
+	return encoded, nil
+}
+
+func decodeContent(content string, encoding v1alpha2.FileEncoding) (string, error) {
+	switch encoding {
+	case v1alpha2.FileEncodingBase64, v1alpha2.FileEncodingGzipBase64:
+		decoded, err := base64.StdEncoding.DecodeString(content)
+		if err != nil {
+			return "", err
+		}
+		if encoding == v1alpha2.FileEncodingBase64 {
+			return string(decoded), nil
+		}
+		r, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		decompressed, err := ioutil.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		return string(decompressed), nil
+	}
+	return content, nil
+}
+
+func encodeContent(content string, lineEndings v1alpha2.FileLineEndings, charset v1alpha2.FileCharset) string {
+	switch lineEndings {
+	case v1alpha2.FileLineEndingsLF:
+		content = strings.Replace(content, "\r\n", "\n", -1)
+	case v1alpha2.FileLineEndingsCRLF:
+		content = strings.Replace(strings.Replace(content, "\r\n", "\n", -1), "\n", "\r\n", -1)
+	}
+
+	switch charset {
+	case v1alpha2.FileCharsetUTF8BOM:
+		if !strings.HasPrefix(content, utf8BOM) {
+			content = utf8BOM + content
+		}
+	case v1alpha2.FileCharsetUTF16LE:
+		units := utf16.Encode([]rune(strings.TrimPrefix(content, utf8BOM)))
+		b := make([]byte, 0, 2*len(units)+2)
+		b = append(b, 0xff, 0xfe)
+		for _, u := range units {
+			b = append(b, byte(u), byte(u>>8))
+		}
+		content = string(b)
+	}
+	return content
+}
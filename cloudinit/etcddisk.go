@@ -0,0 +1,34 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+
+// setEtcdDisk defaults the filesystem and mount path of the etcd disk.
+func (b *BaseUserData) setEtcdDisk() {
+	if b.EtcdDisk == nil {
+		return
+	}
+	disk := *b.EtcdDisk
+	if disk.Filesystem == "" {
+		disk.Filesystem = v1alpha2.DefaultEtcdDiskFilesystem
+	}
+	if disk.MountPath == "" {
+		disk.MountPath = v1alpha2.DefaultEtcdDiskMountPath
+	}
+	b.EtcdDisk = &disk
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	cisSysctlPath = "/etc/sysctl.d/90-kubelet.conf"
+
+	// cisSysctl are the kernel settings the kubelet refuses to start without when protect-kernel-defaults is set.
+	cisSysctl = `vm.overcommit_memory = 1
+vm.panic_on_oom = 0
+kernel.panic = 10
+kernel.panic_on_oops = 1
+kernel.keys.root_maxkeys = 1000000
+kernel.keys.root_maxbytes = 25000000
+`
+)
+
+// cisPostKubeadmCommands restrict the permissions and ownership of the files written by kubeadm, as required
+// by the CIS Kubernetes Benchmark.
+var cisPostKubeadmCommands = []string{
+	"chown -R root:root /etc/kubernetes",
+	`find /etc/kubernetes -type f -name "*.conf" -exec chmod 600 {} +`,
+	`find /etc/kubernetes -type f -path "/etc/kubernetes/manifests/*" -exec chmod 600 {} +`,
+	`find /etc/kubernetes -type f -name "*.key" -exec chmod 600 {} +`,
+	`find /etc/kubernetes -type f -name "*.crt" -exec chmod 644 {} +`,
+	"chmod 600 /var/lib/kubelet/config.yaml",
+	"if [ -d /var/lib/etcd ]; then chmod 700 /var/lib/etcd; fi",
+}
+
+// setHardeningProfile applies the kernel settings of the hardening profile before kubeadm is run, and appends
+// the commands restricting the files written by kubeadm to the ones run after it.
+func (b *BaseUserData) setHardeningProfile() {
+	if b.HardeningProfile != v1alpha2.HardeningProfileCIS {
+		return
+	}
+	b.PreKubeadmCommands = append(b.PreKubeadmCommands, fmt.Sprintf("sysctl -p %s", cisSysctlPath))
+	b.AdditionalCommands = append(b.AdditionalCommands, cisPostKubeadmCommands...)
+}
+
+// hardeningFiles returns the kernel settings of the hardening profile.
+func (b *BaseUserData) hardeningFiles() []v1alpha2.Files {
+	if b.HardeningProfile != v1alpha2.HardeningProfileCIS {
+		return nil
+	}
+	return []v1alpha2.Files{{
+		Path:        cisSysctlPath,
+		Owner:       rootOwnerValue,
+		Permissions: "0644",
+		Content:     cisSysctl,
+	}}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	joinRetryScriptName = "kubeadm-join.sh"
+
+	defaultJoinRetryAttempts              = 5
+	defaultJoinRetryInitialBackoffSeconds = 10
+
+	// kubeadmCertificatesDir is where the certificates written by the user data live. kubeadm reset empties it.
+	kubeadmCertificatesDir = "/etc/kubernetes/pki"
+
+	joinRetryScript = `#!/bin/sh
+# Runs kubeadm join, resetting the machine and backing off exponentially between failed attempts.
+attempts={{.Attempts}}
+backoff={{.InitialBackoffSeconds}}
+pki={{.CertificatesDir}}
+backup={{.CertificatesBackupDir}}
+
+# kubeadm reset removes the certificates written by the bootstrap data, keep a copy to restore them.
+if [ -d "$pki" ]; then
+  rm -rf "$backup"
+  cp -a "$pki" "$backup"
+fi
+
+attempt=1
+until {{.Join}}; do
+  if [ "$attempt" -ge "$attempts" ]; then
+    echo "kubeadm join failed after $attempt attempts" >&2
+    exit 1
+  fi
+  echo "kubeadm join attempt $attempt of $attempts failed, retrying in ${backoff}s" >&2
+  {{.Reset}} || true
+  if [ -d "$backup" ]; then
+    mkdir -p "$pki"
+    cp -a "$backup/." "$pki/"
+  fi
+  sleep "$backoff"
+  attempt=$((attempt + 1))
+  backoff=$((backoff * 2))
+done
+`
+)
+
+// kubeadmCommandLine returns the command running the given kubeadm subcommand with its configuration file.
+func (b *BaseUserData) kubeadmCommandLine(subcommand, kubeadmFile string) string {
+	return fmt.Sprintf("%s %s --config %s%s", b.KubeadmCommand, subcommand, path.Join(b.GeneratedFilesDir, kubeadmFile), b.KubeadmFlags)
+}
+
+// setJoinCommand sets the command joining the machine to the cluster and returns the script retrying kubeadm join,
+// if requested.
+func (b *BaseUserData) setJoinCommand() ([]v1alpha2.Files, error) {
+	join := b.kubeadmCommandLine("join", "kubeadm-join.yaml")
+	if b.JoinRetry == nil {
+		b.JoinCommand = join
+		return nil, nil
+	}
+
+	attempts := b.JoinRetry.Attempts
+	if attempts <= 0 {
+		attempts = defaultJoinRetryAttempts
+	}
+	backoff := b.JoinRetry.InitialBackoffSeconds
+	if backoff <= 0 {
+		backoff = defaultJoinRetryInitialBackoffSeconds
+	}
+
+	t, err := template.New("JoinRetry").Parse(joinRetryScript)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse join retry template")
+	}
+
+	var out bytes.Buffer
+	err = t.Execute(&out, struct {
+		Attempts              int32
+		InitialBackoffSeconds int32
+		CertificatesDir       string
+		CertificatesBackupDir string
+		Join                  string
+		Reset                 string
+	}{
+		Attempts:              attempts,
+		InitialBackoffSeconds: backoff,
+		CertificatesDir:       kubeadmCertificatesDir,
+		CertificatesBackupDir: path.Join(b.GeneratedFilesDir, "pki.orig"),
+		Join:                  join,
+		Reset:                 fmt.Sprintf("%s reset --force", b.KubeadmCommand),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate join retry template")
+	}
+
+	script := path.Join(b.GeneratedFilesDir, joinRetryScriptName)
+	b.JoinCommand = fmt.Sprintf("sh %s", script)
+	return []v1alpha2.Files{{
+		Path:        script,
+		Owner:       rootOwnerValue,
+		Permissions: "0700",
+		Content:     out.String(),
+	}}, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	kubeadmRunScriptName = "kubeadm-run.sh"
+
+	// KubeadmLogFileName is the name of the file, in the generated files directory, keeping the output of kubeadm.
+	KubeadmLogFileName = "kubeadm.log"
+
+	kubeadmRunScript = `#!/bin/sh
+# Runs kubeadm, keeping its output in the log.
+log={{.Log}}
+status={{.Status}}
+# sh has no pipefail, so the exit status of kubeadm is passed around tee in a file.
+{ {{.Kubeadm}} 2>&1; echo $? >"$status"; } | tee "$log"
+code=$(cat "$status")
+rm -f "$status"
+{{- if .FailureHook }}
+if [ "$code" -ne 0 ]; then
+  {{.FailureHook}} "$log" || true
+fi
+{{- end }}
+exit "$code"
+`
+)
+
+// kubeadmRunFiles returns the script running the given kubeadm command and keeping its output, if requested, and
+// the command running it. The KubeadmFailureHook is run with the path of the log if kubeadm fails.
+func (b *BaseUserData) kubeadmRunFiles(kubeadm string) ([]v1alpha2.Files, string, error) {
+	if !b.KeepKubeadmOutput {
+		return nil, kubeadm, nil
+	}
+
+	t, err := template.New("KubeadmRun").Parse(kubeadmRunScript)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to parse kubeadm run template")
+	}
+
+	var out bytes.Buffer
+	err = t.Execute(&out, struct {
+		Log         string
+		Status      string
+		Kubeadm     string
+		FailureHook string
+	}{
+		Log:         path.Join(b.GeneratedFilesDir, KubeadmLogFileName),
+		Status:      path.Join(b.GeneratedFilesDir, "kubeadm.status"),
+		Kubeadm:     kubeadm,
+		FailureHook: b.KubeadmFailureHook,
+	})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to generate kubeadm run template")
+	}
+
+	script := path.Join(b.GeneratedFilesDir, kubeadmRunScriptName)
+	return []v1alpha2.Files{{
+		Path:        script,
+		Owner:       rootOwnerValue,
+		Permissions: "0700",
+		Content:     out.String(),
+	}}, fmt.Sprintf("sh %s", script), nil
+}
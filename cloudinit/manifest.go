@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"path"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// Manifest lists what user data writes and runs on a machine, so that it can be inspected without parsing the
+// user data.
+type Manifest struct {
+	KubeadmVersion string         `json:"kubeadmVersion,omitempty"`
+	KubeadmImage   string         `json:"kubeadmImage,omitempty"`
+	TraceID        string         `json:"traceID,omitempty"`
+	Files          []ManifestFile `json:"files"`
+	Commands       []string       `json:"commands"`
+}
+
+// ManifestFile is a file written by user data, without its content.
+type ManifestFile struct {
+	Path        string `json:"path"`
+	Owner       string `json:"owner,omitempty"`
+	Permissions string `json:"permissions,omitempty"`
+}
+
+// manifest returns the manifest of the user data once generated, given the name of the kubeadm configuration file
+// it writes.
+func (b *BaseUserData) manifest(kubeadmFile string) *Manifest {
+	m := &Manifest{
+		KubeadmVersion: b.KubernetesVersion,
+		TraceID:        b.TraceID,
+		Files:          make([]ManifestFile, 0, len(b.WriteFiles)+1),
+	}
+	if b.KubeadmContainer != nil {
+		m.KubeadmImage = b.KubeadmContainer.Image
+	}
+
+	files := append(append([]v1alpha2.Files{}, b.WriteFiles...), v1alpha2.Files{
+		Path:        path.Join(b.GeneratedFilesDir, kubeadmFile),
+		Owner:       rootOwnerValue,
+		Permissions: "0640",
+	})
+	for _, f := range files {
+		m.Files = append(m.Files, ManifestFile{Path: f.Path, Owner: f.Owner, Permissions: f.Permissions})
+	}
+
+	m.Commands = append(m.Commands, b.PreKubeadmCommands...)
+	m.Commands = append(m.Commands, b.BootstrapCommand, bootstrapSentinelCommand)
+	m.Commands = append(m.Commands, b.AdditionalCommands...)
+	return m
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	// DefaultMultipartMergeType is the merge type of the cloud-config parts of a MIME multi-part archive that sets
+	// none. It appends the lists of the user parts to the generated ones and keeps the generated values otherwise.
+	DefaultMultipartMergeType = "list(append)+dict(no_replace,recurse_list)+str()"
+
+	// jinjaContentType is the content type of cloud-init jinja templates, which the generated cloud-config is.
+	jinjaContentType = "text/jinja2"
+)
+
+// multipart returns the generated user data as the first part of a MIME multi-part archive followed by the parts of
+// b.Multipart, or the user data itself if b.Multipart is nil. The content of the parts must be resolved.
+func (b *BaseUserData) multipart(userData []byte) ([]byte, error) {
+	if b.Multipart == nil {
+		return userData, nil
+	}
+	mergeType := b.Multipart.MergeType
+	if mergeType == "" {
+		mergeType = DefaultMultipartMergeType
+	}
+
+	parts := append([]v1alpha2.UserDataPart{{ContentType: jinjaContentType, Content: string(userData)}}, b.Multipart.Parts...)
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	// a boundary derived from the content keeps the bootstrap data of an unchanged config unchanged
+	if err := w.SetBoundary(multipartBoundary(parts)); err != nil {
+		return nil, errors.Wrap(err, "failed to set the MIME multi-part boundary")
+	}
+	fmt.Fprintf(buf, "Content-Type: multipart/mixed; boundary=%q\r\nMIME-Version: 1.0\r\n\r\n", w.Boundary())
+
+	for i, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", part.ContentType))
+		header.Set("MIME-Version", "1.0")
+		if part.Filename != "" {
+			header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", part.Filename))
+		}
+		if part.ContentType == jinjaContentType || part.ContentType == "text/cloud-config" {
+			header.Set("Merge-Type", mergeType)
+		}
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create MIME part %d", i)
+		}
+		if _, err := pw.Write([]byte(part.Content)); err != nil {
+			return nil, errors.Wrapf(err, "failed to write MIME part %d", i)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close the MIME multi-part archive")
+	}
+	return buf.Bytes(), nil
+}
+
+// multipartBoundary returns the hash of the parts as their boundary, which their content cannot contain in practice.
+func multipartBoundary(parts []v1alpha2.UserDataPart) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%s\n%s\n%d\n%s", part.ContentType, part.Filename, len(part.Content), part.Content)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -18,16 +18,24 @@ package cloudinit
 
 const (
 	nodeCloudInit = `{{.Header}}
-write_files:
--   path: /tmp/kubeadm-node.yaml
+{{template "files" .WriteFiles}}
+-   path: {{.GeneratedFilesDir}}/kubeadm-join.yaml
     owner: root:root
     permissions: '0640'
     content: |
       ---
 {{.JoinConfiguration | Indent 6}}
+{{- template "disk_setup" . }}
+{{- template "ntp" .NTP }}
+{{- template "users" .Users }}
+{{- template "trace_output" .TraceID }}
 runcmd:
-  - 'kubeadm join --config /tmp/kubeadm-node.yaml'
+{{- template "trace_command" .TraceID }}
+{{- template "commands" .PreKubeadmCommands }}
+  - '{{ SingleQuote .BootstrapCommand }}'
+  - '` + bootstrapSentinelCommand + `'
 {{- template "commands" .AdditionalCommands }}
+{{- template "extra_cloud_config" .ExtraCloudConfig }}
 `
 )
 
@@ -41,6 +49,33 @@ type NodeInput struct {
 // NewNode returns the user data string to be used on a node instance.
 func NewNode(input *NodeInput) ([]byte, error) {
 	input.Header = cloudConfigHeader
+	if err := input.setDefaults(); err != nil {
+		return nil, err
+	}
+	generatedFiles, err := input.generatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	joinFiles, err := input.setJoinCommand()
+	if err != nil {
+		return nil, err
+	}
+	generatedFiles = append(generatedFiles, joinFiles...)
+	bootstrapFiles, err := input.setBootstrapCommand(input.JoinCommand)
+	if err != nil {
+		return nil, err
+	}
+	generatedFiles = append(generatedFiles, bootstrapFiles...)
+
 	input.WriteFiles = append(input.WriteFiles, input.AdditionalFiles...)
-	return generate("Node", nodeCloudInit, input)
+	input.WriteFiles, err = encodeFiles(append(input.WriteFiles, generatedFiles...))
+	if err != nil {
+		return nil, err
+	}
+	userData, err := generate("Node", nodeCloudInit, input)
+	if err != nil {
+		return nil, err
+	}
+	input.Manifest = input.manifest("kubeadm-join.yaml")
+	return input.multipart(userData)
 }
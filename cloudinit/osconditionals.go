@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"fmt"
+	"path"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	osFamilyScriptName = "os-family.sh"
+
+	// osFamilyScript prints the OS family of the machine, as detected from /etc/os-release.
+	osFamilyScript = `#!/bin/sh
+. /etc/os-release
+for id in $ID $ID_LIKE; do
+  case $id in
+    debian | ubuntu) echo debian; exit 0 ;;
+    rhel | centos | fedora) echo rhel; exit 0 ;;
+    flatcar | coreos) echo flatcar; exit 0 ;;
+  esac
+done
+echo unknown
+`
+)
+
+// osFamilyCondition returns the shell condition matching machines of the given OS family.
+func (b *BaseUserData) osFamilyCondition(family v1alpha2.OSFamily) string {
+	return fmt.Sprintf(`[ "$(sh %s/%s)" = "%s" ]`, b.GeneratedFilesDir, osFamilyScriptName, family)
+}
+
+// osConditionalStagingPath returns where a file conditional on the given OS family is written, until it is
+// moved in place on machines of that family.
+func (b *BaseUserData) osConditionalStagingPath(family v1alpha2.OSFamily, file v1alpha2.Files) string {
+	return path.Join(b.GeneratedFilesDir, "os", string(family), file.Path)
+}
+
+// setOSConditionals appends to the commands run before kubeadm the ones moving the files conditional on an OS
+// family in place, and running the commands conditional on an OS family, on machines of that family.
+func (b *BaseUserData) setOSConditionals() {
+	for _, conditional := range b.OSConditionals {
+		condition := b.osFamilyCondition(conditional.OSFamily)
+		for _, file := range conditional.Files {
+			b.PreKubeadmCommands = append(b.PreKubeadmCommands, fmt.Sprintf("if %s; then mkdir -p %s && mv %s %s; fi",
+				condition, path.Dir(file.Path), b.osConditionalStagingPath(conditional.OSFamily, file), file.Path))
+		}
+		// the command is evaluated from a single word, so that a comment in it does not comment out the fi
+		for _, command := range conditional.PreKubeadmCommands {
+			b.PreKubeadmCommands = append(b.PreKubeadmCommands, fmt.Sprintf("if %s; then eval %s; fi", condition, shellQuote(command)))
+		}
+	}
+}
+
+// osConditionalFiles returns the OS family detection script and the staged files conditional on an OS family.
+func (b *BaseUserData) osConditionalFiles() []v1alpha2.Files {
+	if len(b.OSConditionals) == 0 {
+		return nil
+	}
+
+	files := []v1alpha2.Files{{
+		Path:        path.Join(b.GeneratedFilesDir, osFamilyScriptName),
+		Owner:       rootOwnerValue,
+		Permissions: "0700",
+		Content:     osFamilyScript,
+	}}
+	for _, conditional := range b.OSConditionals {
+		for _, file := range conditional.Files {
+			staged := file
+			staged.Path = b.osConditionalStagingPath(conditional.OSFamily, file)
+			files = append(files, staged)
+		}
+	}
+	return files
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	aptSourcesDir  = "/etc/apt/sources.list.d"
+	aptKeyringsDir = "/etc/apt/keyrings"
+	yumReposDir    = "/etc/yum.repos.d"
+	rpmGPGKeysDir  = "/etc/pki/rpm-gpg"
+
+	defaultAptComponent = "main"
+
+	// yumRepoTemplate is a yum repository checking the signatures of both its packages and its metadata.
+	yumRepoTemplate = `[%[1]s]
+name=%[1]s
+baseurl=%[2]s
+enabled=1
+gpgcheck=1
+repo_gpgcheck=1
+gpgkey=file://%[3]s
+`
+)
+
+// packageRepositoryConditional returns the files configuring the repository, along with its GPG key, on machines
+// of its OS family.
+func packageRepositoryConditional(repo v1alpha2.PackageRepository) v1alpha2.OSConditional {
+	var keyPath, repoPath, content string
+	switch repo.OSFamily {
+	case v1alpha2.OSFamilyRHEL:
+		keyPath = path.Join(rpmGPGKeysDir, "RPM-GPG-KEY-"+repo.Name)
+		repoPath = path.Join(yumReposDir, repo.Name+".repo")
+		content = fmt.Sprintf(yumRepoTemplate, repo.Name, repo.URL, keyPath)
+	default:
+		components := repo.Components
+		if len(components) == 0 {
+			components = []string{defaultAptComponent}
+		}
+		// apt only reads ASCII armored keys from files with the .asc extension.
+		keyPath = path.Join(aptKeyringsDir, repo.Name+".asc")
+		repoPath = path.Join(aptSourcesDir, repo.Name+".list")
+		content = fmt.Sprintf("deb [signed-by=%s] %s %s %s\n", keyPath, repo.URL, repo.Distribution, strings.Join(components, " "))
+	}
+
+	return v1alpha2.OSConditional{
+		OSFamily: repo.OSFamily,
+		Files: []v1alpha2.Files{
+			{Path: keyPath, Owner: rootOwnerValue, Permissions: "0644", Content: repo.GPGKey},
+			{Path: repoPath, Owner: rootOwnerValue, Permissions: "0644", Content: content},
+		},
+	}
+}
+
+// setPackageRepositories prepends to the OS conditionals the package repositories, so that they are configured
+// before any command conditional on the OS family installs packages, and refreshes the apt package index.
+func (b *BaseUserData) setPackageRepositories() {
+	if len(b.PackageRepositories) == 0 {
+		return
+	}
+
+	var conditionals []v1alpha2.OSConditional
+	var apt bool
+	for _, repo := range b.PackageRepositories {
+		conditionals = append(conditionals, packageRepositoryConditional(repo))
+		apt = apt || repo.OSFamily == v1alpha2.OSFamilyDebian
+	}
+	if apt {
+		conditionals = append(conditionals, v1alpha2.OSConditional{
+			OSFamily:           v1alpha2.OSFamilyDebian,
+			PreKubeadmCommands: []string{"apt-get update"},
+		})
+	}
+	b.OSConditionals = append(conditionals, b.OSConditionals...)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	imagePreflightScriptName = "image-preflight.sh"
+
+	// ImagePreflightFailedFileName is the name of the file, in the generated files directory, listing why the
+	// machine image did not pass the image preflight.
+	ImagePreflightFailedFileName = "image-preflight.failed"
+
+	imagePreflightScript = `#!/bin/sh
+# Verifies that the machine image provides what this bootstrap data expects.
+failed={{.Failed}}
+rm -f "$failed"
+{{- if .InstallDir }}
+PATH="{{.InstallDir}}:$PATH"
+{{- end }}
+
+fail() {
+  echo "image preflight: $*" | tee -a "$failed" >&2
+}
+
+# check_version <name> <expected version> <command> [args...]
+check_version() {
+  name=$1
+  expected=$2
+  shift 2
+  if ! command -v "$1" >/dev/null 2>&1; then
+    fail "$name not found"
+    return
+  fi
+  actual=$("$@" 2>&1)
+  case " $actual " in
+    *" $expected "* | *" $expected."*) ;;
+    *) fail "expected $name $expected, found: $actual" ;;
+  esac
+}
+
+check_command() {
+  if ! command -v "$1" >/dev/null 2>&1; then
+    fail "$1 not found"
+  fi
+}
+
+check_path() {
+  if [ ! -e "$1" ]; then
+    fail "$1 does not exist"
+  fi
+}
+{{ if .CheckKubeadm }}
+{{ if .KubernetesVersion }}check_version kubeadm {{.KubernetesVersion}} kubeadm version -o short{{ else }}check_command kubeadm{{ end }}
+{{- end }}
+{{ if .KubernetesVersion }}check_version kubelet {{.KubernetesVersion}} kubelet --version{{ else }}check_command kubelet{{ end }}
+{{ if .ContainerdVersion }}check_version containerd {{.ContainerdVersion}} containerd --version{{ else }}check_command containerd{{ end }}
+{{- range .RequiredPaths }}
+check_path "{{.}}"
+{{- end }}
+
+if [ -e "$failed" ]; then
+  exit 1
+fi
+`
+)
+
+// setImagePreflight prepends the image preflight to the commands run before kubeadm.
+func (b *BaseUserData) setImagePreflight() {
+	if b.ImagePreflight == nil {
+		return
+	}
+	// runcmd entries run as a single script, so exiting stops the bootstrap before kubeadm is invoked.
+	command := fmt.Sprintf("sh %s/%s || exit 1", b.GeneratedFilesDir, imagePreflightScriptName)
+	b.PreKubeadmCommands = append([]string{command}, b.PreKubeadmCommands...)
+}
+
+// imagePreflightFiles returns the image preflight script, if requested.
+func (b *BaseUserData) imagePreflightFiles() ([]v1alpha2.Files, error) {
+	if b.ImagePreflight == nil {
+		return nil, nil
+	}
+
+	t, err := template.New("ImagePreflight").Parse(imagePreflightScript)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse image preflight template")
+	}
+
+	var installDir string
+	if b.BinaryInstall != nil {
+		installDir = b.binaryInstallDir()
+	}
+
+	var out bytes.Buffer
+	err = t.Execute(&out, struct {
+		Failed            string
+		InstallDir        string
+		CheckKubeadm      bool
+		KubernetesVersion string
+		ContainerdVersion string
+		RequiredPaths     []string
+	}{
+		Failed:            fmt.Sprintf("%s/%s", b.GeneratedFilesDir, ImagePreflightFailedFileName),
+		InstallDir:        installDir,
+		CheckKubeadm:      b.KubeadmContainer == nil,
+		KubernetesVersion: normalizeVersion(b.KubernetesVersion),
+		ContainerdVersion: normalizeVersion(b.ImagePreflight.ContainerdVersion),
+		RequiredPaths:     b.ImagePreflight.RequiredPaths,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate image preflight template")
+	}
+
+	return []v1alpha2.Files{{
+		Path:        fmt.Sprintf("%s/%s", b.GeneratedFilesDir, imagePreflightScriptName),
+		Owner:       rootOwnerValue,
+		Permissions: "0700",
+		Content:     out.String(),
+	}}, nil
+}
+
+// normalizeVersion prefixes a version with v, the way the binaries report it.
+func normalizeVersion(version string) string {
+	if version == "" || strings.HasPrefix(version, "v") {
+		return version
+	}
+	return "v" + version
+}
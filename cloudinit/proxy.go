@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// proxyUnits are the systemd units reaching the network through the proxy.
+var proxyUnits = []string{"containerd", "kubelet"}
+
+// setProxy reloads the systemd units with the proxy drop-ins, restarts containerd so that the images pulled
+// before kubeadm is run go through the proxy, and exports the proxy environment to the commands run after, kubeadm
+// included.
+func (b *BaseUserData) setProxy() {
+	if b.Proxy == nil {
+		return
+	}
+	commands := []string{"systemctl daemon-reload", "systemctl restart containerd"}
+	var exports []string
+	for _, v := range b.proxyEnvironment() {
+		// curl and wget only read the lower case variables
+		exports = append(exports, fmt.Sprintf("%s=\"%s\"", v.name, v.value), fmt.Sprintf("%s=\"%s\"", strings.ToLower(v.name), v.value))
+	}
+	if len(exports) > 0 {
+		commands = append(commands, "export "+strings.Join(exports, " "))
+	}
+	b.PreKubeadmCommands = append(commands, b.PreKubeadmCommands...)
+}
+
+// proxyVariable is an environment variable configuring the proxy.
+type proxyVariable struct {
+	name, value string
+}
+
+// proxyEnvironment returns the environment variables of the proxy that are set.
+func (b *BaseUserData) proxyEnvironment() []proxyVariable {
+	var env []proxyVariable
+	for _, v := range []proxyVariable{
+		{"HTTP_PROXY", b.Proxy.HTTPProxy},
+		{"HTTPS_PROXY", b.Proxy.HTTPSProxy},
+		{"NO_PROXY", strings.Join(b.Proxy.NoProxy, ",")},
+	} {
+		if v.value != "" {
+			env = append(env, v)
+		}
+	}
+	return env
+}
+
+// proxyFiles returns the systemd drop-ins setting the proxy environment of the container runtime and kubelet.
+func (b *BaseUserData) proxyFiles() []v1alpha2.Files {
+	if b.Proxy == nil {
+		return nil
+	}
+
+	var env strings.Builder
+	env.WriteString("[Service]\n")
+	for _, v := range b.proxyEnvironment() {
+		fmt.Fprintf(&env, "Environment=\"%s=%s\"\n", v.name, v.value)
+	}
+
+	files := make([]v1alpha2.Files, 0, len(proxyUnits))
+	for _, unit := range proxyUnits {
+		files = append(files, v1alpha2.Files{
+			Path:        fmt.Sprintf("/etc/systemd/system/%s.service.d/http-proxy.conf", unit),
+			Owner:       rootOwnerValue,
+			Permissions: "0644",
+			Content:     env.String(),
+		})
+	}
+	return files
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	// containerdCertsDir is the registry configuration directory of containerd, which the registries script sets
+	// as the config_path of the CRI registry plugin. It requires containerd 1.5 or later.
+	containerdCertsDir = "/etc/containerd/certs.d"
+
+	// containerdConfigFile is the configuration of containerd, which machine images without one get the default of.
+	containerdConfigFile = "/etc/containerd/config.toml"
+
+	registriesScriptName    = "containerd-registries.sh"
+	registryAuthFileName    = "containerd-registry-auth.toml"
+	registryAuthFileComment = "# registry credentials written by the bootstrap data"
+
+	// registriesScript sets the registry configuration directory of containerd and appends the registry
+	// credentials to its configuration, unless they already are, then restarts it.
+	registriesScript = `#!/bin/sh
+# Configures the registries of containerd and restarts it.
+set -e
+config=` + containerdConfigFile + `
+auth=%s
+registry='[plugins."io.containerd.grpc.v1.cri".registry]'
+config_path='config_path = "` + containerdCertsDir + `"'
+
+if [ ! -s "$config" ]; then
+  mkdir -p "$(dirname "$config")"
+  containerd config default > "$config"
+fi
+
+if grep -q '^[[:space:]]*config_path[[:space:]]*=' "$config"; then
+  sed -i "s#^\([[:space:]]*\)config_path[[:space:]]*=.*#\1$config_path#" "$config"
+elif grep -qF "$registry" "$config"; then
+  awk -v table="$registry" -v line="  $config_path" '{ print } { t = $0; gsub(/^[ \t]+|[ \t]+$/, "", t) } t == table { print line }' \
+    "$config" > "$config.tmp"
+  mv "$config.tmp" "$config"
+else
+  printf '\n%%s\n  %%s\n' "$registry" "$config_path" >> "$config"
+fi
+
+if [ -f "$auth" ] && ! grep -qF '` + registryAuthFileComment + `' "$config"; then
+  cat "$auth" >> "$config"
+fi
+systemctl restart containerd
+`
+)
+
+// registryServers are the upstream servers of the registries whose host differs from their name.
+var registryServers = map[string]string{
+	"docker.io": "https://registry-1.docker.io",
+}
+
+// RegistryCredential is the username and password containerd authenticates to a registry host with.
+type RegistryCredential struct {
+	Host     string
+	Username string
+	Password string
+}
+
+// setRegistries runs the registries script, before the commands pulling images, if the user data configures
+// registry mirrors or credentials.
+func (b *BaseUserData) setRegistries() {
+	if len(b.RegistryMirrors) == 0 && len(b.RegistryCredentials) == 0 {
+		return
+	}
+	script := fmt.Sprintf("sh %s", path.Join(b.GeneratedFilesDir, registriesScriptName))
+	b.PreKubeadmCommands = append([]string{script}, b.PreKubeadmCommands...)
+}
+
+// registryMirrorFiles returns the containerd hosts files pulling the images of each registry from its mirrors,
+// falling back to the registry itself, the registry credentials appended to the containerd configuration and the
+// script configuring containerd with them.
+func (b *BaseUserData) registryMirrorFiles() []v1alpha2.Files {
+	if len(b.RegistryMirrors) == 0 && len(b.RegistryCredentials) == 0 {
+		return nil
+	}
+
+	files := make([]v1alpha2.Files, 0, len(b.RegistryMirrors)+2)
+	for _, mirror := range b.RegistryMirrors {
+		server, ok := registryServers[mirror.Registry]
+		if !ok {
+			server = "https://" + mirror.Registry
+		}
+
+		var hosts strings.Builder
+		fmt.Fprintf(&hosts, "server = %q\n", server)
+		for _, endpoint := range mirror.Endpoints {
+			fmt.Fprintf(&hosts, "\n[host.%q]\n  capabilities = [\"pull\", \"resolve\"]\n", endpoint)
+		}
+
+		files = append(files, v1alpha2.Files{
+			Path:        path.Join(containerdCertsDir, mirror.Registry, "hosts.toml"),
+			Owner:       rootOwnerValue,
+			Permissions: "0644",
+			Content:     hosts.String(),
+		})
+	}
+
+	authFile := path.Join(b.GeneratedFilesDir, registryAuthFileName)
+	if len(b.RegistryCredentials) > 0 {
+		var auth strings.Builder
+		fmt.Fprintf(&auth, "\n%s\n", registryAuthFileComment)
+		for _, c := range b.RegistryCredentials {
+			fmt.Fprintf(&auth, "[plugins.\"io.containerd.grpc.v1.cri\".registry.configs.%q.auth]\n", c.Host)
+			fmt.Fprintf(&auth, "  username = %q\n  password = %q\n", c.Username, c.Password)
+		}
+		files = append(files, v1alpha2.Files{
+			Path:        authFile,
+			Owner:       rootOwnerValue,
+			Permissions: "0600",
+			Content:     auth.String(),
+		})
+	}
+	return append(files, v1alpha2.Files{
+		Path:        path.Join(b.GeneratedFilesDir, registriesScriptName),
+		Owner:       rootOwnerValue,
+		Permissions: "0700",
+		Content:     fmt.Sprintf(registriesScript, authFile),
+	})
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+
+const (
+	// BootstrapSentinelFile is the file the user data writes once kubeadm succeeded, so that infrastructure
+	// providers and node health checks can tell that the machine bootstrapped.
+	BootstrapSentinelFile = "/run/cluster-api/bootstrap-success.complete"
+
+	// bootstrapSentinelCommand writes the bootstrap sentinel file if the command run right before it, kubeadm,
+	// succeeded. It is a command of its own, so that kubeadm failing still stops scripts run with set -e.
+	bootstrapSentinelCommand = "test $? -eq 0 && mkdir -p /run/cluster-api && echo success > " + BootstrapSentinelFile
+)
+
+// setBootstrapCommand sets the command running kubeadm, and returns the script keeping the kubeadm output, if
+// requested.
+func (b *BaseUserData) setBootstrapCommand(kubeadm string) ([]v1alpha2.Files, error) {
+	files, kubeadm, err := b.kubeadmRunFiles(kubeadm)
+	if err != nil {
+		return nil, err
+	}
+	b.BootstrapCommand = kubeadm
+	return files, nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TraceIDEnv is the environment variable holding the trace ID of the bootstrap, for the commands run.
+	TraceIDEnv = "CABPK_TRACE_ID"
+
+	// traceOutputTemplate prefixes each line cloud-init logs with the trace ID, still writing to the default log.
+	traceOutputTemplate = `{{- define "trace_output" -}}
+{{- if . }}
+output:
+  all: '| sed -u "s/^/[{{ . }}] /" | tee -a /var/log/cloud-init-output.log'
+{{- end -}}
+{{- end -}}
+`
+
+	// traceCommandTemplate exports the trace ID; runcmd entries run as a single script, so the commands after it
+	// see it.
+	traceCommandTemplate = `{{- define "trace_command" -}}
+{{- if . }}
+  - 'export ` + TraceIDEnv + `={{ . }}'
+{{- end -}}
+{{- end -}}
+`
+)
+
+// traceIDRegexp matches the trace IDs that can be rendered unquoted in a command and a sed expression.
+var traceIDRegexp = regexp.MustCompile(`^[0-9a-zA-Z-]{1,64}$`)
+
+// validateTraceID returns an error if the trace ID is set but cannot be rendered.
+func (b *BaseUserData) validateTraceID() error {
+	if b.TraceID != "" && !traceIDRegexp.MatchString(b.TraceID) {
+		return errors.Errorf("invalid trace ID %q", b.TraceID)
+	}
+	return nil
+}
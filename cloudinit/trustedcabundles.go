@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudinit
+
+import (
+	"fmt"
+	"path"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// trustStore is where an OS family reads additional CA certificates from, and the command adding them to its
+// trust store.
+type trustStore struct {
+	family    v1alpha2.OSFamily
+	dir       string
+	extension string
+	command   string
+}
+
+var trustStores = []trustStore{
+	{family: v1alpha2.OSFamilyDebian, dir: "/usr/local/share/ca-certificates", extension: ".crt", command: "update-ca-certificates"},
+	{family: v1alpha2.OSFamilyRHEL, dir: "/etc/pki/ca-trust/source/anchors", extension: ".pem", command: "update-ca-trust extract"},
+	{family: v1alpha2.OSFamilyFlatcar, dir: "/etc/ssl/certs", extension: ".pem", command: "update-ca-certificates"},
+}
+
+// setTrustedCABundles prepends to the OS conditionals the CA bundles installed into the trust store of each OS
+// family, so that they are trusted before any package repository is used or image pulled.
+func (b *BaseUserData) setTrustedCABundles() {
+	if len(b.TrustedCABundles) == 0 {
+		return
+	}
+
+	conditionals := make([]v1alpha2.OSConditional, 0, len(trustStores))
+	for _, store := range trustStores {
+		conditional := v1alpha2.OSConditional{
+			OSFamily:           store.family,
+			PreKubeadmCommands: []string{store.command},
+		}
+		for i, bundle := range b.TrustedCABundles {
+			conditional.Files = append(conditional.Files, v1alpha2.Files{
+				Path:        path.Join(store.dir, fmt.Sprintf("cabpk-trusted-ca-%d%s", i, store.extension)),
+				Owner:       rootOwnerValue,
+				Permissions: "0644",
+				Content:     bundle,
+			})
+		}
+		conditionals = append(conditionals, conditional)
+	}
+	b.OSConditionals = append(conditionals, b.OSConditionals...)
+}
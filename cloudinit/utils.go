@@ -30,6 +30,7 @@ var (
 	defaultTemplateFuncMap = template.FuncMap{
 		"Base64Encode": templateBase64Encode,
 		"Indent":       templateYAMLIndent,
+		"SingleQuote":  templateYAMLSingleQuote,
 	}
 )
 
@@ -37,6 +38,16 @@ func templateBase64Encode(s string) string {
 	return base64.StdEncoding.EncodeToString([]byte(s))
 }
 
+// templateYAMLSingleQuote escapes s to be rendered within single quotes, where YAML escapes a quote by doubling it.
+func templateYAMLSingleQuote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+// shellQuote quotes s as a single shell word, closing the single quotes around each single quote it contains.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 func templateYAMLIndent(i int, input string) string {
 	split := strings.Split(input, "\n")
 	ident := "\n" + strings.Repeat(" ", i)
@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// admissionDir is the directory of the admission configuration files on control plane machines, mounted in
+	// the API server pod.
+	admissionDir = "/etc/kubernetes/admission"
+
+	admissionVolume = "admission-config"
+)
+
+// admissionConfigurationPath is the path of the admission configuration on control plane machines.
+var admissionConfigurationPath = path.Join(admissionDir, "admission.yaml")
+
+type admissionConfiguration struct {
+	APIVersion string                  `json:"apiVersion"`
+	Kind       string                  `json:"kind"`
+	Plugins    []admissionPluginConfig `json:"plugins"`
+}
+
+type admissionPluginConfig struct {
+	Name          string      `json:"name"`
+	Path          string      `json:"path,omitempty"`
+	Configuration interface{} `json:"configuration,omitempty"`
+}
+
+// admissionPluginConfigurationPath returns the path of the configuration file of the given admission plugin
+// on control plane machines.
+func admissionPluginConfigurationPath(name string) string {
+	return path.Join(admissionDir, strings.ToLower(name)+".yaml")
+}
+
+// hasAdmissionConfiguration returns whether an admission configuration is generated for the config.
+func hasAdmissionConfiguration(spec *cabpkv1alpha2.KubeadmConfigSpec) bool {
+	return spec.PodSecurity != nil || (spec.AdmissionPlugins != nil && len(spec.AdmissionPlugins.Configurations) > 0)
+}
+
+// admissionFiles returns the admission configuration, and the configuration files of the admission plugins it
+// refers to, to write on control plane machines of the given Kubernetes version, if any.
+func admissionFiles(config *cabpkv1alpha2.KubeadmConfig, version string) ([]cabpkv1alpha2.Files, error) {
+	if !hasAdmissionConfiguration(&config.Spec) {
+		return nil, nil
+	}
+
+	var files []cabpkv1alpha2.Files
+	var plugins []admissionPluginConfig
+	if config.Spec.PodSecurity != nil {
+		plugins = append(plugins, admissionPluginConfig{
+			Name:          "PodSecurity",
+			Configuration: newPodSecurityConfiguration(config.Spec.PodSecurity, version),
+		})
+	}
+	if config.Spec.AdmissionPlugins != nil {
+		for _, plugin := range config.Spec.AdmissionPlugins.Configurations {
+			pluginPath := admissionPluginConfigurationPath(plugin.Name)
+			plugins = append(plugins, admissionPluginConfig{Name: plugin.Name, Path: pluginPath})
+			files = append(files, cabpkv1alpha2.Files{
+				Path:        pluginPath,
+				Owner:       "root:root",
+				Permissions: "0600",
+				Content:     plugin.Configuration,
+			})
+		}
+	}
+
+	content, err := yaml.Marshal(admissionConfiguration{
+		APIVersion: "apiserver.config.k8s.io/v1",
+		Kind:       "AdmissionConfiguration",
+		Plugins:    plugins,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the admission configuration")
+	}
+
+	return append([]cabpkv1alpha2.Files{{
+		Path:        admissionConfigurationPath,
+		Owner:       "root:root",
+		Permissions: "0600",
+		Content:     string(content),
+	}}, files...), nil
+}
+
+// wireAdmission passes the enabled and disabled admission plugins, and the admission configuration, to the API
+// server, and mounts the directory of the admission configuration files in the API server pod. Control planes
+// joining the cluster run with the ClusterConfiguration stored by kubeadm init.
+func wireAdmission(spec *cabpkv1alpha2.KubeadmConfigSpec, cfg *kubeadmv1beta1.ClusterConfiguration) {
+	args := map[string]string{}
+	if plugins := spec.AdmissionPlugins; plugins != nil {
+		if len(plugins.Enable) > 0 {
+			args["enable-admission-plugins"] = strings.Join(plugins.Enable, ",")
+		}
+		if len(plugins.Disable) > 0 {
+			args["disable-admission-plugins"] = strings.Join(plugins.Disable, ",")
+		}
+	}
+	if hasAdmissionConfiguration(spec) {
+		args["admission-control-config-file"] = admissionConfigurationPath
+	}
+	if len(args) == 0 {
+		return
+	}
+	cfg.APIServer.ExtraArgs = defaultArgs(cfg.APIServer.ExtraArgs, args)
+	if hasAdmissionConfiguration(spec) {
+		mountDirectory(&cfg.APIServer.ControlPlaneComponent, admissionVolume, admissionDir)
+	}
+}
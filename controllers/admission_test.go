@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+func TestAdmissionFiles(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.PodSecurity = &cabpkv1alpha2.PodSecurity{Enforce: cabpkv1alpha2.PodSecurityLevelBaseline}
+	config.Spec.AdmissionPlugins = &cabpkv1alpha2.AdmissionPlugins{
+		Enable: []string{"EventRateLimit"},
+		Configurations: []cabpkv1alpha2.AdmissionPluginConfiguration{
+			{Name: "EventRateLimit", Configuration: "kind: Configuration\n"},
+		},
+	}
+
+	files, err := admissionFiles(config, "v1.26.1")
+	if err != nil {
+		t.Fatalf("failed to generate the admission configuration: %v", err)
+	}
+	if len(files) != 2 || files[0].Path != "/etc/kubernetes/admission/admission.yaml" || files[1].Path != "/etc/kubernetes/admission/eventratelimit.yaml" {
+		t.Fatalf("expected the admission configuration and the plugin configuration to be written, got %+v", files)
+	}
+	for _, expected := range []string{
+		"kind: AdmissionConfiguration\n",
+		"- configuration:\n",
+		"enforce: baseline\n",
+		"name: PodSecurity\n",
+		"- name: EventRateLimit\n  path: /etc/kubernetes/admission/eventratelimit.yaml\n",
+	} {
+		if !strings.Contains(files[0].Content, expected) {
+			t.Fatalf("expected the admission configuration to contain %q, got:\n%s", expected, files[0].Content)
+		}
+	}
+	if files[1].Content != "kind: Configuration\n" {
+		t.Fatalf("expected the plugin configuration to be written as is, got %q", files[1].Content)
+	}
+
+	config.Spec.PodSecurity = nil
+	config.Spec.AdmissionPlugins.Configurations = nil
+	if files, err := admissionFiles(config, "v1.26.1"); err != nil || files != nil {
+		t.Fatalf("expected no admission configuration, got %+v, %v", files, err)
+	}
+}
+
+func TestWireAdmission(t *testing.T) {
+	spec := &cabpkv1alpha2.KubeadmConfigSpec{
+		PodSecurity: &cabpkv1alpha2.PodSecurity{},
+		AdmissionPlugins: &cabpkv1alpha2.AdmissionPlugins{
+			Enable:  []string{"EventRateLimit", "AlwaysPullImages"},
+			Disable: []string{"DefaultStorageClass"},
+		},
+	}
+	cfg := &kubeadmv1beta1.ClusterConfiguration{}
+
+	wireAdmission(spec, cfg)
+	wireAdmission(spec, cfg)
+
+	for arg, expected := range map[string]string{
+		"enable-admission-plugins":      "EventRateLimit,AlwaysPullImages",
+		"disable-admission-plugins":     "DefaultStorageClass",
+		"admission-control-config-file": "/etc/kubernetes/admission/admission.yaml",
+	} {
+		if cfg.APIServer.ExtraArgs[arg] != expected {
+			t.Fatalf("expected the API server flag %s to be %q, got %v", arg, expected, cfg.APIServer.ExtraArgs)
+		}
+	}
+	if len(cfg.APIServer.ExtraVolumes) != 1 || cfg.APIServer.ExtraVolumes[0].HostPath != "/etc/kubernetes/admission" || !cfg.APIServer.ExtraVolumes[0].ReadOnly {
+		t.Fatalf("expected the admission configuration to be mounted once, read only, got %+v", cfg.APIServer.ExtraVolumes)
+	}
+}
+
+func TestWireAdmissionWithoutConfiguration(t *testing.T) {
+	spec := &cabpkv1alpha2.KubeadmConfigSpec{
+		AdmissionPlugins: &cabpkv1alpha2.AdmissionPlugins{Enable: []string{"AlwaysPullImages"}},
+	}
+	cfg := &kubeadmv1beta1.ClusterConfiguration{}
+
+	wireAdmission(spec, cfg)
+
+	if _, ok := cfg.APIServer.ExtraArgs["admission-control-config-file"]; ok {
+		t.Fatalf("expected no admission configuration to be passed, got %v", cfg.APIServer.ExtraArgs)
+	}
+	if len(cfg.APIServer.ExtraVolumes) != 0 {
+		t.Fatalf("expected no volume, got %+v", cfg.APIServer.ExtraVolumes)
+	}
+}
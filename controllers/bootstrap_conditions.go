@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api/pkg/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// setCertificatesAvailableCondition sets the CertificatesAvailable condition of the config.
+func setCertificatesAvailableCondition(config *cabpkv1alpha2.KubeadmConfig, available bool, reason, message string) {
+	status := corev1.ConditionFalse
+	if available {
+		status = corev1.ConditionTrue
+	}
+	setCondition(&config.Status, cabpkv1alpha2.KubeadmConfigCondition{
+		Type:    cabpkv1alpha2.CertificatesAvailableCondition,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}, time.Now())
+}
+
+// setDataSecretAvailableCondition sets the DataSecretAvailable condition of the config once its bootstrap data is
+// set, naming where the machine finds it.
+func setDataSecretAvailableCondition(config *cabpkv1alpha2.KubeadmConfig) {
+	condition := cabpkv1alpha2.KubeadmConfigCondition{
+		Type:    cabpkv1alpha2.DataSecretAvailableCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "BootstrapDataSet",
+		Message: "the bootstrap data is set in the status",
+	}
+	if config.Status.DataSecretName != nil {
+		condition.Reason = "DataSecretCreated"
+		condition.Message = fmt.Sprintf("the bootstrap data is stored in secret %s", *config.Status.DataSecretName)
+	}
+	setCondition(&config.Status, condition, time.Now())
+}
+
+// clearDataSecretAvailableCondition sets the DataSecretAvailable condition of the config to false, while its
+// bootstrap data is not generated or after it was cleared.
+func clearDataSecretAvailableCondition(config *cabpkv1alpha2.KubeadmConfig, reason, message string) {
+	setCondition(&config.Status, cabpkv1alpha2.KubeadmConfigCondition{
+		Type:    cabpkv1alpha2.DataSecretAvailableCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}, time.Now())
+}
+
+// setControlPlaneInitializedCondition sets the ControlPlaneInitialized condition of the config.
+func setControlPlaneInitializedCondition(config *cabpkv1alpha2.KubeadmConfig, clusterName string, initialized bool) {
+	condition := cabpkv1alpha2.KubeadmConfigCondition{
+		Type:    cabpkv1alpha2.ControlPlaneInitializedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ControlPlaneInitialized",
+		Message: fmt.Sprintf("the control plane of cluster %s is initialized", clusterName),
+	}
+	if !initialized {
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "WaitingForControlPlane"
+		condition.Message = fmt.Sprintf("the control plane of cluster %s is not initialized yet", clusterName)
+	}
+	setCondition(&config.Status, condition, time.Now())
+}
+
+// reconcileControlPlaneInitializedCondition sets the ControlPlaneInitialized condition of a ready config once the
+// control plane of its cluster is initialized. Ready configs are not reconciled further, so this is how the config
+// of the machine that initialized the cluster observes it.
+func (r *KubeadmConfigReconciler) reconcileControlPlaneInitializedCondition(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) error {
+	if !hasCondition(&config.Status, cabpkv1alpha2.ControlPlaneInitializedCondition) ||
+		conditionTrue(&config.Status, cabpkv1alpha2.ControlPlaneInitializedCondition) {
+		return nil
+	}
+
+	machine, err := util.GetOwnerMachine(ctx, r.Client, config.ObjectMeta)
+	if err != nil || machine == nil {
+		return err
+	}
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, machine.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	initialized, err := r.controlPlaneInitialized(ctx, cluster)
+	if err != nil || !initialized {
+		return err
+	}
+
+	patchConfig := client.MergeFrom(config.DeepCopy())
+	setControlPlaneInitializedCondition(config, cluster.GetName(), true)
+	return r.Status().Patch(ctx, config, patchConfig)
+}
+
+// conditionTrue returns true if the status has a condition of the given type that is true.
+func conditionTrue(status *cabpkv1alpha2.KubeadmConfigStatus, conditionType cabpkv1alpha2.KubeadmConfigConditionType) bool {
+	for _, c := range status.Conditions {
+		if c.Type == conditionType {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
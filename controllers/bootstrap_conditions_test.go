@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileSetsBootstrapConditions(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg")
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, controlPlaneMachine, controlPlaneInitConfig)
+	locker := &fakeInitLocker{}
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: locker,
+	}
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg"},
+	}
+
+	if _, err := k.Reconcile(request); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	expectCondition(t, cfg, cabpkv1alpha2.CertificatesAvailableCondition, corev1.ConditionTrue)
+	expectCondition(t, cfg, cabpkv1alpha2.DataSecretAvailableCondition, corev1.ConditionTrue)
+	expectCondition(t, cfg, cabpkv1alpha2.ControlPlaneInitializedCondition, corev1.ConditionFalse)
+
+	// the ready config observes the control plane initialized by its machine
+	locker.initialized = true
+	if _, err := k.Reconcile(request); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	cfg, err = getKubeadmConfig(myclient, "control-plane-init-cfg")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	expectCondition(t, cfg, cabpkv1alpha2.ControlPlaneInitializedCondition, corev1.ConditionTrue)
+}
+
+func TestReconcileSetsCertificatesAvailableFalseWhileNotProvided(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+	cluster.Annotations = map[string]string{DisableCertificateGenerationAnnotationKey: "true"}
+
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg")
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, controlPlaneMachine, controlPlaneInitConfig)
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: &fakeInitLocker{},
+	}
+	if _, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg"},
+	}); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	expectCondition(t, cfg, cabpkv1alpha2.CertificatesAvailableCondition, corev1.ConditionFalse)
+	expectCondition(t, cfg, cabpkv1alpha2.DataSecretAvailableCondition, corev1.ConditionFalse)
+}
+
+func TestSetDataSecretAvailableCondition(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	clearDataSecretAvailableCondition(config, "BootstrapDataNotGenerated", "the bootstrap data is not generated yet")
+	expectCondition(t, config, cabpkv1alpha2.DataSecretAvailableCondition, corev1.ConditionFalse)
+
+	name := "cfg"
+	config.Status.DataSecretName = &name
+	setDataSecretAvailableCondition(config)
+	expectCondition(t, config, cabpkv1alpha2.DataSecretAvailableCondition, corev1.ConditionTrue)
+	if len(config.Status.Conditions) != 1 || config.Status.Conditions[0].Reason != "DataSecretCreated" {
+		t.Errorf("expected a single DataSecretAvailable condition with reason DataSecretCreated, got %v", config.Status.Conditions)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	// GeneratedSpecAnnotationKey holds the redacted spec the current bootstrap data of a config was generated from.
+	GeneratedSpecAnnotationKey = "bootstrap.cluster.x-k8s.io/generated-spec"
+)
+
+// redactedFields are the spec fields whose values are replaced by a digest, so that changes to them are
+// reported without disclosing them.
+var redactedFields = map[string]bool{
+	"content": true,
+	"gpgKey":  true,
+	"token":   true,
+}
+
+// recordGeneratedSpec stores the redacted spec of config as the one its bootstrap data is generated from, and
+// logs and emits the fields changed since the bootstrap data was previously generated, if it was. Bootstrap data
+// regenerated from an unchanged spec was changed by the cluster, e.g. the resolved bootstrap settings.
+func (r *KubeadmConfigReconciler) recordGeneratedSpec(config *cabpkv1alpha2.KubeadmConfig) error {
+	spec, err := redactSpec(&config.Spec)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the redacted spec")
+	}
+
+	log := r.Log.WithValues("kubeadmconfig", fmt.Sprintf("%s/%s", config.Namespace, config.Name))
+	if previous, ok := config.GetAnnotations()[GeneratedSpecAnnotationKey]; ok {
+		var previousSpec interface{}
+		if err := json.Unmarshal([]byte(previous), &previousSpec); err != nil {
+			// the annotation is only informative, the bootstrap data is regenerated regardless
+			log.Error(err, "failed to unmarshal the previously generated spec")
+		} else if changes := diffFields("spec", previousSpec, spec); len(changes) == 0 {
+			log.Info("Regenerating BootstrapData from an unchanged spec")
+		} else {
+			log.Info("Regenerating BootstrapData", "changes", changes)
+			if r.Recorder != nil {
+				r.Recorder.Eventf(config, corev1.EventTypeNormal, "BootstrapDataRegenerated",
+					"Regenerating the bootstrap data after changes to %s", strings.Join(changedPaths(changes), ", "))
+			}
+		}
+	}
+
+	annotations := config.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[GeneratedSpecAnnotationKey] = string(encoded)
+	config.SetAnnotations(annotations)
+	return nil
+}
+
+// redactSpec returns the JSON representation of spec, with the values of the redacted fields replaced by a digest.
+func redactSpec(spec *cabpkv1alpha2.KubeadmConfigSpec) (interface{}, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the spec")
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal the spec")
+	}
+	return redact(v), nil
+}
+
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if s, ok := field.(string); ok && redactedFields[k] {
+				sum := sha256.Sum256([]byte(s))
+				v[k] = "<redacted " + hex.EncodeToString(sum[:4]) + ">"
+				continue
+			}
+			v[k] = redact(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return v
+}
+
+// diffFields returns the changes between two JSON values, one "path: before -> after" entry per changed field, with
+// the fields of objects sorted by name. Added and removed fields are reported with a <none> value.
+func diffFields(path string, before, after interface{}) []string {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		var changes []string
+		for _, k := range sortedKeys(beforeMap, afterMap) {
+			changes = append(changes, diffFields(path+"."+k, beforeMap[k], afterMap[k])...)
+		}
+		return changes
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList {
+		var changes []string
+		for i := 0; i < len(beforeList) || i < len(afterList); i++ {
+			var b, a interface{}
+			if i < len(beforeList) {
+				b = beforeList[i]
+			}
+			if i < len(afterList) {
+				a = afterList[i]
+			}
+			changes = append(changes, diffFields(fmt.Sprintf("%s[%d]", path, i), b, a)...)
+		}
+		return changes
+	}
+
+	b, a := fieldValue(before), fieldValue(after)
+	if b == a {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %s -> %s", path, b, a)}
+}
+
+// sortedKeys returns the keys of both maps, sorted.
+func sortedKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fieldValue(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// changedPaths returns the paths of the changes returned by diffFields.
+func changedPaths(changes []string) []string {
+	paths := make([]string, 0, len(changes))
+	for _, c := range changes {
+		paths = append(paths, strings.SplitN(c, ": ", 2)[0])
+	}
+	return paths
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestRecordGeneratedSpec(t *testing.T) {
+	config := newWorkerJoinKubeadmConfig(nil, "cfg")
+	config.Spec.JoinConfiguration.Discovery.BootstrapToken = &kubeadmv1beta1.BootstrapTokenDiscovery{
+		Token: "abcdef.0123456789abcdef",
+	}
+	config.Spec.AdditionalUserDataFiles = []cabpkv1alpha2.Files{{Path: "/etc/motd", Content: "hello"}}
+
+	recorder := record.NewFakeRecorder(1)
+	k := &KubeadmConfigReconciler{
+		Log:      log.Log,
+		Recorder: recorder,
+	}
+
+	if err := k.recordGeneratedSpec(config); err != nil {
+		t.Fatalf("failed to record the generated spec: %v", err)
+	}
+	annotation := config.Annotations[GeneratedSpecAnnotationKey]
+	for _, secret := range []string{"0123456789abcdef", "hello"} {
+		if strings.Contains(annotation, secret) {
+			t.Fatalf("expected %q to be redacted, got %s", secret, annotation)
+		}
+	}
+	if len(recorder.Events) != 0 {
+		t.Fatalf("expected no event on the first generation, got %s", <-recorder.Events)
+	}
+
+	config.Spec.JoinConfiguration.Discovery.BootstrapToken.Token = "ghijkl.0123456789abcdef"
+	config.Spec.AdditionalUserDataFiles[0].Owner = "root:root"
+	if err := k.recordGeneratedSpec(config); err != nil {
+		t.Fatalf("failed to record the generated spec: %v", err)
+	}
+	expected := "Normal BootstrapDataRegenerated Regenerating the bootstrap data after changes to " +
+		"spec.additionalUserDataFiles[0].owner, spec.joinConfiguration.discovery.bootstrapToken.token"
+	if event := <-recorder.Events; event != expected {
+		t.Fatalf("expected event %q, got %q", expected, event)
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	before := map[string]interface{}{
+		"a": "1",
+		"b": map[string]interface{}{"c": float64(2), "d": true},
+		"e": []interface{}{"x", "y"},
+	}
+	after := map[string]interface{}{
+		"a": "1",
+		"b": map[string]interface{}{"c": float64(3)},
+		"e": []interface{}{"x"},
+		"f": map[string]interface{}{"g": "h"},
+	}
+
+	expected := []string{
+		"spec.b.c: 2 -> 3",
+		"spec.b.d: true -> <none>",
+		"spec.e[1]: y -> <none>",
+		`spec.f: <none> -> {"g":"h"}`,
+	}
+	changes := diffFields("spec", before, after)
+	if strings.Join(changes, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected changes:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(changes, "\n"))
+	}
+}
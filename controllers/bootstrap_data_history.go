@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	// BootstrapDataRevisionAnnotationKey holds the revision of the bootstrap data stored in a revision secret.
+	BootstrapDataRevisionAnnotationKey = "bootstrap.cluster.x-k8s.io/data-revision"
+)
+
+// BootstrapDataRevisionSecretName returns the name of the secret holding a bootstrap data revision, given a config
+// name and the revision
+func BootstrapDataRevisionSecretName(configName string, revision int32) string {
+	return fmt.Sprintf("%s-bootstrap-data-%d", configName, revision)
+}
+
+// recordBootstrapDataRevision stores data as a new revision when config.Spec.BootstrapDataRevisionHistoryLimit is set,
+// and deletes the revision falling out of the history.
+func (r *KubeadmConfigReconciler) recordBootstrapDataRevision(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, data []byte) error {
+	limit := config.Spec.BootstrapDataRevisionHistoryLimit
+	if limit <= 0 {
+		return nil
+	}
+
+	revision := config.Status.BootstrapDataRevision + 1
+	annotations := map[string]string{
+		BootstrapDataRevisionAnnotationKey: strconv.Itoa(int(revision)),
+	}
+	if err := r.writeBootstrapDataSecret(ctx, config, BootstrapDataRevisionSecretName(config.GetName(), revision), data, annotations); err != nil {
+		return err
+	}
+	config.Status.BootstrapDataRevision = revision
+
+	// revisions are pruned one at a time as new ones are recorded; leftovers of a lowered limit are garbage
+	// collected along with the config
+	if pruned := revision - limit; pruned > 0 {
+		secret := &corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      BootstrapDataRevisionSecretName(config.GetName(), pruned),
+				Namespace: config.GetNamespace(),
+			},
+		}
+		if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to delete bootstrap data revision secret %q", secret.Name)
+		}
+	}
+	return nil
+}
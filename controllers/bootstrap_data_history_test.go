@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestSetBootstrapDataRecordsRevisions(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.BootstrapDataRevisionHistoryLimit = 2
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme())
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: myclient,
+	}
+
+	for _, data := range []string{"#cloud-config\n# 1", "#cloud-config\n# 2", "#cloud-config\n# 3"} {
+		if err := k.setBootstrapData(context.Background(), newCluster("cluster"), config, []byte(data)); err != nil {
+			t.Fatalf("Failed to set bootstrap data:\n %+v", err)
+		}
+	}
+
+	if config.Status.BootstrapDataRevision != 3 {
+		t.Fatalf("expected bootstrap data revision 3, got %d", config.Status.BootstrapDataRevision)
+	}
+
+	secret := &corev1.Secret{}
+	if err := myclient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "cfg-bootstrap-data-1"}, secret); err == nil {
+		t.Fatal("expected revision 1 to be pruned")
+	}
+	for _, revision := range []string{"2", "3"} {
+		if err := myclient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "cfg-bootstrap-data-" + revision}, secret); err != nil {
+			t.Fatalf("Failed to get bootstrap data revision %s:\n %+v", revision, err)
+		}
+		if secret.Annotations[BootstrapDataRevisionAnnotationKey] != revision {
+			t.Fatalf("expected revision annotation %s, got %q", revision, secret.Annotations[BootstrapDataRevisionAnnotationKey])
+		}
+		if string(secret.Data["value"]) != "#cloud-config\n# "+revision {
+			t.Fatalf("expected bootstrap data of revision %s, got %q", revision, string(secret.Data["value"]))
+		}
+	}
+}
+
+func TestSetBootstrapDataSkipsRevisionsByDefault(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme())
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: myclient,
+	}
+	if err := k.setBootstrapData(context.Background(), newCluster("cluster"), config, []byte("#cloud-config")); err != nil {
+		t.Fatalf("Failed to set bootstrap data:\n %+v", err)
+	}
+
+	if config.Status.BootstrapDataRevision != 0 {
+		t.Fatalf("did not expect a bootstrap data revision, got %d", config.Status.BootstrapDataRevision)
+	}
+	if err := myclient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "cfg-bootstrap-data-1"}, &corev1.Secret{}); err == nil {
+		t.Fatal("did not expect a bootstrap data revision secret")
+	}
+}
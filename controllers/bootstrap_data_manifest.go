@@ -0,0 +1,60 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+)
+
+const (
+	// BootstrapDataManifestAnnotationKey holds the JSON manifest of the current bootstrap data of a config: the
+	// files it writes, the commands it runs, the ID of its bootstrap token and its kubeadm version.
+	BootstrapDataManifestAnnotationKey = "bootstrap.cluster.x-k8s.io/bootstrap-data-manifest"
+)
+
+// bootstrapDataManifest is the manifest of the user data, along with the ID of the bootstrap token it joins with.
+type bootstrapDataManifest struct {
+	*cloudinit.Manifest
+	TokenID string `json:"tokenID,omitempty"`
+}
+
+// recordBootstrapDataManifest stores the manifest of the bootstrap data generated for config, so that policy
+// engines and auditors can inspect what the machine runs without parsing the bootstrap data.
+func recordBootstrapDataManifest(config *cabpkv1alpha2.KubeadmConfig, manifest *cloudinit.Manifest) error {
+	m := bootstrapDataManifest{Manifest: manifest}
+	if token := joinToken(config); token != "" {
+		// the token secret is never published, only its public ID
+		m.TokenID = strings.SplitN(token, ".", 2)[0]
+	}
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal the bootstrap data manifest")
+	}
+
+	annotations := config.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[BootstrapDataManifestAnnotationKey] = string(encoded)
+	config.SetAnnotations(annotations)
+	return nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+)
+
+func TestRecordBootstrapDataManifest(t *testing.T) {
+	config := newTokenOnlyKubeadmConfig("cfg", "abcdef.0123456789abcdef")
+	manifest := &cloudinit.Manifest{
+		KubeadmVersion: "v1.15.3",
+		Files:          []cloudinit.ManifestFile{{Path: "/run/kubeadm/kubeadm-join.yaml", Owner: "root:root", Permissions: "0640"}},
+		Commands:       []string{"kubeadm join --config /run/kubeadm/kubeadm-join.yaml"},
+	}
+	if err := recordBootstrapDataManifest(config, manifest); err != nil {
+		t.Fatalf("failed to record the bootstrap data manifest: %v", err)
+	}
+
+	annotation := config.GetAnnotations()[BootstrapDataManifestAnnotationKey]
+	if strings.Contains(annotation, "0123456789abcdef") {
+		t.Fatalf("expected the token secret not to be published, got %s", annotation)
+	}
+	var recorded struct {
+		KubeadmVersion string                   `json:"kubeadmVersion"`
+		TokenID        string                   `json:"tokenID"`
+		Files          []cloudinit.ManifestFile `json:"files"`
+		Commands       []string                 `json:"commands"`
+	}
+	if err := json.Unmarshal([]byte(annotation), &recorded); err != nil {
+		t.Fatalf("failed to unmarshal the manifest annotation %q: %v", annotation, err)
+	}
+	if recorded.TokenID != "abcdef" || recorded.KubeadmVersion != "v1.15.3" {
+		t.Fatalf("expected the token ID and kubeadm version, got %+v", recorded)
+	}
+	if len(recorded.Files) != 1 || len(recorded.Commands) != 1 {
+		t.Fatalf("expected the files and commands of the manifest, got %+v", recorded)
+	}
+}
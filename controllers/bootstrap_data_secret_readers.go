@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+const (
+	// BootstrapDataSecretLabelKey labels the Role and RoleBinding granting read access to a bootstrap data secret
+	// with the name of the secret.
+	BootstrapDataSecretLabelKey = "bootstrap.cluster.x-k8s.io/bootstrap-data-secret"
+)
+
+// BootstrapDataSecretReaderName returns the name of the Role and RoleBinding granting read access to a bootstrap
+// data secret, given its name.
+func BootstrapDataSecretReaderName(secretName string) string {
+	return secretName + "-reader"
+}
+
+// ParseServiceAccounts returns the subjects of a comma separated list of namespace/name service accounts.
+func ParseServiceAccounts(value string) ([]rbacv1.Subject, error) {
+	var subjects []rbacv1.Subject
+	for _, sa := range strings.Split(value, ",") {
+		sa = strings.TrimSpace(sa)
+		if sa == "" {
+			continue
+		}
+		parts := strings.Split(sa, "/")
+		if len(parts) != 2 || len(validation.IsDNS1123Label(parts[0])) > 0 || len(validation.IsDNS1123Subdomain(parts[1])) > 0 {
+			return nil, errors.Errorf("invalid service account %q, expected namespace/name", sa)
+		}
+		subjects = append(subjects, rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: parts[0], Name: parts[1]})
+	}
+	return subjects, nil
+}
+
+// reconcileBootstrapDataSecretReaders grants the BootstrapDataSecretReaders read access to the named bootstrap data
+// secret of config, and to no other secret, with a Role and RoleBinding owned by config.
+func (r *KubeadmConfigReconciler) reconcileBootstrapDataSecretReaders(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, secretName string) error {
+	if len(r.BootstrapDataSecretReaders) == 0 {
+		return nil
+	}
+
+	objectMeta := v1.ObjectMeta{
+		Name:      BootstrapDataSecretReaderName(secretName),
+		Namespace: config.GetNamespace(),
+		Labels: map[string]string{
+			BootstrapDataSecretLabelKey: secretName,
+		},
+		OwnerReferences: []v1.OwnerReference{
+			{
+				APIVersion: cabpkv1alpha2.GroupVersion.String(),
+				Kind:       "KubeadmConfig",
+				Name:       config.GetName(),
+				UID:        config.GetUID(),
+			},
+		},
+	}
+	key := types.NamespacedName{Name: objectMeta.Name, Namespace: objectMeta.Namespace}
+
+	role := &rbacv1.Role{
+		ObjectMeta: objectMeta,
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{""},
+			Resources:     []string{"secrets"},
+			ResourceNames: []string{secretName},
+			Verbs:         []string{"get"},
+		}},
+	}
+	if err := r.Create(ctx, role); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create bootstrap data secret reader role %q", role.Name)
+		}
+		existing := &rbacv1.Role{}
+		if err := r.Get(ctx, key, existing); err != nil {
+			return errors.Wrapf(err, "failed to get bootstrap data secret reader role %q", role.Name)
+		}
+		if !reflect.DeepEqual(existing.Rules, role.Rules) {
+			existing.Rules = role.Rules
+			if err := r.Update(ctx, existing); err != nil {
+				return errors.Wrapf(err, "failed to update bootstrap data secret reader role %q", role.Name)
+			}
+		}
+	}
+
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: objectMeta,
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     role.Name,
+		},
+		Subjects: r.BootstrapDataSecretReaders,
+	}
+	if err := r.Create(ctx, binding); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create bootstrap data secret reader role binding %q", binding.Name)
+		}
+		existing := &rbacv1.RoleBinding{}
+		if err := r.Get(ctx, key, existing); err != nil {
+			return errors.Wrapf(err, "failed to get bootstrap data secret reader role binding %q", binding.Name)
+		}
+		if !reflect.DeepEqual(existing.Subjects, binding.Subjects) {
+			existing.Subjects = binding.Subjects
+			if err := r.Update(ctx, existing); err != nil {
+				return errors.Wrapf(err, "failed to update bootstrap data secret reader role binding %q", binding.Name)
+			}
+		}
+	}
+	return nil
+}
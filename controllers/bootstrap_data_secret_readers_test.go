@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestParseServiceAccounts(t *testing.T) {
+	subjects, err := ParseServiceAccounts("capa-system/capa-controller-manager, capv-system/capv-controller-manager")
+	if err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	expected := []rbacv1.Subject{
+		{Kind: rbacv1.ServiceAccountKind, Namespace: "capa-system", Name: "capa-controller-manager"},
+		{Kind: rbacv1.ServiceAccountKind, Namespace: "capv-system", Name: "capv-controller-manager"},
+	}
+	if !reflect.DeepEqual(subjects, expected) {
+		t.Fatalf("expected %v, got %v", expected, subjects)
+	}
+
+	if subjects, err := ParseServiceAccounts(""); err != nil || len(subjects) != 0 {
+		t.Fatalf("expected no subjects, got %v and error %v", subjects, err)
+	}
+	if _, err := ParseServiceAccounts("capa-controller-manager"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestSetBootstrapDataWithBootstrapDataSecretReaders(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	readers := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "capa-system", Name: "capa-controller-manager"}}
+
+	scheme := setupScheme()
+	rbacv1.AddToScheme(scheme)
+	myclient := fake.NewFakeClientWithScheme(scheme)
+	k := &KubeadmConfigReconciler{
+		Log:                        log.Log,
+		Client:                     myclient,
+		BootstrapDataSecret:        true,
+		BootstrapDataSecretReaders: readers,
+	}
+	if err := k.setBootstrapData(context.Background(), newCluster("cluster"), config, []byte("#cloud-config")); err != nil {
+		t.Fatalf("Failed to set bootstrap data:\n %+v", err)
+	}
+
+	key := types.NamespacedName{Namespace: "default", Name: BootstrapDataSecretReaderName("cfg")}
+	role := &rbacv1.Role{}
+	if err := myclient.Get(context.Background(), key, role); err != nil {
+		t.Fatalf("Failed to get the reader role:\n %+v", err)
+	}
+	if len(role.Rules) != 1 || !reflect.DeepEqual(role.Rules[0].ResourceNames, []string{"cfg"}) || !reflect.DeepEqual(role.Rules[0].Verbs, []string{"get"}) {
+		t.Fatalf("expected the role to only allow getting the bootstrap data secret, got %+v", role.Rules)
+	}
+	if role.Labels[BootstrapDataSecretLabelKey] != "cfg" {
+		t.Fatalf("expected the role to be labeled with the secret name, got %v", role.Labels)
+	}
+
+	binding := &rbacv1.RoleBinding{}
+	if err := myclient.Get(context.Background(), key, binding); err != nil {
+		t.Fatalf("Failed to get the reader role binding:\n %+v", err)
+	}
+	if binding.RoleRef.Name != role.Name || !reflect.DeepEqual(binding.Subjects, readers) {
+		t.Fatalf("expected the role to be bound to the readers, got %+v", binding)
+	}
+}
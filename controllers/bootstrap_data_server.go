@@ -0,0 +1,184 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api/pkg/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// BootstrapDataHashAnnotationKey holds the SHA-256 of the bootstrap data stored in a bootstrap data secret.
+	BootstrapDataHashAnnotationKey = "bootstrap.cluster.x-k8s.io/data-hash"
+
+	// bootstrapDataSecretKey is the key of the secret holding the bootstrap data served to token-only machines.
+	bootstrapDataSecretKey = "value"
+
+	bootstrapDataServerShutdownTimeout = 30 * time.Second
+)
+
+// BootstrapDataSecretName returns the name of the secret holding the bootstrap data served to token-only machines,
+// given a config name
+func BootstrapDataSecretName(configName string) string {
+	return fmt.Sprintf("%s-bootstrap-data", configName)
+}
+
+// BootstrapDataServer serves the bootstrap data of KubeadmConfigs using token-only delivery, at
+// /<namespace>/<name>?token=<join token>. Data is only served while the join token is valid in the workload
+// cluster.
+type BootstrapDataServer struct {
+	Client               client.Client
+	SecretsClientFactory SecretsClientFactory
+	Log                  logr.Logger
+
+	// Addr is the address the server binds to.
+	Addr string
+
+	// CertFile and KeyFile are used to serve over TLS. Bootstrap data holds join tokens and, for control planes,
+	// the keys of the cluster CAs, so it is never served over plain HTTP.
+	CertFile string
+	KeyFile  string
+}
+
+// Start runs the server until stop is closed
+func (s *BootstrapDataServer) Start(stop <-chan struct{}) error {
+	if s.CertFile == "" || s.KeyFile == "" {
+		return errors.New("the bootstrap data server requires a TLS certificate and key")
+	}
+	server := &http.Server{Addr: s.Addr, Handler: s}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServeTLS(s.CertFile, s.KeyFile)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-stop:
+		ctx, cancel := context.WithTimeout(context.Background(), bootstrapDataServerShutdownTimeout)
+		defer cancel()
+		return server.Shutdown(ctx)
+	}
+}
+
+// ServeHTTP serves the bootstrap data of the requested config, if the request is authenticated by its join token.
+// Requests not authenticated for any reason are all answered the same way, so the existence of a config is not
+// leaked.
+func (s *BootstrapDataServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, req)
+		return
+	}
+	key := types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+	log := s.Log.WithValues("kubeadmconfig", key)
+	ctx := context.Background()
+
+	config := &cabpkv1alpha2.KubeadmConfig{}
+	if err := s.Client.Get(ctx, key, config); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		log.Error(err, "failed to get config")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	token := joinToken(config)
+	if config.Spec.Delivery == nil || config.Spec.Delivery.TokenOnly == nil || token == "" ||
+		subtle.ConstantTimeCompare([]byte(req.URL.Query().Get("token")), []byte(token)) != 1 {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	secret := &corev1.Secret{}
+	if err := s.Client.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: BootstrapDataSecretName(key.Name)}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.NotFound(w, req)
+			return
+		}
+		log.Error(err, "failed to get bootstrap data secret")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// the join token is useless once expired, so should be the bootstrap data it gives access to; the token is kept
+	// valid by the controller until the machine registers its node
+	valid, err := s.tokenValid(ctx, config, token)
+	if err != nil {
+		log.Error(err, "failed to check the join token")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	log.Info("Serving BootstrapData to the machine")
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := w.Write(secret.Data[bootstrapDataSecretKey]); err != nil {
+		log.Error(err, "failed to write bootstrap data")
+	}
+}
+
+// tokenValid reports whether the join token of config is valid in the workload cluster of the Machine or
+// MachinePool owning config.
+func (s *BootstrapDataServer) tokenValid(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, token string) (bool, error) {
+	machine, err := util.GetOwnerMachine(ctx, s.Client, config.ObjectMeta)
+	if err != nil {
+		return false, err
+	}
+	if machine == nil {
+		pool, err := getOwnerMachinePool(ctx, s.Client, config.ObjectMeta)
+		if err != nil || pool == nil {
+			return false, err
+		}
+		if machine, err = machinePoolMachine(pool); err != nil {
+			return false, err
+		}
+	}
+	cluster, err := util.GetClusterFromMetadata(ctx, s.Client, machine.ObjectMeta)
+	if err != nil {
+		return false, err
+	}
+	secretsClient, err := s.SecretsClientFactory.NewSecretsClient(s.Client, cluster)
+	if err != nil {
+		return false, err
+	}
+	return tokenValid(secretsClient, token)
+}
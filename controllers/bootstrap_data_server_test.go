@@ -0,0 +1,209 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	cabpkV1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func newTokenOnlyKubeadmConfig(name, token string) *cabpkV1alpha2.KubeadmConfig {
+	config := newKubeadmConfig(nil, name)
+	config.Spec.JoinConfiguration = &kubeadmv1beta1.JoinConfiguration{
+		Discovery: kubeadmv1beta1.Discovery{
+			BootstrapToken: &kubeadmv1beta1.BootstrapTokenDiscovery{
+				APIServerEndpoint: "10.0.0.1:6443",
+				Token:             token,
+				CACertHashes:      []string{"sha256:0123"},
+			},
+		},
+	}
+	config.Spec.Delivery = &cabpkV1alpha2.Delivery{
+		TokenOnly: &cabpkV1alpha2.TokenOnlyDelivery{
+			URL: "https://10.0.0.2:9443",
+		},
+	}
+	return config
+}
+
+func newBootstrapDataSecret(configName string, created time.Time) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              BootstrapDataSecretName(configName),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Data: map[string][]byte{
+			"value": []byte("#cloud-config"),
+		},
+	}
+}
+
+// newTokenSecret returns the secret of a bootstrap token of the workload cluster, expiring at expiration.
+func newTokenSecret(tokenID, tokenSecret string, expiration time.Time) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-" + tokenID, Namespace: metav1.NamespaceSystem},
+		Data: map[string][]byte{
+			bootstrapapi.BootstrapTokenIDKey:         []byte(tokenID),
+			bootstrapapi.BootstrapTokenSecretKey:     []byte(tokenSecret),
+			bootstrapapi.BootstrapTokenExpirationKey: []byte(expiration.UTC().Format(time.RFC3339)),
+		},
+	}
+}
+
+func TestBootstrapDataServer(t *testing.T) {
+	cluster := newCluster("cluster")
+	machine := newWorkerMachine(cluster, "worker-machine")
+	config := func(name, token string) *cabpkV1alpha2.KubeadmConfig {
+		c := newTokenOnlyKubeadmConfig(name, token)
+		c.OwnerReferences = newWorkerJoinKubeadmConfig(machine, name).OwnerReferences
+		return c
+	}
+	plainConfig := newWorkerJoinKubeadmConfig(machine, "plain")
+	plainConfig.Spec.JoinConfiguration = newTokenOnlyKubeadmConfig("plain", "abcdef.0123456789abcdef").Spec.JoinConfiguration
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme(),
+		cluster,
+		machine,
+		config("cfg", "abcdef.0123456789abcdef"),
+		newBootstrapDataSecret("cfg", time.Now()),
+		config("refreshed", "abcdef.0123456789abcdef"),
+		newBootstrapDataSecret("refreshed", time.Now().Add(-time.Hour)),
+		config("expired", "fedcba.0123456789abcdef"),
+		newBootstrapDataSecret("expired", time.Now()),
+		config("revoked", "ghijkl.0123456789abcdef"),
+		newBootstrapDataSecret("revoked", time.Now()),
+		config("nodata", "abcdef.0123456789abcdef"),
+		plainConfig,
+		newBootstrapDataSecret("plain", time.Now()),
+	)
+	secrets := newFakeSecretFactory()
+	for _, secret := range []*corev1.Secret{
+		newTokenSecret("abcdef", "0123456789abcdef", time.Now().Add(time.Hour)),
+		newTokenSecret("fedcba", "0123456789abcdef", time.Now().Add(-time.Minute)),
+	} {
+		if _, err := secrets.client.Create(secret); err != nil {
+			t.Fatalf("failed to create the token secret: %v", err)
+		}
+	}
+	server := &BootstrapDataServer{
+		Client:               myclient,
+		SecretsClientFactory: secrets,
+		Log:                  log.Log,
+	}
+
+	testcases := []struct {
+		name           string
+		method         string
+		target         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "serves bootstrap data",
+			method:         http.MethodGet,
+			target:         "/default/cfg?token=abcdef.0123456789abcdef",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "#cloud-config",
+		},
+		{
+			name:           "rejects wrong token",
+			method:         http.MethodGet,
+			target:         "/default/cfg?token=abcdef.fedcba9876543210",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "rejects missing token",
+			method:         http.MethodGet,
+			target:         "/default/cfg",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "rejects unknown config",
+			method:         http.MethodGet,
+			target:         "/default/unknown?token=abcdef.0123456789abcdef",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "rejects config not using token-only delivery",
+			method:         http.MethodGet,
+			target:         "/default/plain?token=abcdef.0123456789abcdef",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "serves bootstrap data older than the token TTL while the token is refreshed",
+			method:         http.MethodGet,
+			target:         "/default/refreshed?token=abcdef.0123456789abcdef",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "#cloud-config",
+		},
+		{
+			name:           "rejects expired token",
+			method:         http.MethodGet,
+			target:         "/default/expired?token=fedcba.0123456789abcdef",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "rejects token no longer in the workload cluster",
+			method:         http.MethodGet,
+			target:         "/default/revoked?token=ghijkl.0123456789abcdef",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "bootstrap data not stored yet",
+			method:         http.MethodGet,
+			target:         "/default/nodata?token=abcdef.0123456789abcdef",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "rejects other methods",
+			method:         http.MethodPost,
+			target:         "/default/cfg?token=abcdef.0123456789abcdef",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "rejects other paths",
+			method:         http.MethodGet,
+			target:         "/cfg?token=abcdef.0123456789abcdef",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.target, nil))
+
+			if rec.Code != tc.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if tc.expectedBody != "" && rec.Body.String() != tc.expectedBody {
+				t.Fatalf("expected body %q, got %q", tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
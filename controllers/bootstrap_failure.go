@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+)
+
+const (
+	// invalidConfigurationReason is the FailureReason of a config whose spec is inconsistent, or inconsistent with
+	// the role of its machine.
+	invalidConfigurationReason = "InvalidConfiguration"
+
+	// featureGateDisabledReason is the FailureReason of a config using a feature whose gate is disabled.
+	featureGateDisabledReason = "FeatureGateDisabled"
+
+	// invalidExternalCAReason is the FailureReason of a config whose external CAs can not be used.
+	invalidExternalCAReason = "InvalidExternalCA"
+)
+
+// bootstrapFailure is a terminal error generating the bootstrap data of a config, that retrying does not fix until
+// the config, or the objects it refers to, is changed. It is recorded as the FailureReason and FailureMessage of the
+// config instead of being retried.
+type bootstrapFailure struct {
+	reason  string
+	message string
+}
+
+func (f *bootstrapFailure) Error() string {
+	return f.message
+}
+
+// newBootstrapFailure returns a bootstrapFailure with the given reason and formatted message.
+func newBootstrapFailure(reason, format string, args ...interface{}) error {
+	return &bootstrapFailure{reason: reason, message: fmt.Sprintf(format, args...)}
+}
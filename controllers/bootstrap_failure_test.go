@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestBootstrapFailureCause(t *testing.T) {
+	err := errors.Wrap(newBootstrapFailure(invalidConfigurationReason, "variant %q not found", "large"), "failed to resolve variant")
+	failure, ok := errors.Cause(err).(*bootstrapFailure)
+	if !ok {
+		t.Fatalf("expected the cause to be a bootstrap failure, got %T", errors.Cause(err))
+	}
+	if failure.reason != invalidConfigurationReason || failure.message != `variant "large" not found` {
+		t.Errorf("unexpected failure %+v", failure)
+	}
+}
+
+func TestReconcileClearsBootstrapFailure(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg")
+	controlPlaneInitConfig.Status.FailureReason = invalidConfigurationReason
+	controlPlaneInitConfig.Status.FailureMessage = "a failure fixed since"
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, controlPlaneMachine, controlPlaneInitConfig)
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: &fakeInitLocker{},
+	}
+	if _, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg"},
+	}); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	if !cfg.Status.Ready {
+		t.Fatal("expected the config to be ready")
+	}
+	if cfg.Status.FailureReason != "" || cfg.Status.FailureMessage != "" {
+		t.Errorf("expected the failure to be cleared, got %q: %q", cfg.Status.FailureReason, cfg.Status.FailureMessage)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+// resolveBootstrapSettings returns the settings of the BootstrapSettings named by the cluster annotation, or of the
+// default BootstrapSettings if the cluster names none. Clusters without settings get empty ones.
+func (r *KubeadmConfigReconciler) resolveBootstrapSettings(ctx context.Context, cluster *capiv1alpha2.Cluster) (*cabpkv1alpha2.BootstrapSettingsSpec, error) {
+	name, named := cluster.GetAnnotations()[cabpkv1alpha2.BootstrapSettingsAnnotation]
+	if !named {
+		name = cabpkv1alpha2.DefaultBootstrapSettingsName
+	}
+
+	settings := &cabpkv1alpha2.BootstrapSettings{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, settings); err != nil {
+		if apierrors.IsNotFound(err) && !named {
+			return &cabpkv1alpha2.BootstrapSettingsSpec{}, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get the BootstrapSettings %q of cluster %s/%s", name, cluster.GetNamespace(), cluster.GetName())
+	}
+	return &settings.Spec, nil
+}
+
+// bootstrapTokenTTL returns how long the bootstrap tokens created with the settings are valid.
+func bootstrapTokenTTL(settings *cabpkv1alpha2.BootstrapSettingsSpec) time.Duration {
+	if settings.TokenTTL == nil || settings.TokenTTL.Duration <= 0 {
+		return defaultTokenTTL
+	}
+	return settings.TokenTTL.Duration
+}
+
+// ntp returns the NTP configuration of config, defaulting to the one of the settings.
+func ntp(config *cabpkv1alpha2.KubeadmConfig, settings *cabpkv1alpha2.BootstrapSettingsSpec) *cabpkv1alpha2.NTP {
+	if config.Spec.NTP != nil {
+		return config.Spec.NTP
+	}
+	return settings.NTP
+}
+
+// registryMirrors returns the registry mirrors of config, defaulting to the ones of the settings.
+func registryMirrors(config *cabpkv1alpha2.KubeadmConfig, settings *cabpkv1alpha2.BootstrapSettingsSpec) []cabpkv1alpha2.RegistryMirror {
+	if len(config.Spec.RegistryMirrors) > 0 {
+		return config.Spec.RegistryMirrors
+	}
+	return settings.RegistryMirrors
+}
+
+// defaultServiceDomain is the service domain kubeadm defaults to.
+const defaultServiceDomain = "cluster.local"
+
+// loopbackNoProxy are the loopback destinations, which every machine reaches without the proxy.
+var loopbackNoProxy = []string{"localhost", "127.0.0.1", "::1"}
+
+// proxy returns the HTTP proxy of config, defaulting to the one of the settings, with the destinations within the
+// cluster added to the ones reached without it: the loopback addresses, the service domain, the service and pod
+// subnets, the API endpoints of the cluster, and the control plane and discovery endpoints of config.
+func proxy(config *cabpkv1alpha2.KubeadmConfig, cluster *capiv1alpha2.Cluster, settings *cabpkv1alpha2.BootstrapSettingsSpec) *cabpkv1alpha2.Proxy {
+	p := config.Spec.Proxy
+	if p == nil {
+		p = settings.Proxy
+	}
+	if p == nil {
+		return nil
+	}
+	p = p.DeepCopy()
+
+	noProxy := append([]string{}, loopbackNoProxy...)
+	domain := defaultServiceDomain
+	if network := cluster.Spec.ClusterNetwork; network != nil {
+		if network.ServiceDomain != "" {
+			domain = network.ServiceDomain
+		}
+		for _, ranges := range []*capiv1alpha2.NetworkRanges{network.Services, network.Pods} {
+			if ranges != nil {
+				noProxy = append(noProxy, ranges.CIDRBlocks...)
+			}
+		}
+	}
+	if cfg := config.Spec.ClusterConfiguration; cfg != nil && cfg.Networking.DNSDomain != "" {
+		domain = cfg.Networking.DNSDomain
+	}
+	noProxy = append(noProxy, ".svc", "."+domain)
+	for _, endpoint := range cluster.Status.APIEndpoints {
+		noProxy = append(noProxy, endpoint.Host)
+	}
+	endpoints := []string{config.Spec.DiscoveryEndpoint, config.Spec.ControlPlaneEndpointIP}
+	if cfg := config.Spec.ClusterConfiguration; cfg != nil {
+		endpoints = append(endpoints, cfg.ControlPlaneEndpoint)
+	}
+	if cfg := config.Spec.JoinConfiguration; cfg != nil && cfg.Discovery.BootstrapToken != nil {
+		endpoints = append(endpoints, cfg.Discovery.BootstrapToken.APIServerEndpoint)
+	}
+	for _, endpoint := range endpoints {
+		if endpoint == "" {
+			continue
+		}
+		// the IP and the ClusterConfiguration.ControlPlaneEndpoint have no port
+		host, _, err := net.SplitHostPort(endpoint)
+		if err != nil {
+			host = endpoint
+		}
+		noProxy = append(noProxy, host)
+	}
+
+	seen := map[string]bool{}
+	for _, host := range p.NoProxy {
+		seen[host] = true
+	}
+	for _, host := range noProxy {
+		if !seen[host] {
+			p.NoProxy = append(p.NoProxy, host)
+			seen[host] = true
+		}
+	}
+	return p
+}
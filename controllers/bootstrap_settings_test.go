@@ -0,0 +1,246 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func newBootstrapSettings(name string) *cabpkv1alpha2.BootstrapSettings {
+	return &cabpkv1alpha2.BootstrapSettings{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "BootstrapSettings",
+			APIVersion: cabpkv1alpha2.GroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+}
+
+func TestResolveBootstrapSettings(t *testing.T) {
+	defaults := newBootstrapSettings(cabpkv1alpha2.DefaultBootstrapSettingsName)
+	defaults.Spec.ImageRepository = "default.example.com"
+	airgapped := newBootstrapSettings("airgapped")
+	airgapped.Spec.ImageRepository = "airgapped.example.com"
+
+	testcases := []struct {
+		name                    string
+		objects                 []runtime.Object
+		annotations             map[string]string
+		expectedImageRepository string
+		expectErr               bool
+	}{
+		{
+			name:                    "default settings",
+			objects:                 []runtime.Object{defaults, airgapped},
+			expectedImageRepository: "default.example.com",
+		},
+		{
+			name:                    "named settings",
+			objects:                 []runtime.Object{defaults, airgapped},
+			annotations:             map[string]string{cabpkv1alpha2.BootstrapSettingsAnnotation: "airgapped"},
+			expectedImageRepository: "airgapped.example.com",
+		},
+		{
+			name: "no default settings",
+		},
+		{
+			name:        "missing named settings",
+			objects:     []runtime.Object{defaults},
+			annotations: map[string]string{cabpkv1alpha2.BootstrapSettingsAnnotation: "airgapped"},
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := newCluster("cluster")
+			cluster.Annotations = tc.annotations
+
+			k := &KubeadmConfigReconciler{
+				Log:    log.Log,
+				Client: fake.NewFakeClientWithScheme(setupScheme(), tc.objects...),
+			}
+			settings, err := k.resolveBootstrapSettings(context.Background(), cluster)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to resolve bootstrap settings: %v", err)
+			}
+			if settings.ImageRepository != tc.expectedImageRepository {
+				t.Fatalf("expected image repository %q, got %q", tc.expectedImageRepository, settings.ImageRepository)
+			}
+		})
+	}
+}
+
+func TestReconcileDefaultsImageRepositoryFromBootstrapSettings(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg")
+
+	settings := newBootstrapSettings(cabpkv1alpha2.DefaultBootstrapSettingsName)
+	settings.Spec.ImageRepository = "registry.example.com"
+
+	objects := []runtime.Object{
+		cluster,
+		controlPlaneMachine,
+		controlPlaneInitConfig,
+		settings,
+	}
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), objects...)
+
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: &fakeInitLocker{},
+	}
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg"},
+	}
+	if _, err := k.Reconcile(request); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+
+	cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	if cfg.Spec.ClusterConfiguration.ImageRepository != "registry.example.com" {
+		t.Fatalf("expected the image repository of the bootstrap settings, got %q", cfg.Spec.ClusterConfiguration.ImageRepository)
+	}
+}
+
+func TestReconcileDiscoveryTokenTTL(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.APIEndpoints = []capiv1alpha2.APIEndpoint{{Host: "example.com", Port: 6443}}
+
+	config := newWorkerJoinKubeadmConfig(newWorkerMachine(cluster, "worker-machine"), "worker-join-cfg")
+
+	secretFactory := newFakeSecretFactory()
+	k := &KubeadmConfigReconciler{
+		Log:                  log.Log,
+		SecretsClientFactory: secretFactory,
+	}
+	ttl := bootstrapTokenTTL(&cabpkv1alpha2.BootstrapSettingsSpec{TokenTTL: &metav1.Duration{Duration: time.Hour}})
+	if ttl != time.Hour {
+		t.Fatalf("expected a token TTL of 1h, got %s", ttl)
+	}
+	if err := k.reconcileDiscovery(cluster, config, ttl); err != nil {
+		t.Fatalf("failed to reconcile discovery: %v", err)
+	}
+
+	secrets, err := secretFactory.client.List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list the bootstrap token secrets: %v", err)
+	}
+	if len(secrets.Items) != 1 {
+		t.Fatalf("expected one bootstrap token secret, got %d", len(secrets.Items))
+	}
+	expiration, err := time.Parse(time.RFC3339, string(secrets.Items[0].Data[bootstrapapi.BootstrapTokenExpirationKey]))
+	if err != nil {
+		t.Fatalf("failed to parse the token expiration: %v", err)
+	}
+	if until := time.Until(expiration); until <= defaultTokenTTL || until > time.Hour {
+		t.Fatalf("expected the token to expire in 1h, expires in %s", until)
+	}
+}
+
+func TestNTP(t *testing.T) {
+	settings := &cabpkv1alpha2.BootstrapSettingsSpec{NTP: &cabpkv1alpha2.NTP{Servers: []string{"ntp.example.com"}}}
+	config := newKubeadmConfig(nil, "cfg")
+	if out := ntp(config, settings); out != settings.NTP {
+		t.Fatalf("expected the NTP of the settings, got %v", out)
+	}
+
+	config.Spec.NTP = &cabpkv1alpha2.NTP{Servers: []string{"10.0.0.1"}}
+	if out := ntp(config, settings); out != config.Spec.NTP {
+		t.Fatalf("expected the NTP of the config, got %v", out)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	cluster := newCluster("cluster")
+	config := newKubeadmConfig(nil, "cfg")
+	settings := &cabpkv1alpha2.BootstrapSettingsSpec{}
+	if out := proxy(config, cluster, settings); out != nil {
+		t.Fatalf("expected no proxy, got %v", out)
+	}
+
+	settings.Proxy = &cabpkv1alpha2.Proxy{HTTPSProxy: "http://proxy.example.com:3128", NoProxy: []string{"localhost", "registry.example.com"}}
+	out := proxy(config, cluster, settings)
+	expected := []string{"localhost", "registry.example.com", "127.0.0.1", "::1", ".svc", ".cluster.local"}
+	if out.HTTPSProxy != settings.Proxy.HTTPSProxy || !reflect.DeepEqual(out.NoProxy, expected) {
+		t.Fatalf("expected the proxy of the settings reaching %v directly, got %v", expected, out)
+	}
+	if len(settings.Proxy.NoProxy) != 2 {
+		t.Fatal("expected the proxy of the settings not to be modified")
+	}
+
+	cluster.Spec.ClusterNetwork = &capiv1alpha2.ClusterNetwork{
+		Services:      &capiv1alpha2.NetworkRanges{CIDRBlocks: []string{"10.96.0.0/12"}},
+		Pods:          &capiv1alpha2.NetworkRanges{CIDRBlocks: []string{"192.168.0.0/16"}},
+		ServiceDomain: "example.internal",
+	}
+	cluster.Status.APIEndpoints = []capiv1alpha2.APIEndpoint{{Host: "10.0.0.2", Port: 6443}}
+	config.Spec.Proxy = &cabpkv1alpha2.Proxy{HTTPProxy: "http://region.example.com:3128"}
+	config.Spec.DiscoveryEndpoint = "internal.example.com:6443"
+	config.Spec.ControlPlaneEndpointIP = "10.0.0.3"
+	config.Spec.ClusterConfiguration = &kubeadmv1beta1.ClusterConfiguration{ControlPlaneEndpoint: "api.example.com"}
+	out = proxy(config, cluster, settings)
+	expected = []string{"localhost", "127.0.0.1", "::1", "10.96.0.0/12", "192.168.0.0/16", ".svc", ".example.internal", "10.0.0.2",
+		"internal.example.com", "10.0.0.3", "api.example.com"}
+	if out.HTTPProxy != config.Spec.Proxy.HTTPProxy || out.HTTPSProxy != "" || !reflect.DeepEqual(out.NoProxy, expected) {
+		t.Fatalf("expected the proxy of the config reaching %v directly, got %v", expected, out)
+	}
+}
+
+func TestRegistryMirrors(t *testing.T) {
+	settings := &cabpkv1alpha2.BootstrapSettingsSpec{
+		RegistryMirrors: []cabpkv1alpha2.RegistryMirror{{Registry: "docker.io", Endpoints: []string{"https://mirror.example.com"}}},
+	}
+	config := newKubeadmConfig(nil, "cfg")
+	if out := registryMirrors(config, settings); !reflect.DeepEqual(out, settings.RegistryMirrors) {
+		t.Fatalf("expected the registry mirrors of the settings, got %v", out)
+	}
+
+	config.Spec.RegistryMirrors = []cabpkv1alpha2.RegistryMirror{{Registry: "docker.io", Endpoints: []string{"https://cache.example.com"}}}
+	if out := registryMirrors(config, settings); !reflect.DeepEqual(out, config.Spec.RegistryMirrors) {
+		t.Fatalf("expected the registry mirrors of the config, got %v", out)
+	}
+}
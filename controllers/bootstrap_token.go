@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api/pkg/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reconcileBootstrapToken keeps the bootstrap token of a ready join config valid until the owner machine registers
+// its node, so that machines taking longer than the token TTL to boot can still join, then revokes it, so that it
+// can't be used to join the cluster once the node registered. It returns when the config is due to be checked
+// again.
+func (r *KubeadmConfigReconciler) reconcileBootstrapToken(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) (time.Duration, error) {
+	token := joinToken(config)
+	if token == "" || config.Status.BootstrapTokenRevoked {
+		return 0, nil
+	}
+
+	machine, err := util.GetOwnerMachine(ctx, r.Client, config.ObjectMeta)
+	if err != nil {
+		return 0, err
+	}
+	if machine == nil {
+		return r.reconcileMachinePoolBootstrapToken(ctx, config, token)
+	}
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, machine.ObjectMeta)
+	if err != nil {
+		return 0, err
+	}
+	secretsClient, err := r.SecretsClientFactory.NewSecretsClient(r.Client, cluster)
+	if err != nil {
+		return 0, err
+	}
+
+	if machine.Status.NodeRef != nil {
+		if err := revokeToken(secretsClient, token); err != nil {
+			return 0, errors.Wrap(err, "failed to revoke the bootstrap token")
+		}
+		patchConfig := client.MergeFrom(config.DeepCopy())
+		config.Status.BootstrapTokenRevoked = true
+		if err := r.Status().Patch(ctx, config, patchConfig); err != nil {
+			return 0, errors.Wrap(err, "failed to record the revocation of the bootstrap token")
+		}
+		r.Log.Info("Revoked the bootstrap token of the config", "kubeadmconfig", config.Namespace+"/"+config.Name, "node", machine.Status.NodeRef.Name)
+		return 0, nil
+	}
+
+	settings, err := r.resolveBootstrapSettings(ctx, cluster)
+	if err != nil {
+		return 0, err
+	}
+	refreshAfter, refreshed, err := refreshToken(secretsClient, token, bootstrapTokenTTL(settings))
+	if apierrors.IsNotFound(err) {
+		// the token cleaner only deletes expired tokens, the bootstrap data cannot join with it anymore
+		r.Log.Info("The bootstrap token of the config no longer exists", "kubeadmconfig", config.Namespace+"/"+config.Name, "machine", machine.Name)
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to refresh the bootstrap token")
+	}
+	if refreshed {
+		bootstrapTokensTotal.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), "refresh").Inc()
+		r.recordEventf(config, machine, corev1.EventTypeNormal, "BootstrapTokenRefreshed",
+			"Refreshed the bootstrap token, which is next refreshed in %s", refreshAfter)
+	}
+	return refreshAfter, nil
+}
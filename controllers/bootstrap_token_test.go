@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileBootstrapToken(t *testing.T) {
+	testcases := []struct {
+		name          string
+		expiresIn     time.Duration
+		description   string
+		nodeRef       *corev1.ObjectReference
+		missing       bool
+		expectRefresh bool
+		expectRevoke  bool
+		expectAfter   time.Duration
+	}{
+		{
+			name:          "token near expiry",
+			expiresIn:     time.Minute,
+			description:   tokenDescription,
+			expectRefresh: true,
+			expectAfter:   defaultTokenTTL / 2,
+		},
+		{
+			name:        "token far from expiry",
+			expiresIn:   9 * time.Minute,
+			description: tokenDescription,
+			expectAfter: 4 * time.Minute,
+		},
+		{
+			name:         "node registered",
+			expiresIn:    time.Minute,
+			description:  tokenDescription,
+			nodeRef:      &corev1.ObjectReference{Kind: "Node", Name: "worker"},
+			expectRevoke: true,
+		},
+		{
+			name:        "node registered with a token not generated by the controller",
+			expiresIn:   time.Minute,
+			description: "provided token",
+			nodeRef:     &corev1.ObjectReference{Kind: "Node", Name: "worker"},
+		},
+		{
+			name:        "token not generated by the controller",
+			expiresIn:   time.Minute,
+			description: "provided token",
+		},
+		{
+			name:    "token deleted",
+			missing: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := newCluster("cluster")
+			machine := newWorkerMachine(cluster, "worker-machine")
+			machine.Status.NodeRef = tc.nodeRef
+			config := newWorkerJoinKubeadmConfig(machine, "worker-join-cfg")
+			config.Status.Ready = true
+			config.Spec.JoinConfiguration.Discovery.BootstrapToken = &kubeadmv1beta1.BootstrapTokenDiscovery{Token: "abcdef.0123456789abcdef"}
+
+			secrets := newFakeSecretFactory()
+			expiration := time.Now().UTC().Add(tc.expiresIn).Format(time.RFC3339)
+			if !tc.missing {
+				if _, err := secrets.client.Create(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-abcdef", Namespace: metav1.NamespaceSystem},
+					Data: map[string][]byte{
+						bootstrapapi.BootstrapTokenIDKey:          []byte("abcdef"),
+						bootstrapapi.BootstrapTokenSecretKey:      []byte("0123456789abcdef"),
+						bootstrapapi.BootstrapTokenExpirationKey:  []byte(expiration),
+						bootstrapapi.BootstrapTokenDescriptionKey: []byte(tc.description),
+					},
+				}); err != nil {
+					t.Fatalf("failed to create the token secret: %v", err)
+				}
+			}
+
+			myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, machine, config)
+			recorder := record.NewFakeRecorder(2)
+			k := &KubeadmConfigReconciler{
+				Log:                  log.Log,
+				Client:               myclient,
+				SecretsClientFactory: secrets,
+				Recorder:             recorder,
+			}
+			refreshes := bootstrapTokenRefreshes(t)
+			refreshAfter, err := k.reconcileBootstrapToken(context.Background(), config)
+			if err != nil {
+				t.Fatalf("expected nil, got error %v", err)
+			}
+			if counted := bootstrapTokenRefreshes(t) > refreshes; counted != tc.expectRefresh {
+				t.Fatalf("expected the refresh to be counted %t, got %t", tc.expectRefresh, counted)
+			}
+			if tc.expectRefresh && len(recorder.Events) != 2 {
+				t.Fatalf("expected a refresh event for the config and the machine, got %d events", len(recorder.Events))
+			}
+			if !tc.expectRefresh && len(recorder.Events) != 0 {
+				t.Fatalf("expected no event without a refresh, got %s", <-recorder.Events)
+			}
+			if refreshAfter < tc.expectAfter-time.Minute || refreshAfter > tc.expectAfter {
+				t.Fatalf("expected a refresh due in about %s, got %s", tc.expectAfter, refreshAfter)
+			}
+
+			cfg, err := getKubeadmConfig(myclient, "worker-join-cfg")
+			if err != nil {
+				t.Fatalf("failed to get the config: %v", err)
+			}
+			if cfg.Status.BootstrapTokenRevoked != (tc.nodeRef != nil) {
+				t.Fatalf("expected the token revocation to be recorded %t, got %t", tc.nodeRef != nil, cfg.Status.BootstrapTokenRevoked)
+			}
+			if tc.missing {
+				return
+			}
+
+			secret, err := secrets.client.Get("bootstrap-token-abcdef", metav1.GetOptions{})
+			if apierrors.IsNotFound(err) != tc.expectRevoke {
+				t.Fatalf("expected the token secret to be deleted %t, got error %v", tc.expectRevoke, err)
+			}
+			if tc.expectRevoke {
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get the token secret: %v", err)
+			}
+			refreshed := string(secret.Data[bootstrapapi.BootstrapTokenExpirationKey]) != expiration
+			if refreshed != tc.expectRefresh {
+				t.Fatalf("expected refreshed %t, got expiration %s", tc.expectRefresh, secret.Data[bootstrapapi.BootstrapTokenExpirationKey])
+			}
+			if refreshed {
+				extended, err := time.Parse(time.RFC3339, string(secret.Data[bootstrapapi.BootstrapTokenExpirationKey]))
+				if err != nil || extended.Before(time.Now().Add(defaultTokenTTL-time.Minute)) {
+					t.Fatalf("expected the token to be valid for the TTL, got expiration %s", secret.Data[bootstrapapi.BootstrapTokenExpirationKey])
+				}
+			}
+		})
+	}
+}
+
+// bootstrapTokenRefreshes returns the number of refreshes of the bootstrap tokens of the test cluster counted so far.
+func bootstrapTokenRefreshes(t *testing.T) float64 {
+	metric := &dto.Metric{}
+	if err := bootstrapTokensTotal.WithLabelValues("default", "cluster", "refresh").(prometheus.Metric).Write(metric); err != nil {
+		t.Fatalf("Failed to read metric:\n %+v", err)
+	}
+	return metric.GetCounter().GetValue()
+}
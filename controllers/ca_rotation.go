@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+const (
+	// CARotationAnnotationKey requests a phase of the rotation of the CAs of a cluster when set on the Cluster,
+	// one of introduce, regenerate or retire. Each phase is applied once, in order, and only takes effect on the
+	// machines bootstrapped afterwards, which must replace the existing ones before moving to the next phase.
+	CARotationAnnotationKey = "bootstrap.cluster.x-k8s.io/ca-rotation"
+
+	// CARotationPhaseAnnotationKey records the last rotation phase applied to the certificates secret of a cluster.
+	CARotationPhaseAnnotationKey = "bootstrap.cluster.x-k8s.io/ca-rotation-phase"
+)
+
+// caRotationTransitions are the phases each rotation phase may follow.
+var caRotationTransitions = map[certs.CARotationPhase][]certs.CARotationPhase{
+	certs.CARotationIntroduce:  {"", certs.CARotationRetire},
+	certs.CARotationRegenerate: {certs.CARotationIntroduce},
+	certs.CARotationRetire:     {certs.CARotationRegenerate},
+}
+
+// caRotationReasons are the reasons of the CARotation condition, by the phase it was observed in.
+var caRotationReasons = map[certs.CARotationPhase]string{
+	certs.CARotationIntroduce:  "CAIntroduced",
+	certs.CARotationRegenerate: "CARegenerated",
+	certs.CARotationRetire:     "CARetired",
+}
+
+// reconcileCARotation returns the certificates of the cluster, first applying the rotation phase requested on
+// the cluster if it has not been applied yet, and sets the CARotation condition of the config.
+func (r *KubeadmConfigReconciler) reconcileCARotation(ctx context.Context, cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig) (*certs.Certificates, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ClusterCertificatesSecretName(cluster.GetName()), Namespace: config.GetNamespace()}, secret); err != nil {
+		return nil, err
+	}
+	certificates := certs.NewCertificatesFromMap(secret.Data)
+
+	applied := certs.CARotationPhase(secret.GetAnnotations()[CARotationPhaseAnnotationKey])
+	requested := certs.CARotationPhase(cluster.GetAnnotations()[CARotationAnnotationKey])
+	if requested != "" && requested != applied {
+		allowed, ok := caRotationTransitions[requested]
+		if !ok {
+			return nil, errors.Errorf("unknown CA rotation phase %q requested by annotation %s", requested, CARotationAnnotationKey)
+		}
+		if !containsPhase(allowed, applied) {
+			return nil, errors.Errorf("CA rotation phase %q cannot follow phase %q", requested, applied)
+		}
+		if requested == certs.CARotationIntroduce && r.certificateGenerationDisabled(cluster) {
+			return nil, errors.New("CA rotation cannot introduce new CAs while certificate generation is disabled")
+		}
+		opts, err := r.certificateOptions(cluster)
+		if err != nil {
+			return nil, err
+		}
+		if err := certificates.RotateWithOptions(requested, opts); err != nil {
+			return nil, err
+		}
+
+		secret.Data = certificates.ToMap()
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[CARotationPhaseAnnotationKey] = string(requested)
+		if err := r.Update(ctx, secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to store the %s phase of the CA rotation", requested)
+		}
+		r.certificatesCache.evict(types.NamespacedName{Name: secret.GetName(), Namespace: secret.GetNamespace()})
+		certificateGenerationsTotal.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), "rotate").Inc()
+		r.Log.Info("Applied CA rotation phase", "cluster", cluster.GetName(), "phase", requested)
+		applied = requested
+	}
+
+	if applied != "" {
+		condition := cabpkv1alpha2.KubeadmConfigCondition{
+			Type:    cabpkv1alpha2.CARotationCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  caRotationReasons[applied],
+			Message: fmt.Sprintf("bootstrap data generated in the %s phase of the CA rotation", applied),
+		}
+		if applied == certs.CARotationRetire {
+			condition.Status = corev1.ConditionFalse
+		}
+		setCondition(&config.Status, condition, time.Now())
+	}
+	return certificates, nil
+}
+
+func containsPhase(phases []certs.CARotationPhase, phase certs.CARotationPhase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileCARotation(t *testing.T) {
+	cluster := newCluster("rotating-cluster")
+	config := newControlPlaneJoinKubeadmConfig(nil, "cfg")
+
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("Failed to generate certificates:\n %+v", err)
+	}
+	original := certificates.ClusterCA.Cert
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ClusterCertificatesSecretName(cluster.GetName()),
+			Namespace: config.GetNamespace(),
+		},
+		Data: certificates.ToMap(),
+	}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), secret),
+	}
+
+	// without a requested phase the certificates are left alone
+	got, err := k.reconcileCARotation(context.Background(), cluster, config)
+	if err != nil {
+		t.Fatalf("Failed to reconcile CA rotation:\n %+v", err)
+	}
+	if got.ClusterCA.Next != nil || len(config.Status.Conditions) != 0 {
+		t.Fatal("expected no rotation without the annotation")
+	}
+
+	for _, phase := range []certs.CARotationPhase{certs.CARotationIntroduce, certs.CARotationRegenerate, certs.CARotationRetire} {
+		cluster.Annotations = map[string]string{CARotationAnnotationKey: string(phase)}
+		got, err = k.reconcileCARotation(context.Background(), cluster, config)
+		if err != nil {
+			t.Fatalf("Failed to apply CA rotation phase %s:\n %+v", phase, err)
+		}
+
+		stored := &corev1.Secret{}
+		if err := k.Get(context.Background(), types.NamespacedName{Name: secret.GetName(), Namespace: secret.GetNamespace()}, stored); err != nil {
+			t.Fatalf("Failed to get the certificates secret:\n %+v", err)
+		}
+		if stored.Annotations[CARotationPhaseAnnotationKey] != string(phase) {
+			t.Errorf("expected applied phase %s, got %q", phase, stored.Annotations[CARotationPhaseAnnotationKey])
+		}
+		if !bytes.Equal(stored.Data["cluster-ca-cert"], got.ClusterCA.Cert) {
+			t.Errorf("expected the stored certificates to match the ones returned in phase %s", phase)
+		}
+
+		condition := config.Status.Conditions[0]
+		if condition.Type != cabpkv1alpha2.CARotationCondition || condition.Reason != caRotationReasons[phase] {
+			t.Errorf("expected condition %s with reason %s, got %s with reason %s", cabpkv1alpha2.CARotationCondition, caRotationReasons[phase], condition.Type, condition.Reason)
+		}
+	}
+	if bytes.Equal(got.ClusterCA.Cert, original) {
+		t.Error("expected the cluster CA to be rotated")
+	}
+	if config.Status.Conditions[0].Status != corev1.ConditionFalse {
+		t.Errorf("expected the CARotation condition to be false once retired, got %s", config.Status.Conditions[0].Status)
+	}
+
+	// applied phases are not applied again
+	got, err = k.reconcileCARotation(context.Background(), cluster, config)
+	if err != nil {
+		t.Fatalf("Failed to reconcile CA rotation:\n %+v", err)
+	}
+	if got.ClusterCA.Previous != nil {
+		t.Error("expected the retired CA to stay retired")
+	}
+}
+
+func TestReconcileCARotationOutOfOrder(t *testing.T) {
+	cluster := newCluster("rotating-cluster")
+	cluster.Annotations = map[string]string{CARotationAnnotationKey: string(certs.CARotationRetire)}
+	config := newControlPlaneJoinKubeadmConfig(nil, "cfg")
+
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("Failed to generate certificates:\n %+v", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ClusterCertificatesSecretName(cluster.GetName()),
+			Namespace: config.GetNamespace(),
+		},
+		Data: certificates.ToMap(),
+	}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), secret),
+	}
+	if _, err := k.reconcileCARotation(context.Background(), cluster, config); err == nil {
+		t.Error("expected retire to be rejected before introduce")
+	}
+
+	cluster.Annotations[CARotationAnnotationKey] = "rekey"
+	if _, err := k.reconcileCARotation(context.Background(), cluster, config); err == nil {
+		t.Error("expected an unknown phase to be rejected")
+	}
+}
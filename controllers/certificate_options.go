@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+const (
+	// CertificateKeyTypeAnnotationKey sets the type of the keys generated for the CAs and service account key
+	// pair of a cluster when set on the Cluster, one of rsa-2048, rsa-3072, rsa-4096 or ecdsa-p256, overriding the
+	// CertificateKeyType of the reconciler. It only applies to the key pairs generated afterwards, so the CAs of an
+	// existing cluster change type through a CA rotation.
+	CertificateKeyTypeAnnotationKey = "bootstrap.cluster.x-k8s.io/certificate-key-type"
+
+	// CACertificateValidityAnnotationKey sets how long the CA certificates generated for a cluster are valid when
+	// set on the Cluster, as a duration such as 43800h, overriding the CACertificateValidity of the reconciler. The
+	// certificates kubeadm signs with the CAs stay valid for a year, or until the CAs expire if sooner.
+	CACertificateValidityAnnotationKey = "bootstrap.cluster.x-k8s.io/ca-certificate-validity"
+
+	// APIServerCertSANsAnnotationKey adds a comma separated list of Subject Alternative Names, e.g. the DNS name of
+	// a load balancer fronting the API servers, to the serving certificate kubeadm generates for the API servers of
+	// a cluster when set on the Cluster.
+	APIServerCertSANsAnnotationKey = "bootstrap.cluster.x-k8s.io/api-server-cert-sans"
+
+	// minCACertificateValidity is the shortest validity of the generated CA certificates.
+	minCACertificateValidity = 24 * time.Hour
+)
+
+// certificateOptions returns the options of the key pairs generated for the cluster.
+func (r *KubeadmConfigReconciler) certificateOptions(cluster *capiv1alpha2.Cluster) (certs.Options, error) {
+	keyType := r.CertificateKeyType
+	if s, ok := cluster.GetAnnotations()[CertificateKeyTypeAnnotationKey]; ok {
+		kt, err := certs.ParseKeyType(s)
+		if err != nil {
+			return certs.Options{}, errors.Wrapf(err, "invalid annotation %s", CertificateKeyTypeAnnotationKey)
+		}
+		keyType = kt
+	}
+
+	validity := r.CACertificateValidity
+	if s, ok := cluster.GetAnnotations()[CACertificateValidityAnnotationKey]; ok {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return certs.Options{}, errors.Wrapf(err, "invalid annotation %s", CACertificateValidityAnnotationKey)
+		}
+		validity = d
+	}
+	if validity != 0 && validity < minCACertificateValidity {
+		return certs.Options{}, errors.Errorf("the CA certificate validity %s is shorter than %s", validity, minCACertificateValidity)
+	}
+	return certs.Options{KeyType: keyType, CAValidity: validity}, nil
+}
+
+// wireAPIServerCertSANs adds the Subject Alternative Names of the APIServerCertSANsAnnotationKey of the cluster to
+// the API server of the ClusterConfiguration. Machines joining the control plane later use the ClusterConfiguration
+// uploaded by kubeadm init, SANs included.
+func wireAPIServerCertSANs(cluster *capiv1alpha2.Cluster, cfg *kubeadmv1beta1.ClusterConfiguration) {
+	for _, san := range strings.Split(cluster.GetAnnotations()[APIServerCertSANsAnnotationKey], ",") {
+		if san = strings.TrimSpace(san); san != "" && !containsString(cfg.APIServer.CertSANs, san) {
+			cfg.APIServer.CertSANs = append(cfg.APIServer.CertSANs, san)
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
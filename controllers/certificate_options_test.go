@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"reflect"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestCertificateOptions(t *testing.T) {
+	k := &KubeadmConfigReconciler{Log: log.Log, CertificateKeyType: certs.RSA4096KeyType}
+
+	cluster := newCluster("cluster")
+	if opts, err := k.certificateOptions(cluster); err != nil || opts.KeyType != certs.RSA4096KeyType {
+		t.Fatalf("expected the key type of the reconciler, got %v, %v", opts, err)
+	}
+	cluster.Annotations = map[string]string{CertificateKeyTypeAnnotationKey: "ecdsa-p256"}
+	if opts, err := k.certificateOptions(cluster); err != nil || opts.KeyType != certs.ECDSAP256KeyType {
+		t.Fatalf("expected the key type of the annotation, got %v, %v", opts, err)
+	}
+	cluster.Annotations[CertificateKeyTypeAnnotationKey] = "rsa-1024"
+	if _, err := k.certificateOptions(cluster); err == nil {
+		t.Fatal("expected an invalid annotation to fail")
+	}
+}
+
+func TestCreateClusterCertificatesWithKeyType(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Annotations = map[string]string{CertificateKeyTypeAnnotationKey: "ecdsa-p256"}
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme()),
+	}
+	certificates, err := k.createClusterCertificates(context.Background(), cluster, newKubeadmConfig(nil, "cfg"))
+	if err != nil {
+		t.Fatalf("failed to create the cluster certificates: %v", err)
+	}
+	for _, cert := range [][]byte{certificates.ClusterCA.Cert, certificates.EtcdCA.Cert, certificates.FrontProxyCA.Cert} {
+		block, _ := pem.Decode(cert)
+		parsed, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("failed to parse the CA certificate: %v", err)
+		}
+		if _, ok := parsed.PublicKey.(*ecdsa.PublicKey); !ok {
+			t.Fatalf("expected an ECDSA CA, got %T", parsed.PublicKey)
+		}
+	}
+}
+
+func TestCertificateOptionsCAValidity(t *testing.T) {
+	k := &KubeadmConfigReconciler{Log: log.Log, CACertificateValidity: 5 * 365 * 24 * time.Hour}
+
+	cluster := newCluster("cluster")
+	if opts, err := k.certificateOptions(cluster); err != nil || opts.CAValidity != 5*365*24*time.Hour {
+		t.Fatalf("expected the validity of the reconciler, got %v, %v", opts, err)
+	}
+	cluster.Annotations = map[string]string{CACertificateValidityAnnotationKey: "43800h"}
+	if opts, err := k.certificateOptions(cluster); err != nil || opts.CAValidity != 43800*time.Hour {
+		t.Fatalf("expected the validity of the annotation, got %v, %v", opts, err)
+	}
+	for _, invalid := range []string{"5y", "1h"} {
+		cluster.Annotations[CACertificateValidityAnnotationKey] = invalid
+		if _, err := k.certificateOptions(cluster); err == nil {
+			t.Fatalf("expected validity %q to fail", invalid)
+		}
+	}
+}
+
+func TestWireAPIServerCertSANs(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Annotations = map[string]string{APIServerCertSANsAnnotationKey: "lb.example.com, 10.0.0.1,,lb.example.com"}
+	cfg := &kubeadmv1beta1.ClusterConfiguration{}
+	cfg.APIServer.CertSANs = []string{"10.0.0.1"}
+
+	wireAPIServerCertSANs(cluster, cfg)
+	if !reflect.DeepEqual(cfg.APIServer.CertSANs, []string{"10.0.0.1", "lb.example.com"}) {
+		t.Fatalf("unexpected SANs %v", cfg.APIServer.CertSANs)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// certificatesCache holds the data of the certificates secrets of the clusters, so that the reconciles of the
+// many configs of a cluster do not each look its certificates up. Entries are evicted whenever their secret is
+// created, updated or deleted, either by the reconciler or by a watch on the secrets. A nil cache caches nothing.
+type certificatesCache struct {
+	mu      sync.RWMutex
+	secrets map[types.NamespacedName]map[string][]byte
+}
+
+func newCertificatesCache() *certificatesCache {
+	return &certificatesCache{secrets: map[types.NamespacedName]map[string][]byte{}}
+}
+
+// get returns the certificates cached for the secret, or nil if there are none. Each call returns new
+// Certificates, which callers may modify.
+func (c *certificatesCache) get(key types.NamespacedName) *certs.Certificates {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	data, ok := c.secrets[key]
+	if !ok {
+		return nil
+	}
+	return certs.NewCertificatesFromMap(data)
+}
+
+func (c *certificatesCache) set(key types.NamespacedName, data map[string][]byte) {
+	if c == nil {
+		return
+	}
+	copied := make(map[string][]byte, len(data))
+	for k, v := range data {
+		copied[k] = append([]byte(nil), v...)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.secrets[key] = copied
+}
+
+func (c *certificatesCache) evict(key types.NamespacedName) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.secrets, key)
+}
+
+// evictSecretCertificates evicts the cached certificates of a certificates secret on any event of the secret. It
+// never enqueues a config.
+func (r *KubeadmConfigReconciler) evictSecretCertificates(o handler.MapObject) []ctrl.Request {
+	secret, ok := o.Object.(*corev1.Secret)
+	if !ok || !isCertificatesSecret(secret) {
+		return nil
+	}
+	r.certificatesCache.evict(types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()})
+	return nil
+}
+
+// certificatesSecretPredicate filters out the events of the secrets other than the certificates secrets of the
+// clusters, so that the watch on the secrets does not handle every secret of the management cluster.
+var certificatesSecretPredicate = predicate.Funcs{
+	CreateFunc:  func(e event.CreateEvent) bool { return isCertificatesSecret(e.Meta) },
+	UpdateFunc:  func(e event.UpdateEvent) bool { return isCertificatesSecret(e.MetaNew) },
+	DeleteFunc:  func(e event.DeleteEvent) bool { return isCertificatesSecret(e.Meta) },
+	GenericFunc: func(e event.GenericEvent) bool { return isCertificatesSecret(e.Meta) },
+}
+
+// isCertificatesSecret returns whether the object is named as the certificates secret of a cluster.
+func isCertificatesSecret(o metav1.Object) bool {
+	return o != nil && strings.HasSuffix(o.GetName(), ClusterCertificatesSecretName(""))
+}
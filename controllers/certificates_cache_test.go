@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestClusterCertificatesAreCached(t *testing.T) {
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("failed to generate the certificates: %v", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: ClusterCertificatesSecretName("cluster")},
+		Data:       certificates.ToMap(),
+	}
+	k := &KubeadmConfigReconciler{
+		Log:               log.Log,
+		Client:            fake.NewFakeClientWithScheme(setupScheme(), secret),
+		certificatesCache: newCertificatesCache(),
+	}
+
+	cached, err := k.getClusterCertificates(context.Background(), "cluster", "default")
+	if err != nil {
+		t.Fatalf("failed to get the cluster certificates: %v", err)
+	}
+	cached.ClusterCA = &certs.KeyPair{}
+
+	// the cached certificates are returned without looking the secret up again
+	if err := k.Delete(context.Background(), secret); err != nil {
+		t.Fatalf("failed to delete the certificates secret: %v", err)
+	}
+	cached, err = k.getClusterCertificates(context.Background(), "cluster", "default")
+	if err != nil {
+		t.Fatalf("expected the certificates to be cached, got %v", err)
+	}
+	if !bytes.Equal(cached.ClusterCA.Cert, certificates.ClusterCA.Cert) {
+		t.Fatal("expected the cached certificates not to be modified by their callers")
+	}
+
+	// an event of the secret evicts its certificates
+	if requests := k.evictSecretCertificates(handler.MapObject{Meta: secret, Object: secret}); len(requests) != 0 {
+		t.Fatalf("expected no config to be enqueued, got %v", requests)
+	}
+	if _, err := k.getClusterCertificates(context.Background(), "cluster", "default"); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected the evicted certificates to be looked up again, got %v", err)
+	}
+}
+
+func TestCertificatesSecretPredicate(t *testing.T) {
+	certificates := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: ClusterCertificatesSecretName("cluster")}}
+	if !certificatesSecretPredicate.Create(event.CreateEvent{Meta: certificates, Object: certificates}) {
+		t.Error("expected the creation of a certificates secret to be handled")
+	}
+	if !certificatesSecretPredicate.Update(event.UpdateEvent{MetaOld: certificates, ObjectOld: certificates, MetaNew: certificates, ObjectNew: certificates}) {
+		t.Error("expected the update of a certificates secret to be handled")
+	}
+	if !certificatesSecretPredicate.Delete(event.DeleteEvent{Meta: certificates, Object: certificates}) {
+		t.Error("expected the deletion of a certificates secret to be handled")
+	}
+
+	other := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cluster-kubeconfig"}}
+	if certificatesSecretPredicate.Create(event.CreateEvent{Meta: other, Object: other}) {
+		t.Error("did not expect the creation of another secret to be handled")
+	}
+	if certificatesSecretPredicate.Update(event.UpdateEvent{MetaOld: other, ObjectOld: other, MetaNew: other, ObjectNew: other}) {
+		t.Error("did not expect the update of another secret to be handled")
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultCertificatesExpiryWarningWindow is how long before the CertificatesExpiry the CertificatesExpiring
+// condition is set, unless configured otherwise.
+const DefaultCertificatesExpiryWarningWindow = 30 * 24 * time.Hour
+
+// recordCertificatesExpiry publishes the expiry of the CA certificates of the cluster as metrics, records them and
+// the soonest one in the config status and sets the CertificatesExpiring condition accordingly.
+func (r *KubeadmConfigReconciler) recordCertificatesExpiry(config *cabpkv1alpha2.KubeadmConfig, clusterName string, certificates *certs.Certificates) error {
+	expiries, err := certificates.Expiries()
+	if err != nil {
+		return err
+	}
+
+	var soonest time.Time
+	perCertificate := make([]cabpkv1alpha2.CertificateExpiry, 0, len(expiries))
+	for name, notAfter := range expiries {
+		certificateExpiryTimestampSeconds.WithLabelValues(config.GetNamespace(), clusterName, name).Set(float64(notAfter.Unix()))
+		if soonest.IsZero() || notAfter.Before(soonest) {
+			soonest = notAfter
+		}
+		perCertificate = append(perCertificate, cabpkv1alpha2.CertificateExpiry{Name: name, NotAfter: metav1.NewTime(notAfter)})
+	}
+	sort.Slice(perCertificate, func(i, j int) bool { return perCertificate[i].Name < perCertificate[j].Name })
+	expiry := metav1.NewTime(soonest)
+	config.Status.CertificatesExpiry = &expiry
+	config.Status.CertificateExpiries = perCertificate
+	r.setCertificatesExpiringCondition(config, time.Now())
+	return nil
+}
+
+// reconcileCertificatesExpiring re-evaluates the CertificatesExpiring condition of a ready config, and requeues
+// it for when the condition is due to change.
+func (r *KubeadmConfigReconciler) reconcileCertificatesExpiring(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) (ctrl.Result, error) {
+	if config.Status.CertificatesExpiry == nil {
+		return ctrl.Result{}, nil
+	}
+
+	patchConfig := client.MergeFrom(config.DeepCopy())
+	changed, requeueAfter := r.setCertificatesExpiringCondition(config, time.Now())
+	if changed {
+		if err := r.Status().Patch(ctx, config, patchConfig); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// setCertificatesExpiringCondition sets the CertificatesExpiring condition as of now, returning whether it changed
+// and how long until it is due to change, 0 once the certificates are expiring.
+func (r *KubeadmConfigReconciler) setCertificatesExpiringCondition(config *cabpkv1alpha2.KubeadmConfig, now time.Time) (bool, time.Duration) {
+	window := r.CertificatesExpiryWarningWindow
+	if window == 0 {
+		window = DefaultCertificatesExpiryWarningWindow
+	}
+
+	expiry := config.Status.CertificatesExpiry.Time
+	warnAt := expiry.Add(-window)
+	condition := cabpkv1alpha2.KubeadmConfigCondition{
+		Type:    cabpkv1alpha2.CertificatesExpiringCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  "CertificatesValid",
+		Message: fmt.Sprintf("the CA certificates expire at %s", expiry.UTC().Format(time.RFC3339)),
+	}
+	var requeueAfter time.Duration
+	if now.Before(warnAt) {
+		requeueAfter = warnAt.Sub(now)
+	} else {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "CertificatesExpiringSoon"
+		if !now.Before(expiry) {
+			condition.Reason = "CertificatesExpired"
+			condition.Message = fmt.Sprintf("the CA certificates expired at %s", expiry.UTC().Format(time.RFC3339))
+		}
+	}
+	return setCondition(&config.Status, condition, now), requeueAfter
+}
+
+// setCondition sets the condition in the status, replacing the one of the same type. The last transition time
+// is only updated if the status of the condition changes. It returns whether the condition changed.
+func setCondition(status *cabpkv1alpha2.KubeadmConfigStatus, condition cabpkv1alpha2.KubeadmConfigCondition, now time.Time) bool {
+	condition.LastTransitionTime = metav1.NewTime(now)
+	for i, existing := range status.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status {
+			if existing.Reason == condition.Reason && existing.Message == condition.Message {
+				return false
+			}
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		status.Conditions[i] = condition
+		return true
+	}
+	status.Conditions = append(status.Conditions, condition)
+	return true
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+)
+
+func TestSetCertificatesExpiringCondition(t *testing.T) {
+	now := time.Now()
+	testcases := []struct {
+		name                 string
+		expiry               time.Time
+		expectedStatus       corev1.ConditionStatus
+		expectedReason       string
+		expectedRequeueAfter time.Duration
+	}{
+		{
+			name:                 "outside the warning window",
+			expiry:               now.Add(DefaultCertificatesExpiryWarningWindow + time.Hour),
+			expectedStatus:       corev1.ConditionFalse,
+			expectedReason:       "CertificatesValid",
+			expectedRequeueAfter: time.Hour,
+		},
+		{
+			name:           "within the warning window",
+			expiry:         now.Add(time.Hour),
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: "CertificatesExpiringSoon",
+		},
+		{
+			name:           "expired",
+			expiry:         now.Add(-time.Hour),
+			expectedStatus: corev1.ConditionTrue,
+			expectedReason: "CertificatesExpired",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := newKubeadmConfig(nil, "cfg")
+			expiry := metav1.NewTime(tc.expiry)
+			config.Status.CertificatesExpiry = &expiry
+
+			k := &KubeadmConfigReconciler{}
+			changed, requeueAfter := k.setCertificatesExpiringCondition(config, now)
+			if !changed {
+				t.Fatal("expected the condition to be set")
+			}
+			if requeueAfter != tc.expectedRequeueAfter {
+				t.Errorf("expected requeue after %s, got %s", tc.expectedRequeueAfter, requeueAfter)
+			}
+			if len(config.Status.Conditions) != 1 {
+				t.Fatalf("expected one condition, got %v", config.Status.Conditions)
+			}
+			condition := config.Status.Conditions[0]
+			if condition.Type != cabpkv1alpha2.CertificatesExpiringCondition {
+				t.Errorf("expected condition %q, got %q", cabpkv1alpha2.CertificatesExpiringCondition, condition.Type)
+			}
+			if condition.Status != tc.expectedStatus || condition.Reason != tc.expectedReason {
+				t.Errorf("expected status %q with reason %q, got %q with reason %q", tc.expectedStatus, tc.expectedReason, condition.Status, condition.Reason)
+			}
+		})
+	}
+}
+
+func TestSetCertificatesExpiringConditionWindow(t *testing.T) {
+	now := time.Now()
+	config := newKubeadmConfig(nil, "cfg")
+	expiry := metav1.NewTime(now.Add(48 * time.Hour))
+	config.Status.CertificatesExpiry = &expiry
+
+	k := &KubeadmConfigReconciler{CertificatesExpiryWarningWindow: 24 * time.Hour}
+	_, requeueAfter := k.setCertificatesExpiringCondition(config, now)
+	if requeueAfter != 24*time.Hour {
+		t.Errorf("expected requeue after 24h, got %s", requeueAfter)
+	}
+	if config.Status.Conditions[0].Status != corev1.ConditionFalse {
+		t.Errorf("expected the condition to be false, got %q", config.Status.Conditions[0].Status)
+	}
+}
+
+func TestSetConditionKeepsLastTransitionTime(t *testing.T) {
+	status := &cabpkv1alpha2.KubeadmConfigStatus{}
+	condition := cabpkv1alpha2.KubeadmConfigCondition{
+		Type:    cabpkv1alpha2.CertificatesExpiringCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "CertificatesExpiringSoon",
+		Message: "soon",
+	}
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if !setCondition(status, condition, start) {
+		t.Fatal("expected the condition to be added")
+	}
+	if setCondition(status, condition, time.Now()) {
+		t.Error("expected an unchanged condition not to be updated")
+	}
+
+	condition.Reason = "CertificatesExpired"
+	if !setCondition(status, condition, time.Now()) {
+		t.Fatal("expected the condition to be updated")
+	}
+	if !status.Conditions[0].LastTransitionTime.Time.Equal(start) {
+		t.Errorf("expected last transition time %s to be kept, got %s", start, status.Conditions[0].LastTransitionTime)
+	}
+
+	condition.Status = corev1.ConditionFalse
+	setCondition(status, condition, time.Now())
+	if status.Conditions[0].LastTransitionTime.Time.Equal(start) {
+		t.Error("expected last transition time to be updated on a status change")
+	}
+	if len(status.Conditions) != 1 {
+		t.Errorf("expected one condition, got %v", status.Conditions)
+	}
+}
+
+func TestRecordCertificatesExpiry(t *testing.T) {
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("Failed to generate certificates:\n %+v", err)
+	}
+	expiries, err := certificates.Expiries()
+	if err != nil {
+		t.Fatalf("Failed to parse certificate expiries:\n %+v", err)
+	}
+
+	config := newKubeadmConfig(nil, "cfg")
+	k := &KubeadmConfigReconciler{}
+	if err := k.recordCertificatesExpiry(config, "expiry-cluster", certificates); err != nil {
+		t.Fatalf("Failed to record certificates expiry:\n %+v", err)
+	}
+	if config.Status.CertificatesExpiry == nil {
+		t.Fatal("expected the certificates expiry to be set")
+	}
+	if len(config.Status.Conditions) != 1 {
+		t.Errorf("expected one condition, got %v", config.Status.Conditions)
+	}
+	names := []string{}
+	for _, e := range config.Status.CertificateExpiries {
+		names = append(names, e.Name)
+		if !e.NotAfter.Time.Equal(expiries[e.Name]) {
+			t.Errorf("expected %s to expire at %s, got %s", e.Name, expiries[e.Name], e.NotAfter)
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"ca", "etcd-ca", "front-proxy-ca"}) {
+		t.Errorf("expected the expiry of each CA certificate, got %v", config.Status.CertificateExpiries)
+	}
+
+	metric := &dto.Metric{}
+	if err := certificateExpiryTimestampSeconds.WithLabelValues("default", "expiry-cluster", "ca").(prometheus.Metric).Write(metric); err != nil {
+		t.Fatalf("Failed to read metric:\n %+v", err)
+	}
+	if metric.GetGauge().GetValue() != float64(expiries["ca"].Unix()) {
+		t.Errorf("expected expiry %d, got %v", expiries["ca"].Unix(), metric.GetGauge().GetValue())
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"math/big"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+// clusterDNSIPIndex is the index, in the service subnet, of the IP kubeadm allocates to the cluster DNS service.
+const clusterDNSIPIndex = 10
+
+// kubeletDNS is the cluster domain and DNS servers of a kubelet. Empty values leave the ones kubeadm defaults to.
+type kubeletDNS struct {
+	Domain  string
+	Servers []string
+}
+
+// wireClusterNetwork defaults the networking of the ClusterConfiguration to the cluster network of the Cluster, so
+// that kubeadm init and the kubelets of the joining machines agree on it.
+func wireClusterNetwork(cluster *capiv1alpha2.Cluster, cfg *kubeadmv1beta1.ClusterConfiguration) {
+	network := cluster.Spec.ClusterNetwork
+	if network == nil {
+		return
+	}
+	if cfg.Networking.DNSDomain == "" {
+		cfg.Networking.DNSDomain = network.ServiceDomain
+	}
+	if cfg.Networking.ServiceSubnet == "" && network.Services != nil {
+		cfg.Networking.ServiceSubnet = strings.Join(network.Services.CIDRBlocks, ",")
+	}
+	if cfg.Networking.PodSubnet == "" && network.Pods != nil {
+		cfg.Networking.PodSubnet = strings.Join(network.Pods.CIDRBlocks, ",")
+	}
+}
+
+// initKubeletDNS returns the DNS of the kubelets of a cluster initialized with the ClusterConfiguration, unless
+// overridden by the ClusterDNS of spec.
+func initKubeletDNS(spec *cabpkv1alpha2.KubeadmConfigSpec, cfg *kubeadmv1beta1.ClusterConfiguration) (kubeletDNS, error) {
+	dns, err := networkKubeletDNS(cfg.Networking.DNSDomain, cfg.Networking.ServiceSubnet)
+	if err != nil {
+		return kubeletDNS{}, err
+	}
+	return overrideKubeletDNS(spec, dns), nil
+}
+
+// joinKubeletDNS returns the DNS of the kubelet of a machine joining the cluster, unless overridden by the
+// ClusterDNS of spec. Without a cluster network, the kubelet uses the DNS of the configuration uploaded by kubeadm
+// init.
+func joinKubeletDNS(spec *cabpkv1alpha2.KubeadmConfigSpec, cluster *capiv1alpha2.Cluster) (kubeletDNS, error) {
+	var dns kubeletDNS
+	if network := cluster.Spec.ClusterNetwork; network != nil {
+		var subnet string
+		if network.Services != nil {
+			subnet = strings.Join(network.Services.CIDRBlocks, ",")
+		}
+		var err error
+		if dns, err = networkKubeletDNS(network.ServiceDomain, subnet); err != nil {
+			return kubeletDNS{}, err
+		}
+	}
+	return overrideKubeletDNS(spec, dns), nil
+}
+
+// networkKubeletDNS returns the DNS of the kubelets of a cluster of the given service domain and subnets.
+func networkKubeletDNS(domain, serviceSubnet string) (kubeletDNS, error) {
+	dns := kubeletDNS{Domain: domain}
+	if serviceSubnet == "" {
+		return dns, nil
+	}
+	// as kubeadm, the cluster DNS IP is allocated in the first service subnet
+	_, subnet, err := net.ParseCIDR(strings.TrimSpace(strings.Split(serviceSubnet, ",")[0]))
+	if err != nil {
+		return kubeletDNS{}, errors.Wrapf(err, "invalid service subnet %q", serviceSubnet)
+	}
+	ip, err := indexedIP(subnet, clusterDNSIPIndex)
+	if err != nil {
+		return kubeletDNS{}, err
+	}
+	dns.Servers = []string{ip.String()}
+	return dns, nil
+}
+
+// overrideKubeletDNS returns the DNS overridden by the ClusterDNS of spec.
+func overrideKubeletDNS(spec *cabpkv1alpha2.KubeadmConfigSpec, dns kubeletDNS) kubeletDNS {
+	if override := spec.ClusterDNS; override != nil {
+		if override.Domain != "" {
+			dns.Domain = override.Domain
+		}
+		if len(override.Servers) > 0 {
+			dns.Servers = override.Servers
+		}
+	}
+	return dns
+}
+
+// indexedIP returns the IP at the given index of the subnet.
+func indexedIP(subnet *net.IPNet, index int64) (net.IP, error) {
+	base := big.NewInt(0).SetBytes(subnet.IP)
+	ip := big.NewInt(0).Add(base, big.NewInt(index)).Bytes()
+	out := make(net.IP, len(subnet.IP))
+	if len(ip) <= len(out) {
+		copy(out[len(out)-len(ip):], ip)
+	}
+	if len(ip) > len(out) || !subnet.Contains(out) {
+		return nil, errors.Errorf("service subnet %s is too small to allocate the cluster DNS IP", subnet)
+	}
+	return out, nil
+}
+
+// setJoinKubeletDNS sets the kubelet flags of a joining machine to the DNS, unless already set.
+func setJoinKubeletDNS(nodeRegistration *kubeadmv1beta1.NodeRegistrationOptions, dns kubeletDNS) {
+	args := map[string]string{}
+	if dns.Domain != "" {
+		args["cluster-domain"] = dns.Domain
+	}
+	if len(dns.Servers) > 0 {
+		args["cluster-dns"] = strings.Join(dns.Servers, ",")
+	}
+	if len(args) > 0 {
+		nodeRegistration.KubeletExtraArgs = defaultArgs(nodeRegistration.KubeletExtraArgs, args)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+func TestNetworkKubeletDNS(t *testing.T) {
+	testcases := []struct {
+		name      string
+		subnet    string
+		expected  []string
+		expectErr bool
+	}{
+		{name: "no subnet"},
+		{name: "kubeadm default subnet", subnet: "10.96.0.0/12", expected: []string{"10.96.0.10"}},
+		{name: "dual stack subnets", subnet: "fd00:10:96::/112,10.96.0.0/12", expected: []string{"fd00:10:96::a"}},
+		{name: "subnet too small", subnet: "10.96.0.0/29", expectErr: true},
+		{name: "invalid subnet", subnet: "10.96.0.0", expectErr: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dns, err := networkKubeletDNS("example.local", tc.subnet)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected nil, got error %v", err)
+			}
+			if dns.Domain != "example.local" || !reflect.DeepEqual(dns.Servers, tc.expected) {
+				t.Fatalf("expected domain example.local and servers %v, got %+v", tc.expected, dns)
+			}
+		})
+	}
+}
+
+func TestClusterDNSInitAndJoin(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Spec.ClusterNetwork = &capiv1alpha2.ClusterNetwork{
+		Services:      &capiv1alpha2.NetworkRanges{CIDRBlocks: []string{"10.128.0.0/16"}},
+		ServiceDomain: "example.local",
+	}
+	spec := &cabpkv1alpha2.KubeadmConfigSpec{}
+
+	cfg := &kubeadmv1beta1.ClusterConfiguration{}
+	wireClusterNetwork(cluster, cfg)
+	if cfg.Networking.DNSDomain != "example.local" || cfg.Networking.ServiceSubnet != "10.128.0.0/16" {
+		t.Fatalf("expected the networking of the cluster, got %+v", cfg.Networking)
+	}
+	initDNS, err := initKubeletDNS(spec, cfg)
+	if err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	joinDNS, err := joinKubeletDNS(spec, cluster)
+	if err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	expected := kubeletDNS{Domain: "example.local", Servers: []string{"10.128.0.10"}}
+	if !reflect.DeepEqual(initDNS, expected) || !reflect.DeepEqual(joinDNS, expected) {
+		t.Fatalf("expected init and join to agree on %+v, got %+v and %+v", expected, initDNS, joinDNS)
+	}
+
+	data, err := appendKubeletConfiguration(spec, initDNS, "kind: InitConfiguration\n")
+	if err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	if !strings.Contains(data, "clusterDNS:\n- 10.128.0.10\nclusterDomain: example.local\n") {
+		t.Fatalf("expected the kubelet configuration to hold the cluster DNS, got:\n%s", data)
+	}
+
+	nodeRegistration := &kubeadmv1beta1.NodeRegistrationOptions{KubeletExtraArgs: map[string]string{"cluster-dns": "10.128.0.53"}}
+	spec.ClusterDNS = &cabpkv1alpha2.ClusterDNS{Domain: "override.local"}
+	joinDNS, err = joinKubeletDNS(spec, cluster)
+	if err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	setJoinKubeletDNS(nodeRegistration, joinDNS)
+	if args := nodeRegistration.KubeletExtraArgs; args["cluster-domain"] != "override.local" || args["cluster-dns"] != "10.128.0.53" {
+		t.Fatalf("expected the overridden domain and the kubelet flag set by the user, got %v", args)
+	}
+}
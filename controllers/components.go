@@ -0,0 +1,108 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+const (
+	// schedulerConfigurationDir and controllerManagerConfigurationDir are the directories of the configuration
+	// files of the scheduler and controller manager on control plane machines, mounted in their pods.
+	schedulerConfigurationDir         = "/etc/kubernetes/scheduler"
+	controllerManagerConfigurationDir = "/etc/kubernetes/controller-manager"
+
+	schedulerConfigurationVolume         = "scheduler-config"
+	controllerManagerConfigurationVolume = "controller-manager-config"
+)
+
+// controlPlaneFiles returns the files generated for control plane machines of the given Kubernetes version.
+func controlPlaneFiles(config *cabpkv1alpha2.KubeadmConfig, version string) ([]cabpkv1alpha2.Files, error) {
+	files, err := admissionFiles(config, version)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, componentConfigurationFiles(&config.Spec)...), nil
+}
+
+// componentConfigurationFiles returns the configuration files of the scheduler and controller manager to write
+// on control plane machines.
+func componentConfigurationFiles(spec *cabpkv1alpha2.KubeadmConfigSpec) []cabpkv1alpha2.Files {
+	files := componentFiles(spec.SchedulerConfiguration, schedulerConfigurationDir)
+	return append(files, componentFiles(spec.ControllerManagerConfiguration, controllerManagerConfigurationDir)...)
+}
+
+func componentFiles(component *cabpkv1alpha2.ControlPlaneComponentConfiguration, dir string) []cabpkv1alpha2.Files {
+	if component == nil {
+		return nil
+	}
+
+	var files []cabpkv1alpha2.Files
+	for _, file := range component.Files {
+		files = append(files, cabpkv1alpha2.Files{
+			Path:        path.Join(dir, file.Name),
+			Owner:       "root:root",
+			Permissions: "0600",
+			Content:     file.Content,
+		})
+	}
+	return files
+}
+
+// wireComponentConfigurations passes the configuration files of the scheduler and controller manager with their
+// flags, and mounts their directories in the component pods. Control planes joining the cluster run with the
+// ClusterConfiguration stored by kubeadm init.
+func wireComponentConfigurations(spec *cabpkv1alpha2.KubeadmConfigSpec, cfg *kubeadmv1beta1.ClusterConfiguration) {
+	wireComponent(spec.SchedulerConfiguration, &cfg.Scheduler, schedulerConfigurationVolume, schedulerConfigurationDir)
+	wireComponent(spec.ControllerManagerConfiguration, &cfg.ControllerManager, controllerManagerConfigurationVolume, controllerManagerConfigurationDir)
+}
+
+func wireComponent(component *cabpkv1alpha2.ControlPlaneComponentConfiguration, cfg *kubeadmv1beta1.ControlPlaneComponent, volume, dir string) {
+	if component == nil || len(component.Files) == 0 {
+		return
+	}
+
+	args := map[string]string{}
+	for _, file := range component.Files {
+		if file.Flag != "" {
+			args[file.Flag] = path.Join(dir, file.Name)
+		}
+	}
+	cfg.ExtraArgs = defaultArgs(cfg.ExtraArgs, args)
+	mountDirectory(cfg, volume, dir)
+}
+
+// mountDirectory mounts the given directory of control plane machines read only in the component pod, unless
+// a volume with the same name is already mounted.
+func mountDirectory(cfg *kubeadmv1beta1.ControlPlaneComponent, volume, dir string) {
+	for _, v := range cfg.ExtraVolumes {
+		if v.Name == volume {
+			return
+		}
+	}
+	cfg.ExtraVolumes = append(cfg.ExtraVolumes, kubeadmv1beta1.HostPathMount{
+		Name:      volume,
+		HostPath:  dir,
+		MountPath: dir,
+		ReadOnly:  true,
+		PathType:  corev1.HostPathDirectory,
+	})
+}
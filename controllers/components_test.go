@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+func newComponentsKubeadmConfigSpec() *cabpkv1alpha2.KubeadmConfigSpec {
+	return &cabpkv1alpha2.KubeadmConfigSpec{
+		SchedulerConfiguration: &cabpkv1alpha2.ControlPlaneComponentConfiguration{
+			Files: []cabpkv1alpha2.ControlPlaneComponentFile{
+				{Name: "config.yaml", Flag: "config", Content: "kind: KubeSchedulerConfiguration\n"},
+			},
+		},
+		ControllerManagerConfiguration: &cabpkv1alpha2.ControlPlaneComponentConfiguration{
+			Files: []cabpkv1alpha2.ControlPlaneComponentFile{
+				{Name: "cloud.conf", Flag: "cloud-config", Content: "[Global]\n"},
+				{Name: "ca.pem", Content: "ca"},
+			},
+		},
+	}
+}
+
+func TestComponentConfigurationFiles(t *testing.T) {
+	files := componentConfigurationFiles(newComponentsKubeadmConfigSpec())
+
+	expected := []string{
+		"/etc/kubernetes/scheduler/config.yaml",
+		"/etc/kubernetes/controller-manager/cloud.conf",
+		"/etc/kubernetes/controller-manager/ca.pem",
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d files, got %+v", len(expected), files)
+	}
+	for i, p := range expected {
+		if files[i].Path != p {
+			t.Fatalf("expected file %d to be written to %s, got %s", i, p, files[i].Path)
+		}
+	}
+	if files[0].Content != "kind: KubeSchedulerConfiguration\n" {
+		t.Fatalf("expected the scheduler configuration to be written as is, got %q", files[0].Content)
+	}
+}
+
+func TestWireComponentConfigurations(t *testing.T) {
+	cfg := &kubeadmv1beta1.ClusterConfiguration{}
+
+	wireComponentConfigurations(newComponentsKubeadmConfigSpec(), cfg)
+	wireComponentConfigurations(newComponentsKubeadmConfigSpec(), cfg)
+
+	if cfg.Scheduler.ExtraArgs["config"] != "/etc/kubernetes/scheduler/config.yaml" {
+		t.Fatalf("expected the scheduler configuration to be passed with the config flag, got %v", cfg.Scheduler.ExtraArgs)
+	}
+	if len(cfg.ControllerManager.ExtraArgs) != 1 || cfg.ControllerManager.ExtraArgs["cloud-config"] != "/etc/kubernetes/controller-manager/cloud.conf" {
+		t.Fatalf("expected only the cloud configuration to be passed to the controller manager, got %v", cfg.ControllerManager.ExtraArgs)
+	}
+	if len(cfg.Scheduler.ExtraVolumes) != 1 || cfg.Scheduler.ExtraVolumes[0].MountPath != "/etc/kubernetes/scheduler" {
+		t.Fatalf("expected the scheduler directory to be mounted once, got %+v", cfg.Scheduler.ExtraVolumes)
+	}
+	if len(cfg.ControllerManager.ExtraVolumes) != 1 || cfg.ControllerManager.ExtraVolumes[0].MountPath != "/etc/kubernetes/controller-manager" {
+		t.Fatalf("expected the controller manager directory to be mounted once, got %+v", cfg.ControllerManager.ExtraVolumes)
+	}
+	if len(cfg.APIServer.ExtraVolumes) != 0 {
+		t.Fatalf("expected no API server volume, got %+v", cfg.APIServer.ExtraVolumes)
+	}
+}
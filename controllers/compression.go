@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+
+	"github.com/pkg/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// BootstrapDataEncodingAnnotationKey holds the encoding of the bootstrap data stored in a bootstrap data secret, set
+// if it is compressed.
+const BootstrapDataEncodingAnnotationKey = "bootstrap.cluster.x-k8s.io/data-encoding"
+
+// compressBootstrapData returns data compressed with the compression of config, if any, and its encoding. The
+// gzip header carries no modification time, so that the same data compresses to the same bytes.
+func compressBootstrapData(config *cabpkv1alpha2.KubeadmConfig, data []byte) ([]byte, cabpkv1alpha2.BootstrapDataCompression, error) {
+	switch config.Spec.Compression {
+	case "":
+		return data, "", nil
+	case cabpkv1alpha2.BootstrapDataCompressionGzip:
+		var out bytes.Buffer
+		w, err := gzip.NewWriterLevel(&out, gzip.BestCompression)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "failed to create the gzip writer")
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, "", errors.Wrap(err, "failed to compress the bootstrap data")
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", errors.Wrap(err, "failed to compress the bootstrap data")
+		}
+		return out.Bytes(), cabpkv1alpha2.BootstrapDataCompressionGzip, nil
+	}
+	return nil, "", newBootstrapFailure(invalidConfigurationReason, "unknown compression %q of KubeadmConfig %s/%s",
+		config.Spec.Compression, config.GetNamespace(), config.GetName())
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkV1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("expected gzip compressed data, got %v", err)
+	}
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to decompress the data: %v", err)
+	}
+	return string(out)
+}
+
+func TestSetBootstrapDataWithGzipCompression(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.Compression = cabpkV1alpha2.BootstrapDataCompressionGzip
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme())
+	k := &KubeadmConfigReconciler{
+		Log:                 log.Log,
+		Client:              myclient,
+		BootstrapDataSecret: true,
+	}
+	if err := k.setBootstrapData(context.Background(), newCluster("cluster"), config, []byte("#cloud-config\nruncmd: []\n")); err != nil {
+		t.Fatalf("Failed to set bootstrap data:\n %+v", err)
+	}
+
+	if data := gunzip(t, config.Status.BootstrapData); data != "#cloud-config\nruncmd: []\n" {
+		t.Fatalf("expected the compressed bootstrap data in the status, got %q", data)
+	}
+	if config.Status.BootstrapDataEncoding != cabpkV1alpha2.BootstrapDataCompressionGzip {
+		t.Fatalf("expected the gzip encoding to be recorded, got %q", config.Status.BootstrapDataEncoding)
+	}
+
+	secret := &corev1.Secret{}
+	if err := myclient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "cfg"}, secret); err != nil {
+		t.Fatalf("Failed to get bootstrap data secret:\n %+v", err)
+	}
+	if !bytes.Equal(secret.Data["value"], config.Status.BootstrapData) {
+		t.Fatal("expected the compressed bootstrap data to be stored in the secret")
+	}
+	if encoding := secret.Annotations[BootstrapDataEncodingAnnotationKey]; encoding != "gzip" {
+		t.Fatalf("expected the secret to be annotated with the gzip encoding, got %q", encoding)
+	}
+}
+
+func TestCompressBootstrapData(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	data, encoding, err := compressBootstrapData(config, []byte("#cloud-config"))
+	if err != nil || string(data) != "#cloud-config" || encoding != "" {
+		t.Fatalf("expected the data to be left uncompressed, got %q, %q, %v", string(data), encoding, err)
+	}
+
+	// the same data compresses to the same bytes, so that unchanged bootstrap data secrets are not updated
+	config.Spec.Compression = cabpkV1alpha2.BootstrapDataCompressionGzip
+	first, _, err := compressBootstrapData(config, []byte("#cloud-config"))
+	if err != nil {
+		t.Fatalf("failed to compress the data: %v", err)
+	}
+	second, _, err := compressBootstrapData(config, []byte("#cloud-config"))
+	if err != nil {
+		t.Fatalf("failed to compress the data: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatal("expected the compression to be deterministic")
+	}
+
+	config.Spec.Compression = "zstd"
+	if _, _, err := compressBootstrapData(config, []byte("#cloud-config")); err == nil {
+		t.Fatal("expected an unknown compression to be rejected")
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/pkg/errors"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultControlPlaneHealthSuccessThreshold is the number of consecutive successful probes required
+	// when ControlPlaneHealthGate.SuccessThreshold is not set.
+	defaultControlPlaneHealthSuccessThreshold = 3
+)
+
+// ControlPlaneHealthProber define behaviour for probing the health of a cluster control plane
+type ControlPlaneHealthProber interface {
+	// Probe returns an error if the control plane endpoint of the cluster is not healthy
+	Probe(client.Client, *capiv1alpha2.Cluster) error
+}
+
+// ClusterControlPlaneHealthProber probes the /healthz endpoint of the cluster API server
+type ClusterControlPlaneHealthProber struct {
+	// Options tune the clients of the workload clusters.
+	Options WorkloadClusterClientOptions
+}
+
+// Probe returns an error if the /healthz endpoint of the cluster API server does not report ok
+func (p ClusterControlPlaneHealthProber) Probe(client client.Client, cluster *capiv1alpha2.Cluster) error {
+	config, err := p.Options.RESTConfig(client, cluster)
+	if err != nil {
+		return err
+	}
+
+	corev1Client, err := corev1.NewForConfig(config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the client of the workload cluster")
+	}
+
+	body, err := corev1Client.RESTClient().Get().AbsPath("/healthz").Do().Raw()
+	if err != nil {
+		return errors.Wrap(err, "failed to probe control plane health")
+	}
+	if string(body) != "ok" {
+		return errors.Errorf("control plane reported unhealthy status %q", string(body))
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/cluster-api/pkg/util"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// DefaultControlPlaneInitTimeout is how long the machine holding the control plane init lock has to register
+// its node before the lock is handed off to the next control plane machine, unless configured otherwise.
+const DefaultControlPlaneInitTimeout = 30 * time.Minute
+
+// reconcileInitLockHandoff releases the control plane init lock held by the owner machine of a ready init config
+// if the machine failed or is being deleted, or if it was not handed the bootstrap data yet and did not register its
+// node within the init timeout, and clears the bootstrap data of the config, so that the next control plane machine
+// can initialize the cluster. It returns when the config is due to be checked again while the machine is
+// initializing the cluster.
+func (r *KubeadmConfigReconciler) reconcileInitLockHandoff(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) (time.Duration, error) {
+	if config.Spec.InitConfiguration == nil || config.Spec.ClusterConfiguration == nil {
+		return 0, nil
+	}
+
+	machine, err := util.GetOwnerMachine(ctx, r.Client, config.ObjectMeta)
+	if err != nil || machine == nil || machine.Status.NodeRef != nil {
+		return 0, err
+	}
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, machine.ObjectMeta)
+	if err != nil {
+		return 0, err
+	}
+	if cluster.Annotations[ControlPlaneReadyAnnotationKey] == "true" {
+		return 0, nil
+	}
+
+	holder, acquired, err := r.ControlPlaneInitLocker.Holder(cluster)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get the holder of the control plane init lock")
+	}
+	if holder != machine.Name {
+		return 0, nil
+	}
+
+	remaining := r.controlPlaneInitTimeout() - time.Since(acquired)
+	reason := "InitTimedOut"
+	switch {
+	case machine.DeletionTimestamp != nil:
+		reason = "InitMachineDeleted"
+	case isFailedMachine(machine):
+		reason = "InitMachineFailed"
+	case machine.Spec.Bootstrap.Data != nil:
+		// the machine may have booted with the bootstrap data and still be initializing the cluster, which another
+		// machine must not do too, so the lock is only handed off once it fails or is deleted. It is not renewed past
+		// the init timeout though, so that a lease held by a stuck machine expires.
+		if remaining <= 0 {
+			return 0, nil
+		}
+		return r.renewInitLock(cluster, machine, config, remaining)
+	default:
+		if remaining > 0 {
+			return r.renewInitLock(cluster, machine, config, remaining)
+		}
+	}
+
+	if !r.ControlPlaneInitLocker.Release(cluster) {
+		return 0, errors.Errorf("failed to release the control plane init lock of cluster %s/%s", cluster.GetNamespace(), cluster.GetName())
+	}
+	r.Log.Info("Handed off the control plane init lock", "cluster", cluster.GetName(), "machine", machine.GetName(), "reason", reason)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, reason,
+			"Control plane init lock released from machine %s so that another control plane machine can initialize the cluster", machine.GetName())
+	}
+
+	patchConfig := client.MergeFrom(config.DeepCopy())
+	config.Status.Ready = false
+	config.Status.BootstrapData = nil
+	config.Status.DataSecretName = nil
+	clearDataSecretAvailableCondition(config, reason,
+		fmt.Sprintf("the bootstrap data was cleared as the control plane init lock was handed off from machine %s", machine.GetName()))
+	if err := r.Status().Patch(ctx, config, patchConfig); err != nil {
+		return 0, errors.Wrap(err, "failed to clear the bootstrap data of the config")
+	}
+	return 0, nil
+}
+
+// renewInitLock renews the control plane init lock held by the machine while it is initializing the cluster, so
+// that a lease does not expire before the init times out after remaining. It returns when the lock is due to be
+// renewed, or when the init times out if that is sooner.
+func (r *KubeadmConfigReconciler) renewInitLock(cluster *capiv1alpha2.Cluster, machine *capiv1alpha2.Machine, config *cabpkv1alpha2.KubeadmConfig, remaining time.Duration) (time.Duration, error) {
+	if !r.ControlPlaneInitLocker.Acquire(cluster, machine, config) {
+		return 0, errors.Errorf("failed to renew the control plane init lock of cluster %s/%s", cluster.GetNamespace(), cluster.GetName())
+	}
+	if renew := r.ControlPlaneInitLockLeaseDuration / 2; renew > 0 && renew < remaining {
+		return renew, nil
+	}
+	return remaining, nil
+}
+
+// controlPlaneInitTimeout returns how long the machine holding the control plane init lock has to register its node.
+func (r *KubeadmConfigReconciler) controlPlaneInitTimeout() time.Duration {
+	if r.ControlPlaneInitTimeout == 0 {
+		return DefaultControlPlaneInitTimeout
+	}
+	return r.ControlPlaneInitTimeout
+}
+
+// acquireInitLock acquires the control plane init lock of the cluster on behalf of the machine and its config,
+// counting the attempt. Machines that failed or are being deleted never acquire it, it would only be handed off again.
+func (r *KubeadmConfigReconciler) acquireInitLock(cluster *capiv1alpha2.Cluster, machine *capiv1alpha2.Machine, config *cabpkv1alpha2.KubeadmConfig) bool {
+	if !canHoldInitLock(machine) {
+		return false
+	}
+	acquired := r.ControlPlaneInitLocker.Acquire(cluster, machine, config)
+	result := "acquired"
+	if !acquired {
+		result = "failed"
+	}
+	initLockAcquisitionsTotal.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), result).Inc()
+	return acquired
+}
+
+// canHoldInitLock returns whether the machine may hold the control plane init lock, i.e. it neither failed nor is being
+// deleted.
+func canHoldInitLock(machine *capiv1alpha2.Machine) bool {
+	return machine.DeletionTimestamp == nil && !isFailedMachine(machine)
+}
+
+// isFailedMachine returns whether the machine failed.
+func isFailedMachine(machine *capiv1alpha2.Machine) bool {
+	return capiv1alpha2.MachinePhase(machine.Status.Phase) == capiv1alpha2.MachinePhaseFailed || machine.Status.ErrorReason != nil
+}
+
+// releaseDeletedInitLockHolder releases the control plane init lock of the cluster if the machine holding it was
+// deleted, or is being deleted, before initializing the cluster. The config of a deleted machine may be deleted
+// with it, so the lock is checked on behalf of the other control plane machines waiting for it. It returns true if
+// the lock was released.
+func (r *KubeadmConfigReconciler) releaseDeletedInitLockHolder(ctx context.Context, cluster *capiv1alpha2.Cluster) (bool, error) {
+	holder, _, err := r.ControlPlaneInitLocker.Holder(cluster)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get the holder of the control plane init lock")
+	}
+	if holder == "" {
+		return false, nil
+	}
+
+	machine := &capiv1alpha2.Machine{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: cluster.GetNamespace(), Name: holder}, machine)
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return false, errors.Wrapf(err, "failed to get the machine %s holding the control plane init lock", holder)
+	case machine.DeletionTimestamp == nil:
+		return false, nil
+	}
+
+	if !r.ControlPlaneInitLocker.Release(cluster) {
+		return false, errors.Errorf("failed to release the control plane init lock of cluster %s/%s", cluster.GetNamespace(), cluster.GetName())
+	}
+	r.Log.Info("Released the control plane init lock of a deleted machine", "cluster", cluster.GetName(), "machine", holder)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InitMachineDeleted",
+			"Control plane init lock released from deleted machine %s so that another control plane machine can initialize the cluster", holder)
+	}
+	return true, nil
+}
+
+// machineToKubeadmConfig maps a Machine to the KubeadmConfig it is bootstrapped with.
+func (r *KubeadmConfigReconciler) machineToKubeadmConfig(o handler.MapObject) []ctrl.Request {
+	machine, ok := o.Object.(*capiv1alpha2.Machine)
+	if !ok {
+		return nil
+	}
+	ref := machine.Spec.Bootstrap.ConfigRef
+	if ref == nil || ref.Kind != "KubeadmConfig" || ref.GroupVersionKind().Group != cabpkv1alpha2.GroupVersion.Group {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: client.ObjectKey{Namespace: machine.GetNamespace(), Name: ref.Name}}}
+}
+
+// deletedMachineToKubeadmConfigs maps a control plane Machine being deleted to the KubeadmConfigs of the other
+// control plane machines of its cluster, so that one of them takes over the control plane init lock without
+// waiting to be requeued.
+func (r *KubeadmConfigReconciler) deletedMachineToKubeadmConfigs(o handler.MapObject) []ctrl.Request {
+	machine, ok := o.Object.(*capiv1alpha2.Machine)
+	if !ok || machine.DeletionTimestamp == nil || !util.IsControlPlaneMachine(machine) {
+		return nil
+	}
+	clusterName, ok := machine.Labels[capiv1alpha2.MachineClusterLabelName]
+	if !ok {
+		return nil
+	}
+
+	machines := &capiv1alpha2.MachineList{}
+	if err := r.List(context.Background(), machines, client.InNamespace(machine.Namespace),
+		client.MatchingLabels(map[string]string{capiv1alpha2.MachineClusterLabelName: clusterName})); err != nil {
+		r.Log.Error(err, "failed to list the machines of the cluster", "cluster", clusterName, "namespace", machine.Namespace)
+		return nil
+	}
+
+	var requests []ctrl.Request
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if m.Name == machine.Name || !util.IsControlPlaneMachine(m) {
+			continue
+		}
+		requests = append(requests, r.machineToKubeadmConfig(handler.MapObject{Meta: m, Object: m})...)
+	}
+	return requests
+}
@@ -0,0 +1,470 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	capierrors "sigs.k8s.io/cluster-api/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestRequeueInitControlPlaneIfInitLockIsHeld(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	holderMachine := newControlPlaneMachine(cluster, "control-plane-machine-1")
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine-2")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg-2")
+
+	objects := []runtime.Object{
+		cluster,
+		holderMachine,
+		controlPlaneMachine,
+		controlPlaneInitConfig,
+	}
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 fake.NewFakeClientWithScheme(setupScheme(), objects...),
+		ControlPlaneInitLocker: &fakeInitLocker{holder: "control-plane-machine-1", acquired: time.Now()},
+	}
+
+	result, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg-2"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	if result.RequeueAfter != 30*time.Second {
+		t.Fatalf("expected to requeue after 30s, got %s", result.RequeueAfter)
+	}
+}
+
+func TestReconcileInitLockHandoff(t *testing.T) {
+	testcases := []struct {
+		name               string
+		phase              capiv1alpha2.MachinePhase
+		deleting           bool
+		bootstrapData      bool
+		nodeRef            *corev1.ObjectReference
+		acquired           time.Duration
+		holder             string
+		expectRelease      bool
+		expectHandoffAfter bool
+	}{
+		{
+			name:          "machine failed",
+			phase:         capiv1alpha2.MachinePhaseFailed,
+			acquired:      time.Minute,
+			holder:        "control-plane-machine",
+			expectRelease: true,
+		},
+		{
+			name:          "machine being deleted",
+			phase:         capiv1alpha2.MachinePhaseProvisioned,
+			deleting:      true,
+			acquired:      time.Minute,
+			holder:        "control-plane-machine",
+			expectRelease: true,
+		},
+		{
+			name:          "init timed out",
+			phase:         capiv1alpha2.MachinePhaseProvisioned,
+			acquired:      DefaultControlPlaneInitTimeout + time.Minute,
+			holder:        "control-plane-machine",
+			expectRelease: true,
+		},
+		{
+			name:          "machine with bootstrap data being deleted",
+			phase:         capiv1alpha2.MachinePhaseProvisioned,
+			deleting:      true,
+			bootstrapData: true,
+			acquired:      time.Minute,
+			holder:        "control-plane-machine",
+			expectRelease: true,
+		},
+		{
+			name:          "init timed out with bootstrap data",
+			phase:         capiv1alpha2.MachinePhaseProvisioned,
+			bootstrapData: true,
+			acquired:      DefaultControlPlaneInitTimeout + time.Minute,
+			holder:        "control-plane-machine",
+		},
+		{
+			name:          "machine with bootstrap data failed",
+			phase:         capiv1alpha2.MachinePhaseFailed,
+			bootstrapData: true,
+			acquired:      time.Minute,
+			holder:        "control-plane-machine",
+			expectRelease: true,
+		},
+		{
+			name:               "init in progress",
+			phase:              capiv1alpha2.MachinePhaseProvisioned,
+			acquired:           time.Minute,
+			holder:             "control-plane-machine",
+			expectHandoffAfter: true,
+		},
+		{
+			name:     "node registered",
+			phase:    capiv1alpha2.MachinePhaseFailed,
+			nodeRef:  &corev1.ObjectReference{Name: "node"},
+			acquired: time.Minute,
+			holder:   "control-plane-machine",
+		},
+		{
+			name:     "lock held by another machine",
+			phase:    capiv1alpha2.MachinePhaseFailed,
+			acquired: time.Minute,
+			holder:   "other-machine",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := newCluster("cluster")
+			machine := newControlPlaneMachine(cluster, "control-plane-machine")
+			machine.Status.Phase = string(tc.phase)
+			machine.Status.NodeRef = tc.nodeRef
+			if tc.bootstrapData {
+				data := "I2Nsb3VkLWNvbmZpZw=="
+				machine.Spec.Bootstrap.Data = &data
+			}
+			if tc.deleting {
+				now := metav1.Now()
+				machine.DeletionTimestamp = &now
+			}
+			config := newControlPlaneInitKubeadmConfig(machine, "control-plane-init-cfg")
+			config.Status.Ready = true
+			config.Status.BootstrapData = []byte("#cloud-config")
+
+			locker := &fakeInitLocker{holder: tc.holder, acquired: time.Now().Add(-tc.acquired), release: true}
+			myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, machine, config)
+			k := &KubeadmConfigReconciler{
+				Log:                    log.Log,
+				Client:                 myclient,
+				ControlPlaneInitLocker: locker,
+				Recorder:               record.NewFakeRecorder(1),
+			}
+
+			handoffAfter, err := k.reconcileInitLockHandoff(context.Background(), config)
+			if err != nil {
+				t.Fatalf("Failed to reconcile init lock handoff:\n %+v", err)
+			}
+			if locker.released != tc.expectRelease {
+				t.Fatalf("expected released %t, got %t", tc.expectRelease, locker.released)
+			}
+			if (handoffAfter > 0) != tc.expectHandoffAfter {
+				t.Errorf("expected a handoff check to be scheduled %t, got %s", tc.expectHandoffAfter, handoffAfter)
+			}
+
+			cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg")
+			if err != nil {
+				t.Fatalf("Failed to get config:\n %+v", err)
+			}
+			if tc.expectRelease && (cfg.Status.Ready || cfg.Status.BootstrapData != nil) {
+				t.Error("expected the bootstrap data of the config to be cleared")
+			}
+			if !tc.expectRelease && !cfg.Status.Ready {
+				t.Error("expected the config to stay ready")
+			}
+		})
+	}
+}
+
+func TestReconcileInitLockHandoffRenewsLease(t *testing.T) {
+	cluster := newCluster("cluster")
+	machine := newControlPlaneMachine(cluster, "control-plane-machine")
+	machine.Status.Phase = string(capiv1alpha2.MachinePhaseProvisioned)
+	config := newControlPlaneInitKubeadmConfig(machine, "control-plane-init-cfg")
+	config.Status.Ready = true
+
+	k := &KubeadmConfigReconciler{
+		Log:                               log.Log,
+		Client:                            fake.NewFakeClientWithScheme(setupScheme(), cluster, machine, config),
+		ControlPlaneInitLocker:            &fakeInitLocker{holder: "control-plane-machine", acquired: time.Now()},
+		ControlPlaneInitLockLeaseDuration: 2 * time.Minute,
+	}
+
+	handoffAfter, err := k.reconcileInitLockHandoff(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Failed to reconcile init lock handoff:\n %+v", err)
+	}
+	if handoffAfter != time.Minute {
+		t.Errorf("expected the lease to be renewed after 1m, got %s", handoffAfter)
+	}
+}
+
+func TestReconcileInitLockHandoffStuckHolderLosesLease(t *testing.T) {
+	cluster := newCluster("cluster")
+	machine := newControlPlaneMachine(cluster, "control-plane-machine-1")
+	machine.Status.Phase = string(capiv1alpha2.MachinePhaseProvisioned)
+	data := "I2Nsb3VkLWNvbmZpZw=="
+	machine.Spec.Bootstrap.Data = &data
+	config := newControlPlaneInitKubeadmConfig(machine, "control-plane-init-cfg-1")
+	config.Status.Ready = true
+
+	// the machine acquired the lease longer than the init timeout ago and never registered its node
+	now := time.Now().Add(-DefaultControlPlaneInitTimeout - time.Minute)
+	locker := newControlPlaneInitLeaseLocker(log.Log, fakeclient.NewSimpleClientset().CoordinationV1(), time.Minute, DefaultControlPlaneInitTimeout)
+	locker.now = func() time.Time { return now }
+	if !locker.Acquire(cluster, machine, config) {
+		t.Fatal("expected control-plane-machine-1 to acquire the lock")
+	}
+	now = time.Now()
+
+	k := &KubeadmConfigReconciler{
+		Log:                               log.Log,
+		Client:                            fake.NewFakeClientWithScheme(setupScheme(), cluster, machine, config),
+		ControlPlaneInitLocker:            locker,
+		ControlPlaneInitLockLeaseDuration: time.Minute,
+	}
+	if _, err := k.reconcileInitLockHandoff(context.Background(), config); err != nil {
+		t.Fatalf("Failed to reconcile init lock handoff:\n %+v", err)
+	}
+
+	other := newControlPlaneMachine(cluster, "control-plane-machine-2")
+	if !locker.Acquire(cluster, other, newControlPlaneInitKubeadmConfig(other, "control-plane-init-cfg-2")) {
+		t.Fatal("expected control-plane-machine-2 to take over the lease of the stuck machine")
+	}
+}
+
+func TestReleaseDeletedInitLockHolder(t *testing.T) {
+	testcases := []struct {
+		name          string
+		holder        string
+		holderExists  bool
+		deleting      bool
+		expectRelease bool
+	}{
+		{
+			name: "lock not held",
+		},
+		{
+			name:         "holder exists",
+			holder:       "control-plane-machine-1",
+			holderExists: true,
+		},
+		{
+			name:          "holder being deleted",
+			holder:        "control-plane-machine-1",
+			holderExists:  true,
+			deleting:      true,
+			expectRelease: true,
+		},
+		{
+			name:          "holder deleted",
+			holder:        "control-plane-machine-1",
+			expectRelease: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cluster := newCluster("cluster")
+			objects := []runtime.Object{cluster}
+			if tc.holderExists {
+				holder := newControlPlaneMachine(cluster, tc.holder)
+				if tc.deleting {
+					now := metav1.Now()
+					holder.DeletionTimestamp = &now
+				}
+				objects = append(objects, holder)
+			}
+
+			locker := &fakeInitLocker{holder: tc.holder, acquired: time.Now(), release: true}
+			recorder := record.NewFakeRecorder(1)
+			k := &KubeadmConfigReconciler{
+				Log:                    log.Log,
+				Client:                 fake.NewFakeClientWithScheme(setupScheme(), objects...),
+				ControlPlaneInitLocker: locker,
+				Recorder:               recorder,
+			}
+
+			released, err := k.releaseDeletedInitLockHolder(context.Background(), cluster)
+			if err != nil {
+				t.Fatalf("Failed to release the init lock of a deleted machine:\n %+v", err)
+			}
+			if released != tc.expectRelease || locker.released != tc.expectRelease {
+				t.Fatalf("expected released %t, got %t", tc.expectRelease, released)
+			}
+			if tc.expectRelease && len(recorder.Events) != 1 {
+				t.Error("expected an event for the release")
+			}
+		})
+	}
+}
+
+func TestReconcileTakesOverInitLockOfDeletedMachine(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine-2")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg-2")
+
+	locker := &fakeInitLocker{holder: "control-plane-machine-1", acquired: time.Now(), release: true}
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 fake.NewFakeClientWithScheme(setupScheme(), cluster, controlPlaneMachine, controlPlaneInitConfig),
+		ControlPlaneInitLocker: locker,
+		Recorder:               record.NewFakeRecorder(10),
+	}
+
+	if _, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg-2"},
+	}); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	if locker.holder != "control-plane-machine-2" {
+		t.Errorf("expected control-plane-machine-2 to take over the init lock, got %q", locker.holder)
+	}
+}
+
+func TestReconcileTakesOverInitLockOfFailedMachine(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	failedMachine := newControlPlaneMachine(cluster, "control-plane-machine-1")
+	failedMachine.Status.Phase = string(capiv1alpha2.MachinePhaseFailed)
+	failedInitConfig := newControlPlaneInitKubeadmConfig(failedMachine, "control-plane-init-cfg-1")
+	failedInitConfig.Status.Ready = true
+	failedInitConfig.Status.BootstrapData = []byte("#cloud-config")
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine-2")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg-2")
+
+	locker := &fakeInitLocker{holder: "control-plane-machine-1", acquired: time.Now(), release: true}
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, failedMachine, failedInitConfig, controlPlaneMachine, controlPlaneInitConfig)
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: locker,
+		Recorder:               record.NewFakeRecorder(10),
+	}
+
+	// the config of the failed machine is reconciled twice, handing off the lock and then not acquiring it again
+	for i := 0; i < 2; i++ {
+		if _, err := k.Reconcile(ctrl.Request{
+			NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg-1"},
+		}); err != nil {
+			t.Fatalf("Failed to reconcile:\n %+v", err)
+		}
+		if locker.holder != "" {
+			t.Fatalf("expected the failed machine to hand off the init lock, got %q holding it", locker.holder)
+		}
+	}
+
+	if _, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg-2"},
+	}); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	if locker.holder != "control-plane-machine-2" {
+		t.Fatalf("expected control-plane-machine-2 to take over the init lock, got %q", locker.holder)
+	}
+	cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg-2")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	if !cfg.Status.Ready || cfg.Status.BootstrapData == nil {
+		t.Error("expected control-plane-machine-2 to get the bootstrap data initializing the cluster")
+	}
+	failedCfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg-1")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	if failedCfg.Status.Ready || failedCfg.Status.BootstrapData != nil {
+		t.Error("expected the bootstrap data of the failed machine to be cleared")
+	}
+}
+
+func TestAcquireInitLockRefusesFailedAndDeletedMachines(t *testing.T) {
+	cluster := newCluster("cluster")
+	failed := newControlPlaneMachine(cluster, "failed-machine")
+	reason := capierrors.CreateMachineError
+	failed.Status.ErrorReason = &reason
+	deleted := newControlPlaneMachine(cluster, "deleted-machine")
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+
+	for _, machine := range []*capiv1alpha2.Machine{failed, deleted} {
+		locker := &fakeInitLocker{}
+		k := &KubeadmConfigReconciler{Log: log.Log, ControlPlaneInitLocker: locker}
+		if k.acquireInitLock(cluster, machine, newControlPlaneInitKubeadmConfig(machine, "cfg")) || locker.holder != "" {
+			t.Errorf("expected %s not to acquire the init lock, got %q holding it", machine.Name, locker.holder)
+		}
+	}
+}
+
+func TestDeletedMachineToKubeadmConfigs(t *testing.T) {
+	cluster := newCluster("cluster")
+	deleted := newControlPlaneMachine(cluster, "control-plane-machine-1")
+	deleted.Spec.Bootstrap.ConfigRef.Name = "control-plane-cfg-1"
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	controlPlane := newControlPlaneMachine(cluster, "control-plane-machine-2")
+	controlPlane.Spec.Bootstrap.ConfigRef.Name = "control-plane-cfg-2"
+	worker := newWorkerMachine(cluster, "worker-machine")
+	worker.Spec.Bootstrap.ConfigRef.Name = "worker-cfg"
+	other := newControlPlaneMachine(newCluster("other"), "other-control-plane-machine")
+	other.Spec.Bootstrap.ConfigRef.Name = "other-cfg"
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), deleted, controlPlane, worker, other),
+	}
+
+	requests := k.deletedMachineToKubeadmConfigs(handler.MapObject{Meta: deleted, Object: deleted})
+	if len(requests) != 1 || requests[0].Name != "control-plane-cfg-2" {
+		t.Errorf("expected a request for default/control-plane-cfg-2, got %v", requests)
+	}
+
+	if requests := k.deletedMachineToKubeadmConfigs(handler.MapObject{Meta: controlPlane, Object: controlPlane}); len(requests) != 0 {
+		t.Errorf("expected no request for a machine not being deleted, got %v", requests)
+	}
+}
+
+func TestMachineToKubeadmConfig(t *testing.T) {
+	machine := newControlPlaneMachine(newCluster("cluster"), "machine")
+	machine.Spec.Bootstrap.ConfigRef = &corev1.ObjectReference{
+		APIVersion: "bootstrap.cluster.x-k8s.io/v1alpha2",
+		Kind:       "KubeadmConfig",
+		Name:       "machine-config",
+	}
+
+	k := &KubeadmConfigReconciler{}
+	requests := k.machineToKubeadmConfig(handler.MapObject{Meta: machine, Object: machine})
+	if len(requests) != 1 || requests[0].Name != "machine-config" || requests[0].Namespace != "default" {
+		t.Errorf("expected a request for default/machine-config, got %v", requests)
+	}
+
+	machine.Spec.Bootstrap.ConfigRef.Kind = "OtherConfig"
+	if requests := k.machineToKubeadmConfig(handler.MapObject{Meta: machine, Object: machine}); len(requests) != 0 {
+		t.Errorf("expected no request for another bootstrap provider, got %v", requests)
+	}
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	clusterv2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+// controlPlaneInitLeaseLocker uses a Lease to synchronize cluster initialization. Unlike the ConfigMap lock, the
+// lease expires if its holder does not renew it, by acquiring it again, within the lease duration before the
+// cluster is initialized, so that a stuck or deleted holder loses the lock to the next control plane machine. The
+// holder can't renew the lease past the init timeout, so that a holder that is still there but stuck loses it too.
+type controlPlaneInitLeaseLocker struct {
+	log           logr.Logger
+	leaseClient   coordinationv1client.LeasesGetter
+	leaseDuration time.Duration
+	initTimeout   time.Duration
+	// now returns the current time, overridden in tests.
+	now func() time.Time
+}
+
+var _ ControlPlaneInitLocker = &controlPlaneInitLeaseLocker{}
+
+func newControlPlaneInitLeaseLocker(log logr.Logger, leaseClient coordinationv1client.LeasesGetter, leaseDuration, initTimeout time.Duration) *controlPlaneInitLeaseLocker {
+	return &controlPlaneInitLeaseLocker{
+		log:           log,
+		leaseClient:   leaseClient,
+		leaseDuration: leaseDuration,
+		initTimeout:   initTimeout,
+		now:           time.Now,
+	}
+}
+
+func (l *controlPlaneInitLeaseLocker) Acquire(cluster *clusterv2.Cluster, machine *clusterv2.Machine, config *cabpkv1alpha2.KubeadmConfig) bool {
+	leaseName := fmt.Sprintf("%s-controlplane", cluster.UID)
+	log := l.log.WithValues("namespace", cluster.Namespace, "cluster-name", cluster.Name, "lease-name", leaseName)
+
+	existing, err := l.getLease(cluster.Namespace, leaseName)
+	if err != nil {
+		log.Error(err, "Error checking for control plane lease lock existence")
+		return false
+	}
+
+	now := metav1.NewMicroTime(l.now())
+	if existing == nil {
+		durationSeconds := int32(l.leaseDuration / time.Second)
+		lease := &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cluster.Namespace,
+				Name:      leaseName,
+				Annotations: map[string]string{
+					controlPlaneInitLockConfigUIDKey: string(config.UID),
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: cluster.APIVersion,
+						Kind:       cluster.Kind,
+						Name:       cluster.Name,
+						UID:        cluster.UID,
+					},
+				},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &machine.Name,
+				LeaseDurationSeconds: &durationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+
+		log.Info("Attempting to create control plane lease lock", "machine", machine.Name)
+		if _, err := l.leaseClient.Leases(cluster.Namespace).Create(lease); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				log.Info("Control plane lease lock already exists")
+			} else {
+				log.Error(err, "Error creating control plane lease lock")
+			}
+			return false
+		}
+		return true
+	}
+
+	lease := existing.DeepCopy()
+	switch {
+	case isControlPlaneInitLeaseHolder(lease, machine, config):
+		if l.initTimedOut(lease) {
+			log.Info("Not renewing the control plane lease lock past the init timeout", "machine", machine.Name)
+			return false
+		}
+		lease.Spec.RenewTime = &now
+	case l.expired(lease):
+		log.Info("Taking over expired control plane lease lock", "machine", machine.Name, "holder", leaseHolder(lease))
+		transitions := int32(1)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions + 1
+		}
+		if lease.Annotations == nil {
+			lease.Annotations = map[string]string{}
+		}
+		lease.Annotations[controlPlaneInitLockConfigUIDKey] = string(config.UID)
+		lease.Spec.HolderIdentity = &machine.Name
+		lease.Spec.AcquireTime = &now
+		lease.Spec.RenewTime = &now
+		lease.Spec.LeaseTransitions = &transitions
+	default:
+		log.Info("Control plane lease lock is held by another machine", "machine", machine.Name, "holder", leaseHolder(lease))
+		return false
+	}
+
+	// the update fails on a conflict if another machine renewed or took over the lease in the meantime
+	if _, err := l.leaseClient.Leases(cluster.Namespace).Update(lease); err != nil {
+		log.Error(err, "Error updating control plane lease lock")
+		return false
+	}
+	return true
+}
+
+func (l *controlPlaneInitLeaseLocker) Release(cluster *clusterv2.Cluster) bool {
+	leaseName := fmt.Sprintf("%s-controlplane", cluster.UID)
+	log := l.log.WithValues("namespace", cluster.Namespace, "cluster-name", cluster.Name, "lease-name", leaseName)
+
+	err := l.leaseClient.Leases(cluster.Namespace).Delete(leaseName, nil)
+	switch {
+	case apierrors.IsNotFound(err):
+		log.Info("Control plane lease lock not found, it may have been released already")
+	case err != nil:
+		log.Error(err, "Error deleting control plane lease lock")
+		return false
+	}
+	return true
+}
+
+func (l *controlPlaneInitLeaseLocker) Holder(cluster *clusterv2.Cluster) (string, time.Time, error) {
+	lease, err := l.getLease(cluster.Namespace, fmt.Sprintf("%s-controlplane", cluster.UID))
+	if err != nil || lease == nil {
+		return "", time.Time{}, err
+	}
+	acquired := lease.CreationTimestamp.Time
+	if lease.Spec.AcquireTime != nil {
+		acquired = lease.Spec.AcquireTime.Time
+	}
+	return leaseHolder(lease), acquired, nil
+}
+
+func (l *controlPlaneInitLeaseLocker) MarkInitialized(cluster *clusterv2.Cluster) error {
+	leaseName := fmt.Sprintf("%s-controlplane", cluster.UID)
+	lease, err := l.getLease(cluster.Namespace, leaseName)
+	if err != nil {
+		return err
+	}
+	if lease == nil {
+		return errors.Errorf("control plane lease lock %s/%s not found", cluster.Namespace, leaseName)
+	}
+	if lease.Annotations[controlPlaneInitLockInitializedKey] == "true" {
+		return nil
+	}
+
+	if lease.Annotations == nil {
+		lease.Annotations = map[string]string{}
+	}
+	lease.Annotations[controlPlaneInitLockInitializedKey] = "true"
+	_, err = l.leaseClient.Leases(cluster.Namespace).Update(lease)
+	return err
+}
+
+func (l *controlPlaneInitLeaseLocker) Initialized(cluster *clusterv2.Cluster) (bool, error) {
+	lease, err := l.getLease(cluster.Namespace, fmt.Sprintf("%s-controlplane", cluster.UID))
+	if err != nil || lease == nil {
+		return false, err
+	}
+	return lease.Annotations[controlPlaneInitLockInitializedKey] == "true", nil
+}
+
+// expired returns true if the holder of the lease did not renew it within its duration, and did not initialize
+// the cluster.
+func (l *controlPlaneInitLeaseLocker) expired(lease *coordinationv1.Lease) bool {
+	if lease.Annotations[controlPlaneInitLockInitializedKey] == "true" {
+		return false
+	}
+	renewed := lease.CreationTimestamp.Time
+	if lease.Spec.RenewTime != nil {
+		renewed = lease.Spec.RenewTime.Time
+	}
+	duration := l.leaseDuration
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	return l.now().After(renewed.Add(duration))
+}
+
+// initTimedOut returns true if the holder of the lease acquired it longer than the init timeout ago, and did not
+// initialize the cluster.
+func (l *controlPlaneInitLeaseLocker) initTimedOut(lease *coordinationv1.Lease) bool {
+	if l.initTimeout <= 0 || lease.Annotations[controlPlaneInitLockInitializedKey] == "true" {
+		return false
+	}
+	acquired := lease.CreationTimestamp.Time
+	if lease.Spec.AcquireTime != nil {
+		acquired = lease.Spec.AcquireTime.Time
+	}
+	return !l.now().Before(acquired.Add(l.initTimeout))
+}
+
+// getLease returns the Lease, or nil if it does not exist.
+func (l *controlPlaneInitLeaseLocker) getLease(namespace, name string) (*coordinationv1.Lease, error) {
+	lease, err := l.leaseClient.Leases(namespace).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// isControlPlaneInitLeaseHolder returns true if the lease is held by the machine and its KubeadmConfig.
+func isControlPlaneInitLeaseHolder(lease *coordinationv1.Lease, machine *clusterv2.Machine, config *cabpkv1alpha2.KubeadmConfig) bool {
+	return leaseHolder(lease) == machine.Name && lease.Annotations[controlPlaneInitLockConfigUIDKey] == string(config.UID)
+}
+
+// leaseHolder returns the name of the machine holding the lease.
+func leaseHolder(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+	clusterv2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func newLeaseLockerCluster() *clusterv2.Cluster {
+	return &clusterv2.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1",
+			Name:      "name1",
+			UID:       types.UID("uid1"),
+		},
+	}
+}
+
+func TestControlPlaneInitLeaseLockerAcquire(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	clientset := fakeclient.NewSimpleClientset()
+	l := newControlPlaneInitLeaseLocker(log.Log, clientset.CoordinationV1(), time.Minute, 0)
+	l.now = func() time.Time { return now }
+	cluster := newLeaseLockerCluster()
+	machine1 := &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine1"}}
+	machine2 := &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine2"}}
+
+	if !l.Acquire(cluster, machine1, newLockHolderConfig("cfg-uid1")) {
+		t.Fatal("expected machine1 to acquire the lock")
+	}
+	lease, err := clientset.CoordinationV1().Leases("ns1").Get("uid1-controlplane", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the lease to be created, got %v", err)
+	}
+	if leaseHolder(lease) != "machine1" || lease.Annotations[controlPlaneInitLockConfigUIDKey] != "cfg-uid1" {
+		t.Errorf("expected the lease to be held by machine1 and cfg-uid1, got %q and %v", leaseHolder(lease), lease.Annotations)
+	}
+	if lease.Spec.LeaseDurationSeconds == nil || *lease.Spec.LeaseDurationSeconds != 60 {
+		t.Errorf("expected a lease duration of 60s, got %v", lease.Spec.LeaseDurationSeconds)
+	}
+
+	holder, acquired, err := l.Holder(cluster)
+	if err != nil || holder != "machine1" || !acquired.Equal(now) {
+		t.Errorf("expected machine1 to hold the lock since %s, got %q since %s, %v", now, holder, acquired, err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if l.Acquire(cluster, machine2, newLockHolderConfig("cfg-uid2")) {
+		t.Error("expected machine2 not to acquire a lease that did not expire")
+	}
+	if l.Acquire(cluster, machine1, newLockHolderConfig("cfg-uid2")) {
+		t.Error("expected another config of machine1 not to acquire the lease")
+	}
+	if !l.Acquire(cluster, machine1, newLockHolderConfig("cfg-uid1")) {
+		t.Fatal("expected the holder to renew the lease")
+	}
+
+	// the lease was renewed, so it expires a minute after the renewal
+	now = now.Add(45 * time.Second)
+	if l.Acquire(cluster, machine2, newLockHolderConfig("cfg-uid2")) {
+		t.Error("expected machine2 not to acquire a renewed lease")
+	}
+
+	now = now.Add(30 * time.Second)
+	if !l.Acquire(cluster, machine2, newLockHolderConfig("cfg-uid2")) {
+		t.Fatal("expected machine2 to take over the expired lease")
+	}
+	lease, err = clientset.CoordinationV1().Leases("ns1").Get("uid1-controlplane", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get the lease: %v", err)
+	}
+	if leaseHolder(lease) != "machine2" || lease.Annotations[controlPlaneInitLockConfigUIDKey] != "cfg-uid2" {
+		t.Errorf("expected the lease to be held by machine2 and cfg-uid2, got %q and %v", leaseHolder(lease), lease.Annotations)
+	}
+	if lease.Spec.LeaseTransitions == nil || *lease.Spec.LeaseTransitions != 1 {
+		t.Errorf("expected a lease transition, got %v", lease.Spec.LeaseTransitions)
+	}
+	if l.Acquire(cluster, machine1, newLockHolderConfig("cfg-uid1")) {
+		t.Error("expected machine1 to have lost the lease")
+	}
+}
+
+func TestControlPlaneInitLeaseLockerNotRenewedPastInitTimeout(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	clientset := fakeclient.NewSimpleClientset()
+	l := newControlPlaneInitLeaseLocker(log.Log, clientset.CoordinationV1(), time.Minute, 10*time.Minute)
+	l.now = func() time.Time { return now }
+	cluster := newLeaseLockerCluster()
+	machine1 := &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine1"}}
+	machine2 := &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine2"}}
+
+	if !l.Acquire(cluster, machine1, newLockHolderConfig("cfg-uid1")) {
+		t.Fatal("expected machine1 to acquire the lock")
+	}
+	// the holder keeps renewing the lease, but is stuck and does not initialize the cluster
+	for i := 0; i < 9; i++ {
+		now = now.Add(time.Minute)
+		if !l.Acquire(cluster, machine1, newLockHolderConfig("cfg-uid1")) {
+			t.Fatalf("expected the holder to renew the lease within the init timeout, after %d minutes", i+1)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if l.Acquire(cluster, machine1, newLockHolderConfig("cfg-uid1")) {
+		t.Fatal("expected the holder not to renew the lease past the init timeout")
+	}
+	if l.Acquire(cluster, machine2, newLockHolderConfig("cfg-uid2")) {
+		t.Error("expected machine2 not to acquire the lease before it expires")
+	}
+	now = now.Add(time.Minute + time.Second)
+	if !l.Acquire(cluster, machine2, newLockHolderConfig("cfg-uid2")) {
+		t.Fatal("expected machine2 to take over the lease of the stuck holder")
+	}
+	if !l.Acquire(cluster, machine2, newLockHolderConfig("cfg-uid2")) {
+		t.Error("expected machine2 to renew the lease it just acquired")
+	}
+}
+
+func TestControlPlaneInitLeaseLockerInitialized(t *testing.T) {
+	now := time.Now()
+	clientset := fakeclient.NewSimpleClientset()
+	l := newControlPlaneInitLeaseLocker(log.Log, clientset.CoordinationV1(), time.Minute, 0)
+	l.now = func() time.Time { return now }
+	cluster := newLeaseLockerCluster()
+
+	if err := l.MarkInitialized(cluster); err == nil {
+		t.Error("expected an error without a lock")
+	}
+	if !l.Acquire(cluster, &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine1"}}, newLockHolderConfig("cfg-uid1")) {
+		t.Fatal("expected machine1 to acquire the lock")
+	}
+	initialized, err := l.Initialized(cluster)
+	if err != nil || initialized {
+		t.Fatalf("expected the cluster not to be initialized, got %t, %v", initialized, err)
+	}
+	if err := l.MarkInitialized(cluster); err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	initialized, err = l.Initialized(cluster)
+	if err != nil || !initialized {
+		t.Fatalf("expected the cluster to be initialized, got %t, %v", initialized, err)
+	}
+
+	now = now.Add(time.Hour)
+	if l.Acquire(cluster, &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine2"}}, newLockHolderConfig("cfg-uid2")) {
+		t.Error("expected the lease of an initialized cluster not to expire")
+	}
+}
+
+func TestControlPlaneInitLeaseLockerRelease(t *testing.T) {
+	clientset := fakeclient.NewSimpleClientset()
+	l := newControlPlaneInitLeaseLocker(log.Log, clientset.CoordinationV1(), time.Minute, 0)
+	cluster := newLeaseLockerCluster()
+
+	if !l.Release(cluster) {
+		t.Error("expected a lock that is not held to be released")
+	}
+	if !l.Acquire(cluster, &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine1"}}, newLockHolderConfig("cfg-uid1")) {
+		t.Fatal("expected machine1 to acquire the lock")
+	}
+	if !l.Release(cluster) {
+		t.Fatal("expected the lock to be released")
+	}
+	if holder, _, err := l.Holder(cluster); err != nil || holder != "" {
+		t.Errorf("expected the lock not to be held, got %q, %v", holder, err)
+	}
+	if !l.Acquire(cluster, &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine2"}}, newLockHolderConfig("cfg-uid2")) {
+		t.Error("expected machine2 to acquire the released lock")
+	}
+}
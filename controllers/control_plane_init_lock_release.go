@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ReleaseInitLockAnnotationKey forcibly releases the control plane init lock of a cluster when set on the
+	// Cluster, so that another control plane machine can initialize it. Its value is recorded, together with the
+	// release, in an event on the Cluster, and the annotation is removed once the lock is released.
+	ReleaseInitLockAnnotationKey = "bootstrap.cluster.x-k8s.io/release-init-lock"
+)
+
+// reconcileInitLockRelease releases the control plane init lock of the cluster when requested by the
+// ReleaseInitLockAnnotationKey.
+func (r *KubeadmConfigReconciler) reconcileInitLockRelease(ctx context.Context, cluster *capiv1alpha2.Cluster) error {
+	reason, ok := cluster.GetAnnotations()[ReleaseInitLockAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if r.ControlPlaneInitLocker == nil {
+		return errors.New("no control plane init locker is configured")
+	}
+
+	if !r.ControlPlaneInitLocker.Release(cluster) {
+		return errors.Errorf("failed to release the control plane init lock of cluster %s/%s", cluster.GetNamespace(), cluster.GetName())
+	}
+	r.Log.Info("Force-released the control plane init lock", "cluster", cluster.GetName(), "namespace", cluster.GetNamespace(), "reason", reason)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(cluster, corev1.EventTypeWarning, "InitLockReleased",
+			"Control plane init lock force-released by annotation %s: %q", ReleaseInitLockAnnotationKey, reason)
+	}
+
+	patchCluster := client.MergeFrom(cluster.DeepCopy())
+	delete(cluster.Annotations, ReleaseInitLockAnnotationKey)
+	if err := r.Patch(ctx, cluster, patchCluster); err != nil {
+		return errors.Wrap(err, "failed to remove the init lock release annotation")
+	}
+	return nil
+}
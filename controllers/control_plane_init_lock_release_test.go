@@ -0,0 +1,139 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	clusterv2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+type fakeInitLocker struct {
+	holder      string
+	acquired    time.Time
+	initialized bool
+	released    bool
+	release     bool
+}
+
+func (l *fakeInitLocker) Acquire(_ *clusterv2.Cluster, machine *clusterv2.Machine, _ *cabpkv1alpha2.KubeadmConfig) bool {
+	if l.holder == "" {
+		l.holder = machine.Name
+		l.acquired = time.Now()
+	}
+	return l.holder == machine.Name
+}
+
+func (l *fakeInitLocker) Release(*clusterv2.Cluster) bool {
+	l.released = true
+	if l.release {
+		l.holder = ""
+		l.initialized = false
+	}
+	return l.release
+}
+
+func (l *fakeInitLocker) Holder(*clusterv2.Cluster) (string, time.Time, error) {
+	return l.holder, l.acquired, nil
+}
+
+func (l *fakeInitLocker) MarkInitialized(*clusterv2.Cluster) error {
+	l.initialized = true
+	return nil
+}
+
+func (l *fakeInitLocker) Initialized(*clusterv2.Cluster) (bool, error) {
+	return l.initialized, nil
+}
+
+func TestReconcileInitLockRelease(t *testing.T) {
+	cluster := newCluster("wedged-cluster")
+	cluster.Annotations = map[string]string{ReleaseInitLockAnnotationKey: "first control plane never came up"}
+
+	locker := &fakeInitLocker{release: true}
+	recorder := record.NewFakeRecorder(1)
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 fake.NewFakeClientWithScheme(setupScheme(), cluster.DeepCopy()),
+		ControlPlaneInitLocker: locker,
+		Recorder:               recorder,
+	}
+
+	if err := k.reconcileInitLockRelease(context.Background(), cluster); err != nil {
+		t.Fatalf("Failed to release the init lock:\n %+v", err)
+	}
+	if !locker.released {
+		t.Fatal("expected the init lock to be released")
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "InitLockReleased") || !strings.Contains(event, "first control plane never came up") {
+			t.Errorf("expected an InitLockReleased event with the reason, got %q", event)
+		}
+	default:
+		t.Error("expected an event to be recorded")
+	}
+
+	stored := &clusterv2.Cluster{}
+	if err := k.Get(context.Background(), types.NamespacedName{Name: cluster.GetName(), Namespace: cluster.GetNamespace()}, stored); err != nil {
+		t.Fatalf("Failed to get cluster:\n %+v", err)
+	}
+	if _, ok := stored.Annotations[ReleaseInitLockAnnotationKey]; ok {
+		t.Error("expected the release annotation to be removed")
+	}
+}
+
+func TestReconcileInitLockReleaseFails(t *testing.T) {
+	cluster := newCluster("wedged-cluster")
+	cluster.Annotations = map[string]string{ReleaseInitLockAnnotationKey: ""}
+
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 fake.NewFakeClientWithScheme(setupScheme(), cluster.DeepCopy()),
+		ControlPlaneInitLocker: &fakeInitLocker{release: false},
+		Recorder:               record.NewFakeRecorder(1),
+	}
+	if err := k.reconcileInitLockRelease(context.Background(), cluster); err == nil {
+		t.Fatal("expected an error when the lock cannot be released")
+	}
+	if _, ok := cluster.Annotations[ReleaseInitLockAnnotationKey]; !ok {
+		t.Error("expected the release annotation to be kept for a retry")
+	}
+}
+
+func TestReconcileInitLockReleaseWithoutAnnotation(t *testing.T) {
+	locker := &fakeInitLocker{release: true}
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		ControlPlaneInitLocker: locker,
+	}
+	if err := k.reconcileInitLockRelease(context.Background(), newCluster("cluster")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if locker.released {
+		t.Error("expected the init lock to be left alone")
+	}
+}
@@ -18,19 +18,45 @@ package controllers
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
 	apicorev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
 	clusterv2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
 )
 
-// ControlPlaneInitLocker provides a locking mechanism for cluster initialization.
+const (
+	// controlPlaneInitLockMachineKey is the key of the lock ConfigMap holding the name of the machine it was
+	// acquired for.
+	controlPlaneInitLockMachineKey = "machine"
+	// controlPlaneInitLockConfigUIDKey is the key of the lock ConfigMap holding the UID of the KubeadmConfig it was
+	// acquired for.
+	controlPlaneInitLockConfigUIDKey = "kubeadmconfig-uid"
+	// controlPlaneInitLockInitializedKey is the key of the lock ConfigMap set once the holder initialized the
+	// cluster.
+	controlPlaneInitLockInitializedKey = "initialized"
+)
+
+// ControlPlaneInitLocker provides a locking mechanism for cluster initialization. There is a single lock per
+// cluster, shared by all its control plane machines whatever pool or MachineDeployment they belong to.
 type ControlPlaneInitLocker interface {
-	// Acquire returns true if it acquires the lock for the cluster.
-	Acquire(cluster *clusterv2.Cluster) bool
+	// Acquire returns true if it acquires the lock for the cluster on behalf of the machine and its KubeadmConfig,
+	// or they already hold it.
+	Acquire(cluster *clusterv2.Cluster, machine *clusterv2.Machine, config *cabpkv1alpha2.KubeadmConfig) bool
+	// Release returns true if the lock for the cluster is released, or was not held.
+	Release(cluster *clusterv2.Cluster) bool
+	// Holder returns the name of the machine holding the lock for the cluster and when it acquired it, or an
+	// empty name if the lock is not held.
+	Holder(cluster *clusterv2.Cluster) (string, time.Time, error)
+	// MarkInitialized records that the holder of the lock for the cluster initialized it.
+	MarkInitialized(cluster *clusterv2.Cluster) error
+	// Initialized returns true if the holder of the lock for the cluster initialized it.
+	Initialized(cluster *clusterv2.Cluster) (bool, error)
 }
 
 // controlPlaneInitLocker uses a ConfigMap to synchronize cluster initialization.
@@ -48,17 +74,22 @@ func newControlPlaneInitLocker(log logr.Logger, configMapClient corev1.ConfigMap
 	}
 }
 
-func (l *controlPlaneInitLocker) Acquire(cluster *clusterv2.Cluster) bool {
+func (l *controlPlaneInitLocker) Acquire(cluster *clusterv2.Cluster, machine *clusterv2.Machine, config *cabpkv1alpha2.KubeadmConfig) bool {
 	configMapName := fmt.Sprintf("%s-controlplane", cluster.UID)
 	log := l.log.WithValues("namespace", cluster.Namespace, "cluster-name", cluster.Name, "configmap-name", configMapName)
 
-	exists, err := l.configMapExists(cluster.Namespace, configMapName)
+	existing, err := l.getConfigMap(cluster.Namespace, configMapName)
 	if err != nil {
 		log.Error(err, "Error checking for control plane configmap lock existence")
 		return false
 	}
-	if exists {
-		return false
+	if existing != nil {
+		if !isControlPlaneInitLockHolder(existing, machine, config) {
+			log.Info("Control plane configmap lock is held by another machine", "machine", machine.Name,
+				"holder", existing.Data[controlPlaneInitLockMachineKey])
+			return false
+		}
+		return true
 	}
 
 	controlPlaneConfigMap := &apicorev1.ConfigMap{
@@ -74,9 +105,13 @@ func (l *controlPlaneInitLocker) Acquire(cluster *clusterv2.Cluster) bool {
 				},
 			},
 		},
+		Data: map[string]string{
+			controlPlaneInitLockMachineKey:   machine.Name,
+			controlPlaneInitLockConfigUIDKey: string(config.UID),
+		},
 	}
 
-	log.Info("Attempting to create control plane configmap lock")
+	log.Info("Attempting to create control plane configmap lock", "machine", machine.Name)
 	_, err = l.configMapClient.ConfigMaps(cluster.Namespace).Create(controlPlaneConfigMap)
 	if err != nil {
 		if apierrors.IsAlreadyExists(err) {
@@ -116,11 +151,61 @@ func (l *controlPlaneInitLocker) Release(cluster *clusterv2.Cluster) bool {
 	return true
 }
 
-func (l *controlPlaneInitLocker) configMapExists(namespace, name string) (bool, error) {
-	_, err := l.configMapClient.ConfigMaps(namespace).Get(name, metav1.GetOptions{})
-	if apierrors.IsNotFound(err) {
-		return false, nil
+func (l *controlPlaneInitLocker) Holder(cluster *clusterv2.Cluster) (string, time.Time, error) {
+	configMap, err := l.getConfigMap(cluster.Namespace, fmt.Sprintf("%s-controlplane", cluster.UID))
+	if err != nil || configMap == nil {
+		return "", time.Time{}, err
+	}
+	return configMap.Data[controlPlaneInitLockMachineKey], configMap.CreationTimestamp.Time, nil
+}
+
+func (l *controlPlaneInitLocker) MarkInitialized(cluster *clusterv2.Cluster) error {
+	configMapName := fmt.Sprintf("%s-controlplane", cluster.UID)
+	configMap, err := l.getConfigMap(cluster.Namespace, configMapName)
+	if err != nil {
+		return err
+	}
+	if configMap == nil {
+		return errors.Errorf("control plane configmap lock %s/%s not found", cluster.Namespace, configMapName)
+	}
+	if configMap.Data[controlPlaneInitLockInitializedKey] == "true" {
+		return nil
 	}
 
-	return err == nil, err
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[controlPlaneInitLockInitializedKey] = "true"
+	_, err = l.configMapClient.ConfigMaps(cluster.Namespace).Update(configMap)
+	return err
+}
+
+func (l *controlPlaneInitLocker) Initialized(cluster *clusterv2.Cluster) (bool, error) {
+	configMap, err := l.getConfigMap(cluster.Namespace, fmt.Sprintf("%s-controlplane", cluster.UID))
+	if err != nil || configMap == nil {
+		return false, err
+	}
+	return configMap.Data[controlPlaneInitLockInitializedKey] == "true", nil
+}
+
+// isControlPlaneInitLockHolder returns true if the lock ConfigMap was acquired for the machine and its
+// KubeadmConfig. Locks acquired before the KubeadmConfig UID was recorded only identify the machine.
+func isControlPlaneInitLockHolder(configMap *apicorev1.ConfigMap, machine *clusterv2.Machine, config *cabpkv1alpha2.KubeadmConfig) bool {
+	if configMap.Data[controlPlaneInitLockMachineKey] != machine.Name {
+		return false
+	}
+	uid, ok := configMap.Data[controlPlaneInitLockConfigUIDKey]
+	return !ok || uid == string(config.UID)
+}
+
+// getConfigMap returns the ConfigMap, or nil if it does not exist.
+func (l *controlPlaneInitLocker) getConfigMap(namespace, name string) (*apicorev1.ConfigMap, error) {
+	configMap, err := l.configMapClient.ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return configMap, nil
 }
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
@@ -27,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
 	clusterv2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
 )
@@ -44,11 +46,12 @@ func TestControlPlaneInitLockerAcquire(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
+			configMapClient := &configMapsGetter{
+				getError: tc.getError,
+			}
 			l := &controlPlaneInitLocker{
-				log: log.Log,
-				configMapClient: &configMapsGetter{
-					getError: tc.getError,
-				},
+				log:             log.Log,
+				configMapClient: configMapClient,
 			}
 
 			cluster := &clusterv2.Cluster{
@@ -59,10 +62,17 @@ func TestControlPlaneInitLockerAcquire(t *testing.T) {
 				},
 			}
 
-			acquired := l.Acquire(cluster)
+			acquired := l.Acquire(cluster, &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine1"}}, newLockHolderConfig("cfg-uid1"))
 			if !acquired {
 				t.Fatal("acquired was false but it should have been true")
 			}
+			created := configMapClient.created
+			if created == nil {
+				t.Fatal("expected the lock configmap to be created")
+			}
+			if created.Data[controlPlaneInitLockMachineKey] != "machine1" || created.Data[controlPlaneInitLockConfigUIDKey] != "cfg-uid1" {
+				t.Errorf("expected the lock to record machine1 and cfg-uid1, got %v", created.Data)
+			}
 		})
 	}
 }
@@ -112,7 +122,7 @@ func TestControlPlaneInitLockerAcquireErrors(t *testing.T) {
 				},
 			}
 
-			acquired := l.Acquire(cluster)
+			acquired := l.Acquire(cluster, &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: "machine1"}}, newLockHolderConfig("cfg-uid1"))
 			if acquired {
 				t.Fatal("expected acquired to be false but it is true")
 			}
@@ -120,6 +130,167 @@ func TestControlPlaneInitLockerAcquireErrors(t *testing.T) {
 	}
 }
 
+func TestControlPlaneInitLockerAcquireByHolder(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          map[string]string
+		machine       string
+		configUID     string
+		expectAcquire bool
+	}{
+		{
+			name:          "holder",
+			data:          map[string]string{controlPlaneInitLockMachineKey: "machine1", controlPlaneInitLockConfigUIDKey: "cfg-uid1"},
+			machine:       "machine1",
+			configUID:     "cfg-uid1",
+			expectAcquire: true,
+		},
+		{
+			name:      "another machine",
+			data:      map[string]string{controlPlaneInitLockMachineKey: "machine1", controlPlaneInitLockConfigUIDKey: "cfg-uid1"},
+			machine:   "machine2",
+			configUID: "cfg-uid2",
+		},
+		{
+			name:      "holder machine with another config",
+			data:      map[string]string{controlPlaneInitLockMachineKey: "machine1", controlPlaneInitLockConfigUIDKey: "cfg-uid1"},
+			machine:   "machine1",
+			configUID: "cfg-uid2",
+		},
+		{
+			name:          "lock without the config UID",
+			data:          map[string]string{controlPlaneInitLockMachineKey: "machine1"},
+			machine:       "machine1",
+			configUID:     "cfg-uid1",
+			expectAcquire: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			configMapClient := &configMapsGetter{
+				configMap: &v1.ConfigMap{Data: tc.data},
+			}
+			l := &controlPlaneInitLocker{
+				log:             log.Log,
+				configMapClient: configMapClient,
+			}
+
+			cluster := &clusterv2.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "ns1",
+					Name:      "name1",
+					UID:       types.UID("uid1"),
+				},
+			}
+
+			acquired := l.Acquire(cluster, &clusterv2.Machine{ObjectMeta: metav1.ObjectMeta{Name: tc.machine}}, newLockHolderConfig(tc.configUID))
+			if acquired != tc.expectAcquire {
+				t.Errorf("expected acquired %t, got %t", tc.expectAcquire, acquired)
+			}
+			if configMapClient.created != nil {
+				t.Error("expected the existing lock not to be recreated")
+			}
+		})
+	}
+}
+
+func TestControlPlaneInitLockerHolder(t *testing.T) {
+	acquired := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	tests := []struct {
+		name           string
+		configMap      *v1.ConfigMap
+		getError       error
+		expectHolder   string
+		expectAcquired time.Time
+		expectErr      bool
+	}{
+		{
+			name:     "lock not held",
+			getError: apierrors.NewNotFound(schema.GroupResource{Group: "", Resource: "configmaps"}, "uid1-configmap"),
+		},
+		{
+			name: "lock held",
+			configMap: &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: acquired},
+				Data:       map[string]string{controlPlaneInitLockMachineKey: "machine1"},
+			},
+			expectHolder:   "machine1",
+			expectAcquired: acquired.Time,
+		},
+		{
+			name:      "error getting configmap",
+			getError:  errors.New("get error"),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &controlPlaneInitLocker{
+				log: log.Log,
+				configMapClient: &configMapsGetter{
+					configMap: tc.configMap,
+					getError:  tc.getError,
+				},
+			}
+
+			cluster := &clusterv2.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "ns1",
+					Name:      "name1",
+					UID:       types.UID("uid1"),
+				},
+			}
+
+			holder, at, err := l.Holder(cluster)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %t, got %v", tc.expectErr, err)
+			}
+			if holder != tc.expectHolder || !at.Equal(tc.expectAcquired) {
+				t.Errorf("expected holder %q acquired at %s, got %q acquired at %s", tc.expectHolder, tc.expectAcquired, holder, at)
+			}
+		})
+	}
+}
+
+func TestControlPlaneInitLockerMarkInitialized(t *testing.T) {
+	configMap := &v1.ConfigMap{
+		Data: map[string]string{controlPlaneInitLockMachineKey: "machine1"},
+	}
+	l := &controlPlaneInitLocker{
+		log:             log.Log,
+		configMapClient: &configMapsGetter{configMap: configMap},
+	}
+
+	cluster := &clusterv2.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns1",
+			Name:      "name1",
+			UID:       types.UID("uid1"),
+		},
+	}
+
+	initialized, err := l.Initialized(cluster)
+	if err != nil || initialized {
+		t.Fatalf("expected the cluster not to be initialized, got %t, %v", initialized, err)
+	}
+	if err := l.MarkInitialized(cluster); err != nil {
+		t.Fatalf("error should be nil but is %v", err)
+	}
+	initialized, err = l.Initialized(cluster)
+	if err != nil || !initialized {
+		t.Fatalf("expected the cluster to be initialized, got %t, %v", initialized, err)
+	}
+
+	l.configMapClient = &configMapsGetter{
+		getError: apierrors.NewNotFound(schema.GroupResource{Group: "", Resource: "configmaps"}, "uid1-configmap"),
+	}
+	if err := l.MarkInitialized(cluster); err == nil {
+		t.Error("expected an error without a lock")
+	}
+}
+
 func TestControlPlaneInitLockerRelease(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -176,30 +347,45 @@ func TestControlPlaneInitLockerRelease(t *testing.T) {
 	}
 }
 
+// newLockHolderConfig returns a KubeadmConfig with the given UID to acquire the lock for.
+func newLockHolderConfig(uid string) *cabpkv1alpha2.KubeadmConfig {
+	return &cabpkv1alpha2.KubeadmConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "cfg", UID: types.UID(uid)}}
+}
+
 type configMapsGetter struct {
 	configMap   *v1.ConfigMap
 	getError    error
 	createError error
+	updateError error
 	deleteError error
+	// created is the last ConfigMap created
+	created *v1.ConfigMap
 }
 
 func (c *configMapsGetter) ConfigMaps(namespace string) corev1client.ConfigMapInterface {
 	return &configMapClient{
+		getter:      c,
 		configMap:   c.configMap,
 		getError:    c.getError,
 		createError: c.createError,
+		updateError: c.updateError,
 		deleteError: c.deleteError,
 	}
 }
 
 type configMapClient struct {
+	getter      *configMapsGetter
 	configMap   *v1.ConfigMap
 	getError    error
 	createError error
+	updateError error
 	deleteError error
 }
 
 func (c *configMapClient) Create(configMap *v1.ConfigMap) (*v1.ConfigMap, error) {
+	if c.createError == nil {
+		c.getter.created = configMap
+	}
 	return c.configMap, c.createError
 }
 
@@ -210,8 +396,8 @@ func (c *configMapClient) Get(name string, getOptions metav1.GetOptions) (*v1.Co
 	return c.configMap, nil
 }
 
-func (c *configMapClient) Update(*v1.ConfigMap) (*v1.ConfigMap, error) {
-	panic("not implemented")
+func (c *configMapClient) Update(configMap *v1.ConfigMap) (*v1.ConfigMap, error) {
+	return configMap, c.updateError
 }
 
 func (c *configMapClient) Delete(name string, options *metav1.DeleteOptions) error {
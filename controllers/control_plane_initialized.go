@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// controlPlaneInitialized returns true once the control plane of the cluster is observed initialized, either by
+// the ControlPlaneReadyAnnotationKey on the cluster or by the machine holding the init lock registering its node.
+// The latter is recorded in the lock, so that the control plane machines of every pool join the cluster without
+// waiting for the annotation.
+func (r *KubeadmConfigReconciler) controlPlaneInitialized(ctx context.Context, cluster *capiv1alpha2.Cluster) (bool, error) {
+	if cluster.Annotations[ControlPlaneReadyAnnotationKey] == "true" {
+		return true, nil
+	}
+
+	initialized, err := r.ControlPlaneInitLocker.Initialized(cluster)
+	if err != nil || initialized {
+		return initialized, err
+	}
+	holder, _, err := r.ControlPlaneInitLocker.Holder(cluster)
+	if err != nil || holder == "" {
+		return false, err
+	}
+
+	machine := &capiv1alpha2.Machine{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: cluster.GetNamespace(), Name: holder}, machine); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "failed to get the machine %s holding the control plane init lock", holder)
+	}
+	if machine.Status.NodeRef == nil {
+		return false, nil
+	}
+	if err := r.ControlPlaneInitLocker.MarkInitialized(cluster); err != nil {
+		return false, errors.Wrap(err, "failed to record the control plane as initialized")
+	}
+	return true, nil
+}
+
+// defaultControlPlaneJoinConfiguration sets the JoinConfiguration of a control plane config that only defines an
+// InitConfiguration, as the configs of a control plane pool share one, so that the machines of the pool that did
+// not initialize the cluster join it.
+func defaultControlPlaneJoinConfiguration(config *cabpkv1alpha2.KubeadmConfig) {
+	if config.Spec.JoinConfiguration != nil || config.Spec.InitConfiguration == nil {
+		return
+	}
+	config.Spec.JoinConfiguration = &kubeadmv1beta1.JoinConfiguration{
+		TypeMeta: v1.TypeMeta{
+			APIVersion: "kubeadm.k8s.io/v1beta1",
+			Kind:       "JoinConfiguration",
+		},
+		NodeRegistration: config.Spec.InitConfiguration.NodeRegistration,
+		ControlPlane:     &kubeadmv1beta1.JoinControlPlane{},
+	}
+}
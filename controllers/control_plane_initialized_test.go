@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestControlPlaneInitialized(t *testing.T) {
+	cluster := newCluster("cluster")
+	holder := newControlPlaneMachine(cluster, "control-plane-machine-1")
+
+	locker := &fakeInitLocker{holder: holder.Name, acquired: time.Now()}
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, holder)
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: locker,
+	}
+
+	initialized, err := k.controlPlaneInitialized(context.Background(), cluster)
+	if err != nil || initialized {
+		t.Fatalf("expected the control plane not to be initialized before the holder registered its node, got %t, %v", initialized, err)
+	}
+
+	holder.Status.NodeRef = &corev1.ObjectReference{Name: "node-1"}
+	if err := myclient.Status().Update(context.Background(), holder); err != nil {
+		t.Fatalf("Failed to update machine:\n %+v", err)
+	}
+	initialized, err = k.controlPlaneInitialized(context.Background(), cluster)
+	if err != nil || !initialized {
+		t.Fatalf("expected the control plane to be initialized once the holder registered its node, got %t, %v", initialized, err)
+	}
+	if !locker.initialized {
+		t.Error("expected the initialization to be recorded in the lock")
+	}
+}
+
+func TestReconcileJoinsControlPlanePoolOnceInitialized(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+	cluster.Status.APIEndpoints = []capiv1alpha2.APIEndpoint{{Host: "100.105.150.1", Port: 6443}}
+
+	poolMachine := newControlPlaneMachine(cluster, "control-plane-machine-2")
+	poolConfig := newControlPlaneInitKubeadmConfig(poolMachine, "control-plane-pool-cfg")
+	poolConfig.Spec.InitConfiguration.NodeRegistration.Name = "pool-node"
+
+	objects := []runtime.Object{
+		cluster,
+		poolMachine,
+		poolConfig,
+	}
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), objects...)
+	certificates, _ := certs.NewCertificates()
+	_ = myclient.Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ClusterCertificatesSecretName(cluster.GetName()),
+			Namespace: poolConfig.GetNamespace(),
+		},
+		Data: certificates.ToMap(),
+	})
+
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		SecretsClientFactory:   newFakeSecretFactory(),
+		ControlPlaneInitLocker: &fakeInitLocker{holder: "control-plane-machine-1", acquired: time.Now(), initialized: true},
+	}
+
+	result, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-pool-cfg"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Fatalf("did not expect to requeue, got %s", result.RequeueAfter)
+	}
+
+	cfg, err := getKubeadmConfig(myclient, "control-plane-pool-cfg")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	if !cfg.Status.Ready {
+		t.Fatal("expected the pool machine to join the initialized control plane")
+	}
+	if cfg.Spec.JoinConfiguration == nil || cfg.Spec.JoinConfiguration.ControlPlane == nil {
+		t.Fatal("expected a control plane JoinConfiguration to be defaulted")
+	}
+	if cfg.Spec.JoinConfiguration.NodeRegistration.Name != "pool-node" {
+		t.Errorf("expected the node registration of the InitConfiguration, got %q", cfg.Spec.JoinConfiguration.NodeRegistration.Name)
+	}
+}
+
+func TestDefaultControlPlaneJoinConfiguration(t *testing.T) {
+	config := newControlPlaneJoinKubeadmConfig(nil, "cfg")
+	config.Spec.InitConfiguration = &kubeadmv1beta1.InitConfiguration{}
+	config.Spec.JoinConfiguration.ControlPlane = nil
+	defaultControlPlaneJoinConfiguration(config)
+	if config.Spec.JoinConfiguration.ControlPlane != nil {
+		t.Error("expected a user provided JoinConfiguration to be respected")
+	}
+}
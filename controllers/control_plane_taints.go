@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/yaml"
+)
+
+// setControlPlaneTaints returns data, the YAML of the kubeadm configuration document registering a control plane
+// node, with the node registration taints set to the ControlPlaneTaints of config, if set. The taints are set in
+// the YAML rather than the configuration, as kubeadm taints the node when they are omitted, which an empty list is
+// once marshalled.
+func setControlPlaneTaints(config *cabpkv1alpha2.KubeadmConfig, kind, data string) (string, error) {
+	if config.Spec.ControlPlaneTaints == nil {
+		return data, nil
+	}
+
+	// a nil list would be marshalled to null, removing the taints instead
+	taints := append([]corev1.Taint{}, *config.Spec.ControlPlaneTaints...)
+	patch, err := json.Marshal(map[string]interface{}{
+		"nodeRegistration": map[string]interface{}{"taints": taints},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal the control plane taints")
+	}
+	doc, err := yaml.YAMLToJSON([]byte(data))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to convert %s to JSON", kind)
+	}
+	patched, err := jsonpatch.MergePatch(doc, patch)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to set the control plane taints of %s", kind)
+	}
+	out, err := yaml.JSONToYAML(patched)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to convert %s to YAML", kind)
+	}
+	return string(out), nil
+}
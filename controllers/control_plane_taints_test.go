@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetControlPlaneTaints(t *testing.T) {
+	initConfiguration := `apiVersion: kubeadm.k8s.io/v1beta1
+kind: InitConfiguration
+nodeRegistration:
+  name: node
+`
+
+	testcases := []struct {
+		name     string
+		taints   *[]corev1.Taint
+		expected string
+	}{
+		{
+			name:     "default taints",
+			expected: initConfiguration,
+		},
+		{
+			name:   "untainted control plane",
+			taints: &[]corev1.Taint{},
+			expected: `apiVersion: kubeadm.k8s.io/v1beta1
+kind: InitConfiguration
+nodeRegistration:
+  name: node
+  taints: []
+`,
+		},
+		{
+			name:   "custom taints",
+			taints: &[]corev1.Taint{{Key: "example.com/dedicated", Effect: corev1.TaintEffectNoSchedule}},
+			expected: `apiVersion: kubeadm.k8s.io/v1beta1
+kind: InitConfiguration
+nodeRegistration:
+  name: node
+  taints:
+  - effect: NoSchedule
+    key: example.com/dedicated
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := newKubeadmConfig(nil, "cfg")
+			config.Spec.ControlPlaneTaints = tc.taints
+
+			data, err := setControlPlaneTaints(config, "InitConfiguration", initConfiguration)
+			if err != nil {
+				t.Fatalf("failed to set the control plane taints: %v", err)
+			}
+			if data != tc.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", tc.expected, data)
+			}
+		})
+	}
+}
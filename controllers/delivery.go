@@ -0,0 +1,476 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/feature"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/objectstorage"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/cluster-api/pkg/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultSSHPort = "22"
+	defaultSSHUser = "root"
+
+	// sshKnownHostKey is the key of the delivery secret holding the expected host public key.
+	sshKnownHostKey = "ssh-known-host"
+
+	sshDialTimeout = 30 * time.Second
+
+	// sshDeliveryScript seeds the NoCloud datasource with the user data read from stdin and runs cloud-init
+	// against it, so the pushed data is processed exactly as if it had been provided as user data.
+	sshDeliveryScript = `set -e
+mkdir -p /var/lib/cloud/seed/nocloud
+cat > /var/lib/cloud/seed/nocloud/user-data
+echo "instance-id: %s" > /var/lib/cloud/seed/nocloud/meta-data
+cloud-init clean
+cloud-init init
+cloud-init modules --mode=config
+cloud-init modules --mode=final`
+
+	defaultObjectStorageRegion    = "us-east-1"
+	defaultObjectStorageURLExpiry = 15 * time.Minute
+
+	// keys of the object storage delivery secret
+	objectStorageAccessKeyIDKey     = "accessKeyID"
+	objectStorageSecretAccessKeyKey = "secretAccessKey"
+	objectStorageSessionTokenKey    = "sessionToken"
+
+	// objectStorageFinalizer holds a config back from deletion until the object holding its bootstrap data is deleted.
+	objectStorageFinalizer = "kubeadmconfig.bootstrap.cluster.x-k8s.io/object-storage"
+)
+
+// SSHPusher define behaviour for pushing bootstrap data to a machine over SSH
+type SSHPusher interface {
+	// Push connects to address and runs command, writing data to its stdin
+	Push(address string, config *ssh.ClientConfig, command string, data []byte) error
+}
+
+// SSHClientPusher pushes bootstrap data using an SSH client session
+type SSHClientPusher struct{}
+
+// Push connects to address and runs command, writing data to its stdin
+func (p SSHClientPusher) Push(address string, config *ssh.ClientConfig, command string, data []byte) error {
+	client, err := ssh.Dial("tcp", address, config)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to %s", address)
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return errors.Wrapf(err, "failed to open session on %s", address)
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stdin = bytes.NewReader(data)
+	session.Stderr = &stderr
+	if err := session.Run(command); err != nil {
+		return errors.Wrapf(err, "failed to run bootstrap on %s: %s", address, stderr.String())
+	}
+	return nil
+}
+
+// reconcileDelivery pushes the bootstrap data of a ready config to its machine when config.Spec.Delivery is set.
+func (r *KubeadmConfigReconciler) reconcileDelivery(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) error {
+	if config.Spec.Delivery == nil || config.Spec.Delivery.SSH == nil {
+		return nil
+	}
+	if err := checkDeliveryEnabled(config); err != nil {
+		return err
+	}
+	delivery := config.Spec.Delivery.SSH
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: delivery.SecretName, Namespace: config.GetNamespace()}, secret); err != nil {
+		return errors.Wrapf(err, "failed to get SSH delivery secret %q", delivery.SecretName)
+	}
+
+	clientConfig, err := sshClientConfig(delivery, secret)
+	if err != nil {
+		return err
+	}
+
+	address := delivery.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultSSHPort)
+	}
+
+	command := fmt.Sprintf("sh -c '%s'", fmt.Sprintf(sshDeliveryScript, config.GetUID()))
+	if clientConfig.User != defaultSSHUser {
+		command = "sudo " + command
+	}
+	return r.SSHPusher.Push(address, clientConfig, command, config.Status.BootstrapData)
+}
+
+// checkDeliveryEnabled returns an error if config.Spec.Delivery is set while the ExternalDelivery feature is disabled.
+func checkDeliveryEnabled(config *cabpkv1alpha2.KubeadmConfig) error {
+	if config.Spec.Delivery == nil || feature.Gates.Enabled(feature.ExternalDelivery) {
+		return nil
+	}
+	return newBootstrapFailure(featureGateDisabledReason, "delivery of the bootstrap data of KubeadmConfig %s/%s requires the %s feature gate",
+		config.GetNamespace(), config.GetName(), feature.ExternalDelivery)
+}
+
+// sshClientConfig builds the SSH client configuration out of the delivery settings and secret.
+func sshClientConfig(delivery *cabpkv1alpha2.SSHDelivery, secret *corev1.Secret) (*ssh.ClientConfig, error) {
+	signer, err := ssh.ParsePrivateKey(secret.Data[corev1.SSHAuthPrivateKey])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q from SSH delivery secret %q", corev1.SSHAuthPrivateKey, secret.Name)
+	}
+
+	// the bootstrap data holds the cluster CA keys or a join token, so it is only pushed to a machine
+	// presenting the known host key
+	knownHost, ok := secret.Data[sshKnownHostKey]
+	if !ok {
+		return nil, errors.Errorf("SSH delivery secret %q has no %q host key", secret.Name, sshKnownHostKey)
+	}
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey(knownHost)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q from SSH delivery secret %q", sshKnownHostKey, secret.Name)
+	}
+
+	user := delivery.User
+	if user == "" {
+		user = defaultSSHUser
+	}
+
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         sshDialTimeout,
+	}, nil
+}
+
+// setBootstrapData sets data as the config bootstrap data, unless config.Spec.Delivery requires handing the machine
+// user data that only refers to it, compresses the resulting user data if requested and records its size.
+func (r *KubeadmConfigReconciler) setBootstrapData(ctx context.Context, cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig, data []byte) error {
+	if err := checkDeliveryEnabled(config); err != nil {
+		return err
+	}
+	if err := r.recordBootstrapDataRevision(ctx, config, data); err != nil {
+		return err
+	}
+
+	var err error
+	switch {
+	case config.Spec.Delivery != nil && config.Spec.Delivery.TokenOnly != nil:
+		err = r.setTokenOnlyBootstrapData(ctx, cluster, config, data)
+	case config.Spec.Delivery != nil && config.Spec.Delivery.ObjectStorage != nil:
+		err = r.setObjectStorageBootstrapData(ctx, config, data)
+	default:
+		config.Status.BootstrapData = data
+	}
+	if err != nil {
+		return err
+	}
+	format := bootstrapDataFormat(config.Status.BootstrapData)
+	bootstrapData, encoding, err := compressBootstrapData(config, config.Status.BootstrapData)
+	if err != nil {
+		return err
+	}
+	config.Status.BootstrapData = bootstrapData
+	config.Status.BootstrapDataEncoding = encoding
+	if r.BootstrapDataSecret || r.BootstrapDataSecretOnly {
+		var annotations map[string]string
+		if encoding != "" {
+			annotations = map[string]string{BootstrapDataEncodingAnnotationKey: string(encoding)}
+		}
+		if err := r.writeBootstrapDataSecret(ctx, config, config.GetName(), bootstrapData, annotations); err != nil {
+			return err
+		}
+		if err := r.reconcileBootstrapDataSecretReaders(ctx, config, config.GetName()); err != nil {
+			return err
+		}
+		name := config.GetName()
+		config.Status.DataSecretName = &name
+	}
+	// SSH delivery pushes the bootstrap data from the status once the machine has an address
+	if r.BootstrapDataSecretOnly && (config.Spec.Delivery == nil || config.Spec.Delivery.SSH == nil) {
+		config.Status.BootstrapData = nil
+	}
+	if err := r.recordGeneratedSpec(config); err != nil {
+		return err
+	}
+	setDataSecretAvailableCondition(config)
+
+	bootstrapDataSizeBytes.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), format).
+		Observe(float64(len(bootstrapData)))
+	return nil
+}
+
+// setObjectStorageBootstrapData uploads data to the bucket, the bootstrap data only includes a pre-signed URL to fetch it.
+// The config keeps a finalizer until the object is deleted.
+func (r *KubeadmConfigReconciler) setObjectStorageBootstrapData(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, data []byte) error {
+	delivery := config.Spec.Delivery.ObjectStorage
+	storage, err := r.objectStorageClient(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	expiry := defaultObjectStorageURLExpiry
+	if delivery.URLExpiry != nil {
+		expiry = delivery.URLExpiry.Duration
+	}
+
+	if !hasFinalizer(config.ObjectMeta, objectStorageFinalizer) {
+		config.SetFinalizers(append(config.GetFinalizers(), objectStorageFinalizer))
+	}
+	key := objectStorageKey(config)
+	if err := storage.Put(key, data); err != nil {
+		return err
+	}
+	url, err := storage.PresignURL(http.MethodGet, key, time.Now(), expiry)
+	if err != nil {
+		return err
+	}
+
+	include, err := includeBootstrapData(config, url)
+	if err != nil {
+		return err
+	}
+	config.Status.BootstrapData = include
+	return nil
+}
+
+// reconcileObjectStorageCleanup deletes the object holding the bootstrap data of config once the node of its
+// machine registered, or once config is being deleted, and then removes the finalizer of config. The replicas of a
+// MachinePool share the object, so it is only deleted with their config.
+func (r *KubeadmConfigReconciler) reconcileObjectStorageCleanup(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) error {
+	if !hasFinalizer(config.ObjectMeta, objectStorageFinalizer) {
+		return nil
+	}
+	if config.GetDeletionTimestamp() == nil {
+		machine, err := util.GetOwnerMachine(ctx, r.Client, config.ObjectMeta)
+		if err != nil || machine == nil || machine.Status.NodeRef == nil {
+			return err
+		}
+	}
+
+	if config.Spec.Delivery != nil && config.Spec.Delivery.ObjectStorage != nil {
+		storage, err := r.objectStorageClient(ctx, config)
+		switch {
+		case apierrors.IsNotFound(errors.Cause(err)):
+			// without credentials the object can't be deleted, which must not keep the config from being deleted
+			r.Log.Info("Leaving the bootstrap data object in place, the object storage delivery secret is gone",
+				"kubeadmconfig", fmt.Sprintf("%s/%s", config.GetNamespace(), config.GetName()))
+		case err != nil:
+			return err
+		default:
+			if err := storage.Delete(objectStorageKey(config)); err != nil {
+				return err
+			}
+		}
+	}
+
+	patchConfig := client.MergeFrom(config.DeepCopy())
+	finalizers := []string{}
+	for _, f := range config.GetFinalizers() {
+		if f != objectStorageFinalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	config.SetFinalizers(finalizers)
+	return errors.Wrap(r.Patch(ctx, config, patchConfig), "failed to remove the object storage finalizer")
+}
+
+// objectStorageClient returns the client of the bucket of the object storage delivery of config.
+func (r *KubeadmConfigReconciler) objectStorageClient(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) (*objectstorage.Client, error) {
+	delivery := config.Spec.Delivery.ObjectStorage
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: delivery.SecretName, Namespace: config.GetNamespace()}, secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to get object storage delivery secret %q", delivery.SecretName)
+	}
+	for _, key := range []string{objectStorageAccessKeyIDKey, objectStorageSecretAccessKeyKey} {
+		if len(secret.Data[key]) == 0 {
+			return nil, errors.Errorf("object storage delivery secret %q is missing %q", delivery.SecretName, key)
+		}
+	}
+
+	region := delivery.Region
+	if region == "" {
+		region = defaultObjectStorageRegion
+	}
+	return &objectstorage.Client{
+		Endpoint: delivery.Endpoint,
+		Region:   region,
+		Bucket:   delivery.Bucket,
+		Credentials: objectstorage.Credentials{
+			AccessKeyID:     string(secret.Data[objectStorageAccessKeyIDKey]),
+			SecretAccessKey: string(secret.Data[objectStorageSecretAccessKeyKey]),
+			SessionToken:    string(secret.Data[objectStorageSessionTokenKey]),
+		},
+	}, nil
+}
+
+// objectStorageKey returns the key of the object holding the bootstrap data of config.
+func objectStorageKey(config *cabpkv1alpha2.KubeadmConfig) string {
+	return path.Join(config.GetNamespace(), config.GetName())
+}
+
+// hasFinalizer returns whether obj has the finalizer.
+func hasFinalizer(obj v1.ObjectMeta, finalizer string) bool {
+	for _, f := range obj.Finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// setTokenOnlyBootstrapData stores data in a secret served by the BootstrapDataServer, the bootstrap data only
+// includes the URL to fetch it, authenticated by the join token. The URL also holds the API server endpoint and
+// the public key hash of the cluster CA the machine joins with.
+func (r *KubeadmConfigReconciler) setTokenOnlyBootstrapData(ctx context.Context, cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig, data []byte) error {
+	token := joinToken(config)
+	if token == "" {
+		return newBootstrapFailure(invalidConfigurationReason, "token-only delivery requires a JoinConfiguration with a bootstrap token")
+	}
+	discovery := config.Spec.JoinConfiguration.Discovery.BootstrapToken
+	caCertHash := ""
+	if len(discovery.CACertHashes) > 0 {
+		caCertHash = discovery.CACertHashes[0]
+	} else {
+		var err error
+		if caCertHash, err = r.clusterCACertHash(ctx, cluster.GetName(), config.GetNamespace()); err != nil {
+			return err
+		}
+	}
+
+	if err := r.writeBootstrapDataSecret(ctx, config, BootstrapDataSecretName(config.GetName()), data, nil); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("token", token)
+	if discovery.APIServerEndpoint != "" {
+		query.Set("endpoint", discovery.APIServerEndpoint)
+	}
+	if caCertHash != "" {
+		query.Set("caCertHash", caCertHash)
+	}
+	dataURL := fmt.Sprintf("%s/%s/%s?%s", strings.TrimSuffix(config.Spec.Delivery.TokenOnly.URL, "/"),
+		config.GetNamespace(), config.GetName(), query.Encode())
+	include, err := includeBootstrapData(config, dataURL)
+	if err != nil {
+		return err
+	}
+	config.Status.BootstrapData = include
+	return nil
+}
+
+// writeBootstrapDataSecret creates a secret owned by config storing data, or updates it if it already exists with
+// different data and is owned by config. annotations are added to the secret annotations.
+func (r *KubeadmConfigReconciler) writeBootstrapDataSecret(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, name string, data []byte, annotations map[string]string) error {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	secret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: config.GetNamespace(),
+			Annotations: map[string]string{
+				BootstrapDataHashAnnotationKey: hash,
+			},
+			OwnerReferences: []v1.OwnerReference{
+				{
+					APIVersion: cabpkv1alpha2.GroupVersion.String(),
+					Kind:       "KubeadmConfig",
+					Name:       config.GetName(),
+					UID:        config.GetUID(),
+				},
+			},
+		},
+		Data: map[string][]byte{
+			bootstrapDataSecretKey: data,
+		},
+	}
+	for k, v := range annotations {
+		secret.Annotations[k] = v
+	}
+
+	if err := r.Create(ctx, secret); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "failed to create bootstrap data secret %q", secret.Name)
+		}
+		existing := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}, existing); err != nil {
+			return errors.Wrapf(err, "failed to get bootstrap data secret %q", secret.Name)
+		}
+		// never take over a secret of the same name that is not owned by the config, e.g. one of a user, even if it
+		// happens to hold the same data
+		if !isOwnedBy(existing.ObjectMeta, config.GetUID()) {
+			return errors.Errorf("bootstrap data secret %q already exists and is not owned by KubeadmConfig %q", secret.Name, config.GetName())
+		}
+		// skip no-op updates, which would bump the secret resourceVersion for nothing
+		if existing.GetAnnotations()[BootstrapDataHashAnnotationKey] != hash {
+			if existing.Annotations == nil {
+				existing.Annotations = map[string]string{}
+			}
+			for k, v := range secret.Annotations {
+				existing.Annotations[k] = v
+			}
+			existing.Data = secret.Data
+			if err := r.Update(ctx, existing); err != nil {
+				return errors.Wrapf(err, "failed to update bootstrap data secret %q", secret.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// isOwnedBy reports whether obj has an owner reference to the object with the given uid.
+func isOwnedBy(obj v1.ObjectMeta, uid types.UID) bool {
+	for _, ref := range obj.OwnerReferences {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// joinToken returns the bootstrap token used by the config to join the cluster, if any.
+func joinToken(config *cabpkv1alpha2.KubeadmConfig) string {
+	if config.Spec.JoinConfiguration == nil || config.Spec.JoinConfiguration.Discovery.BootstrapToken == nil {
+		return ""
+	}
+	return config.Spec.JoinConfiguration.Discovery.BootstrapToken.Token
+}
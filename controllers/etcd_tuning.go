@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+// wireEtcdTuning renders the EtcdTuning of spec into the local etcd flags and data directory of the
+// ClusterConfiguration. Control planes joining the cluster run with the ClusterConfiguration stored by kubeadm init.
+func wireEtcdTuning(spec *cabpkv1alpha2.KubeadmConfigSpec, cfg *kubeadmv1beta1.ClusterConfiguration) {
+	tuning := spec.EtcdTuning
+	if tuning == nil || cfg.Etcd.External != nil {
+		return
+	}
+	if cfg.Etcd.Local == nil {
+		cfg.Etcd.Local = &kubeadmv1beta1.LocalEtcd{}
+	}
+
+	args := map[string]string{}
+	if tuning.QuotaBackendBytes != nil {
+		args["quota-backend-bytes"] = strconv.FormatInt(*tuning.QuotaBackendBytes, 10)
+	}
+	if tuning.HeartbeatInterval != nil {
+		args["heartbeat-interval"] = strconv.FormatInt(int64(tuning.HeartbeatInterval.Duration/time.Millisecond), 10)
+	}
+	if tuning.ElectionTimeout != nil {
+		args["election-timeout"] = strconv.FormatInt(int64(tuning.ElectionTimeout.Duration/time.Millisecond), 10)
+	}
+	if len(tuning.ListenMetricsURLs) > 0 {
+		args["listen-metrics-urls"] = strings.Join(tuning.ListenMetricsURLs, ",")
+	}
+	cfg.Etcd.Local.ExtraArgs = defaultArgs(cfg.Etcd.Local.ExtraArgs, args)
+
+	if tuning.DataDir != "" {
+		cfg.Etcd.Local.DataDir = tuning.DataDir
+	}
+}
+
+// wireEtcdDisk sets the local etcd data directory of the ClusterConfiguration to the etcd directory of the
+// EtcdDisk of spec, which is mounted on control plane machines.
+func wireEtcdDisk(spec *cabpkv1alpha2.KubeadmConfigSpec, cfg *kubeadmv1beta1.ClusterConfiguration) {
+	if spec.EtcdDisk == nil || cfg.Etcd.External != nil {
+		return
+	}
+	if cfg.Etcd.Local == nil {
+		cfg.Etcd.Local = &kubeadmv1beta1.LocalEtcd{}
+	}
+	mountPath := spec.EtcdDisk.MountPath
+	if mountPath == "" {
+		mountPath = cabpkv1alpha2.DefaultEtcdDiskMountPath
+	}
+	cfg.Etcd.Local.DataDir = path.Join(mountPath, "etcd")
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+func TestWireEtcdTuning(t *testing.T) {
+	quota := int64(8589934592)
+	spec := &cabpkv1alpha2.KubeadmConfigSpec{
+		EtcdTuning: &cabpkv1alpha2.EtcdTuning{
+			QuotaBackendBytes: &quota,
+			HeartbeatInterval: &metav1.Duration{Duration: 250 * time.Millisecond},
+			ElectionTimeout:   &metav1.Duration{Duration: 2500 * time.Millisecond},
+			ListenMetricsURLs: []string{"http://127.0.0.1:2381", "http://10.0.0.1:2381"},
+			DataDir:           "/mnt/etcd",
+		},
+	}
+	cfg := &kubeadmv1beta1.ClusterConfiguration{}
+
+	wireEtcdTuning(spec, cfg)
+
+	if cfg.Etcd.Local == nil {
+		t.Fatal("expected local etcd to be configured")
+	}
+	expected := map[string]string{
+		"quota-backend-bytes": "8589934592",
+		"heartbeat-interval":  "250",
+		"election-timeout":    "2500",
+		"listen-metrics-urls": "http://127.0.0.1:2381,http://10.0.0.1:2381",
+	}
+	for flag, value := range expected {
+		if cfg.Etcd.Local.ExtraArgs[flag] != value {
+			t.Errorf("expected etcd flag %s to be %q, got %q", flag, value, cfg.Etcd.Local.ExtraArgs[flag])
+		}
+	}
+	if cfg.Etcd.Local.DataDir != "/mnt/etcd" {
+		t.Fatalf("expected the etcd data directory to be /mnt/etcd, got %q", cfg.Etcd.Local.DataDir)
+	}
+}
+
+func TestWireEtcdTuningSkipsExternalEtcd(t *testing.T) {
+	quota := int64(8589934592)
+	spec := &cabpkv1alpha2.KubeadmConfigSpec{
+		EtcdTuning: &cabpkv1alpha2.EtcdTuning{QuotaBackendBytes: &quota},
+	}
+	cfg := &kubeadmv1beta1.ClusterConfiguration{
+		Etcd: kubeadmv1beta1.Etcd{External: &kubeadmv1beta1.ExternalEtcd{Endpoints: []string{"https://10.0.0.1:2379"}}},
+	}
+
+	wireEtcdTuning(spec, cfg)
+
+	if cfg.Etcd.Local != nil {
+		t.Fatal("did not expect local etcd to be configured along with external etcd")
+	}
+}
+
+func TestWireEtcdDisk(t *testing.T) {
+	spec := &cabpkv1alpha2.KubeadmConfigSpec{
+		EtcdDisk: &cabpkv1alpha2.EtcdDisk{Device: "/dev/nvme1n1"},
+	}
+	cfg := &kubeadmv1beta1.ClusterConfiguration{}
+
+	wireEtcdDisk(spec, cfg)
+
+	if cfg.Etcd.Local == nil || cfg.Etcd.Local.DataDir != "/var/lib/etcddisk/etcd" {
+		t.Fatalf("expected the etcd data directory to be on the etcd disk, got %+v", cfg.Etcd.Local)
+	}
+}
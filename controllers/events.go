@@ -0,0 +1,35 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+// recordEventf records an event on the config and, if not nil, on its machine, so that the bootstrap of a machine
+// can be followed with kubectl describe rather than in the controller logs. Machines standing in for the replicas
+// of a MachinePool do not exist, only the config gets their events.
+func (r *KubeadmConfigReconciler) recordEventf(config *cabpkv1alpha2.KubeadmConfig, machine *capiv1alpha2.Machine, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(config, eventType, reason, messageFmt, args...)
+	if machine != nil && !isMachinePoolMachine(machine) {
+		r.Recorder.Eventf(machine, eventType, reason, messageFmt, args...)
+	}
+}
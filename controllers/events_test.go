@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestRecordEventf(t *testing.T) {
+	machine := newControlPlaneMachine(newCluster("cluster"), "machine")
+	config := newKubeadmConfig(machine, "cfg")
+
+	recorder := record.NewFakeRecorder(2)
+	k := &KubeadmConfigReconciler{Log: log.Log, Recorder: recorder}
+	k.recordEventf(config, machine, corev1.EventTypeNormal, "BootstrapDataGenerated", "Generated the bootstrap data of %s", "a joining worker")
+	for i := 0; i < 2; i++ {
+		if event := <-recorder.Events; event != "Normal BootstrapDataGenerated Generated the bootstrap data of a joining worker" {
+			t.Errorf("unexpected event %q", event)
+		}
+	}
+
+	// without a recorder, events are dropped
+	k.Recorder = nil
+	k.recordEventf(config, nil, corev1.EventTypeNormal, "BootstrapDataGenerated", "Generated the bootstrap data")
+}
+
+func TestReconcileRecordsInitEvents(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg")
+
+	recorder := record.NewFakeRecorder(10)
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 fake.NewFakeClientWithScheme(setupScheme(), cluster, controlPlaneMachine, controlPlaneInitConfig),
+		ControlPlaneInitLocker: &fakeInitLocker{},
+		Recorder:               recorder,
+	}
+
+	if _, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg"},
+	}); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	close(recorder.Events)
+
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	for _, reason := range []string{"InitLockAcquired", "CertificatesGenerated", "BootstrapDataGenerated"} {
+		count := 0
+		for _, event := range events {
+			if strings.HasPrefix(event, "Normal "+reason+" ") {
+				count++
+			}
+		}
+		// once on the config, once on the machine
+		if count != 2 {
+			t.Errorf("expected two %s events, got %d in %v", reason, count, events)
+		}
+	}
+}
+
+func TestReconcileRecordsFailureEvent(t *testing.T) {
+	cluster := newCluster("cluster")
+	machine := newMachine(cluster, "machine")
+	config := newKubeadmConfig(machine, "cfg")
+
+	recorder := record.NewFakeRecorder(10)
+	k := &KubeadmConfigReconciler{
+		Log: log.Log,
+		// intentionally omitting cluster
+		Client:                 fake.NewFakeClientWithScheme(setupScheme(), machine, config),
+		ControlPlaneInitLocker: &fakeInitLocker{},
+		Recorder:               recorder,
+	}
+
+	if _, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "cfg"},
+	}); err == nil {
+		t.Fatal("expected an error reconciling a config without its cluster")
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning ReconcileFailed ") {
+			t.Errorf("expected a ReconcileFailed event, got %q", event)
+		}
+	default:
+		t.Error("expected a ReconcileFailed event")
+	}
+}
+
+func TestReconcileRecordsBootstrapFailureEvent(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+	cluster.Annotations = map[string]string{ControlPlaneReadyAnnotationKey: "true"}
+
+	workerMachine := newWorkerMachine(cluster, "worker-machine")
+	// a worker without a join configuration can't be bootstrapped
+	workerConfig := newKubeadmConfig(workerMachine, "worker-cfg")
+
+	recorder := record.NewFakeRecorder(10)
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 fake.NewFakeClientWithScheme(setupScheme(), cluster, workerMachine, workerConfig),
+		ControlPlaneInitLocker: &fakeInitLocker{},
+		Recorder:               recorder,
+	}
+
+	if _, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "worker-cfg"},
+	}); err != nil {
+		t.Fatalf("expected the failure to be recorded rather than retried, got %v", err)
+	}
+	close(recorder.Events)
+
+	count := 0
+	for event := range recorder.Events {
+		if strings.HasPrefix(event, "Warning ReconcileFailed ") {
+			t.Errorf("expected no ReconcileFailed event for a terminal failure, got %q", event)
+		}
+		if strings.HasPrefix(event, "Warning BootstrapFailed ") {
+			count++
+		}
+	}
+	// once on the config, once on the machine
+	if count != 2 {
+		t.Errorf("expected two BootstrapFailed events, got %d", count)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+)
+
+// setExternalCAs replaces the etcd and front proxy CAs of certificates with the ones provided by the ExternalCAs
+// of config, and checks that the resulting CAs are distinct.
+func (r *KubeadmConfigReconciler) setExternalCAs(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, certificates *certs.Certificates) error {
+	cas := config.Spec.ExternalCAs
+	if cas == nil {
+		return nil
+	}
+
+	if cas.EtcdCASecretName != "" {
+		ca, err := r.getExternalCA(ctx, config.GetNamespace(), cas.EtcdCASecretName)
+		if err != nil {
+			return err
+		}
+		certificates.EtcdCA = ca
+	}
+	if cas.FrontProxyCASecretName != "" {
+		ca, err := r.getExternalCA(ctx, config.GetNamespace(), cas.FrontProxyCASecretName)
+		if err != nil {
+			return err
+		}
+		certificates.FrontProxyCA = ca
+	}
+	if err := certificates.ValidateDistinctCAs(); err != nil {
+		return newBootstrapFailure(invalidExternalCAReason, "inconsistent external CAs: %v", err)
+	}
+	return nil
+}
+
+// getExternalCA returns the CA held by the given secret.
+func (r *KubeadmConfigReconciler) getExternalCA(ctx context.Context, namespace, name string) (*certs.KeyPair, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to get external CA secret %q", name)
+	}
+	ca := &certs.KeyPair{
+		Cert: secret.Data[corev1.TLSCertKey],
+		Key:  secret.Data[corev1.TLSPrivateKeyKey],
+	}
+	if err := ca.ValidateCA(); err != nil {
+		return nil, errors.Wrapf(err, "invalid CA in secret %q", name)
+	}
+	return ca, nil
+}
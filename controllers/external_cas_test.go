@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func newCASecret(t *testing.T, name string) *corev1.Secret {
+	t.Helper()
+	cert, key, err := certs.NewCertificateAuthority()
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       certs.EncodeCertPEM(cert),
+			corev1.TLSPrivateKeyKey: certs.EncodePrivateKeyPEM(key),
+		},
+	}
+}
+
+func TestCreateClusterCertificatesWithExternalCAs(t *testing.T) {
+	etcdCA := newCASecret(t, "etcd-ca")
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.ExternalCAs = &cabpkv1alpha2.ExternalCAs{EtcdCASecretName: "etcd-ca"}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), etcdCA),
+	}
+	certificates, err := k.createClusterCertificates(context.Background(), newCluster("cluster"), config)
+	if err != nil {
+		t.Fatalf("failed to create the cluster certificates: %v", err)
+	}
+	if !bytes.Equal(certificates.EtcdCA.Cert, etcdCA.Data[corev1.TLSCertKey]) || !bytes.Equal(certificates.EtcdCA.Key, etcdCA.Data[corev1.TLSPrivateKeyKey]) {
+		t.Fatal("expected the etcd CA to be the external one")
+	}
+	if err := certificates.FrontProxyCA.ValidateCA(); err != nil {
+		t.Fatalf("expected the front proxy CA to be generated, got %v", err)
+	}
+
+	stored, err := k.getClusterCertificates(context.Background(), "cluster", "default")
+	if err != nil {
+		t.Fatalf("failed to get the cluster certificates: %v", err)
+	}
+	if !bytes.Equal(stored.EtcdCA.Cert, etcdCA.Data[corev1.TLSCertKey]) {
+		t.Fatal("expected the stored etcd CA to be the external one")
+	}
+}
+
+func TestSetExternalCAs(t *testing.T) {
+	notCA := newCASecret(t, "not-ca")
+	sa, err := certs.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	notCA.Data[corev1.TLSPrivateKeyKey] = certs.EncodePrivateKeyPEM(sa)
+
+	testcases := []struct {
+		name      string
+		cas       *cabpkv1alpha2.ExternalCAs
+		expectErr bool
+	}{
+		{
+			name: "etcd and front proxy CAs",
+			cas:  &cabpkv1alpha2.ExternalCAs{EtcdCASecretName: "etcd-ca", FrontProxyCASecretName: "front-proxy-ca"},
+		},
+		{
+			name:      "missing secret",
+			cas:       &cabpkv1alpha2.ExternalCAs{FrontProxyCASecretName: "missing"},
+			expectErr: true,
+		},
+		{
+			name:      "key not matching the certificate",
+			cas:       &cabpkv1alpha2.ExternalCAs{EtcdCASecretName: "not-ca"},
+			expectErr: true,
+		},
+		{
+			name:      "same CA in two secrets",
+			cas:       &cabpkv1alpha2.ExternalCAs{EtcdCASecretName: "etcd-ca", FrontProxyCASecretName: "etcd-ca-copy"},
+			expectErr: true,
+		},
+	}
+
+	etcdCA := newCASecret(t, "etcd-ca")
+	etcdCACopy := etcdCA.DeepCopy()
+	etcdCACopy.Name = "etcd-ca-copy"
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), etcdCA, etcdCACopy, newCASecret(t, "front-proxy-ca"), notCA),
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			certificates, err := certs.NewCertificates()
+			if err != nil {
+				t.Fatalf("failed to generate certificates: %v", err)
+			}
+			config := newKubeadmConfig(nil, "cfg")
+			config.Spec.ExternalCAs = tc.cas
+
+			err = k.setExternalCAs(context.Background(), config, certificates)
+			if tc.expectErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("expected nil, got error %v", err)
+			}
+		})
+	}
+}
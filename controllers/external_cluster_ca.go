@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+// completeExternalCACertificates generates the key pairs missing from the certificates secret of a cluster whose
+// CA is external, i.e. provided by a pre-created secret holding its certificate without its key. The etcd and
+// front proxy CAs may still be provided by the ExternalCAs of config. The cluster CA is never generated, so that
+// kubeadm runs in external CA mode.
+func (r *KubeadmConfigReconciler) completeExternalCACertificates(ctx context.Context, cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig, certificates *certs.Certificates) error {
+	if certificates.Validate() == nil {
+		return nil
+	}
+	opts, err := r.certificateOptions(cluster)
+	if err != nil {
+		return err
+	}
+	if _, err := certificates.Complete(opts); err != nil {
+		return errors.Wrap(err, "failed to generate the certificates missing next to the external cluster CA")
+	}
+	if err := r.setExternalCAs(ctx, config, certificates); err != nil {
+		return err
+	}
+	if err := certificates.Validate(); err != nil {
+		return newBootstrapFailure(invalidExternalCAReason, "the certificates of cluster %s with an external CA are incomplete: %v", cluster.GetName(), err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Name: ClusterCertificatesSecretName(cluster.GetName()), Namespace: config.GetNamespace()}, secret); err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	// only fill in the missing keys, the provided ones are authoritative
+	for k, v := range certificates.ToMap() {
+		if len(v) > 0 && len(secret.Data[k]) == 0 {
+			secret.Data[k] = v
+		}
+	}
+	if err := r.Update(ctx, secret); err != nil {
+		return errors.Wrap(err, "failed to store the certificates generated next to the external cluster CA")
+	}
+	r.certificatesCache.evict(types.NamespacedName{Name: secret.GetName(), Namespace: secret.GetNamespace()})
+	*certificates = *certs.NewCertificatesFromMap(secret.Data)
+	r.Log.Info("Completed the certificates of a cluster with an external CA, kubeadm runs in external CA mode", "cluster", cluster.GetName())
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestCompleteExternalCACertificates(t *testing.T) {
+	cert, _, err := certs.NewCertificateAuthority()
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+	caCert := certs.EncodeCertPEM(cert)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: ClusterCertificatesSecretName("cluster")},
+		Data:       map[string][]byte{"cluster-ca-cert": caCert},
+	}
+	config := newKubeadmConfig(nil, "cfg")
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), secret),
+	}
+	certificates, err := k.getClusterCertificates(context.Background(), "cluster", "default")
+	if err != nil {
+		t.Fatalf("failed to get the cluster certificates: %v", err)
+	}
+	if !certificates.ExternalClusterCA() {
+		t.Fatal("expected the cluster CA to be external")
+	}
+	if err := k.completeExternalCACertificates(context.Background(), newCluster("cluster"), config, certificates); err != nil {
+		t.Fatalf("failed to complete the cluster certificates: %v", err)
+	}
+	if err := certificates.Validate(); err != nil {
+		t.Fatalf("expected the certificates to be complete, got %v", err)
+	}
+
+	stored, err := k.getClusterCertificates(context.Background(), "cluster", "default")
+	if err != nil {
+		t.Fatalf("failed to get the cluster certificates: %v", err)
+	}
+	if !bytes.Equal(stored.ClusterCA.Cert, caCert) || len(stored.ClusterCA.Key) != 0 {
+		t.Fatal("expected the stored cluster CA to stay external")
+	}
+	if !bytes.Equal(stored.EtcdCA.Cert, certificates.EtcdCA.Cert) || !bytes.Equal(stored.ServiceAccount.Key, certificates.ServiceAccount.Key) {
+		t.Fatal("expected the generated key pairs to be stored")
+	}
+	for _, f := range certs.CertificatesToFiles(*stored) {
+		if f.Path == "/etc/kubernetes/pki/ca.key" {
+			t.Fatal("expected the key of the external cluster CA not to be written")
+		}
+	}
+}
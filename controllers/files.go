@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// resolveFiles returns the files, with the content of those referencing a Secret or ConfigMap key read from the
+// config namespace.
+func (r *KubeadmConfigReconciler) resolveFiles(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, files []cabpkv1alpha2.Files) ([]cabpkv1alpha2.Files, error) {
+	resolved := make([]cabpkv1alpha2.Files, 0, len(files))
+	for _, file := range files {
+		file := *file.DeepCopy()
+		if file.ContentFrom != nil {
+			content, err := r.fileContent(ctx, config.GetNamespace(), file.ContentFrom)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to resolve the content of file %q", file.Path)
+			}
+			file.Content = content
+			file.ContentFrom = nil
+		}
+		resolved = append(resolved, file)
+	}
+	return resolved, nil
+}
+
+// resolveOSConditionals returns the OS conditionals of the config, with the content of their files resolved.
+func (r *KubeadmConfigReconciler) resolveOSConditionals(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) ([]cabpkv1alpha2.OSConditional, error) {
+	var conditionals []cabpkv1alpha2.OSConditional
+	for _, conditional := range config.Spec.OSConditionals {
+		conditional := *conditional.DeepCopy()
+		files, err := r.resolveFiles(ctx, config, conditional.Files)
+		if err != nil {
+			return nil, err
+		}
+		conditional.Files = files
+		conditionals = append(conditionals, conditional)
+	}
+	return conditionals, nil
+}
+
+// resolveMultipart returns the MIME multi-part archive of the config, with the content of its parts resolved, or nil
+// if the config renders none.
+func (r *KubeadmConfigReconciler) resolveMultipart(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) (*cabpkv1alpha2.Multipart, error) {
+	if config.Spec.Multipart == nil {
+		return nil, nil
+	}
+	multipart := config.Spec.Multipart.DeepCopy()
+	for i := range multipart.Parts {
+		part := &multipart.Parts[i]
+		if part.ContentFrom == nil {
+			continue
+		}
+		content, err := r.fileContent(ctx, config.GetNamespace(), part.ContentFrom)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve the content of MIME part %d", i)
+		}
+		part.Content = content
+		part.ContentFrom = nil
+	}
+	return multipart, nil
+}
+
+// fileContent reads the content referenced by source from the given namespace.
+func (r *KubeadmConfigReconciler) fileContent(ctx context.Context, namespace string, source *cabpkv1alpha2.FileSource) (string, error) {
+	switch {
+	case source.Secret != nil:
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: source.Secret.Name, Namespace: namespace}, secret); err != nil {
+			return "", errors.Wrapf(err, "failed to get secret %q", source.Secret.Name)
+		}
+		content, ok := secret.Data[source.Secret.Key]
+		if !ok {
+			return "", errors.Errorf("secret %q has no %s key", source.Secret.Name, source.Secret.Key)
+		}
+		return string(content), nil
+	case source.ConfigMap != nil:
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, types.NamespacedName{Name: source.ConfigMap.Name, Namespace: namespace}, configMap); err != nil {
+			return "", errors.Wrapf(err, "failed to get config map %q", source.ConfigMap.Name)
+		}
+		if content, ok := configMap.Data[source.ConfigMap.Key]; ok {
+			return content, nil
+		}
+		if content, ok := configMap.BinaryData[source.ConfigMap.Key]; ok {
+			return string(content), nil
+		}
+		return "", errors.Errorf("config map %q has no %s key", source.ConfigMap.Name, source.ConfigMap.Key)
+	}
+	return "", errors.New("file content source references neither a secret nor a config map")
+}
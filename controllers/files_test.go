@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestResolveFiles(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "registry"},
+		Data:       map[string][]byte{"ca.crt": []byte("registry-ca")},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "containerd"},
+		Data:       map[string]string{"config.toml": "version = 2"},
+	}
+
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.AdditionalUserDataFiles = []cabpkv1alpha2.Files{
+		{Path: "/etc/inline", Content: "inline"},
+		{
+			Path:        "/etc/containerd/certs.d/registry/ca.crt",
+			ContentFrom: &cabpkv1alpha2.FileSource{Secret: &cabpkv1alpha2.FileSourceKey{Name: "registry", Key: "ca.crt"}},
+		},
+		{
+			Path:        "/etc/containerd/config.toml",
+			ContentFrom: &cabpkv1alpha2.FileSource{ConfigMap: &cabpkv1alpha2.FileSourceKey{Name: "containerd", Key: "config.toml"}},
+		},
+	}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), secret, configMap),
+	}
+	files, err := k.resolveFiles(context.Background(), config, config.Spec.AdditionalUserDataFiles)
+	if err != nil {
+		t.Fatalf("failed to resolve files: %v", err)
+	}
+
+	expected := []string{"inline", "registry-ca", "version = 2"}
+	for i, file := range files {
+		if file.Content != expected[i] || file.ContentFrom != nil {
+			t.Errorf("expected %s to have content %q, got %q from %v", file.Path, expected[i], file.Content, file.ContentFrom)
+		}
+	}
+	if config.Spec.AdditionalUserDataFiles[1].Content != "" {
+		t.Fatal("expected the spec files not to be modified")
+	}
+
+	config.Spec.AdditionalUserDataFiles[1].ContentFrom.Secret.Key = "tls.crt"
+	if _, err := k.resolveFiles(context.Background(), config, config.Spec.AdditionalUserDataFiles); err == nil {
+		t.Fatal("expected an error for a missing secret key, got nil")
+	}
+}
+
+func TestResolveMultipart(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "proxy"},
+		Data:       map[string]string{"proxy.yaml": "#cloud-config\n"},
+	}
+
+	config := newKubeadmConfig(nil, "cfg")
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), configMap),
+	}
+	multipart, err := k.resolveMultipart(context.Background(), config)
+	if err != nil || multipart != nil {
+		t.Fatalf("expected no MIME multi-part archive, got %v: %v", multipart, err)
+	}
+
+	config.Spec.Multipart = &cabpkv1alpha2.Multipart{Parts: []cabpkv1alpha2.UserDataPart{
+		{ContentType: "text/x-shellscript", Content: "#!/bin/sh\n"},
+		{
+			ContentType: "text/cloud-config",
+			ContentFrom: &cabpkv1alpha2.FileSource{ConfigMap: &cabpkv1alpha2.FileSourceKey{Name: "proxy", Key: "proxy.yaml"}},
+		},
+	}}
+	multipart, err = k.resolveMultipart(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to resolve the MIME parts: %v", err)
+	}
+	expected := []string{"#!/bin/sh\n", "#cloud-config\n"}
+	for i, part := range multipart.Parts {
+		if part.Content != expected[i] || part.ContentFrom != nil {
+			t.Errorf("expected part %d to have content %q, got %q from %v", i, expected[i], part.Content, part.ContentFrom)
+		}
+	}
+	if config.Spec.Multipart.Parts[1].Content != "" {
+		t.Fatal("expected the spec parts not to be modified")
+	}
+
+	config.Spec.Multipart.Parts[1].ContentFrom.ConfigMap.Name = "region"
+	if _, err := k.resolveMultipart(context.Background(), config); err == nil {
+		t.Fatal("expected an error for a missing config map, got nil")
+	}
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sync"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/feature"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/ignition"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/windows"
+)
+
+// BootstrapGenerator renders the bootstrap data of machines in a format. The inputs are the ones of the cloudinit
+// package, whose generators default and validate them; other generators can run the cloudinit ones first to do so.
+type BootstrapGenerator interface {
+	// InitControlPlane returns the bootstrap data of the control plane machine initializing the cluster.
+	InitControlPlane(input *cloudinit.ControlPlaneInput) ([]byte, error)
+	// JoinControlPlane returns the bootstrap data of a control plane machine joining the cluster.
+	JoinControlPlane(input *cloudinit.ControlPlaneJoinInput) ([]byte, error)
+	// Node returns the bootstrap data of a worker machine joining the cluster.
+	Node(input *cloudinit.NodeInput) ([]byte, error)
+	// Include returns the bootstrap data making the machine fetch and process the one served at url.
+	Include(url string) ([]byte, error)
+}
+
+var (
+	bootstrapGeneratorsMu sync.RWMutex
+	// bootstrapGenerators are the generators of the bootstrap data formats, by format.
+	bootstrapGenerators = map[cabpkv1alpha2.Format]BootstrapGenerator{
+		cabpkv1alpha2.FormatCloudConfig: cloudConfigGenerator{},
+		cabpkv1alpha2.FormatIgnition:    ignitionGenerator{},
+		cabpkv1alpha2.FormatWindows:     windowsGenerator{},
+	}
+
+	// formatFeatures are the feature gates the built-in formats require, by format.
+	formatFeatures = map[cabpkv1alpha2.Format]feature.Feature{
+		cabpkv1alpha2.FormatIgnition: feature.Ignition,
+		cabpkv1alpha2.FormatWindows:  feature.Windows,
+	}
+)
+
+// RegisterBootstrapGenerator registers the generator of the bootstrap data of configs of the given format, replacing
+// the one already registered for it, if any. It is meant to be called before the manager is started, so that
+// other formats can be supported without forking the controller.
+func RegisterBootstrapGenerator(format cabpkv1alpha2.Format, generator BootstrapGenerator) {
+	bootstrapGeneratorsMu.Lock()
+	defer bootstrapGeneratorsMu.Unlock()
+	bootstrapGenerators[format] = generator
+}
+
+// configFormat returns the format of the bootstrap data of config, cloud-config unless set.
+func configFormat(config *cabpkv1alpha2.KubeadmConfig) cabpkv1alpha2.Format {
+	if config.Spec.Format == "" {
+		return cabpkv1alpha2.FormatCloudConfig
+	}
+	return config.Spec.Format
+}
+
+// bootstrapGenerator returns the generator of the format of config. It returns an error if the format requires a
+// disabled feature, or has no registered generator.
+func bootstrapGenerator(config *cabpkv1alpha2.KubeadmConfig) (BootstrapGenerator, error) {
+	format := configFormat(config)
+	if f, ok := formatFeatures[format]; ok && !feature.Gates.Enabled(f) {
+		return nil, newBootstrapFailure(featureGateDisabledReason, "the %s format of KubeadmConfig %s/%s requires the %s feature gate",
+			format, config.GetNamespace(), config.GetName(), f)
+	}
+
+	bootstrapGeneratorsMu.RLock()
+	defer bootstrapGeneratorsMu.RUnlock()
+	generator, ok := bootstrapGenerators[format]
+	if !ok {
+		return nil, newBootstrapFailure(invalidConfigurationReason, "no bootstrap generator is registered for the format %q of KubeadmConfig %s/%s",
+			format, config.GetNamespace(), config.GetName())
+	}
+	return generator, nil
+}
+
+// newInitControlPlaneData returns the bootstrap data of the control plane machine initializing the cluster, in
+// the format of config.
+func newInitControlPlaneData(config *cabpkv1alpha2.KubeadmConfig, input *cloudinit.ControlPlaneInput) ([]byte, error) {
+	generator, err := bootstrapGenerator(config)
+	if err != nil {
+		return nil, err
+	}
+	return generator.InitControlPlane(input)
+}
+
+// newJoinControlPlaneData returns the bootstrap data of a control plane machine joining the cluster, in the format
+// of config.
+func newJoinControlPlaneData(config *cabpkv1alpha2.KubeadmConfig, input *cloudinit.ControlPlaneJoinInput) ([]byte, error) {
+	generator, err := bootstrapGenerator(config)
+	if err != nil {
+		return nil, err
+	}
+	return generator.JoinControlPlane(input)
+}
+
+// newNodeData returns the bootstrap data of a worker machine joining the cluster, in the format of config.
+func newNodeData(config *cabpkv1alpha2.KubeadmConfig, input *cloudinit.NodeInput) ([]byte, error) {
+	generator, err := bootstrapGenerator(config)
+	if err != nil {
+		return nil, err
+	}
+	return generator.Node(input)
+}
+
+// includeBootstrapData returns the bootstrap data making the machine fetch and process the one served at url, in
+// the format of config.
+func includeBootstrapData(config *cabpkv1alpha2.KubeadmConfig, url string) ([]byte, error) {
+	generator, err := bootstrapGenerator(config)
+	if err != nil {
+		return nil, err
+	}
+	return generator.Include(url)
+}
+
+// cloudConfigGenerator renders cloud-init cloud-configs.
+type cloudConfigGenerator struct{}
+
+func (cloudConfigGenerator) InitControlPlane(input *cloudinit.ControlPlaneInput) ([]byte, error) {
+	return cloudinit.NewInitControlPlane(input)
+}
+
+func (cloudConfigGenerator) JoinControlPlane(input *cloudinit.ControlPlaneJoinInput) ([]byte, error) {
+	return cloudinit.NewJoinControlPlane(input)
+}
+
+func (cloudConfigGenerator) Node(input *cloudinit.NodeInput) ([]byte, error) {
+	return cloudinit.NewNode(input)
+}
+
+func (cloudConfigGenerator) Include(url string) ([]byte, error) {
+	// cloud-init fetches and processes the included URL as if it was the user data
+	return []byte(fmt.Sprintf("#include\n%s\n", url)), nil
+}
+
+// ignitionGenerator renders Ignition configs.
+type ignitionGenerator struct{}
+
+func (ignitionGenerator) InitControlPlane(input *cloudinit.ControlPlaneInput) ([]byte, error) {
+	return ignition.NewInitControlPlane(input)
+}
+
+func (ignitionGenerator) JoinControlPlane(input *cloudinit.ControlPlaneJoinInput) ([]byte, error) {
+	return ignition.NewJoinControlPlane(input)
+}
+
+func (ignitionGenerator) Node(input *cloudinit.NodeInput) ([]byte, error) {
+	return ignition.NewNode(input)
+}
+
+func (ignitionGenerator) Include(url string) ([]byte, error) {
+	return ignition.NewReplace(url)
+}
+
+// windowsGenerator renders the PowerShell user data of Windows worker machines.
+type windowsGenerator struct{}
+
+func (windowsGenerator) InitControlPlane(input *cloudinit.ControlPlaneInput) ([]byte, error) {
+	return nil, newBootstrapFailure(invalidConfigurationReason, "the windows format does not support control plane machines")
+}
+
+func (windowsGenerator) JoinControlPlane(input *cloudinit.ControlPlaneJoinInput) ([]byte, error) {
+	return nil, newBootstrapFailure(invalidConfigurationReason, "the windows format does not support control plane machines")
+}
+
+func (windowsGenerator) Node(input *cloudinit.NodeInput) ([]byte, error) {
+	return windows.NewNode(input)
+}
+
+func (windowsGenerator) Include(url string) ([]byte, error) {
+	return windows.NewInclude(url), nil
+}
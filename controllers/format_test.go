@@ -0,0 +1,175 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	cabpkV1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/feature"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestBootstrapGenerator(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	if _, err := bootstrapGenerator(config); err != nil {
+		t.Fatalf("expected the default format to be enabled, got %v", err)
+	}
+	if format := configFormat(config); format != cabpkV1alpha2.FormatCloudConfig {
+		t.Fatalf("expected the format to default to %s, got %s", cabpkV1alpha2.FormatCloudConfig, format)
+	}
+
+	config.Spec.Format = cabpkV1alpha2.FormatIgnition
+	if _, err := bootstrapGenerator(config); err == nil {
+		t.Fatal("expected an error with the Ignition feature gate disabled")
+	}
+
+	if err := feature.Gates.Set("Ignition=true"); err != nil {
+		t.Fatalf("Failed to enable the feature gate:\n %+v", err)
+	}
+	defer feature.Gates.Set("Ignition=false")
+	if _, err := bootstrapGenerator(config); err != nil {
+		t.Fatalf("expected the ignition format to be enabled, got %v", err)
+	}
+
+	config.Spec.Format = "talos"
+	if _, err := bootstrapGenerator(config); err == nil {
+		t.Fatal("expected an error for a format without generator")
+	}
+}
+
+// fakeGenerator renders the bootstrap data as the name of the generated input.
+type fakeGenerator struct{}
+
+func (fakeGenerator) InitControlPlane(input *cloudinit.ControlPlaneInput) ([]byte, error) {
+	return []byte("init"), nil
+}
+
+func (fakeGenerator) JoinControlPlane(input *cloudinit.ControlPlaneJoinInput) ([]byte, error) {
+	return []byte("join-control-plane"), nil
+}
+
+func (fakeGenerator) Node(input *cloudinit.NodeInput) ([]byte, error) {
+	return []byte("node"), nil
+}
+
+func (fakeGenerator) Include(url string) ([]byte, error) {
+	return []byte("include " + url), nil
+}
+
+func TestRegisterBootstrapGenerator(t *testing.T) {
+	RegisterBootstrapGenerator("fake", fakeGenerator{})
+	defer func() {
+		bootstrapGeneratorsMu.Lock()
+		delete(bootstrapGenerators, "fake")
+		bootstrapGeneratorsMu.Unlock()
+	}()
+
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.Format = "fake"
+	data, err := newNodeData(config, &cloudinit.NodeInput{})
+	if err != nil {
+		t.Fatalf("Failed to generate the bootstrap data:\n %+v", err)
+	}
+	if string(data) != "node" {
+		t.Fatalf("expected the bootstrap data of the registered generator, got %q", string(data))
+	}
+	data, err = includeBootstrapData(config, "https://10.0.0.2:9443/default/cfg")
+	if err != nil {
+		t.Fatalf("Failed to generate the bootstrap data:\n %+v", err)
+	}
+	if string(data) != "include https://10.0.0.2:9443/default/cfg" {
+		t.Fatalf("expected the bootstrap data of the registered generator, got %q", string(data))
+	}
+}
+
+func TestNewNodeDataWithIgnitionFormat(t *testing.T) {
+	if err := feature.Gates.Set("Ignition=true"); err != nil {
+		t.Fatalf("Failed to enable the feature gate:\n %+v", err)
+	}
+	defer feature.Gates.Set("Ignition=false")
+
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.Format = cabpkV1alpha2.FormatIgnition
+	data, err := newNodeData(config, &cloudinit.NodeInput{JoinConfiguration: "kind: JoinConfiguration"})
+	if err != nil {
+		t.Fatalf("Failed to generate the bootstrap data:\n %+v", err)
+	}
+	if format := bootstrapDataFormat(data); format != "ignition" {
+		t.Fatalf("expected an Ignition config, got %s:\n%s", format, string(data))
+	}
+
+	config.Spec.Format = ""
+	data, err = newNodeData(config, &cloudinit.NodeInput{JoinConfiguration: "kind: JoinConfiguration"})
+	if err != nil {
+		t.Fatalf("Failed to generate the bootstrap data:\n %+v", err)
+	}
+	if format := bootstrapDataFormat(data); format != "cloud-config" {
+		t.Fatalf("expected a cloud-config, got %s:\n%s", format, string(data))
+	}
+}
+
+func TestNewDataWithWindowsFormat(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.Format = cabpkV1alpha2.FormatWindows
+	if _, err := newNodeData(config, &cloudinit.NodeInput{}); err == nil {
+		t.Fatal("expected an error with the Windows feature gate disabled")
+	}
+
+	if err := feature.Gates.Set("Windows=true"); err != nil {
+		t.Fatalf("Failed to enable the feature gate:\n %+v", err)
+	}
+	defer feature.Gates.Set("Windows=false")
+
+	input := &cloudinit.NodeInput{JoinConfiguration: "kind: JoinConfiguration"}
+	data, err := newNodeData(config, input)
+	if err != nil {
+		t.Fatalf("Failed to generate the bootstrap data:\n %+v", err)
+	}
+	if format := bootstrapDataFormat(data); format != "windows" {
+		t.Fatalf("expected PowerShell user data, got %s:\n%s", format, string(data))
+	}
+	if input.Manifest == nil {
+		t.Fatal("expected the manifest of the Windows user data")
+	}
+
+	_, err = newJoinControlPlaneData(config, &cloudinit.ControlPlaneJoinInput{})
+	if failure, ok := err.(*bootstrapFailure); !ok || failure.reason != invalidConfigurationReason {
+		t.Fatalf("expected control plane machines to be rejected as an invalid configuration, got %v", err)
+	}
+}
+
+func TestSetBootstrapDataTokenOnlyWithIgnitionFormat(t *testing.T) {
+	config := newTokenOnlyKubeadmConfig("cfg", "abcdef.0123456789abcdef")
+	config.Spec.Format = cabpkV1alpha2.FormatIgnition
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme()),
+	}
+	if err := k.setBootstrapData(context.Background(), newCluster("cluster"), config, []byte(`{"ignition":{"version":"3.0.0"}}`)); err != nil {
+		t.Fatalf("Failed to set bootstrap data:\n %+v", err)
+	}
+	data := string(config.Status.BootstrapData)
+	if !strings.HasPrefix(data, `{"ignition":{"version":"3.0.0","config":{"replace":{"source":"`) || !strings.Contains(data, "token=abcdef.0123456789abcdef") {
+		t.Fatalf("expected an Ignition config replaced by the served one, got %s", data)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+var (
+	// cisAPIServerArgs are the API server flags required by the CIS Kubernetes Benchmark that kubeadm does
+	// not already set.
+	cisAPIServerArgs = map[string]string{
+		"profiling":              "false",
+		"service-account-lookup": "true",
+	}
+
+	// cisControllerManagerArgs are the controller manager flags required by the CIS Kubernetes Benchmark that
+	// kubeadm does not already set.
+	cisControllerManagerArgs = map[string]string{
+		"profiling":                   "false",
+		"terminated-pod-gc-threshold": "10",
+	}
+
+	// cisSchedulerArgs are the scheduler flags required by the CIS Kubernetes Benchmark that kubeadm does not
+	// already set.
+	cisSchedulerArgs = map[string]string{
+		"profiling": "false",
+	}
+
+	// cisKubeletArgs are the kubelet flags required by the CIS Kubernetes Benchmark that kubeadm does not
+	// already set. protect-kernel-defaults relies on the kernel settings applied by the cloudinit package.
+	cisKubeletArgs = map[string]string{
+		"event-qps":                         "0",
+		"make-iptables-util-chains":         "true",
+		"protect-kernel-defaults":           "true",
+		"read-only-port":                    "0",
+		"streaming-connection-idle-timeout": "5m",
+	}
+)
+
+// hardenClusterConfiguration defaults the control plane component flags required by the hardening profile.
+// Control planes joining the cluster run with the flags of the ClusterConfiguration stored by kubeadm init.
+func hardenClusterConfiguration(profile cabpkv1alpha2.HardeningProfile, cfg *kubeadmv1beta1.ClusterConfiguration) {
+	if profile != cabpkv1alpha2.HardeningProfileCIS {
+		return
+	}
+	cfg.APIServer.ExtraArgs = defaultArgs(cfg.APIServer.ExtraArgs, cisAPIServerArgs)
+	cfg.ControllerManager.ExtraArgs = defaultArgs(cfg.ControllerManager.ExtraArgs, cisControllerManagerArgs)
+	cfg.Scheduler.ExtraArgs = defaultArgs(cfg.Scheduler.ExtraArgs, cisSchedulerArgs)
+}
+
+// hardenNodeRegistration defaults the kubelet flags required by the hardening profile.
+func hardenNodeRegistration(profile cabpkv1alpha2.HardeningProfile, nodeRegistration *kubeadmv1beta1.NodeRegistrationOptions) {
+	if profile != cabpkv1alpha2.HardeningProfileCIS {
+		return
+	}
+	nodeRegistration.KubeletExtraArgs = defaultArgs(nodeRegistration.KubeletExtraArgs, cisKubeletArgs)
+}
+
+// defaultArgs sets in args the defaults that are not already set.
+func defaultArgs(args, defaults map[string]string) map[string]string {
+	if args == nil {
+		args = map[string]string{}
+	}
+	for k, v := range defaults {
+		if _, ok := args[k]; !ok {
+			args[k] = v
+		}
+	}
+	return args
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+func TestHardenClusterConfiguration(t *testing.T) {
+	cfg := &kubeadmv1beta1.ClusterConfiguration{}
+	cfg.APIServer.ExtraArgs = map[string]string{"profiling": "true"}
+
+	hardenClusterConfiguration(cabpkv1alpha2.HardeningProfileCIS, cfg)
+
+	if cfg.APIServer.ExtraArgs["profiling"] != "true" {
+		t.Fatalf("expected the API server flags of the config to take precedence, got %v", cfg.APIServer.ExtraArgs)
+	}
+	if cfg.APIServer.ExtraArgs["service-account-lookup"] != "true" {
+		t.Fatalf("expected the API server flags to be defaulted, got %v", cfg.APIServer.ExtraArgs)
+	}
+	if cfg.ControllerManager.ExtraArgs["terminated-pod-gc-threshold"] != "10" {
+		t.Fatalf("expected the controller manager flags to be defaulted, got %v", cfg.ControllerManager.ExtraArgs)
+	}
+	if cfg.Scheduler.ExtraArgs["profiling"] != "false" {
+		t.Fatalf("expected the scheduler flags to be defaulted, got %v", cfg.Scheduler.ExtraArgs)
+	}
+}
+
+func TestHardenNodeRegistration(t *testing.T) {
+	nodeRegistration := &kubeadmv1beta1.NodeRegistrationOptions{}
+
+	hardenNodeRegistration("", nodeRegistration)
+	if nodeRegistration.KubeletExtraArgs != nil {
+		t.Fatalf("expected no kubelet flags without hardening profile, got %v", nodeRegistration.KubeletExtraArgs)
+	}
+
+	hardenNodeRegistration(cabpkv1alpha2.HardeningProfileCIS, nodeRegistration)
+	if nodeRegistration.KubeletExtraArgs["protect-kernel-defaults"] != "true" || nodeRegistration.KubeletExtraArgs["read-only-port"] != "0" {
+		t.Fatalf("expected the kubelet flags to be defaulted, got %v", nodeRegistration.KubeletExtraArgs)
+	}
+}
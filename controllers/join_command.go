@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+const (
+	// PublishJoinCommandAnnotationKey, when set to "true" on a Cluster, makes the joining configs of the cluster
+	// publish the kubeadm join command of their bootstrap token in a secret, so that operators can manually join an
+	// out-of-band machine to the cluster.
+	PublishJoinCommandAnnotationKey = "bootstrap.cluster.x-k8s.io/publish-join-command"
+)
+
+// JoinCommandSecretName returns the name of the secret holding the join command of a config, given its name.
+func JoinCommandSecretName(configName string) string {
+	return fmt.Sprintf("%s-join-command", configName)
+}
+
+// reconcileJoinCommand writes the kubeadm join command of the bootstrap token discovery of config to its join
+// command secret, if the cluster has the PublishJoinCommandAnnotationKey. The command verifies the cluster CA
+// unless its certificate can't be found.
+func (r *KubeadmConfigReconciler) reconcileJoinCommand(ctx context.Context, cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig) error {
+	if cluster.GetAnnotations()[PublishJoinCommandAnnotationKey] != "true" || config.Spec.JoinConfiguration == nil {
+		return nil
+	}
+	discovery := config.Spec.JoinConfiguration.Discovery.BootstrapToken
+	if discovery == nil || discovery.Token == "" {
+		return nil
+	}
+
+	caCertHash, err := r.clusterCACertHash(ctx, cluster.GetName(), config.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	name := JoinCommandSecretName(config.GetName())
+	if err := r.writeBootstrapDataSecret(ctx, config, name, []byte(joinCommand(discovery, caCertHash)), nil); err != nil {
+		return err
+	}
+	config.Status.JoinCommandSecretName = &name
+	return nil
+}
+
+// clusterCACertHash returns the public key hash of the cluster CA, or "" if its certificate can't be found.
+func (r *KubeadmConfigReconciler) clusterCACertHash(ctx context.Context, clusterName, namespace string) (string, error) {
+	certificates, err := r.getClusterCertificates(ctx, clusterName, namespace)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the cluster certificates")
+	}
+	caCertHash, err := certificates.ClusterCA.PublicKeyHash()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to hash the cluster CA public key")
+	}
+	return caCertHash, nil
+}
+
+// joinCommand returns the kubeadm join command of a worker using the given bootstrap token discovery, verifying the
+// cluster CA by the given public key hash unless it is empty.
+func joinCommand(discovery *kubeadmv1beta1.BootstrapTokenDiscovery, caCertHash string) string {
+	command := fmt.Sprintf("kubeadm join %s --token %s", discovery.APIServerEndpoint, discovery.Token)
+	if caCertHash == "" {
+		return command + " --discovery-token-unsafe-skip-ca-verification"
+	}
+	return command + " --discovery-token-ca-cert-hash " + caCertHash
+}
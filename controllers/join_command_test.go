@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func newJoiningKubeadmConfig(name string) *cabpkv1alpha2.KubeadmConfig {
+	config := newKubeadmConfig(nil, name)
+	config.Spec.JoinConfiguration = &kubeadmv1beta1.JoinConfiguration{
+		Discovery: kubeadmv1beta1.Discovery{
+			BootstrapToken: &kubeadmv1beta1.BootstrapTokenDiscovery{
+				APIServerEndpoint: "10.0.0.1:6443",
+				Token:             "abcdef.0123456789abcdef",
+			},
+		},
+	}
+	return config
+}
+
+func TestReconcileJoinCommand(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Annotations = map[string]string{PublishJoinCommandAnnotationKey: "true"}
+	config := newJoiningKubeadmConfig("cfg")
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme()),
+	}
+	certificates, err := k.createClusterCertificates(context.Background(), cluster, config)
+	if err != nil {
+		t.Fatalf("Failed to create the cluster certificates:\n %+v", err)
+	}
+	hash, err := certificates.ClusterCA.PublicKeyHash()
+	if err != nil {
+		t.Fatalf("Failed to hash the cluster CA:\n %+v", err)
+	}
+
+	if err := k.reconcileJoinCommand(context.Background(), cluster, config); err != nil {
+		t.Fatalf("Failed to reconcile the join command:\n %+v", err)
+	}
+	if config.Status.JoinCommandSecretName == nil || *config.Status.JoinCommandSecretName != JoinCommandSecretName("cfg") {
+		t.Fatalf("expected the join command secret name to be recorded, got %v", config.Status.JoinCommandSecretName)
+	}
+	secret := &corev1.Secret{}
+	if err := k.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: JoinCommandSecretName("cfg")}, secret); err != nil {
+		t.Fatalf("Failed to get the join command secret:\n %+v", err)
+	}
+	expected := "kubeadm join 10.0.0.1:6443 --token abcdef.0123456789abcdef --discovery-token-ca-cert-hash " + hash
+	if command := string(secret.Data[bootstrapDataSecretKey]); command != expected {
+		t.Fatalf("expected join command %q, got %q", expected, command)
+	}
+}
+
+func TestReconcileJoinCommandWithoutClusterCertificates(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Annotations = map[string]string{PublishJoinCommandAnnotationKey: "true"}
+	config := newJoiningKubeadmConfig("cfg")
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme()),
+	}
+	if err := k.reconcileJoinCommand(context.Background(), cluster, config); err != nil {
+		t.Fatalf("Failed to reconcile the join command:\n %+v", err)
+	}
+	secret := &corev1.Secret{}
+	if err := k.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: JoinCommandSecretName("cfg")}, secret); err != nil {
+		t.Fatalf("Failed to get the join command secret:\n %+v", err)
+	}
+	if command := string(secret.Data[bootstrapDataSecretKey]); !strings.HasSuffix(command, " --discovery-token-unsafe-skip-ca-verification") {
+		t.Fatalf("expected the join command to skip the CA verification, got %q", command)
+	}
+}
+
+func TestReconcileJoinCommandWithoutAnnotation(t *testing.T) {
+	config := newJoiningKubeadmConfig("cfg")
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme()),
+	}
+	if err := k.reconcileJoinCommand(context.Background(), newCluster("cluster"), config); err != nil {
+		t.Fatalf("Failed to reconcile the join command:\n %+v", err)
+	}
+	if config.Status.JoinCommandSecretName != nil {
+		t.Fatalf("expected no join command secret, got %s", *config.Status.JoinCommandSecretName)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/yaml"
+)
+
+// patchKubeadmConfiguration applies the config patches targeting kind to data, the YAML of a kubeadm configuration
+// document of that kind.
+func patchKubeadmConfiguration(config *cabpkv1alpha2.KubeadmConfig, kind, data string) (string, error) {
+	var doc []byte
+	for i, p := range config.Spec.KubeadmConfigPatches {
+		if p.Kind != kind {
+			continue
+		}
+		if doc == nil {
+			var err error
+			if doc, err = yaml.YAMLToJSON([]byte(data)); err != nil {
+				return "", errors.Wrapf(err, "failed to convert %s to JSON", kind)
+			}
+		}
+
+		patched, err := applyKubeadmConfigPatch(p, doc)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to apply kubeadmConfigPatches[%d]", i)
+		}
+		doc = patched
+	}
+	if doc == nil {
+		return data, nil
+	}
+
+	out, err := yaml.JSONToYAML(doc)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to convert patched %s to YAML", kind)
+	}
+	return string(out), nil
+}
+
+func applyKubeadmConfigPatch(p cabpkv1alpha2.KubeadmConfigPatch, doc []byte) ([]byte, error) {
+	patchJSON, err := yaml.YAMLToJSON([]byte(p.Patch))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert patch to JSON")
+	}
+
+	if p.Type == cabpkv1alpha2.KubeadmConfigMergePatch {
+		return jsonpatch.MergePatch(doc, patchJSON)
+	}
+	patch, err := jsonpatch.DecodePatch(patchJSON)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode JSON patch")
+	}
+	return patch.Apply(doc)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	cabpkV1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+func TestPatchKubeadmConfiguration(t *testing.T) {
+	clusterConfiguration := `apiVersion: kubeadm.k8s.io/v1beta1
+kind: ClusterConfiguration
+apiServer:
+  extraArgs:
+    audit-log-maxage: "30"
+`
+
+	testcases := []struct {
+		name      string
+		patches   []cabpkV1alpha2.KubeadmConfigPatch
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "no patches",
+			expected: clusterConfiguration,
+		},
+		{
+			name: "patches of other kinds are ignored",
+			patches: []cabpkV1alpha2.KubeadmConfigPatch{
+				{
+					Kind:  "JoinConfiguration",
+					Patch: `[{"op": "remove", "path": "/apiServer"}]`,
+				},
+			},
+			expected: clusterConfiguration,
+		},
+		{
+			name: "JSON patch",
+			patches: []cabpkV1alpha2.KubeadmConfigPatch{
+				{
+					Kind:  "ClusterConfiguration",
+					Patch: "- op: replace\n  path: /apiServer/extraArgs/audit-log-maxage\n  value: \"7\"",
+				},
+			},
+			expected: `apiServer:
+  extraArgs:
+    audit-log-maxage: "7"
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: ClusterConfiguration
+`,
+		},
+		{
+			name: "merge patches applied in order",
+			patches: []cabpkV1alpha2.KubeadmConfigPatch{
+				{
+					Kind:  "ClusterConfiguration",
+					Type:  cabpkV1alpha2.KubeadmConfigMergePatch,
+					Patch: `{"apiServer": {"extraArgs": {"audit-log-maxage": null, "audit-log-path": "-"}}}`,
+				},
+				{
+					Kind:  "ClusterConfiguration",
+					Type:  cabpkV1alpha2.KubeadmConfigMergePatch,
+					Patch: `{"apiServer": {"extraArgs": {"audit-log-path": "/var/log/audit.log"}}}`,
+				},
+			},
+			expected: `apiServer:
+  extraArgs:
+    audit-log-path: /var/log/audit.log
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: ClusterConfiguration
+`,
+		},
+		{
+			name: "failing patch",
+			patches: []cabpkV1alpha2.KubeadmConfigPatch{
+				{
+					Kind:  "ClusterConfiguration",
+					Patch: `[{"op": "remove", "path": "/controllerManager"}]`,
+				},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := newKubeadmConfig(nil, "cfg")
+			config.Spec.KubeadmConfigPatches = tc.patches
+
+			out, err := patchKubeadmConfiguration(config, "ClusterConfiguration", clusterConfiguration)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected nil, got error %v", err)
+			}
+			if out != tc.expected {
+				t.Fatalf("\nout:\n%s\nexpected:\n%s\n", out, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	kubeadmv1beta2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta2"
+)
+
+// kubeadmV1beta2MinorVersion is the first Kubernetes minor version whose kubeadm reads kubeadm.k8s.io/v1beta2.
+const kubeadmV1beta2MinorVersion = 15
+
+// kubeadmGroupVersion returns the kubeadm config API version read by the kubeadm of the given Kubernetes version,
+// or the empty group version if the version can't be parsed.
+func kubeadmGroupVersion(version string) schema.GroupVersion {
+	m := minorVersionRegexp.FindStringSubmatch(version)
+	if m == nil {
+		return schema.GroupVersion{}
+	}
+	minor, err := strconv.Atoi(m[1])
+	if err != nil {
+		return schema.GroupVersion{}
+	}
+	if minor < kubeadmV1beta2MinorVersion {
+		return kubeadmv1beta1.GroupVersion
+	}
+	return kubeadmv1beta2.GroupVersion
+}
+
+// kubeadmConfigurationToYAML returns the YAML of obj, an embedded kubeadm configuration, rendered with the kubeadm
+// config API version of the machine's Kubernetes version. Without a machine version, the apiVersion of obj is used,
+// and configurations without either are rendered as v1beta1.
+func kubeadmConfigurationToYAML(obj runtime.Object, version string) (string, error) {
+	gv := kubeadmGroupVersion(version)
+	if gv.Empty() {
+		gv = obj.GetObjectKind().GroupVersionKind().GroupVersion()
+	}
+
+	switch gv {
+	case schema.GroupVersion{}, kubeadmv1beta1.GroupVersion:
+		return kubeadmv1beta1.ConfigurationToYAML(obj)
+	case kubeadmv1beta2.GroupVersion:
+		converted, err := kubeadmv1beta2.ConvertFromV1beta1(obj)
+		if err != nil {
+			return "", err
+		}
+		return kubeadmv1beta2.ConfigurationToYAML(converted)
+	default:
+		return "", errors.Errorf("unsupported kubeadm configuration apiVersion %q", gv)
+	}
+}
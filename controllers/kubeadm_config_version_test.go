@@ -0,0 +1,125 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+func TestKubeadmConfigurationToYAML(t *testing.T) {
+	testcases := []struct {
+		name       string
+		apiVersion string
+		version    string
+		expected   string
+		expectErr  bool
+	}{
+		{
+			name:     "no apiVersion",
+			expected: "apiVersion: kubeadm.k8s.io/v1beta1\n",
+		},
+		{
+			name:       "v1beta1",
+			apiVersion: "kubeadm.k8s.io/v1beta1",
+			expected:   "apiVersion: kubeadm.k8s.io/v1beta1\n",
+		},
+		{
+			name:       "v1beta2",
+			apiVersion: "kubeadm.k8s.io/v1beta2",
+			expected:   "apiVersion: kubeadm.k8s.io/v1beta2\n",
+		},
+		{
+			name:     "v1.14 machine",
+			version:  "v1.14.6",
+			expected: "apiVersion: kubeadm.k8s.io/v1beta1\n",
+		},
+		{
+			name:     "v1.15 machine",
+			version:  "v1.15.3",
+			expected: "apiVersion: kubeadm.k8s.io/v1beta2\n",
+		},
+		{
+			name:       "machine version overriding the apiVersion",
+			apiVersion: "kubeadm.k8s.io/v1beta2",
+			version:    "v1.14.6",
+			expected:   "apiVersion: kubeadm.k8s.io/v1beta1\n",
+		},
+		{
+			name:       "unparsable machine version",
+			apiVersion: "kubeadm.k8s.io/v1beta2",
+			version:    "latest",
+			expected:   "apiVersion: kubeadm.k8s.io/v1beta2\n",
+		},
+		{
+			name:       "unsupported",
+			apiVersion: "kubeadm.k8s.io/v1alpha3",
+			expectErr:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			join := &kubeadmv1beta1.JoinConfiguration{
+				TypeMeta:         metav1.TypeMeta{APIVersion: tc.apiVersion, Kind: "JoinConfiguration"},
+				NodeRegistration: kubeadmv1beta1.NodeRegistrationOptions{Name: "node"},
+			}
+
+			data, err := kubeadmConfigurationToYAML(join, tc.version)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected nil, got error %v", err)
+			}
+			if !strings.Contains(data, tc.expected) {
+				t.Errorf("expected %q in:\n%s", tc.expected, data)
+			}
+			if !strings.Contains(data, "name: node\n") {
+				t.Errorf("expected the node registration in:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestKubeadmGroupVersion(t *testing.T) {
+	testcases := []struct {
+		version  string
+		expected string
+	}{
+		{version: "v1.13.0", expected: "kubeadm.k8s.io/v1beta1"},
+		{version: "1.14.6", expected: "kubeadm.k8s.io/v1beta1"},
+		{version: "v1.15.0", expected: "kubeadm.k8s.io/v1beta2"},
+		{version: "v1.16.2", expected: "kubeadm.k8s.io/v1beta2"},
+		{version: "", expected: ""},
+		{version: "stable", expected: ""},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.version, func(t *testing.T) {
+			if gv := kubeadmGroupVersion(tc.version).String(); gv != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, gv)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/cluster-api/pkg/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// kubeadmFailureReportScript is the name of the script, in the generated files directory, reporting the end of
+	// the kubeadm output to the workload cluster.
+	kubeadmFailureReportScript = "report-kubeadm-failure.sh"
+
+	// kubeadmFailureReportCA is the name of the cluster CA certificate, in the generated files directory, the
+	// report script verifies the workload cluster with.
+	kubeadmFailureReportCA = "kubeadm-failure-report-ca.crt"
+
+	// kubeadmFailureReportKey is the key of the report ConfigMap holding the end of the kubeadm output.
+	kubeadmFailureReportKey = "kubeadm.log"
+
+	// kubeadmFailureReporterName is the name of the Role and RoleBinding of the workload cluster allowing the
+	// bootstrap tokens to report kubeadm failures.
+	kubeadmFailureReporterName = "kubeadm:kubeadm-failure-reporter"
+
+	// bootstrapTokenGroup is the group of the bootstrap tokens the controller generates.
+	bootstrapTokenGroup = "system:bootstrappers:kubeadm:default-node-token"
+
+	defaultKubeadmFailureReportMaxBytes = 4096
+)
+
+// KubeadmFailureReportName returns the name of the ConfigMap of the workload cluster, in kube-system, a machine
+// reports the kubeadm failure of its config to, given its name.
+func KubeadmFailureReportName(configName string) string {
+	return fmt.Sprintf("%s-kubeadm-failure", configName)
+}
+
+// KubeadmFailureReports define behaviour for reading the kubeadm failures machines report to their workload cluster
+type KubeadmFailureReports interface {
+	// Allow grants the bootstrap tokens of the cluster the right to report kubeadm failures
+	Allow(client.Client, *capiv1alpha2.Cluster) error
+	// Get returns the kubeadm output reported under the given name, nil if none was reported
+	Get(client.Client, *capiv1alpha2.Cluster, string) ([]byte, error)
+}
+
+// ClusterKubeadmFailureReports reads the kubeadm failures reported to ConfigMaps of the workload clusters
+type ClusterKubeadmFailureReports struct {
+	// Options tune the clients of the workload clusters.
+	Options WorkloadClusterClientOptions
+}
+
+// Allow grants the bootstrap tokens of the cluster the right to create ConfigMaps in kube-system, where the machines
+// report kubeadm failures. Create requests can not be restricted to resource names, so the existing ConfigMaps stay
+// out of reach of the tokens, but not the name of new ones.
+func (f ClusterKubeadmFailureReports) Allow(c client.Client, cluster *capiv1alpha2.Cluster) error {
+	clientset, err := f.clientset(c, cluster)
+	if err != nil {
+		return err
+	}
+
+	objectMeta := metav1.ObjectMeta{Name: kubeadmFailureReporterName, Namespace: metav1.NamespaceSystem}
+	role := &rbacv1.Role{
+		ObjectMeta: objectMeta,
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"create"},
+		}},
+	}
+	if _, err := clientset.RbacV1().Roles(metav1.NamespaceSystem).Create(role); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create the kubeadm failure reporter role of cluster %s/%s", cluster.GetNamespace(), cluster.GetName())
+	}
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: objectMeta,
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: role.Name},
+		Subjects:   []rbacv1.Subject{{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: bootstrapTokenGroup}},
+	}
+	if _, err := clientset.RbacV1().RoleBindings(metav1.NamespaceSystem).Create(binding); err != nil && !apierrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create the kubeadm failure reporter role binding of cluster %s/%s", cluster.GetNamespace(), cluster.GetName())
+	}
+	return nil
+}
+
+// Get returns the kubeadm output reported to the named ConfigMap of kube-system, nil if it does not exist.
+func (f ClusterKubeadmFailureReports) Get(c client.Client, cluster *capiv1alpha2.Cluster, name string) ([]byte, error) {
+	clientset, err := f.clientset(c, cluster)
+	if err != nil {
+		return nil, err
+	}
+	configMap, err := clientset.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the kubeadm failure report %q of cluster %s/%s", name, cluster.GetNamespace(), cluster.GetName())
+	}
+	if data, ok := configMap.BinaryData[kubeadmFailureReportKey]; ok {
+		return data, nil
+	}
+	return []byte(configMap.Data[kubeadmFailureReportKey]), nil
+}
+
+func (f ClusterKubeadmFailureReports) clientset(c client.Client, cluster *capiv1alpha2.Cluster) (kubernetes.Interface, error) {
+	config, err := f.Options.RESTConfig(c, cluster)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the client of the workload cluster")
+	}
+	return clientset, nil
+}
+
+// kubeadmFailureReport returns the files reporting a failed kubeadm join of config to the workload cluster, and the
+// hook running them with the kubeadm log. The report authenticates with the bootstrap token of the join and verifies
+// the cluster CA, so it is skipped for other discoveries, the replicas of machine pools, which share the config, and
+// when the cluster certificates are not found.
+func (r *KubeadmConfigReconciler) kubeadmFailureReport(ctx context.Context, cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig, machine *capiv1alpha2.Machine) ([]cabpkv1alpha2.Files, string, error) {
+	token := joinToken(config)
+	if config.Spec.KubeadmFailureReport == nil || token == "" || isMachinePoolMachine(machine) {
+		return nil, "", nil
+	}
+	certificates, err := r.getClusterCertificates(ctx, cluster.GetName(), config.GetNamespace())
+	if apierrors.IsNotFound(err) {
+		r.Log.Info("Not reporting kubeadm failures, the cluster certificates are not found", "kubeadmconfig", config.Namespace+"/"+config.Name)
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to get the cluster certificates")
+	}
+	if err := r.KubeadmFailureReports.Allow(r.Client, cluster); err != nil {
+		return nil, "", err
+	}
+
+	maxBytes := config.Spec.KubeadmFailureReport.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultKubeadmFailureReportMaxBytes
+	}
+	generatedFilesDir := config.Spec.GeneratedFilesDir
+	if generatedFilesDir == "" {
+		generatedFilesDir = cabpkv1alpha2.DefaultGeneratedFilesDir
+	}
+	caPath := path.Join(generatedFilesDir, kubeadmFailureReportCA)
+
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n# Reports the end of the kubeadm output, in the log given as argument, to the workload cluster.\n")
+	fmt.Fprintf(&script, "data=$(tail -c %d \"$1\" | base64 | tr -d '\\n')\n", maxBytes)
+	fmt.Fprintf(&script, "curl -sS --retry 5 --cacert %s -X POST -H \"Authorization: Bearer %s\" -H \"Content-Type: application/json\" \\\n", caPath, token)
+	fmt.Fprintf(&script, "  --data \"{\\\"apiVersion\\\":\\\"v1\\\",\\\"kind\\\":\\\"ConfigMap\\\",\\\"metadata\\\":{\\\"name\\\":\\\"%s\\\"},\\\"binaryData\\\":{\\\"%s\\\":\\\"$data\\\"}}\" \\\n",
+		KubeadmFailureReportName(config.GetName()), kubeadmFailureReportKey)
+	fmt.Fprintf(&script, "  https://%s/api/v1/namespaces/%s/configmaps\n", config.Spec.JoinConfiguration.Discovery.BootstrapToken.APIServerEndpoint, metav1.NamespaceSystem)
+
+	scriptPath := path.Join(generatedFilesDir, kubeadmFailureReportScript)
+	files := []cabpkv1alpha2.Files{
+		{
+			Path:        caPath,
+			Owner:       "root:root",
+			Permissions: "0644",
+			Content:     string(certificates.ClusterCA.TrustBundle()),
+		},
+		{
+			Path:        scriptPath,
+			Owner:       "root:root",
+			Permissions: "0700",
+			Content:     script.String(),
+		},
+	}
+	return files, "sh " + scriptPath, nil
+}
+
+// reconcileKubeadmFailureReport copies the kubeadm failure the machine of a ready join config reported to the
+// workload cluster to the KubeadmFailed condition of the config, until the machine registers its node.
+func (r *KubeadmConfigReconciler) reconcileKubeadmFailureReport(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) error {
+	if config.Spec.KubeadmFailureReport == nil || joinToken(config) == "" || config.Status.BootstrapTokenRevoked ||
+		conditionTrue(&config.Status, cabpkv1alpha2.KubeadmFailedCondition) {
+		return nil
+	}
+
+	machine, err := util.GetOwnerMachine(ctx, r.Client, config.ObjectMeta)
+	if err != nil || machine == nil || machine.Status.NodeRef != nil {
+		return err
+	}
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, machine.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	report, err := r.KubeadmFailureReports.Get(r.Client, cluster, KubeadmFailureReportName(config.GetName()))
+	if err != nil || report == nil {
+		return err
+	}
+
+	patchConfig := client.MergeFrom(config.DeepCopy())
+	setCondition(&config.Status, cabpkv1alpha2.KubeadmConfigCondition{
+		Type:    cabpkv1alpha2.KubeadmFailedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "KubeadmJoinFailed",
+		Message: string(report),
+	}, time.Now())
+	if err := r.Status().Patch(ctx, config, patchConfig); err != nil {
+		return errors.Wrap(err, "failed to record the kubeadm failure")
+	}
+	r.recordEventf(config, machine, corev1.EventTypeWarning, "KubeadmFailed",
+		"kubeadm join failed on the machine, its output is in the %s condition and %s/%s of the workload cluster",
+		cabpkv1alpha2.KubeadmFailedCondition, metav1.NamespaceSystem, KubeadmFailureReportName(config.GetName()))
+	return nil
+}
@@ -19,24 +19,32 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
 	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
 	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/feature"
 	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
 	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
 	capierrors "sigs.k8s.io/cluster-api/pkg/errors"
 	"sigs.k8s.io/cluster-api/pkg/util"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
@@ -48,8 +56,62 @@ const (
 // KubeadmConfigReconciler reconciles a KubeadmConfig object
 type KubeadmConfigReconciler struct {
 	client.Client
-	SecretsClientFactory SecretsClientFactory
-	Log                  logr.Logger
+	SecretsClientFactory     SecretsClientFactory
+	ControlPlaneHealthProber ControlPlaneHealthProber
+	SSHPusher                SSHPusher
+	Log                      logr.Logger
+
+	// ControlPlaneInitLocker holds the control plane init lock of the clusters, and is released on request by
+	// the ReleaseInitLockAnnotationKey. Defaults to a ConfigMap based lock in SetupWithManager, or a Lease based
+	// lock if ControlPlaneInitLockLeaseDuration is set.
+	ControlPlaneInitLocker ControlPlaneInitLocker
+	// ControlPlaneInitLockLeaseDuration, if set, is the duration of the Lease based control plane init lock, which
+	// the holder loses to the next control plane machine if it is not renewed within it, e.g. after its config
+	// was deleted.
+	ControlPlaneInitLockLeaseDuration time.Duration
+	// KubeadmFailureReports reads the kubeadm failures the machines of the configs with a KubeadmFailureReport
+	// report to their workload cluster.
+	KubeadmFailureReports KubeadmFailureReports
+	// Recorder records the events audited by the reconciler. Defaults to the recorder of the manager in
+	// SetupWithManager.
+	Recorder record.EventRecorder
+
+	// ControlPlaneInitTimeout is how long the machine holding the control plane init lock has to register its
+	// node before the lock is handed off. Defaults to DefaultControlPlaneInitTimeout.
+	ControlPlaneInitTimeout time.Duration
+
+	// DisableCertificateGeneration requires the certificates of all clusters to be provided rather than
+	// generated, as DisableCertificateGenerationAnnotationKey does for a single cluster.
+	DisableCertificateGeneration bool
+
+	// CertificatesExpiryWarningWindow is how long before the CertificatesExpiry of a config its
+	// CertificatesExpiring condition is set. Defaults to DefaultCertificatesExpiryWarningWindow.
+	CertificatesExpiryWarningWindow time.Duration
+
+	// CertificateKeyType is the type of the keys generated for the CAs and service account key pair of the
+	// clusters, as CertificateKeyTypeAnnotationKey sets for a single cluster. Defaults to certs.DefaultKeyType.
+	CertificateKeyType certs.KeyType
+
+	// CACertificateValidity is how long the CA certificates generated for the clusters are valid, as
+	// CACertificateValidityAnnotationKey sets for a single cluster. Defaults to certs.DefaultCAValidity.
+	CACertificateValidity time.Duration
+
+	// BootstrapDataSecret also writes the bootstrap data to a Secret named after the config and referenced by
+	// its status.dataSecretName, so that infrastructure providers reading either keep working.
+	BootstrapDataSecret bool
+
+	// BootstrapDataSecretOnly writes the bootstrap data to the Secret referenced by status.dataSecretName without
+	// storing it in status.bootstrapData as well, where anyone allowed to read KubeadmConfigs can see the keys and
+	// tokens it embeds. It implies BootstrapDataSecret.
+	BootstrapDataSecretOnly bool
+
+	// BootstrapDataSecretReaders, if set, are granted read access to each bootstrap data Secret, and to no other
+	// Secret, by a Role and RoleBinding owned by the config, so that the infrastructure provider consuming the
+	// bootstrap data need not be allowed to read every Secret of the management cluster.
+	BootstrapDataSecretReaders []rbacv1.Subject
+
+	// certificatesCache caches the certificates of the clusters once set up with a manager.
+	certificatesCache *certificatesCache
 }
 
 // SecretsClientFactory define behaviour for creating a secrets client
@@ -63,10 +125,17 @@ func ClusterCertificatesSecretName(clusterName string) string {
 	return fmt.Sprintf("%s-certs", clusterName)
 }
 
+// +kubebuilder:rbac:groups=bootstrap.cluster.x-k8s.io,resources=bootstrapsettings,verbs=get;list;watch
 // +kubebuilder:rbac:groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=bootstrap.cluster.x-k8s.io,resources=kubeadmconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;machines,verbs=get;list;watch
-// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters,verbs=patch
+// +kubebuilder:rbac:groups=exp.cluster.x-k8s.io,resources=machinepools,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update
 
 // Reconcile TODO
 func (r *KubeadmConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, rerr error) {
@@ -75,6 +144,18 @@ func (r *KubeadmConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, re
 	log := r.Log.WithValues("kubeadmconfig", req.NamespacedName)
 
 	config := &cabpkv1alpha2.KubeadmConfig{}
+	defer func() {
+		if rerr == nil {
+			return
+		}
+		category := reconcileErrorCategory(rerr)
+		reconcileErrorsTotal.WithLabelValues(category).Inc()
+		// requeues wait on other controllers and conflicts are retried, neither is worth an event
+		if category != "requeue" && category != "conflict" && config.Name != "" {
+			r.recordEventf(config, nil, corev1.EventTypeWarning, "ReconcileFailed", "Failed to reconcile: %v", rerr)
+		}
+	}()
+
 	if err := r.Get(ctx, req.NamespacedName, config); err != nil {
 		if apierrors.IsNotFound(err) {
 			return ctrl.Result{}, nil
@@ -83,17 +164,80 @@ func (r *KubeadmConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, re
 		return ctrl.Result{}, err
 	}
 
-	// bail super early if it's already ready
-	if config.Status.Ready {
-		log.Info("ignoring an already ready config")
+	if err := r.reconcileObjectStorageCleanup(ctx, config); err != nil {
+		log.Error(err, "failed to delete the bootstrap data object")
+		return ctrl.Result{}, err
+	}
+	if config.GetDeletionTimestamp() != nil {
 		return ctrl.Result{}, nil
 	}
 
+	// bail super early if it's already ready, unless the bootstrap data still has to be pushed to the machine
+	if config.Status.Ready {
+		if config.Spec.Delivery == nil || config.Spec.Delivery.SSH == nil || config.Status.Delivered {
+			handoffAfter, err := r.reconcileInitLockHandoff(ctx, config)
+			if err != nil {
+				log.Error(err, "failed to hand off the control plane init lock")
+				return ctrl.Result{}, err
+			}
+			if !config.Status.Ready {
+				return ctrl.Result{}, nil
+			}
+			if err := r.reconcileControlPlaneInitializedCondition(ctx, config); err != nil {
+				log.Error(err, "failed to set the ControlPlaneInitialized condition")
+				return ctrl.Result{}, err
+			}
+
+			tokenAfter, err := r.reconcileBootstrapToken(ctx, config)
+			if err != nil {
+				log.Error(err, "failed to reconcile the bootstrap token")
+				return ctrl.Result{}, err
+			}
+			if err := r.reconcileKubeadmFailureReport(ctx, config); err != nil {
+				log.Error(err, "failed to reconcile the kubeadm failure report")
+				return ctrl.Result{}, err
+			}
+
+			log.Info("ignoring an already ready config")
+			result, err := r.reconcileCertificatesExpiring(ctx, config)
+			for _, after := range []time.Duration{handoffAfter, tokenAfter} {
+				if after > 0 && (result.RequeueAfter == 0 || after < result.RequeueAfter) {
+					result.RequeueAfter = after
+				}
+			}
+			return result, err
+		}
+
+		patchConfig := client.MergeFrom(config.DeepCopy())
+		if err := r.reconcileDelivery(ctx, config); err != nil {
+			log.Error(err, "failed to deliver bootstrap data")
+			return ctrl.Result{}, err
+		}
+		config.Status.Delivered = true
+		log.Info("Delivered BootstrapData to the machine")
+		return ctrl.Result{}, r.Status().Patch(ctx, config, patchConfig)
+	}
+
 	machine, err := util.GetOwnerMachine(ctx, r.Client, config.ObjectMeta)
 	if err != nil {
 		log.Error(err, "could not get owner machine")
 		return ctrl.Result{}, err
 	}
+	if machine == nil {
+		// the replicas of a MachinePool share the bootstrap data of the config
+		pool, err := getOwnerMachinePool(ctx, r.Client, config.ObjectMeta)
+		if err != nil {
+			log.Error(err, "could not get owner machine pool")
+			return ctrl.Result{}, err
+		}
+		if pool != nil {
+			machine, err = machinePoolMachine(pool)
+			if err != nil {
+				log.Error(err, "could not get the machine template of the owner machine pool")
+				return ctrl.Result{}, err
+			}
+		}
+	}
 	if machine == nil {
 		log.Info("Waiting for Machine Controller to set OwnerRef on the KubeadmConfig")
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
@@ -110,6 +254,11 @@ func (r *KubeadmConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, re
 		return ctrl.Result{}, err
 	}
 
+	if err := r.reconcileInitLockRelease(ctx, cluster); err != nil {
+		log.Error(err, "failed to release the control plane init lock")
+		return ctrl.Result{}, err
+	}
+
 	// Check for infrastructure ready. If it's not ready then we will requeue the machine until it is.
 	// The cluster-api machine controller set this value.
 	if cluster.Status.InfrastructureReady != true {
@@ -126,10 +275,98 @@ func (r *KubeadmConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, re
 			rerr = err
 		}
 	}()
+	// terminal failures are recorded on the config rather than retried, the config is reconciled again once changed
+	config.Status.FailureReason = ""
+	config.Status.FailureMessage = ""
+	defer func() {
+		failure, ok := errors.Cause(rerr).(*bootstrapFailure)
+		if !ok {
+			return
+		}
+		log.Error(rerr, "failed to generate bootstrap data", "reason", failure.reason)
+		config.Status.FailureReason = failure.reason
+		config.Status.FailureMessage = failure.message
+		r.recordEventf(config, machine, corev1.EventTypeWarning, "BootstrapFailed", "%s", failure.message)
+		rerr = nil
+	}()
+
+	if err := reconcileTraceID(config); err != nil {
+		log.Error(err, "failed to generate the trace ID of the config")
+		return ctrl.Result{}, err
+	}
+	log = log.WithValues("traceID", config.Status.TraceID)
+	if !hasCondition(&config.Status, cabpkv1alpha2.DataSecretAvailableCondition) {
+		clearDataSecretAvailableCondition(config, "BootstrapDataNotGenerated", "the bootstrap data is not generated yet")
+	}
+
+	if err := resolveVariant(config, machine); err != nil {
+		log.Error(err, "failed to resolve the variant of the config")
+		return ctrl.Result{}, err
+	}
+	if isMachinePoolMachine(machine) {
+		if err := validateMachinePoolConfig(config, machine); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	missing, err := r.reconcileReferences(ctx, config)
+	if err != nil {
+		log.Error(err, "failed to check the objects referenced by the config")
+		return ctrl.Result{}, err
+	}
+	if len(missing) > 0 {
+		log.Info("Waiting for the objects referenced by the config", "missing", missing)
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	packageRepositories, err := r.resolvePackageRepositories(ctx, config)
+	if err != nil {
+		log.Error(err, "failed to resolve the GPG keys of the package repositories")
+		return ctrl.Result{}, err
+	}
+	trustedCABundles, err := r.resolveTrustedCABundles(ctx, config)
+	if err != nil {
+		log.Error(err, "failed to resolve the trusted CA bundles")
+		return ctrl.Result{}, err
+	}
+	registryCredentials, err := r.resolveRegistryCredentials(ctx, config)
+	if err != nil {
+		log.Error(err, "failed to resolve the registry credentials")
+		return ctrl.Result{}, err
+	}
+
+	files, err := r.resolveFiles(ctx, config, config.Spec.AdditionalUserDataFiles)
+	if err != nil {
+		log.Error(err, "failed to resolve the content of the additional user data files")
+		return ctrl.Result{}, err
+	}
+	osConditionals, err := r.resolveOSConditionals(ctx, config)
+	if err != nil {
+		log.Error(err, "failed to resolve the content of the files of the OS conditionals")
+		return ctrl.Result{}, err
+	}
+	multipart, err := r.resolveMultipart(ctx, config)
+	if err != nil {
+		log.Error(err, "failed to resolve the content of the MIME parts")
+		return ctrl.Result{}, err
+	}
+
+	settings, err := r.resolveBootstrapSettings(ctx, cluster)
+	if err != nil {
+		log.Error(err, "failed to resolve the bootstrap settings of the cluster")
+		return ctrl.Result{}, err
+	}
 
 	// Check for control plane ready. If it's not ready then we will requeue the machine until it is.
-	// The cluster-api machine controller set this value.
-	if cluster.Annotations[ControlPlaneReadyAnnotationKey] != "true" {
+	// The cluster-api machine controller set this value, unless the machine that initialized the cluster is
+	// observed with a node first.
+	initialized, err := r.controlPlaneInitialized(ctx, cluster)
+	if err != nil {
+		log.Error(err, "failed to check whether the control plane is initialized")
+		return ctrl.Result{}, err
+	}
+	setControlPlaneInitializedCondition(config, cluster.GetName(), initialized)
+	if !initialized {
 		// if it's NOT a control plane machine, requeue
 		if !util.IsControlPlaneMachine(machine) {
 			log.Info("Control plane is not ready, requeing worker nodes until ready.")
@@ -142,7 +379,25 @@ func (r *KubeadmConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, re
 			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 		}
 
-		//TODO(fp) use init lock so only the first machine configured as control plane get processed, everything else gets requeued
+		// only the first machine configured as control plane gets processed, everything else gets requeued
+		// unless the machine holding the lock was deleted
+		if !r.acquireInitLock(cluster, machine, config) {
+			if !canHoldInitLock(machine) {
+				log.Info("Not initializing the control plane from a machine that failed or is being deleted.")
+				return ctrl.Result{}, nil
+			}
+			released, err := r.releaseDeletedInitLockHolder(ctx, cluster)
+			if err != nil {
+				log.Error(err, "failed to release the control plane init lock of a deleted machine")
+				return ctrl.Result{}, err
+			}
+			if !released || !r.acquireInitLock(cluster, machine, config) {
+				log.Info("A control plane is already being initialized, requeing until ready.")
+				return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+			}
+		}
+		r.recordEventf(config, machine, corev1.EventTypeNormal, "InitLockAcquired",
+			"Acquired the control plane init lock of cluster %s", cluster.GetName())
 
 		// otherwise it is a init control plane
 		// Nb. in this case JoinConfiguration should not be defined by users, but in case of misconfigurations, CABPK simply ignore it
@@ -161,12 +416,27 @@ func (r *KubeadmConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, re
 				},
 			}
 		}
-		initdata, err := kubeadmv1beta1.ConfigurationToYAML(config.Spec.InitConfiguration)
+		hardenNodeRegistration(config.Spec.HardeningProfile, &config.Spec.InitConfiguration.NodeRegistration)
+		initdata, err := kubeadmConfigurationToYAML(config.Spec.InitConfiguration, machineVersion(machine))
 		if err != nil {
 			log.Error(err, "failed to marshal init configuration")
 			return ctrl.Result{}, err
 		}
-
+		initdata, err = setControlPlaneTaints(config, "InitConfiguration", initdata)
+		if err != nil {
+			log.Error(err, "failed to set the control plane taints of the init configuration")
+			return ctrl.Result{}, err
+		}
+		initdata, err = setStartupTaint(config, "InitConfiguration", initdata, true)
+		if err != nil {
+			log.Error(err, "failed to set the startup taint of the init configuration")
+			return ctrl.Result{}, err
+		}
+		initdata, err = patchKubeadmConfiguration(config, "InitConfiguration", initdata)
+		if err != nil {
+			log.Error(err, "failed to patch init configuration")
+			return ctrl.Result{}, err
+		}
 		if config.Spec.ClusterConfiguration == nil {
 			config.Spec.ClusterConfiguration = &kubeadmv1beta1.ClusterConfiguration{
 				TypeMeta: v1.TypeMeta{
@@ -184,40 +454,147 @@ func (r *KubeadmConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, re
 			log.Info("Altering ClusterConfiguration", "ControlPlaneEndpoint", config.Spec.ClusterConfiguration.ControlPlaneEndpoint)
 		}
 
-		clusterdata, err := kubeadmv1beta1.ConfigurationToYAML(config.Spec.ClusterConfiguration)
+		wireClusterNetwork(cluster, config.Spec.ClusterConfiguration)
+		wireAPIServerCertSANs(cluster, config.Spec.ClusterConfiguration)
+		dns, err := initKubeletDNS(&config.Spec, config.Spec.ClusterConfiguration)
+		if err != nil {
+			log.Error(err, "failed to compute the DNS of the kubelets")
+			return ctrl.Result{}, err
+		}
+		initdata, err = appendKubeletConfiguration(&config.Spec, dns, initdata)
+		if err != nil {
+			log.Error(err, "failed to render the kubelet configuration")
+			return ctrl.Result{}, err
+		}
+
+		if config.Spec.ClusterConfiguration.ImageRepository == "" && settings.ImageRepository != "" {
+			config.Spec.ClusterConfiguration.ImageRepository = settings.ImageRepository
+			log.Info("Altering ClusterConfiguration", "ImageRepository", config.Spec.ClusterConfiguration.ImageRepository)
+		}
+
+		preKubeadmCommands, err := controlPlaneEndpointHostCommands(config, config.Spec.ClusterConfiguration.ControlPlaneEndpoint)
+		if err != nil {
+			log.Error(err, "failed to pin the control plane endpoint host")
+			return ctrl.Result{}, err
+		}
+
+		// the flags and files generated from the spec are only rendered into the bootstrap data; writing them back
+		// to the spec would have the update of the config refused by the validation of those very fields
+		clusterConfiguration := config.Spec.ClusterConfiguration.DeepCopy()
+		hardenClusterConfiguration(config.Spec.HardeningProfile, clusterConfiguration)
+		wireAdmission(&config.Spec, clusterConfiguration)
+		wireComponentConfigurations(&config.Spec, clusterConfiguration)
+		wireEtcdTuning(&config.Spec, clusterConfiguration)
+		wireEtcdDisk(&config.Spec, clusterConfiguration)
+		clusterdata, err := kubeadmConfigurationToYAML(clusterConfiguration, machineVersion(machine))
 		if err != nil {
 			log.Error(err, "failed to marshal cluster configuration")
 			return ctrl.Result{}, err
 		}
+		clusterdata, err = patchKubeadmConfiguration(config, "ClusterConfiguration", clusterdata)
+		if err != nil {
+			log.Error(err, "failed to patch cluster configuration")
+			return ctrl.Result{}, err
+		}
 
 		certificates, err := r.getClusterCertificates(ctx, cluster.GetName(), config.GetNamespace())
-		if err != nil {
-			if apierrors.IsNotFound(err) {
-				certificates, err = r.createClusterCertificates(ctx, cluster.GetName(), config)
-				if err != nil {
-					log.Error(err, "unable to create cluster certificates")
-					return ctrl.Result{}, err
-				}
-			} else {
-				log.Error(err, "unable to lookup cluster certificates")
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "unable to lookup cluster certificates")
+			return ctrl.Result{}, err
+		}
+		certificatesReason := "CertificatesFound"
+		if r.certificateGenerationDisabled(cluster) {
+			certificatesReason = "CertificatesProvided"
+			if !reconcileProvidedCertificates(config, cluster.GetName(), certificates) {
+				setCertificatesAvailableCondition(config, false, "CertificatesNotProvided",
+					fmt.Sprintf("waiting for the certificates of cluster %s to be provided", cluster.GetName()))
+				log.Info("Certificate generation is disabled, requeing until the cluster certificates are provided.")
+				return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+			}
+		} else if certificates == nil {
+			certificates, err = r.createClusterCertificates(ctx, cluster, config)
+			if err != nil {
+				log.Error(err, "unable to create cluster certificates")
+				return ctrl.Result{}, err
+			}
+			certificatesReason = "CertificatesGenerated"
+			r.recordEventf(config, machine, corev1.EventTypeNormal, "CertificatesGenerated",
+				"Generated the certificates of cluster %s", cluster.GetName())
+		} else if certificates.ExternalClusterCA() {
+			if err := r.completeExternalCACertificates(ctx, cluster, config, certificates); err != nil {
+				log.Error(err, "unable to complete the certificates of the external cluster CA")
 				return ctrl.Result{}, err
 			}
 		}
+		setCertificatesAvailableCondition(config, true, certificatesReason,
+			fmt.Sprintf("the certificates of cluster %s are available", cluster.GetName()))
+		if err := r.recordCertificatesExpiry(config, cluster.GetName(), certificates); err != nil {
+			log.Error(err, "failed to record the expiry of the cluster certificates")
+			return ctrl.Result{}, err
+		}
+
+		version := machineVersion(machine)
+		if version == "" {
+			version = config.Spec.ClusterConfiguration.KubernetesVersion
+		}
+		generatedFiles, err := controlPlaneFiles(config, version)
+		if err != nil {
+			log.Error(err, "failed to generate the control plane files")
+			return ctrl.Result{}, err
+		}
+		taintFiles, taintCommands := startupTaintRemoval(config, config.Spec.InitConfiguration.NodeRegistration.Name)
+		generatedFiles = append(generatedFiles, taintFiles...)
 
-		cloudInitData, err := cloudinit.NewInitControlPlane(&cloudinit.ControlPlaneInput{
+		input := &cloudinit.ControlPlaneInput{
 			BaseUserData: cloudinit.BaseUserData{
-				AdditionalFiles: config.Spec.AdditionalUserDataFiles,
+				GeneratedFilesDir:   config.Spec.GeneratedFilesDir,
+				KubeadmContainer:    config.Spec.KubeadmContainer,
+				Verbosity:           config.Spec.Verbosity,
+				KeepKubeadmOutput:   config.Spec.KubeadmFailureReport != nil,
+				KubernetesVersion:   machineVersion(machine),
+				ImagePreflight:      config.Spec.ImagePreflight,
+				OSConditionals:      osConditionals,
+				Architecture:        machine.Labels[cabpkv1alpha2.ArchitectureLabel],
+				Artifacts:           config.Spec.Artifacts,
+				BinaryInstall:       config.Spec.BinaryInstall,
+				PackageRepositories: packageRepositories,
+				TrustedCABundles:    trustedCABundles,
+				HardeningProfile:    config.Spec.HardeningProfile,
+				AdditionalFiles:     append(generatedFiles, files...),
+				Multipart:           multipart,
+				ExtraCloudConfig:    config.Spec.AdditionalCloudConfig,
+				PreKubeadmCommands:  append(preKubeadmCommands, config.Spec.PreKubeadmCommands...),
+				AdditionalCommands:  postKubeadmCommands(config, taintCommands),
+				Proxy:               proxy(config, cluster, settings),
+				RegistryMirrors:     registryMirrors(config, settings),
+				RegistryCredentials: registryCredentials,
+				NTP:                 ntp(config, settings),
+				Users:               config.Spec.Users,
+				TraceID:             config.Status.TraceID,
+				EtcdDisk:            config.Spec.EtcdDisk,
+				DiskSetup:           config.Spec.DiskSetup,
+				Mounts:              config.Spec.Mounts,
 			},
 			InitConfiguration:    string(initdata),
 			ClusterConfiguration: string(clusterdata),
 			Certificates:         *certificates,
-		})
+		}
+		cloudInitData, err := newInitControlPlaneData(config, input)
 		if err != nil {
 			log.Error(err, "failed to generate cloud init for bootstrap control plane")
 			return ctrl.Result{}, err
 		}
+		if err := recordBootstrapDataManifest(config, input.Manifest); err != nil {
+			log.Error(err, "failed to record the bootstrap data manifest")
+			return ctrl.Result{}, err
+		}
 
-		config.Status.BootstrapData = cloudInitData
+		if err := r.setBootstrapData(ctx, cluster, config, cloudInitData); err != nil {
+			log.Error(err, "failed to set bootstrap data for bootstrap control plane")
+			return ctrl.Result{}, err
+		}
+		bootstrapDataGenerationsTotal.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), bootstrapDataRoleInit).Inc()
+		r.recordEventf(config, machine, corev1.EventTypeNormal, "BootstrapDataGenerated", "Generated the bootstrap data of the init control plane")
 		config.Status.Ready = true
 		return ctrl.Result{}, nil
 	}
@@ -225,88 +602,301 @@ func (r *KubeadmConfigReconciler) Reconcile(req ctrl.Request) (_ ctrl.Result, re
 	// Every other case it's a join scenario
 	// Nb. in this case ClusterConfiguration and JoinConfiguration should not be defined by users, but in case of misconfigurations, CABPK simply ignore them
 
+	// control plane machines of a pool share the config of the machine that initialized the cluster
+	if util.IsControlPlaneMachine(machine) {
+		defaultControlPlaneJoinConfiguration(config)
+	}
+
 	if config.Spec.JoinConfiguration == nil {
-		return ctrl.Result{}, errors.New("Control plane already exists for the cluster, only KubeadmConfig objects with JoinConfiguration are allowed")
+		return ctrl.Result{}, newBootstrapFailure(invalidConfigurationReason, "Control plane already exists for the cluster, only KubeadmConfig objects with JoinConfiguration are allowed")
+	}
+
+	// if requested, hold the worker join data until the control plane is healthy, so that bootstrap tokens
+	// are not created (and do not expire) while the control plane is still coming up
+	if !util.IsControlPlaneMachine(machine) {
+		if err := r.reconcileControlPlaneHealthGate(cluster, config); err != nil {
+			if requeueErr, ok := errors.Cause(err).(capierrors.HasRequeueAfterError); ok {
+				log.Info(err.Error())
+				return ctrl.Result{RequeueAfter: requeueErr.GetRequeueAfter()}, nil
+			}
+			return ctrl.Result{}, err
+		}
+	}
+
+	// workers with a retained identity discover the cluster with it instead of a bootstrap token
+	var identityFiles []cabpkv1alpha2.Files
+	if !util.IsControlPlaneMachine(machine) {
+		identityFiles, err = r.reconcileRetainedIdentity(ctx, cluster, config)
+		if err != nil {
+			if requeueErr, ok := errors.Cause(err).(capierrors.HasRequeueAfterError); ok {
+				log.Info(err.Error())
+				return ctrl.Result{RequeueAfter: requeueErr.GetRequeueAfter()}, nil
+			}
+			log.Error(err, "failed to reconcile the retained identity")
+			return ctrl.Result{}, err
+		}
 	}
 
 	// ensure that joinConfiguration.Discovery is properly set for joining node on the current cluster
-	if err := r.reconcileDiscovery(cluster, config); err != nil {
+	if err := r.reconcileDiscovery(cluster, config, bootstrapTokenTTL(settings)); err != nil {
 		if requeueErr, ok := errors.Cause(err).(capierrors.HasRequeueAfterError); ok {
 			log.Info(err.Error())
 			return ctrl.Result{RequeueAfter: requeueErr.GetRequeueAfter()}, nil
 		}
 		return ctrl.Result{}, err
 	}
+	if err := r.reconcileJoinCommand(ctx, cluster, config); err != nil {
+		log.Error(err, "failed to publish the join command")
+		return ctrl.Result{}, err
+	}
 
-	joinBytes, err := kubeadmv1beta1.ConfigurationToYAML(config.Spec.JoinConfiguration)
+	dns, err := joinKubeletDNS(&config.Spec, cluster)
+	if err != nil {
+		log.Error(err, "failed to compute the DNS of the kubelet")
+		return ctrl.Result{}, err
+	}
+	setJoinKubeletDNS(&config.Spec.JoinConfiguration.NodeRegistration, dns)
+	hardenNodeRegistration(config.Spec.HardeningProfile, &config.Spec.JoinConfiguration.NodeRegistration)
+	joinBytes, err := kubeadmConfigurationToYAML(config.Spec.JoinConfiguration, machineVersion(machine))
 	if err != nil {
 		log.Error(err, "failed to marshal join configuration")
 		return ctrl.Result{}, err
 	}
+	if util.IsControlPlaneMachine(machine) {
+		joinBytes, err = setControlPlaneTaints(config, "JoinConfiguration", joinBytes)
+		if err != nil {
+			log.Error(err, "failed to set the control plane taints of the join configuration")
+			return ctrl.Result{}, err
+		}
+	}
+	joinBytes, err = setStartupTaint(config, "JoinConfiguration", joinBytes, util.IsControlPlaneMachine(machine))
+	if err != nil {
+		log.Error(err, "failed to set the startup taint of the join configuration")
+		return ctrl.Result{}, err
+	}
+	joinBytes, err = patchKubeadmConfiguration(config, "JoinConfiguration", joinBytes)
+	if err != nil {
+		log.Error(err, "failed to patch join configuration")
+		return ctrl.Result{}, err
+	}
+
+	var discoveryEndpoint string
+	if config.Spec.JoinConfiguration.Discovery.BootstrapToken != nil {
+		discoveryEndpoint = config.Spec.JoinConfiguration.Discovery.BootstrapToken.APIServerEndpoint
+	}
+	preKubeadmCommands, err := controlPlaneEndpointHostCommands(config, discoveryEndpoint)
+	if err != nil {
+		log.Error(err, "failed to pin the control plane endpoint host")
+		return ctrl.Result{}, err
+	}
+
+	taintFiles, taintCommands := startupTaintRemoval(config, config.Spec.JoinConfiguration.NodeRegistration.Name)
+	reportFiles, reportHook, err := r.kubeadmFailureReport(ctx, cluster, config, machine)
+	if err != nil {
+		log.Error(err, "failed to set up the kubeadm failure report")
+		return ctrl.Result{}, err
+	}
+	taintFiles = append(taintFiles, reportFiles...)
 
 	//TODO(fp) remove init lock
 
 	// it's a control plane join
 	if util.IsControlPlaneMachine(machine) {
 		if config.Spec.JoinConfiguration.ControlPlane == nil {
-			return ctrl.Result{}, errors.New("Machine is a ControlPlane, but JoinConfiguration.ControlPlane is not set in the KubeadmConfig object")
+			return ctrl.Result{}, newBootstrapFailure(invalidConfigurationReason, "Machine is a ControlPlane, but JoinConfiguration.ControlPlane is not set in the KubeadmConfig object")
 		}
 
-		certificates, err := r.getClusterCertificates(ctx, cluster.GetName(), config.GetNamespace())
+		// new control plane machines pick up the trust bundles and signing CAs of the current CA rotation phase
+		certificates, err := r.reconcileCARotation(ctx, cluster, config)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				setCertificatesAvailableCondition(config, false, "CertificatesNotFound",
+					fmt.Sprintf("the certificates of cluster %s are not found", cluster.GetName()))
+			}
+			log.Error(err, "failed to reconcile the rotation of the cluster certificates")
+			return ctrl.Result{}, err
+		}
+		setCertificatesAvailableCondition(config, true, "CertificatesFound",
+			fmt.Sprintf("the certificates of cluster %s are available", cluster.GetName()))
+		if err := r.recordCertificatesExpiry(config, cluster.GetName(), certificates); err != nil {
+			log.Error(err, "failed to record the expiry of the cluster certificates")
+			return ctrl.Result{}, err
+		}
+
+		generatedFiles, err := controlPlaneFiles(config, machineVersion(machine))
 		if err != nil {
-			log.Error(err, "unable to locate cluster certificates")
+			log.Error(err, "failed to generate the control plane files")
 			return ctrl.Result{}, err
 		}
+		generatedFiles = append(generatedFiles, taintFiles...)
 
-		joinData, err := cloudinit.NewJoinControlPlane(&cloudinit.ControlPlaneJoinInput{
+		input := &cloudinit.ControlPlaneJoinInput{
 			JoinConfiguration: string(joinBytes),
 			Certificates:      *certificates,
 			BaseUserData: cloudinit.BaseUserData{
-				AdditionalFiles: config.Spec.AdditionalUserDataFiles,
+				GeneratedFilesDir:   config.Spec.GeneratedFilesDir,
+				KubeadmContainer:    config.Spec.KubeadmContainer,
+				Verbosity:           config.Spec.Verbosity,
+				KeepKubeadmOutput:   config.Spec.KubeadmFailureReport != nil,
+				JoinRetry:           config.Spec.JoinRetry,
+				KubeadmFailureHook:  reportHook,
+				KubernetesVersion:   machineVersion(machine),
+				ImagePreflight:      config.Spec.ImagePreflight,
+				OSConditionals:      osConditionals,
+				Architecture:        machine.Labels[cabpkv1alpha2.ArchitectureLabel],
+				Artifacts:           config.Spec.Artifacts,
+				BinaryInstall:       config.Spec.BinaryInstall,
+				PackageRepositories: packageRepositories,
+				TrustedCABundles:    trustedCABundles,
+				HardeningProfile:    config.Spec.HardeningProfile,
+				AdditionalFiles:     append(generatedFiles, files...),
+				Multipart:           multipart,
+				ExtraCloudConfig:    config.Spec.AdditionalCloudConfig,
+				PreKubeadmCommands:  append(preKubeadmCommands, config.Spec.PreKubeadmCommands...),
+				AdditionalCommands:  postKubeadmCommands(config, taintCommands),
+				Proxy:               proxy(config, cluster, settings),
+				RegistryMirrors:     registryMirrors(config, settings),
+				RegistryCredentials: registryCredentials,
+				NTP:                 ntp(config, settings),
+				Users:               config.Spec.Users,
+				TraceID:             config.Status.TraceID,
+				EtcdDisk:            config.Spec.EtcdDisk,
+				DiskSetup:           config.Spec.DiskSetup,
+				Mounts:              config.Spec.Mounts,
 			},
-		})
+		}
+		joinData, err := newJoinControlPlaneData(config, input)
 		if err != nil {
 			log.Error(err, "failed to create a control plane join configuration")
 			return ctrl.Result{}, err
 		}
+		if err := recordBootstrapDataManifest(config, input.Manifest); err != nil {
+			log.Error(err, "failed to record the bootstrap data manifest")
+			return ctrl.Result{}, err
+		}
 
-		config.Status.BootstrapData = joinData
+		if err := r.setBootstrapData(ctx, cluster, config, joinData); err != nil {
+			log.Error(err, "failed to set bootstrap data for control plane join")
+			return ctrl.Result{}, err
+		}
+		bootstrapDataGenerationsTotal.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), bootstrapDataRoleControlPlaneJoin).Inc()
+		r.recordEventf(config, machine, corev1.EventTypeNormal, "BootstrapDataGenerated", "Generated the bootstrap data of a joining control plane")
 		config.Status.Ready = true
 		return ctrl.Result{}, nil
 	}
 
 	// otherwise it is a node
 	if config.Spec.JoinConfiguration.ControlPlane != nil {
-		return ctrl.Result{}, errors.New("Machine is a Worker, but JoinConfiguration.ControlPlane is set in the KubeadmConfig object")
+		return ctrl.Result{}, newBootstrapFailure(invalidConfigurationReason, "Machine is a Worker, but JoinConfiguration.ControlPlane is set in the KubeadmConfig object")
 	}
 
-	joinData, err := cloudinit.NewNode(&cloudinit.NodeInput{
+	input := &cloudinit.NodeInput{
 		BaseUserData: cloudinit.BaseUserData{
-			AdditionalFiles: config.Spec.AdditionalUserDataFiles,
+			GeneratedFilesDir:   config.Spec.GeneratedFilesDir,
+			KubeadmContainer:    config.Spec.KubeadmContainer,
+			Verbosity:           config.Spec.Verbosity,
+			KeepKubeadmOutput:   config.Spec.KubeadmFailureReport != nil,
+			JoinRetry:           config.Spec.JoinRetry,
+			KubeadmFailureHook:  reportHook,
+			KubernetesVersion:   machineVersion(machine),
+			ImagePreflight:      config.Spec.ImagePreflight,
+			OSConditionals:      osConditionals,
+			Architecture:        machine.Labels[cabpkv1alpha2.ArchitectureLabel],
+			Artifacts:           config.Spec.Artifacts,
+			BinaryInstall:       config.Spec.BinaryInstall,
+			PackageRepositories: packageRepositories,
+			TrustedCABundles:    trustedCABundles,
+			HardeningProfile:    config.Spec.HardeningProfile,
+			AdditionalFiles:     append(append(taintFiles, identityFiles...), files...),
+			Multipart:           multipart,
+			ExtraCloudConfig:    config.Spec.AdditionalCloudConfig,
+			PreKubeadmCommands:  append(preKubeadmCommands, config.Spec.PreKubeadmCommands...),
+			AdditionalCommands:  postKubeadmCommands(config, taintCommands),
+			Proxy:               proxy(config, cluster, settings),
+			RegistryMirrors:     registryMirrors(config, settings),
+			RegistryCredentials: registryCredentials,
+			NTP:                 ntp(config, settings),
+			Users:               config.Spec.Users,
+			TraceID:             config.Status.TraceID,
+			DiskSetup:           config.Spec.DiskSetup,
+			Mounts:              config.Spec.Mounts,
 		},
 		JoinConfiguration: string(joinBytes),
-	})
+	}
+	joinData, err := newNodeData(config, input)
 	if err != nil {
 		log.Error(err, "failed to create a worker join configuration")
 		return ctrl.Result{}, err
 	}
-	config.Status.BootstrapData = joinData
+	if err := recordBootstrapDataManifest(config, input.Manifest); err != nil {
+		log.Error(err, "failed to record the bootstrap data manifest")
+		return ctrl.Result{}, err
+	}
+	if err := r.setBootstrapData(ctx, cluster, config, joinData); err != nil {
+		log.Error(err, "failed to set bootstrap data for worker join")
+		return ctrl.Result{}, err
+	}
+	bootstrapDataGenerationsTotal.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), bootstrapDataRoleWorkerJoin).Inc()
+	r.recordEventf(config, machine, corev1.EventTypeNormal, "BootstrapDataGenerated", "Generated the bootstrap data of a joining worker")
 	config.Status.Ready = true
 	return ctrl.Result{}, nil
 }
 
 // SetupWithManager TODO
 func (r *KubeadmConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if r.ControlPlaneInitLocker == nil {
+		clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			return errors.Wrap(err, "failed to create the client of the control plane init lock")
+		}
+		if r.ControlPlaneInitLockLeaseDuration > 0 {
+			r.ControlPlaneInitLocker = newControlPlaneInitLeaseLocker(r.Log.WithName("init-locker"), clientset.CoordinationV1(),
+				r.ControlPlaneInitLockLeaseDuration, r.controlPlaneInitTimeout())
+		} else {
+			r.ControlPlaneInitLocker = newControlPlaneInitLocker(r.Log.WithName("init-locker"), clientset.CoreV1())
+		}
+	}
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("kubeadmconfig-controller")
+	}
+	r.certificatesCache = newCertificatesCache()
+
+	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&cabpkv1alpha2.KubeadmConfig{}).
-		Complete(r)
+		Watches(
+			&source.Kind{Type: &capiv1alpha2.Machine{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.machineToKubeadmConfig)},
+		).
+		Watches(
+			&source.Kind{Type: &capiv1alpha2.Machine{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.deletedMachineToKubeadmConfigs)},
+		)
+	if feature.Gates.Enabled(feature.MachinePool) {
+		pool := &unstructured.Unstructured{}
+		pool.SetGroupVersionKind(MachinePoolGroupVersion.WithKind(machinePoolKind))
+		builder = builder.Watches(
+			&source.Kind{Type: pool},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.machinePoolToKubeadmConfig)},
+		)
+	}
+	c, err := builder.Build(r)
+	if err != nil {
+		return err
+	}
+
+	// the builder can't filter the events of a single watch, which the secrets one needs
+	return c.Watch(
+		&source.Kind{Type: &corev1.Secret{}},
+		&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.evictSecretCertificates)},
+		certificatesSecretPredicate,
+	)
 }
 
 // reconcileDiscovery ensure that config.JoinConfiguration.Discovery is properly set for the joining node.
 // The implementation func respect user provided discovery configurations, but in case some of them are missing, a valid BootstrapToken object
 // is automatically injected into config.JoinConfiguration.Discovery.
 // This allows to simplify configuration UX, by providing the option to delegate to CABPK the configuration of kubeadm join discovery.
-func (r *KubeadmConfigReconciler) reconcileDiscovery(cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig) error {
+func (r *KubeadmConfigReconciler) reconcileDiscovery(cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig, tokenTTL time.Duration) error {
 	log := r.Log.WithValues("kubeadmconfig", fmt.Sprintf("%s/%s", config.Namespace, config.Name))
 
 	// if config already contains a file discovery configuration, respect it without further validations
@@ -319,9 +909,18 @@ func (r *KubeadmConfigReconciler) reconcileDiscovery(cluster *capiv1alpha2.Clust
 		config.Spec.JoinConfiguration.Discovery.BootstrapToken = &kubeadmv1beta1.BootstrapTokenDiscovery{}
 	}
 
-	// if BootstrapToken already contains an APIServerEndpoint, respect it; otherwise inject the APIServerEndpoint endpoint defined in cluster status
+	// if BootstrapToken already contains an APIServerEndpoint, respect it; otherwise inject the DiscoveryEndpoint override if defined,
+	// or the APIServerEndpoint endpoint defined in cluster status
 	//TODO(fp) might be we want to validate user provided APIServerEndpoint and warn/error if it doesn't match the api endpoint defined at cluster level
 	apiServerEndpoint := config.Spec.JoinConfiguration.Discovery.BootstrapToken.APIServerEndpoint
+	if apiServerEndpoint == "" && config.Spec.DiscoveryEndpoint != "" {
+		if _, _, err := net.SplitHostPort(config.Spec.DiscoveryEndpoint); err != nil {
+			return errors.Wrapf(err, "invalid DiscoveryEndpoint %q, expected host:port", config.Spec.DiscoveryEndpoint)
+		}
+		apiServerEndpoint = config.Spec.DiscoveryEndpoint
+		config.Spec.JoinConfiguration.Discovery.BootstrapToken.APIServerEndpoint = apiServerEndpoint
+		log.Info("Altering JoinConfiguration.Discovery.BootstrapToken", "APIServerEndpoint", apiServerEndpoint)
+	}
 	if apiServerEndpoint == "" {
 		if len(cluster.Status.APIEndpoints) == 0 {
 			return errors.Wrap(&capierrors.RequeueAfterError{RequeueAfter: 10 * time.Second}, "Waiting for Cluster Controller to set cluster.Status.APIEndpoints")
@@ -341,11 +940,18 @@ func (r *KubeadmConfigReconciler) reconcileDiscovery(cluster *capiv1alpha2.Clust
 			return err
 		}
 
-		token, err := createToken(secretsClient)
+		token, err := createToken(secretsClient, tokenTTL)
 		if err != nil {
+			if isWorkloadClusterUnreachable(err) {
+				return workloadClusterUnreachable(config, "TokenCreationFailed", err)
+			}
 			return errors.Wrapf(err, "failed to create new bootstrap token")
 		}
+		workloadClusterReachable(config)
 
+		bootstrapTokensTotal.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), "create").Inc()
+		r.recordEventf(config, nil, corev1.EventTypeNormal, "BootstrapTokenCreated",
+			"Created a bootstrap token in cluster %s", cluster.GetName())
 		config.Spec.JoinConfiguration.Discovery.BootstrapToken.Token = token
 		log.Info("Altering JoinConfiguration.Discovery.BootstrapToken", "Token", token)
 	}
@@ -360,26 +966,101 @@ func (r *KubeadmConfigReconciler) reconcileDiscovery(cluster *capiv1alpha2.Clust
 	return nil
 }
 
+// reconcileControlPlaneHealthGate probes the control plane endpoint when config.Spec.ControlPlaneHealthGate is set,
+// and returns a RequeueAfterError until the probe succeeded the required number of consecutive times.
+// The count of consecutive successful probes is tracked in config.Status.ControlPlaneHealthChecks.
+func (r *KubeadmConfigReconciler) reconcileControlPlaneHealthGate(cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig) error {
+	gate := config.Spec.ControlPlaneHealthGate
+	if gate == nil {
+		return nil
+	}
+
+	threshold := gate.SuccessThreshold
+	if threshold <= 0 {
+		threshold = defaultControlPlaneHealthSuccessThreshold
+	}
+
+	if config.Status.ControlPlaneHealthChecks >= threshold {
+		return nil
+	}
+
+	if err := r.ControlPlaneHealthProber.Probe(r.Client, cluster); err != nil {
+		config.Status.ControlPlaneHealthChecks = 0
+		return workloadClusterUnreachable(config, "HealthProbeFailed", err)
+	}
+	workloadClusterReachable(config)
+
+	config.Status.ControlPlaneHealthChecks++
+	if config.Status.ControlPlaneHealthChecks < threshold {
+		return errors.Wrapf(&capierrors.RequeueAfterError{RequeueAfter: 5 * time.Second}, "Waiting for the control plane to pass %d consecutive health probes, %d passed", threshold, config.Status.ControlPlaneHealthChecks)
+	}
+	return nil
+}
+
+// machineVersion returns the Kubernetes version of the machine, if set.
+func machineVersion(machine *capiv1alpha2.Machine) string {
+	if machine.Spec.Version == nil {
+		return ""
+	}
+	return *machine.Spec.Version
+}
+
+// controlPlaneEndpointHostCommands returns the commands pinning the host of the given control plane endpoint
+// to config.Spec.ControlPlaneEndpointIP in /etc/hosts, so the node can reach the endpoint even if DNS does not
+// resolve it at first boot. No commands are returned if no IP is configured or the endpoint host is an IP already.
+func controlPlaneEndpointHostCommands(config *cabpkv1alpha2.KubeadmConfig, endpoint string) ([]string, error) {
+	if config.Spec.ControlPlaneEndpointIP == "" || endpoint == "" {
+		return nil, nil
+	}
+
+	if net.ParseIP(config.Spec.ControlPlaneEndpointIP) == nil {
+		return nil, errors.Errorf("invalid ControlPlaneEndpointIP %q, expected an IP address", config.Spec.ControlPlaneEndpointIP)
+	}
+
+	// the port is optional in ClusterConfiguration.ControlPlaneEndpoint
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		host = endpoint
+	}
+	if net.ParseIP(host) != nil {
+		return nil, nil
+	}
+
+	return []string{fmt.Sprintf(`echo "%s %s" >> /etc/hosts`, config.Spec.ControlPlaneEndpointIP, host)}, nil
+}
+
 func (r *KubeadmConfigReconciler) getClusterCertificates(ctx context.Context, clusterName, namespace string) (*certs.Certificates, error) {
-	secret := &corev1.Secret{}
+	key := types.NamespacedName{Name: ClusterCertificatesSecretName(clusterName), Namespace: namespace}
+	if certificates := r.certificatesCache.get(key); certificates != nil {
+		return certificates, nil
+	}
 
-	err := r.Get(ctx, types.NamespacedName{Name: ClusterCertificatesSecretName(clusterName), Namespace: namespace}, secret)
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, key, secret)
 	if err != nil {
 		return nil, err
 	}
+	r.certificatesCache.set(key, secret.Data)
 
 	return certs.NewCertificatesFromMap(secret.Data), nil
 }
 
-func (r *KubeadmConfigReconciler) createClusterCertificates(ctx context.Context, clusterName string, config *cabpkv1alpha2.KubeadmConfig) (*certs.Certificates, error) {
-	certificates, err := certs.NewCertificates()
+func (r *KubeadmConfigReconciler) createClusterCertificates(ctx context.Context, cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig) (*certs.Certificates, error) {
+	opts, err := r.certificateOptions(cluster)
+	if err != nil {
+		return nil, err
+	}
+	certificates, err := certs.NewCertificatesWithOptions(opts)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.setExternalCAs(ctx, config, certificates); err != nil {
+		return nil, err
+	}
 
 	secret := &corev1.Secret{
 		ObjectMeta: v1.ObjectMeta{
-			Name:      ClusterCertificatesSecretName(clusterName),
+			Name:      ClusterCertificatesSecretName(cluster.GetName()),
 			Namespace: config.GetNamespace(),
 			OwnerReferences: []v1.OwnerReference{
 				{
@@ -396,14 +1077,19 @@ func (r *KubeadmConfigReconciler) createClusterCertificates(ctx context.Context,
 	if err := r.Create(ctx, secret); err != nil {
 		return nil, err
 	}
+	r.certificatesCache.evict(types.NamespacedName{Name: secret.GetName(), Namespace: secret.GetNamespace()})
+	certificateGenerationsTotal.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), "generate").Inc()
 
 	return certificates, nil
 }
 
+// patchConfig patches the spec and then the status of config, recording the generation of the patched spec as
+// the observed one.
 func (r *KubeadmConfigReconciler) patchConfig(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, patchConfig client.Patch) error {
 	if err := r.Patch(ctx, config, patchConfig); err != nil {
 		return err
 	}
+	config.Status.ObservedGeneration = config.GetGeneration()
 	if err := r.Status().Patch(ctx, config, patchConfig); err != nil {
 		return err
 	}
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"testing"
@@ -34,6 +35,7 @@ import (
 	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
 	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
 	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	capierrors "sigs.k8s.io/cluster-api/pkg/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -288,8 +290,9 @@ func TestRequeueKubeadmConfigForJoinNodesIfControlPlaneIsNotReady(t *testing.T)
 	myclient := fake.NewFakeClientWithScheme(setupScheme(), objects...)
 
 	k := &KubeadmConfigReconciler{
-		Log:    log.Log,
-		Client: myclient,
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: &fakeInitLocker{},
 	}
 
 	request := ctrl.Request{
@@ -342,8 +345,9 @@ func TestReconcileKubeadmConfigForInitNodesIfControlPlaneIsNotReady(t *testing.T
 	myclient := fake.NewFakeClientWithScheme(setupScheme(), objects...)
 
 	k := &KubeadmConfigReconciler{
-		Log:    log.Log,
-		Client: myclient,
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: &fakeInitLocker{},
 	}
 
 	request := ctrl.Request{
@@ -388,6 +392,60 @@ func TestReconcileKubeadmConfigForInitNodesIfControlPlaneIsNotReady(t *testing.T
 	}
 }
 
+func TestReconcileKubeadmConfigForInitNodesKeepsSpecValid(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg")
+	quota := int64(8589934592)
+	controlPlaneInitConfig.Spec.HardeningProfile = cabpkV1alpha2.HardeningProfileCIS
+	controlPlaneInitConfig.Spec.EtcdTuning = &cabpkV1alpha2.EtcdTuning{QuotaBackendBytes: &quota}
+	controlPlaneInitConfig.Spec.EtcdDisk = &cabpkV1alpha2.EtcdDisk{Device: "/dev/nvme1n1"}
+	controlPlaneInitConfig.Spec.AdmissionPlugins = &cabpkV1alpha2.AdmissionPlugins{Enable: []string{"EventRateLimit"}}
+	if err := controlPlaneInitConfig.ValidateCreate(); err != nil {
+		t.Fatalf("expected the config to be valid, got %v", err)
+	}
+
+	objects := []runtime.Object{
+		cluster,
+		controlPlaneMachine,
+		controlPlaneInitConfig,
+	}
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), objects...)
+
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: &fakeInitLocker{},
+	}
+
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: "default",
+			Name:      "control-plane-init-cfg",
+		},
+	}
+	if _, err := k.Reconcile(request); err != nil {
+		t.Fatal(fmt.Sprintf("Failed to reconcile:\n %+v", err))
+	}
+
+	cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg")
+	if err != nil {
+		t.Fatal(fmt.Sprintf("Failed to reconcile:\n %+v", err))
+	}
+	if cfg.Status.Ready != true {
+		t.Fatal("Expected status ready")
+	}
+	// the reconciled config is patched back through the validating webhook
+	if err := cfg.ValidateUpdate(controlPlaneInitConfig); err != nil {
+		t.Fatalf("expected the reconciled config to be valid, got %v", err)
+	}
+	if cfg.Spec.ClusterConfiguration.Etcd.Local != nil {
+		t.Fatalf("did not expect the generated local etcd configuration to be written to the spec, got %+v", cfg.Spec.ClusterConfiguration.Etcd.Local)
+	}
+}
+
 // Tests for cluster with infrastructure ready, control pane ready
 
 func TestFailIfNotJoinConfigurationAndControlPlaneIsReady(t *testing.T) {
@@ -417,10 +475,7 @@ func TestFailIfNotJoinConfigurationAndControlPlaneIsReady(t *testing.T) {
 			Name:      "worker-join-cfg",
 		},
 	}
-	_, err := k.Reconcile(request)
-	if err == nil {
-		t.Fatal("Expected error, got nil")
-	}
+	expectBootstrapFailure(t, k, request, invalidConfigurationReason)
 }
 
 func TestFailIfJoinConfigurationInconsistentWithMachineRole(t *testing.T) {
@@ -452,9 +507,56 @@ func TestFailIfJoinConfigurationInconsistentWithMachineRole(t *testing.T) {
 			Name:      "control-plane-join-cfg",
 		},
 	}
-	_, err := k.Reconcile(request)
-	if err == nil {
-		t.Fatal("Expected error, got nil")
+	expectBootstrapFailure(t, k, request, invalidConfigurationReason)
+}
+
+// expectBootstrapFailure reconciles the request, expecting the config not to be retried but to record the failure.
+func expectBootstrapFailure(t *testing.T, k *KubeadmConfigReconciler, request ctrl.Request, reason string) {
+	t.Helper()
+	result, err := k.Reconcile(request)
+	if err != nil {
+		t.Fatalf("expected the failure to be recorded rather than retried, got %v", err)
+	}
+	if result.Requeue || result.RequeueAfter != 0 {
+		t.Errorf("expected no requeue, got %+v", result)
+	}
+	cfg, err := getKubeadmConfig(k.Client, request.Name)
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	if cfg.Status.Ready {
+		t.Error("expected the config not to be ready")
+	}
+	if cfg.Status.FailureReason != reason || cfg.Status.FailureMessage == "" {
+		t.Errorf("expected failure reason %s with a message, got %q: %q", reason, cfg.Status.FailureReason, cfg.Status.FailureMessage)
+	}
+}
+
+func TestReconcileRecordsObservedGeneration(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg")
+	controlPlaneInitConfig.Generation = 3
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, controlPlaneMachine, controlPlaneInitConfig)
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: &fakeInitLocker{},
+	}
+	if _, err := k.Reconcile(ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg"},
+	}); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	if !cfg.Status.Ready || cfg.Status.ObservedGeneration != 3 {
+		t.Errorf("expected a ready config observed at generation 3, got ready %t at generation %d", cfg.Status.Ready, cfg.Status.ObservedGeneration)
 	}
 }
 
@@ -605,6 +707,54 @@ func TestReconcileIfJoinNodesAndControlPlaneIsReady(t *testing.T) {
 	}
 }
 
+func TestReconcileRunsPreAndPostKubeadmCommands(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+	cluster.Annotations = map[string]string{ControlPlaneReadyAnnotationKey: "true"}
+	cluster.Status.APIEndpoints = []capiv1alpha2.APIEndpoint{{Host: "100.105.150.1", Port: 6443}}
+
+	workerMachine := newWorkerMachine(cluster, "worker-machine")
+	workerJoinConfig := newWorkerJoinKubeadmConfig(workerMachine, "worker-join-cfg")
+	workerJoinConfig.Spec.PreKubeadmCommands = []string{"modprobe br_netfilter"}
+	workerJoinConfig.Spec.PostKubeadmCommands = []string{"/usr/local/bin/register-node"}
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, workerMachine, workerJoinConfig)
+	certificates, _ := certs.NewCertificates()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ClusterCertificatesSecretName(cluster.GetName()),
+			Namespace: workerJoinConfig.GetNamespace(),
+		},
+		Data: certificates.ToMap(),
+	}
+	_ = myclient.Create(context.Background(), secret)
+
+	k := &KubeadmConfigReconciler{
+		Log:                  log.Log,
+		Client:               myclient,
+		SecretsClientFactory: newFakeSecretFactory(),
+	}
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "worker-join-cfg"}}
+	if _, err := k.Reconcile(request); err != nil {
+		t.Fatal(fmt.Sprintf("Failed to reconcile:\n %+v", err))
+	}
+
+	cfg, err := getKubeadmConfig(myclient, "worker-join-cfg")
+	if err != nil {
+		t.Fatal(fmt.Sprintf("Failed to reconcile:\n %+v", err))
+	}
+	manifest := struct {
+		Commands []string `json:"commands"`
+	}{}
+	if err := json.Unmarshal([]byte(cfg.Annotations[BootstrapDataManifestAnnotationKey]), &manifest); err != nil {
+		t.Fatalf("Failed to unmarshal the bootstrap data manifest:\n %+v", err)
+	}
+	commands := manifest.Commands
+	if len(commands) < 3 || commands[0] != "modprobe br_netfilter" || commands[len(commands)-1] != "/usr/local/bin/register-node" {
+		t.Fatalf("expected the pre and post kubeadm commands around kubeadm join, got %v", commands)
+	}
+}
+
 func TestReconcileDiscoverySuccces(t *testing.T) {
 	k := &KubeadmConfigReconciler{
 		Log:                  log.Log,
@@ -696,6 +846,46 @@ func TestReconcileDiscoverySuccces(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name:    "Use DiscoveryEndpoint override",
+			cluster: goodcluster,
+			config: &cabpkV1alpha2.KubeadmConfig{
+				Spec: cabpkV1alpha2.KubeadmConfigSpec{
+					DiscoveryEndpoint: "internal.foo.com:6443",
+					JoinConfiguration: &kubeadmv1beta1.JoinConfiguration{},
+				},
+			},
+			validateDiscovery: func(c *cabpkV1alpha2.KubeadmConfig) error {
+				d := c.Spec.JoinConfiguration.Discovery
+				if d.BootstrapToken.APIServerEndpoint != "internal.foo.com:6443" {
+					return errors.Errorf("BootstrapToken.APIServerEndpoint=internal.foo.com:6443 expected, got %q", d.BootstrapToken.APIServerEndpoint)
+				}
+				return nil
+			},
+		},
+		{
+			name:    "Respect discoveryConfiguration.BootstrapToken.APIServerEndpoint over DiscoveryEndpoint",
+			cluster: goodcluster,
+			config: &cabpkV1alpha2.KubeadmConfig{
+				Spec: cabpkV1alpha2.KubeadmConfigSpec{
+					DiscoveryEndpoint: "internal.foo.com:6443",
+					JoinConfiguration: &kubeadmv1beta1.JoinConfiguration{
+						Discovery: kubeadmv1beta1.Discovery{
+							BootstrapToken: &kubeadmv1beta1.BootstrapTokenDiscovery{
+								APIServerEndpoint: "bar.com:6443",
+							},
+						},
+					},
+				},
+			},
+			validateDiscovery: func(c *cabpkV1alpha2.KubeadmConfig) error {
+				d := c.Spec.JoinConfiguration.Discovery
+				if d.BootstrapToken.APIServerEndpoint != "bar.com:6443" {
+					return errors.Errorf("BootstrapToken.APIServerEndpoint=bar.com:6443 expected, got %q", d.BootstrapToken.APIServerEndpoint)
+				}
+				return nil
+			},
+		},
 		{
 			name:    "Respect discoveryConfiguration.BootstrapToken.Token",
 			cluster: goodcluster,
@@ -744,7 +934,7 @@ func TestReconcileDiscoverySuccces(t *testing.T) {
 
 	for _, rt := range useCases {
 		t.Run(rt.name, func(t *testing.T) {
-			err := k.reconcileDiscovery(rt.cluster, rt.config)
+			err := k.reconcileDiscovery(rt.cluster, rt.config, defaultTokenTTL)
 			if err != nil {
 				t.Errorf("expected nil, got error %v", err)
 			}
@@ -776,11 +966,25 @@ func TestReconcileDiscoveryErrors(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Fail if DiscoveryEndpoint is not host:port",
+			cluster: &capiv1alpha2.Cluster{
+				Status: capiv1alpha2.ClusterStatus{
+					APIEndpoints: []capiv1alpha2.APIEndpoint{{Host: "foo.com", Port: 6443}},
+				},
+			},
+			config: &cabpkV1alpha2.KubeadmConfig{
+				Spec: cabpkV1alpha2.KubeadmConfigSpec{
+					DiscoveryEndpoint: "internal.foo.com",
+					JoinConfiguration: &kubeadmv1beta1.JoinConfiguration{},
+				},
+			},
+		},
 	}
 
 	for _, rt := range useCases {
 		t.Run(rt.name, func(t *testing.T) {
-			err := k.reconcileDiscovery(rt.cluster, rt.config)
+			err := k.reconcileDiscovery(rt.cluster, rt.config, defaultTokenTTL)
 			if err == nil {
 				t.Error("expected error, got nil")
 			}
@@ -788,6 +992,139 @@ func TestReconcileDiscoveryErrors(t *testing.T) {
 	}
 }
 
+func TestReconcileControlPlaneHealthGate(t *testing.T) {
+	cluster := newCluster("cluster")
+
+	var useCases = []struct {
+		name           string
+		gate           *cabpkV1alpha2.ControlPlaneHealthGate
+		previousChecks int32
+		probeErr       error
+		expectRequeue  bool
+		expectChecks   int32
+	}{
+		{
+			name:          "No gate defined",
+			gate:          nil,
+			expectRequeue: false,
+			expectChecks:  0,
+		},
+		{
+			name:          "First successful probe requeues",
+			gate:          &cabpkV1alpha2.ControlPlaneHealthGate{SuccessThreshold: 2},
+			expectRequeue: true,
+			expectChecks:  1,
+		},
+		{
+			name:           "Threshold reached with the last successful probe",
+			gate:           &cabpkV1alpha2.ControlPlaneHealthGate{SuccessThreshold: 2},
+			previousChecks: 1,
+			expectRequeue:  false,
+			expectChecks:   2,
+		},
+		{
+			name:           "Failed probe resets the consecutive count",
+			gate:           &cabpkV1alpha2.ControlPlaneHealthGate{SuccessThreshold: 2},
+			previousChecks: 1,
+			probeErr:       errors.New("connection refused"),
+			expectRequeue:  true,
+			expectChecks:   0,
+		},
+		{
+			name:           "Default threshold is used when not set",
+			gate:           &cabpkV1alpha2.ControlPlaneHealthGate{},
+			previousChecks: 1,
+			expectRequeue:  true,
+			expectChecks:   2,
+		},
+	}
+
+	for _, rt := range useCases {
+		t.Run(rt.name, func(t *testing.T) {
+			k := &KubeadmConfigReconciler{
+				Log:                      log.Log,
+				ControlPlaneHealthProber: fakeControlPlaneHealthProber{err: rt.probeErr},
+			}
+
+			config := newWorkerJoinKubeadmConfig(nil, "cfg")
+			config.Spec.ControlPlaneHealthGate = rt.gate
+			config.Status.ControlPlaneHealthChecks = rt.previousChecks
+
+			err := k.reconcileControlPlaneHealthGate(cluster, config)
+			if rt.expectRequeue {
+				if _, ok := errors.Cause(err).(capierrors.HasRequeueAfterError); !ok {
+					t.Fatalf("expected a requeue error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("expected nil, got error %v", err)
+			}
+
+			if config.Status.ControlPlaneHealthChecks != rt.expectChecks {
+				t.Fatalf("expected %d consecutive health checks, got %d", rt.expectChecks, config.Status.ControlPlaneHealthChecks)
+			}
+		})
+	}
+}
+
+func TestControlPlaneEndpointHostCommands(t *testing.T) {
+	var useCases = []struct {
+		name         string
+		ip           string
+		endpoint     string
+		expectErr    bool
+		expectedCmds []string
+	}{
+		{
+			name:     "No IP defined",
+			endpoint: "api.foo.com:6443",
+		},
+		{
+			name:         "Pin endpoint with port",
+			ip:           "10.0.0.10",
+			endpoint:     "api.foo.com:6443",
+			expectedCmds: []string{`echo "10.0.0.10 api.foo.com" >> /etc/hosts`},
+		},
+		{
+			name:         "Pin endpoint without port",
+			ip:           "10.0.0.10",
+			endpoint:     "api.foo.com",
+			expectedCmds: []string{`echo "10.0.0.10 api.foo.com" >> /etc/hosts`},
+		},
+		{
+			name:     "Skip endpoint that is already an IP",
+			ip:       "10.0.0.10",
+			endpoint: "10.0.0.11:6443",
+		},
+		{
+			name:      "Fail if the IP is not valid",
+			ip:        "api.foo.com",
+			endpoint:  "api.foo.com:6443",
+			expectErr: true,
+		},
+	}
+
+	for _, rt := range useCases {
+		t.Run(rt.name, func(t *testing.T) {
+			config := newKubeadmConfig(nil, "cfg")
+			config.Spec.ControlPlaneEndpointIP = rt.ip
+
+			cmds, err := controlPlaneEndpointHostCommands(config, rt.endpoint)
+			if rt.expectErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected nil, got error %v", err)
+			}
+			if !reflect.DeepEqual(cmds, rt.expectedCmds) {
+				t.Fatalf("expected commands %v, got %v", rt.expectedCmds, cmds)
+			}
+		})
+	}
+}
+
 // test utils
 
 // newCluster return a CAPI cluster object
@@ -919,3 +1256,11 @@ type FakeSecretFactory struct {
 func (f FakeSecretFactory) NewSecretsClient(client client.Client, cluster *capiv1alpha2.Cluster) (typedcorev1.SecretInterface, error) {
 	return f.client, nil
 }
+
+type fakeControlPlaneHealthProber struct {
+	err error
+}
+
+func (p fakeControlPlaneHealthProber) Probe(client client.Client, cluster *capiv1alpha2.Cluster) error {
+	return p.err
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/yaml"
+)
+
+// kubeletConfiguration is the subset of the kubelet.config.k8s.io/v1beta1 KubeletConfiguration rendered from the
+// KubeletTuning and the cluster DNS.
+type kubeletConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	ClusterDomain                    string            `json:"clusterDomain,omitempty"`
+	ClusterDNS                       []string          `json:"clusterDNS,omitempty"`
+	ShutdownGracePeriod              *metav1.Duration  `json:"shutdownGracePeriod,omitempty"`
+	ShutdownGracePeriodCriticalPods  *metav1.Duration  `json:"shutdownGracePeriodCriticalPods,omitempty"`
+	EvictionHard                     map[string]string `json:"evictionHard,omitempty"`
+	EvictionSoft                     map[string]string `json:"evictionSoft,omitempty"`
+	EvictionSoftGracePeriod          map[string]string `json:"evictionSoftGracePeriod,omitempty"`
+	EvictionMinimumReclaim           map[string]string `json:"evictionMinimumReclaim,omitempty"`
+	EvictionPressureTransitionPeriod *metav1.Duration  `json:"evictionPressureTransitionPeriod,omitempty"`
+	EvictionMaxPodGracePeriod        int32             `json:"evictionMaxPodGracePeriod,omitempty"`
+}
+
+// appendKubeletConfiguration appends the KubeletConfiguration rendered from the KubeletTuning of spec and the
+// cluster DNS to the given InitConfiguration document. kubeadm init uploads it to the kubelet-config ConfigMap,
+// which the kubelets of the joining machines then use.
+func appendKubeletConfiguration(spec *cabpkv1alpha2.KubeadmConfigSpec, dns kubeletDNS, data string) (string, error) {
+	if spec.KubeletTuning == nil && dns.Domain == "" && len(dns.Servers) == 0 {
+		return data, nil
+	}
+
+	cfg := kubeletConfiguration{
+		TypeMeta:      metav1.TypeMeta{APIVersion: "kubelet.config.k8s.io/v1beta1", Kind: "KubeletConfiguration"},
+		ClusterDomain: dns.Domain,
+		ClusterDNS:    dns.Servers,
+	}
+	var tuning cabpkv1alpha2.KubeletTuning
+	if spec.KubeletTuning != nil {
+		tuning = *spec.KubeletTuning
+	}
+	if shutdown := tuning.GracefulShutdown; shutdown != nil {
+		cfg.ShutdownGracePeriod = &shutdown.GracePeriod
+		cfg.ShutdownGracePeriodCriticalPods = shutdown.CriticalPodsGracePeriod
+	}
+	if eviction := tuning.Eviction; eviction != nil {
+		cfg.EvictionHard = eviction.Hard
+		cfg.EvictionSoft = eviction.Soft
+		cfg.EvictionSoftGracePeriod = eviction.SoftGracePeriod
+		cfg.EvictionMinimumReclaim = eviction.MinimumReclaim
+		cfg.EvictionPressureTransitionPeriod = eviction.PressureTransitionPeriod
+		cfg.EvictionMaxPodGracePeriod = eviction.MaxPodGracePeriod
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal the kubelet configuration")
+	}
+	return strings.TrimSuffix(data, "\n") + "\n---\n" + string(out), nil
+}
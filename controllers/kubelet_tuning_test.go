@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+func TestAppendKubeletConfiguration(t *testing.T) {
+	spec := &cabpkv1alpha2.KubeadmConfigSpec{
+		KubeletTuning: &cabpkv1alpha2.KubeletTuning{
+			GracefulShutdown: &cabpkv1alpha2.KubeletGracefulShutdown{
+				GracePeriod:             metav1.Duration{Duration: 30 * time.Second},
+				CriticalPodsGracePeriod: &metav1.Duration{Duration: 10 * time.Second},
+			},
+			Eviction: &cabpkv1alpha2.KubeletEviction{
+				Hard:              map[string]string{"memory.available": "500Mi"},
+				Soft:              map[string]string{"nodefs.available": "15%"},
+				SoftGracePeriod:   map[string]string{"nodefs.available": "1m30s"},
+				MaxPodGracePeriod: 60,
+			},
+		},
+	}
+
+	out, err := appendKubeletConfiguration(spec, kubeletDNS{}, "apiVersion: kubeadm.k8s.io/v1beta1\nkind: InitConfiguration\n")
+	if err != nil {
+		t.Fatalf("failed to render the kubelet configuration: %v", err)
+	}
+	expected := `apiVersion: kubeadm.k8s.io/v1beta1
+kind: InitConfiguration
+---
+apiVersion: kubelet.config.k8s.io/v1beta1
+evictionHard:
+  memory.available: 500Mi
+evictionMaxPodGracePeriod: 60
+evictionSoft:
+  nodefs.available: 15%
+evictionSoftGracePeriod:
+  nodefs.available: 1m30s
+kind: KubeletConfiguration
+shutdownGracePeriod: 30s
+shutdownGracePeriodCriticalPods: 10s
+`
+	if out != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestAppendKubeletConfigurationWithoutTuning(t *testing.T) {
+	data := "apiVersion: kubeadm.k8s.io/v1beta1\nkind: InitConfiguration\n"
+	out, err := appendKubeletConfiguration(&cabpkv1alpha2.KubeadmConfigSpec{}, kubeletDNS{}, data)
+	if err != nil {
+		t.Fatalf("failed to render the kubelet configuration: %v", err)
+	}
+	if out != data {
+		t.Fatalf("expected the init configuration to be unchanged, got:\n%s", out)
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/feature"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/cluster-api/pkg/util"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+const (
+	// machinePoolKind is the kind of the MachinePools of the experimental API of Cluster API, whose replicas share
+	// the bootstrap data of a single KubeadmConfig.
+	machinePoolKind = "MachinePool"
+
+	// machinePoolAnnotationKey is set on the Machine standing in for the replicas of a MachinePool, naming the pool.
+	machinePoolAnnotationKey = "bootstrap.cluster.x-k8s.io/machine-pool"
+)
+
+// MachinePoolGroupVersion is the group version of the MachinePools the controller watches, with the MachinePool
+// feature gate.
+var MachinePoolGroupVersion = schema.GroupVersion{Group: "exp.cluster.x-k8s.io", Version: "v1alpha3"}
+
+// getOwnerMachinePool returns the MachinePool owning the config, or nil if it is not owned by one or the
+// MachinePool feature gate is disabled. Cluster API versions without MachinePools lack their types, so the pool
+// is read as an unstructured object.
+func getOwnerMachinePool(ctx context.Context, c client.Client, obj metav1.ObjectMeta) (*unstructured.Unstructured, error) {
+	if !feature.Gates.Enabled(feature.MachinePool) {
+		return nil, nil
+	}
+	for _, ref := range obj.OwnerReferences {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid owner reference apiVersion %q", ref.APIVersion)
+		}
+		if ref.Kind != machinePoolKind || gv.Group != MachinePoolGroupVersion.Group {
+			continue
+		}
+		pool := &unstructured.Unstructured{}
+		pool.SetGroupVersionKind(gv.WithKind(machinePoolKind))
+		if err := c.Get(ctx, client.ObjectKey{Namespace: obj.Namespace, Name: ref.Name}, pool); err != nil {
+			return nil, errors.Wrapf(err, "failed to get MachinePool %s/%s", obj.Namespace, ref.Name)
+		}
+		return pool, nil
+	}
+	return nil, nil
+}
+
+// machinePoolMachine returns a Machine standing in for the replicas of the pool, so that the bootstrap data of its
+// config is generated as for a worker machine: it carries the labels of the pool and of its machine template, the
+// cluster of the pool and the Kubernetes version of the template.
+func machinePoolMachine(pool *unstructured.Unstructured) (*capiv1alpha2.Machine, error) {
+	labels, _, err := unstructured.NestedStringMap(pool.Object, "spec", "template", "metadata", "labels")
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid machine template labels of MachinePool %s/%s", pool.GetNamespace(), pool.GetName())
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range pool.GetLabels() {
+		labels[k] = v
+	}
+	clusterName, _, err := unstructured.NestedString(pool.Object, "spec", "clusterName")
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid cluster name of MachinePool %s/%s", pool.GetNamespace(), pool.GetName())
+	}
+	if clusterName != "" {
+		labels[capiv1alpha2.MachineClusterLabelName] = clusterName
+	}
+	version, _, err := unstructured.NestedString(pool.Object, "spec", "template", "spec", "version")
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid Kubernetes version of MachinePool %s/%s", pool.GetNamespace(), pool.GetName())
+	}
+
+	machine := &capiv1alpha2.Machine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pool.GetName(),
+			Namespace:   pool.GetNamespace(),
+			Labels:      labels,
+			Annotations: map[string]string{machinePoolAnnotationKey: pool.GetName()},
+		},
+	}
+	if version != "" {
+		machine.Spec.Version = &version
+	}
+	return machine, nil
+}
+
+// isMachinePoolMachine returns true if the machine stands in for the replicas of a MachinePool.
+func isMachinePoolMachine(machine *capiv1alpha2.Machine) bool {
+	_, ok := machine.GetAnnotations()[machinePoolAnnotationKey]
+	return ok
+}
+
+// validateMachinePoolConfig returns a bootstrapFailure if the config can not bootstrap every replica of its
+// MachinePool with the same bootstrap data.
+func validateMachinePoolConfig(config *cabpkv1alpha2.KubeadmConfig, machine *capiv1alpha2.Machine) error {
+	pool := machine.GetAnnotations()[machinePoolAnnotationKey]
+	switch {
+	case util.IsControlPlaneMachine(machine):
+		return newBootstrapFailure(invalidConfigurationReason, "MachinePool %s/%s can not be a control plane, only workers are supported", machine.Namespace, pool)
+	case config.Spec.RetainedIdentity != nil:
+		return newBootstrapFailure(invalidConfigurationReason, "the replicas of MachinePool %s/%s can not share a retained identity", machine.Namespace, pool)
+	case config.Spec.JoinConfiguration != nil && config.Spec.JoinConfiguration.NodeRegistration.Name != "":
+		return newBootstrapFailure(invalidConfigurationReason, "the replicas of MachinePool %s/%s can not share the node name %q", machine.Namespace, pool, config.Spec.JoinConfiguration.NodeRegistration.Name)
+	case config.Spec.Delivery != nil && config.Spec.Delivery.SSH != nil:
+		return newBootstrapFailure(invalidConfigurationReason, "the bootstrap data of MachinePool %s/%s can not be pushed by SSH to a single address", machine.Namespace, pool)
+	}
+	return nil
+}
+
+// reconcileMachinePoolBootstrapToken keeps the bootstrap token of a ready config of a MachinePool valid for as
+// long as the pool exists, as its scaled up replicas join with it. A token that no longer exists, e.g. when it
+// expired while the controller was down, is rotated: the config is generated again with a new token. It returns
+// when the config is due to be checked again.
+func (r *KubeadmConfigReconciler) reconcileMachinePoolBootstrapToken(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, token string) (time.Duration, error) {
+	pool, err := getOwnerMachinePool(ctx, r.Client, config.ObjectMeta)
+	if err != nil || pool == nil {
+		return 0, err
+	}
+	machine, err := machinePoolMachine(pool)
+	if err != nil {
+		return 0, err
+	}
+	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, machine.ObjectMeta)
+	if err != nil {
+		return 0, err
+	}
+	secretsClient, err := r.SecretsClientFactory.NewSecretsClient(r.Client, cluster)
+	if err != nil {
+		return 0, err
+	}
+	settings, err := r.resolveBootstrapSettings(ctx, cluster)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshAfter, refreshed, err := refreshToken(secretsClient, token, bootstrapTokenTTL(settings))
+	if apierrors.IsNotFound(err) {
+		return 0, r.rotateMachinePoolBootstrapToken(ctx, config, pool)
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to refresh the bootstrap token")
+	}
+	if refreshed {
+		bootstrapTokensTotal.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), "refresh").Inc()
+		r.recordEventf(config, nil, corev1.EventTypeNormal, "BootstrapTokenRefreshed",
+			"Refreshed the bootstrap token, which is next refreshed in %s", refreshAfter)
+	}
+	return refreshAfter, nil
+}
+
+// rotateMachinePoolBootstrapToken clears the bootstrap token and data of the config of a MachinePool, so that the
+// config is generated again with a new token.
+func (r *KubeadmConfigReconciler) rotateMachinePoolBootstrapToken(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig, pool *unstructured.Unstructured) error {
+	patchConfig := client.MergeFrom(config.DeepCopy())
+	config.Spec.JoinConfiguration.Discovery.BootstrapToken.Token = ""
+	if err := r.Patch(ctx, config, patchConfig); err != nil {
+		return errors.Wrap(err, "failed to clear the bootstrap token of the config")
+	}
+
+	patchConfig = client.MergeFrom(config.DeepCopy())
+	config.Status.Ready = false
+	config.Status.BootstrapData = nil
+	clearDataSecretAvailableCondition(config, "BootstrapTokenRotated",
+		"the bootstrap data is generated again as the bootstrap token it joined with no longer exists")
+	if err := r.Status().Patch(ctx, config, patchConfig); err != nil {
+		return errors.Wrap(err, "failed to clear the bootstrap data of the config")
+	}
+	r.Log.Info("Rotating the bootstrap token of the config of a MachinePool", "kubeadmconfig", config.Namespace+"/"+config.Name, "machinepool", pool.GetName())
+	return nil
+}
+
+// machinePoolToKubeadmConfig maps a MachinePool to the KubeadmConfig its replicas are bootstrapped with.
+func (r *KubeadmConfigReconciler) machinePoolToKubeadmConfig(o handler.MapObject) []ctrl.Request {
+	pool, ok := o.Object.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	ref, _, err := unstructured.NestedStringMap(pool.Object, "spec", "template", "spec", "bootstrap", "configRef")
+	if err != nil || ref["kind"] != "KubeadmConfig" || ref["name"] == "" {
+		return nil
+	}
+	if gv, err := schema.ParseGroupVersion(ref["apiVersion"]); err != nil || gv.Group != cabpkv1alpha2.GroupVersion.Group {
+		return nil
+	}
+	return []ctrl.Request{{NamespacedName: client.ObjectKey{Namespace: pool.GetNamespace(), Name: ref["name"]}}}
+}
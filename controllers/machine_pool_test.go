@@ -0,0 +1,193 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func newMachinePool(name string) *unstructured.Unstructured {
+	pool := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"clusterName": "cluster",
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{"pool": name},
+				},
+				"spec": map[string]interface{}{
+					"version": "v1.15.3",
+					"bootstrap": map[string]interface{}{
+						"configRef": map[string]interface{}{
+							"apiVersion": cabpkv1alpha2.GroupVersion.String(),
+							"kind":       "KubeadmConfig",
+							"name":       name + "-config",
+						},
+					},
+				},
+			},
+		},
+	}}
+	pool.SetGroupVersionKind(MachinePoolGroupVersion.WithKind(machinePoolKind))
+	pool.SetName(name)
+	pool.SetNamespace("default")
+	pool.SetLabels(map[string]string{"team": "a"})
+	return pool
+}
+
+func TestMachinePoolMachine(t *testing.T) {
+	machine, err := machinePoolMachine(newMachinePool("pool"))
+	if err != nil {
+		t.Fatalf("failed to get the machine of the pool: %v", err)
+	}
+	if !isMachinePoolMachine(machine) || machine.Name != "pool" || machine.Namespace != "default" {
+		t.Errorf("unexpected machine %+v", machine.ObjectMeta)
+	}
+	if machineVersion(machine) != "v1.15.3" {
+		t.Errorf("expected the version of the machine template, got %q", machineVersion(machine))
+	}
+	for k, v := range map[string]string{"pool": "pool", "team": "a", capiv1alpha2.MachineClusterLabelName: "cluster"} {
+		if machine.Labels[k] != v {
+			t.Errorf("expected label %s=%s, got %v", k, v, machine.Labels)
+		}
+	}
+	if isMachinePoolMachine(newWorkerMachine(newCluster("cluster"), "machine")) {
+		t.Error("expected a machine not to stand in for a pool")
+	}
+}
+
+func TestValidateMachinePoolConfig(t *testing.T) {
+	machine, err := machinePoolMachine(newMachinePool("pool"))
+	if err != nil {
+		t.Fatalf("failed to get the machine of the pool: %v", err)
+	}
+
+	var useCases = []struct {
+		name      string
+		mutate    func(*capiv1alpha2.Machine, *cabpkv1alpha2.KubeadmConfig)
+		expectErr bool
+	}{
+		{
+			name:   "worker join",
+			mutate: func(*capiv1alpha2.Machine, *cabpkv1alpha2.KubeadmConfig) {},
+		},
+		{
+			name: "control plane",
+			mutate: func(m *capiv1alpha2.Machine, _ *cabpkv1alpha2.KubeadmConfig) {
+				m.Labels[capiv1alpha2.MachineControlPlaneLabelName] = "true"
+			},
+			expectErr: true,
+		},
+		{
+			name: "node name",
+			mutate: func(_ *capiv1alpha2.Machine, c *cabpkv1alpha2.KubeadmConfig) {
+				c.Spec.JoinConfiguration.NodeRegistration.Name = "node"
+			},
+			expectErr: true,
+		},
+		{
+			name: "retained identity",
+			mutate: func(_ *capiv1alpha2.Machine, c *cabpkv1alpha2.KubeadmConfig) {
+				c.Spec.RetainedIdentity = &cabpkv1alpha2.RetainedIdentity{NodeName: "node"}
+			},
+			expectErr: true,
+		},
+		{
+			name: "ssh delivery",
+			mutate: func(_ *capiv1alpha2.Machine, c *cabpkv1alpha2.KubeadmConfig) {
+				c.Spec.Delivery = &cabpkv1alpha2.Delivery{SSH: &cabpkv1alpha2.SSHDelivery{Address: "10.0.0.10", SecretName: "ssh-key"}}
+			},
+			expectErr: true,
+		},
+	}
+	for _, tc := range useCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := machine.DeepCopy()
+			config := newWorkerJoinKubeadmConfig(nil, "cfg")
+			tc.mutate(m, config)
+
+			err := validateMachinePoolConfig(config, m)
+			if !tc.expectErr {
+				if err != nil {
+					t.Fatalf("expected nil, got error %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*bootstrapFailure); !ok {
+				t.Fatalf("expected a bootstrap failure, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetOwnerMachinePoolRequiresFeatureGate(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	config.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: MachinePoolGroupVersion.String(),
+		Kind:       machinePoolKind,
+		Name:       "pool",
+	}}
+	pool, err := getOwnerMachinePool(context.Background(), fake.NewFakeClientWithScheme(setupScheme()), config.ObjectMeta)
+	if err != nil || pool != nil {
+		t.Fatalf("expected no pool with the MachinePool feature gate disabled, got %v and %v", pool, err)
+	}
+}
+
+func TestMachinePoolToKubeadmConfig(t *testing.T) {
+	k := &KubeadmConfigReconciler{Log: log.Log}
+
+	pool := newMachinePool("pool")
+	requests := k.machinePoolToKubeadmConfig(handler.MapObject{Meta: pool, Object: pool})
+	if len(requests) != 1 || requests[0].Name != "pool-config" || requests[0].Namespace != "default" {
+		t.Errorf("expected a request for the config of the pool, got %v", requests)
+	}
+
+	if err := unstructured.SetNestedField(pool.Object, "OtherConfig", "spec", "template", "spec", "bootstrap", "configRef", "kind"); err != nil {
+		t.Fatalf("failed to set the kind of the config reference: %v", err)
+	}
+	if requests := k.machinePoolToKubeadmConfig(handler.MapObject{Meta: pool, Object: pool}); len(requests) != 0 {
+		t.Errorf("expected no request for another kind of config, got %v", requests)
+	}
+}
+
+func TestRecordEventfSkipsMachinePoolMachine(t *testing.T) {
+	machine, err := machinePoolMachine(newMachinePool("pool"))
+	if err != nil {
+		t.Fatalf("failed to get the machine of the pool: %v", err)
+	}
+	recorder := record.NewFakeRecorder(2)
+	k := &KubeadmConfigReconciler{Log: log.Log, Recorder: recorder}
+	k.recordEventf(newKubeadmConfig(nil, "cfg"), machine, "Normal", "BootstrapDataGenerated", "Generated the bootstrap data of a joining worker")
+	close(recorder.Events)
+
+	count := 0
+	for range recorder.Events {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("expected a single event on the config, got %d", count)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/windows"
+	capierrors "sigs.k8s.io/cluster-api/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// The roles of the machines bootstrap data is generated for.
+const (
+	bootstrapDataRoleInit             = "init"
+	bootstrapDataRoleControlPlaneJoin = "control-plane-join"
+	bootstrapDataRoleWorkerJoin       = "worker-join"
+)
+
+var (
+	// bootstrapDataSizeBytes tracks the size of the user data handed over to machines, to spot payloads
+	// creeping toward the user data limits of infrastructure providers (e.g. 16KiB on AWS).
+	bootstrapDataSizeBytes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cabpk_bootstrap_data_size_bytes",
+			Help:    "Size in bytes of the bootstrap data handed over to machines as user data.",
+			Buckets: prometheus.ExponentialBuckets(1024, 2, 10), // 1KiB to 512KiB
+		},
+		[]string{"namespace", "cluster", "format"},
+	)
+
+	// certificateExpiryTimestampSeconds tracks the expiry of the CA certificates managed by the controller, so
+	// that alerts can fire well before control planes can no longer be joined.
+	certificateExpiryTimestampSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cabpk_certificate_expiry_timestamp_seconds",
+			Help: "Expiry, in seconds since the epoch, of the CA certificates of the cluster.",
+		},
+		[]string{"namespace", "cluster", "certificate"},
+	)
+
+	// bootstrapDataGenerationsTotal counts the bootstrap data generated by machine role, for capacity planning.
+	bootstrapDataGenerationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cabpk_bootstrap_data_generations_total",
+			Help: "Number of times bootstrap data was generated, by machine role.",
+		},
+		[]string{"namespace", "cluster", "role"},
+	)
+
+	// bootstrapTokensTotal counts the bootstrap tokens created for joining machines, and their refreshes.
+	bootstrapTokensTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cabpk_bootstrap_tokens_total",
+			Help: "Number of bootstrap tokens created or refreshed in workload clusters.",
+		},
+		[]string{"namespace", "cluster", "operation"},
+	)
+
+	// certificateGenerationsTotal counts the cluster certificates generated, whether for a new cluster or a phase
+	// of a CA rotation.
+	certificateGenerationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cabpk_certificate_generations_total",
+			Help: "Number of times the certificates of a cluster were generated or rotated.",
+		},
+		[]string{"namespace", "cluster", "operation"},
+	)
+
+	// initLockAcquisitionsTotal counts the attempts to acquire the control plane init lock by result, to spot
+	// clusters where control plane machines keep waiting for the lock.
+	initLockAcquisitionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cabpk_init_lock_acquisitions_total",
+			Help: "Number of attempts to acquire the control plane init lock, by result.",
+		},
+		[]string{"namespace", "cluster", "result"},
+	)
+
+	// reconcileErrorsTotal counts the errors KubeadmConfigs were reconciled with by category, to alert on
+	// bootstrap failures.
+	reconcileErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cabpk_reconcile_errors_total",
+			Help: "Number of KubeadmConfig reconciliations that returned an error, by category.",
+		},
+		[]string{"category"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(bootstrapDataSizeBytes, certificateExpiryTimestampSeconds, bootstrapDataGenerationsTotal,
+		bootstrapTokensTotal, certificateGenerationsTotal, initLockAcquisitionsTotal, reconcileErrorsTotal)
+}
+
+// bootstrapDataFormat returns the user data format of data, as identified by its header line.
+func bootstrapDataFormat(data []byte) string {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		// cloud-init jinja templates have their own header, preceding the user data one
+		if bytes.HasPrefix(line, []byte("## template:")) {
+			continue
+		}
+		// Ignition configs are JSON objects
+		if bytes.HasPrefix(line, []byte("{")) {
+			return "ignition"
+		}
+		// MIME multi-part archives start with their headers
+		if bytes.HasPrefix(line, []byte("Content-Type: multipart/")) {
+			return "multipart"
+		}
+		switch string(line) {
+		case "#cloud-config":
+			return "cloud-config"
+		case "#include":
+			return "include"
+		case windows.Header:
+			return "windows"
+		}
+		break
+	}
+	return "unknown"
+}
+
+// reconcileErrorCategory returns the category err is counted in by reconcileErrorsTotal.
+func reconcileErrorCategory(err error) string {
+	cause := errors.Cause(err)
+	if _, ok := cause.(capierrors.HasRequeueAfterError); ok {
+		return "requeue"
+	}
+	switch {
+	case apierrors.IsConflict(cause):
+		return "conflict"
+	case apierrors.IsNotFound(cause):
+		return "not_found"
+	case apierrors.IsAlreadyExists(cause):
+		return "already_exists"
+	case apierrors.IsInvalid(cause):
+		return "invalid"
+	case apierrors.IsForbidden(cause) || apierrors.IsUnauthorized(cause):
+		return "forbidden"
+	case apierrors.IsTimeout(cause) || apierrors.IsServerTimeout(cause) || apierrors.IsServiceUnavailable(cause):
+		return "timeout"
+	}
+	return "other"
+}
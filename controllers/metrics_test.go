@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capierrors "sigs.k8s.io/cluster-api/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestBootstrapDataFormat(t *testing.T) {
+	testcases := []struct {
+		data     string
+		expected string
+	}{
+		{data: "#cloud-config\nwrite_files:\n", expected: "cloud-config"},
+		{data: "## template: jinja\n#cloud-config\nwrite_files:\n", expected: "cloud-config"},
+		{data: "## template: jinja\n#!/bin/sh\n", expected: "unknown"},
+		{data: "#include\nhttps://10.0.0.2:9443/default/cfg\n", expected: "include"},
+		{data: "#cloud-config", expected: "cloud-config"},
+		{data: "#!/bin/sh\n", expected: "unknown"},
+		{data: `{"ignition":{"version":"3.0.0"}}`, expected: "ignition"},
+		{data: "#ps1_sysnative\n$ErrorActionPreference = \"Stop\"\n", expected: "windows"},
+		{data: "Content-Type: multipart/mixed; boundary=\"abc\"\r\nMIME-Version: 1.0\r\n", expected: "multipart"},
+		{data: "", expected: "unknown"},
+	}
+
+	for _, tc := range testcases {
+		if out := bootstrapDataFormat([]byte(tc.data)); out != tc.expected {
+			t.Errorf("expected format %q for %q, got %q", tc.expected, tc.data, out)
+		}
+	}
+}
+
+func TestSetBootstrapDataObservesSize(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme()),
+	}
+	if err := k.setBootstrapData(context.Background(), newCluster("size-cluster"), config, []byte("#cloud-config")); err != nil {
+		t.Fatalf("Failed to set bootstrap data:\n %+v", err)
+	}
+
+	metric := &dto.Metric{}
+	if err := bootstrapDataSizeBytes.WithLabelValues("default", "size-cluster", "cloud-config").(prometheus.Metric).Write(metric); err != nil {
+		t.Fatalf("Failed to read metric:\n %+v", err)
+	}
+	if metric.GetHistogram().GetSampleCount() != 1 {
+		t.Fatalf("expected one observation, got %d", metric.GetHistogram().GetSampleCount())
+	}
+	if metric.GetHistogram().GetSampleSum() != float64(len("#cloud-config")) {
+		t.Fatalf("expected observed size %d, got %v", len("#cloud-config"), metric.GetHistogram().GetSampleSum())
+	}
+}
+
+func TestReconcileErrorCategory(t *testing.T) {
+	testcases := []struct {
+		err      error
+		expected string
+	}{
+		{err: errors.Wrap(&capierrors.RequeueAfterError{RequeueAfter: time.Second}, "waiting"), expected: "requeue"},
+		{err: errors.Wrap(apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, "certs", errors.New("modified")), "failed"), expected: "conflict"},
+		{err: apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "certs"), expected: "not_found"},
+		{err: apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "certs", errors.New("denied")), expected: "forbidden"},
+		{err: apierrors.NewServerTimeout(schema.GroupResource{Resource: "secrets"}, "get", 1), expected: "timeout"},
+		{err: errors.New("failed to marshal init configuration"), expected: "other"},
+	}
+
+	for _, tc := range testcases {
+		if out := reconcileErrorCategory(tc.err); out != tc.expected {
+			t.Errorf("expected category %q for %v, got %q", tc.expected, tc.err, out)
+		}
+	}
+}
+
+func TestAcquireInitLockCountsAttempts(t *testing.T) {
+	cluster := newCluster("lock-metrics-cluster")
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		ControlPlaneInitLocker: &fakeInitLocker{},
+	}
+
+	first := newControlPlaneMachine(cluster, "control-plane-machine-1")
+	second := newControlPlaneMachine(cluster, "control-plane-machine-2")
+	if !k.acquireInitLock(cluster, first, newKubeadmConfig(first, "cfg-1")) {
+		t.Fatal("expected the first machine to acquire the lock")
+	}
+	if k.acquireInitLock(cluster, second, newKubeadmConfig(second, "cfg-2")) {
+		t.Fatal("expected the second machine not to acquire the lock")
+	}
+
+	for _, result := range []string{"acquired", "failed"} {
+		metric := &dto.Metric{}
+		if err := initLockAcquisitionsTotal.WithLabelValues("default", "lock-metrics-cluster", result).Write(metric); err != nil {
+			t.Fatalf("Failed to read metric:\n %+v", err)
+		}
+		if metric.GetCounter().GetValue() != 1 {
+			t.Errorf("expected one %s attempt, got %v", result, metric.GetCounter().GetValue())
+		}
+	}
+}
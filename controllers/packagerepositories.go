@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// resolvePackageRepositories returns the package repositories of the config, with the GPG keys referenced by
+// Secret read from the config namespace.
+func (r *KubeadmConfigReconciler) resolvePackageRepositories(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) ([]cabpkv1alpha2.PackageRepository, error) {
+	repos := make([]cabpkv1alpha2.PackageRepository, 0, len(config.Spec.PackageRepositories))
+	for _, repo := range config.Spec.PackageRepositories {
+		repo := *repo.DeepCopy()
+		if repo.GPGKeySecretName != "" {
+			secret := &corev1.Secret{}
+			if err := r.Get(ctx, types.NamespacedName{Name: repo.GPGKeySecretName, Namespace: config.GetNamespace()}, secret); err != nil {
+				return nil, errors.Wrapf(err, "failed to get the GPG key secret %q of package repository %q", repo.GPGKeySecretName, repo.Name)
+			}
+			key, ok := secret.Data[cabpkv1alpha2.PackageRepositoryGPGKeySecretKey]
+			if !ok {
+				return nil, errors.Errorf("secret %q has no %s key for package repository %q",
+					repo.GPGKeySecretName, cabpkv1alpha2.PackageRepositoryGPGKeySecretKey, repo.Name)
+			}
+			repo.GPGKey = string(key)
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
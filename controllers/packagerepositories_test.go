@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestResolvePackageRepositories(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kubernetes-gpg"},
+		Data: map[string][]byte{
+			cabpkv1alpha2.PackageRepositoryGPGKeySecretKey: []byte("secret-key"),
+		},
+	}
+
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.PackageRepositories = []cabpkv1alpha2.PackageRepository{
+		{Name: "inline", OSFamily: cabpkv1alpha2.OSFamilyRHEL, URL: "https://example.com/inline", GPGKey: "inline-key"},
+		{Name: "secret", OSFamily: cabpkv1alpha2.OSFamilyRHEL, URL: "https://example.com/secret", GPGKeySecretName: "kubernetes-gpg"},
+	}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), secret),
+	}
+	repos, err := k.resolvePackageRepositories(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to resolve package repositories: %v", err)
+	}
+
+	if len(repos) != 2 || repos[0].GPGKey != "inline-key" || repos[1].GPGKey != "secret-key" {
+		t.Fatalf("expected the inline and secret keys to be resolved, got %+v", repos)
+	}
+	if config.Spec.PackageRepositories[1].GPGKey != "" {
+		t.Fatal("expected the secret key not to be copied into the config spec")
+	}
+}
+
+func TestResolvePackageRepositoriesFailsWithoutSecretKey(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kubernetes-gpg"},
+	}
+
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.PackageRepositories = []cabpkv1alpha2.PackageRepository{
+		{Name: "secret", OSFamily: cabpkv1alpha2.OSFamilyRHEL, URL: "https://example.com/secret", GPGKeySecretName: "kubernetes-gpg"},
+	}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), secret),
+	}
+	if _, err := k.resolvePackageRepositories(context.Background(), config); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"regexp"
+	"strconv"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// podSecurityV1MinorVersion is the first Kubernetes minor version serving pod-security.admission.config.k8s.io/v1.
+const podSecurityV1MinorVersion = 25
+
+// minorVersionRegexp matches the minor version of Kubernetes versions, e.g. v1.24.3.
+var minorVersionRegexp = regexp.MustCompile(`^v?1\.([0-9]+)`)
+
+type podSecurityConfiguration struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Defaults   podSecurityDefaults   `json:"defaults"`
+	Exemptions podSecurityExemptions `json:"exemptions"`
+}
+
+type podSecurityDefaults struct {
+	Enforce        cabpkv1alpha2.PodSecurityLevel `json:"enforce"`
+	EnforceVersion string                         `json:"enforce-version"`
+	Audit          cabpkv1alpha2.PodSecurityLevel `json:"audit"`
+	AuditVersion   string                         `json:"audit-version"`
+	Warn           cabpkv1alpha2.PodSecurityLevel `json:"warn"`
+	WarnVersion    string                         `json:"warn-version"`
+}
+
+type podSecurityExemptions struct {
+	Usernames      []string `json:"usernames,omitempty"`
+	Namespaces     []string `json:"namespaces,omitempty"`
+	RuntimeClasses []string `json:"runtimeClasses,omitempty"`
+}
+
+// podSecurityLevel returns the given level, privileged if unset.
+func podSecurityLevel(level cabpkv1alpha2.PodSecurityLevel) cabpkv1alpha2.PodSecurityLevel {
+	if level == "" {
+		return cabpkv1alpha2.PodSecurityLevelPrivileged
+	}
+	return level
+}
+
+// newPodSecurityConfiguration returns the configuration of the PodSecurity admission plugin for API servers of
+// the given Kubernetes version. Versions before v1.25 get the v1beta1 configuration.
+func newPodSecurityConfiguration(podSecurity *cabpkv1alpha2.PodSecurity, version string) podSecurityConfiguration {
+	apiVersion := "pod-security.admission.config.k8s.io/v1"
+	if m := minorVersionRegexp.FindStringSubmatch(version); m != nil {
+		if minor, err := strconv.Atoi(m[1]); err == nil && minor < podSecurityV1MinorVersion {
+			apiVersion = "pod-security.admission.config.k8s.io/v1beta1"
+		}
+	}
+
+	return podSecurityConfiguration{
+		APIVersion: apiVersion,
+		Kind:       "PodSecurityConfiguration",
+		Defaults: podSecurityDefaults{
+			Enforce:        podSecurityLevel(podSecurity.Enforce),
+			EnforceVersion: "latest",
+			Audit:          podSecurityLevel(podSecurity.Audit),
+			AuditVersion:   "latest",
+			Warn:           podSecurityLevel(podSecurity.Warn),
+			WarnVersion:    "latest",
+		},
+		Exemptions: podSecurityExemptions{
+			Usernames:      podSecurity.ExemptUsernames,
+			Namespaces:     podSecurity.ExemptNamespaces,
+			RuntimeClasses: podSecurity.ExemptRuntimeClasses,
+		},
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+func TestNewPodSecurityConfiguration(t *testing.T) {
+	podSecurity := &cabpkv1alpha2.PodSecurity{
+		Enforce:          cabpkv1alpha2.PodSecurityLevelBaseline,
+		ExemptNamespaces: []string{"kube-system"},
+	}
+
+	cfg := newPodSecurityConfiguration(podSecurity, "v1.26.1")
+	if cfg.APIVersion != "pod-security.admission.config.k8s.io/v1" {
+		t.Fatalf("expected the v1 configuration, got %q", cfg.APIVersion)
+	}
+	if cfg.Defaults.Enforce != cabpkv1alpha2.PodSecurityLevelBaseline || cfg.Defaults.Audit != cabpkv1alpha2.PodSecurityLevelPrivileged {
+		t.Fatalf("expected the unset levels to default to privileged, got %+v", cfg.Defaults)
+	}
+	if len(cfg.Exemptions.Namespaces) != 1 || cfg.Exemptions.Namespaces[0] != "kube-system" {
+		t.Fatalf("expected the namespace to be exempted, got %+v", cfg.Exemptions)
+	}
+
+	if cfg := newPodSecurityConfiguration(podSecurity, "v1.24.3"); cfg.APIVersion != "pod-security.admission.config.k8s.io/v1beta1" {
+		t.Fatalf("expected the v1beta1 configuration before v1.25, got %q", cfg.APIVersion)
+	}
+}
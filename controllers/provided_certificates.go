@@ -0,0 +1,63 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+const (
+	// DisableCertificateGenerationAnnotationKey disables the generation of the certificates of a cluster when set
+	// to "true" on the Cluster; its certificates secret must then be provided.
+	DisableCertificateGenerationAnnotationKey = "bootstrap.cluster.x-k8s.io/disable-certificate-generation"
+)
+
+// certificateGenerationDisabled returns true if the certificates of the cluster must be provided rather than
+// generated, either for all clusters or by the DisableCertificateGenerationAnnotationKey.
+func (r *KubeadmConfigReconciler) certificateGenerationDisabled(cluster *capiv1alpha2.Cluster) bool {
+	return r.DisableCertificateGeneration || cluster.GetAnnotations()[DisableCertificateGenerationAnnotationKey] == "true"
+}
+
+// reconcileProvidedCertificates sets the CertificatesMissing condition of a config of a cluster whose certificates
+// must be provided, given the certificates found in its certificates secret or nil if there is none. It returns
+// true if the certificates are complete.
+func reconcileProvidedCertificates(config *cabpkv1alpha2.KubeadmConfig, clusterName string, certificates *certs.Certificates) bool {
+	secretName := ClusterCertificatesSecretName(clusterName)
+	condition := cabpkv1alpha2.KubeadmConfigCondition{
+		Type:    cabpkv1alpha2.CertificatesMissingCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  "CertificatesProvided",
+		Message: fmt.Sprintf("the cluster certificates are provided by secret %s", secretName),
+	}
+	if certificates == nil {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "CertificateGenerationDisabled"
+		condition.Message = fmt.Sprintf("certificate generation is disabled and secret %s is not found", secretName)
+	} else if err := certificates.Validate(); err != nil {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "CertificateGenerationDisabled"
+		condition.Message = fmt.Sprintf("certificate generation is disabled and secret %s is incomplete: %v", secretName, err)
+	}
+	setCondition(&config.Status, condition, time.Now())
+	return condition.Status == corev1.ConditionFalse
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileWaitsForProvidedCertificates(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+	cluster.Annotations = map[string]string{DisableCertificateGenerationAnnotationKey: "true"}
+
+	controlPlaneMachine := newControlPlaneMachine(cluster, "control-plane-machine")
+	controlPlaneInitConfig := newControlPlaneInitKubeadmConfig(controlPlaneMachine, "control-plane-init-cfg")
+
+	objects := []runtime.Object{
+		cluster,
+		controlPlaneMachine,
+		controlPlaneInitConfig,
+	}
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), objects...)
+
+	k := &KubeadmConfigReconciler{
+		Log:                    log.Log,
+		Client:                 myclient,
+		ControlPlaneInitLocker: &fakeInitLocker{},
+	}
+	request := ctrl.Request{
+		NamespacedName: types.NamespacedName{Namespace: "default", Name: "control-plane-init-cfg"},
+	}
+
+	result, err := k.Reconcile(request)
+	if err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	if result.RequeueAfter != 30*time.Second {
+		t.Fatalf("expected to requeue after 30s, got %s", result.RequeueAfter)
+	}
+	if _, err := getCertsSecret(myclient, cluster.GetName()); err == nil {
+		t.Fatal("expected no certificates to be generated")
+	}
+	cfg, err := getKubeadmConfig(myclient, "control-plane-init-cfg")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	if cfg.Status.Ready {
+		t.Fatal("expected the config not to be ready")
+	}
+	expectCondition(t, cfg, cabpkv1alpha2.CertificatesMissingCondition, corev1.ConditionTrue)
+
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("Failed to generate certificates:\n %+v", err)
+	}
+	if err := myclient.Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ClusterCertificatesSecretName(cluster.GetName()),
+			Namespace: "default",
+		},
+		Data: certificates.ToMap(),
+	}); err != nil {
+		t.Fatalf("Failed to create certificates secret:\n %+v", err)
+	}
+
+	if _, err := k.Reconcile(request); err != nil {
+		t.Fatalf("Failed to reconcile:\n %+v", err)
+	}
+	cfg, err = getKubeadmConfig(myclient, "control-plane-init-cfg")
+	if err != nil {
+		t.Fatalf("Failed to get config:\n %+v", err)
+	}
+	if !cfg.Status.Ready {
+		t.Fatal("expected the config to be ready once the certificates are provided")
+	}
+	expectCondition(t, cfg, cabpkv1alpha2.CertificatesMissingCondition, corev1.ConditionFalse)
+}
+
+func TestReconcileProvidedCertificatesIncomplete(t *testing.T) {
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("Failed to generate certificates:\n %+v", err)
+	}
+	certificates.EtcdCA.Key = nil
+
+	config := newKubeadmConfig(nil, "cfg")
+	if reconcileProvidedCertificates(config, "cluster", certificates) {
+		t.Fatal("expected incomplete certificates to be rejected")
+	}
+	expectCondition(t, config, cabpkv1alpha2.CertificatesMissingCondition, corev1.ConditionTrue)
+}
+
+func TestCARotationIntroduceRejectedWithoutCertificateGeneration(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Annotations = map[string]string{CARotationAnnotationKey: string(certs.CARotationIntroduce)}
+	config := newControlPlaneJoinKubeadmConfig(nil, "cfg")
+
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("Failed to generate certificates:\n %+v", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ClusterCertificatesSecretName(cluster.GetName()),
+			Namespace: "default",
+		},
+		Data: certificates.ToMap(),
+	}
+
+	k := &KubeadmConfigReconciler{
+		Log:                          log.Log,
+		Client:                       fake.NewFakeClientWithScheme(setupScheme(), secret),
+		DisableCertificateGeneration: true,
+	}
+	if _, err := k.reconcileCARotation(context.Background(), cluster, config); err == nil {
+		t.Fatal("expected the introduction of new CAs to be rejected")
+	}
+}
+
+func expectCondition(t *testing.T, config *cabpkv1alpha2.KubeadmConfig, conditionType cabpkv1alpha2.KubeadmConfigConditionType, status corev1.ConditionStatus) {
+	t.Helper()
+	for _, condition := range config.Status.Conditions {
+		if condition.Type == conditionType {
+			if condition.Status != status {
+				t.Errorf("expected condition %s to be %s, got %s", conditionType, status, condition.Status)
+			}
+			return
+		}
+	}
+	t.Errorf("expected condition %s, got %v", conditionType, config.Status.Conditions)
+}
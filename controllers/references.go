@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// reference is a Secret or ConfigMap in the namespace of a config, and the keys the config requires it to hold.
+type reference struct {
+	kind string
+	name string
+	keys []string
+}
+
+// references returns the Secrets and ConfigMaps referenced by the spec of config.
+func references(config *cabpkv1alpha2.KubeadmConfig) []reference {
+	var refs []reference
+	for _, repo := range config.Spec.PackageRepositories {
+		if repo.GPGKeySecretName != "" {
+			refs = append(refs, reference{"Secret", repo.GPGKeySecretName, []string{cabpkv1alpha2.PackageRepositoryGPGKeySecretKey}})
+		}
+	}
+	if delivery := config.Spec.Delivery; delivery != nil {
+		if delivery.SSH != nil {
+			refs = append(refs, reference{"Secret", delivery.SSH.SecretName, []string{corev1.SSHAuthPrivateKey}})
+		}
+		if delivery.ObjectStorage != nil {
+			refs = append(refs, reference{"Secret", delivery.ObjectStorage.SecretName, []string{objectStorageAccessKeyIDKey, objectStorageSecretAccessKeyKey}})
+		}
+	}
+	for _, credentials := range config.Spec.RegistryCredentials {
+		refs = append(refs, reference{"Secret", credentials.SecretName, []string{corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey}})
+	}
+	if cas := config.Spec.ExternalCAs; cas != nil {
+		for _, name := range []string{cas.EtcdCASecretName, cas.FrontProxyCASecretName} {
+			if name != "" {
+				refs = append(refs, reference{"Secret", name, []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey}})
+			}
+		}
+	}
+	files := append([]cabpkv1alpha2.Files{}, config.Spec.AdditionalUserDataFiles...)
+	for _, conditional := range config.Spec.OSConditionals {
+		files = append(files, conditional.Files...)
+	}
+	sources := make([]cabpkv1alpha2.FileSource, 0, len(files)+len(config.Spec.TrustedCABundles))
+	for _, file := range files {
+		if file.ContentFrom != nil {
+			sources = append(sources, *file.ContentFrom)
+		}
+	}
+	if config.Spec.Multipart != nil {
+		for _, part := range config.Spec.Multipart.Parts {
+			if part.ContentFrom != nil {
+				sources = append(sources, *part.ContentFrom)
+			}
+		}
+	}
+	for _, source := range append(sources, config.Spec.TrustedCABundles...) {
+		switch {
+		case source.Secret != nil:
+			refs = append(refs, reference{"Secret", source.Secret.Name, []string{source.Secret.Key}})
+		case source.ConfigMap != nil:
+			refs = append(refs, reference{"ConfigMap", source.ConfigMap.Name, []string{source.ConfigMap.Key}})
+		}
+	}
+	return refs
+}
+
+// referencedKeys returns the keys held by the referenced object, or nil if it is not found.
+func (r *KubeadmConfigReconciler) referencedKeys(ctx context.Context, namespace string, ref reference) (map[string]bool, error) {
+	key := types.NamespacedName{Name: ref.name, Namespace: namespace}
+	keys := map[string]bool{}
+	var err error
+	if ref.kind == "ConfigMap" {
+		configMap := &corev1.ConfigMap{}
+		if err = r.Get(ctx, key, configMap); err == nil {
+			for k := range configMap.Data {
+				keys[k] = true
+			}
+			for k := range configMap.BinaryData {
+				keys[k] = true
+			}
+		}
+	} else {
+		secret := &corev1.Secret{}
+		if err = r.Get(ctx, key, secret); err == nil {
+			for k := range secret.Data {
+				keys[k] = true
+			}
+		}
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get referenced %s %q", ref.kind, ref.name)
+	}
+	return keys, nil
+}
+
+// reconcileReferences sets the MissingReference condition of config, listing the referenced Secrets and ConfigMaps
+// that are not found or miss a required key. It returns the missing references.
+func (r *KubeadmConfigReconciler) reconcileReferences(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) ([]string, error) {
+	var missing []string
+	for _, ref := range references(config) {
+		keys, err := r.referencedKeys(ctx, config.GetNamespace(), ref)
+		if err != nil {
+			return nil, err
+		}
+		if keys == nil {
+			missing = append(missing, fmt.Sprintf("%s %s", ref.kind, ref.name))
+			continue
+		}
+		for _, key := range ref.keys {
+			if !keys[key] {
+				missing = append(missing, fmt.Sprintf("%s %s key %s", ref.kind, ref.name, key))
+			}
+		}
+	}
+
+	condition := cabpkv1alpha2.KubeadmConfigCondition{
+		Type:   cabpkv1alpha2.MissingReferenceCondition,
+		Status: corev1.ConditionFalse,
+		Reason: "ReferencesResolved",
+	}
+	if len(missing) > 0 {
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "ReferencesNotFound"
+		condition.Message = fmt.Sprintf("referenced objects not found: %s", strings.Join(missing, ", "))
+	} else if !hasCondition(&config.Status, cabpkv1alpha2.MissingReferenceCondition) {
+		// configs whose references always resolved are not cluttered with the condition
+		return nil, nil
+	}
+	setCondition(&config.Status, condition, time.Now())
+	return missing, nil
+}
+
+// hasCondition returns true if the status has a condition of the given type.
+func hasCondition(status *cabpkv1alpha2.KubeadmConfigStatus, conditionType cabpkv1alpha2.KubeadmConfigConditionType) bool {
+	for _, c := range status.Conditions {
+		if c.Type == conditionType {
+			return true
+		}
+	}
+	return false
+}
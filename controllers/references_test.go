@@ -0,0 +1,137 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileReferences(t *testing.T) {
+	credentials := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "bucket-credentials"},
+		Data: map[string][]byte{
+			objectStorageAccessKeyIDKey: []byte("id"),
+		},
+	}
+
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.PackageRepositories = []cabpkv1alpha2.PackageRepository{
+		{Name: "secret", OSFamily: cabpkv1alpha2.OSFamilyRHEL, URL: "https://example.com/secret", GPGKeySecretName: "kubernetes-gpg"},
+	}
+	config.Spec.Delivery = &cabpkv1alpha2.Delivery{
+		ObjectStorage: &cabpkv1alpha2.ObjectStorageDelivery{
+			Endpoint:   "https://s3.example.com",
+			Bucket:     "bootstrap",
+			SecretName: "bucket-credentials",
+		},
+	}
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), credentials)
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: myclient,
+	}
+	missing, err := k.reconcileReferences(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to reconcile references: %v", err)
+	}
+	if len(missing) != 2 {
+		t.Fatalf("expected the GPG key secret and a credentials key to be missing, got %v", missing)
+	}
+	expectCondition(t, config, cabpkv1alpha2.MissingReferenceCondition, corev1.ConditionTrue)
+	message := config.Status.Conditions[0].Message
+	if !strings.Contains(message, "Secret kubernetes-gpg") || !strings.Contains(message, "Secret bucket-credentials key "+objectStorageSecretAccessKeyKey) {
+		t.Fatalf("expected the condition message to list the missing references, got %q", message)
+	}
+
+	gpg := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kubernetes-gpg"},
+		Data: map[string][]byte{
+			cabpkv1alpha2.PackageRepositoryGPGKeySecretKey: []byte("secret-key"),
+		},
+	}
+	credentials.Data[objectStorageSecretAccessKeyKey] = []byte("secret")
+	k.Client = fake.NewFakeClientWithScheme(setupScheme(), gpg, credentials)
+	missing, err = k.reconcileReferences(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to reconcile references: %v", err)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("expected no missing references, got %v", missing)
+	}
+	expectCondition(t, config, cabpkv1alpha2.MissingReferenceCondition, corev1.ConditionFalse)
+}
+
+func TestReconcileReferencesToFileContent(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "containerd"},
+		BinaryData: map[string][]byte{"config.toml": []byte("version = 2")},
+	}
+
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.AdditionalUserDataFiles = []cabpkv1alpha2.Files{{
+		Path:        "/etc/containerd/config.toml",
+		ContentFrom: &cabpkv1alpha2.FileSource{ConfigMap: &cabpkv1alpha2.FileSourceKey{Name: "containerd", Key: "config.toml"}},
+	}}
+	config.Spec.OSConditionals = []cabpkv1alpha2.OSConditional{{
+		OSFamily: cabpkv1alpha2.OSFamilyDebian,
+		Files: []cabpkv1alpha2.Files{{
+			Path:        "/etc/containerd/certs.d/registry/ca.crt",
+			ContentFrom: &cabpkv1alpha2.FileSource{Secret: &cabpkv1alpha2.FileSourceKey{Name: "registry", Key: "ca.crt"}},
+		}},
+	}}
+	config.Spec.Multipart = &cabpkv1alpha2.Multipart{Parts: []cabpkv1alpha2.UserDataPart{{
+		ContentType: "text/x-shellscript",
+		ContentFrom: &cabpkv1alpha2.FileSource{Secret: &cabpkv1alpha2.FileSourceKey{Name: "proxy", Key: "proxy.sh"}},
+	}}}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), configMap),
+	}
+	missing, err := k.reconcileReferences(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to reconcile references: %v", err)
+	}
+	if len(missing) != 2 || missing[0] != "Secret registry" || missing[1] != "Secret proxy" {
+		t.Fatalf("expected only the registry and proxy secrets to be missing, got %v", missing)
+	}
+}
+
+func TestReconcileReferencesWithoutReferences(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme()),
+	}
+	missing, err := k.reconcileReferences(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to reconcile references: %v", err)
+	}
+	if len(missing) != 0 || len(config.Status.Conditions) != 0 {
+		t.Fatalf("expected no missing references nor conditions, got %v and %v", missing, config.Status.Conditions)
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+)
+
+// resolveRegistryCredentials returns the usernames and passwords of the registry credentials of the config, read
+// from their Secret in the config namespace.
+func (r *KubeadmConfigReconciler) resolveRegistryCredentials(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) ([]cloudinit.RegistryCredential, error) {
+	credentials := make([]cloudinit.RegistryCredential, 0, len(config.Spec.RegistryCredentials))
+	for _, c := range config.Spec.RegistryCredentials {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{Name: c.SecretName, Namespace: config.GetNamespace()}, secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to get the credentials secret %q of registry %q", c.SecretName, c.Host)
+		}
+		credential := cloudinit.RegistryCredential{Host: c.Host}
+		for key, value := range map[string]*string{
+			corev1.BasicAuthUsernameKey: &credential.Username,
+			corev1.BasicAuthPasswordKey: &credential.Password,
+		} {
+			data, ok := secret.Data[key]
+			if !ok {
+				return nil, errors.Errorf("secret %q has no %s key for registry %q", c.SecretName, key, c.Host)
+			}
+			*value = string(data)
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
+}
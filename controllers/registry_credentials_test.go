@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestResolveRegistryCredentials(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "registry"},
+		Type:       corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte("puller"),
+			corev1.BasicAuthPasswordKey: []byte("secret"),
+		},
+	}
+
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.RegistryCredentials = []cabpkv1alpha2.RegistryCredentials{
+		{Host: "registry.example.com:5000", SecretName: "registry"},
+	}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), secret),
+	}
+	credentials, err := k.resolveRegistryCredentials(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to resolve registry credentials: %v", err)
+	}
+	expected := cloudinit.RegistryCredential{Host: "registry.example.com:5000", Username: "puller", Password: "secret"}
+	if len(credentials) != 1 || credentials[0] != expected {
+		t.Fatalf("expected %+v, got %+v", expected, credentials)
+	}
+
+	delete(secret.Data, corev1.BasicAuthPasswordKey)
+	k.Client = fake.NewFakeClientWithScheme(setupScheme(), secret)
+	if _, err := k.resolveRegistryCredentials(context.Background(), config); err == nil {
+		t.Fatal("expected an error for a missing password, got nil")
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	kubeadmv1beta1 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	capierrors "sigs.k8s.io/cluster-api/pkg/errors"
+)
+
+const (
+	// retainedIdentityKubeconfig is the name of the discovery kubeconfig, in the generated files directory, holding
+	// the retained kubelet client certificate.
+	retainedIdentityKubeconfig = "kubelet-identity.conf"
+
+	// retainedIdentityValidity is how long the retained kubelet client certificates are valid for.
+	retainedIdentityValidity = 365 * 24 * time.Hour
+
+	// retainedIdentityRenewBefore is how long before it expires a retained kubelet client certificate is reissued.
+	retainedIdentityRenewBefore = 30 * 24 * time.Hour
+)
+
+// RetainedIdentitySecretName returns the name of the secret holding the kubelet client certificate retained for
+// the named node of a cluster.
+func RetainedIdentitySecretName(clusterName, nodeName string) string {
+	return fmt.Sprintf("%s-kubelet-%s", clusterName, nodeName)
+}
+
+// reconcileRetainedIdentity sets the JoinConfiguration of config to discover the cluster, and bootstrap the
+// kubelet, with the kubelet client certificate retained for the RetainedIdentity node, if set. kubeadm uses the
+// credentials of a discovery kubeconfig for the TLS bootstrap, so that no bootstrap token is needed. It returns the
+// discovery kubeconfig file, which is only written in the bootstrap data.
+func (r *KubeadmConfigReconciler) reconcileRetainedIdentity(ctx context.Context, cluster *capiv1alpha2.Cluster, config *cabpkv1alpha2.KubeadmConfig) ([]cabpkv1alpha2.Files, error) {
+	identity := config.Spec.RetainedIdentity
+	if identity == nil {
+		return nil, nil
+	}
+
+	apiServerEndpoint := config.Spec.DiscoveryEndpoint
+	if apiServerEndpoint == "" {
+		if len(cluster.Status.APIEndpoints) == 0 {
+			return nil, errors.Wrap(&capierrors.RequeueAfterError{RequeueAfter: 10 * time.Second}, "Waiting for Cluster Controller to set cluster.Status.APIEndpoints")
+		}
+		apiServerEndpoint = fmt.Sprintf("%s:%d", cluster.Status.APIEndpoints[0].Host, cluster.Status.APIEndpoints[0].Port)
+	}
+
+	certificates, err := r.getClusterCertificates(ctx, cluster.GetName(), config.GetNamespace())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the cluster certificates")
+	}
+	if certificates.ExternalClusterCA() {
+		return nil, errors.New("a retained identity cannot be signed by an external cluster CA")
+	}
+	kp, err := r.retainedIdentity(ctx, cluster, config.GetNamespace(), identity.NodeName, certificates.ClusterCA)
+	if err != nil {
+		return nil, err
+	}
+
+	user := "system:node:" + identity.NodeName
+	kubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			cluster.GetName(): {
+				Server:                   "https://" + apiServerEndpoint,
+				CertificateAuthorityData: certificates.ClusterCA.TrustBundle(),
+			},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			user: {
+				ClientCertificateData: kp.Cert,
+				ClientKeyData:         kp.Key,
+			},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			user: {
+				Cluster:  cluster.GetName(),
+				AuthInfo: user,
+			},
+		},
+		CurrentContext: user,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to write the kubelet identity kubeconfig")
+	}
+
+	generatedFilesDir := config.Spec.GeneratedFilesDir
+	if generatedFilesDir == "" {
+		generatedFilesDir = cabpkv1alpha2.DefaultGeneratedFilesDir
+	}
+	kubeconfigPath := path.Join(generatedFilesDir, retainedIdentityKubeconfig)
+
+	join := config.Spec.JoinConfiguration
+	join.Discovery.BootstrapToken = nil
+	join.Discovery.TLSBootstrapToken = ""
+	join.Discovery.File = &kubeadmv1beta1.FileDiscovery{KubeConfigPath: kubeconfigPath}
+	if join.NodeRegistration.Name == "" {
+		join.NodeRegistration.Name = identity.NodeName
+	}
+
+	return []cabpkv1alpha2.Files{{
+		Path:        kubeconfigPath,
+		Owner:       "root:root",
+		Permissions: "0600",
+		Content:     string(kubeconfig),
+	}}, nil
+}
+
+// retainedIdentity returns the kubelet client certificate retained for the named node of cluster. It is issued by
+// the given CA, and stored in a secret owned by the cluster so that it outlives the machines of the node, when
+// missing, expiring, or no longer signed by the CA.
+func (r *KubeadmConfigReconciler) retainedIdentity(ctx context.Context, cluster *capiv1alpha2.Cluster, namespace, nodeName string, ca *certs.KeyPair) (*certs.KeyPair, error) {
+	name := RetainedIdentitySecretName(cluster.GetName(), nodeName)
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to get kubelet identity secret %q", name)
+	}
+	found := err == nil
+	if found {
+		kp := &certs.KeyPair{Cert: secret.Data[corev1.TLSCertKey], Key: secret.Data[corev1.TLSPrivateKeyKey]}
+		if notAfter, err := kp.NotAfter(); err == nil && time.Until(notAfter) > retainedIdentityRenewBefore && kp.SignedBy(ca) {
+			return kp, nil
+		}
+	}
+
+	kp, err := ca.NewClientCertificate(certs.Config{
+		CommonName:   "system:node:" + nodeName,
+		Organization: []string{"system:nodes"},
+	}, retainedIdentityValidity)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to issue the kubelet client certificate of node %q", nodeName)
+	}
+	data := map[string][]byte{
+		corev1.TLSCertKey:       kp.Cert,
+		corev1.TLSPrivateKeyKey: kp.Key,
+	}
+
+	if found {
+		secret.Data = data
+		if err := r.Update(ctx, secret); err != nil {
+			return nil, errors.Wrapf(err, "failed to update kubelet identity secret %q", name)
+		}
+		return kp, nil
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			OwnerReferences: []v1.OwnerReference{
+				{
+					APIVersion: capiv1alpha2.SchemeGroupVersion.String(),
+					Kind:       "Cluster",
+					Name:       cluster.GetName(),
+					UID:        cluster.GetUID(),
+				},
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: data,
+	}
+	if err := r.Create(ctx, secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to create kubelet identity secret %q", name)
+	}
+	return kp, nil
+}
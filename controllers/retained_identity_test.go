@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestReconcileRetainedIdentity(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.InfrastructureReady = true
+	cluster.Annotations = map[string]string{ControlPlaneReadyAnnotationKey: "true"}
+	cluster.Status.APIEndpoints = []capiv1alpha2.APIEndpoint{{Host: "100.105.150.1", Port: 6443}}
+
+	workerMachine := newWorkerMachine(cluster, "worker-machine")
+	workerJoinConfig := newWorkerJoinKubeadmConfig(workerMachine, "worker-join-cfg")
+	workerJoinConfig.Spec.RetainedIdentity = &cabpkv1alpha2.RetainedIdentity{NodeName: "worker-0"}
+	reimagedMachine := newWorkerMachine(cluster, "reimaged-machine")
+	reimagedJoinConfig := newWorkerJoinKubeadmConfig(reimagedMachine, "reimaged-join-cfg")
+	reimagedJoinConfig.Spec.RetainedIdentity = &cabpkv1alpha2.RetainedIdentity{NodeName: "worker-0"}
+
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), cluster, workerMachine, workerJoinConfig, reimagedMachine, reimagedJoinConfig)
+	certificates, _ := certs.NewCertificates()
+	_ = myclient.Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ClusterCertificatesSecretName(cluster.GetName()),
+			Namespace: workerJoinConfig.GetNamespace(),
+		},
+		Data: certificates.ToMap(),
+	})
+
+	k := &KubeadmConfigReconciler{
+		Log:                  log.Log,
+		Client:               myclient,
+		SecretsClientFactory: newFakeSecretFactory(),
+	}
+	identity := func(configName string) []byte {
+		request := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: configName}}
+		if _, err := k.Reconcile(request); err != nil {
+			t.Fatalf("Failed to reconcile:\n %+v", err)
+		}
+		cfg, err := getKubeadmConfig(myclient, configName)
+		if err != nil {
+			t.Fatalf("Failed to get the config:\n %+v", err)
+		}
+		discovery := cfg.Spec.JoinConfiguration.Discovery
+		if discovery.BootstrapToken != nil || discovery.File == nil || discovery.File.KubeConfigPath != "/run/kubeadm/kubelet-identity.conf" {
+			t.Fatalf("expected the discovery of the retained identity kubeconfig, got %+v", discovery)
+		}
+		if name := cfg.Spec.JoinConfiguration.NodeRegistration.Name; name != "worker-0" {
+			t.Fatalf("expected the node to be registered as worker-0, got %q", name)
+		}
+
+		secret := &corev1.Secret{}
+		key := types.NamespacedName{Namespace: "default", Name: RetainedIdentitySecretName(cluster.GetName(), "worker-0")}
+		if err := myclient.Get(context.Background(), key, secret); err != nil {
+			t.Fatalf("Failed to get the kubelet identity secret:\n %+v", err)
+		}
+		kp := &certs.KeyPair{Cert: secret.Data[corev1.TLSCertKey], Key: secret.Data[corev1.TLSPrivateKeyKey]}
+		if !kp.SignedBy(certificates.ClusterCA) {
+			t.Fatal("expected the kubelet client certificate to be signed by the cluster CA")
+		}
+		return kp.Cert
+	}
+
+	// the re-imaged machine rejoins with the certificate issued for the first one
+	if cert := identity("worker-join-cfg"); !bytes.Equal(cert, identity("reimaged-join-cfg")) {
+		t.Fatal("expected the kubelet client certificate to be retained")
+	}
+}
+
+func TestRetainedIdentityReissuedForRotatedCA(t *testing.T) {
+	cluster := newCluster("cluster")
+	previous, _ := certs.NewCertificates()
+	current, _ := certs.NewCertificates()
+	kp, err := previous.ClusterCA.NewClientCertificate(certs.Config{CommonName: "system:node:worker-0"}, retainedIdentityValidity)
+	if err != nil {
+		t.Fatalf("Failed to issue the client certificate:\n %+v", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: RetainedIdentitySecretName(cluster.GetName(), "worker-0")},
+		Data:       map[string][]byte{corev1.TLSCertKey: kp.Cert, corev1.TLSPrivateKeyKey: kp.Key},
+	}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), secret),
+	}
+	reissued, err := k.retainedIdentity(context.Background(), cluster, "default", "worker-0", current.ClusterCA)
+	if err != nil {
+		t.Fatalf("Failed to get the retained identity:\n %+v", err)
+	}
+	if !reissued.SignedBy(current.ClusterCA) {
+		t.Fatal("expected the kubelet client certificate to be reissued by the current cluster CA")
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// startupTaintScript is the name of the script, in the generated files directory, verifying the bootstrap of
+	// the machine and removing the startup taint of its node.
+	startupTaintScript = "remove-startup-taint.sh"
+
+	// kubeletKubeconfig is the kubeconfig kubeadm writes for the kubelet, authorized to update the node.
+	kubeletKubeconfig = "/etc/kubernetes/kubelet.conf"
+
+	// defaultControlPlaneTaintKey is the key of the taint kubeadm registers control plane nodes with when the node
+	// registration omits the taints.
+	defaultControlPlaneTaintKey = "node-role.kubernetes.io/master"
+)
+
+// startupTaintKey returns the key of the startup taint of config.
+func startupTaintKey(config *cabpkv1alpha2.KubeadmConfig) string {
+	if config.Spec.StartupTaint.Key != "" {
+		return config.Spec.StartupTaint.Key
+	}
+	return cabpkv1alpha2.DefaultStartupTaintKey
+}
+
+// setStartupTaint returns data, the YAML of the kubeadm configuration document registering a node, with the
+// StartupTaint of config added to the node registration taints, if set. kubeadm only taints control plane nodes
+// when the taints are omitted, so that taint is kept along for them.
+func setStartupTaint(config *cabpkv1alpha2.KubeadmConfig, kind, data string, controlPlane bool) (string, error) {
+	if config.Spec.StartupTaint == nil {
+		return data, nil
+	}
+
+	doc := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return "", errors.Wrapf(err, "failed to unmarshal %s", kind)
+	}
+	registration, ok := doc["nodeRegistration"].(map[string]interface{})
+	if !ok {
+		registration = map[string]interface{}{}
+		doc["nodeRegistration"] = registration
+	}
+	taints, ok := registration["taints"].([]interface{})
+	if !ok && controlPlane {
+		taints = []interface{}{map[string]interface{}{"key": defaultControlPlaneTaintKey, "effect": string(corev1.TaintEffectNoSchedule)}}
+	}
+	registration["taints"] = append(taints, map[string]interface{}{"key": startupTaintKey(config), "effect": string(corev1.TaintEffectNoSchedule)})
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal %s", kind)
+	}
+	return string(out), nil
+}
+
+// startupTaintRemoval returns the script removing the startup taint of config from the named node once the
+// verification commands succeed, and the command running it after the PostKubeadmCommands. The node is named after
+// the host, as by kubeadm, if nodeName is empty.
+func startupTaintRemoval(config *cabpkv1alpha2.KubeadmConfig, nodeName string) ([]cabpkv1alpha2.Files, []string) {
+	if config.Spec.StartupTaint == nil {
+		return nil, nil
+	}
+
+	if nodeName == "" {
+		nodeName = "$(hostname | tr '[:upper:]' '[:lower:]')"
+	}
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\nset -e\n")
+	for _, command := range config.Spec.StartupTaint.VerificationCommands {
+		script.WriteString(command + "\n")
+	}
+	fmt.Fprintf(&script, "kubectl --kubeconfig=%s taint node \"%s\" %s:%s-\n",
+		kubeletKubeconfig, nodeName, startupTaintKey(config), corev1.TaintEffectNoSchedule)
+
+	generatedFilesDir := config.Spec.GeneratedFilesDir
+	if generatedFilesDir == "" {
+		generatedFilesDir = cabpkv1alpha2.DefaultGeneratedFilesDir
+	}
+	scriptPath := path.Join(generatedFilesDir, startupTaintScript)
+	files := []cabpkv1alpha2.Files{{
+		Path:        scriptPath,
+		Owner:       "root:root",
+		Permissions: "0700",
+		Content:     script.String(),
+	}}
+	return files, []string{scriptPath}
+}
+
+// postKubeadmCommands returns the PostKubeadmCommands of config, followed by the given commands.
+func postKubeadmCommands(config *cabpkv1alpha2.KubeadmConfig, commands []string) []string {
+	return append(append([]string{}, config.Spec.PostKubeadmCommands...), commands...)
+}
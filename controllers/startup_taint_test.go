@@ -0,0 +1,138 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+func TestSetStartupTaint(t *testing.T) {
+	joinConfiguration := `apiVersion: kubeadm.k8s.io/v1beta1
+kind: JoinConfiguration
+nodeRegistration:
+  name: node
+`
+
+	testcases := []struct {
+		name         string
+		data         string
+		controlPlane bool
+		expected     string
+	}{
+		{
+			name: "worker",
+			data: joinConfiguration,
+			expected: `apiVersion: kubeadm.k8s.io/v1beta1
+kind: JoinConfiguration
+nodeRegistration:
+  name: node
+  taints:
+  - effect: NoSchedule
+    key: node.cluster.x-k8s.io/uninitialized
+`,
+		},
+		{
+			name:         "control plane with the default taints",
+			data:         joinConfiguration,
+			controlPlane: true,
+			expected: `apiVersion: kubeadm.k8s.io/v1beta1
+kind: JoinConfiguration
+nodeRegistration:
+  name: node
+  taints:
+  - effect: NoSchedule
+    key: node-role.kubernetes.io/master
+  - effect: NoSchedule
+    key: node.cluster.x-k8s.io/uninitialized
+`,
+		},
+		{
+			name:         "untainted control plane",
+			data:         joinConfiguration + "  taints: []\n",
+			controlPlane: true,
+			expected: `apiVersion: kubeadm.k8s.io/v1beta1
+kind: JoinConfiguration
+nodeRegistration:
+  name: node
+  taints:
+  - effect: NoSchedule
+    key: node.cluster.x-k8s.io/uninitialized
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := newKubeadmConfig(nil, "cfg")
+			config.Spec.StartupTaint = &cabpkv1alpha2.StartupTaint{}
+
+			data, err := setStartupTaint(config, "JoinConfiguration", tc.data, tc.controlPlane)
+			if err != nil {
+				t.Fatalf("failed to set the startup taint: %v", err)
+			}
+			if data != tc.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", tc.expected, data)
+			}
+		})
+	}
+}
+
+func TestSetStartupTaintWithoutStartupTaint(t *testing.T) {
+	data := "apiVersion: kubeadm.k8s.io/v1beta1\nkind: JoinConfiguration\n"
+	out, err := setStartupTaint(newKubeadmConfig(nil, "cfg"), "JoinConfiguration", data, false)
+	if err != nil {
+		t.Fatalf("failed to set the startup taint: %v", err)
+	}
+	if out != data {
+		t.Fatalf("expected the join configuration to be unchanged, got\n%s", out)
+	}
+}
+
+func TestStartupTaintRemoval(t *testing.T) {
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.PostKubeadmCommands = []string{"/usr/local/bin/register-node"}
+	config.Spec.StartupTaint = &cabpkv1alpha2.StartupTaint{
+		Key:                  "example.com/unverified",
+		VerificationCommands: []string{"systemctl is-active kubelet"},
+	}
+
+	files, commands := startupTaintRemoval(config, "")
+	if len(files) != 1 || files[0].Path != "/run/kubeadm/remove-startup-taint.sh" {
+		t.Fatalf("expected the removal script in the generated files directory, got %v", files)
+	}
+	expected := `#!/bin/sh
+set -e
+systemctl is-active kubelet
+kubectl --kubeconfig=/etc/kubernetes/kubelet.conf taint node "$(hostname | tr '[:upper:]' '[:lower:]')" example.com/unverified:NoSchedule-
+`
+	if files[0].Content != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, files[0].Content)
+	}
+
+	commands = postKubeadmCommands(config, commands)
+	if len(commands) != 2 || commands[1] != files[0].Path {
+		t.Fatalf("expected the removal script to run after the post kubeadm commands, got %v", commands)
+	}
+
+	files, _ = startupTaintRemoval(config, "node-1")
+	if !strings.Contains(files[0].Content, `taint node "node-1" `) {
+		t.Fatalf("expected the taint to be removed from node-1, got\n%s", files[0].Content)
+	}
+}
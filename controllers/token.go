@@ -17,43 +17,50 @@ limitations under the License.
 package controllers
 
 import (
+	"crypto/subtle"
 	"time"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
 	bootstraputil "k8s.io/cluster-bootstrap/token/util"
 	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
-	capiremote "sigs.k8s.io/cluster-api/pkg/controller/remote"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	defaultTokenTTL = 10 * time.Minute
+
+	// tokenDescription is the description of the bootstrap tokens the controller generates.
+	tokenDescription = "token generated by cluster-api-bootstrap-provider-kubeadm"
 )
 
 // ClusterSecretsClientFactory support creation of secrets client for clusters
-type ClusterSecretsClientFactory struct{}
+type ClusterSecretsClientFactory struct {
+	// Options tune the clients of the workload clusters.
+	Options WorkloadClusterClientOptions
+}
 
 // NewSecretsClient returns a new client supporting SecretInterface for the cluster
 func (f ClusterSecretsClientFactory) NewSecretsClient(client client.Client, cluster *capiv1alpha2.Cluster) (corev1.SecretInterface, error) {
-	remoteClient, err := capiremote.NewClusterClient(client, cluster)
+	config, err := f.Options.RESTConfig(client, cluster)
 	if err != nil {
 		return nil, err
 	}
 
-	corev1Client, err := remoteClient.CoreV1()
+	corev1Client, err := corev1.NewForConfig(config)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "failed to create the client of the workload cluster")
 	}
 
 	return corev1Client.Secrets(metav1.NamespaceSystem), nil
 }
 
-// createToken attempts to create a token with the given ID.
-func createToken(client corev1.SecretInterface) (string, error) {
+// createToken attempts to create a token valid for the given ttl.
+func createToken(client corev1.SecretInterface, ttl time.Duration) (string, error) {
 	token, err := bootstraputil.GenerateBootstrapToken()
 	if err != nil {
 		return "", errors.Wrap(err, "unable to generate bootstrap token")
@@ -76,11 +83,11 @@ func createToken(client corev1.SecretInterface) (string, error) {
 		Data: map[string][]byte{
 			bootstrapapi.BootstrapTokenIDKey:               []byte(tokenID),
 			bootstrapapi.BootstrapTokenSecretKey:           []byte(tokenSecret),
-			bootstrapapi.BootstrapTokenExpirationKey:       []byte(time.Now().UTC().Add(defaultTokenTTL).Format(time.RFC3339)),
+			bootstrapapi.BootstrapTokenExpirationKey:       []byte(time.Now().UTC().Add(ttl).Format(time.RFC3339)),
 			bootstrapapi.BootstrapTokenUsageSigningKey:     []byte("true"),
 			bootstrapapi.BootstrapTokenUsageAuthentication: []byte("true"),
 			bootstrapapi.BootstrapTokenExtraGroupsKey:      []byte("system:bootstrappers:kubeadm:default-node-token"),
-			bootstrapapi.BootstrapTokenDescriptionKey:      []byte("token generated by cluster-api-bootstrap-provider-kubeadm"),
+			bootstrapapi.BootstrapTokenDescriptionKey:      []byte(tokenDescription),
 		},
 	}
 
@@ -89,3 +96,88 @@ func createToken(client corev1.SecretInterface) (string, error) {
 	}
 	return token, nil
 }
+
+// refreshToken extends the expiration of a bootstrap token generated by the controller to ttl from now, once it
+// expires within half the ttl. The token itself is kept, so the bootstrap data joining with it stays valid. It
+// returns how long until the token is due to be refreshed again, 0 if the token is not one the controller
+// generated, and whether its expiration was extended.
+func refreshToken(client corev1.SecretInterface, token string, ttl time.Duration) (time.Duration, bool, error) {
+	secret, err := generatedTokenSecret(client, token)
+	if err != nil || secret == nil {
+		return 0, false, err
+	}
+
+	now := time.Now().UTC()
+	expiration, err := time.Parse(time.RFC3339, string(secret.Data[bootstrapapi.BootstrapTokenExpirationKey]))
+	if err == nil && expiration.Sub(now) > ttl/2 {
+		return expiration.Sub(now) - ttl/2, false, nil
+	}
+
+	secret.Data[bootstrapapi.BootstrapTokenExpirationKey] = []byte(now.Add(ttl).Format(time.RFC3339))
+	if _, err := client.Update(secret); err != nil {
+		return 0, false, err
+	}
+	return ttl / 2, true, nil
+}
+
+// tokenValid reports whether the bootstrap token exists in the workload cluster and has not expired, whether or
+// not the controller generated it.
+func tokenValid(client corev1.SecretInterface, token string) (bool, error) {
+	substrs := bootstraputil.BootstrapTokenRegexp.FindStringSubmatch(token)
+	if len(substrs) != 3 {
+		return false, nil
+	}
+
+	secret, err := client.Get(bootstraputil.BootstrapTokenSecretName(substrs[1]), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if subtle.ConstantTimeCompare(secret.Data[bootstrapapi.BootstrapTokenSecretKey], []byte(substrs[2])) != 1 {
+		return false, nil
+	}
+	rawExpiration := string(secret.Data[bootstrapapi.BootstrapTokenExpirationKey])
+	if rawExpiration == "" {
+		return true, nil
+	}
+	expiration, err := time.Parse(time.RFC3339, rawExpiration)
+	if err != nil {
+		return false, nil
+	}
+	return time.Now().Before(expiration), nil
+}
+
+// revokeToken deletes the secret of a bootstrap token generated by the controller, so that it can no longer be
+// used to join the cluster. Tokens already deleted, or not generated by the controller, are left as is.
+func revokeToken(client corev1.SecretInterface, token string) error {
+	secret, err := generatedTokenSecret(client, token)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil || secret == nil {
+		return err
+	}
+	if err := client.Delete(secret.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// generatedTokenSecret returns the secret of the bootstrap token, or nil if the controller did not generate it.
+func generatedTokenSecret(client corev1.SecretInterface, token string) (*v1.Secret, error) {
+	substrs := bootstraputil.BootstrapTokenRegexp.FindStringSubmatch(token)
+	if len(substrs) != 3 {
+		return nil, errors.Errorf("the bootstrap token was not of the form %q", bootstrapapi.BootstrapTokenPattern)
+	}
+
+	secret, err := client.Get(bootstraputil.BootstrapTokenSecretName(substrs[1]), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if string(secret.Data[bootstrapapi.BootstrapTokenDescriptionKey]) != tokenDescription {
+		return nil, nil
+	}
+	return secret, nil
+}
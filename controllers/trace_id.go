@@ -0,0 +1,42 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// traceIDBytes is the number of random bytes of a trace ID, rendered as hex.
+const traceIDBytes = 16
+
+// reconcileTraceID generates the trace ID of the bootstrap of the config, once, so that the controller logs and
+// the logs of the machine can be correlated.
+func reconcileTraceID(config *cabpkv1alpha2.KubeadmConfig) error {
+	if config.Status.TraceID != "" {
+		return nil
+	}
+	b := make([]byte, traceIDBytes)
+	if _, err := rand.Read(b); err != nil {
+		return errors.Wrap(err, "failed to generate the trace ID")
+	}
+	config.Status.TraceID = hex.EncodeToString(b)
+	return nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"regexp"
+	"testing"
+
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+func TestReconcileTraceID(t *testing.T) {
+	config := &cabpkv1alpha2.KubeadmConfig{}
+	if err := reconcileTraceID(config); err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	traceID := config.Status.TraceID
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(traceID) {
+		t.Fatalf("expected a 32 hex digits trace ID, got %q", traceID)
+	}
+
+	if err := reconcileTraceID(config); err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	if config.Status.TraceID != traceID {
+		t.Fatalf("expected the trace ID %q to be kept, got %q", traceID, config.Status.TraceID)
+	}
+
+	other := &cabpkv1alpha2.KubeadmConfig{}
+	if err := reconcileTraceID(other); err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	if other.Status.TraceID == traceID {
+		t.Fatalf("expected configs to get distinct trace IDs, got %q twice", traceID)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+// resolveTrustedCABundles returns the PEM encoded CA certificates of the trusted CA bundles of the config. Only
+// the certificates of each bundle are kept, so that a private key stored alongside them never reaches the machine.
+func (r *KubeadmConfigReconciler) resolveTrustedCABundles(ctx context.Context, config *cabpkv1alpha2.KubeadmConfig) ([]string, error) {
+	bundles := make([]string, 0, len(config.Spec.TrustedCABundles))
+	for i := range config.Spec.TrustedCABundles {
+		content, err := r.fileContent(ctx, config.GetNamespace(), &config.Spec.TrustedCABundles[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve trusted CA bundle %d", i)
+		}
+		bundle, err := certificatesPEM([]byte(content))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid trusted CA bundle %d", i)
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, nil
+}
+
+// certificatesPEM returns the CERTIFICATE blocks of the PEM encoded data, or an error if it holds none.
+func certificatesPEM(data []byte) (string, error) {
+	var out []byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			out = append(out, pem.EncodeToMemory(block)...)
+		}
+	}
+	if len(out) == 0 {
+		return "", errors.New("no PEM encoded certificate found")
+	}
+	return string(out), nil
+}
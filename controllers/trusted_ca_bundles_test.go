@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/pem"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestResolveTrustedCABundles(t *testing.T) {
+	registryCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("registry-ca")}))
+	proxyCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("proxy-ca")}))
+	key := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("key")}))
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "registry"},
+		Data:       map[string][]byte{"tls.crt": []byte(registryCA + key)},
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "proxy"},
+		Data:       map[string]string{"ca-bundle.pem": proxyCA, "README": "not a certificate"},
+	}
+
+	config := newKubeadmConfig(nil, "cfg")
+	config.Spec.TrustedCABundles = []cabpkv1alpha2.FileSource{
+		{Secret: &cabpkv1alpha2.FileSourceKey{Name: "registry", Key: "tls.crt"}},
+		{ConfigMap: &cabpkv1alpha2.FileSourceKey{Name: "proxy", Key: "ca-bundle.pem"}},
+	}
+
+	k := &KubeadmConfigReconciler{
+		Log:    log.Log,
+		Client: fake.NewFakeClientWithScheme(setupScheme(), secret, configMap),
+	}
+	bundles, err := k.resolveTrustedCABundles(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to resolve the trusted CA bundles: %v", err)
+	}
+	if len(bundles) != 2 || bundles[0] != registryCA || bundles[1] != proxyCA {
+		t.Fatalf("expected the certificates of the bundles only, got %q", bundles)
+	}
+
+	config.Spec.TrustedCABundles[1].ConfigMap.Key = "README"
+	if _, err := k.resolveTrustedCABundles(context.Background(), config); err == nil {
+		t.Fatal("expected an error for a bundle without certificates, got nil")
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/yaml"
+)
+
+// resolveVariant applies to the config spec the variant named by the VariantLabel of the owning machine, then
+// clears the variants, so that the persisted spec is the one the bootstrap data is generated from.
+func resolveVariant(config *cabpkv1alpha2.KubeadmConfig, machine *capiv1alpha2.Machine) error {
+	if len(config.Spec.Variants) == 0 {
+		return nil
+	}
+
+	label := config.Spec.VariantLabel
+	if label == "" {
+		label = cabpkv1alpha2.DefaultVariantLabel
+	}
+
+	name, ok := machine.Labels[label]
+	if !ok {
+		config.Spec.Variants = nil
+		return nil
+	}
+
+	for _, variant := range config.Spec.Variants {
+		if variant.Name != name {
+			continue
+		}
+
+		spec := config.Spec.DeepCopy()
+		spec.Variants = nil
+		doc, err := json.Marshal(spec)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal KubeadmConfigSpec")
+		}
+		patch, err := yaml.YAMLToJSON([]byte(variant.Patch))
+		if err != nil {
+			return errors.Wrapf(err, "failed to convert the patch of variant %q to JSON", name)
+		}
+		patched, err := jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return errors.Wrapf(err, "failed to apply variant %q", name)
+		}
+
+		resolved := cabpkv1alpha2.KubeadmConfigSpec{}
+		if err := json.Unmarshal(patched, &resolved); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal KubeadmConfigSpec patched by variant %q", name)
+		}
+		resolved.Variants = nil
+		config.Spec = resolved
+		return nil
+	}
+
+	return newBootstrapFailure(invalidConfigurationReason, "Machine %s/%s selects variant %q with label %s, but the KubeadmConfig has no such variant",
+		machine.Namespace, machine.Name, name, label)
+}
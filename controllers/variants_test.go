@@ -0,0 +1,117 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+)
+
+func newVariantsKubeadmConfig(variantLabel string) *cabpkv1alpha2.KubeadmConfig {
+	return &cabpkv1alpha2.KubeadmConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "cfg", Namespace: "default"},
+		Spec: cabpkv1alpha2.KubeadmConfigSpec{
+			GeneratedFilesDir: "/run/kubeadm",
+			AdditionalUserDataFiles: []cabpkv1alpha2.Files{
+				{Path: "/etc/example", Content: "default"},
+			},
+			VariantLabel: variantLabel,
+			Variants: []cabpkv1alpha2.KubeadmConfigVariant{
+				{
+					Name:  "flatcar",
+					Patch: "generatedFilesDir: /var/lib/kubeadm\nosConditionals:\n- osFamily: flatcar\n  preKubeadmCommands: [\"systemctl enable containerd\"]",
+				},
+				{
+					Name:  "no-files",
+					Patch: `{"additionalUserDataFiles": null}`,
+				},
+			},
+		},
+	}
+}
+
+func newVariantMachine(labels map[string]string) *capiv1alpha2.Machine {
+	return &capiv1alpha2.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "machine", Namespace: "default", Labels: labels},
+	}
+}
+
+func TestResolveVariant(t *testing.T) {
+	config := newVariantsKubeadmConfig("")
+	machine := newVariantMachine(map[string]string{cabpkv1alpha2.DefaultVariantLabel: "flatcar"})
+
+	if err := resolveVariant(config, machine); err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	if config.Spec.Variants != nil {
+		t.Fatalf("expected the variants to be cleared, got %v", config.Spec.Variants)
+	}
+	if config.Spec.GeneratedFilesDir != "/var/lib/kubeadm" {
+		t.Fatalf("expected the variant generatedFilesDir, got %q", config.Spec.GeneratedFilesDir)
+	}
+	if len(config.Spec.OSConditionals) != 1 || config.Spec.OSConditionals[0].OSFamily != cabpkv1alpha2.OSFamilyFlatcar {
+		t.Fatalf("expected the variant osConditionals, got %v", config.Spec.OSConditionals)
+	}
+	if len(config.Spec.AdditionalUserDataFiles) != 1 {
+		t.Fatalf("expected the fields not in the variant to be kept, got %v", config.Spec.AdditionalUserDataFiles)
+	}
+}
+
+func TestResolveVariantCustomLabel(t *testing.T) {
+	config := newVariantsKubeadmConfig("example.com/os")
+	machine := newVariantMachine(map[string]string{"example.com/os": "no-files"})
+
+	if err := resolveVariant(config, machine); err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	if config.Spec.AdditionalUserDataFiles != nil {
+		t.Fatalf("expected the files to be removed by the variant, got %v", config.Spec.AdditionalUserDataFiles)
+	}
+	if config.Spec.VariantLabel != "example.com/os" {
+		t.Fatalf("expected the variant label to be kept, got %q", config.Spec.VariantLabel)
+	}
+}
+
+func TestResolveVariantWithoutLabel(t *testing.T) {
+	config := newVariantsKubeadmConfig("")
+	machine := newVariantMachine(nil)
+
+	if err := resolveVariant(config, machine); err != nil {
+		t.Fatalf("expected nil, got error %v", err)
+	}
+	if config.Spec.Variants != nil {
+		t.Fatalf("expected the variants to be cleared, got %v", config.Spec.Variants)
+	}
+	if config.Spec.GeneratedFilesDir != "/run/kubeadm" {
+		t.Fatalf("expected the spec to be unchanged, got generatedFilesDir %q", config.Spec.GeneratedFilesDir)
+	}
+}
+
+func TestResolveVariantUnknown(t *testing.T) {
+	config := newVariantsKubeadmConfig("")
+	machine := newVariantMachine(map[string]string{cabpkv1alpha2.DefaultVariantLabel: "windows"})
+
+	if err := resolveVariant(config, machine); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(config.Spec.Variants) != 2 {
+		t.Fatalf("expected the variants to be kept, got %v", config.Spec.Variants)
+	}
+}
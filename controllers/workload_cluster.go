@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	capierrors "sigs.k8s.io/cluster-api/pkg/errors"
+)
+
+const (
+	workloadClusterInitialBackoff = 10 * time.Second
+	workloadClusterMaxBackoff     = 5 * time.Minute
+)
+
+// isWorkloadClusterUnreachable returns true if err is a failure to reach the workload cluster, or to be
+// authenticated by it, rather than an error returned by its API server for the request.
+func isWorkloadClusterUnreachable(err error) bool {
+	if _, ok := errors.Cause(err).(apierrors.APIStatus); !ok {
+		return true
+	}
+	return apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) || apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) || apierrors.IsUnauthorized(err)
+}
+
+// workloadClusterBackoff returns how long to wait before reaching the workload cluster again, after the given
+// number of consecutive failures.
+func workloadClusterBackoff(failures int32) time.Duration {
+	backoff := workloadClusterInitialBackoff
+	for i := int32(1); i < failures && backoff < workloadClusterMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > workloadClusterMaxBackoff {
+		return workloadClusterMaxBackoff
+	}
+	return backoff
+}
+
+// workloadClusterUnreachable records a failed attempt to reach the workload cluster in the WorkloadClusterUnreachable
+// condition of the config, and returns a RequeueAfterError backing off with the consecutive failures.
+func workloadClusterUnreachable(config *cabpkv1alpha2.KubeadmConfig, reason string, err error) error {
+	config.Status.WorkloadClusterFailures++
+	setCondition(&config.Status, cabpkv1alpha2.KubeadmConfigCondition{
+		Type:    cabpkv1alpha2.WorkloadClusterUnreachableCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: err.Error(),
+	}, time.Now())
+
+	backoff := workloadClusterBackoff(config.Status.WorkloadClusterFailures)
+	return errors.Wrapf(&capierrors.RequeueAfterError{RequeueAfter: backoff},
+		"Workload cluster is unreachable after %d attempts, retrying in %s: %v", config.Status.WorkloadClusterFailures, backoff, err)
+}
+
+// workloadClusterReachable clears the failed attempts to reach the workload cluster recorded in the config.
+func workloadClusterReachable(config *cabpkv1alpha2.KubeadmConfig) {
+	if config.Status.WorkloadClusterFailures == 0 {
+		return
+	}
+	config.Status.WorkloadClusterFailures = 0
+	setCondition(&config.Status, cabpkv1alpha2.KubeadmConfigCondition{
+		Type:   cabpkv1alpha2.WorkloadClusterUnreachableCondition,
+		Status: corev1.ConditionFalse,
+		Reason: "WorkloadClusterReachable",
+	}, time.Now())
+}
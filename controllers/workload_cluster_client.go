@@ -0,0 +1,169 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// KubeconfigContextAnnotationKey selects the context of the kubeconfig of a Cluster the controller reaches its
+	// workload cluster with, instead of the current context of the kubeconfig.
+	KubeconfigContextAnnotationKey = "bootstrap.cluster.x-k8s.io/kubeconfig-context"
+
+	// kubeconfigSecretKey is the key of the kubeconfig in the kubeconfig secret of a Cluster.
+	kubeconfigSecretKey = "value"
+
+	// WorkloadClusterCredentialsAnnotationKey names the Secret, in the namespace of a Cluster, holding the client
+	// certificate the controller authenticates to its workload cluster with instead of its kubeconfig, e.g. for API
+	// servers fronted by an authenticating proxy.
+	WorkloadClusterCredentialsAnnotationKey = "bootstrap.cluster.x-k8s.io/workload-cluster-credentials"
+
+	// keys of the workload cluster credentials secret, besides the tls.crt and tls.key client certificate
+	workloadClusterCredentialsServerKey = "server"
+	workloadClusterCredentialsCAKey     = "ca.crt"
+
+	// DefaultWorkloadClusterTimeout is the default timeout of the requests to workload clusters.
+	DefaultWorkloadClusterTimeout = 10 * time.Second
+)
+
+// WorkloadClusterClientOptions tune the clients of workload clusters. Zero values keep the client-go defaults.
+type WorkloadClusterClientOptions struct {
+	// Timeout is the timeout of each request.
+	Timeout time.Duration
+
+	// QPS and Burst rate limit the requests to each workload cluster.
+	QPS   float32
+	Burst int
+
+	// CAFile, if set, is the CA bundle trusted for the API servers of the workload clusters, instead of the CA of
+	// their kubeconfig. Credentials secrets with a CA keep trusting it.
+	CAFile string
+}
+
+// KubeconfigSecretName returns the name of the secret holding the kubeconfig of a cluster.
+func KubeconfigSecretName(clusterName string) string {
+	return fmt.Sprintf("%s-kubeconfig", clusterName)
+}
+
+// RESTConfig returns the configuration of the clients of the workload cluster, out of the credentials secret named
+// by the cluster annotation or else the kubeconfig secret of the cluster.
+func (o WorkloadClusterClientOptions) RESTConfig(c client.Client, cluster *capiv1alpha2.Cluster) (*rest.Config, error) {
+	var config *rest.Config
+	var err error
+	name, credentials := cluster.GetAnnotations()[WorkloadClusterCredentialsAnnotationKey]
+	if credentials {
+		config, err = credentialsRESTConfig(c, cluster, name)
+	} else {
+		config, err = kubeconfigRESTConfig(c, cluster)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Timeout > 0 {
+		config.Timeout = o.Timeout
+	}
+	if o.QPS > 0 {
+		config.QPS = o.QPS
+	}
+	if o.Burst > 0 {
+		config.Burst = o.Burst
+	}
+	// the CA of a credentials secret is specific to its cluster, so it takes precedence
+	if o.CAFile != "" && (!credentials || len(config.TLSClientConfig.CAData) == 0) {
+		config.TLSClientConfig.CAData = nil
+		config.TLSClientConfig.CAFile = o.CAFile
+	}
+	return config, nil
+}
+
+// kubeconfigRESTConfig returns the client configuration of the kubeconfig secret of the cluster.
+func kubeconfigRESTConfig(c client.Client, cluster *capiv1alpha2.Cluster) (*rest.Config, error) {
+	secret, err := getWorkloadClusterSecret(c, cluster, KubeconfigSecretName(cluster.GetName()))
+	if err != nil {
+		return nil, err
+	}
+	data, ok := secret.Data[kubeconfigSecretKey]
+	if !ok {
+		return nil, errors.Errorf("secret %s has no %s key", secret.Name, kubeconfigSecretKey)
+	}
+
+	kubeconfig, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the kubeconfig of cluster %s/%s", cluster.GetNamespace(), cluster.GetName())
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: cluster.GetAnnotations()[KubeconfigContextAnnotationKey]}
+	config, err := clientcmd.NewDefaultClientConfig(*kubeconfig, overrides).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build the client configuration of cluster %s/%s", cluster.GetNamespace(), cluster.GetName())
+	}
+	return config, nil
+}
+
+// credentialsRESTConfig returns the client configuration of the named credentials secret. The server defaults to
+// the API endpoint of the cluster.
+func credentialsRESTConfig(c client.Client, cluster *capiv1alpha2.Cluster, name string) (*rest.Config, error) {
+	secret, err := getWorkloadClusterSecret(c, cluster, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if len(secret.Data[key]) == 0 {
+			return nil, errors.Errorf("workload cluster credentials secret %s has no %s key", name, key)
+		}
+	}
+
+	server := string(secret.Data[workloadClusterCredentialsServerKey])
+	if server == "" {
+		if len(cluster.Status.APIEndpoints) == 0 {
+			return nil, errors.Errorf("workload cluster credentials secret %s has no %s key and cluster %s/%s has no API endpoint",
+				name, workloadClusterCredentialsServerKey, cluster.GetNamespace(), cluster.GetName())
+		}
+		// NB. CABPK only uses the first APIServerEndpoint defined in cluster status if there are multiple defined.
+		server = fmt.Sprintf("https://%s:%d", cluster.Status.APIEndpoints[0].Host, cluster.Status.APIEndpoints[0].Port)
+	}
+
+	return &rest.Config{
+		Host: server,
+		TLSClientConfig: rest.TLSClientConfig{
+			CertData: secret.Data[corev1.TLSCertKey],
+			KeyData:  secret.Data[corev1.TLSPrivateKeyKey],
+			CAData:   secret.Data[workloadClusterCredentialsCAKey],
+		},
+	}, nil
+}
+
+// getWorkloadClusterSecret returns the named secret in the namespace of the cluster.
+func getWorkloadClusterSecret(c client.Client, cluster *capiv1alpha2.Cluster, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: cluster.GetNamespace(), Name: name}
+	if err := c.Get(context.Background(), key, secret); err != nil {
+		return nil, errors.Wrapf(err, "failed to get secret %q of cluster %s/%s", name, cluster.GetNamespace(), cluster.GetName())
+	}
+	return secret, nil
+}
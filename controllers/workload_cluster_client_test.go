@@ -0,0 +1,153 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newKubeconfigSecret(t *testing.T, clusterName string) *corev1.Secret {
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["public"] = &clientcmdapi.Cluster{Server: "https://public.example.com:6443", CertificateAuthorityData: []byte("ca")}
+	kubeconfig.Clusters["internal"] = &clientcmdapi.Cluster{Server: "https://10.0.0.1:6443", CertificateAuthorityData: []byte("ca")}
+	kubeconfig.AuthInfos["admin"] = &clientcmdapi.AuthInfo{Token: "token"}
+	kubeconfig.Contexts["public"] = &clientcmdapi.Context{Cluster: "public", AuthInfo: "admin"}
+	kubeconfig.Contexts["internal"] = &clientcmdapi.Context{Cluster: "internal", AuthInfo: "admin"}
+	kubeconfig.CurrentContext = "public"
+
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      KubeconfigSecretName(clusterName),
+		},
+		Data: map[string][]byte{kubeconfigSecretKey: data},
+	}
+}
+
+func TestWorkloadClusterRESTConfig(t *testing.T) {
+	cluster := newCluster("cluster")
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), newKubeconfigSecret(t, "cluster"))
+
+	config, err := WorkloadClusterClientOptions{}.RESTConfig(myclient, cluster)
+	if err != nil {
+		t.Fatalf("failed to build the client configuration: %v", err)
+	}
+	if config.Host != "https://public.example.com:6443" {
+		t.Fatalf("expected the current context to be used, got host %s", config.Host)
+	}
+	if string(config.TLSClientConfig.CAData) != "ca" {
+		t.Fatalf("expected the CA of the kubeconfig to be trusted, got %q", string(config.TLSClientConfig.CAData))
+	}
+
+	cluster.Annotations = map[string]string{KubeconfigContextAnnotationKey: "internal"}
+	options := WorkloadClusterClientOptions{
+		Timeout: 30 * time.Second,
+		QPS:     50,
+		Burst:   100,
+		CAFile:  "/etc/workload-clusters/ca.crt",
+	}
+	config, err = options.RESTConfig(myclient, cluster)
+	if err != nil {
+		t.Fatalf("failed to build the client configuration: %v", err)
+	}
+	if config.Host != "https://10.0.0.1:6443" {
+		t.Fatalf("expected the annotated context to be used, got host %s", config.Host)
+	}
+	if config.Timeout != 30*time.Second || config.QPS != 50 || config.Burst != 100 {
+		t.Fatalf("expected the options to be applied, got timeout %s, qps %v and burst %d", config.Timeout, config.QPS, config.Burst)
+	}
+	if config.TLSClientConfig.CAData != nil || config.TLSClientConfig.CAFile != "/etc/workload-clusters/ca.crt" {
+		t.Fatalf("expected the CA file to be trusted instead of the kubeconfig CA, got %+v", config.TLSClientConfig)
+	}
+}
+
+func TestWorkloadClusterRESTConfigFailsWithoutKubeconfig(t *testing.T) {
+	myclient := fake.NewFakeClientWithScheme(setupScheme())
+	if _, err := (WorkloadClusterClientOptions{}).RESTConfig(myclient, newCluster("cluster")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestWorkloadClusterRESTConfigFromCredentials(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Annotations = map[string]string{WorkloadClusterCredentialsAnnotationKey: "proxy-credentials"}
+	cluster.Status.APIEndpoints = []capiv1alpha2.APIEndpoint{{Host: "10.0.0.1", Port: 6443}}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "proxy-credentials",
+		},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("cert"),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+		},
+	}
+	myclient := fake.NewFakeClientWithScheme(setupScheme(), secret, newKubeconfigSecret(t, "cluster"))
+
+	options := WorkloadClusterClientOptions{CAFile: "/etc/workload-clusters/ca.crt"}
+	config, err := options.RESTConfig(myclient, cluster)
+	if err != nil {
+		t.Fatalf("failed to build the client configuration: %v", err)
+	}
+	if config.Host != "https://10.0.0.1:6443" {
+		t.Fatalf("expected the API endpoint of the cluster, got host %s", config.Host)
+	}
+	if string(config.TLSClientConfig.CertData) != "cert" || string(config.TLSClientConfig.KeyData) != "key" {
+		t.Fatalf("expected the client certificate of the credentials secret, got %+v", config.TLSClientConfig)
+	}
+	if config.TLSClientConfig.CAFile != "/etc/workload-clusters/ca.crt" {
+		t.Fatalf("expected the CA file to be trusted without a CA in the secret, got %+v", config.TLSClientConfig)
+	}
+
+	secret.Data[workloadClusterCredentialsServerKey] = []byte("https://proxy.example.com/clusters/cluster")
+	secret.Data[workloadClusterCredentialsCAKey] = []byte("proxy-ca")
+	if err := myclient.Update(context.Background(), secret); err != nil {
+		t.Fatalf("failed to update the credentials secret: %v", err)
+	}
+	config, err = options.RESTConfig(myclient, cluster)
+	if err != nil {
+		t.Fatalf("failed to build the client configuration: %v", err)
+	}
+	if config.Host != "https://proxy.example.com/clusters/cluster" {
+		t.Fatalf("expected the server of the credentials secret, got host %s", config.Host)
+	}
+	if string(config.TLSClientConfig.CAData) != "proxy-ca" || config.TLSClientConfig.CAFile != "" {
+		t.Fatalf("expected the CA of the credentials secret to be trusted, got %+v", config.TLSClientConfig)
+	}
+
+	delete(secret.Data, corev1.TLSPrivateKeyKey)
+	if err := myclient.Update(context.Background(), secret); err != nil {
+		t.Fatalf("failed to update the credentials secret: %v", err)
+	}
+	if _, err := options.RESTConfig(myclient, cluster); err == nil {
+		t.Fatal("expected error without a client key, got nil")
+	}
+}
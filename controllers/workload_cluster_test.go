@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	capiv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
+	capierrors "sigs.k8s.io/cluster-api/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+func TestWorkloadClusterBackoff(t *testing.T) {
+	for failures, expected := range map[int32]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		3:  40 * time.Second,
+		6:  5 * time.Minute,
+		50: 5 * time.Minute,
+	} {
+		if backoff := workloadClusterBackoff(failures); backoff != expected {
+			t.Errorf("expected a backoff of %s after %d failures, got %s", expected, failures, backoff)
+		}
+	}
+}
+
+func TestIsWorkloadClusterUnreachable(t *testing.T) {
+	secrets := schema.GroupResource{Resource: "secrets"}
+	testcases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "connection error", err: errors.New("dial tcp 10.0.0.1:6443: connect: connection refused"), expected: true},
+		{name: "rotated certificate", err: apierrors.NewUnauthorized("Unauthorized"), expected: true},
+		{name: "unavailable API server", err: apierrors.NewServiceUnavailable("unavailable"), expected: true},
+		{name: "forbidden", err: apierrors.NewForbidden(secrets, "token", errors.New("forbidden")), expected: false},
+		{name: "conflict", err: apierrors.NewAlreadyExists(secrets, "token"), expected: false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if unreachable := isWorkloadClusterUnreachable(errors.Wrap(tc.err, "failed")); unreachable != tc.expected {
+				t.Fatalf("expected %t, got %t", tc.expected, unreachable)
+			}
+		})
+	}
+}
+
+func TestReconcileDiscoveryBacksOffWhenWorkloadClusterUnreachable(t *testing.T) {
+	cluster := newCluster("cluster")
+	cluster.Status.APIEndpoints = []capiv1alpha2.APIEndpoint{{Host: "example.com", Port: 6443}}
+	config := newWorkerJoinKubeadmConfig(nil, "cfg")
+
+	clientset := fakeclient.NewSimpleClientset()
+	clientset.PrependReactor("create", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("dial tcp 10.0.0.1:6443: connect: connection refused")
+	})
+	k := &KubeadmConfigReconciler{
+		Log:                  log.Log,
+		SecretsClientFactory: FakeSecretFactory{client: clientset.CoreV1().Secrets(metav1.NamespaceSystem)},
+	}
+
+	for _, expected := range []time.Duration{10 * time.Second, 20 * time.Second} {
+		err := k.reconcileDiscovery(cluster, config, defaultTokenTTL)
+		requeueErr, ok := errors.Cause(err).(capierrors.HasRequeueAfterError)
+		if !ok {
+			t.Fatalf("expected a requeue error, got %v", err)
+		}
+		if requeueErr.GetRequeueAfter() != expected {
+			t.Fatalf("expected to requeue after %s, got %s", expected, requeueErr.GetRequeueAfter())
+		}
+	}
+	expectCondition(t, config, cabpkv1alpha2.WorkloadClusterUnreachableCondition, corev1.ConditionTrue)
+
+	k.SecretsClientFactory = newFakeSecretFactory()
+	if err := k.reconcileDiscovery(cluster, config, defaultTokenTTL); err != nil {
+		t.Fatalf("failed to reconcile discovery: %v", err)
+	}
+	if config.Status.WorkloadClusterFailures != 0 {
+		t.Fatalf("expected the failures to be cleared, got %d", config.Status.WorkloadClusterFailures)
+	}
+	expectCondition(t, config, cabpkv1alpha2.WorkloadClusterUnreachableCondition, corev1.ConditionFalse)
+}
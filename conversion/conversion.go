@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conversion implements helpers to convert API objects between versions without losing data.
+package conversion
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// DataAnnotation is the annotation that conversion webhooks use to retain the data in case of down-conversion
+	// from a newer API version, so that converting back to it does not drop the fields the older version lacks.
+	DataAnnotation = "bootstrap.cluster.x-k8s.io/conversion-data"
+)
+
+// MarshalData stores the source object as json data in the destination object annotations map.
+// It ignores the metadata of the source object.
+func MarshalData(src metav1.Object, dst metav1.Object) error {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(src)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert object to unstructured")
+	}
+	delete(u, "metadata")
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal conversion data")
+	}
+
+	annotations := dst.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[DataAnnotation] = string(data)
+	dst.SetAnnotations(annotations)
+	return nil
+}
+
+// UnmarshalData tries to retrieve the data stashed by MarshalData in the from object annotations and unmarshals it
+// into the to object, removing the annotation from the from object. It returns false if there is no such data.
+func UnmarshalData(from metav1.Object, to interface{}) (bool, error) {
+	annotations := from.GetAnnotations()
+	data, ok := annotations[DataAnnotation]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal([]byte(data), to); err != nil {
+		return false, errors.Wrap(err, "failed to unmarshal conversion data")
+	}
+
+	delete(annotations, DataAnnotation)
+	from.SetAnnotations(annotations)
+	return true, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cabpkv1alpha2 "sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+)
+
+func TestMarshalUnmarshalData(t *testing.T) {
+	src := &cabpkv1alpha2.KubeadmConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cfg",
+			Namespace: "default",
+		},
+		Spec: cabpkv1alpha2.KubeadmConfigSpec{
+			DiscoveryEndpoint: "10.0.0.1:6443",
+			AdditionalUserDataFiles: []cabpkv1alpha2.Files{
+				{Path: "/etc/foo", Content: "foo"},
+			},
+		},
+	}
+
+	// the destination stands in for the object converted to an older version
+	dst := &cabpkv1alpha2.KubeadmConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cfg",
+			Namespace:   "default",
+			Annotations: map[string]string{"foo": "bar"},
+		},
+	}
+	if err := MarshalData(src, dst); err != nil {
+		t.Fatalf("failed to marshal data: %v", err)
+	}
+	if _, ok := dst.Annotations[DataAnnotation]; !ok {
+		t.Fatal("expected conversion data to be stored in the annotations")
+	}
+	if dst.Annotations["foo"] != "bar" {
+		t.Fatal("expected existing annotations to be preserved")
+	}
+
+	restored := &cabpkv1alpha2.KubeadmConfig{}
+	ok, err := UnmarshalData(dst, restored)
+	if err != nil {
+		t.Fatalf("failed to unmarshal data: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected conversion data to be found")
+	}
+	if !reflect.DeepEqual(restored.Spec, src.Spec) {
+		t.Fatalf("expected spec %+v to be restored, got %+v", src.Spec, restored.Spec)
+	}
+	if _, ok := dst.Annotations[DataAnnotation]; ok {
+		t.Fatal("expected conversion data annotation to be removed")
+	}
+
+	// once consumed, there is no data left to restore
+	ok, err = UnmarshalData(dst, &cabpkv1alpha2.KubeadmConfig{})
+	if err != nil {
+		t.Fatalf("failed to unmarshal data: %v", err)
+	}
+	if ok {
+		t.Fatal("did not expect conversion data to be found")
+	}
+}
+
+func TestUnmarshalDataFailsOnInvalidData(t *testing.T) {
+	from := &cabpkv1alpha2.KubeadmConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{DataAnnotation: "{"},
+		},
+	}
+	if _, err := UnmarshalData(from, &cabpkv1alpha2.KubeadmConfig{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package feature implements the feature gates of the experimental provider features.
+package feature
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Feature is the name of a feature gate.
+type Feature string
+
+const (
+	// MachinePool enables bootstrapping the machines of MachinePools.
+	MachinePool Feature = "MachinePool"
+
+	// Ignition enables rendering bootstrap data as Ignition, for operating systems that do not run cloud-init.
+	Ignition Feature = "Ignition"
+
+	// Windows enables rendering bootstrap data as PowerShell, for Windows worker machines.
+	Windows Feature = "Windows"
+
+	// ExternalDelivery enables the delivery of bootstrap data by SSH, object storage and bootstrap data server,
+	// instead of the machine user data.
+	ExternalDelivery Feature = "ExternalDelivery"
+)
+
+// Stage is the maturity of a feature.
+type Stage string
+
+const (
+	// Alpha features are disabled by default and may change or be removed without notice.
+	Alpha Stage = "ALPHA"
+
+	// Beta features are enabled by default and are only changed in backwards compatible ways.
+	Beta Stage = "BETA"
+)
+
+// Spec defines the default and maturity of a feature.
+type Spec struct {
+	Default bool
+	Stage   Stage
+}
+
+// defaultFeatures are the features known to the provider.
+var defaultFeatures = map[Feature]Spec{
+	MachinePool:      {Default: false, Stage: Alpha},
+	Ignition:         {Default: false, Stage: Alpha},
+	ExternalDelivery: {Default: true, Stage: Beta},
+	Windows:          {Default: false, Stage: Alpha},
+}
+
+// Gates are the feature gates of the manager, set by its --feature-gates flag.
+var Gates = NewGates(defaultFeatures)
+
+// FeatureGates reports which features are enabled. It implements flag.Value, accepting comma separated
+// Feature=bool pairs.
+type FeatureGates struct {
+	mu       sync.RWMutex
+	known    map[Feature]Spec
+	enabled  map[Feature]bool
+	explicit []string
+}
+
+// NewGates returns gates of the given features, each set to its default.
+func NewGates(known map[Feature]Spec) *FeatureGates {
+	g := &FeatureGates{
+		known:   known,
+		enabled: make(map[Feature]bool, len(known)),
+	}
+	for f, spec := range known {
+		g.enabled[f] = spec.Default
+	}
+	return g
+}
+
+// Enabled returns whether the feature is enabled. Unknown features are disabled.
+func (g *FeatureGates) Enabled(f Feature) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.enabled[f]
+}
+
+// Set enables or disables the features of the comma separated Feature=bool pairs. Features not listed keep their
+// current state.
+func (g *FeatureGates) Set(value string) error {
+	enabled := map[Feature]bool{}
+	var explicit []string
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return errors.Errorf("missing bool value for feature gate %q", kv[0])
+		}
+		f := Feature(strings.TrimSpace(kv[0]))
+		if _, ok := g.known[f]; !ok {
+			return errors.Errorf("unrecognized feature gate %q", f)
+		}
+		v, err := strconv.ParseBool(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return errors.Errorf("invalid value %q for feature gate %q, expected a bool", kv[1], f)
+		}
+		enabled[f] = v
+		explicit = append(explicit, fmt.Sprintf("%s=%t", f, v))
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for f, v := range enabled {
+		g.enabled[f] = v
+	}
+	g.explicit = append(g.explicit, explicit...)
+	return nil
+}
+
+// String returns the features set by Set.
+func (g *FeatureGates) String() string {
+	if g == nil {
+		return ""
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return strings.Join(g.explicit, ",")
+}
+
+// KnownFeatures describes the known features, with their maturity and default, sorted by name.
+func (g *FeatureGates) KnownFeatures() []string {
+	known := make([]string, 0, len(g.known))
+	for f, spec := range g.known {
+		known = append(known, fmt.Sprintf("%s=true|false (%s - default=%t)", f, spec.Stage, spec.Default))
+	}
+	sort.Strings(known)
+	return known
+}
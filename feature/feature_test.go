@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feature
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestGatesDefaults(t *testing.T) {
+	g := NewGates(defaultFeatures)
+	if g.Enabled(MachinePool) || g.Enabled(Ignition) {
+		t.Fatal("expected alpha features to be disabled by default")
+	}
+	if !g.Enabled(ExternalDelivery) {
+		t.Fatal("expected beta features to be enabled by default")
+	}
+	if g.Enabled(Feature("Unknown")) {
+		t.Fatal("expected unknown features to be disabled")
+	}
+}
+
+func TestGatesFlag(t *testing.T) {
+	g := NewGates(defaultFeatures)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(g, "feature-gates", "")
+	if err := fs.Parse([]string{"--feature-gates=MachinePool=true, ExternalDelivery=false"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+
+	if !g.Enabled(MachinePool) {
+		t.Fatal("expected MachinePool to be enabled")
+	}
+	if g.Enabled(ExternalDelivery) {
+		t.Fatal("expected ExternalDelivery to be disabled")
+	}
+	if g.Enabled(Ignition) {
+		t.Fatal("expected Ignition to keep its default")
+	}
+	if g.String() != "MachinePool=true,ExternalDelivery=false" {
+		t.Fatalf("unexpected flag value %q", g.String())
+	}
+}
+
+func TestGatesSetInvalid(t *testing.T) {
+	for _, value := range []string{
+		"Unknown=true",
+		"MachinePool",
+		"MachinePool=maybe",
+	} {
+		g := NewGates(defaultFeatures)
+		if err := g.Set(value); err == nil {
+			t.Fatalf("expected %q to be rejected", value)
+		}
+		if g.Enabled(MachinePool) {
+			t.Fatalf("expected %q not to change the gates", value)
+		}
+	}
+}
+
+func TestKnownFeatures(t *testing.T) {
+	known := NewGates(defaultFeatures).KnownFeatures()
+	expected := []string{
+		"ExternalDelivery=true|false (BETA - default=true)",
+		"Ignition=true|false (ALPHA - default=false)",
+		"MachinePool=true|false (ALPHA - default=false)",
+		"Windows=true|false (ALPHA - default=false)",
+	}
+	if len(known) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, known)
+	}
+	for i := range expected {
+		if known[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, known)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ignition renders bootstrap data as an Ignition v3 config, for operating systems such as Flatcar
+// Container Linux and Fedora CoreOS that do not run cloud-init. The files and commands are the ones of the
+// cloud-init user data generated from the same input.
+package ignition
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+)
+
+const (
+	// Version is the Ignition config specification version rendered.
+	Version = "3.0.0"
+
+	// bootstrapScriptName is the name of the script running the commands, in the generated files directory.
+	bootstrapScriptName = "bootstrap.sh"
+
+	// bootstrapUnitName is the systemd unit running the bootstrap script once, at first boot.
+	bootstrapUnitName = "kubeadm-bootstrap.service"
+
+	// etcdDiskLabel is the label of the partition and filesystem of the etcd disk.
+	etcdDiskLabel = "etcd_disk"
+)
+
+// NewInitControlPlane returns the Ignition config of a control plane machine initializing the cluster.
+func NewInitControlPlane(input *cloudinit.ControlPlaneInput) ([]byte, error) {
+	if _, err := cloudinit.NewInitControlPlane(input); err != nil {
+		return nil, err
+	}
+	return render(&input.BaseUserData, fmt.Sprintf("---\n%s\n---\n%s\n", input.ClusterConfiguration, input.InitConfiguration))
+}
+
+// NewJoinControlPlane returns the Ignition config of a control plane machine joining the cluster.
+func NewJoinControlPlane(input *cloudinit.ControlPlaneJoinInput) ([]byte, error) {
+	if _, err := cloudinit.NewJoinControlPlane(input); err != nil {
+		return nil, err
+	}
+	return render(&input.BaseUserData, input.JoinConfiguration+"\n")
+}
+
+// NewNode returns the Ignition config of a worker machine joining the cluster.
+func NewNode(input *cloudinit.NodeInput) ([]byte, error) {
+	if _, err := cloudinit.NewNode(input); err != nil {
+		return nil, err
+	}
+	return render(&input.BaseUserData, fmt.Sprintf("---\n%s\n", input.JoinConfiguration))
+}
+
+// NewReplace returns the Ignition config replaced by the one Ignition fetches from url.
+func NewReplace(url string) ([]byte, error) {
+	out, err := json.Marshal(config{Ignition: ignition{Version: Version, Config: &ignitionConfig{Replace: &contents{Source: url}}}})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the Ignition config")
+	}
+	return out, nil
+}
+
+// render returns the Ignition config writing the files and running the commands listed in the manifest of the
+// generated user data, given the content of its kubeadm configuration file.
+func render(b *cloudinit.BaseUserData, kubeadmConfiguration string) ([]byte, error) {
+	manifest := b.Manifest
+	if manifest == nil || len(manifest.Files) == 0 {
+		return nil, errors.New("the user data manifest lists no kubeadm configuration file")
+	}
+
+	// the kubeadm configuration file is the last one of the manifest, the others are written as is
+	kubeadmFile := manifest.Files[len(manifest.Files)-1]
+	files := append(append([]v1alpha2.Files{}, b.WriteFiles...), v1alpha2.Files{
+		Path:        kubeadmFile.Path,
+		Owner:       kubeadmFile.Owner,
+		Permissions: kubeadmFile.Permissions,
+		Content:     kubeadmConfiguration,
+	})
+	script := path.Join(b.GeneratedFilesDir, bootstrapScriptName)
+	files = append(files, v1alpha2.Files{
+		Path:        script,
+		Owner:       "root:root",
+		Permissions: "0700",
+		Content:     bootstrapScript(manifest.Commands),
+	})
+	files = append(files, ntpFiles(b.NTP)...)
+	files = append(files, sudoersFiles(b.Users)...)
+
+	cfg := config{Ignition: ignition{Version: Version}, Storage: &storage{}, Systemd: &systemd{}}
+	for _, f := range files {
+		rendered, err := newFile(f)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Storage.Files = append(cfg.Storage.Files, rendered)
+	}
+
+	if len(b.Users) > 0 {
+		cfg.Passwd = &passwd{Users: passwdUsers(b.Users)}
+	}
+
+	// the disk setup and mounts only hold the etcd disk, as the ignition format rejects the ones of the spec, and
+	// the etcd disk is rendered on its own since Ignition refers to its partition by label
+	var mounts []string
+	if etcdDisk := b.EtcdDisk; etcdDisk != nil {
+		cfg.Storage.Disks = append(cfg.Storage.Disks, disk{
+			Device:     etcdDisk.Device,
+			Partitions: []partition{{Label: etcdDiskLabel, Number: 1}},
+		})
+		cfg.Storage.Filesystems = append(cfg.Storage.Filesystems, filesystem{
+			Device: "/dev/disk/by-partlabel/" + etcdDiskLabel,
+			Format: etcdDisk.Filesystem,
+			Label:  etcdDiskLabel,
+		})
+		cfg.Systemd.Units = append(cfg.Systemd.Units, unit{
+			Name:     mountUnitName(etcdDisk.MountPath),
+			Enabled:  true,
+			Contents: mountUnit(etcdDisk),
+		})
+		mounts = append(mounts, etcdDisk.MountPath)
+	}
+	cfg.Systemd.Units = append(cfg.Systemd.Units, unit{
+		Name:     bootstrapUnitName,
+		Enabled:  true,
+		Contents: bootstrapUnit(script, mounts, b.TraceID),
+	})
+
+	out, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the Ignition config")
+	}
+	return out, nil
+}
+
+// newFile returns the Ignition file writing f, its content embedded as a data URL.
+func newFile(f v1alpha2.Files) (file, error) {
+	out := file{
+		Path:      f.Path,
+		Overwrite: true,
+		Contents:  contents{Source: "data:;base64," + base64.StdEncoding.EncodeToString([]byte(f.Content))},
+	}
+	if f.Permissions != "" {
+		mode, err := strconv.ParseInt(f.Permissions, 8, 32)
+		if err != nil {
+			return file{}, errors.Wrapf(err, "invalid permissions %q of file %s", f.Permissions, f.Path)
+		}
+		m := int(mode)
+		out.Mode = &m
+	}
+	if f.Owner != "" {
+		owner := strings.SplitN(f.Owner, ":", 2)
+		out.User = &node{Name: owner[0]}
+		if len(owner) == 2 {
+			out.Group = &node{Name: owner[1]}
+		}
+	}
+	return out, nil
+}
+
+// bootstrapScript returns the script running the commands in order, stopping at the first failing one.
+func bootstrapScript(commands []string) string {
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\nset -e\n")
+	for _, c := range commands {
+		script.WriteString(c)
+		script.WriteString("\n")
+	}
+	return script.String()
+}
+
+// bootstrapUnit returns the systemd unit running the bootstrap script once the given paths are mounted. It only
+// runs if the script exists and kubeadm has not yet written the kubelet kubeconfig, so that it runs once. When
+// set, the trace ID is exported to the script and tags its journal entries.
+func bootstrapUnit(script string, mounts []string, traceID string) string {
+	var u strings.Builder
+	u.WriteString("[Unit]\nDescription=Bootstrap the node with kubeadm\n")
+	u.WriteString("Wants=network-online.target\nAfter=network-online.target\n")
+	for _, m := range mounts {
+		fmt.Fprintf(&u, "RequiresMountsFor=%s\n", m)
+	}
+	fmt.Fprintf(&u, "ConditionPathExists=%s\nConditionPathExists=!/etc/kubernetes/kubelet.conf\n\n", script)
+	fmt.Fprintf(&u, "[Service]\nType=oneshot\nExecStart=%s\n", script)
+	if traceID != "" {
+		fmt.Fprintf(&u, "Environment=%s=%s\nSyslogIdentifier=kubeadm-bootstrap-%s\n", cloudinit.TraceIDEnv, traceID, traceID)
+	}
+	u.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+	return u.String()
+}
+
+// mountUnit returns the systemd unit mounting the etcd disk.
+func mountUnit(etcdDisk *v1alpha2.EtcdDisk) string {
+	return fmt.Sprintf("[Unit]\nDescription=Mount the etcd disk\n\n[Mount]\nWhat=/dev/disk/by-label/%s\nWhere=%s\nType=%s\n\n[Install]\nWantedBy=local-fs.target\n",
+		etcdDiskLabel, etcdDisk.MountPath, etcdDisk.Filesystem)
+}
+
+// mountUnitName returns the name systemd requires for the mount unit of the given path, e.g. var-lib-etcd.mount.
+func mountUnitName(mountPath string) string {
+	p := strings.Trim(path.Clean(mountPath), "/")
+	p = strings.Replace(p, "-", `\x2d`, -1)
+	return strings.Replace(p, "/", "-", -1) + ".mount"
+}
+
+// ntpFiles returns the systemd-timesyncd drop-in setting the NTP servers, as Ignition has no NTP configuration.
+func ntpFiles(ntp *v1alpha2.NTP) []v1alpha2.Files {
+	if ntp == nil || len(ntp.Servers) == 0 || (ntp.Enabled != nil && !*ntp.Enabled) {
+		return nil
+	}
+	return []v1alpha2.Files{{
+		Path:        "/etc/systemd/timesyncd.conf.d/ntp.conf",
+		Owner:       "root:root",
+		Permissions: "0644",
+		Content:     fmt.Sprintf("[Time]\nNTP=%s\n", strings.Join(ntp.Servers, " ")),
+	}}
+}
+
+// passwdUsers returns the Ignition users of the given users. As with cloud-init, the password is only set when
+// it is explicitly unlocked.
+func passwdUsers(users []v1alpha2.User) []passwdUser {
+	var out []passwdUser
+	for _, u := range users {
+		user := passwdUser{Name: u.Name, SSHAuthorizedKeys: u.SSHAuthorizedKeys}
+		if u.Passwd != "" && u.LockPassword != nil && !*u.LockPassword {
+			hash := u.Passwd
+			user.PasswordHash = &hash
+		}
+		if u.Shell != "" {
+			shell := u.Shell
+			user.Shell = &shell
+		}
+		out = append(out, user)
+	}
+	return out
+}
+
+// sudoersFiles returns the sudoers drop-ins of the users allowed to use sudo, as Ignition has no sudo
+// configuration.
+func sudoersFiles(users []v1alpha2.User) []v1alpha2.Files {
+	var files []v1alpha2.Files
+	for _, u := range users {
+		if u.Sudo == "" {
+			continue
+		}
+		files = append(files, v1alpha2.Files{
+			Path:        "/etc/sudoers.d/" + u.Name,
+			Owner:       "root:root",
+			Permissions: "0440",
+			Content:     fmt.Sprintf("%s %s\n", u.Name, u.Sudo),
+		})
+	}
+	return files
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+)
+
+func decode(t *testing.T, out []byte) *config {
+	t.Helper()
+	cfg := &config{}
+	if err := json.Unmarshal(out, cfg); err != nil {
+		t.Fatalf("failed to unmarshal the Ignition config: %v", err)
+	}
+	if cfg.Ignition.Version != Version {
+		t.Fatalf("expected version %s, got %s", Version, cfg.Ignition.Version)
+	}
+	return cfg
+}
+
+func fileContent(t *testing.T, cfg *config, p string) (file, string) {
+	t.Helper()
+	for _, f := range cfg.Storage.Files {
+		if f.Path != p {
+			continue
+		}
+		content, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(f.Contents.Source, "data:;base64,"))
+		if err != nil {
+			t.Fatalf("failed to decode the content of %s: %v", p, err)
+		}
+		return f, string(content)
+	}
+	t.Fatalf("expected file %s, got %+v", p, cfg.Storage.Files)
+	return file{}, ""
+}
+
+func TestNewInitControlPlane(t *testing.T) {
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("failed to generate certificates: %v", err)
+	}
+
+	out, err := NewInitControlPlane(&cloudinit.ControlPlaneInput{
+		BaseUserData: cloudinit.BaseUserData{
+			PreKubeadmCommands: []string{"echo pre"},
+			AdditionalCommands: []string{"echo post"},
+		},
+		Certificates:         *certificates,
+		ClusterConfiguration: "kind: ClusterConfiguration",
+		InitConfiguration:    "kind: InitConfiguration",
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the Ignition config: %v", err)
+	}
+	cfg := decode(t, out)
+
+	f, content := fileContent(t, cfg, v1alpha2.DefaultGeneratedFilesDir+"/kubeadm-init.yaml")
+	if expected := "---\nkind: ClusterConfiguration\n---\nkind: InitConfiguration\n"; content != expected {
+		t.Fatalf("expected kubeadm configuration %q, got %q", expected, content)
+	}
+	if f.Mode == nil || *f.Mode != 0640 || f.User == nil || f.User.Name != "root" || f.Group == nil || f.Group.Name != "root" {
+		t.Fatalf("expected the kubeadm configuration to be owned by root:root with mode 0640, got %+v", f)
+	}
+	fileContent(t, cfg, "/etc/kubernetes/pki/ca.crt")
+
+	_, script := fileContent(t, cfg, v1alpha2.DefaultGeneratedFilesDir+"/"+bootstrapScriptName)
+	expected := "#!/bin/sh\nset -e\necho pre\nkubeadm init --config " + v1alpha2.DefaultGeneratedFilesDir + "/kubeadm-init.yaml\n" +
+		"test $? -eq 0 && mkdir -p /run/cluster-api && echo success > " + cloudinit.BootstrapSentinelFile + "\necho post\n"
+	if script != expected {
+		t.Fatalf("expected bootstrap script %q, got %q", expected, script)
+	}
+
+	if len(cfg.Systemd.Units) != 1 || cfg.Systemd.Units[0].Name != bootstrapUnitName || !cfg.Systemd.Units[0].Enabled {
+		t.Fatalf("expected the enabled %s unit, got %+v", bootstrapUnitName, cfg.Systemd.Units)
+	}
+	if !strings.Contains(cfg.Systemd.Units[0].Contents, "ExecStart="+v1alpha2.DefaultGeneratedFilesDir+"/"+bootstrapScriptName+"\n") {
+		t.Fatalf("expected the unit to run the bootstrap script, got:\n%s", cfg.Systemd.Units[0].Contents)
+	}
+}
+
+func TestNewNode(t *testing.T) {
+	enabled := true
+	out, err := NewNode(&cloudinit.NodeInput{
+		BaseUserData: cloudinit.BaseUserData{
+			AdditionalFiles: []v1alpha2.Files{{Path: "/etc/motd", Content: "hello"}},
+			NTP:             &v1alpha2.NTP{Enabled: &enabled, Servers: []string{"0.pool.ntp.org", "1.pool.ntp.org"}},
+		},
+		JoinConfiguration: "kind: JoinConfiguration",
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the Ignition config: %v", err)
+	}
+	cfg := decode(t, out)
+
+	if _, content := fileContent(t, cfg, v1alpha2.DefaultGeneratedFilesDir+"/kubeadm-join.yaml"); content != "---\nkind: JoinConfiguration\n" {
+		t.Fatalf("unexpected kubeadm configuration %q", content)
+	}
+	f, content := fileContent(t, cfg, "/etc/motd")
+	if content != "hello" || f.Mode != nil || f.User != nil {
+		t.Fatalf("expected the additional file as is, got %+v with content %q", f, content)
+	}
+	if _, content := fileContent(t, cfg, "/etc/systemd/timesyncd.conf.d/ntp.conf"); content != "[Time]\nNTP=0.pool.ntp.org 1.pool.ntp.org\n" {
+		t.Fatalf("unexpected timesyncd configuration %q", content)
+	}
+	if len(cfg.Storage.Disks) != 0 || len(cfg.Storage.Filesystems) != 0 {
+		t.Fatalf("expected no disk, got %+v", cfg.Storage)
+	}
+}
+
+func TestNewNodeWithUsers(t *testing.T) {
+	unlocked := false
+	out, err := NewNode(&cloudinit.NodeInput{
+		BaseUserData: cloudinit.BaseUserData{
+			Users: []v1alpha2.User{
+				{Name: "ops", SSHAuthorizedKeys: []string{"ssh-ed25519 AAAA ops@example.com"}, Sudo: "ALL=(ALL) NOPASSWD:ALL", Passwd: "$6$salt$hash"},
+				{Name: "console", Shell: "/bin/bash", Passwd: "$6$salt$hash", LockPassword: &unlocked},
+			},
+		},
+		JoinConfiguration: "kind: JoinConfiguration",
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the Ignition config: %v", err)
+	}
+	cfg := decode(t, out)
+
+	if cfg.Passwd == nil || len(cfg.Passwd.Users) != 2 {
+		t.Fatalf("expected two users, got %+v", cfg.Passwd)
+	}
+	ops, console := cfg.Passwd.Users[0], cfg.Passwd.Users[1]
+	if ops.Name != "ops" || len(ops.SSHAuthorizedKeys) != 1 || ops.PasswordHash != nil {
+		t.Fatalf("expected the ops user with its key and a locked password, got %+v", ops)
+	}
+	if console.PasswordHash == nil || *console.PasswordHash != "$6$salt$hash" || console.Shell == nil || *console.Shell != "/bin/bash" {
+		t.Fatalf("expected the console user with its password and shell, got %+v", console)
+	}
+	if f, content := fileContent(t, cfg, "/etc/sudoers.d/ops"); content != "ops ALL=(ALL) NOPASSWD:ALL\n" || f.Mode == nil || *f.Mode != 0440 {
+		t.Fatalf("unexpected sudoers drop-in %+v with content %q", f, content)
+	}
+}
+
+func TestNewNodeWithTraceID(t *testing.T) {
+	out, err := NewNode(&cloudinit.NodeInput{
+		BaseUserData:      cloudinit.BaseUserData{TraceID: "0123abcd"},
+		JoinConfiguration: "kind: JoinConfiguration",
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the Ignition config: %v", err)
+	}
+	cfg := decode(t, out)
+
+	unit := cfg.Systemd.Units[len(cfg.Systemd.Units)-1].Contents
+	if !strings.Contains(unit, "Environment=CABPK_TRACE_ID=0123abcd\nSyslogIdentifier=kubeadm-bootstrap-0123abcd\n") {
+		t.Fatalf("expected the bootstrap unit to export and tag the trace ID, got:\n%s", unit)
+	}
+}
+
+func TestNewJoinControlPlaneWithEtcdDisk(t *testing.T) {
+	certificates, err := certs.NewCertificates()
+	if err != nil {
+		t.Fatalf("failed to generate certificates: %v", err)
+	}
+
+	out, err := NewJoinControlPlane(&cloudinit.ControlPlaneJoinInput{
+		BaseUserData: cloudinit.BaseUserData{
+			EtcdDisk: &v1alpha2.EtcdDisk{Device: "/dev/nvme1n1"},
+		},
+		Certificates:      *certificates,
+		JoinConfiguration: "kind: JoinConfiguration",
+	})
+	if err != nil {
+		t.Fatalf("failed to generate the Ignition config: %v", err)
+	}
+	cfg := decode(t, out)
+
+	if len(cfg.Storage.Disks) != 1 || cfg.Storage.Disks[0].Device != "/dev/nvme1n1" {
+		t.Fatalf("expected the etcd disk to be partitioned, got %+v", cfg.Storage.Disks)
+	}
+	if len(cfg.Storage.Filesystems) != 1 || cfg.Storage.Filesystems[0].Format != v1alpha2.DefaultEtcdDiskFilesystem {
+		t.Fatalf("expected the etcd disk to be formatted, got %+v", cfg.Storage.Filesystems)
+	}
+	if len(cfg.Systemd.Units) != 2 || cfg.Systemd.Units[0].Name != "var-lib-etcddisk.mount" {
+		t.Fatalf("expected the etcd disk mount unit, got %+v", cfg.Systemd.Units)
+	}
+	if !strings.Contains(cfg.Systemd.Units[1].Contents, "RequiresMountsFor="+v1alpha2.DefaultEtcdDiskMountPath+"\n") {
+		t.Fatalf("expected the bootstrap unit to require the etcd disk, got:\n%s", cfg.Systemd.Units[1].Contents)
+	}
+}
+
+func TestNewReplace(t *testing.T) {
+	out, err := NewReplace("https://10.0.0.2:9443/default/cfg?token=abc")
+	if err != nil {
+		t.Fatalf("failed to generate the Ignition config: %v", err)
+	}
+	if expected := `{"ignition":{"version":"3.0.0","config":{"replace":{"source":"https://10.0.0.2:9443/default/cfg?token=abc"}}}}`; string(out) != expected {
+		t.Fatalf("expected %s, got %s", expected, string(out))
+	}
+}
+
+func TestNewFileWithInvalidPermissions(t *testing.T) {
+	if _, err := newFile(v1alpha2.Files{Path: "/etc/motd", Permissions: "0x644"}); err == nil {
+		t.Fatal("expected an error for invalid permissions")
+	}
+}
+
+func TestMountUnitName(t *testing.T) {
+	testcases := map[string]string{
+		"/var/lib/etcddisk": "var-lib-etcddisk.mount",
+		"/mnt/etcd-data/":   `mnt-etcd\x2ddata.mount`,
+		"/var//lib/etcd":    "var-lib-etcd.mount",
+	}
+	for p, expected := range testcases {
+		if name := mountUnitName(p); name != expected {
+			t.Errorf("expected %s for %s, got %s", expected, p, name)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+// The subset of the Ignition v3.0.0 config specification rendered by the package.
+
+type config struct {
+	Ignition ignition `json:"ignition"`
+	Passwd   *passwd  `json:"passwd,omitempty"`
+	Storage  *storage `json:"storage,omitempty"`
+	Systemd  *systemd `json:"systemd,omitempty"`
+}
+
+type ignition struct {
+	Version string          `json:"version"`
+	Config  *ignitionConfig `json:"config,omitempty"`
+}
+
+type ignitionConfig struct {
+	Replace *contents `json:"replace,omitempty"`
+}
+
+type passwd struct {
+	Users []passwdUser `json:"users,omitempty"`
+}
+
+type passwdUser struct {
+	Name              string   `json:"name"`
+	PasswordHash      *string  `json:"passwordHash,omitempty"`
+	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
+	Shell             *string  `json:"shell,omitempty"`
+}
+
+type storage struct {
+	Disks       []disk       `json:"disks,omitempty"`
+	Filesystems []filesystem `json:"filesystems,omitempty"`
+	Files       []file       `json:"files,omitempty"`
+}
+
+type disk struct {
+	Device     string      `json:"device"`
+	Partitions []partition `json:"partitions,omitempty"`
+}
+
+type partition struct {
+	Label  string `json:"label,omitempty"`
+	Number int    `json:"number,omitempty"`
+}
+
+type filesystem struct {
+	Device string `json:"device"`
+	Format string `json:"format,omitempty"`
+	Label  string `json:"label,omitempty"`
+}
+
+type file struct {
+	Path      string   `json:"path"`
+	Overwrite bool     `json:"overwrite,omitempty"`
+	Mode      *int     `json:"mode,omitempty"`
+	User      *node    `json:"user,omitempty"`
+	Group     *node    `json:"group,omitempty"`
+	Contents  contents `json:"contents"`
+}
+
+type node struct {
+	Name string `json:"name,omitempty"`
+}
+
+type contents struct {
+	Source string `json:"source,omitempty"`
+}
+
+type systemd struct {
+	Units []unit `json:"units,omitempty"`
+}
+
+type unit struct {
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled,omitempty"`
+	Contents string `json:"contents,omitempty"`
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+// ConvertFromV1beta1 converts a v1beta1 InitConfiguration, ClusterConfiguration or JoinConfiguration to its
+// v1beta2 equivalent. v1beta2 is a superset of v1beta1, so the fields are carried over unchanged.
+func ConvertFromV1beta1(obj runtime.Object) (runtime.Object, error) {
+	var out runtime.Object
+	var kind string
+	switch obj.(type) {
+	case *v1beta1.InitConfiguration:
+		out, kind = &InitConfiguration{}, "InitConfiguration"
+	case *v1beta1.ClusterConfiguration:
+		out, kind = &ClusterConfiguration{}, "ClusterConfiguration"
+	case *v1beta1.JoinConfiguration:
+		out, kind = &JoinConfiguration{}, "JoinConfiguration"
+	default:
+		return nil, errors.Errorf("unsupported kubeadm configuration type %T", obj)
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal v1beta1 %s", kind)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, errors.Wrapf(err, "failed to convert %s to v1beta2", kind)
+	}
+	out.GetObjectKind().SetGroupVersionKind(GroupVersion.WithKind(kind))
+	return out, nil
+}
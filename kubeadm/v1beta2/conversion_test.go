@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/kubeadm/v1beta1"
+)
+
+func TestConvertFromV1beta1(t *testing.T) {
+	in := &v1beta1.InitConfiguration{
+		TypeMeta: metav1.TypeMeta{APIVersion: "kubeadm.k8s.io/v1beta1", Kind: "InitConfiguration"},
+		BootstrapTokens: []v1beta1.BootstrapToken{
+			{Token: &v1beta1.BootstrapTokenString{ID: "abcdef", Secret: "abcdef0123456789"}},
+		},
+		NodeRegistration: v1beta1.NodeRegistrationOptions{
+			Name:             "node",
+			KubeletExtraArgs: map[string]string{"cloud-provider": "aws"},
+		},
+		LocalAPIEndpoint: v1beta1.APIEndpoint{AdvertiseAddress: "10.0.0.1", BindPort: 6443},
+	}
+
+	obj, err := ConvertFromV1beta1(in)
+	if err != nil {
+		t.Fatalf("ConvertFromV1beta1 returned an unexpected error: %v", err)
+	}
+	out, ok := obj.(*InitConfiguration)
+	if !ok {
+		t.Fatalf("expected an InitConfiguration, got %T", obj)
+	}
+	if out.APIVersion != "kubeadm.k8s.io/v1beta2" || out.Kind != "InitConfiguration" {
+		t.Errorf("expected kubeadm.k8s.io/v1beta2 InitConfiguration, got %s %s", out.APIVersion, out.Kind)
+	}
+	if len(out.BootstrapTokens) != 1 || out.BootstrapTokens[0].Token.String() != "abcdef.abcdef0123456789" {
+		t.Errorf("expected the bootstrap token to be carried over, got %v", out.BootstrapTokens)
+	}
+	if out.NodeRegistration.Name != "node" || out.NodeRegistration.KubeletExtraArgs["cloud-provider"] != "aws" {
+		t.Errorf("expected the node registration to be carried over, got %v", out.NodeRegistration)
+	}
+	if out.LocalAPIEndpoint.AdvertiseAddress != "10.0.0.1" || out.LocalAPIEndpoint.BindPort != 6443 {
+		t.Errorf("expected the local API endpoint to be carried over, got %v", out.LocalAPIEndpoint)
+	}
+}
+
+func TestConvertFromV1beta1Join(t *testing.T) {
+	in := &v1beta1.JoinConfiguration{
+		Discovery: v1beta1.Discovery{
+			BootstrapToken: &v1beta1.BootstrapTokenDiscovery{APIServerEndpoint: "10.0.0.1:6443", Token: "abcdef.abcdef0123456789"},
+		},
+		ControlPlane: &v1beta1.JoinControlPlane{},
+	}
+
+	obj, err := ConvertFromV1beta1(in)
+	if err != nil {
+		t.Fatalf("ConvertFromV1beta1 returned an unexpected error: %v", err)
+	}
+	out := obj.(*JoinConfiguration)
+	if out.Kind != "JoinConfiguration" {
+		t.Errorf("expected kind JoinConfiguration, got %q", out.Kind)
+	}
+	if out.Discovery.BootstrapToken == nil || out.Discovery.BootstrapToken.APIServerEndpoint != "10.0.0.1:6443" {
+		t.Errorf("expected the bootstrap token discovery to be carried over, got %v", out.Discovery.BootstrapToken)
+	}
+	if out.ControlPlane == nil {
+		t.Error("expected the control plane to be carried over")
+	}
+
+	data, err := ConfigurationToYAML(out)
+	if err != nil {
+		t.Fatalf("ConfigurationToYAML returned an unexpected error: %v", err)
+	}
+	if !strings.Contains(data, "apiVersion: kubeadm.k8s.io/v1beta2\n") {
+		t.Errorf("expected the v1beta2 apiVersion, got:\n%s", data)
+	}
+}
+
+func TestConvertFromV1beta1Unsupported(t *testing.T) {
+	if _, err := ConvertFromV1beta1(&v1beta1.ClusterStatus{}); err == nil {
+		t.Error("expected an error converting a ClusterStatus")
+	}
+}
@@ -19,14 +19,20 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/klog"
 	"k8s.io/klog/klogr"
 	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha3"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/certs"
 	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/controllers"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/feature"
 	clusterv1alpha2 "sigs.k8s.io/cluster-api/pkg/apis/cluster/v1alpha2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	// +kubebuilder:scaffold:imports
@@ -40,6 +46,7 @@ var (
 func init() {
 	_ = scheme.AddToScheme(myscheme)
 	_ = v1alpha2.AddToScheme(myscheme)
+	_ = v1alpha3.AddToScheme(myscheme)
 	_ = clusterv1alpha2.AddToScheme(myscheme)
 	// +kubebuilder:scaffold:scheme
 }
@@ -49,33 +56,163 @@ func main() {
 
 	var metricsAddr string
 	var enableLeaderElection bool
+	var webhookPort int
+	var bootstrapDataAddr, bootstrapDataCertFile, bootstrapDataKeyFile string
+	var certificatesExpiryWarningWindow, controlPlaneInitTimeout, controlPlaneInitLockLeaseDuration time.Duration
+	var disableCertificateGeneration, bootstrapDataSecret, bootstrapDataSecretOnly bool
+	var kubeAPIQPS float64
+	var kubeAPIBurst int
+	var workloadClusterTimeout time.Duration
+	var workloadClusterQPS float64
+	var workloadClusterBurst int
+	var workloadClusterCAFile string
+	var bootstrapDataSecretReaders string
+	var certificateKeyType string
+	var caCertificateValidity time.Duration
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.IntVar(&webhookPort, "webhook-port", 0,
+		"The port the webhook server binds to. Webhooks are disabled if 0.")
+	flag.StringVar(&bootstrapDataAddr, "bootstrap-data-addr", "",
+		"The address the bootstrap data server for token-only delivery binds to. The server is disabled if empty.")
+	flag.StringVar(&bootstrapDataCertFile, "bootstrap-data-tls-cert-file", "",
+		"The TLS certificate of the bootstrap data server, required along with its key when the server is enabled.")
+	flag.StringVar(&bootstrapDataKeyFile, "bootstrap-data-tls-key-file", "", "The TLS key of the bootstrap data server.")
+	flag.DurationVar(&certificatesExpiryWarningWindow, "certificates-expiry-warning-window", controllers.DefaultCertificatesExpiryWarningWindow,
+		"How long before the CA certificates of a cluster expire the CertificatesExpiring condition of its KubeadmConfigs is set.")
+	flag.DurationVar(&controlPlaneInitTimeout, "control-plane-init-timeout", controllers.DefaultControlPlaneInitTimeout,
+		"How long the first control plane machine of a cluster has to register its node before another control plane machine may initialize the cluster.")
+	flag.DurationVar(&controlPlaneInitLockLeaseDuration, "control-plane-init-lock-lease-duration", 0,
+		"If set, the control plane init lock of each cluster is a Lease of this duration rather than a ConfigMap, lost by a holder that does not renew it or does not initialize the cluster within --control-plane-init-timeout.")
+	flag.BoolVar(&disableCertificateGeneration, "disable-certificate-generation", false,
+		"Never generate the certificates of a cluster; control plane machines wait until they are provided instead.")
+	flag.StringVar(&certificateKeyType, "certificate-key-type", string(certs.DefaultKeyType),
+		"The type of the keys generated for the CAs and service account key pair of the clusters, one of rsa-2048, rsa-3072, rsa-4096 or ecdsa-p256.")
+	flag.DurationVar(&caCertificateValidity, "ca-certificate-validity", certs.DefaultCAValidity,
+		"How long the CA certificates generated for the clusters are valid.")
+	flag.BoolVar(&bootstrapDataSecret, "bootstrap-data-secret", false,
+		"Also write the bootstrap data of each KubeadmConfig to a Secret referenced by status.dataSecretName, for infrastructure providers consuming it from a secret.")
+	flag.BoolVar(&bootstrapDataSecretOnly, "bootstrap-data-secret-only", false,
+		"Write the bootstrap data of each KubeadmConfig only to the Secret referenced by status.dataSecretName, leaving status.bootstrapData empty unless it is delivered over SSH.")
+	flag.StringVar(&bootstrapDataSecretReaders, "bootstrap-data-secret-readers", "",
+		"A comma separated list of namespace/name service accounts, e.g. of the infrastructure provider, granted read access to each bootstrap data Secret by a Role and RoleBinding limited to it.")
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", 0,
+		"The maximum queries per second to the management cluster API server. The client-go default is used if 0.")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", 0,
+		"The maximum burst of queries to the management cluster API server. The client-go default is used if 0.")
+	flag.DurationVar(&workloadClusterTimeout, "workload-cluster-timeout", controllers.DefaultWorkloadClusterTimeout,
+		"The timeout of the requests to workload clusters.")
+	flag.Float64Var(&workloadClusterQPS, "workload-cluster-qps", 0,
+		"The maximum queries per second to each workload cluster. The client-go default is used if 0.")
+	flag.IntVar(&workloadClusterBurst, "workload-cluster-burst", 0,
+		"The maximum burst of queries to each workload cluster. The client-go default is used if 0.")
+	flag.StringVar(&workloadClusterCAFile, "workload-cluster-ca-file", "",
+		"The CA bundle trusted for the API servers of workload clusters, instead of the CA of their kubeconfig or the system roots.")
+	flag.Var(feature.Gates, "feature-gates", "A set of key=value pairs enabling or disabling experimental features. Options are:\n"+
+		strings.Join(feature.Gates.KnownFeatures(), "\n"))
 	flag.Parse()
 
 	ctrl.SetLogger(klogr.New())
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	secretReaders, err := controllers.ParseServiceAccounts(bootstrapDataSecretReaders)
+	if err != nil {
+		setupLog.Error(err, "invalid --bootstrap-data-secret-readers")
+		os.Exit(1)
+	}
+
+	keyType, err := certs.ParseKeyType(certificateKeyType)
+	if err != nil {
+		setupLog.Error(err, "invalid --certificate-key-type")
+		os.Exit(1)
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+	if kubeAPIQPS > 0 {
+		restConfig.QPS = float32(kubeAPIQPS)
+	}
+	if kubeAPIBurst > 0 {
+		restConfig.Burst = kubeAPIBurst
+	}
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:             myscheme,
 		MetricsBindAddress: metricsAddr,
 		LeaderElection:     enableLeaderElection,
+		Port:               webhookPort,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	workloadClusterOptions := controllers.WorkloadClusterClientOptions{
+		Timeout: workloadClusterTimeout,
+		QPS:     float32(workloadClusterQPS),
+		Burst:   workloadClusterBurst,
+		CAFile:  workloadClusterCAFile,
+	}
 	if err := (&controllers.KubeadmConfigReconciler{
-		Client:               mgr.GetClient(),
-		SecretsClientFactory: controllers.ClusterSecretsClientFactory{},
-		Log:                  ctrl.Log.WithName("reconciler"),
+		Client:                            mgr.GetClient(),
+		SecretsClientFactory:              controllers.ClusterSecretsClientFactory{Options: workloadClusterOptions},
+		ControlPlaneHealthProber:          controllers.ClusterControlPlaneHealthProber{Options: workloadClusterOptions},
+		SSHPusher:                         controllers.SSHClientPusher{},
+		KubeadmFailureReports:             controllers.ClusterKubeadmFailureReports{Options: workloadClusterOptions},
+		Log:                               ctrl.Log.WithName("reconciler"),
+		ControlPlaneInitTimeout:           controlPlaneInitTimeout,
+		ControlPlaneInitLockLeaseDuration: controlPlaneInitLockLeaseDuration,
+		DisableCertificateGeneration:      disableCertificateGeneration,
+		CertificatesExpiryWarningWindow:   certificatesExpiryWarningWindow,
+		CertificateKeyType:                keyType,
+		CACertificateValidity:             caCertificateValidity,
+		BootstrapDataSecret:               bootstrapDataSecret,
+		BootstrapDataSecretOnly:           bootstrapDataSecretOnly,
+		BootstrapDataSecretReaders:        secretReaders,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "reconciler")
 		os.Exit(1)
 	}
+	if webhookPort != 0 {
+		if err := (&v1alpha2.KubeadmConfig{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "KubeadmConfig")
+			os.Exit(1)
+		}
+		if err := (&v1alpha2.KubeadmConfigTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "KubeadmConfigTemplate")
+			os.Exit(1)
+		}
+		if err := (&v1alpha2.BootstrapSettings{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "BootstrapSettings")
+			os.Exit(1)
+		}
+		if err := (&v1alpha3.KubeadmConfig{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "KubeadmConfig", "version", "v1alpha3")
+			os.Exit(1)
+		}
+		if err := (&v1alpha3.KubeadmConfigTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "KubeadmConfigTemplate", "version", "v1alpha3")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if bootstrapDataAddr != "" {
+		if bootstrapDataCertFile == "" || bootstrapDataKeyFile == "" {
+			setupLog.Error(errors.New("--bootstrap-data-tls-cert-file and --bootstrap-data-tls-key-file are required"), "invalid --bootstrap-data-addr")
+			os.Exit(1)
+		}
+		if err := mgr.Add(&controllers.BootstrapDataServer{
+			Client:               mgr.GetClient(),
+			SecretsClientFactory: controllers.ClusterSecretsClientFactory{Options: workloadClusterOptions},
+			Log:                  ctrl.Log.WithName("bootstrap-data-server"),
+			Addr:                 bootstrapDataAddr,
+			CertFile:             bootstrapDataCertFile,
+			KeyFile:              bootstrapDataKeyFile,
+		}); err != nil {
+			setupLog.Error(err, "unable to add bootstrap data server")
+			os.Exit(1)
+		}
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package objectstorage implements the minimal subset of the S3 API needed to store bootstrap data
+// in S3-compatible object storage and hand out pre-signed URLs to fetch it.
+package objectstorage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	algorithm    = "AWS4-HMAC-SHA256"
+	service      = "s3"
+	amzDate      = "20060102T150405Z"
+	shortDate    = "20060102"
+	maxExpiry    = 7 * 24 * time.Hour
+	maxErrorBody = 1024
+)
+
+// Credentials holds the keys used to sign requests.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// Client stores objects in a bucket of an S3-compatible object storage, using path-style addressing.
+type Client struct {
+	Endpoint    string
+	Region      string
+	Bucket      string
+	Credentials Credentials
+	HTTPClient  *http.Client
+}
+
+// Put uploads data under key.
+func (c *Client) Put(key string, data []byte) error {
+	resp, err := c.do(http.MethodPut, key, data)
+	if err != nil {
+		return errors.Wrapf(err, "failed to upload object %q", key)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return errors.Errorf("failed to upload object %q: %s: %s", key, resp.Status, string(body))
+	}
+	return nil
+}
+
+// Delete deletes the object under key. Deleting an object that does not exist succeeds.
+func (c *Client) Delete(key string) error {
+	resp, err := c.do(http.MethodDelete, key, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete object %q", key)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return errors.Errorf("failed to delete object %q: %s: %s", key, resp.Status, string(body))
+}
+
+// do sends a request performing method on key, signed for 15 minutes.
+func (c *Client) do(method, key string, data []byte) (*http.Response, error) {
+	u, err := c.PresignURL(method, key, time.Now(), 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// PresignURL returns a URL allowing anyone holding it to perform method on key until expiry elapsed from now.
+func (c *Client) PresignURL(method, key string, now time.Time, expiry time.Duration) (string, error) {
+	if expiry <= 0 || expiry > maxExpiry {
+		return "", errors.Errorf("invalid expiry %s, must be greater than zero and at most %s", expiry, maxExpiry)
+	}
+
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid endpoint %q", c.Endpoint)
+	}
+	if u.Host == "" {
+		return "", errors.Errorf("invalid endpoint %q, expected an absolute URL", c.Endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.Bucket + "/" + strings.TrimPrefix(key, "/")
+
+	return presign(method, u, c.Region, c.Credentials, now, expiry), nil
+}
+
+// presign signs u with AWS signature version 4 using query parameters, see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func presign(method string, u *url.URL, region string, creds Credentials, now time.Time, expiry time.Duration) string {
+	now = now.UTC()
+	scope := strings.Join([]string{now.Format(shortDate), region, service, "aws4_request"}, "/")
+
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", algorithm)
+	query.Set("X-Amz-Credential", creds.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(amzDate))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expiry/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if creds.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	canonicalQuery := canonicalQueryString(query)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		uriEncode(u.Path, false),
+		canonicalQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		algorithm,
+		now.Format(amzDate),
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), now.Format(shortDate))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", u.Scheme, u.Host, uriEncode(u.Path, false), canonicalQuery, signature)
+}
+
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes every byte except the unreserved characters; slashes are only encoded if encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package windows renders the bootstrap data of Windows worker machines as a PowerShell script run by
+// cloudbase-init. The machine image must provide kubeadm.exe and kubelet.exe in KubernetesDir, and nssm to register
+// the kubelet service. Only the files, the commands and the kubeadm join of the input are rendered: the parts of
+// the cloud-init user data that are Linux specific, such as the proxy and registry mirror files, are left out.
+package windows
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+)
+
+const (
+	// Header is the cloudbase-init header of the user data run by the 64 bit PowerShell.
+	Header = "#ps1_sysnative"
+
+	// KubernetesDir is the directory of the machine image holding kubeadm.exe and kubelet.exe.
+	KubernetesDir = "C:/k"
+
+	// kubeletServiceName is the name of the service kubeadm join starts kubelet with.
+	kubeletServiceName = "kubelet"
+
+	// kubeletStartScriptName is the name of the script, in the generated files directory, the kubelet service runs.
+	kubeletStartScriptName = "start-kubelet.ps1"
+
+	// kubeletStartScript starts kubelet with the flags kubeadm join writes, as on Linux the systemd drop-in of the
+	// Kubernetes packages does. Paths are rooted at the system drive, as kubelet does not resolve drive-less ones.
+	kubeletStartScript = `# Starts kubelet with the flags written by kubeadm join.
+$flags = (Get-Content -Raw -Path "$env:SYSTEMDRIVE/var/lib/kubelet/kubeadm-flags.env").Trim()
+$flags = $flags -replace '^KUBELET_KUBEADM_ARGS=', '' -replace '^"|"$', ''
+$kubeletArgs = @(
+  "--cert-dir=$env:SYSTEMDRIVE/var/lib/kubelet/pki",
+  "--config=$env:SYSTEMDRIVE/var/lib/kubelet/config.yaml",
+  "--bootstrap-kubeconfig=$env:SYSTEMDRIVE/etc/kubernetes/bootstrap-kubelet.conf",
+  "--kubeconfig=$env:SYSTEMDRIVE/etc/kubernetes/kubelet.conf",
+  "--hostname-override=$(hostname)",
+  "--cgroups-per-qos=false",
+  '--enforce-node-allocatable=""',
+  '--resolv-conf=""'
+)
+Invoke-Expression "` + KubernetesDir + `/kubelet.exe $($kubeletArgs -join ' ') $flags"
+`
+
+	// writeFileFunction writes a file from its base64 encoded content, creating its directory. Files are written
+	// with the default ACLs, their owner and permissions do not apply on Windows.
+	writeFileFunction = `function Write-BootstrapFile([string]$Path, [string]$Content) {
+  New-Item -ItemType Directory -Force -Path (Split-Path -Parent $Path) | Out-Null
+  [IO.File]::WriteAllBytes($Path, [Convert]::FromBase64String($Content))
+}`
+)
+
+// NewNode returns the PowerShell user data of a Windows worker machine joining the cluster. The pre and post
+// kubeadm commands are PowerShell commands.
+func NewNode(input *cloudinit.NodeInput) ([]byte, error) {
+	// the cloudinit generator defaults and validates the portable part of the input, and encodes its files
+	portable := &cloudinit.NodeInput{
+		BaseUserData: cloudinit.BaseUserData{
+			GeneratedFilesDir:  input.GeneratedFilesDir,
+			Verbosity:          input.Verbosity,
+			KubernetesVersion:  input.KubernetesVersion,
+			TraceID:            input.TraceID,
+			PreKubeadmCommands: input.PreKubeadmCommands,
+			AdditionalCommands: input.AdditionalCommands,
+			AdditionalFiles:    input.AdditionalFiles,
+		},
+		JoinConfiguration: input.JoinConfiguration,
+	}
+	if _, err := cloudinit.NewNode(portable); err != nil {
+		return nil, err
+	}
+
+	kubeadmFile := path.Join(portable.GeneratedFilesDir, "kubeadm-join.yaml")
+	startScript := path.Join(portable.GeneratedFilesDir, kubeletStartScriptName)
+	files := append(append([]v1alpha2.Files{}, portable.WriteFiles...),
+		v1alpha2.Files{Path: startScript, Content: kubeletStartScript},
+		v1alpha2.Files{Path: kubeadmFile, Content: fmt.Sprintf("---\n%s\n", input.JoinConfiguration)},
+	)
+
+	commands := append([]string{}, input.PreKubeadmCommands...)
+	commands = append(commands, kubeletServiceCommands(startScript)...)
+	commands = append(commands,
+		fmt.Sprintf("& %s/kubeadm.exe join --config %s%s", KubernetesDir, kubeadmFile, portable.KubeadmFlags),
+		`if ($LASTEXITCODE -ne 0) { throw "kubeadm join failed with exit code $LASTEXITCODE" }`,
+		fmt.Sprintf("New-Item -ItemType Directory -Force -Path %s | Out-Null; Set-Content -Path %s -Value success",
+			path.Dir(cloudinit.BootstrapSentinelFile), cloudinit.BootstrapSentinelFile),
+	)
+	commands = append(commands, input.AdditionalCommands...)
+
+	var script strings.Builder
+	fmt.Fprintf(&script, "%s\n$ErrorActionPreference = \"Stop\"\n", Header)
+	if input.TraceID != "" {
+		fmt.Fprintf(&script, "$env:%s = %s\n", cloudinit.TraceIDEnv, quote(input.TraceID))
+	}
+	script.WriteString(writeFileFunction + "\n")
+	for _, f := range files {
+		fmt.Fprintf(&script, "Write-BootstrapFile %s %s\n", quote(f.Path), quote(base64.StdEncoding.EncodeToString([]byte(f.Content))))
+	}
+	for _, c := range commands {
+		script.WriteString(c + "\n")
+	}
+
+	input.Manifest = &cloudinit.Manifest{
+		KubeadmVersion: input.KubernetesVersion,
+		TraceID:        input.TraceID,
+		Files:          make([]cloudinit.ManifestFile, 0, len(files)),
+		Commands:       commands,
+	}
+	for _, f := range files {
+		input.Manifest.Files = append(input.Manifest.Files, cloudinit.ManifestFile{Path: f.Path})
+	}
+	return []byte(script.String()), nil
+}
+
+// NewInclude returns the PowerShell user data downloading and running the one served at url, as cloudbase-init
+// has no include directive.
+func NewInclude(url string) []byte {
+	return []byte(fmt.Sprintf(`%s
+$ErrorActionPreference = "Stop"
+$script = Join-Path $env:TEMP "bootstrap-data.ps1"
+Invoke-WebRequest -UseBasicParsing -Uri %s -OutFile $script
+& $script
+`, Header, quote(url)))
+}
+
+// kubeletServiceCommands returns the commands registering the kubelet service running the start script, which
+// kubeadm join starts once it wrote the kubelet configuration.
+func kubeletServiceCommands(startScript string) []string {
+	return []string{
+		fmt.Sprintf("nssm install %s (Get-Command powershell).Source %s", kubeletServiceName,
+			quote("-ExecutionPolicy Bypass -NoProfile -File "+startScript)),
+		fmt.Sprintf("nssm set %s AppDirectory %s", kubeletServiceName, KubernetesDir),
+	}
+}
+
+// quote returns s as a single quoted PowerShell string.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package windows
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/api/v1alpha2"
+	"sigs.k8s.io/cluster-api-bootstrap-provider-kubeadm/cloudinit"
+)
+
+// fileContent returns the decoded content of the file the script writes at p.
+func fileContent(t *testing.T, script, p string) string {
+	t.Helper()
+	prefix := "Write-BootstrapFile '" + p + "' '"
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		content, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(line, prefix), "'"))
+		if err != nil {
+			t.Fatalf("failed to decode the content of %s: %v", p, err)
+		}
+		return string(content)
+	}
+	t.Fatalf("expected file %s, got:\n%s", p, script)
+	return ""
+}
+
+func TestNewNode(t *testing.T) {
+	verbosity := int32(2)
+	input := &cloudinit.NodeInput{
+		BaseUserData: cloudinit.BaseUserData{
+			Verbosity:          &verbosity,
+			TraceID:            "abc-123",
+			AdditionalFiles:    []v1alpha2.Files{{Path: "C:/k/config.txt", Content: "hello\n", LineEndings: v1alpha2.FileLineEndingsCRLF}},
+			PreKubeadmCommands: []string{"Write-Output pre"},
+			AdditionalCommands: []string{"Write-Output post"},
+			Proxy:              &v1alpha2.Proxy{HTTPProxy: "http://proxy:3128"},
+		},
+		JoinConfiguration: "kind: JoinConfiguration",
+	}
+	out, err := NewNode(input)
+	if err != nil {
+		t.Fatalf("failed to generate the user data: %v", err)
+	}
+	script := string(out)
+
+	if !strings.HasPrefix(script, Header+"\n$ErrorActionPreference = \"Stop\"\n$env:CABPK_TRACE_ID = 'abc-123'\n") {
+		t.Fatalf("expected the PowerShell header and the trace ID first, got:\n%s", script)
+	}
+	if content := fileContent(t, script, "C:/k/config.txt"); content != "hello\r\n" {
+		t.Fatalf("expected the file content in its line endings, got %q", content)
+	}
+	if content := fileContent(t, script, v1alpha2.DefaultGeneratedFilesDir+"/kubeadm-join.yaml"); content != "---\nkind: JoinConfiguration\n" {
+		t.Fatalf("unexpected kubeadm configuration %q", content)
+	}
+	if content := fileContent(t, script, v1alpha2.DefaultGeneratedFilesDir+"/"+kubeletStartScriptName); content != kubeletStartScript {
+		t.Fatalf("unexpected kubelet start script %q", content)
+	}
+	if strings.Contains(script, "proxy") {
+		t.Fatalf("expected the Linux proxy files to be left out, got:\n%s", script)
+	}
+
+	expected := strings.Join([]string{
+		"Write-Output pre",
+		"nssm install kubelet (Get-Command powershell).Source '-ExecutionPolicy Bypass -NoProfile -File /run/kubeadm/start-kubelet.ps1'",
+		"nssm set kubelet AppDirectory C:/k",
+		"& C:/k/kubeadm.exe join --config /run/kubeadm/kubeadm-join.yaml --v=2",
+		`if ($LASTEXITCODE -ne 0) { throw "kubeadm join failed with exit code $LASTEXITCODE" }`,
+		"New-Item -ItemType Directory -Force -Path /run/cluster-api | Out-Null; Set-Content -Path " + cloudinit.BootstrapSentinelFile + " -Value success",
+		"Write-Output post",
+	}, "\n") + "\n"
+	if !strings.HasSuffix(script, expected) {
+		t.Fatalf("expected the script to end with\n%s\ngot:\n%s", expected, script)
+	}
+
+	if input.Manifest == nil || len(input.Manifest.Commands) != 7 || input.Manifest.TraceID != "abc-123" {
+		t.Fatalf("expected the manifest to list the commands, got %+v", input.Manifest)
+	}
+	if last := input.Manifest.Files[len(input.Manifest.Files)-1]; last.Path != "/run/kubeadm/kubeadm-join.yaml" {
+		t.Fatalf("expected the kubeadm configuration to be the last file of the manifest, got %s", last.Path)
+	}
+}
+
+func TestNewNodeValidatesInput(t *testing.T) {
+	if _, err := NewNode(&cloudinit.NodeInput{BaseUserData: cloudinit.BaseUserData{TraceID: "a b"}}); err == nil {
+		t.Fatal("expected an invalid trace ID to be rejected")
+	}
+}
+
+func TestNewInclude(t *testing.T) {
+	out := string(NewInclude("https://10.0.0.2:9443/default/cfg?token=it's"))
+	if !strings.HasPrefix(out, Header+"\n") {
+		t.Fatalf("expected the PowerShell header, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Invoke-WebRequest -UseBasicParsing -Uri 'https://10.0.0.2:9443/default/cfg?token=it''s' -OutFile $script\n") {
+		t.Fatalf("expected the quoted URL to be downloaded, got:\n%s", out)
+	}
+}